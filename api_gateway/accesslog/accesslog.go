@@ -0,0 +1,216 @@
+// Package accesslog пишет журнал доступа API Gateway в отдельный поток
+// (stdout, файл или сокет) в стабильной JSON схеме, независимой от логов приложения:
+// одна строка JSON на запрос, пригодная для загрузки в ELK/ClickHouse
+package accesslog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SchemaVersion версия схемы записи; увеличивается только при несовместимых изменениях
+const SchemaVersion = 1
+
+// Entry запись журнала доступа. Все поля присутствуют в каждой строке,
+// чтобы схема таблицы на стороне хранилища оставалась фиксированной
+type Entry struct {
+	SchemaVersion int       `json:"schema_version"`
+	Timestamp     time.Time `json:"timestamp"`
+	RequestID     string    `json:"request_id"`
+	TraceID       string    `json:"trace_id"`
+	Method        string    `json:"method"`
+	// Route шаблон маршрута (/v1/orders/{id}); пусто, если маршрут не найден
+	Route      string  `json:"route"`
+	Path       string  `json:"path"`
+	Status     int     `json:"status"`
+	LatencyMS  float64 `json:"latency_ms"`
+	BytesIn    int64   `json:"bytes_in"`
+	BytesOut   int64   `json:"bytes_out"`
+	UserID     string  `json:"user_id"`
+	Upstream   string  `json:"upstream"`
+	RemoteAddr string  `json:"remote_addr"`
+	UserAgent  string  `json:"user_agent"`
+	// CostCenter тег центра затрат; пусто, если тегирование отключено
+	CostCenter string `json:"cost_center"`
+	// Stages разбивка LatencyMS по этапам
+	Stages Stages `json:"stages"`
+}
+
+// Stages разбивка времени запроса по этапам, мс. Этапы сервиса берутся из заголовка
+// Server-Timing его ответа и равны нулю, если запрос не дошел до сервиса или сервис
+// их не сообщил. Сумма этапов равна latency_ms с точностью до округления
+type Stages struct {
+	// AuthMS проверка JWT токена и эпохи ролей
+	AuthMS float64 `json:"auth_ms"`
+	// GatewayMS остальная обработка в шлюзе, включая передачу тела ответа клиенту
+	GatewayMS float64 `json:"gateway_ms"`
+	// ProxyMS сеть и очереди между шлюзом и сервисом: время до заголовков ответа сервиса
+	// за вычетом обработки в сервисе
+	ProxyMS float64 `json:"proxy_ms"`
+	// ServiceMS обработчик сервиса без запросов к БД и публикации событий
+	ServiceMS float64 `json:"service_ms"`
+	// DBMS запросы сервиса к БД
+	DBMS float64 `json:"db_ms"`
+	// PublishMS публикация событий сервисом
+	PublishMS float64 `json:"publish_ms"`
+}
+
+type entryKey struct{}
+
+// NewContext возвращает контекст с записью, которую заполняют обработчики по ходу запроса
+func NewContext(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// FromContext возвращает запись текущего запроса или nil, если контекст не содержит записи
+func FromContext(ctx context.Context) *Entry {
+	entry, _ := ctx.Value(entryKey{}).(*Entry)
+	return entry
+}
+
+// Logger асинхронно записывает строки журнала. Запись не блокирует обработку
+// запросов: при переполнении буфера строки отбрасываются и учитываются в Dropped
+type Logger struct {
+	sink    io.WriteCloser
+	lines   chan []byte
+	dropped atomic.Uint64
+	done    chan struct{}
+	once    sync.Once
+}
+
+// New создает Logger для output:
+// "stdout", "stderr", "file:///path" (или просто путь к файлу),
+// "tcp://host:port", "udp://host:port", "unix:///path"
+func New(output string, bufferSize int) (*Logger, error) {
+	sink, err := openSink(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+
+	l := &Logger{
+		sink:  sink,
+		lines: make(chan []byte, bufferSize),
+		done:  make(chan struct{}),
+	}
+	go l.run()
+	return l, nil
+}
+
+// Log ставит запись в очередь на запись
+func (l *Logger) Log(entry *Entry) {
+	entry.SchemaVersion = SchemaVersion
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		l.dropped.Add(1)
+		return
+	}
+	line = append(line, '\n')
+
+	select {
+	case l.lines <- line:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Dropped возвращает число отброшенных записей
+func (l *Logger) Dropped() uint64 {
+	return l.dropped.Load()
+}
+
+// Close дописывает буфер и закрывает поток
+func (l *Logger) Close() error {
+	l.once.Do(func() {
+		close(l.lines)
+	})
+	<-l.done
+	return l.sink.Close()
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+	for line := range l.lines {
+		if _, err := l.sink.Write(line); err != nil {
+			l.dropped.Add(1)
+		}
+	}
+}
+
+// openSink открывает поток вывода журнала
+func openSink(output string) (io.WriteCloser, error) {
+	switch {
+	case output == "" || output == "stdout":
+		return nopCloser{os.Stdout}, nil
+	case output == "stderr":
+		return nopCloser{os.Stderr}, nil
+	case strings.HasPrefix(output, "tcp://"), strings.HasPrefix(output, "udp://"), strings.HasPrefix(output, "unix://"):
+		network, address, _ := strings.Cut(output, "://")
+		return &socketSink{network: network, address: address}, nil
+	}
+
+	path := strings.TrimPrefix(output, "file://")
+	// O_APPEND позволяет использовать logrotate в режиме copytruncate
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла журнала доступа: %v", err)
+	}
+	return file, nil
+}
+
+// nopCloser не закрывает стандартные потоки процесса
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// socketSink пишет в сокет; при ошибке соединение пересоздается на следующей записи,
+// поэтому недоступность сборщика логов не влияет на работу шлюза
+type socketSink struct {
+	network string
+	address string
+	conn    net.Conn
+}
+
+func (s *socketSink) Write(p []byte) (int, error) {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, time.Second)
+		if err != nil {
+			return 0, err
+		}
+		s.conn = conn
+	}
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(time.Second)); err != nil {
+		return 0, err
+	}
+
+	n, err := s.conn.Write(p)
+	if err != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return n, err
+}
+
+func (s *socketSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
@@ -0,0 +1,356 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"api_gateway/logger"
+
+	"pkg/rbac"
+
+	"go.uber.org/zap"
+)
+
+// RoutePolicy описывает правила кеширования для префикса маршрута
+type RoutePolicy struct {
+	Prefix string
+	// TTL время, в течение которого ответ считается свежим
+	TTL time.Duration
+	// StaleWhileRevalidate время после TTL, в течение которого отдаётся устаревший ответ
+	// с фоновым обновлением
+	StaleWhileRevalidate time.Duration
+	// StaleIfError время после TTL, в течение которого устаревший ответ отдаётся
+	// при ошибке upstream сервиса
+	StaleIfError time.Duration
+}
+
+// entry закешированный ответ upstream сервиса
+type entry struct {
+	key        string
+	statusCode int
+	header     http.Header
+	body       []byte
+	storedAt   time.Time
+}
+
+// ResponseCache in-memory кеш ответов с поддержкой stale-while-revalidate и stale-if-error.
+// Число записей ограничено: при превышении вытесняются давно не запрашивавшиеся (LRU)
+type ResponseCache struct {
+	policies   []RoutePolicy
+	maxEntries int
+	// timeout предельное время фонового обновления запроса; nil или 0 — без ограничения
+	timeout func(*http.Request) time.Duration
+	logger  *zap.Logger
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	// recency записи кеша (*entry) от недавно запрошенных к давно не запрашивавшимся
+	recency      *list.List
+	revalidating map[string]bool
+}
+
+// NewResponseCache создает новый кеш ответов с заданными политиками маршрутов,
+// хранящий не более maxEntries ответов. timeout задает предельное время фонового
+// обновления записи — тот же таймаут маршрута, что и у запросов клиентов
+func NewResponseCache(policies []RoutePolicy, maxEntries int, timeout func(*http.Request) time.Duration, logger *zap.Logger) *ResponseCache {
+	return &ResponseCache{
+		policies:     policies,
+		maxEntries:   maxEntries,
+		timeout:      timeout,
+		logger:       logger,
+		entries:      make(map[string]*list.Element),
+		recency:      list.New(),
+		revalidating: make(map[string]bool),
+	}
+}
+
+// Middleware кеширует GET-ответы для маршрутов, на которые распространяется политика
+func (c *ResponseCache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy, ok := c.policyFor(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Изменяющие запросы инвалидируют кеш пользователя для этого маршрута
+		if r.Method != http.MethodGet {
+			c.purgeUser(policy.Prefix, r.Header.Get("X-User-ID"))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+			c.fetchAndStore(w, r, next, cacheKey(r), "BYPASS")
+			return
+		}
+
+		key := cacheKey(r)
+		cached := c.get(key)
+		if cached == nil {
+			c.fetchAndStore(w, r, next, key, "MISS")
+			return
+		}
+
+		age := time.Since(cached.storedAt)
+		switch {
+		case age <= policy.TTL:
+			writeEntry(w, cached, "HIT")
+		case age <= policy.TTL+policy.StaleWhileRevalidate:
+			writeEntry(w, cached, "STALE")
+			c.revalidate(r, next, key)
+		default:
+			c.fetchWithFallback(w, r, next, key, cached, policy)
+		}
+	})
+}
+
+// Purge очищает все закешированные ответы и возвращает их число
+func (c *ResponseCache) Purge() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	purged := len(c.entries)
+	c.entries = make(map[string]*list.Element)
+	c.recency.Init()
+	return purged
+}
+
+// policyFor возвращает политику для пути запроса
+func (c *ResponseCache) policyFor(path string) (RoutePolicy, bool) {
+	for _, p := range c.policies {
+		if strings.HasPrefix(path, p.Prefix) {
+			return p, true
+		}
+	}
+	return RoutePolicy{}, false
+}
+
+// fetchAndStore выполняет запрос к upstream и сохраняет успешный ответ
+func (c *ResponseCache) fetchAndStore(w http.ResponseWriter, r *http.Request, next http.Handler, key, status string) {
+	buf := newBufferedResponse()
+	next.ServeHTTP(buf, r)
+	if buf.statusCode == http.StatusOK {
+		c.set(key, buf.toEntry())
+	}
+	writeEntry(w, buf.toEntry(), status)
+}
+
+// fetchWithFallback выполняет запрос к upstream и при ошибке отдаёт устаревший ответ,
+// если он ещё укладывается в окно stale-if-error
+func (c *ResponseCache) fetchWithFallback(w http.ResponseWriter, r *http.Request, next http.Handler, key string, cached *entry, policy RoutePolicy) {
+	buf := newBufferedResponse()
+	next.ServeHTTP(buf, r)
+
+	if isUpstreamFailure(buf.statusCode) && time.Since(cached.storedAt) <= policy.TTL+policy.StaleIfError {
+		log := logger.WithRequestID(c.logger, r.Header.Get("X-Request-ID"))
+		log.Warn("Upstream недоступен, отдаём устаревший ответ из кеша",
+			zap.String("path", r.URL.Path),
+			zap.Int("upstream_status", buf.statusCode),
+			zap.Duration("age", time.Since(cached.storedAt)),
+		)
+		writeEntry(w, cached, "STALE-IF-ERROR")
+		return
+	}
+
+	if buf.statusCode == http.StatusOK {
+		c.set(key, buf.toEntry())
+	}
+	writeEntry(w, buf.toEntry(), "MISS")
+}
+
+// revalidate обновляет запись кеша в фоне; одновременно выполняется не более одного обновления на ключ
+func (c *ResponseCache) revalidate(r *http.Request, next http.Handler, key string) {
+	c.mutex.Lock()
+	if c.revalidating[key] {
+		c.mutex.Unlock()
+		return
+	}
+	c.revalidating[key] = true
+	c.mutex.Unlock()
+
+	// Клонируем запрос с независимым контекстом: исходный будет отменён после ответа клиенту.
+	// Зависший сервис не должен удерживать обновление дольше таймаута маршрута
+	var limit time.Duration
+	if c.timeout != nil {
+		limit = c.timeout(r)
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if limit > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), limit)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	req := r.Clone(ctx)
+
+	go func() {
+		defer func() {
+			cancel()
+			c.mutex.Lock()
+			delete(c.revalidating, key)
+			c.mutex.Unlock()
+		}()
+
+		buf := newBufferedResponse()
+		next.ServeHTTP(buf, req)
+		if buf.statusCode == http.StatusOK {
+			c.set(key, buf.toEntry())
+		}
+	}()
+}
+
+// get возвращает запись кеша по ключу и отмечает ее как недавно запрошенную
+func (c *ResponseCache) get(key string) *entry {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.recency.MoveToFront(element)
+	return element.Value.(*entry)
+}
+
+// set сохраняет запись кеша по ключу и вытесняет давно не запрашивавшиеся записи сверх maxEntries
+func (c *ResponseCache) set(key string, e *entry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e.key = key
+	if element, ok := c.entries[key]; ok {
+		element.Value = e
+		c.recency.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.recency.PushFront(e)
+
+	for c.maxEntries > 0 && c.recency.Len() > c.maxEntries {
+		c.remove(c.recency.Back())
+	}
+}
+
+// remove удаляет запись кеша; вызывается под c.mutex
+func (c *ResponseCache) remove(element *list.Element) {
+	c.recency.Remove(element)
+	delete(c.entries, element.Value.(*entry).key)
+}
+
+// purgeUser удаляет закешированные ответы пользователя для префикса маршрута
+func (c *ResponseCache) purgeUser(prefix, userID string) {
+	keyPrefix := fmt.Sprintf("%s|", userID)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, keyPrefix) && strings.Contains(key, "|"+prefix) {
+			c.remove(element)
+		}
+	}
+}
+
+// cacheKey формирует ключ кеша: ответы различаются по пользователю, его ролям и правам,
+// а также по Accept-Language, от которого зависят локализованные поля (status_name заказов)
+func cacheKey(r *http.Request) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s", r.Header.Get("X-User-ID"), r.Header.Get("X-User-Roles"), r.Header.Get(rbac.HeaderPermissions),
+		r.Header.Get("Accept-Language"), r.URL.RequestURI())
+}
+
+// isUpstreamFailure проверяет, является ли статус признаком недоступности upstream
+func isUpstreamFailure(statusCode int) bool {
+	return statusCode == http.StatusBadGateway ||
+		statusCode == http.StatusServiceUnavailable ||
+		statusCode == http.StatusGatewayTimeout
+}
+
+// writeEntry отправляет закешированный ответ клиенту
+func writeEntry(w http.ResponseWriter, e *entry, status string) {
+	for k, values := range e.header {
+		for _, v := range values {
+			w.Header().Add(k, v)
+		}
+	}
+	w.Header().Set("X-Cache", status)
+	if status != "MISS" && status != "BYPASS" {
+		w.Header().Set("Age", fmt.Sprintf("%d", int(time.Since(e.storedAt).Seconds())))
+	}
+	w.WriteHeader(e.statusCode)
+	w.Write(e.body)
+}
+
+// bufferedResponse накапливает ответ upstream в памяти
+type bufferedResponse struct {
+	header     http.Header
+	body       bytes.Buffer
+	statusCode int
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: make(http.Header), statusCode: http.StatusOK}
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	b.statusCode = code
+}
+
+func (b *bufferedResponse) toEntry() *entry {
+	return &entry{
+		statusCode: b.statusCode,
+		header:     b.header.Clone(),
+		body:       b.body.Bytes(),
+		storedAt:   time.Now(),
+	}
+}
+
+// ParsePolicies разбирает политики кеширования из строки вида
+// "/v1/orders=5s,30s,5m;/v1/users/profile=10s,1m,10m",
+// где значения — TTL, stale-while-revalidate и stale-if-error соответственно
+func ParsePolicies(spec string) ([]RoutePolicy, error) {
+	var policies []RoutePolicy
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("некорректная политика кеширования: %s", part)
+		}
+
+		values := strings.Split(kv[1], ",")
+		if len(values) != 3 {
+			return nil, fmt.Errorf("политика %s должна содержать ttl, stale-while-revalidate и stale-if-error", kv[0])
+		}
+
+		durations := make([]time.Duration, len(values))
+		for i, v := range values {
+			d, err := time.ParseDuration(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("некорректная длительность в политике %s: %v", kv[0], err)
+			}
+			durations[i] = d
+		}
+
+		policies = append(policies, RoutePolicy{
+			Prefix:               strings.TrimSpace(kv[0]),
+			TTL:                  durations[0],
+			StaleWhileRevalidate: durations[1],
+			StaleIfError:         durations[2],
+		})
+	}
+	return policies, nil
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// countingUpstream отвечает 200 и считает запросы по пути
+type countingUpstream map[string]int
+
+func (u countingUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u[r.URL.RequestURI()]++
+	w.Write([]byte(r.URL.RequestURI()))
+}
+
+func serveCached(handler http.Handler, target string) string {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec.Header().Get("X-Cache")
+}
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	policies := []RoutePolicy{{Prefix: "/v1/orders", TTL: time.Minute}}
+	c := NewResponseCache(policies, 2, nil, zap.NewNop())
+	upstream := countingUpstream{}
+	handler := c.Middleware(upstream)
+
+	serveCached(handler, "/v1/orders/a")
+	serveCached(handler, "/v1/orders/b")
+	// a запрошен позже b, поэтому при добавлении c вытесняется b
+	if status := serveCached(handler, "/v1/orders/a"); status != "HIT" {
+		t.Fatalf("X-Cache %q для a, ожидался HIT", status)
+	}
+	serveCached(handler, "/v1/orders/c")
+
+	if len(c.entries) != 2 || c.recency.Len() != 2 {
+		t.Fatalf("записей %d (в списке %d), ожидалось не больше 2", len(c.entries), c.recency.Len())
+	}
+	if status := serveCached(handler, "/v1/orders/a"); status != "HIT" {
+		t.Errorf("X-Cache %q для a, ожидался HIT", status)
+	}
+	if status := serveCached(handler, "/v1/orders/b"); status != "MISS" {
+		t.Errorf("X-Cache %q для вытесненного b, ожидался MISS", status)
+	}
+	if upstream["/v1/orders/b"] != 2 {
+		t.Errorf("запросов b к сервису %d, ожидалось 2", upstream["/v1/orders/b"])
+	}
+}
+
+func TestResponseCachePurge(t *testing.T) {
+	policies := []RoutePolicy{{Prefix: "/v1/orders", TTL: time.Minute}}
+	c := NewResponseCache(policies, 10, nil, zap.NewNop())
+	handler := c.Middleware(countingUpstream{})
+
+	serveCached(handler, "/v1/orders/a")
+	serveCached(handler, "/v1/orders/b")
+
+	if purged := c.Purge(); purged != 2 {
+		t.Fatalf("очищено %d записей, ожидалось 2", purged)
+	}
+	if c.recency.Len() != 0 {
+		t.Fatalf("после очистки в списке вытеснения %d записей", c.recency.Len())
+	}
+	if status := serveCached(handler, "/v1/orders/a"); status != "MISS" {
+		t.Errorf("X-Cache %q после очистки, ожидался MISS", status)
+	}
+}
+
+func TestResponseCacheVariesByAcceptLanguage(t *testing.T) {
+	policies := []RoutePolicy{{Prefix: "/v1/orders", TTL: time.Minute}}
+	c := NewResponseCache(policies, 10, nil, zap.NewNop())
+	handler := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+
+	serve := func(lang string) (string, string) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/orders/a", nil)
+		req.Header.Set("Accept-Language", lang)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Header().Get("X-Cache"), rec.Body.String()
+	}
+
+	serve("ru")
+	if status, body := serve("en"); status != "MISS" || body != "en" {
+		t.Fatalf("ответ для en: X-Cache %q, тело %q; ожидался отдельный ответ", status, body)
+	}
+	if status, body := serve("ru"); status != "HIT" || body != "ru" {
+		t.Fatalf("ответ для ru: X-Cache %q, тело %q; ожидался закешированный ответ ru", status, body)
+	}
+}
+
+func TestResponseCacheRevalidationBoundedByTimeout(t *testing.T) {
+	policies := []RoutePolicy{{Prefix: "/v1/orders", TTL: time.Nanosecond, StaleWhileRevalidate: time.Minute}}
+	timeout := func(*http.Request) time.Duration { return 20 * time.Millisecond }
+	revalidated := make(chan error, 1)
+	calls := 0
+	c := NewResponseCache(policies, 10, timeout, zap.NewNop())
+	handler := c.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Write([]byte("a"))
+			return
+		}
+		// Фоновое обновление зависает до отмены контекста
+		<-r.Context().Done()
+		revalidated <- r.Context().Err()
+	}))
+
+	serveCached(handler, "/v1/orders/a")
+	time.Sleep(time.Millisecond)
+	if status := serveCached(handler, "/v1/orders/a"); status != "STALE" {
+		t.Fatalf("X-Cache %q, ожидался STALE", status)
+	}
+
+	select {
+	case err := <-revalidated:
+		if err != context.DeadlineExceeded {
+			t.Errorf("обновление прервано с %v, ожидался context.DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("фоновое обновление не прервано по таймауту маршрута")
+	}
+}
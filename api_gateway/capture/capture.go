@@ -0,0 +1,228 @@
+// Package capture записывает обезличенный профиль трафика API Gateway для нагрузочных
+// тестов: метод, шаблон пути, время, размеры и статус запроса. Тела, query строки,
+// заголовки, идентификаторы и адреса клиентов не сохраняются. Записанный файл
+// воспроизводит команда cmd/replay_traffic
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"api_gateway/accesslog"
+)
+
+// SchemaVersion версия схемы записи; увеличивается только при несовместимых изменениях
+const SchemaVersion = 1
+
+// Record обезличенная запись о запросе
+type Record struct {
+	SchemaVersion int `json:"schema_version"`
+	// Timestamp время начала запроса с точностью до миллисекунды
+	Timestamp time.Time `json:"timestamp"`
+	Method    string    `json:"method"`
+	// Path шаблон пути: переменные маршрута и сегменты, похожие на идентификаторы,
+	// заменены на {name}, {uuid}, {n} или {param}
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	BytesIn   int64   `json:"bytes_in"`
+	BytesOut  int64   `json:"bytes_out"`
+	// Authenticated запрос содержал действительный access токен
+	Authenticated bool `json:"authenticated"`
+}
+
+// NewRecord формирует запись по записи журнала доступа. Возвращает false для запросов,
+// не подошедших ни под один маршрут: их путь произволен и может содержать данные клиента
+func NewRecord(entry *accesslog.Entry) (Record, bool) {
+	if entry.Route == "" {
+		return Record{}, false
+	}
+
+	path := entry.Route
+	if strings.Contains(path, "{") {
+		path = normalizeTemplate(path)
+	} else {
+		// Маршруты-префиксы (/v1/users) не содержат переменных, поэтому шаблон строится по пути
+		path = anonymizePath(entry.Path)
+	}
+
+	return Record{
+		SchemaVersion: SchemaVersion,
+		Timestamp:     entry.Timestamp.UTC().Truncate(time.Millisecond),
+		Method:        entry.Method,
+		Path:          path,
+		Status:        entry.Status,
+		LatencyMS:     entry.LatencyMS,
+		BytesIn:       entry.BytesIn,
+		BytesOut:      entry.BytesOut,
+		Authenticated: entry.UserID != "",
+	}, true
+}
+
+var (
+	uuidSegment    = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	numericSegment = regexp.MustCompile(`^[0-9]+$`)
+	// literalSegment сегменты, которые сохраняются как есть: слова в нижнем регистре
+	// и версии API (v1). Все остальное может быть токеном или данными клиента
+	literalSegment = regexp.MustCompile(`^[a-z][a-z_-]{0,31}$|^v[0-9]{1,2}$`)
+)
+
+// anonymizePath заменяет сегменты пути, похожие на идентификаторы, на плейсхолдеры
+func anonymizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == "" || literalSegment.MatchString(segment):
+		case uuidSegment.MatchString(segment):
+			segments[i] = "{uuid}"
+		case numericSegment.MatchString(segment):
+			segments[i] = "{n}"
+		default:
+			segments[i] = "{param}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// normalizeTemplate убирает регулярные выражения из переменных шаблона маршрута:
+// /v1/users/{id:[0-9a-f-]{36}}/overview -> /v1/users/{id}/overview
+func normalizeTemplate(template string) string {
+	var builder strings.Builder
+	depth := 0
+	skipping := false
+	for _, char := range template {
+		switch {
+		case char == '{':
+			depth++
+			if depth == 1 {
+				builder.WriteRune(char)
+			}
+		case char == '}':
+			depth--
+			if depth == 0 {
+				skipping = false
+				builder.WriteRune(char)
+			}
+		case depth == 1 && char == ':':
+			skipping = true
+		case depth == 0 || !skipping:
+			builder.WriteRune(char)
+		}
+	}
+	return builder.String()
+}
+
+// Recorder асинхронно записывает профиль трафика в файл. Запись не блокирует
+// обработку запросов: при переполнении буфера записи отбрасываются и учитываются
+// в Dropped. После MaxRecords записей запись прекращается, чтобы забытый включенным
+// режим не заполнил диск
+type Recorder struct {
+	file       *os.File
+	records    chan Record
+	maxRecords uint64
+	accepted   atomic.Uint64
+	dropped    atomic.Uint64
+	done       chan struct{}
+	once       sync.Once
+}
+
+// New открывает файл path на дозапись и создает Recorder; maxRecords 0 — без ограничения
+func New(path string, bufferSize int, maxRecords int) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла записи трафика: %v", err)
+	}
+
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+
+	r := &Recorder{
+		file:       file,
+		records:    make(chan Record, bufferSize),
+		maxRecords: uint64(maxRecords),
+		done:       make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+// Record ставит запрос в очередь на запись
+func (r *Recorder) Record(entry *accesslog.Entry) {
+	record, ok := NewRecord(entry)
+	if !ok {
+		return
+	}
+
+	if r.maxRecords > 0 && r.accepted.Add(1) > r.maxRecords {
+		return
+	}
+
+	select {
+	case r.records <- record:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// Dropped возвращает число отброшенных записей
+func (r *Recorder) Dropped() uint64 {
+	return r.dropped.Load()
+}
+
+// Close дописывает буфер и закрывает файл
+func (r *Recorder) Close() error {
+	r.once.Do(func() {
+		close(r.records)
+	})
+	<-r.done
+	return r.file.Close()
+}
+
+func (r *Recorder) run() {
+	defer close(r.done)
+
+	writer := bufio.NewWriter(r.file)
+	encoder := json.NewEncoder(writer)
+	for record := range r.records {
+		if err := encoder.Encode(record); err != nil {
+			r.dropped.Add(1)
+		}
+		// Сбрасываем буфер, когда очередь пуста: файл остается пригодным
+		// для чтения во время записи, а при нагрузке записи группируются
+		if len(r.records) == 0 {
+			if err := writer.Flush(); err != nil {
+				r.dropped.Add(1)
+			}
+		}
+	}
+	writer.Flush()
+}
+
+// Read читает записи профиля трафика из reader
+func Read(reader io.Reader) ([]Record, error) {
+	var records []Record
+	decoder := json.NewDecoder(reader)
+	for {
+		var record Record
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения записи %d: %v", len(records)+1, err)
+		}
+		if record.SchemaVersion != SchemaVersion {
+			return nil, fmt.Errorf("запись %d: неподдерживаемая версия схемы %d", len(records)+1, record.SchemaVersion)
+		}
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,282 @@
+// Команда replay_traffic воспроизводит профиль трафика, записанный шлюзом
+// (TRAFFIC_CAPTURE_ENABLED), против тестового окружения: запросы отправляются
+// с исходными методами, путями, интервалами и размерами тел.
+//
+// Профиль не содержит идентификаторов, поэтому переменные пути заполняются значениями
+// -param (например, -param uuid=<id заказа>), а без них — случайными UUID. Тела
+// запросов заменяются пустым JSON объектом исходного размера: запросы на запись
+// доходят до сервисов, но отклоняются валидацией. Запросы к /v1/admin по умолчанию
+// не воспроизводятся.
+//
+//	go run ./cmd/replay_traffic -file traffic_capture.jsonl -target https://staging.example.com -token $TOKEN -speed 2
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"api_gateway/capture"
+
+	"github.com/google/uuid"
+)
+
+func main() {
+	file := flag.String("file", "traffic_capture.jsonl", "файл профиля трафика")
+	target := flag.String("target", "", "базовый URL тестового окружения (обязательно)")
+	speed := flag.Float64("speed", 1, "множитель скорости: 2 — вдвое быстрее исходного трафика")
+	token := flag.String("token", "", "access токен для запросов, которые в профиле были аутентифицированы")
+	concurrency := flag.Int("concurrency", 64, "максимальное число одновременных запросов")
+	timeout := flag.Duration("timeout", 30*time.Second, "таймаут одного запроса")
+	includeAdmin := flag.Bool("include-admin", false, "воспроизводить запросы к /v1/admin")
+	params := make(map[string]string)
+	flag.Func("param", "значение переменной пути name=value (можно указать несколько раз)", func(value string) error {
+		name, paramValue, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("ожидается name=value")
+		}
+		params[name] = paramValue
+		return nil
+	})
+	flag.Parse()
+
+	if *target == "" {
+		log.Fatalf("Не указан -target")
+	}
+	if *speed <= 0 || *concurrency <= 0 {
+		log.Fatalf("-speed и -concurrency должны быть больше 0")
+	}
+
+	records, err := readRecords(*file, *includeAdmin)
+	if err != nil {
+		log.Fatalf("Ошибка чтения профиля: %v", err)
+	}
+	if len(records) == 0 {
+		log.Fatalf("Профиль %s не содержит запросов для воспроизведения", *file)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	replayer := &replayer{
+		target:  strings.TrimRight(*target, "/"),
+		token:   *token,
+		params:  params,
+		client:  &http.Client{Timeout: *timeout},
+		slots:   make(chan struct{}, *concurrency),
+		results: make([]result, len(records)),
+	}
+
+	first := records[0].Timestamp
+	log.Printf("Воспроизведение %d запросов (исходная длительность %s, скорость x%g) против %s",
+		len(records), records[len(records)-1].Timestamp.Sub(first), *speed, replayer.target)
+
+	started := time.Now()
+	replayer.run(ctx, records, first, *speed)
+	replayer.report(records, time.Since(started))
+}
+
+// readRecords читает профиль и упорядочивает записи по времени начала запроса:
+// шлюз пишет запись по завершении запроса, поэтому порядок в файле может отличаться
+func readRecords(path string, includeAdmin bool) ([]capture.Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	all, err := capture.Read(file)
+	if err != nil {
+		return nil, err
+	}
+
+	records := all[:0]
+	for _, record := range all {
+		if !includeAdmin && strings.HasPrefix(record.Path, "/v1/admin") {
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+	return records, nil
+}
+
+// result итог воспроизведения одного запроса; Status 0 — ошибка транспорта
+type result struct {
+	Status  int
+	Latency time.Duration
+	// Lag задержка отправки относительно расписания из-за ограничения -concurrency
+	Lag time.Duration
+}
+
+type replayer struct {
+	target  string
+	token   string
+	params  map[string]string
+	client  *http.Client
+	slots   chan struct{}
+	results []result
+}
+
+// run отправляет запросы по расписанию профиля и ждет завершения всех запросов
+func (rp *replayer) run(ctx context.Context, records []capture.Record, first time.Time, speed float64) {
+	var wg sync.WaitGroup
+	start := time.Now()
+
+	for i, record := range records {
+		due := start.Add(time.Duration(float64(record.Timestamp.Sub(first)) / speed))
+		select {
+		case <-ctx.Done():
+			log.Printf("Прервано: отправлено %d из %d запросов", i, len(records))
+			wg.Wait()
+			return
+		case <-time.After(time.Until(due)):
+		}
+
+		select {
+		case <-ctx.Done():
+			continue
+		case rp.slots <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int, record capture.Record) {
+			defer wg.Done()
+			defer func() { <-rp.slots }()
+
+			lag := time.Since(due)
+			status, latency := rp.send(ctx, record)
+			rp.results[i] = result{Status: status, Latency: latency, Lag: lag}
+		}(i, record)
+	}
+	wg.Wait()
+}
+
+// send отправляет один запрос и возвращает статус и время ответа
+func (rp *replayer) send(ctx context.Context, record capture.Record) (int, time.Duration) {
+	var body io.Reader
+	if record.BytesIn > 0 {
+		body = bytes.NewReader(paddedBody(record.BytesIn))
+	}
+
+	request, err := http.NewRequestWithContext(ctx, record.Method, rp.target+rp.fillPath(record.Path), body)
+	if err != nil {
+		return 0, 0
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if record.Authenticated && rp.token != "" {
+		request.Header.Set("Authorization", "Bearer "+rp.token)
+	}
+	request.Header.Set("User-Agent", "replay_traffic")
+
+	started := time.Now()
+	response, err := rp.client.Do(request)
+	if err != nil {
+		return 0, time.Since(started)
+	}
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+	return response.StatusCode, time.Since(started)
+}
+
+// fillPath подставляет значения переменных в шаблон пути
+func (rp *replayer) fillPath(template string) string {
+	segments := strings.Split(template, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		name := strings.Trim(segment, "{}")
+		if value, ok := rp.params[name]; ok {
+			segments[i] = value
+		} else if name == "n" {
+			segments[i] = "1"
+		} else {
+			segments[i] = uuid.NewString()
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// paddedBody возвращает пустой JSON объект размером size байт
+func paddedBody(size int64) []byte {
+	if size < 2 {
+		size = 2
+	}
+	body := bytes.Repeat([]byte(" "), int(size))
+	body[0] = '{'
+	body[size-1] = '}'
+	return body
+}
+
+// report выводит сравнение воспроизведенного трафика с исходным
+func (rp *replayer) report(records []capture.Record, elapsed time.Duration) {
+	var captured, replayed, lags []time.Duration
+	capturedStatuses := make(map[string]int)
+	replayedStatuses := make(map[string]int)
+
+	for i, record := range records {
+		result := rp.results[i]
+		if result.Status == 0 && result.Latency == 0 {
+			// Запрос не был отправлен (прерывание)
+			continue
+		}
+		captured = append(captured, time.Duration(record.LatencyMS*float64(time.Millisecond)))
+		replayed = append(replayed, result.Latency)
+		lags = append(lags, result.Lag)
+		capturedStatuses[statusClass(record.Status)]++
+		replayedStatuses[statusClass(result.Status)]++
+	}
+
+	fmt.Printf("Отправлено запросов: %d за %s\n", len(replayed), elapsed.Round(time.Millisecond))
+	fmt.Printf("%-12s %12s %12s\n", "", "профиль", "тест")
+	for _, class := range []string{"2xx", "3xx", "4xx", "5xx", "error"} {
+		if capturedStatuses[class] == 0 && replayedStatuses[class] == 0 {
+			continue
+		}
+		fmt.Printf("%-12s %12d %12d\n", class, capturedStatuses[class], replayedStatuses[class])
+	}
+	for _, q := range []float64{0.5, 0.95, 0.99} {
+		label := fmt.Sprintf("p%g", q*100)
+		fmt.Printf("%-12s %12s %12s\n", label, percentile(captured, q), percentile(replayed, q))
+	}
+	fmt.Printf("Максимальная задержка отправки: %s\n", percentile(lags, 1))
+}
+
+func statusClass(status int) string {
+	if status == 0 {
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// percentile возвращает квантиль q выборки, округленный до десятых долей миллисекунды
+func percentile(values []time.Duration, q float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	index := int(q*float64(len(sorted))+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index].Round(100 * time.Microsecond)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Типы событий оповещения; как и события alert.* детектора аномалий service_orders,
+// событие содержит id, type, timestamp и data
+const (
+	alertProbeFailed    = "alert.synthetic_probe"
+	alertProbeRecovered = "alert.synthetic_probe_recovered"
+)
+
+// alertEvent событие оповещения о результате синтетических проверок
+type alertEvent struct {
+	ID        uuid.UUID `json:"id"`
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+	Data      alertData `json:"data"`
+}
+
+// alertData данные оповещения
+type alertData struct {
+	Target string `json:"target"`
+	// Step и Error шаг и ошибка последнего неудачного прогона
+	Step  string `json:"step,omitempty"`
+	Error string `json:"error,omitempty"`
+	// ConsecutiveFailures неудачных прогонов подряд; для восстановления — до успешного прогона
+	ConsecutiveFailures int `json:"consecutive_failures"`
+}
+
+// alerter отправляет оповещение после after неудачных прогонов подряд и одно
+// событие восстановления после первого успешного прогона
+type alerter struct {
+	webhook string
+	after   int
+	client  *http.Client
+
+	failures int
+	alerted  bool
+}
+
+// newAlerter создает alerter; пустой webhook — события только записываются в журнал
+func newAlerter(webhook string, after int, client *http.Client) *alerter {
+	return &alerter{webhook: webhook, after: after, client: client}
+}
+
+// failure учитывает неудачный прогон
+func (a *alerter) failure(ctx context.Context, target, step string, err error) {
+	a.failures++
+	if a.alerted || a.failures < a.after {
+		return
+	}
+	a.alerted = true
+	a.send(ctx, alertProbeFailed, alertData{
+		Target:              target,
+		Step:                step,
+		Error:               err.Error(),
+		ConsecutiveFailures: a.failures,
+	})
+}
+
+// success учитывает успешный прогон
+func (a *alerter) success(ctx context.Context, target string) {
+	failures := a.failures
+	a.failures = 0
+	if !a.alerted {
+		return
+	}
+	a.alerted = false
+	a.send(ctx, alertProbeRecovered, alertData{Target: target, ConsecutiveFailures: failures})
+}
+
+// send записывает событие в журнал и отправляет его на webhook
+func (a *alerter) send(ctx context.Context, eventType string, data alertData) {
+	event := alertEvent{
+		ID:        uuid.New(),
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		Source:    "synthetic_probe",
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Ошибка сериализации оповещения: %v", err)
+		return
+	}
+	log.Printf("Оповещение: %s", payload)
+
+	if a.webhook == "" {
+		return
+	}
+	if err := a.post(ctx, payload); err != nil {
+		log.Printf("Ошибка отправки оповещения на webhook: %v", err)
+	}
+}
+
+// post отправляет событие на webhook
+func (a *alerter) post(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.webhook, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("статус %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Шаги сценария в порядке выполнения
+const (
+	stepRegister    = "register"
+	stepLogin       = "login"
+	stepCreateOrder = "create_order"
+	stepCancelOrder = "cancel_order"
+)
+
+// maxResponseSize размер тела ответа, читаемого для разбора
+const maxResponseSize = 1 << 20
+
+// stepResult итог шага сценария
+type stepResult struct {
+	Step     string
+	Duration time.Duration
+	Err      error
+}
+
+// journey один прогон сценария от имени новой одноразовой учетной записи
+type journey struct {
+	target string
+	client *http.Client
+	email  string
+	// product и price позиции пробного заказа
+	product string
+	price   float64
+
+	password string
+	token    string
+	orderID  string
+}
+
+// newJourney создает прогон с одноразовой учетной записью probe+<uuid>@emailDomain
+func newJourney(target string, client *http.Client, emailDomain, product string, price float64) (*journey, error) {
+	buf := make([]byte, 18)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("ошибка генерации пароля: %v", err)
+	}
+	return &journey{
+		target:   target,
+		client:   client,
+		email:    fmt.Sprintf("probe+%s@%s", uuid.New(), emailDomain),
+		product:  product,
+		price:    price,
+		password: base64.RawURLEncoding.EncodeToString(buf),
+	}, nil
+}
+
+// run выполняет шаги по порядку до первой ошибки и возвращает итоги выполненных шагов
+func (j *journey) run(ctx context.Context) []stepResult {
+	steps := []struct {
+		name string
+		fn   func(context.Context) error
+	}{
+		{stepRegister, j.register},
+		{stepLogin, j.login},
+		{stepCreateOrder, j.createOrder},
+		{stepCancelOrder, j.cancelOrder},
+	}
+
+	results := make([]stepResult, 0, len(steps))
+	for _, step := range steps {
+		started := time.Now()
+		err := step.fn(ctx)
+		results = append(results, stepResult{Step: step.name, Duration: time.Since(started), Err: err})
+		if err != nil {
+			break
+		}
+	}
+	return results
+}
+
+func (j *journey) register(ctx context.Context) error {
+	return j.call(ctx, http.MethodPost, "/v1/users/register", map[string]string{
+		"email":    j.email,
+		"password": j.password,
+		"name":     "Synthetic Probe",
+	}, http.StatusCreated, nil)
+}
+
+func (j *journey) login(ctx context.Context) error {
+	var data struct {
+		Token string `json:"token"`
+	}
+	err := j.call(ctx, http.MethodPost, "/v1/users/login", map[string]string{
+		"email":    j.email,
+		"password": j.password,
+	}, http.StatusOK, &data)
+	if err != nil {
+		return err
+	}
+	if data.Token == "" {
+		return fmt.Errorf("ответ входа не содержит токен")
+	}
+	j.token = data.Token
+	return nil
+}
+
+func (j *journey) createOrder(ctx context.Context) error {
+	var data struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	err := j.call(ctx, http.MethodPost, "/v1/orders", map[string]interface{}{
+		"items": []map[string]interface{}{
+			{"product": j.product, "quantity": 1, "price": j.price},
+		},
+	}, http.StatusCreated, &data)
+	if err != nil {
+		return err
+	}
+	if data.ID == "" {
+		return fmt.Errorf("ответ создания заказа не содержит id")
+	}
+	j.orderID = data.ID
+	return nil
+}
+
+func (j *journey) cancelOrder(ctx context.Context) error {
+	var data struct {
+		Status string `json:"status"`
+	}
+	if err := j.call(ctx, http.MethodPut, "/v1/orders/"+j.orderID+"/cancel", nil, http.StatusOK, &data); err != nil {
+		return err
+	}
+	if data.Status != "cancelled" {
+		return fmt.Errorf("статус заказа после отмены %q, ожидался cancelled", data.Status)
+	}
+	return nil
+}
+
+// call отправляет JSON запрос и разбирает поле data успешного ответа в out
+func (j *journey) call(ctx context.Context, method, path string, body interface{}, wantStatus int, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, j.target+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Request-ID", "probe-"+uuid.NewString())
+	if j.token != "" {
+		req.Header.Set("Authorization", "Bearer "+j.token)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("%s %s: ошибка чтения ответа: %v", method, path, err)
+	}
+	if resp.StatusCode != wantStatus {
+		return fmt.Errorf("%s %s: статус %d, ожидался %d (X-Request-ID %s): %s",
+			method, path, resp.StatusCode, wantStatus, req.Header.Get("X-Request-ID"), truncate(respBody, 200))
+	}
+	if out == nil {
+		return nil
+	}
+
+	envelope := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(respBody, &envelope); err != nil {
+		return fmt.Errorf("%s %s: некорректный JSON ответа: %v", method, path, err)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("%s %s: некорректное поле data: %v", method, path, err)
+	}
+	return nil
+}
+
+// truncate обрезает тело ответа для сообщения об ошибке
+func truncate(body []byte, limit int) string {
+	if len(body) <= limit {
+		return string(body)
+	}
+	return string(body[:limit]) + "..."
+}
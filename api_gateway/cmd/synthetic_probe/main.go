@@ -0,0 +1,159 @@
+// Команда synthetic_probe периодически проходит пользовательский сценарий против
+// развернутого окружения через API Gateway: регистрация → вход → создание заказа → отмена.
+// Каждый прогон использует новую одноразовую учетную запись probe+<uuid>@-email-domain
+// (учетные записи не удаляются: API удаления пользователей нет, поэтому для проб
+// используйте отдельный домен). Заказ создается на товар -product и сразу отменяется.
+//
+// Результаты публикуются метриками synthetic_probe_* на -listen (/metrics), а после
+// -alert-after неудачных прогонов подряд отправляется событие alert.synthetic_probe
+// (в журнал и на -alert-webhook); после восстановления — alert.synthetic_probe_recovered.
+// Команда запускается отдельным процессом или sidecar контейнером; с -once выполняет
+// один прогон и завершается с кодом 1 при ошибке (проверка после развертывания).
+//
+//	go run ./cmd/synthetic_probe -target https://staging.example.com -interval 1m -alert-webhook https://alerts.example.com/hook
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"api_gateway/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// userAgent отличает запросы проб в журналах сервисов
+const userAgent = "synthetic-probe/1"
+
+func main() {
+	target := flag.String("target", "", "базовый URL API Gateway окружения (обязательно)")
+	interval := flag.Duration("interval", time.Minute, "период прогона сценария")
+	timeout := flag.Duration("timeout", 10*time.Second, "таймаут одного запроса")
+	once := flag.Bool("once", false, "выполнить один прогон и завершиться (код 1 при ошибке)")
+	listen := flag.String("listen", ":9102", "адрес /metrics и /healthz (пусто — не публиковать)")
+	emailDomain := flag.String("email-domain", "synthetic-probe.example.com", "домен email одноразовых учетных записей")
+	product := flag.String("product", "synthetic-probe", "товар пробного заказа")
+	price := flag.Float64("price", 1, "цена товара пробного заказа")
+	alertAfter := flag.Int("alert-after", 2, "число неудачных прогонов подряд до оповещения")
+	alertWebhook := flag.String("alert-webhook", "", "URL для POST событий оповещения (пусто — только журнал)")
+	flag.Parse()
+
+	if *target == "" {
+		log.Fatalf("Не указан -target")
+	}
+	if *interval <= 0 || *timeout <= 0 || *alertAfter <= 0 {
+		log.Fatalf("-interval, -timeout и -alert-after должны быть больше 0")
+	}
+
+	registry := prometheus.NewRegistry()
+	probeMetrics, err := metrics.NewProbeMetrics(registry)
+	if err != nil {
+		log.Fatalf("Ошибка регистрации метрик: %v", err)
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	p := &prober{
+		target:      strings.TrimRight(*target, "/"),
+		client:      client,
+		emailDomain: *emailDomain,
+		product:     *product,
+		price:       *price,
+		metrics:     probeMetrics,
+		alerts:      newAlerter(*alertWebhook, *alertAfter, client),
+	}
+
+	if *once {
+		if !p.probe(context.Background()) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *listen != "" {
+		go serveMetrics(*listen, registry)
+	}
+
+	log.Printf("Синтетические проверки %s каждые %s", p.target, *interval)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		p.probe(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// serveMetrics публикует метрики и проверку живости пробы
+func serveMetrics(addr string, registry *prometheus.Registry) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	if err := http.ListenAndServe(addr, mux); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Ошибка сервера метрик: %v", err)
+	}
+}
+
+// prober выполняет прогоны сценария и учитывает их результаты
+type prober struct {
+	target      string
+	client      *http.Client
+	emailDomain string
+	product     string
+	price       float64
+	metrics     *metrics.ProbeMetrics
+	alerts      *alerter
+}
+
+// probe выполняет один прогон сценария и возвращает true при успехе
+func (p *prober) probe(ctx context.Context) bool {
+	j, err := newJourney(p.target, p.client, p.emailDomain, p.product, p.price)
+	if err != nil {
+		log.Printf("Ошибка подготовки прогона: %v", err)
+		return false
+	}
+
+	started := time.Now()
+	results := j.run(ctx)
+	if ctx.Err() != nil {
+		// Прогон прерван остановкой пробы и не учитывается
+		return false
+	}
+
+	var failed *stepResult
+	for i := range results {
+		result := &results[i]
+		p.metrics.ObserveStep(result.Step, result.Duration, result.Err != nil)
+		if result.Err != nil {
+			failed = result
+		}
+	}
+
+	finishedAt := time.Now()
+	p.metrics.ObserveRun(failed == nil, finishedAt)
+	if failed != nil {
+		log.Printf("Прогон не пройден на шаге %s за %s: %v", failed.Step, finishedAt.Sub(started), failed.Err)
+		p.alerts.failure(ctx, p.target, failed.Step, failed.Err)
+		return false
+	}
+
+	log.Printf("Прогон пройден за %s (%s, заказ %s)", finishedAt.Sub(started), j.email, j.orderID)
+	p.alerts.success(ctx, p.target)
+	return true
+}
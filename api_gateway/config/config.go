@@ -0,0 +1,1051 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"api_gateway/cache"
+	"api_gateway/costcenter"
+	"api_gateway/grpcproxy"
+	"api_gateway/ratelimit"
+	"api_gateway/timeout"
+	"api_gateway/waf"
+
+	"pkg/jwtkeys"
+	"pkg/profile"
+)
+
+// Config содержит конфигурацию API Gateway
+type Config struct {
+	Environment string
+	// Profile профиль окружения со значениями по умолчанию для настроек безопасности
+	Profile     profile.Profile
+	Server      ServerConfig
+	Services    ServicesConfig
+	JWT         JWTConfig
+	RateLimit   RateLimitConfig
+	Timeout     TimeoutConfig
+	Concurrency ConcurrencyConfig
+	Cache       CacheConfig
+	CORS        CORSConfig
+	Security    SecurityHeadersConfig
+	Compression CompressionConfig
+	TLS         TLSConfig
+	Metrics     MetricsConfig
+	Auth        AuthConfig
+	AccessLog   AccessLogConfig
+	Capture     CaptureConfig
+	OpenAPI     OpenAPIConfig
+	RequestLog  RequestLogConfig
+	CostCenter  CostCenterConfig
+	GRPC        GRPCConfig
+	Redis       RedisConfig
+	Quota       QuotaConfig
+	SLA         SLAConfig
+	WAF         WAFConfig
+	// ConfigHistory снимки действующей конфигурации для административного API
+	ConfigHistory ConfigHistoryConfig
+	Debug         DebugConfig
+}
+
+// ServerConfig содержит конфигурацию HTTP сервера
+type ServerConfig struct {
+	Port string
+	// HTTP2 принимать HTTP/2 через TLS (ALPN h2)
+	HTTP2 bool
+	// H2C принимать HTTP/2 без TLS (h2c) — для балансировщика перед шлюзом во внутренней сети
+	H2C bool
+	// HTTP2MaxConcurrentStreams максимальное число одновременных потоков в соединении HTTP/2
+	HTTP2MaxConcurrentStreams int
+	// IdleTimeout время ожидания следующего запроса в keep-alive соединении клиента
+	IdleTimeout time.Duration
+}
+
+// Режимы HTTP/2 транспорта reverse proxy
+const (
+	// ProxyHTTP2Auto HTTP/2 для https сервисов по ALPN, HTTP/1.1 для http
+	ProxyHTTP2Auto = "auto"
+	// ProxyHTTP2H2C HTTP/2 без TLS (h2c) для http сервисов во внутренней сети
+	ProxyHTTP2H2C = "h2c"
+	// ProxyHTTP2Off только HTTP/1.1
+	ProxyHTTP2Off = "off"
+)
+
+// ServicesConfig содержит адреса upstream сервисов
+type ServicesConfig struct {
+	UsersURL  string
+	OrdersURL string
+	// UsersReplicaURLs и OrdersReplicaURLs дополнительные реплики сервисов: запросы
+	// распределяются между UsersURL/OrdersURL и репликами по хешу идентификатора пользователя
+	UsersReplicaURLs  []string
+	OrdersReplicaURLs []string
+	// DNSRefreshInterval период переразрешения DNS имен сервисов (0 — отключено)
+	DNSRefreshInterval time.Duration
+	Transport          TransportConfig
+	// UsersCanary и OrdersCanary вторые версии сервисов для канареечных выкладок
+	UsersCanary  CanaryConfig
+	OrdersCanary CanaryConfig
+	// CanaryHeader заголовок, которым тестировщики явно выбирают версию (canary или stable)
+	CanaryHeader string
+	Breaker      BreakerConfig
+	// UsersFallbackURL и OrdersFallbackURL резервные экземпляры сервисов (пусто — без резервирования)
+	UsersFallbackURL  string
+	OrdersFallbackURL string
+	Failover          FailoverConfig
+}
+
+// FailoverConfig содержит условия переключения на резервный экземпляр сервиса
+type FailoverConfig struct {
+	// Failures число ответов 502/503/504 основного экземпляра подряд до переключения
+	// (0 — переключение только по проверке)
+	Failures int
+	// CheckInterval период проверки основного экземпляра (0 — проверка отключена)
+	CheckInterval time.Duration
+	CheckTimeout  time.Duration
+	// HealthPath путь HTTP проверки; пусто — проверяется TCP соединение
+	HealthPath string
+}
+
+// BreakerConfig содержит настройки circuit breaker перед каждым сервисом
+type BreakerConfig struct {
+	// Failures число ответов 502/503/504 подряд до размыкания (0 — только ручное размыкание)
+	Failures int
+	// OpenTimeout время, в течение которого запросы к сервису отклоняются
+	OpenTimeout time.Duration
+}
+
+// CanaryConfig содержит адрес канареечной версии сервиса и ее долю трафика.
+// Пустой URL отключает разделение; Weight 0 направляет на канарейку только запросы с CanaryHeader
+type CanaryConfig struct {
+	URL string
+	// Weight процент запросов (0-100), направляемых на канареечную версию
+	Weight int
+}
+
+// TransportConfig содержит настройки HTTP транспорта reverse proxy.
+// Значения по умолчанию Go (2 idle соединения на хост) приводят к постоянному
+// пересозданию соединений под нагрузкой
+type TransportConfig struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost ограничение числа соединений к хосту (0 — без ограничения)
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout время ожидания заголовков ответа (0 — без ограничения)
+	ResponseHeaderTimeout time.Duration
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	DisableKeepAlives     bool
+	// HTTP2 режим HTTP/2 к сервисам: ProxyHTTP2Auto, ProxyHTTP2H2C или ProxyHTTP2Off
+	HTTP2 string
+	// HTTP2PingTimeout период ping простаивающего соединения HTTP/2; соединение без ответа
+	// на ping закрывается (0 — без проверки)
+	HTTP2PingTimeout time.Duration
+}
+
+// JWTConfig содержит конфигурацию JWT
+type JWTConfig struct {
+	Secret string
+	// PreviousSecrets предыдущие секреты HMAC, токены которых еще принимаются
+	// на время ротации JWT_SECRET
+	PreviousSecrets []string
+	// Keys набор секретов HMAC для проверки токенов по kid; nil, если HMAC алгоритмы не разрешены
+	Keys *jwtkeys.Keyring
+	// Algorithms допустимые алгоритмы подписи (HS256 — общий секрет, RS256/ES256 — ключи из JWKS)
+	Algorithms []string
+	// JWKSURL адрес JWKS с публичными ключами для асимметричных алгоритмов
+	JWKSURL string
+	// JWKSRefreshInterval период обновления закешированных ключей JWKS
+	JWKSRefreshInterval time.Duration
+}
+
+// RateLimitConfig содержит конфигурацию ограничения частоты запросов.
+// RPS, Burst и Key применяются к запросам, не подходящим ни под одно правило Routes
+type RateLimitConfig struct {
+	RPS   float64
+	Burst int
+	// Key стратегия ключа лимита по умолчанию: global, ip или user
+	Key string
+	// Routes правила для отдельных маршрутов; применяется первое подходящее
+	Routes []ratelimit.Rule
+}
+
+// Default возвращает правило по умолчанию для всех запросов
+func (c RateLimitConfig) Default() ratelimit.Rule {
+	return ratelimit.Rule{Method: "*", Prefix: "/", RPS: c.RPS, Burst: c.Burst, Key: c.Key}
+}
+
+// TimeoutConfig содержит предельное время обработки запросов шлюзом.
+// Default применяется к запросам, не подходящим ни под одно правило Routes
+type TimeoutConfig struct {
+	// Default таймаут по умолчанию (0 — без ограничения)
+	Default time.Duration
+	// Routes таймауты отдельных маршрутов; применяется первое подходящее правило
+	Routes []timeout.Rule
+}
+
+// ConcurrencyConfig содержит ограничения числа одновременно выполняемых запросов к сервисам.
+// Запрос сверх предела ждет в очереди, а при ее заполнении или по истечении QueueTimeout
+// получает 503
+type ConcurrencyConfig struct {
+	// MaxInFlight предел для всех запросов к сервисам (0 — без ограничения)
+	MaxInFlight int
+	// UpstreamMaxInFlight предел для каждого сервиса (0 — без ограничения)
+	UpstreamMaxInFlight int
+	// QueueSize число ожидающих запросов для каждого ограничителя
+	QueueSize    int
+	QueueTimeout time.Duration
+}
+
+// CacheConfig содержит политики кеширования ответов
+type CacheConfig struct {
+	Routes []cache.RoutePolicy
+	// MaxEntries предельное число ответов в кеше; сверх него вытесняются давно не запрашивавшиеся
+	MaxEntries int
+}
+
+// CORSConfig содержит конфигурацию CORS
+type CORSConfig struct {
+	AllowedOrigins []string
+}
+
+// SecurityHeadersConfig содержит заголовки безопасности, добавляемые ко всем ответам шлюза
+type SecurityHeadersConfig struct {
+	Enabled bool
+	// HSTSMaxAge срок Strict-Transport-Security (0 — заголовок не отправляется)
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// FrameOptions значение X-Frame-Options: DENY или SAMEORIGIN
+	FrameOptions   string
+	ReferrerPolicy string
+	// ContentSecurityPolicy политика CSP (пусто — заголовок не отправляется)
+	ContentSecurityPolicy string
+}
+
+// Headers возвращает заголовки безопасности с учетом конфигурации
+func (c SecurityHeadersConfig) Headers() map[string]string {
+	if !c.Enabled {
+		return nil
+	}
+	headers := map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        c.FrameOptions,
+		"Referrer-Policy":        c.ReferrerPolicy,
+	}
+	if c.HSTSMaxAge > 0 {
+		hsts := fmt.Sprintf("max-age=%d", int64(c.HSTSMaxAge.Seconds()))
+		if c.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if c.HSTSPreload {
+			hsts += "; preload"
+		}
+		headers["Strict-Transport-Security"] = hsts
+	}
+	if c.ContentSecurityPolicy != "" {
+		headers["Content-Security-Policy"] = c.ContentSecurityPolicy
+	}
+	return headers
+}
+
+// referrerPolicies допустимые значения Referrer-Policy
+var referrerPolicies = map[string]bool{
+	"no-referrer": true, "no-referrer-when-downgrade": true, "origin": true,
+	"origin-when-cross-origin": true, "same-origin": true, "strict-origin": true,
+	"strict-origin-when-cross-origin": true, "unsafe-url": true,
+}
+
+// CompressionConfig содержит конфигурацию сжатия ответов
+type CompressionConfig struct {
+	Enabled bool
+	// MinSize минимальный размер ответа в байтах, начиная с которого он сжимается
+	MinSize int
+	// Level уровень сжатия (-1 — уровень по умолчанию)
+	Level int
+}
+
+// TLSConfig содержит конфигурацию HTTPS
+type TLSConfig struct {
+	Enabled  bool
+	CertFile string
+	KeyFile  string
+	// ReloadInterval период проверки файлов сертификата на изменение
+	ReloadInterval time.Duration
+	// HTTPPort порт HTTP для редиректа на HTTPS и ACME http-01 (пусто — не слушать)
+	HTTPPort string
+	ACME     ACMEConfig
+}
+
+// ACMEConfig содержит конфигурацию автоматического получения сертификатов (Let's Encrypt)
+type ACMEConfig struct {
+	Enabled  bool
+	Domains  []string
+	Email    string
+	CacheDir string
+	// DirectoryURL адрес ACME сервера (пусто — production Let's Encrypt)
+	DirectoryURL string
+}
+
+// MetricsConfig содержит конфигурацию Prometheus метрик
+type MetricsConfig struct {
+	Enabled bool
+	// Port отдельный порт для /metrics (пусто — метрики на основном порту)
+	Port string
+	Path string
+	// RateLimitTopK число самых активных ключей каждого класса с отдельной меткой
+	RateLimitTopK int
+	// RateLimitHashKeys хешировать ключи (IP, пользователь, API ключ) в метках
+	RateLimitHashKeys bool
+}
+
+// AuthConfig содержит конфигурацию выдачи токенов клиентам
+type AuthConfig struct {
+	// CookieMode передавать refresh токен в HTTP-only cookie вместо тела ответа
+	CookieMode        bool
+	RefreshCookieName string
+	CookieDomain      string
+	CookiePath        string
+	CookieSecure      bool
+	// RefreshCookieMaxAge срок жизни cookie; должен совпадать с JWT_REFRESH_TTL сервиса пользователей
+	RefreshCookieMaxAge time.Duration
+	// RolesEpochFailClosed отклонять запросы, если эпоху ролей не удалось проверить (Redis недоступен)
+	RolesEpochFailClosed bool
+	// SessionMode выдавать при входе cookie сессии шлюза вместо токенов: токены хранит шлюз
+	// (в Redis, если задан REDIS_HOST, иначе в памяти) и подставляет в запросы с cookie
+	SessionMode       bool
+	SessionCookieName string
+	// SessionTTL время жизни сессии и ее cookie от входа; не должно превышать JWT_REFRESH_TTL
+	SessionTTL time.Duration
+	// SessionRenewBefore за сколько до истечения access токена сессия продлевается
+	SessionRenewBefore time.Duration
+	// CSRFEnabled в режиме cookie или сессий требовать CSRF токен (double-submit) в изменяющих запросах
+	// с cookie refresh токена или сессии к маршрутам CSRFRoutes
+	CSRFEnabled    bool
+	CSRFCookieName string
+	CSRFHeader     string
+	// CSRFRoutes префиксы путей, защищенных CSRF токеном
+	CSRFRoutes []string
+	// APIKeysEnabled принимать персональные API ключи (Authorization: ApiKey <ключ>):
+	// шлюз обменивает ключ на access токен в сервисе пользователей и кеширует токен
+	APIKeysEnabled bool
+}
+
+// RedisConfig содержит подключение к Redis с эпохами ролей пользователей.
+// Пустой Host отключает проверку эпохи ролей в access токенах
+type RedisConfig struct {
+	Host     string
+	Port     string
+	Password string
+	DB       int
+	Timeout  time.Duration
+}
+
+// QuotaConfig содержит конфигурацию суточных квот запросов пользователей (хранятся в Redis)
+type QuotaConfig struct {
+	// DailyLimit запросов аутентифицированного пользователя в сутки (UTC); 0 — квоты отключены
+	DailyLimit int64
+	// FailClosed отклонять запросы, если квоту не удалось проверить (Redis недоступен)
+	FailClosed bool
+}
+
+// SLAConfig содержит конфигурацию данных для отчетов SLA (хранятся в Redis, см. пакет sla)
+type SLAConfig struct {
+	Enabled bool
+	// TargetPercent цель доступности, %, от которой считается бюджет ошибок
+	TargetPercent float64
+	// FlushInterval период записи накопленных агрегатов запросов в Redis
+	FlushInterval time.Duration
+	// Retention время хранения суточных агрегатов
+	Retention time.Duration
+	// CheckInterval период проверок доступности сервисов (0 — проверки отключены)
+	CheckInterval time.Duration
+	CheckTimeout  time.Duration
+	// CheckPath путь GET запроса проверки к каждому сервису
+	CheckPath string
+}
+
+// AccessLogConfig содержит конфигурацию отдельного JSON журнала доступа
+type AccessLogConfig struct {
+	Enabled bool
+	// Output поток журнала: stdout, stderr, путь к файлу, tcp://, udp:// или unix:// адрес
+	Output string
+	// BufferSize число записей в очереди; при переполнении записи отбрасываются
+	BufferSize int
+}
+
+// OpenAPIConfig содержит конфигурацию проверки запросов по спецификации OpenAPI
+type OpenAPIConfig struct {
+	// SpecFiles файлы спецификации: первый — спецификация шлюза, остальные —
+	// спецификации сервисов. Пустой список отключает проверку и /v1/openapi.json
+	SpecFiles []string
+	// Validate отклонять запросы, не соответствующие спецификации
+	Validate bool
+	// ScopeByRole отдавать спецификацию только с операциями, доступными по правам ролей
+	// пользователя: анонимным — публичные, с правом gateway.manage — все
+	ScopeByRole bool
+	// SwaggerUI открывать Swagger UI объединенной спецификации на /docs
+	SwaggerUI bool
+	// SwaggerUIAssetsURL адрес статики swagger-ui-dist (скрипты и стили страницы /docs)
+	SwaggerUIAssetsURL string
+}
+
+// CaptureConfig содержит конфигурацию записи обезличенного профиля трафика
+// для нагрузочных тестов (см. пакет capture)
+type CaptureConfig struct {
+	Enabled bool
+	// File файл профиля; записи дописываются в конец
+	File string
+	// BufferSize число записей в очереди; при переполнении записи отбрасываются
+	BufferSize int
+	// MaxRecords после указанного числа записей запись прекращается (0 — без ограничения)
+	MaxRecords int
+}
+
+// RequestLogConfig содержит конфигурацию записи о запросе в логе приложения
+type RequestLogConfig struct {
+	// SlowThreshold запросы не быстрее порога помечаются slow и не сэмплируются (0 — отключено)
+	SlowThreshold time.Duration
+	// SampleInitial число успешных (2xx) запросов в секунду, которые логируются все;
+	// из остальных логируется каждый SampleThereafter-й (0 — сэмплирование отключено)
+	SampleInitial    int
+	SampleThereafter int
+}
+
+// CostCenterConfig содержит правила отнесения запросов к центрам затрат.
+// Пустой список правил отключает тегирование
+type CostCenterConfig struct {
+	// Rules правила классификации; применяется первое подходящее
+	Rules []costcenter.Rule
+	// Default тег запросов, не подходящих ни под одно правило
+	Default string
+}
+
+// GRPCConfig содержит маршруты к gRPC сервисам с транскодированием JSON в protobuf
+type GRPCConfig struct {
+	Routes []grpcproxy.Route
+	// DescriptorSet путь к FileDescriptorSet с описаниями сервисов
+	DescriptorSet string
+	Timeout       time.Duration
+	// UpstreamTLS подключаться к gRPC сервисам по TLS
+	UpstreamTLS bool
+}
+
+// WAFConfig содержит конфигурацию фильтра вредоносных запросов (см. пакет waf)
+type WAFConfig struct {
+	Enabled bool
+	// Rules включенные правила (sqli, xss, path_traversal, header_size)
+	Rules []string
+	// DetectOnly только записывать срабатывания в лог и метрики, не блокируя запросы
+	DetectOnly bool
+	// MaxHeaderBytes предельный суммарный размер заголовков запроса для правила header_size
+	MaxHeaderBytes int
+	// BodyInspectBytes сколько байт тела запроса проверяется
+	BodyInspectBytes int
+	// SkipFields поля JSON и форм, которые не проверяются
+	SkipFields []string
+}
+
+// ConfigHistoryConfig содержит конфигурацию истории снимков действующей конфигурации
+type ConfigHistoryConfig struct {
+	// Size сколько последних снимков хранится
+	Size int
+}
+
+// DebugConfig содержит конфигурацию отладочных эндпоинтов
+type DebugConfig struct {
+	// Enabled открывать /debug/pprof без аутентификации (только для разработки)
+	Enabled bool
+}
+
+// Load загружает конфигурацию из переменных окружения
+func Load() (*Config, error) {
+	config := &Config{}
+
+	config.Environment = getEnv("ENVIRONMENT", "development")
+
+	env, err := profile.Get(config.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ENVIRONMENT: %v", err)
+	}
+	config.Profile = env
+
+	// Конфигурация сервера
+	config.Server.Port = getEnv("API_GATEWAY_PORT", "8080")
+	config.Server.HTTP2 = getBoolEnv("SERVER_HTTP2_ENABLED", true)
+	config.Server.H2C = getBoolEnv("SERVER_H2C_ENABLED", false)
+	if config.Server.HTTP2MaxConcurrentStreams, err = getIntEnv("SERVER_HTTP2_MAX_CONCURRENT_STREAMS", "250"); err != nil {
+		return nil, err
+	}
+	if config.Server.IdleTimeout, err = getDurationEnv("SERVER_IDLE_TIMEOUT", "120s"); err != nil {
+		return nil, err
+	}
+	if config.Server.HTTP2MaxConcurrentStreams < 0 || config.Server.IdleTimeout < 0 {
+		return nil, fmt.Errorf("invalid SERVER_HTTP2_MAX_CONCURRENT_STREAMS/SERVER_IDLE_TIMEOUT: must be >= 0")
+	}
+
+	// URL сервисов берём из переменных окружения, чтобы избежать ошибок проксирования
+	config.Services.UsersURL = getEnv("USERS_SERVICE_URL", "http://service_users:8081")
+	config.Services.OrdersURL = getEnv("ORDERS_SERVICE_URL", "http://service_orders:8082")
+	config.Services.UsersReplicaURLs = splitList(getEnv("USERS_REPLICA_URLS", ""))
+	config.Services.OrdersReplicaURLs = splitList(getEnv("ORDERS_REPLICA_URLS", ""))
+
+	dnsRefresh, err := time.ParseDuration(getEnv("UPSTREAM_DNS_REFRESH_INTERVAL", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid UPSTREAM_DNS_REFRESH_INTERVAL: %v", err)
+	}
+	config.Services.DNSRefreshInterval = dnsRefresh
+
+	// Настройки транспорта reverse proxy
+	transport := &config.Services.Transport
+	if transport.MaxIdleConns, err = getIntEnv("PROXY_MAX_IDLE_CONNS", "100"); err != nil {
+		return nil, err
+	}
+	if transport.MaxIdleConnsPerHost, err = getIntEnv("PROXY_MAX_IDLE_CONNS_PER_HOST", "32"); err != nil {
+		return nil, err
+	}
+	if transport.MaxConnsPerHost, err = getIntEnv("PROXY_MAX_CONNS_PER_HOST", "0"); err != nil {
+		return nil, err
+	}
+	if transport.IdleConnTimeout, err = getDurationEnv("PROXY_IDLE_CONN_TIMEOUT", "90s"); err != nil {
+		return nil, err
+	}
+	if transport.TLSHandshakeTimeout, err = getDurationEnv("PROXY_TLS_HANDSHAKE_TIMEOUT", "10s"); err != nil {
+		return nil, err
+	}
+	if transport.ResponseHeaderTimeout, err = getDurationEnv("PROXY_RESPONSE_HEADER_TIMEOUT", "30s"); err != nil {
+		return nil, err
+	}
+	if transport.DialTimeout, err = getDurationEnv("PROXY_DIAL_TIMEOUT", "5s"); err != nil {
+		return nil, err
+	}
+	if transport.KeepAlive, err = getDurationEnv("PROXY_KEEP_ALIVE", "30s"); err != nil {
+		return nil, err
+	}
+	transport.DisableKeepAlives = getBoolEnv("PROXY_DISABLE_KEEP_ALIVES", false)
+	transport.HTTP2 = strings.ToLower(getEnv("PROXY_HTTP2", ProxyHTTP2Auto))
+	switch transport.HTTP2 {
+	case ProxyHTTP2Auto, ProxyHTTP2H2C, ProxyHTTP2Off:
+	default:
+		return nil, fmt.Errorf("invalid PROXY_HTTP2: must be auto, h2c or off")
+	}
+	// Без keep-alive каждый запрос h2c открывал бы новое соединение HTTP/2
+	if transport.HTTP2 == ProxyHTTP2H2C && transport.DisableKeepAlives {
+		return nil, fmt.Errorf("invalid PROXY_HTTP2: h2c requires keep-alive connections (PROXY_DISABLE_KEEP_ALIVES=false)")
+	}
+	if transport.HTTP2PingTimeout, err = getDurationEnv("PROXY_HTTP2_PING_TIMEOUT", "30s"); err != nil {
+		return nil, err
+	}
+
+	// Канареечные версии сервисов
+	config.Services.CanaryHeader = getEnv("CANARY_HEADER", "X-Canary")
+	config.Services.UsersCanary.URL = getEnv("USERS_CANARY_URL", "")
+	if config.Services.UsersCanary.Weight, err = getIntEnv("USERS_CANARY_WEIGHT", "0"); err != nil {
+		return nil, err
+	}
+	config.Services.OrdersCanary.URL = getEnv("ORDERS_CANARY_URL", "")
+	if config.Services.OrdersCanary.Weight, err = getIntEnv("ORDERS_CANARY_WEIGHT", "0"); err != nil {
+		return nil, err
+	}
+	for name, canary := range map[string]CanaryConfig{"USERS_CANARY_WEIGHT": config.Services.UsersCanary, "ORDERS_CANARY_WEIGHT": config.Services.OrdersCanary} {
+		if canary.Weight < 0 || canary.Weight > 100 {
+			return nil, fmt.Errorf("invalid %s: must be between 0 and 100", name)
+		}
+	}
+
+	// Circuit breaker сервисов
+	if config.Services.Breaker.Failures, err = getIntEnv("CIRCUIT_BREAKER_FAILURES", "5"); err != nil {
+		return nil, err
+	}
+	if config.Services.Breaker.OpenTimeout, err = getDurationEnv("CIRCUIT_BREAKER_OPEN_TIMEOUT", "30s"); err != nil {
+		return nil, err
+	}
+
+	// Резервные экземпляры сервисов
+	config.Services.UsersFallbackURL = getEnv("USERS_FALLBACK_URL", "")
+	config.Services.OrdersFallbackURL = getEnv("ORDERS_FALLBACK_URL", "")
+	if config.Services.Failover.Failures, err = getIntEnv("FAILOVER_FAILURES", "3"); err != nil {
+		return nil, err
+	}
+	if config.Services.Failover.CheckInterval, err = getDurationEnv("FAILOVER_CHECK_INTERVAL", "5s"); err != nil {
+		return nil, err
+	}
+	if config.Services.Failover.CheckTimeout, err = getDurationEnv("FAILOVER_CHECK_TIMEOUT", "2s"); err != nil {
+		return nil, err
+	}
+	if config.Services.Failover.Failures < 0 || config.Services.Failover.CheckInterval < 0 || config.Services.Failover.CheckTimeout <= 0 {
+		return nil, fmt.Errorf("invalid FAILOVER_FAILURES/FAILOVER_CHECK_INTERVAL/FAILOVER_CHECK_TIMEOUT: must be >= 0, >= 0 and > 0")
+	}
+	config.Services.Failover.HealthPath = getEnv("FAILOVER_HEALTH_PATH", "")
+	if path := config.Services.Failover.HealthPath; path != "" && !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid FAILOVER_HEALTH_PATH: must start with /")
+	}
+
+	// Конфигурация JWT
+	config.JWT.Secret = getEnv("JWT_SECRET", "your_secret_key")
+	config.JWT.PreviousSecrets = splitList(getEnv("JWT_PREVIOUS_SECRETS", ""))
+	config.JWT.Algorithms = splitList(getEnv("JWT_ALGORITHMS", "HS256"))
+	config.JWT.JWKSURL = getEnv("JWT_JWKS_URL", "")
+
+	jwksRefresh, err := time.ParseDuration(getEnv("JWT_JWKS_REFRESH_INTERVAL", "10m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_JWKS_REFRESH_INTERVAL: %v", err)
+	}
+	config.JWT.JWKSRefreshInterval = jwksRefresh
+
+	for _, alg := range config.JWT.Algorithms {
+		switch {
+		case strings.HasPrefix(alg, "HS"):
+		case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "ES"):
+			if config.JWT.JWKSURL == "" {
+				return nil, fmt.Errorf("JWT_JWKS_URL is required for JWT algorithm %s", alg)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported JWT algorithm: %s", alg)
+		}
+	}
+
+	for _, alg := range config.JWT.Algorithms {
+		if strings.HasPrefix(alg, "HS") {
+			if err := env.CheckSecret(config.JWT.Secret, "your_secret_key"); err != nil {
+				return nil, err
+			}
+			for _, secret := range config.JWT.PreviousSecrets {
+				if err := env.CheckSecret(secret, "your_secret_key"); err != nil {
+					return nil, fmt.Errorf("invalid JWT_PREVIOUS_SECRETS: %v", err)
+				}
+			}
+			if config.JWT.Keys, err = jwtkeys.New(config.JWT.Secret, config.JWT.PreviousSecrets); err != nil {
+				return nil, fmt.Errorf("invalid JWT_SECRET: %v", err)
+			}
+			break
+		}
+	}
+
+	// Конфигурация ограничения частоты запросов: по умолчанию из профиля окружения
+	rps, err := strconv.ParseFloat(getEnv("RATE_LIMIT_RPS", strconv.FormatFloat(env.RateLimitRPS, 'f', -1, 64)), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_RPS: %v", err)
+	}
+	config.RateLimit.RPS = rps
+
+	burst, err := strconv.Atoi(getEnv("RATE_LIMIT_BURST", strconv.Itoa(env.RateLimitBurst)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_BURST: %v", err)
+	}
+	config.RateLimit.Burst = burst
+
+	config.RateLimit.Key = strings.ToLower(getEnv("RATE_LIMIT_KEY", ratelimit.KeyGlobal))
+	if err := config.RateLimit.Default().Validate(); err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_RPS, RATE_LIMIT_BURST or RATE_LIMIT_KEY: %v", err)
+	}
+
+	if config.RateLimit.Routes, err = ratelimit.ParseRules(getEnv("RATE_LIMIT_ROUTES", "GET /v1/track/=1,10,ip")); err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_ROUTES: %v", err)
+	}
+
+	// Таймауты запросов: синхронизация с каталогом обрабатывает тысячи записей и выполняется дольше
+	if config.Timeout.Default, err = getDurationEnv("REQUEST_TIMEOUT", "30s"); err != nil {
+		return nil, err
+	}
+	if config.Timeout.Default < 0 {
+		return nil, fmt.Errorf("invalid REQUEST_TIMEOUT: must be >= 0")
+	}
+	if config.Timeout.Routes, err = timeout.ParseRules(getEnv("ROUTE_TIMEOUTS", "POST /v1/admin/directory-sync=5m")); err != nil {
+		return nil, fmt.Errorf("invalid ROUTE_TIMEOUTS: %v", err)
+	}
+
+	// Ограничение одновременных запросов к сервисам
+	if config.Concurrency.MaxInFlight, err = getIntEnv("MAX_INFLIGHT_REQUESTS", "1000"); err != nil {
+		return nil, err
+	}
+	if config.Concurrency.UpstreamMaxInFlight, err = getIntEnv("UPSTREAM_MAX_INFLIGHT_REQUESTS", "200"); err != nil {
+		return nil, err
+	}
+	if config.Concurrency.QueueSize, err = getIntEnv("INFLIGHT_QUEUE_SIZE", "100"); err != nil {
+		return nil, err
+	}
+	if config.Concurrency.QueueTimeout, err = getDurationEnv("INFLIGHT_QUEUE_TIMEOUT", "100ms"); err != nil {
+		return nil, err
+	}
+	if config.Concurrency.MaxInFlight < 0 || config.Concurrency.UpstreamMaxInFlight < 0 || config.Concurrency.QueueSize < 0 || config.Concurrency.QueueTimeout < 0 {
+		return nil, fmt.Errorf("invalid MAX_INFLIGHT_REQUESTS/UPSTREAM_MAX_INFLIGHT_REQUESTS/INFLIGHT_QUEUE_SIZE/INFLIGHT_QUEUE_TIMEOUT: must be >= 0")
+	}
+
+	// Конфигурация кеша ответов: TTL, stale-while-revalidate и stale-if-error для каждого маршрута
+	policies, err := cache.ParsePolicies(getEnv("CACHE_ROUTES", "/v1/orders=5s,30s,5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CACHE_ROUTES: %v", err)
+	}
+	config.Cache.Routes = policies
+	if config.Cache.MaxEntries, err = getIntEnv("CACHE_MAX_ENTRIES", "10000"); err != nil {
+		return nil, err
+	}
+	if config.Cache.MaxEntries <= 0 {
+		return nil, fmt.Errorf("invalid CACHE_MAX_ENTRIES: must be > 0")
+	}
+
+	// Конфигурация CORS
+	config.CORS.AllowedOrigins = splitList(getEnv("CORS_ALLOWED_ORIGINS", strings.Join(env.CORSAllowedOrigins, ",")))
+	if err := env.CheckCORSOrigins(config.CORS.AllowedOrigins); err != nil {
+		return nil, fmt.Errorf("invalid CORS_ALLOWED_ORIGINS: %v", err)
+	}
+
+	// Конфигурация заголовков безопасности: срок HSTS по умолчанию из профиля окружения
+	security := &config.Security
+	security.Enabled = getBoolEnv("SECURITY_HEADERS_ENABLED", true)
+	if security.HSTSMaxAge, err = getDurationEnv("SECURITY_HSTS_MAX_AGE", env.HSTSMaxAge.String()); err != nil {
+		return nil, err
+	}
+	if security.HSTSMaxAge < 0 {
+		return nil, fmt.Errorf("invalid SECURITY_HSTS_MAX_AGE: must be >= 0")
+	}
+	security.HSTSIncludeSubdomains = getBoolEnv("SECURITY_HSTS_INCLUDE_SUBDOMAINS", false)
+	security.HSTSPreload = getBoolEnv("SECURITY_HSTS_PRELOAD", false)
+	security.FrameOptions = strings.ToUpper(getEnv("SECURITY_FRAME_OPTIONS", "DENY"))
+	if security.FrameOptions != "DENY" && security.FrameOptions != "SAMEORIGIN" {
+		return nil, fmt.Errorf("invalid SECURITY_FRAME_OPTIONS: must be DENY or SAMEORIGIN")
+	}
+	security.ReferrerPolicy = strings.ToLower(getEnv("SECURITY_REFERRER_POLICY", "no-referrer"))
+	if !referrerPolicies[security.ReferrerPolicy] {
+		return nil, fmt.Errorf("invalid SECURITY_REFERRER_POLICY: unknown policy %q", security.ReferrerPolicy)
+	}
+	// Ответы шлюза — JSON, поэтому по умолчанию запрещена загрузка любых ресурсов; off отключает CSP
+	security.ContentSecurityPolicy = getEnv("SECURITY_CSP", "default-src 'none'; frame-ancestors 'none'")
+	if strings.EqualFold(security.ContentSecurityPolicy, "off") {
+		security.ContentSecurityPolicy = ""
+	}
+	if security.HSTSPreload && (security.HSTSMaxAge < 8760*time.Hour || !security.HSTSIncludeSubdomains) {
+		return nil, fmt.Errorf("invalid SECURITY_HSTS_PRELOAD: preload requires SECURITY_HSTS_MAX_AGE >= 8760h and SECURITY_HSTS_INCLUDE_SUBDOMAINS=true")
+	}
+
+	// Конфигурация сжатия ответов
+	config.Compression.Enabled = getBoolEnv("COMPRESSION_ENABLED", true)
+
+	minSize, err := strconv.Atoi(getEnv("COMPRESSION_MIN_SIZE", "1024"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid COMPRESSION_MIN_SIZE: %v", err)
+	}
+	config.Compression.MinSize = minSize
+
+	level, err := strconv.Atoi(getEnv("COMPRESSION_LEVEL", "-1"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid COMPRESSION_LEVEL: %v", err)
+	}
+	config.Compression.Level = level
+
+	// Конфигурация TLS
+	config.TLS.Enabled = getBoolEnv("TLS_ENABLED", false)
+	config.TLS.CertFile = getEnv("TLS_CERT_FILE", "")
+	config.TLS.KeyFile = getEnv("TLS_KEY_FILE", "")
+	config.TLS.HTTPPort = getEnv("TLS_HTTP_PORT", "")
+
+	reloadInterval, err := time.ParseDuration(getEnv("TLS_RELOAD_INTERVAL", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS_RELOAD_INTERVAL: %v", err)
+	}
+	config.TLS.ReloadInterval = reloadInterval
+
+	config.TLS.ACME.Enabled = getBoolEnv("TLS_ACME_ENABLED", false)
+	config.TLS.ACME.Domains = splitList(getEnv("TLS_ACME_DOMAINS", ""))
+	config.TLS.ACME.Email = getEnv("TLS_ACME_EMAIL", "")
+	config.TLS.ACME.CacheDir = getEnv("TLS_ACME_CACHE_DIR", "/var/cache/api_gateway/acme")
+	config.TLS.ACME.DirectoryURL = getEnv("TLS_ACME_DIRECTORY_URL", "")
+
+	if config.TLS.Enabled {
+		if config.TLS.ACME.Enabled {
+			if len(config.TLS.ACME.Domains) == 0 {
+				return nil, fmt.Errorf("TLS_ACME_DOMAINS is required when TLS_ACME_ENABLED=true")
+			}
+		} else if config.TLS.CertFile == "" || config.TLS.KeyFile == "" {
+			return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE are required when TLS_ENABLED=true")
+		}
+	}
+
+	// Конфигурация метрик
+	config.Metrics.Enabled = getBoolEnv("ENABLE_METRICS", true)
+	config.Metrics.Port = getEnv("METRICS_PORT", "")
+	config.Metrics.Path = getEnv("METRICS_PATH", "/metrics")
+	config.Metrics.RateLimitHashKeys = getBoolEnv("RATE_LIMIT_METRICS_HASH_KEYS", true)
+
+	topK, err := strconv.Atoi(getEnv("RATE_LIMIT_METRICS_TOP_K", "20"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_METRICS_TOP_K: %v", err)
+	}
+	config.Metrics.RateLimitTopK = topK
+
+	// Конфигурация refresh токенов в cookie
+	config.Auth.CookieMode = getBoolEnv("AUTH_COOKIE_MODE", false)
+	config.Auth.RefreshCookieName = getEnv("AUTH_REFRESH_COOKIE_NAME", "refresh_token")
+	config.Auth.CookieDomain = getEnv("AUTH_COOKIE_DOMAIN", "")
+	config.Auth.CookiePath = getEnv("AUTH_COOKIE_PATH", "/v1/auth")
+	config.Auth.CookieSecure = getBoolEnv("AUTH_COOKIE_SECURE", true)
+	if config.Auth.RefreshCookieMaxAge, err = getDurationEnv("AUTH_REFRESH_COOKIE_MAX_AGE", env.RefreshTokenTTL.String()); err != nil {
+		return nil, err
+	}
+	config.Auth.RolesEpochFailClosed = getBoolEnv("AUTH_ROLES_EPOCH_FAIL_CLOSED", false)
+	config.Auth.APIKeysEnabled = getBoolEnv("AUTH_API_KEYS_ENABLED", true)
+	if config.Auth.CookieMode {
+		if err := env.CheckCookieSecure(config.Auth.CookieSecure); err != nil {
+			return nil, fmt.Errorf("invalid AUTH_COOKIE_SECURE: %v", err)
+		}
+	}
+
+	// Конфигурация сессий шлюза
+	config.Auth.SessionMode = getBoolEnv("AUTH_SESSION_MODE", false)
+	config.Auth.SessionCookieName = getEnv("AUTH_SESSION_COOKIE_NAME", "session")
+	if config.Auth.SessionTTL, err = getDurationEnv("AUTH_SESSION_TTL", env.RefreshTokenTTL.String()); err != nil {
+		return nil, err
+	}
+	if config.Auth.SessionRenewBefore, err = getDurationEnv("AUTH_SESSION_RENEW_BEFORE", "1m"); err != nil {
+		return nil, err
+	}
+	if config.Auth.SessionMode {
+		if config.Auth.CookieMode {
+			return nil, fmt.Errorf("invalid AUTH_SESSION_MODE: cannot be combined with AUTH_COOKIE_MODE")
+		}
+		if err := env.CheckCookieSecure(config.Auth.CookieSecure); err != nil {
+			return nil, fmt.Errorf("invalid AUTH_COOKIE_SECURE: %v", err)
+		}
+		if config.Auth.SessionTTL <= 0 {
+			return nil, fmt.Errorf("invalid AUTH_SESSION_TTL: must be positive")
+		}
+		if config.Auth.SessionRenewBefore < 0 {
+			return nil, fmt.Errorf("invalid AUTH_SESSION_RENEW_BEFORE: must not be negative")
+		}
+	}
+
+	// Сессия аутентифицирует все маршруты /v1, поэтому по умолчанию они все защищены CSRF токеном
+	csrfRoutes := "/v1/auth"
+	if config.Auth.SessionMode {
+		csrfRoutes = "/v1"
+	}
+	config.Auth.CSRFEnabled = (config.Auth.CookieMode || config.Auth.SessionMode) && getBoolEnv("AUTH_CSRF_ENABLED", true)
+	config.Auth.CSRFCookieName = getEnv("AUTH_CSRF_COOKIE_NAME", "csrf_token")
+	config.Auth.CSRFHeader = getEnv("AUTH_CSRF_HEADER", "X-CSRF-Token")
+	config.Auth.CSRFRoutes = splitList(getEnv("AUTH_CSRF_ROUTES", csrfRoutes))
+	for _, prefix := range config.Auth.CSRFRoutes {
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid AUTH_CSRF_ROUTES: %q must start with /", prefix)
+		}
+	}
+	if config.Auth.CSRFEnabled && config.Auth.CSRFCookieName == config.Auth.RefreshCookieName {
+		return nil, fmt.Errorf("invalid AUTH_CSRF_COOKIE_NAME: must differ from AUTH_REFRESH_COOKIE_NAME")
+	}
+	if config.Auth.SessionMode && config.Auth.CSRFCookieName == config.Auth.SessionCookieName {
+		return nil, fmt.Errorf("invalid AUTH_CSRF_COOKIE_NAME: must differ from AUTH_SESSION_COOKIE_NAME")
+	}
+
+	// История конфигурации
+	if config.ConfigHistory.Size, err = getIntEnv("CONFIG_HISTORY_SIZE", "20"); err != nil {
+		return nil, err
+	}
+	if config.ConfigHistory.Size < 1 {
+		return nil, fmt.Errorf("invalid CONFIG_HISTORY_SIZE: must be >= 1")
+	}
+
+	// Отладочные эндпоинты
+	config.Debug.Enabled = getBoolEnv("ENABLE_DEBUG_ENDPOINTS", env.DebugEndpoints)
+	if err := env.CheckDebugEndpoints(config.Debug.Enabled); err != nil {
+		return nil, fmt.Errorf("invalid ENABLE_DEBUG_ENDPOINTS: %v", err)
+	}
+
+	// Конфигурация Redis
+	config.Redis.Host = getEnv("REDIS_HOST", "")
+	config.Redis.Port = getEnv("REDIS_PORT", "6379")
+	config.Redis.Password = getEnv("REDIS_PASSWORD", "")
+	if config.Redis.DB, err = getIntEnv("REDIS_DB", "0"); err != nil {
+		return nil, err
+	}
+	if config.Redis.Timeout, err = getDurationEnv("REDIS_TIMEOUT", "200ms"); err != nil {
+		return nil, err
+	}
+
+	// Конфигурация суточных квот
+	if config.Quota.DailyLimit, err = strconv.ParseInt(getEnv("QUOTA_DAILY_LIMIT", "0"), 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid QUOTA_DAILY_LIMIT: %v", err)
+	}
+	if config.Quota.DailyLimit < 0 {
+		return nil, fmt.Errorf("invalid QUOTA_DAILY_LIMIT: must be >= 0")
+	}
+	if config.Quota.DailyLimit > 0 && config.Redis.Host == "" {
+		return nil, fmt.Errorf("REDIS_HOST is required for QUOTA_DAILY_LIMIT")
+	}
+	config.Quota.FailClosed = getBoolEnv("QUOTA_FAIL_CLOSED", false)
+
+	// Конфигурация данных для отчетов SLA
+	config.SLA.Enabled = getBoolEnv("SLA_ENABLED", false)
+	if config.SLA.TargetPercent, err = strconv.ParseFloat(getEnv("SLA_TARGET_PERCENT", "99.9"), 64); err != nil {
+		return nil, fmt.Errorf("invalid SLA_TARGET_PERCENT: %v", err)
+	}
+	if config.SLA.TargetPercent <= 0 || config.SLA.TargetPercent >= 100 {
+		return nil, fmt.Errorf("invalid SLA_TARGET_PERCENT: must be > 0 and < 100")
+	}
+	if config.SLA.FlushInterval, err = getDurationEnv("SLA_FLUSH_INTERVAL", "10s"); err != nil {
+		return nil, err
+	}
+	if config.SLA.Retention, err = getDurationEnv("SLA_RETENTION", "9600h"); err != nil {
+		return nil, err
+	}
+	if config.SLA.CheckInterval, err = getDurationEnv("SLA_CHECK_INTERVAL", "30s"); err != nil {
+		return nil, err
+	}
+	if config.SLA.CheckTimeout, err = getDurationEnv("SLA_CHECK_TIMEOUT", "5s"); err != nil {
+		return nil, err
+	}
+	if config.SLA.FlushInterval <= 0 || config.SLA.Retention <= 0 || config.SLA.CheckInterval < 0 || config.SLA.CheckTimeout <= 0 {
+		return nil, fmt.Errorf("invalid SLA_FLUSH_INTERVAL/SLA_RETENTION/SLA_CHECK_INTERVAL/SLA_CHECK_TIMEOUT: must be > 0 (SLA_CHECK_INTERVAL >= 0)")
+	}
+	config.SLA.CheckPath = getEnv("SLA_CHECK_PATH", "/version")
+	if !strings.HasPrefix(config.SLA.CheckPath, "/") {
+		return nil, fmt.Errorf("invalid SLA_CHECK_PATH: must start with /")
+	}
+	if config.SLA.Enabled && config.Redis.Host == "" {
+		return nil, fmt.Errorf("REDIS_HOST is required for SLA_ENABLED")
+	}
+
+	// Конфигурация фильтра запросов
+	config.WAF.Enabled = getBoolEnv("WAF_ENABLED", false)
+	if config.WAF.Rules, err = waf.ParseRules(getEnv("WAF_RULES", "")); err != nil {
+		return nil, fmt.Errorf("invalid WAF_RULES: %v", err)
+	}
+	config.WAF.DetectOnly = getBoolEnv("WAF_DETECT_ONLY", false)
+	if config.WAF.MaxHeaderBytes, err = getIntEnv("WAF_MAX_HEADER_BYTES", "16384"); err != nil {
+		return nil, err
+	}
+	if config.WAF.BodyInspectBytes, err = getIntEnv("WAF_BODY_INSPECT_BYTES", "65536"); err != nil {
+		return nil, err
+	}
+	if config.WAF.MaxHeaderBytes <= 0 || config.WAF.BodyInspectBytes < 0 {
+		return nil, fmt.Errorf("invalid WAF_MAX_HEADER_BYTES/WAF_BODY_INSPECT_BYTES: must be > 0 and >= 0")
+	}
+	config.WAF.SkipFields = splitList(getEnv("WAF_SKIP_FIELDS", "password,current_password,new_password"))
+
+	// Конфигурация журнала доступа
+	config.AccessLog.Enabled = getBoolEnv("ACCESS_LOG_ENABLED", false)
+	config.AccessLog.Output = getEnv("ACCESS_LOG_OUTPUT", "stdout")
+	if config.AccessLog.BufferSize, err = getIntEnv("ACCESS_LOG_BUFFER_SIZE", "8192"); err != nil {
+		return nil, err
+	}
+
+	// Конфигурация записи профиля трафика
+	config.Capture.Enabled = getBoolEnv("TRAFFIC_CAPTURE_ENABLED", false)
+	config.Capture.File = getEnv("TRAFFIC_CAPTURE_FILE", "traffic_capture.jsonl")
+	if config.Capture.BufferSize, err = getIntEnv("TRAFFIC_CAPTURE_BUFFER_SIZE", "8192"); err != nil {
+		return nil, err
+	}
+	if config.Capture.MaxRecords, err = getIntEnv("TRAFFIC_CAPTURE_MAX_RECORDS", "1000000"); err != nil {
+		return nil, err
+	}
+	if config.Capture.MaxRecords < 0 {
+		return nil, fmt.Errorf("invalid TRAFFIC_CAPTURE_MAX_RECORDS: must be >= 0")
+	}
+
+	// Конфигурация проверки запросов по спецификации OpenAPI
+	config.OpenAPI.SpecFiles = splitList(getEnv("OPENAPI_SPEC_FILES", "../docs/openapi.yaml,../docs/service_users_api.yaml,../docs/service_orders_api.yaml"))
+	config.OpenAPI.Validate = getBoolEnv("OPENAPI_VALIDATE", true)
+	config.OpenAPI.ScopeByRole = getBoolEnv("OPENAPI_SCOPE_BY_ROLE", true)
+	// Swagger UI показывает объединенную спецификацию, поэтому без нее не открывается
+	config.OpenAPI.SwaggerUI = getBoolEnv("SWAGGER_UI_ENABLED", env.APIDocs) && len(config.OpenAPI.SpecFiles) > 0
+	config.OpenAPI.SwaggerUIAssetsURL = strings.TrimRight(getEnv("SWAGGER_UI_ASSETS_URL", "https://unpkg.com/swagger-ui-dist@5.17.14"), "/")
+	if assetsURL, err := url.Parse(config.OpenAPI.SwaggerUIAssetsURL); err != nil || assetsURL.Scheme == "" || assetsURL.Host == "" {
+		return nil, fmt.Errorf("invalid SWAGGER_UI_ASSETS_URL: expected absolute URL")
+	}
+
+	// Конфигурация лога запросов
+	if config.RequestLog.SlowThreshold, err = getDurationEnv("REQUEST_LOG_SLOW_THRESHOLD", "1s"); err != nil {
+		return nil, err
+	}
+	if config.RequestLog.SampleInitial, err = getIntEnv("REQUEST_LOG_SAMPLE_INITIAL", "0"); err != nil {
+		return nil, err
+	}
+	if config.RequestLog.SampleThereafter, err = getIntEnv("REQUEST_LOG_SAMPLE_THEREAFTER", "10"); err != nil {
+		return nil, err
+	}
+	if config.RequestLog.SampleInitial < 0 || config.RequestLog.SampleThereafter < 1 {
+		return nil, fmt.Errorf("invalid REQUEST_LOG_SAMPLE_INITIAL/REQUEST_LOG_SAMPLE_THEREAFTER: must be >= 0 and >= 1")
+	}
+
+	// Конфигурация центров затрат
+	if config.CostCenter.Rules, err = costcenter.ParseRules(getEnv("COST_CENTER_RULES", "")); err != nil {
+		return nil, fmt.Errorf("invalid COST_CENTER_RULES: %v", err)
+	}
+	config.CostCenter.Default = getEnv("COST_CENTER_DEFAULT", costcenter.DefaultTag)
+	if err := costcenter.ValidateTag(config.CostCenter.Default); err != nil {
+		return nil, fmt.Errorf("invalid COST_CENTER_DEFAULT: %v", err)
+	}
+
+	// Конфигурация gRPC маршрутов
+	if config.GRPC.Routes, err = grpcproxy.ParseRoutes(getEnv("GRPC_ROUTES", "")); err != nil {
+		return nil, fmt.Errorf("invalid GRPC_ROUTES: %v", err)
+	}
+	config.GRPC.DescriptorSet = getEnv("GRPC_DESCRIPTOR_SET", "")
+	config.GRPC.UpstreamTLS = getBoolEnv("GRPC_UPSTREAM_TLS", false)
+	if config.GRPC.Timeout, err = getDurationEnv("GRPC_TIMEOUT", "10s"); err != nil {
+		return nil, err
+	}
+
+	for _, route := range config.GRPC.Routes {
+		if !strings.HasPrefix(route.Path, "/v1/") {
+			return nil, fmt.Errorf("invalid GRPC_ROUTES: path %s must start with /v1/", route.Path)
+		}
+	}
+	if len(config.GRPC.Routes) > 0 && config.GRPC.DescriptorSet == "" {
+		return nil, fmt.Errorf("GRPC_DESCRIPTOR_SET is required when GRPC_ROUTES is set")
+	}
+
+	return config, nil
+}
+
+// getEnv возвращает значение переменной окружения или значение по умолчанию
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getIntEnv возвращает int значение переменной окружения или значение по умолчанию
+func getIntEnv(key, defaultValue string) (int, error) {
+	value, err := strconv.Atoi(getEnv(key, defaultValue))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return value, nil
+}
+
+// getDurationEnv возвращает time.Duration значение переменной окружения или значение по умолчанию
+func getDurationEnv(key, defaultValue string) (time.Duration, error) {
+	value, err := time.ParseDuration(getEnv(key, defaultValue))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return value, nil
+}
+
+// getBoolEnv возвращает bool значение переменной окружения или значение по умолчанию
+func getBoolEnv(key string, defaultValue bool) bool {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "true", "1", "yes", "on":
+		return true
+	case "false", "0", "no", "off":
+		return false
+	}
+	return defaultValue
+}
+
+// splitList разбирает список значений, разделенных запятыми
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
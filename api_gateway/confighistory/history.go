@@ -0,0 +1,124 @@
+// Package confighistory хранит последние снимки действующей конфигурации шлюза
+// и изменения между ними, чтобы после инцидента можно было ответить на вопрос
+// "что поменялось перед ним": при запуске и при каждом изменении настроек через
+// административный API снимок сравнивается с предыдущим, а разница пишется в лог
+package confighistory
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Change изменение одного параметра; пустые Old или New — параметр добавлен или удален
+type Change struct {
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// Snapshot снимок действующей конфигурации
+type Snapshot struct {
+	// Version порядковый номер снимка с момента запуска экземпляра, начиная с 1
+	Version int `json:"version"`
+	// Source причина снимка: startup или действие административного API
+	Source string `json:"source"`
+	// Actor администратор, изменивший настройки; пусто для startup
+	Actor   string    `json:"actor,omitempty"`
+	TakenAt time.Time `json:"taken_at"`
+	// Changes изменения относительно предыдущего снимка; у первого снимка пусто
+	Changes []Change          `json:"changes"`
+	Values  map[string]string `json:"values,omitempty"`
+}
+
+// History последние снимки конфигурации
+type History struct {
+	size int
+
+	mutex     sync.RWMutex
+	snapshots []Snapshot
+	version   int
+	now       func() time.Time
+}
+
+// New создает историю из size последних снимков
+func New(size int) *History {
+	return &History{size: size, now: time.Now}
+}
+
+// Record сохраняет снимок values и возвращает его вместе с изменениями
+// относительно предыдущего снимка. Снимок без изменений не сохраняется (ok == false)
+func (h *History) Record(source, actor string, values map[string]string) (Snapshot, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	changes := []Change{}
+	if len(h.snapshots) > 0 {
+		changes = Diff(h.snapshots[len(h.snapshots)-1].Values, values)
+		if len(changes) == 0 {
+			return Snapshot{}, false
+		}
+	}
+
+	h.version++
+	snapshot := Snapshot{
+		Version: h.version,
+		Source:  source,
+		Actor:   actor,
+		TakenAt: h.now().UTC(),
+		Changes: changes,
+		Values:  values,
+	}
+
+	h.snapshots = append(h.snapshots, snapshot)
+	if len(h.snapshots) > h.size {
+		h.snapshots = append([]Snapshot(nil), h.snapshots[len(h.snapshots)-h.size:]...)
+	}
+	return snapshot, true
+}
+
+// List возвращает снимки от новых к старым без значений параметров
+func (h *History) List() []Snapshot {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	list := make([]Snapshot, 0, len(h.snapshots))
+	for i := len(h.snapshots) - 1; i >= 0; i-- {
+		snapshot := h.snapshots[i]
+		snapshot.Values = nil
+		list = append(list, snapshot)
+	}
+	return list
+}
+
+// Get возвращает снимок по номеру версии; ok == false, если он вытеснен или не существует
+func (h *History) Get(version int) (Snapshot, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for _, snapshot := range h.snapshots {
+		if snapshot.Version == version {
+			return snapshot, true
+		}
+	}
+	return Snapshot{}, false
+}
+
+// Diff возвращает изменения от old к new, упорядоченные по ключу
+func Diff(old, new map[string]string) []Change {
+	var changes []Change
+	for key, value := range new {
+		if previous, ok := old[key]; !ok || previous != value {
+			changes = append(changes, Change{Key: key, Old: old[key], New: value})
+		}
+	}
+	for key, value := range old {
+		if _, ok := new[key]; !ok {
+			changes = append(changes, Change{Key: key, Old: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
@@ -0,0 +1,168 @@
+// Package costcenter относит запросы API Gateway к центрам затрат (клиентское
+// приложение, API ключ, арендатор, группа маршрутов) по настраиваемым правилам
+// и накапливает потребление по каждому тегу для внутреннего распределения затрат
+package costcenter
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Типы условий правил
+const (
+	// MatchApp клиентское приложение из заголовка X-Client-App
+	MatchApp = "app"
+	// MatchAPIKey персональный API ключ из заголовка Authorization: ApiKey <ключ>
+	MatchAPIKey = "api_key"
+	// MatchTenant арендатор из заголовка X-Tenant-ID
+	MatchTenant = "tenant"
+	// MatchRoute HTTP метод и префикс пути
+	MatchRoute = "route"
+)
+
+// Заголовки, по которым классифицируются запросы
+const (
+	HeaderClientApp = "X-Client-App"
+	HeaderTenant    = "X-Tenant-ID"
+)
+
+// apiKeyScheme схема заголовка Authorization с персональным API ключом
+const apiKeyScheme = "ApiKey "
+
+// DefaultTag тег запросов, не подходящих ни под одно правило
+const DefaultTag = "unattributed"
+
+// tagPattern допустимый тег: используется в метках метрик и в заголовке baggage
+var tagPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// ValidateTag проверяет тег центра затрат
+func ValidateTag(tag string) error {
+	if !tagPattern.MatchString(tag) {
+		return fmt.Errorf("некорректный тег %q: допустимы строчные латинские буквы, цифры, '_', '.', '-' (до 64 символов)", tag)
+	}
+	return nil
+}
+
+// Rule правило отнесения запроса к центру затрат Tag
+type Rule struct {
+	Tag  string `json:"tag"`
+	Kind string `json:"kind"`
+	// Value значение заголовка для app, api_key и tenant
+	Value string `json:"value,omitempty"`
+	// Method и Prefix условие для route; Method "*" — любой
+	Method string `json:"method,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// Matches проверяет, подходит ли правило для запроса
+func (rule Rule) Matches(r *http.Request) bool {
+	switch rule.Kind {
+	case MatchApp:
+		return strings.EqualFold(r.Header.Get(HeaderClientApp), rule.Value)
+	case MatchAPIKey:
+		return requestAPIKey(r) == rule.Value
+	case MatchTenant:
+		return r.Header.Get(HeaderTenant) == rule.Value
+	case MatchRoute:
+		return (rule.Method == "*" || rule.Method == r.Method) && strings.HasPrefix(r.URL.Path, rule.Prefix)
+	}
+	return false
+}
+
+// requestAPIKey возвращает API ключ из заголовка Authorization: ApiKey <ключ>
+// или пустую строку для других схем
+func requestAPIKey(r *http.Request) string {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, apiKeyScheme) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authHeader, apiKeyScheme))
+}
+
+// String возвращает правило в формате конфигурации; API ключ не раскрывается
+func (rule Rule) String() string {
+	switch rule.Kind {
+	case MatchRoute:
+		return fmt.Sprintf("%s=%s:%s %s", rule.Tag, rule.Kind, rule.Method, rule.Prefix)
+	case MatchAPIKey:
+		return fmt.Sprintf("%s=%s:***", rule.Tag, rule.Kind)
+	}
+	return fmt.Sprintf("%s=%s:%s", rule.Tag, rule.Kind, rule.Value)
+}
+
+// ParseRules разбирает правила в формате
+// "mobile=app:ios;partner-acme=api_key:KEY;acme=tenant:acme;reports=route:GET /v1/orders/all".
+// Для route метод можно не указывать (любой метод). Правила проверяются по порядку
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		tag, condition, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("некорректное правило %q: ожидается 'tag=kind:value'", item)
+		}
+		kind, value, ok := strings.Cut(condition, ":")
+		if !ok {
+			return nil, fmt.Errorf("некорректное правило %q: ожидается 'tag=kind:value'", item)
+		}
+
+		rule := Rule{Tag: strings.TrimSpace(tag), Kind: strings.ToLower(strings.TrimSpace(kind))}
+		if err := ValidateTag(rule.Tag); err != nil {
+			return nil, err
+		}
+
+		value = strings.TrimSpace(value)
+		switch rule.Kind {
+		case MatchApp, MatchAPIKey, MatchTenant:
+			if value == "" {
+				return nil, fmt.Errorf("правило %q: не указано значение", item)
+			}
+			rule.Value = value
+		case MatchRoute:
+			fields := strings.Fields(value)
+			switch len(fields) {
+			case 1:
+				rule.Method, rule.Prefix = "*", fields[0]
+			case 2:
+				rule.Method, rule.Prefix = strings.ToUpper(fields[0]), fields[1]
+			default:
+				return nil, fmt.Errorf("правило %q: ожидается '[METHOD] /path'", item)
+			}
+			if !strings.HasPrefix(rule.Prefix, "/") {
+				return nil, fmt.Errorf("правило %q: путь должен начинаться с /", item)
+			}
+		default:
+			return nil, fmt.Errorf("правило %q: неизвестный тип условия %q (app, api_key, tenant, route)", item, rule.Kind)
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// Classifier относит запрос к центру затрат по первому подходящему правилу
+type Classifier struct {
+	rules    []Rule
+	fallback string
+}
+
+// NewClassifier создает классификатор; запросы без подходящего правила получают тег fallback
+func NewClassifier(rules []Rule, fallback string) *Classifier {
+	return &Classifier{rules: rules, fallback: fallback}
+}
+
+// Classify возвращает тег центра затрат запроса
+func (c *Classifier) Classify(r *http.Request) string {
+	for _, rule := range c.rules {
+		if rule.Matches(r) {
+			return rule.Tag
+		}
+	}
+	return c.fallback
+}
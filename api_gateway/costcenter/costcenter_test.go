@@ -0,0 +1,33 @@
+package costcenter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKeyRuleMatchesAuthorizationScheme(t *testing.T) {
+	rule := Rule{Tag: "partner-acme", Kind: MatchAPIKey, Value: "acme-key"}
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          bool
+	}{
+		{"схема ApiKey", "ApiKey acme-key", true},
+		{"другой ключ", "ApiKey other-key", false},
+		{"Bearer токен", "Bearer acme-key", false},
+		{"без заголовка", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/orders", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			if got := rule.Matches(req); got != tt.want {
+				t.Errorf("Matches %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package costcenter
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// TagUsage потребление центра затрат с момента Report.Since
+type TagUsage struct {
+	Tag      string `json:"tag"`
+	Requests uint64 `json:"requests"`
+	// Errors ответы со статусом 5xx
+	Errors     uint64  `json:"errors"`
+	BytesIn    int64   `json:"bytes_in"`
+	BytesOut   int64   `json:"bytes_out"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Report потребление по всем тегам за период [Since, Until)
+type Report struct {
+	Since time.Time  `json:"since"`
+	Until time.Time  `json:"until"`
+	Tags  []TagUsage `json:"tags"`
+}
+
+// Usage накапливает потребление по тегам в памяти экземпляра шлюза.
+// Число тегов ограничено правилами конфигурации, поэтому память не растет
+type Usage struct {
+	mutex sync.Mutex
+	since time.Time
+	tags  map[string]*TagUsage
+}
+
+// NewUsage создает пустой накопитель потребления
+func NewUsage() *Usage {
+	return &Usage{since: time.Now().UTC(), tags: make(map[string]*TagUsage)}
+}
+
+// Record учитывает завершенный запрос центра затрат tag
+func (u *Usage) Record(tag string, status int, bytesIn, bytesOut int64, duration time.Duration) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	usage, ok := u.tags[tag]
+	if !ok {
+		usage = &TagUsage{Tag: tag}
+		u.tags[tag] = usage
+	}
+
+	usage.Requests++
+	if status >= 500 {
+		usage.Errors++
+	}
+	usage.BytesIn += bytesIn
+	usage.BytesOut += bytesOut
+	usage.DurationMS += float64(duration.Microseconds()) / 1000
+}
+
+// Snapshot возвращает потребление с момента запуска или последнего сброса
+func (u *Usage) Snapshot() Report {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.report()
+}
+
+// Reset возвращает накопленное потребление и начинает новый период. Отчет и сброс
+// выполняются атомарно, поэтому запросы не теряются между периодами
+func (u *Usage) Reset() Report {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	report := u.report()
+	u.since = report.Until
+	u.tags = make(map[string]*TagUsage)
+	return report
+}
+
+// report формирует отчет; вызывается под mutex
+func (u *Usage) report() Report {
+	report := Report{Since: u.since, Until: time.Now().UTC(), Tags: make([]TagUsage, 0, len(u.tags))}
+	for _, usage := range u.tags {
+		report.Tags = append(report.Tags, *usage)
+	}
+	sort.Slice(report.Tags, func(i, j int) bool {
+		return report.Tags[i].Tag < report.Tags[j].Tag
+	})
+	return report
+}
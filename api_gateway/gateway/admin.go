@@ -0,0 +1,340 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"api_gateway/logger"
+	"api_gateway/ratelimit"
+	"api_gateway/upstream"
+
+	"pkg/rbac"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// adminPathPrefix префикс административного API; его маршруты нельзя отключить,
+// иначе администратор потеряет возможность включить их обратно
+const adminPathPrefix = "/v1/admin/gateway"
+
+// RouteToggle маршрут, отключенный администратором: запросы с методом Method
+// и путем, начинающимся с Prefix, отклоняются с 503
+type RouteToggle struct {
+	// Method HTTP метод; "*" — любой
+	Method string `json:"method"`
+	Prefix string `json:"prefix"`
+}
+
+// matches проверяет, подходит ли запрос под отключенный маршрут
+func (t RouteToggle) matches(r *http.Request) bool {
+	return (t.Method == "*" || t.Method == r.Method) && strings.HasPrefix(r.URL.Path, t.Prefix)
+}
+
+// routeToggles список отключенных маршрутов
+type routeToggles struct {
+	mutex    sync.RWMutex
+	disabled []RouteToggle
+}
+
+// set включает или отключает маршрут
+func (rt *routeToggles) set(toggle RouteToggle, enabled bool) {
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+
+	disabled := rt.disabled[:0:0]
+	for _, existing := range rt.disabled {
+		if existing != toggle {
+			disabled = append(disabled, existing)
+		}
+	}
+	if !enabled {
+		disabled = append(disabled, toggle)
+	}
+	rt.disabled = disabled
+}
+
+// list возвращает отключенные маршруты
+func (rt *routeToggles) list() []RouteToggle {
+	rt.mutex.RLock()
+	defer rt.mutex.RUnlock()
+	return append([]RouteToggle{}, rt.disabled...)
+}
+
+// isDisabled проверяет, отключен ли маршрут запроса
+func (rt *routeToggles) isDisabled(r *http.Request) bool {
+	rt.mutex.RLock()
+	defer rt.mutex.RUnlock()
+	for _, toggle := range rt.disabled {
+		if toggle.matches(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeToggleMiddleware отклоняет запросы к отключенным маршрутам
+func (g *Gateway) routeToggleMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, adminPathPrefix) && g.routes.isDisabled(r) {
+			g.respondWithError(w, http.StatusServiceUnavailable, "Маршрут временно отключен")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminOnlyMiddleware пропускает только пользователей с правом gateway.manage. Права
+// берутся из заголовка, который jwtAuthMiddleware заполняет по проверенному токену
+func (g *Gateway) adminOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !rbac.Has(r, rbac.GatewayManage) {
+			g.respondWithError(w, http.StatusForbidden, "Доступ запрещен")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// adminStateResponse текущее состояние настраиваемых параметров шлюза
+type adminStateResponse struct {
+	RateLimits     rateLimitsRequest       `json:"rate_limits"`
+	DisabledRoutes []RouteToggle           `json:"disabled_routes"`
+	Breakers       []upstream.BreakerState `json:"breakers"`
+	// Failovers состояние резервирования сервисов с резервным экземпляром
+	Failovers []upstream.FailoverState `json:"failovers"`
+	// Concurrency состояние ограничителей одновременных запросов: общего и по сервисам
+	Concurrency []upstream.LimiterState `json:"concurrency"`
+}
+
+// rateLimitsRequest правила ограничения частоты запросов
+type rateLimitsRequest struct {
+	Default ratelimit.Rule   `json:"default"`
+	Routes  []ratelimit.Rule `json:"routes"`
+}
+
+// toggleRouteRequest запрос на включение или отключение маршрута
+type toggleRouteRequest struct {
+	RouteToggle
+	Enabled bool `json:"enabled"`
+}
+
+// adminState возвращает правила rate limit, отключенные маршруты и состояние breakers
+func (g *Gateway) adminState(w http.ResponseWriter, r *http.Request) {
+	g.respondWithJSON(w, http.StatusOK, g.state())
+}
+
+// adminUpdateRateLimits заменяет правила ограничения частоты запросов
+func (g *Gateway) adminUpdateRateLimits(w http.ResponseWriter, r *http.Request) {
+	var req rateLimitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.respondWithError(w, http.StatusBadRequest, "Некорректный JSON")
+		return
+	}
+
+	for i := range req.Routes {
+		req.Routes[i].Method = strings.ToUpper(req.Routes[i].Method)
+		if !strings.HasPrefix(req.Routes[i].Prefix, "/") {
+			g.respondWithError(w, http.StatusBadRequest, "Префикс маршрута должен начинаться с /")
+			return
+		}
+	}
+	req.Default.Method = "*"
+	req.Default.Prefix = "/"
+
+	if err := g.deps.RateLimiter.SetRules(req.Routes, req.Default); err != nil {
+		g.respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	g.auditAdminAction(r, "rate_limits_update", zap.Any("rate_limits", req))
+	g.recordConfig("rate_limits_update", r.Header.Get("X-User-ID"))
+	g.respondWithJSON(w, http.StatusOK, g.state())
+}
+
+// adminToggleRoute включает или отключает маршрут
+func (g *Gateway) adminToggleRoute(w http.ResponseWriter, r *http.Request) {
+	var req toggleRouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		g.respondWithError(w, http.StatusBadRequest, "Некорректный JSON")
+		return
+	}
+
+	req.Method = strings.ToUpper(req.Method)
+	if req.Method == "" {
+		req.Method = "*"
+	}
+	if !strings.HasPrefix(req.Prefix, "/") {
+		g.respondWithError(w, http.StatusBadRequest, "Префикс маршрута должен начинаться с /")
+		return
+	}
+
+	g.routes.set(req.RouteToggle, req.Enabled)
+
+	g.auditAdminAction(r, "route_toggle",
+		zap.String("route_method", req.Method),
+		zap.String("route_prefix", req.Prefix),
+		zap.Bool("enabled", req.Enabled),
+	)
+	g.recordConfig("route_toggle", r.Header.Get("X-User-ID"))
+	g.respondWithJSON(w, http.StatusOK, g.state())
+}
+
+// adminFlushCache очищает кеш ответов
+func (g *Gateway) adminFlushCache(w http.ResponseWriter, r *http.Request) {
+	purged := g.deps.ResponseCache.Purge()
+
+	g.auditAdminAction(r, "cache_flush", zap.Int("purged", purged))
+	g.respondWithJSON(w, http.StatusOK, map[string]int{"purged": purged})
+}
+
+// adminTripBreaker размыкает circuit breaker сервиса до сброса
+func (g *Gateway) adminTripBreaker(w http.ResponseWriter, r *http.Request) {
+	g.updateBreaker(w, r, "breaker_trip", (*upstream.Breaker).Trip)
+}
+
+// adminResetBreaker закрывает circuit breaker сервиса
+func (g *Gateway) adminResetBreaker(w http.ResponseWriter, r *http.Request) {
+	g.updateBreaker(w, r, "breaker_reset", (*upstream.Breaker).Reset)
+}
+
+// updateBreaker применяет действие к breaker сервиса из пути запроса
+func (g *Gateway) updateBreaker(w http.ResponseWriter, r *http.Request, action string, apply func(*upstream.Breaker)) {
+	service := mux.Vars(r)["service"]
+	breaker, ok := g.deps.Breakers[service]
+	if !ok {
+		g.respondWithError(w, http.StatusNotFound, "Сервис не найден")
+		return
+	}
+
+	apply(breaker)
+
+	g.auditAdminAction(r, action, zap.String("service", service))
+	g.respondWithJSON(w, http.StatusOK, breaker.State())
+}
+
+// adminUsage возвращает потребление по центрам затрат с момента запуска или последнего сброса.
+// Данные относятся только к экземпляру, принявшему запрос
+func (g *Gateway) adminUsage(w http.ResponseWriter, r *http.Request) {
+	if g.deps.CostCenterUsage == nil {
+		g.respondWithError(w, http.StatusNotFound, "Тегирование по центрам затрат не настроено")
+		return
+	}
+	g.respondWithJSON(w, http.StatusOK, g.deps.CostCenterUsage.Snapshot())
+}
+
+// adminResetUsage возвращает потребление за завершенный период и начинает новый
+func (g *Gateway) adminResetUsage(w http.ResponseWriter, r *http.Request) {
+	if g.deps.CostCenterUsage == nil {
+		g.respondWithError(w, http.StatusNotFound, "Тегирование по центрам затрат не настроено")
+		return
+	}
+	report := g.deps.CostCenterUsage.Reset()
+
+	g.auditAdminAction(r, "usage_reset", zap.Time("since", report.Since), zap.Time("until", report.Until))
+	g.respondWithJSON(w, http.StatusOK, report)
+}
+
+// adminQuota возвращает потребление суточной квоты пользователем
+func (g *Gateway) adminQuota(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.quotaUserID(w, r)
+	if !ok {
+		return
+	}
+
+	usage, err := g.deps.Quotas.Get(r.Context(), userID)
+	if err != nil {
+		g.respondWithError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	g.respondWithJSON(w, http.StatusOK, usage)
+}
+
+// adminResetQuota обнуляет потребление суточной квоты пользователем и возвращает
+// потребление до сброса
+func (g *Gateway) adminResetQuota(w http.ResponseWriter, r *http.Request) {
+	userID, ok := g.quotaUserID(w, r)
+	if !ok {
+		return
+	}
+
+	usage, err := g.deps.Quotas.Reset(r.Context(), userID)
+	if err != nil {
+		g.respondWithError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	g.auditAdminAction(r, "quota_reset", zap.String("quota_user_id", userID), zap.Int64("used", usage.Used))
+	g.respondWithJSON(w, http.StatusOK, usage)
+}
+
+// quotaUserID проверяет, что квоты включены, и возвращает пользователя из пути запроса
+func (g *Gateway) quotaUserID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if g.deps.Quotas == nil {
+		g.respondWithError(w, http.StatusNotFound, "Суточные квоты не настроены")
+		return "", false
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["user_id"])
+	if err != nil {
+		g.respondWithError(w, http.StatusBadRequest, "Некорректный ID пользователя")
+		return "", false
+	}
+	return userID.String(), true
+}
+
+// state собирает текущее состояние настраиваемых параметров
+func (g *Gateway) state() adminStateResponse {
+	routes, fallback := g.deps.RateLimiter.Rules()
+
+	names := make([]string, 0, len(g.deps.Breakers))
+	for name := range g.deps.Breakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	breakers := make([]upstream.BreakerState, 0, len(names))
+	for _, name := range names {
+		breakers = append(breakers, g.deps.Breakers[name].State())
+	}
+
+	failovers := make([]upstream.FailoverState, 0, len(g.deps.Failovers))
+	for _, name := range names {
+		if failover, ok := g.deps.Failovers[name]; ok {
+			failovers = append(failovers, failover.State())
+		}
+	}
+
+	concurrency := make([]upstream.LimiterState, 0, len(g.deps.Limiters)+1)
+	if g.deps.Concurrency != nil {
+		concurrency = append(concurrency, g.deps.Concurrency.State())
+	}
+	for _, name := range names {
+		if limiter, ok := g.deps.Limiters[name]; ok {
+			concurrency = append(concurrency, limiter.State())
+		}
+	}
+
+	return adminStateResponse{
+		RateLimits:     rateLimitsRequest{Default: fallback, Routes: routes},
+		DisabledRoutes: g.routes.list(),
+		Breakers:       breakers,
+		Failovers:      failovers,
+		Concurrency:    concurrency,
+	}
+}
+
+// auditAdminAction записывает изменение настроек с администратором, который его выполнил
+func (g *Gateway) auditAdminAction(r *http.Request, action string, fields ...zap.Field) {
+	log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+	log.Info("Изменение настроек шлюза",
+		append([]zap.Field{
+			zap.String("action", action),
+			zap.String("admin_id", r.Header.Get("X-User-ID")),
+		}, fields...)...,
+	)
+}
@@ -0,0 +1,210 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"api_gateway/logger"
+
+	"pkg/clients"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// apiKeyScheme схема заголовка Authorization с персональным API ключом
+	apiKeyScheme = "ApiKey "
+	// apiKeyRenewBefore за сколько до истечения токен ключа запрашивается заново
+	apiKeyRenewBefore = 30 * time.Second
+	// maxCachedAPIKeys предел числа токенов в кеше; при переполнении кеш очищается
+	maxCachedAPIKeys = 10000
+)
+
+// errAPIKeyRejected сервис пользователей отклонил API ключ; status — код его ответа
+type errAPIKeyRejected struct {
+	status int
+}
+
+func (e *errAPIKeyRejected) Error() string {
+	return fmt.Sprintf("сервис пользователей отклонил API ключ: статус %d", e.status)
+}
+
+// apiKeyContextKey ключ контекста с API ключом запроса: apiKeyMiddleware заменяет
+// заголовок Authorization токеном ключа, а метрикам ограничителя нужен сам ключ
+type apiKeyContextKey struct{}
+
+// requestAPIKey возвращает API ключ запроса, принятый apiKeyMiddleware
+func requestAPIKey(r *http.Request) string {
+	key, _ := r.Context().Value(apiKeyContextKey{}).(string)
+	return key
+}
+
+// apiKeyToken access токен, выданный по API ключу
+type apiKeyToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// apiKeyCache токены API ключей по SHA-256 хешу ключа. Кеш локален для экземпляра:
+// каждый экземпляр шлюза обменивает ключ не чаще раза за время жизни токена
+type apiKeyCache struct {
+	mu     sync.Mutex
+	tokens map[string]apiKeyToken
+}
+
+func newAPIKeyCache() *apiKeyCache {
+	return &apiKeyCache{tokens: make(map[string]apiKeyToken)}
+}
+
+// get возвращает токен ключа, если до его истечения больше apiKeyRenewBefore
+func (c *apiKeyCache) get(hash string, now time.Time) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.tokens[hash]
+	if !ok || now.Add(apiKeyRenewBefore).After(cached.expiresAt) {
+		return "", false
+	}
+	return cached.token, true
+}
+
+// put сохраняет токен ключа, предварительно удаляя истекшие при заполнении кеша
+func (c *apiKeyCache) put(hash string, token apiKeyToken, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.tokens) >= maxCachedAPIKeys {
+		for key, cached := range c.tokens {
+			if now.After(cached.expiresAt) {
+				delete(c.tokens, key)
+			}
+		}
+		if len(c.tokens) >= maxCachedAPIKeys {
+			c.tokens = make(map[string]apiKeyToken)
+		}
+	}
+	c.tokens[hash] = token
+}
+
+// apiKeyMiddleware аутентифицирует запросы с заголовком Authorization: ApiKey <ключ>:
+// обменивает ключ на access токен в сервисе пользователей и подставляет токен как Bearer.
+// Области ключа проверяет jwtAuthMiddleware по claims токена
+func (g *Gateway) apiKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if !g.config.Auth.APIKeysEnabled || !strings.HasPrefix(authHeader, apiKeyScheme) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := strings.TrimSpace(strings.TrimPrefix(authHeader, apiKeyScheme))
+		if key == "" {
+			g.respondWithError(w, http.StatusUnauthorized, "Недействительный API ключ")
+			return
+		}
+
+		token, err := g.apiKeyAccessToken(r, key)
+		if err != nil {
+			if rejected, ok := err.(*errAPIKeyRejected); ok {
+				if rejected.status == http.StatusForbidden {
+					g.respondWithError(w, http.StatusForbidden, "Доступ по API ключу запрещен")
+					return
+				}
+				g.respondWithError(w, http.StatusUnauthorized, "Недействительный API ключ")
+				return
+			}
+			log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+			log.Error("Failed to authenticate API key", zap.Error(err))
+			g.respondWithError(w, http.StatusServiceUnavailable, "Не удалось проверить API ключ")
+			return
+		}
+
+		r.Header.Set("Authorization", "Bearer "+token)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key)))
+	})
+}
+
+// apiKeyAccessToken возвращает access токен ключа из кеша или обменивает ключ
+// в сервисе пользователей
+func (g *Gateway) apiKeyAccessToken(r *http.Request, key string) (string, error) {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+	now := time.Now()
+	if token, ok := g.apiKeys.get(hash, now); ok {
+		return token, nil
+	}
+
+	token, ttl, err := g.exchangeAPIKey(clients.WithRequest(r.Context(), r), key)
+	if err != nil {
+		return "", err
+	}
+	g.apiKeys.put(hash, apiKeyToken{token: token, expiresAt: now.Add(ttl)}, now)
+	return token, nil
+}
+
+// exchangeAPIKey обменивает API ключ на access токен в сервисе пользователей
+func (g *Gateway) exchangeAPIKey(ctx context.Context, key string) (string, time.Duration, error) {
+	resp, err := g.users.AuthenticateAPIKey(ctx, key)
+	if err != nil {
+		switch status := clients.StatusOf(err); status {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusBadRequest:
+			return "", 0, &errAPIKeyRejected{status: status}
+		case 0:
+			return "", 0, err
+		default:
+			return "", 0, fmt.Errorf("сервис пользователей вернул статус %d", status)
+		}
+	}
+	if resp.Token == "" {
+		return "", 0, fmt.Errorf("ответ обмена API ключа не содержит токен")
+	}
+	return resp.Token, time.Duration(resp.ExpiresIn) * time.Second, nil
+}
+
+// requiredAPIKeyScope возвращает область API ключа, необходимую для запроса:
+// orders или users по пути и :read для GET и HEAD, :write для остальных методов.
+// Пустая строка — маршрут недоступен по API ключу: учетная запись (/v1/users/me)
+// и сами API ключи управляются только после входа
+func requiredAPIKeyScope(r *http.Request) string {
+	var resource string
+	switch {
+	case hasPathPrefix(r.URL.Path, "/v1/users/me"), hasPathPrefix(r.URL.Path, "/v1/users/profile/api-keys"):
+		return ""
+	case hasPathPrefix(r.URL.Path, "/v1/orders"):
+		resource = "orders"
+	case hasPathPrefix(r.URL.Path, "/v1/users"):
+		resource = "users"
+	default:
+		return ""
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return resource + ":read"
+	}
+	return resource + ":write"
+}
+
+// apiKeyScopeAllowed проверяет, что области scopes токена API ключа разрешают запрос
+func apiKeyScopeAllowed(scopes []string, r *http.Request) bool {
+	required := requiredAPIKeyScope(r)
+	if required == "" {
+		return false
+	}
+	for _, scope := range scopes {
+		if scope == required {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix проверяет, что path равен prefix или вложен в него
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
@@ -0,0 +1,204 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// maxAuthBodySize ограничение размера тела запросов и ответов входа и обновления токена
+const maxAuthBodySize = 1 << 20
+
+// Маршруты сервиса пользователей, принимающие refresh токен
+const (
+	refreshPath = "/v1/auth/refresh"
+	revokePath  = "/v1/auth/revoke"
+)
+
+// refreshCookieMiddleware в режиме cookie переносит refresh токен между телом и
+// HTTP-only cookie: в запрос токен подставляется из cookie, если его нет в теле,
+// а из успешного ответа сервиса пользователей токен извлекается в cookie,
+// чтобы он не был доступен JavaScript клиента
+func (g *Gateway) refreshCookieMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !g.config.Auth.CookieMode {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxAuthBodySize))
+		if err != nil {
+			g.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		body = g.injectRefreshCookie(r, body)
+
+		// Ответ разбирается шлюзом, поэтому сжатие со стороны сервиса не нужно
+		r.Header.Del("Accept-Encoding")
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+
+		buffered := newBufferedResponse()
+		next.ServeHTTP(buffered, r)
+
+		g.writeRefreshResponse(w, r, buffered)
+	})
+}
+
+// injectRefreshCookie добавляет refresh токен из cookie в тело запроса обновления или отзыва токена
+func (g *Gateway) injectRefreshCookie(r *http.Request, body []byte) []byte {
+	if r.URL.Path != refreshPath && r.URL.Path != revokePath {
+		return body
+	}
+
+	cookie, err := r.Cookie(g.config.Auth.RefreshCookieName)
+	if err != nil || cookie.Value == "" {
+		return body
+	}
+
+	payload := map[string]interface{}{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			// Некорректный JSON передается сервису как есть, он вернет ошибку валидации
+			return body
+		}
+	}
+	if token, ok := payload["refresh_token"].(string); ok && token != "" {
+		return body
+	}
+
+	payload["refresh_token"] = cookie.Value
+	injected, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	r.Header.Set("Content-Type", "application/json")
+	return injected
+}
+
+// writeRefreshResponse отправляет клиенту ответ сервиса, перенося refresh токен в cookie
+func (g *Gateway) writeRefreshResponse(w http.ResponseWriter, r *http.Request, resp *bufferedResponse) {
+	body := resp.body.Bytes()
+
+	switch {
+	case resp.status >= 200 && resp.status < 300 && r.URL.Path == revokePath:
+		// Отозванный токен больше не нужен браузеру
+		http.SetCookie(w, g.refreshCookie("", -1))
+		g.setCSRFToken(w, -1)
+	case resp.status >= 200 && resp.status < 300:
+		if fields, stripped, ok := stripDataFields(body, "refresh_token"); ok {
+			http.SetCookie(w, g.refreshCookie(fields["refresh_token"], int(g.config.Auth.RefreshCookieMaxAge.Seconds())))
+			g.setCSRFToken(w, int(g.config.Auth.RefreshCookieMaxAge.Seconds()))
+			body = stripped
+		}
+	case resp.status == http.StatusUnauthorized && r.URL.Path == refreshPath:
+		// Отозванный или истекший токен удаляется из браузера
+		http.SetCookie(w, g.refreshCookie("", -1))
+		g.setCSRFToken(w, -1)
+	}
+
+	g.writeBufferedResponse(w, resp, body)
+}
+
+// writeBufferedResponse отправляет клиенту накопленный ответ сервиса с измененным телом
+func (g *Gateway) writeBufferedResponse(w http.ResponseWriter, resp *bufferedResponse, body []byte) {
+	for key, values := range resp.header {
+		switch key {
+		case "Content-Length":
+			continue
+		case "Set-Cookie":
+			// Cookie сервиса дополняют cookie шлюза (refresh токен, сессия), а не заменяют их
+			w.Header()[key] = append(w.Header()[key], values...)
+		default:
+			w.Header()[key] = values
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(resp.status)
+
+	if _, err := w.Write(body); err != nil {
+		g.logger.Error("Failed to write auth response", zap.Error(err))
+	}
+}
+
+// refreshCookie создает cookie refresh токена; maxAge < 0 удаляет cookie
+func (g *Gateway) refreshCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     g.config.Auth.RefreshCookieName,
+		Value:    value,
+		Path:     g.config.Auth.CookiePath,
+		Domain:   g.config.Auth.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   g.config.Auth.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// stripDataFields извлекает строковые поля data.<name> из ответа сервиса и возвращает
+// тело без них; ok == false, если какое-либо поле отсутствует или пусто
+func stripDataFields(body []byte, names ...string) (map[string]string, []byte, bool) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, nil, false
+	}
+
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(envelope["data"], &data); err != nil {
+		return nil, nil, false
+	}
+
+	fields := make(map[string]string, len(names))
+	for _, name := range names {
+		var value string
+		if err := json.Unmarshal(data[name], &value); err != nil || value == "" {
+			return nil, nil, false
+		}
+		fields[name] = value
+		delete(data, name)
+	}
+
+	rawData, err := json.Marshal(data)
+	if err != nil {
+		return nil, nil, false
+	}
+	envelope["data"] = rawData
+
+	stripped, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, nil, false
+	}
+	return fields, stripped, true
+}
+
+// bufferedResponse накапливает ответ upstream сервиса для последующей обработки
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBufferedResponse() *bufferedResponse {
+	return &bufferedResponse{header: make(http.Header), status: http.StatusOK}
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	b.status = code
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.body.Len()+len(p) > maxAuthBodySize {
+		return 0, io.ErrShortWrite
+	}
+	return b.body.Write(p)
+}
@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressionMiddleware сжимает ответы gzip/brotli в соответствии с Accept-Encoding клиента.
+// Сжимаются только ответы сжимаемых типов размером не меньше порога;
+// ответы, уже сжатые upstream сервисом, и ответы на запросы с Range (докачка выгрузок)
+// передаются без изменений
+func (g *Gateway) compressionMiddleware(next http.Handler) http.Handler {
+	if !g.config.Compression.Enabled {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		// Диапазон байтов относится к несжатому содержимому
+		if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			minSize:        g.config.Compression.MinSize,
+			level:          g.config.Compression.Level,
+		}
+		defer cw.Close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding выбирает кодировку по Accept-Encoding: brotli предпочтительнее gzip
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[name] = q
+	}
+
+	for _, encoding := range []string{"br", "gzip"} {
+		if q, ok := accepted[encoding]; ok && q > 0 {
+			return encoding
+		}
+	}
+	if q, ok := accepted["*"]; ok && q > 0 {
+		return "gzip"
+	}
+	return ""
+}
+
+// isCompressibleType проверяет, имеет ли смысл сжимать содержимое данного типа
+func isCompressibleType(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressWriter буферизует начало ответа до порога размера,
+// после чего решает, сжимать ли ответ
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	level    int
+
+	statusCode int
+	buf        bytes.Buffer
+	decided    bool
+	encoder    io.WriteCloser
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.statusCode == 0 {
+		cw.statusCode = code
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.statusCode == 0 {
+		cw.statusCode = http.StatusOK
+	}
+
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf.Write(p)
+	if cw.buf.Len() >= cw.minSize {
+		if err := cw.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush сбрасывает накопленные данные клиенту (для потоковых ответов)
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.decide(cw.buf.Len() >= cw.minSize)
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close завершает ответ: записывает буферизованные данные и закрывает кодировщик
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.statusCode == 0 && cw.buf.Len() == 0 {
+			return nil
+		}
+		if err := cw.decide(false); err != nil {
+			return err
+		}
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
+
+// decide выбирает режим ответа и отправляет заголовки и буферизованные данные
+func (cw *compressWriter) decide(largeEnough bool) error {
+	cw.decided = true
+	if cw.statusCode == 0 {
+		cw.statusCode = http.StatusOK
+	}
+
+	header := cw.ResponseWriter.Header()
+	compress := largeEnough &&
+		header.Get("Content-Encoding") == "" && // upstream уже сжал ответ
+		cw.statusCode != http.StatusNoContent &&
+		cw.statusCode != http.StatusNotModified &&
+		cw.statusCode != http.StatusPartialContent &&
+		cw.statusCode >= http.StatusOK &&
+		isCompressibleType(header.Get("Content-Type"))
+
+	if compress {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+
+		switch cw.encoding {
+		case "br":
+			cw.encoder = brotli.NewWriterLevel(cw.ResponseWriter, brotliLevel(cw.level))
+		default:
+			gz, err := gzip.NewWriterLevel(cw.ResponseWriter, cw.level)
+			if err != nil {
+				gz = gzip.NewWriter(cw.ResponseWriter)
+			}
+			cw.encoder = gz
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	if cw.buf.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(cw.buf.Bytes())
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf.Bytes())
+	}
+	cw.buf.Reset()
+	return err
+}
+
+// brotliLevel приводит уровень сжатия gzip (1-9) к шкале brotli (0-11)
+func brotliLevel(level int) int {
+	if level <= 0 {
+		return brotli.DefaultCompression
+	}
+	if level > brotli.BestCompression {
+		return brotli.BestCompression
+	}
+	return level
+}
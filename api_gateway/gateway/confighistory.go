@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api_gateway/confighistory"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// effectiveConfig действующие значения параметров шлюза: конфигурация запуска
+// и настройки, измененные через административный API (раздел Runtime)
+func (g *Gateway) effectiveConfig() map[string]string {
+	values := confighistory.Flatten(g.config)
+
+	if g.deps.RateLimiter != nil {
+		routes, fallback := g.deps.RateLimiter.Rules()
+		rules := make([]string, 0, len(routes))
+		for _, rule := range routes {
+			rules = append(rules, rule.String())
+		}
+		values["Runtime.RateLimit.Default"] = fallback.String()
+		values["Runtime.RateLimit.Routes"] = strings.Join(rules, ",")
+	}
+
+	disabled := g.routes.list()
+	toggles := make([]string, 0, len(disabled))
+	for _, toggle := range disabled {
+		toggles = append(toggles, toggle.Method+" "+toggle.Prefix)
+	}
+	values["Runtime.DisabledRoutes"] = strings.Join(toggles, ",")
+	return values
+}
+
+// recordConfig сохраняет снимок действующей конфигурации и пишет в лог изменения
+// относительно предыдущего снимка; первый снимок пишется в лог целиком
+func (g *Gateway) recordConfig(source, actor string) {
+	if g.deps.ConfigHistory == nil {
+		return
+	}
+
+	snapshot, changed := g.deps.ConfigHistory.Record(source, actor, g.effectiveConfig())
+	if !changed {
+		return
+	}
+	if snapshot.Version == 1 {
+		g.logger.Info("Действующая конфигурация",
+			zap.Int("config_version", snapshot.Version),
+			zap.String("source", source),
+			zap.Any("config", snapshot.Values),
+		)
+		return
+	}
+	g.logger.Info("Изменение конфигурации",
+		zap.Int("config_version", snapshot.Version),
+		zap.String("source", source),
+		zap.String("admin_id", actor),
+		zap.Any("changes", snapshot.Changes),
+	)
+}
+
+// adminConfigHistory возвращает снимки конфигурации экземпляра от новых к старым
+// с изменениями относительно предыдущего снимка
+func (g *Gateway) adminConfigHistory(w http.ResponseWriter, r *http.Request) {
+	if g.deps.ConfigHistory == nil {
+		g.respondWithError(w, http.StatusNotFound, "История конфигурации не ведется")
+		return
+	}
+	g.respondWithJSON(w, http.StatusOK, g.deps.ConfigHistory.List())
+}
+
+// adminConfigSnapshot возвращает снимок конфигурации со всеми значениями
+func (g *Gateway) adminConfigSnapshot(w http.ResponseWriter, r *http.Request) {
+	if g.deps.ConfigHistory == nil {
+		g.respondWithError(w, http.StatusNotFound, "История конфигурации не ведется")
+		return
+	}
+
+	version, err := strconv.Atoi(mux.Vars(r)["version"])
+	if err != nil {
+		g.respondWithError(w, http.StatusBadRequest, "Некорректная версия конфигурации")
+		return
+	}
+	snapshot, ok := g.deps.ConfigHistory.Get(version)
+	if !ok {
+		g.respondWithError(w, http.StatusNotFound, "Снимок конфигурации не найден")
+		return
+	}
+	g.respondWithJSON(w, http.StatusOK, snapshot)
+}
@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"api_gateway/logger"
+
+	"go.uber.org/zap"
+)
+
+// csrfMiddleware защищает маршруты AUTH_CSRF_ROUTES от подделки межсайтовых запросов
+// в режимах cookie и сессий. Браузер отправляет cookie refresh токена и сессии автоматически,
+// поэтому изменяющий запрос с такой cookie принимается, только если заголовок AUTH_CSRF_HEADER
+// совпадает с CSRF cookie (double-submit): сторонний сайт не может прочитать cookie
+// и подставить ее значение в заголовок. Запросы без этих cookie не проверяются —
+// они аутентифицируются заголовком Authorization, который браузер сам не добавляет
+func (g *Gateway) csrfMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !g.config.Auth.CSRFEnabled || isSafeMethod(r.Method) || !g.csrfProtected(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !g.hasCredentialCookie(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !g.validCSRFToken(r) {
+			log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+			log.Warn("CSRF token mismatch",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("origin", r.Header.Get("Origin")),
+			)
+			g.respondWithError(w, http.StatusForbidden, "Недействительный CSRF токен")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// hasCredentialCookie проверяет, содержит ли запрос cookie, аутентифицирующую клиента
+func (g *Gateway) hasCredentialCookie(r *http.Request) bool {
+	names := []string{g.config.Auth.RefreshCookieName}
+	if g.config.Auth.SessionMode {
+		names = append(names, g.config.Auth.SessionCookieName)
+	}
+	for _, name := range names {
+		if cookie, err := r.Cookie(name); err == nil && cookie.Value != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// csrfProtected проверяет, защищен ли путь CSRF токеном
+func (g *Gateway) csrfProtected(path string) bool {
+	for _, prefix := range g.config.Auth.CSRFRoutes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validCSRFToken сравнивает CSRF токен из заголовка с CSRF cookie
+func (g *Gateway) validCSRFToken(r *http.Request) bool {
+	cookie, err := r.Cookie(g.config.Auth.CSRFCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+	header := r.Header.Get(g.config.Auth.CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) == 1
+}
+
+// setCSRFToken выдает новый CSRF токен вместе с cookie refresh токена или сессии: в cookie, доступной
+// JavaScript клиента, и в заголовке ответа для клиентов на другом домене.
+// maxAge < 0 удаляет cookie
+func (g *Gateway) setCSRFToken(w http.ResponseWriter, maxAge int) {
+	if !g.config.Auth.CSRFEnabled {
+		return
+	}
+
+	token := ""
+	if maxAge >= 0 {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			g.logger.Error("Failed to generate CSRF token", zap.Error(err))
+			return
+		}
+		token = base64.RawURLEncoding.EncodeToString(buf)
+		w.Header().Set(g.config.Auth.CSRFHeader, token)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     g.config.Auth.CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Domain:   g.config.Auth.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   g.config.Auth.CookieSecure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// isSafeMethod проверяет, что метод не изменяет состояние
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,885 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"sync"
+	"time"
+
+	"api_gateway/accesslog"
+	"api_gateway/cache"
+	"api_gateway/capture"
+	"api_gateway/config"
+	"api_gateway/confighistory"
+	"api_gateway/costcenter"
+	"api_gateway/graphqlapi"
+	"api_gateway/grpcproxy"
+	"api_gateway/jwks"
+	"api_gateway/metrics"
+	"api_gateway/openapi"
+	"api_gateway/quota"
+	"api_gateway/ratelimit"
+	"api_gateway/session"
+	"api_gateway/sla"
+	"api_gateway/timeout"
+	"api_gateway/tracecontext"
+	"api_gateway/upstream"
+	"api_gateway/waf"
+
+	"pkg/buildinfo"
+	"pkg/clients"
+	ordersclient "pkg/clients/orders"
+	usersclient "pkg/clients/users"
+	"pkg/httpmw"
+	"pkg/redact"
+	"pkg/rolesepoch"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/cors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Gateway API Gateway: маршрутизация, middleware и проксирование к микросервисам.
+// Все зависимости передаются через конструктор, поэтому в одном процессе
+// может работать несколько независимых экземпляров
+type Gateway struct {
+	config *config.Config
+	logger *zap.Logger
+	deps   Dependencies
+	// routes маршруты, отключенные через административный API
+	routes *routeToggles
+	// sampledLogger логгер успешных запросов с сэмплированием под нагрузкой
+	sampledLogger *zap.Logger
+	// apiKeys access токены, выданные по персональным API ключам
+	apiKeys *apiKeyCache
+	// users клиент сервиса пользователей для собственных вызовов шлюза (продление
+	// сессий, обмен API ключей) через UserProxy
+	users *usersclient.Client
+}
+
+// Dependencies внешние зависимости Gateway
+type Dependencies struct {
+	UserProxy     http.Handler
+	OrderProxy    http.Handler
+	RateLimiter   *ratelimit.Limiter
+	ResponseCache *cache.ResponseCache
+	// Timeouts предельное время обработки запросов по маршрутам; nil — без ограничения
+	Timeouts *timeout.Table
+
+	// Breakers circuit breakers сервисов по имени (service_users, service_orders)
+	Breakers map[string]*upstream.Breaker
+	// Failovers резервирование сервисов по имени; содержит только сервисы с резервным экземпляром
+	Failovers map[string]*upstream.Failover
+	// Concurrency общий предел одновременных запросов к сервисам; nil — без ограничения
+	Concurrency *upstream.Limiter
+	// Limiters пределы одновременных запросов по сервисам; пустой, если ограничение отключено
+	Limiters map[string]*upstream.Limiter
+
+	// RateLimitMetrics и MetricsHandler равны nil, если метрики отключены
+	RateLimitMetrics *metrics.RateLimitMetrics
+	MetricsHandler   http.Handler
+
+	// KeySet ключи JWKS для RS256/ES256; nil, если разрешен только HMAC
+	KeySet *jwks.KeySet
+
+	// RolesEpochs эпохи ролей пользователей в Redis; nil, если проверка отключена
+	RolesEpochs *rolesepoch.Store
+	// Quotas суточные квоты пользователей в Redis; nil, если квоты отключены
+	Quotas *quota.Store
+	// Sessions сессии браузерных клиентов; nil, если режим сессий отключен
+	Sessions *session.Manager
+	// SLA агрегаты запросов и проверок доступности для отчетов SLA в Redis; nil, если
+	// отчеты отключены. SLAProber равен nil, если проверки доступности отключены
+	SLA       *sla.Recorder
+	SLAProber *sla.Prober
+
+	// WAF фильтр вредоносных запросов; nil, если отключен. WAFMetrics равен nil,
+	// если метрики отключены
+	WAF        *waf.Filter
+	WAFMetrics *metrics.WAFMetrics
+
+	// ProtocolMetrics версии HTTP и повторное использование соединений; nil, если метрики отключены
+	ProtocolMetrics *metrics.ProtocolMetrics
+
+	// AccessLog отдельный JSON журнал доступа; nil, если отключен
+	AccessLog *accesslog.Logger
+	// Capture запись обезличенного профиля трафика; nil, если отключена
+	Capture *capture.Recorder
+
+	// OpenAPI объединенная спецификация API; nil, если файлы спецификации не заданы
+	OpenAPI *openapi.Validator
+
+	// CostCenters классификатор запросов по центрам затрат и накопленное потребление;
+	// nil, если правила не заданы. CostCenterMetrics равен nil, если метрики отключены
+	CostCenters       *costcenter.Classifier
+	CostCenterUsage   *costcenter.Usage
+	CostCenterMetrics *metrics.CostCenterMetrics
+
+	// GraphQL агрегирующий эндпоинт /v1/graphql; nil, если не используется
+	GraphQL http.Handler
+	// Overview сводка пользователя /v1/users/{id}/overview; nil, если не используется
+	Overview http.Handler
+
+	// ConfigHistory снимки действующей конфигурации; nil, если история не ведется
+	ConfigHistory *confighistory.History
+
+	// GRPCEndpoints маршруты к gRPC сервисам с транскодированием JSON
+	GRPCEndpoints []*grpcproxy.Endpoint
+	// Closers ресурсы, освобождаемые при остановке сервера
+	Closers []io.Closer
+}
+
+// New создает новый Gateway с переданными зависимостями
+func New(cfg *config.Config, logger *zap.Logger, deps Dependencies) *Gateway {
+	sampledLogger := logger
+	if cfg.RequestLog.SampleInitial > 0 {
+		sampledLogger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewSamplerWithOptions(core, time.Second, cfg.RequestLog.SampleInitial, cfg.RequestLog.SampleThereafter)
+		}))
+	}
+
+	return &Gateway{
+		config:        cfg,
+		logger:        logger,
+		deps:          deps,
+		routes:        &routeToggles{},
+		sampledLogger: sampledLogger,
+		apiKeys:       newAPIKeyCache(),
+		users:         usersclient.New(clients.Config{HTTP: clients.HandlerDoer(deps.UserProxy)}),
+	}
+}
+
+// NewFromConfig создает Gateway с зависимостями по умолчанию, построенными из конфигурации
+func NewFromConfig(cfg *config.Config, logger *zap.Logger) (*Gateway, error) {
+	var err error
+	var registry *prometheus.Registry
+	var upstreamMetrics *metrics.UpstreamMetrics
+	var protocolMetrics *metrics.ProtocolMetrics
+	if cfg.Metrics.Enabled {
+		registry = prometheus.NewRegistry()
+		registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+
+		if upstreamMetrics, err = metrics.NewUpstreamMetrics(registry); err != nil {
+			return nil, fmt.Errorf("ошибка регистрации метрик: %v", err)
+		}
+		if protocolMetrics, err = metrics.NewProtocolMetrics(registry); err != nil {
+			return nil, fmt.Errorf("ошибка регистрации метрик: %v", err)
+		}
+	}
+
+	userProxy, userFailover, err := newServiceProxy(cfg, "service_users", cfg.Services.UsersURL, cfg.Services.UsersReplicaURLs, cfg.Services.UsersFallbackURL, cfg.Services.UsersCanary, upstreamMetrics, protocolMetrics, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	orderProxy, orderFailover, err := newServiceProxy(cfg, "service_orders", cfg.Services.OrdersURL, cfg.Services.OrdersReplicaURLs, cfg.Services.OrdersFallbackURL, cfg.Services.OrdersCanary, upstreamMetrics, protocolMetrics, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	// Breaker снаружи канареечного разделения: размыкается при недоступности сервиса
+	// независимо от версии; GraphQL обращается к сервисам через те же breakers
+	userBreaker := upstream.NewBreaker("service_users", userProxy, cfg.Services.Breaker.Failures, cfg.Services.Breaker.OpenTimeout, logger)
+	orderBreaker := upstream.NewBreaker("service_orders", orderProxy, cfg.Services.Breaker.Failures, cfg.Services.Breaker.OpenTimeout, logger)
+
+	// Ограничители одновременных запросов снаружи breaker: запрос, отклоненный
+	// при перегрузке, не считается недоступностью сервиса
+	var users, orders http.Handler = userBreaker, orderBreaker
+	limiters := make(map[string]*upstream.Limiter)
+	if limit := cfg.Concurrency.UpstreamMaxInFlight; limit > 0 {
+		limiters["service_users"] = upstream.NewLimiter("service_users", limit, cfg.Concurrency.QueueSize, cfg.Concurrency.QueueTimeout, logger)
+		limiters["service_orders"] = upstream.NewLimiter("service_orders", limit, cfg.Concurrency.QueueSize, cfg.Concurrency.QueueTimeout, logger)
+		users = limiters["service_users"].Wrap(users)
+		orders = limiters["service_orders"].Wrap(orders)
+	}
+
+	// Агрегирующие эндпоинты вызывают сервисы от имени клиента: заголовки запроса
+	// передаются прокси без изменений
+	usersClient := usersclient.New(clients.Config{HTTP: clients.HandlerDoer(users), Auth: clients.ForwardRequest})
+	ordersClient := ordersclient.New(clients.Config{HTTP: clients.HandlerDoer(orders), Auth: clients.ForwardRequest})
+
+	graphQL, err := graphqlapi.NewHandler(usersClient, ordersClient)
+	if err != nil {
+		return nil, err
+	}
+
+	timeouts := timeout.NewTable(cfg.Timeout.Routes, cfg.Timeout.Default)
+	deps := Dependencies{
+		UserProxy:     users,
+		OrderProxy:    orders,
+		ResponseCache: cache.NewResponseCache(cfg.Cache.Routes, cfg.Cache.MaxEntries, timeouts.For, logger),
+		Timeouts:      timeouts,
+		Breakers: map[string]*upstream.Breaker{
+			"service_users":  userBreaker,
+			"service_orders": orderBreaker,
+		},
+		Failovers: make(map[string]*upstream.Failover),
+		GraphQL:   graphQL,
+		Overview:  graphqlapi.NewOverviewHandler(usersClient, ordersClient),
+		Limiters:  limiters,
+
+		ProtocolMetrics: protocolMetrics,
+	}
+	if limit := cfg.Concurrency.MaxInFlight; limit > 0 {
+		deps.Concurrency = upstream.NewLimiter("gateway", limit, cfg.Concurrency.QueueSize, cfg.Concurrency.QueueTimeout, logger)
+	}
+	if userFailover != nil {
+		deps.Failovers["service_users"] = userFailover
+	}
+	if orderFailover != nil {
+		deps.Failovers["service_orders"] = orderFailover
+	}
+
+	if cfg.AccessLog.Enabled {
+		deps.AccessLog, err = accesslog.New(cfg.AccessLog.Output, cfg.AccessLog.BufferSize)
+		if err != nil {
+			return nil, err
+		}
+		deps.Closers = append(deps.Closers, deps.AccessLog)
+	}
+
+	if cfg.Capture.Enabled {
+		deps.Capture, err = capture.New(cfg.Capture.File, cfg.Capture.BufferSize, cfg.Capture.MaxRecords)
+		if err != nil {
+			return nil, err
+		}
+		deps.Closers = append(deps.Closers, deps.Capture)
+		logger.Info("Запись профиля трафика включена", zap.String("file", cfg.Capture.File))
+	}
+
+	if len(cfg.OpenAPI.SpecFiles) > 0 {
+		deps.OpenAPI, err = openapi.Load(cfg.OpenAPI.SpecFiles)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Спецификация OpenAPI загружена",
+			zap.Strings("files", cfg.OpenAPI.SpecFiles),
+			zap.Bool("validate", cfg.OpenAPI.Validate),
+		)
+	}
+
+	if cfg.WAF.Enabled {
+		deps.WAF = waf.NewFilter(cfg.WAF.Rules, cfg.WAF.MaxHeaderBytes, cfg.WAF.BodyInspectBytes, cfg.WAF.SkipFields)
+		logger.Info("Фильтр запросов включен",
+			zap.Strings("rules", deps.WAF.Rules()),
+			zap.Bool("detect_only", cfg.WAF.DetectOnly),
+		)
+	}
+
+	if len(cfg.CostCenter.Rules) > 0 {
+		deps.CostCenters = costcenter.NewClassifier(cfg.CostCenter.Rules, cfg.CostCenter.Default)
+		deps.CostCenterUsage = costcenter.NewUsage()
+	}
+
+	if len(cfg.GRPC.Routes) > 0 {
+		transcoder, err := grpcproxy.NewTranscoder(cfg.GRPC.DescriptorSet, cfg.GRPC.Timeout, cfg.GRPC.UpstreamTLS)
+		if err != nil {
+			return nil, err
+		}
+		deps.Closers = append(deps.Closers, transcoder)
+
+		for _, route := range cfg.GRPC.Routes {
+			endpoint, err := transcoder.Endpoint(route)
+			if err != nil {
+				return nil, err
+			}
+			deps.GRPCEndpoints = append(deps.GRPCEndpoints, endpoint)
+		}
+	}
+
+	if registry != nil {
+		deps.RateLimitMetrics, err = metrics.NewRateLimitMetrics(registry, cfg.Metrics.RateLimitTopK, cfg.Metrics.RateLimitHashKeys)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка регистрации метрик: %v", err)
+		}
+		if deps.CostCenters != nil {
+			if deps.CostCenterMetrics, err = metrics.NewCostCenterMetrics(registry); err != nil {
+				return nil, fmt.Errorf("ошибка регистрации метрик: %v", err)
+			}
+		}
+		if deps.WAF != nil {
+			if deps.WAFMetrics, err = metrics.NewWAFMetrics(registry); err != nil {
+				return nil, fmt.Errorf("ошибка регистрации метрик: %v", err)
+			}
+		}
+		deps.MetricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+
+		if accessLog := deps.AccessLog; accessLog != nil {
+			registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Namespace: "gateway",
+				Subsystem: "access_log",
+				Name:      "dropped_total",
+				Help:      "Записи журнала доступа, отброшенные из-за переполнения буфера или ошибки записи",
+			}, func() float64 {
+				return float64(accessLog.Dropped())
+			}))
+		}
+
+		if err := registerLimiterMetrics(registry, deps); err != nil {
+			return nil, fmt.Errorf("ошибка регистрации метрик: %v", err)
+		}
+
+		if recorder := deps.Capture; recorder != nil {
+			registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Namespace: "gateway",
+				Subsystem: "traffic_capture",
+				Name:      "dropped_total",
+				Help:      "Записи профиля трафика, отброшенные из-за переполнения буфера или ошибки записи",
+			}, func() float64 {
+				return float64(recorder.Dropped())
+			}))
+		}
+	}
+
+	if cfg.Redis.Host != "" {
+		// TTL не используется: Gateway только читает эпохи
+		client := rolesepoch.NewRedisClient(net.JoinHostPort(cfg.Redis.Host, cfg.Redis.Port), cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.Timeout)
+		deps.RolesEpochs = rolesepoch.NewStore(client, 0)
+		deps.Closers = append(deps.Closers, deps.RolesEpochs)
+	}
+
+	if cfg.Quota.DailyLimit > 0 {
+		client := rolesepoch.NewRedisClient(net.JoinHostPort(cfg.Redis.Host, cfg.Redis.Port), cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.Timeout)
+		deps.Quotas = quota.NewStore(client, cfg.Quota.DailyLimit)
+		deps.Closers = append(deps.Closers, deps.Quotas)
+	}
+
+	if cfg.SLA.Enabled {
+		client := rolesepoch.NewRedisClient(net.JoinHostPort(cfg.Redis.Host, cfg.Redis.Port), cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.Timeout)
+		deps.SLA = sla.NewRecorder(client, cfg.SLA.FlushInterval, cfg.SLA.Retention, logger)
+		deps.Closers = append(deps.Closers, deps.SLA)
+		if cfg.SLA.CheckInterval > 0 {
+			services := map[string]http.Handler{"service_users": users, "service_orders": orders}
+			deps.SLAProber = sla.NewProber(deps.SLA, services, cfg.SLA.CheckPath, cfg.SLA.CheckInterval, cfg.SLA.CheckTimeout, logger)
+		}
+	}
+
+	if cfg.JWT.JWKSURL != "" {
+		deps.KeySet = jwks.NewKeySet(cfg.JWT.JWKSURL, cfg.JWT.JWKSRefreshInterval, nil)
+	}
+
+	// Ключ лимита пользователя берется из токена, проверенного ключами Gateway
+	g := New(cfg, logger, deps)
+	g.deps.RateLimiter = ratelimit.New(cfg.RateLimit.Routes, cfg.RateLimit.Default(), g.rateLimitKey)
+
+	// Сессии продлеваются через прокси сервиса пользователей этого же Gateway
+	if cfg.Auth.SessionMode {
+		store := session.NewMemoryStore()
+		if cfg.Redis.Host != "" {
+			client := rolesepoch.NewRedisClient(net.JoinHostPort(cfg.Redis.Host, cfg.Redis.Port), cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.Timeout)
+			store = session.NewRedisStore(client)
+		} else {
+			logger.Warn("Сессии хранятся в памяти экземпляра: задайте REDIS_HOST для нескольких экземпляров Gateway")
+		}
+		g.deps.Sessions = session.NewManager(store, cfg.Auth.SessionTTL, cfg.Auth.SessionRenewBefore, g.renewSession)
+		g.deps.Closers = append(g.deps.Closers, g.deps.Sessions)
+	}
+
+	g.deps.ConfigHistory = confighistory.New(cfg.ConfigHistory.Size)
+	g.recordConfig("startup", "")
+	return g, nil
+}
+
+// newServiceProxy создает reverse proxy к сервису; при заданном URL канареечной версии
+// запросы распределяются между основной и канареечной версиями. При заданном fallbackURL
+// основная версия резервируется вторым экземпляром, и возвращается его Failover.
+// При заданных replicaURLs основная версия состоит из нескольких реплик, между которыми
+// запросы распределяются по хешу пользователя; резервный экземпляр подменяет только rawURL
+func newServiceProxy(cfg *config.Config, name, rawURL string, replicaURLs []string, fallbackURL string, canary config.CanaryConfig, upstreamMetrics *metrics.UpstreamMetrics, protocolMetrics *metrics.ProtocolMetrics, logger *zap.Logger) (http.Handler, *upstream.Failover, error) {
+	// Каждый экземпляр получает собственный транспорт и пул соединений
+	newUpstream := func(name, rawURL string) (*upstream.Upstream, error) {
+		return upstream.New(name, rawURL, newProxyTransport(cfg.Services.Transport), cfg.Services.DNSRefreshInterval, protocolMetrics, logger)
+	}
+
+	primary, err := newUpstream(name, rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stable http.Handler = primary
+	var failover *upstream.Failover
+	if fallbackURL != "" {
+		secondary, err := newUpstream(name+"_secondary", fallbackURL)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		policy := cfg.Services.Failover
+		failover = upstream.NewFailover(name, primary, secondary, policy.Failures, upstream.HealthCheck{
+			Interval: policy.CheckInterval,
+			Timeout:  policy.CheckTimeout,
+			Path:     policy.HealthPath,
+		}, logger)
+		stable = failover
+
+		logger.Info("Резервный экземпляр сервиса настроен",
+			zap.String("service", name),
+			zap.String("fallback_url", fallbackURL),
+			zap.Int("failures", policy.Failures),
+			zap.Duration("check_interval", policy.CheckInterval),
+		)
+	}
+
+	if len(replicaURLs) > 0 {
+		replicas := []http.Handler{stable}
+		for i, replicaURL := range replicaURLs {
+			replica, err := newUpstream(fmt.Sprintf("%s_replica%d", name, i+1), replicaURL)
+			if err != nil {
+				return nil, nil, err
+			}
+			replicas = append(replicas, replica)
+		}
+		stable = upstream.NewRing(replicas, append([]string{rawURL}, replicaURLs...), affinityKey)
+
+		logger.Info("Реплики сервиса настроены",
+			zap.String("service", name),
+			zap.Strings("replica_urls", replicaURLs),
+		)
+	}
+
+	if canary.URL == "" {
+		if upstreamMetrics == nil {
+			return stable, failover, nil
+		}
+		// Без канарейки метрики upstream тоже пишутся, с target="stable"
+		return upstream.NewSplit(name, stable, nil, 0, "", upstreamMetrics), failover, nil
+	}
+
+	canaryProxy, err := newUpstream(name+"_canary", canary.URL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logger.Info("Канареечная версия сервиса включена",
+		zap.String("service", name),
+		zap.String("canary_url", canary.URL),
+		zap.Int("weight", canary.Weight),
+		zap.String("override_header", cfg.Services.CanaryHeader),
+	)
+	return upstream.NewSplit(name, stable, canaryProxy, canary.Weight, cfg.Services.CanaryHeader, upstreamMetrics), failover, nil
+}
+
+// registerLimiterMetrics регистрирует метрики ограничителей одновременных запросов
+// с меткой limiter (gateway или имя сервиса)
+func registerLimiterMetrics(registry *prometheus.Registry, deps Dependencies) error {
+	limiters := make([]*upstream.Limiter, 0, len(deps.Limiters)+1)
+	if deps.Concurrency != nil {
+		limiters = append(limiters, deps.Concurrency)
+	}
+	for _, limiter := range deps.Limiters {
+		limiters = append(limiters, limiter)
+	}
+
+	for _, limiter := range limiters {
+		labels := prometheus.Labels{"limiter": limiter.State().Name}
+		limiterCollectors := []prometheus.Collector{
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace:   "gateway",
+				Subsystem:   "concurrency",
+				Name:        "in_flight",
+				Help:        "Запросы, выполняемые в данный момент",
+				ConstLabels: labels,
+			}, func() float64 {
+				return float64(limiter.State().InFlight)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace:   "gateway",
+				Subsystem:   "concurrency",
+				Name:        "waiting",
+				Help:        "Запросы, ожидающие свободного места",
+				ConstLabels: labels,
+			}, func() float64 {
+				return float64(limiter.State().Waiting)
+			}),
+			prometheus.NewCounterFunc(prometheus.CounterOpts{
+				Namespace:   "gateway",
+				Subsystem:   "concurrency",
+				Name:        "rejected_total",
+				Help:        "Запросы, отклоненные с 503 из-за превышения предела одновременных запросов",
+				ConstLabels: labels,
+			}, func() float64 {
+				return float64(limiter.State().Rejected)
+			}),
+		}
+		for _, collector := range limiterCollectors {
+			if err := registry.Register(collector); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Run запускает фоновые задачи зависимостей (например, переразрешение DNS upstream сервисов,
+// запись агрегатов SLA), пока не будет отменен ctx
+func (g *Gateway) Run(ctx context.Context) {
+	deps := []interface{}{g.deps.UserProxy, g.deps.OrderProxy}
+	if g.deps.SLA != nil {
+		deps = append(deps, g.deps.SLA)
+	}
+	if g.deps.SLAProber != nil {
+		deps = append(deps, g.deps.SLAProber)
+	}
+
+	var wg sync.WaitGroup
+	for _, dep := range deps {
+		if runner, ok := dep.(interface{ Run(context.Context) }); ok {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runner.Run(ctx)
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// Server HTTP(S) сервер API Gateway. При включенном TLS дополнительно
+// слушает HTTP порт для редиректа на HTTPS и ACME http-01
+type Server struct {
+	*http.Server
+	httpServer    *http.Server
+	metricsServer *http.Server
+	closers       []io.Closer
+	cancel        context.CancelFunc
+	logger        *zap.Logger
+}
+
+// NewServer создает сервер API Gateway по конфигурации
+func NewServer(cfg *config.Config, zapLogger *zap.Logger) (*Server, error) {
+	gw, err := NewFromConfig(cfg, zapLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server := &Server{
+		Server: &http.Server{
+			Addr:        ":" + cfg.Server.Port,
+			Handler:     gw.Handler(),
+			IdleTimeout: cfg.Server.IdleTimeout,
+			Protocols:   serverProtocols(cfg.Server),
+			HTTP2: &http.HTTP2Config{
+				MaxConcurrentStreams: cfg.Server.HTTP2MaxConcurrentStreams,
+			},
+			ConnState: gw.deps.ProtocolMetrics.ConnState,
+		},
+		closers: gw.deps.Closers,
+		cancel:  cancel,
+		logger:  zapLogger,
+	}
+	go gw.Run(ctx)
+
+	// Метрики на отдельном порту, чтобы не публиковать их наружу вместе с API
+	if handler := gw.deps.MetricsHandler; handler != nil && cfg.Metrics.Port != "" {
+		mux := http.NewServeMux()
+		mux.Handle(cfg.Metrics.Path, handler)
+		server.metricsServer = &http.Server{
+			Addr:    ":" + cfg.Metrics.Port,
+			Handler: mux,
+		}
+	}
+
+	if !cfg.TLS.Enabled {
+		return server, nil
+	}
+
+	setup, err := newTLSSetup(cfg.TLS, cfg.Server.Port, zapLogger)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	server.TLSConfig = setup.config
+
+	if cfg.TLS.HTTPPort != "" {
+		server.httpServer = &http.Server{
+			Addr:    ":" + cfg.TLS.HTTPPort,
+			Handler: setup.httpHandler,
+		}
+	}
+
+	if setup.run != nil {
+		go setup.run(ctx)
+	}
+
+	return server, nil
+}
+
+// serverProtocols возвращает версии HTTP, принимаемые от клиентов. HTTP/2 через TLS
+// согласуется по ALPN, поэтому без TLS_ENABLED работает только h2c
+func serverProtocols(cfg config.ServerConfig) *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(cfg.HTTP2)
+	protocols.SetUnencryptedHTTP2(cfg.H2C)
+	return protocols
+}
+
+// ListenAndServe запускает сервер: HTTPS, если настроен TLS, иначе HTTP
+func (s *Server) ListenAndServe() error {
+	if s.metricsServer != nil {
+		go func() {
+			if err := s.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				s.logger.Error("Ошибка HTTP сервера метрик", zap.Error(err))
+			}
+		}()
+	}
+
+	if s.TLSConfig == nil {
+		return s.Server.ListenAndServe()
+	}
+
+	if s.httpServer != nil {
+		go func() {
+			if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				s.logger.Error("Ошибка HTTP сервера редиректа", zap.Error(err))
+			}
+		}()
+	}
+
+	// Сертификаты берутся из TLSConfig (GetCertificate)
+	return s.Server.ListenAndServeTLS("", "")
+}
+
+// Shutdown корректно останавливает серверы, фоновую перезагрузку сертификатов
+// и освобождает ресурсы (журнал доступа, gRPC соединения, Redis). Ресурсы
+// освобождаются, даже если запросы не завершились до истечения ctx
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	var lastErr error
+	for _, server := range []*http.Server{s.httpServer, s.metricsServer, s.Server} {
+		if server == nil {
+			continue
+		}
+		if err := server.Shutdown(ctx); err != nil {
+			lastErr = err
+		}
+	}
+	for _, closer := range s.closers {
+		if err := closer.Close(); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// Handler возвращает корневой HTTP обработчик со всеми маршрутами и middleware
+func (g *Gateway) Handler() http.Handler {
+	router := mux.NewRouter()
+
+	// Шаблон маршрута для лога запросов
+	router.Use(g.routeMiddleware)
+
+	// Фильтр вредоносных запросов до любой обработки запроса
+	router.Use(g.wafMiddleware)
+
+	// Токен сессии из cookie; до ограничения частоты, чтобы лимит учитывал пользователя
+	router.Use(g.sessionMiddleware)
+
+	// Токен персонального API ключа; тоже до ограничения частоты
+	router.Use(g.apiKeyMiddleware)
+
+	// Middleware для ограничения частоты запросов
+	router.Use(g.rateLimitMiddleware)
+
+	// Маршруты, отключенные администратором
+	router.Use(g.routeToggleMiddleware)
+
+	// CSRF токен для изменяющих запросов с cookie refresh токена или сессии
+	router.Use(g.csrfMiddleware)
+
+	// Таймаут запроса: отменяет обращение к зависшему сервису
+	router.Use(mux.MiddlewareFunc(httpmw.Timeout(httpmw.TimeoutConfig{Timeout: g.routeTimeout})))
+
+	// Публичные маршруты (регистрация, вход, вход через провайдеров, сброс пароля, подтверждение email, требования к паролю, обновление и отзыв токена)
+	router.HandleFunc("/v1/users/register", g.proxyToUsersService).Methods("POST")
+	router.HandleFunc("/v1/users/password-reset/request", g.proxyToUsersService).Methods("POST")
+	router.HandleFunc("/v1/users/password-reset/confirm", g.proxyToUsersService).Methods("POST")
+	router.HandleFunc("/v1/users/profile/email/confirm", g.proxyToUsersService).Methods("POST")
+	router.HandleFunc("/v1/users/password-policy", g.proxyToUsersService).Methods("GET")
+	router.Handle("/v1/users/login", g.sessionLoginMiddleware(g.refreshCookieMiddleware(http.HandlerFunc(g.proxyToUsersService)))).Methods("POST")
+	router.Handle(refreshPath, g.refreshCookieMiddleware(http.HandlerFunc(g.proxyToUsersService))).Methods("POST")
+	router.Handle(revokePath, g.refreshCookieMiddleware(http.HandlerFunc(g.proxyToUsersService))).Methods("POST")
+	// Вход через Google и GitHub: callback выдает токены так же, как вход по паролю
+	router.HandleFunc("/v1/users/oauth/{provider}/start", g.proxyToUsersService).Methods("GET")
+	router.Handle("/v1/users/oauth/{provider}/callback", g.sessionLoginMiddleware(g.refreshCookieMiddleware(http.HandlerFunc(g.proxyToUsersService)))).Methods("GET")
+	// Публичные ключи подписи токенов service_users (RS256, ES256) для внешних сервисов
+	router.HandleFunc("/.well-known/jwks.json", g.proxyToUsersService).Methods("GET")
+	if g.deps.Sessions != nil {
+		router.HandleFunc("/v1/auth/logout", g.logout).Methods("POST")
+	}
+
+	// Объединенная спецификация API шлюза и сервисов
+	if g.deps.OpenAPI != nil {
+		router.HandleFunc("/v1/openapi.json", g.serveOpenAPISpec).Methods("GET")
+	}
+	if g.deps.OpenAPI != nil && g.config.OpenAPI.SwaggerUI {
+		router.HandleFunc("/docs", g.serveSwaggerUI).Methods("GET")
+	}
+
+	// Версия сборки шлюза для проверки расхождения версий (cmd/doctor)
+	router.HandleFunc("/version", buildinfo.Handler("api_gateway")).Methods("GET")
+
+	// Статус заказа по ссылке отслеживания (без входа; токен проверяет service_orders)
+	router.HandleFunc("/v1/track/{token}", g.proxyToOrdersService).Methods("GET")
+
+	// Изменения складских остатков от складских систем (подпись проверяет service_orders)
+	router.HandleFunc("/v1/inventory/stock-webhook", g.proxyToOrdersService).Methods("POST")
+
+	// Защищенные маршруты
+	subrouter := router.PathPrefix("/v1").Subrouter()
+	subrouter.Use(g.jwtAuthMiddleware) // JWT аутентификация для защищенных маршрутов
+	subrouter.Use(g.quotaMiddleware)   // Суточные квоты аутентифицированных пользователей
+
+	// Маршруты к gRPC сервисам (регистрируются до префиксов HTTP сервисов и имеют приоритет)
+	for _, endpoint := range g.deps.GRPCEndpoints {
+		path := strings.TrimPrefix(endpoint.Route.Path, "/v1")
+		subrouter.Handle(path, g.proxyToGRPCService(endpoint)).Methods(endpoint.Route.HTTPMethod)
+	}
+
+	// Административный API шлюза (только для администраторов)
+	admin := subrouter.PathPrefix("/admin/gateway").Subrouter()
+	admin.Use(g.adminOnlyMiddleware)
+	admin.HandleFunc("/state", g.adminState).Methods("GET")
+	admin.HandleFunc("/rate-limits", g.adminUpdateRateLimits).Methods("PUT")
+	admin.HandleFunc("/routes", g.adminToggleRoute).Methods("PUT")
+	admin.HandleFunc("/cache/flush", g.adminFlushCache).Methods("POST")
+	admin.HandleFunc("/breakers/{service}/trip", g.adminTripBreaker).Methods("POST")
+	admin.HandleFunc("/breakers/{service}/reset", g.adminResetBreaker).Methods("POST")
+	admin.HandleFunc("/usage", g.adminUsage).Methods("GET")
+	admin.HandleFunc("/usage/reset", g.adminResetUsage).Methods("POST")
+	admin.HandleFunc("/quotas/{user_id}", g.adminQuota).Methods("GET")
+	admin.HandleFunc("/quotas/{user_id}/reset", g.adminResetQuota).Methods("POST")
+	admin.HandleFunc("/config/history", g.adminConfigHistory).Methods("GET")
+	admin.HandleFunc("/sla", g.adminSLAReport).Methods("GET")
+	admin.HandleFunc("/config/history/{version:[0-9]+}", g.adminConfigSnapshot).Methods("GET")
+
+	// GraphQL запросы, объединяющие данные service_users и service_orders
+	if g.deps.GraphQL != nil {
+		subrouter.Handle("/graphql", g.proxyToAggregator("graphql", g.deps.GraphQL)).Methods("GET", "POST")
+	}
+
+	// Сводка пользователя (профиль и последние заказы) одним запросом
+	if g.deps.Overview != nil {
+		subrouter.Handle("/users/{id}/overview", g.proxyToAggregator("overview", g.deps.Overview)).Methods("GET")
+	}
+
+	// Маршруты для сервиса пользователей (защищенные)
+	subrouter.PathPrefix("/users").Handler(http.HandlerFunc(g.proxyToUsersService))
+
+	// Маршруты для сервиса заказов (защищенные)
+	subrouter.PathPrefix("/orders").Handler(g.deps.ResponseCache.Middleware(http.HandlerFunc(g.proxyToOrdersService)))
+
+	// Администрирование исходящих доставок (обрабатывается service_orders)
+	subrouter.PathPrefix("/admin/deliveries").Handler(http.HandlerFunc(g.proxyToOrdersService))
+
+	// Рассылки объявлений сегментам пользователей (обрабатывается service_orders)
+	subrouter.PathPrefix("/admin/broadcasts").Handler(http.HandlerFunc(g.proxyToOrdersService))
+
+	// Очередь заказов операторов (обрабатывается service_orders)
+	subrouter.PathPrefix("/admin/orders").Handler(http.HandlerFunc(g.proxyToOrdersService))
+
+	// Сохраненные наборы фильтров списка заказов (обрабатывается service_orders)
+	subrouter.PathPrefix("/admin/order-filters").Handler(http.HandlerFunc(g.proxyToOrdersService))
+
+	// Администрирование обработчиков доменных событий (обрабатывается service_orders)
+	subrouter.PathPrefix("/admin/event-handlers").Handler(http.HandlerFunc(g.proxyToOrdersService))
+
+	// Синхронизация пользователей с внешним каталогом (обрабатывается service_users)
+	subrouter.PathPrefix("/admin/directory-sync").Handler(http.HandlerFunc(g.proxyToUsersService))
+
+	// Удаление данных пользователей администратором (обрабатывается service_users)
+	subrouter.PathPrefix("/admin/users").Handler(http.HandlerFunc(g.proxyToUsersService))
+
+	// Роли и права ролей (обрабатывается service_users)
+	subrouter.PathPrefix("/admin/roles").Handler(http.HandlerFunc(g.proxyToUsersService))
+	subrouter.PathPrefix("/admin/permissions").Handler(http.HandlerFunc(g.proxyToUsersService))
+
+	// Запрещенные и одноразовые домены email (обрабатывается service_users)
+	subrouter.PathPrefix("/admin/email-domains").Handler(http.HandlerFunc(g.proxyToUsersService))
+
+	// Черный список покупателей (обрабатывается service_users, заказы проверяет service_orders)
+	subrouter.PathPrefix("/admin/blacklist").Handler(http.HandlerFunc(g.proxyToUsersService))
+
+	// Отчет о пересмотре доступа привилегированных пользователей (обрабатывается service_users)
+	subrouter.PathPrefix("/admin/access-review").Handler(http.HandlerFunc(g.proxyToUsersService))
+
+	// Просмотр заказов и пользователей для поддержки с маскированием персональных данных
+	subrouter.PathPrefix("/support/orders").Handler(http.HandlerFunc(g.proxyToOrdersService))
+	subrouter.PathPrefix("/support/users").Handler(http.HandlerFunc(g.proxyToUsersService))
+
+	// CORS Middleware
+	// Range, If-Range, Content-Location и Content-Range нужны для докачки выгрузок
+	allowedHeaders := []string{"Authorization", "Content-Type", "X-Request-ID", "Range", "If-Range",
+		tracecontext.HeaderTraceParent, tracecontext.HeaderTraceState, g.config.Services.CanaryHeader}
+	exposedHeaders := []string{"X-Request-ID", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After",
+		"X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset", "Content-Location", "Content-Range", "Content-Disposition", "ETag", redact.HeaderRedacted}
+	if g.config.Auth.CSRFEnabled {
+		allowedHeaders = append(allowedHeaders, g.config.Auth.CSRFHeader)
+		exposedHeaders = append(exposedHeaders, g.config.Auth.CSRFHeader)
+	}
+	c := cors.New(cors.Options{
+		AllowedOrigins:   g.config.CORS.AllowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   allowedHeaders,
+		ExposedHeaders:   exposedHeaders,
+		AllowCredentials: true,
+		MaxAge:           300, // 5 минут
+	})
+
+	// Лог запросов снаружи сжатия и CORS: учитывает итоговый размер ответа и preflight запросы.
+	// X-Request-ID снаружи CORS и роутера: назначается и ответам, не дошедшим до маршрута.
+	// Центр затрат назначается после X-Request-ID, чтобы попасть в baggage трассы.
+	// Входящие X-User-* удаляются до любой обработки, включая лог запросов.
+	// Panic перехватывается внутри X-Request-ID и лога запросов: ответ 500 получает
+	// идентификатор запроса и попадает в лог с итоговым статусом
+	handler := httpmw.NewChain(
+		g.protocolMetricsMiddleware,
+		g.loggingMiddleware(),
+		g.costCenterMetricsMiddleware(),
+		httpmw.RequestID(httpmw.RequestIDConfig{}),
+		g.stripUserHeadersMiddleware,
+		g.traceContextMiddleware,
+		g.recoveryMiddleware(),
+		g.costCenterMiddleware,
+		c.Handler,
+		g.compressionMiddleware,
+	).Then(router)
+
+	// Метрики на основном порту, если не задан отдельный, и отладочные эндпоинты; без rate limit и JWT
+	metricsOnMainPort := g.deps.MetricsHandler != nil && g.config.Metrics.Port == ""
+	if !metricsOnMainPort && !g.config.Debug.Enabled {
+		return g.securityHeadersMiddleware(handler)
+	}
+
+	root := http.NewServeMux()
+	if metricsOnMainPort {
+		root.Handle(g.config.Metrics.Path, g.deps.MetricsHandler)
+	}
+	if g.config.Debug.Enabled {
+		root.HandleFunc("/debug/pprof/", pprof.Index)
+		root.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		root.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		root.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		root.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	root.Handle("/", handler)
+
+	// Заголовки безопасности снаружи всего: добавляются и к метрикам, и к отладочным эндпоинтам
+	return g.securityHeadersMiddleware(root)
+}
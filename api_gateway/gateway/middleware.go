@@ -0,0 +1,589 @@
+package gateway
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"api_gateway/accesslog"
+	"api_gateway/logger"
+	"api_gateway/metrics"
+	"api_gateway/ratelimit"
+	"api_gateway/tracecontext"
+
+	"pkg/httpmw"
+	"pkg/rbac"
+	"pkg/servertiming"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// JWTClaims представляет claims для JWT токена
+type JWTClaims struct {
+	UserID uuid.UUID `json:"user_id"`
+	Email  string    `json:"email"`
+	Roles  []string  `json:"roles"`
+	// RolesEpoch эпоха ролей на момент выдачи токена
+	RolesEpoch int64 `json:"roles_epoch"`
+	// Scopes области API ключа, по которому выдан токен; пусто у токенов входа
+	Scopes []string `json:"scopes,omitempty"`
+	// Permissions права ролей пользователя на момент выдачи токена
+	Permissions []string `json:"permissions,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// userHeaderPrefix префикс заголовков пользовательского контекста, которым доверяют сервисы
+const userHeaderPrefix = "X-User-"
+
+// stripUserHeadersMiddleware удаляет входящие заголовки X-User-*: на границе доверия
+// их может установить только jwtAuthMiddleware по проверенному токену. Иначе клиент
+// мог бы передать сервисам чужой X-User-ID или роль admin на публичных маршрутах
+func (g *Gateway) stripUserHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var stripped []string
+		for name := range r.Header {
+			if strings.HasPrefix(name, userHeaderPrefix) {
+				stripped = append(stripped, name)
+				r.Header.Del(name)
+			}
+		}
+
+		if len(stripped) > 0 {
+			log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+			log.Warn("Inbound user context headers stripped",
+				zap.Strings("headers", stripped),
+				zap.String("path", r.URL.Path),
+				zap.String("client_ip", clientIP(r)),
+			)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// jwtAuthMiddleware middleware для проверки JWT токена и передачи пользовательского контекста.
+// Время проверки учитывается в этапе auth
+func (g *Gateway) jwtAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stopAuth := sync.OnceFunc(servertiming.FromContext(r.Context()).Start(stageAuth))
+		defer stopAuth()
+
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			g.respondWithError(w, http.StatusUnauthorized, "Требуется токен авторизации")
+			return
+		}
+
+		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
+
+		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, g.jwtKeyFunc, jwt.WithValidMethods(g.config.JWT.Algorithms))
+
+		if err != nil {
+			g.respondWithError(w, http.StatusUnauthorized, fmt.Sprintf("Недействительный токен: %v", err))
+			return
+		}
+
+		if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+			current, err := g.rolesEpochCurrent(r, claims)
+			if err != nil {
+				g.respondWithError(w, http.StatusServiceUnavailable, "Не удалось проверить актуальность токена")
+				return
+			}
+			if !current {
+				g.respondWithError(w, http.StatusUnauthorized, "Роли пользователя изменены, обновите токен")
+				return
+			}
+
+			// Токен API ключа допускает только маршруты из областей ключа
+			if len(claims.Scopes) > 0 && !apiKeyScopeAllowed(claims.Scopes, r) {
+				g.respondWithError(w, http.StatusForbidden, "Недостаточно прав API ключа")
+				return
+			}
+
+			// Добавляем пользовательский контекст в заголовки для микросервисов
+			r.Header.Set("X-User-ID", claims.UserID.String())
+			r.Header.Set("X-User-Email", claims.Email)
+			r.Header.Set("X-User-Roles", strings.Join(claims.Roles, ","))
+			if len(claims.Scopes) > 0 {
+				r.Header.Set("X-User-Scopes", strings.Join(claims.Scopes, ","))
+			}
+			if len(claims.Permissions) > 0 {
+				r.Header.Set(rbac.HeaderPermissions, strings.Join(claims.Permissions, ","))
+			}
+
+			if entry := accesslog.FromContext(r.Context()); entry != nil {
+				entry.UserID = claims.UserID.String()
+			}
+
+			// Структурированное логирование аутентификации
+			log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+			log = logger.WithUserContext(log, claims.UserID.String(), claims.Email, claims.Roles)
+			log.Info("Пользователь успешно аутентифицирован",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+			)
+
+			stopAuth()
+			next.ServeHTTP(w, r)
+			return
+		}
+		g.respondWithError(w, http.StatusUnauthorized, "Недействительный токен")
+	})
+}
+
+// rolesEpochCurrent проверяет, что роли пользователя не изменялись после выдачи токена.
+// Ошибка возвращается, только если Redis недоступен и включен AUTH_ROLES_EPOCH_FAIL_CLOSED;
+// иначе при недоступности Redis токен принимается
+func (g *Gateway) rolesEpochCurrent(r *http.Request, claims *JWTClaims) (bool, error) {
+	if g.deps.RolesEpochs == nil {
+		return true, nil
+	}
+
+	epoch, err := g.deps.RolesEpochs.Get(r.Context(), claims.UserID.String())
+	if err != nil {
+		log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+		log.Warn("Не удалось проверить эпоху ролей", zap.Error(err))
+		if g.config.Auth.RolesEpochFailClosed {
+			return false, err
+		}
+		return true, nil
+	}
+
+	return claims.RolesEpoch >= epoch, nil
+}
+
+// jwtKeyFunc возвращает ключ проверки подписи: общий секрет для HMAC
+// или публичный ключ из JWKS (по kid) для RS256/ES256. HMAC токен с kid проверяется
+// секретом с этим идентификатором, токен без kid (выпущенный до ротации) — каждым
+// из текущего и предыдущих секретов
+func (g *Gateway) jwtKeyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		keys := g.config.JWT.Keys
+		if keys == nil {
+			return nil, fmt.Errorf("секрет HMAC не настроен")
+		}
+
+		kid, ok := token.Header["kid"].(string)
+		if !ok {
+			keySet := jwt.VerificationKeySet{}
+			for _, secret := range keys.Secrets() {
+				keySet.Keys = append(keySet.Keys, secret)
+			}
+			return keySet, nil
+		}
+
+		secret, ok := keys.Secret(kid)
+		if !ok {
+			return nil, fmt.Errorf("неизвестный идентификатор ключа: %s", kid)
+		}
+		return secret, nil
+
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		if g.deps.KeySet == nil {
+			return nil, fmt.Errorf("JWKS не настроен")
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, err := g.deps.KeySet.Key(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// Тип ключа должен соответствовать алгоритму, иначе возможна подмена алгоритма
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
+				return key, nil
+			}
+		case *ecdsa.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodECDSA); ok {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("ключ %q не подходит для алгоритма %v", kid, token.Header["alg"])
+	}
+
+	return nil, fmt.Errorf("Неожиданный метод подписи: %v", token.Header["alg"])
+}
+
+// rateLimitMiddleware middleware для ограничения частоты запросов
+func (g *Gateway) rateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rule, decision := g.deps.RateLimiter.Allow(r)
+		g.observeRateLimit(r, decision.Allowed)
+		setRateLimitHeaders(w, decision)
+
+		if !decision.Allowed {
+			// Логируем превышение лимита с контекстом
+			log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+			log.Warn("Rate limit exceeded",
+				zap.String("remote_addr", r.RemoteAddr),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("rule", rule.String()),
+			)
+
+			g.respondWithError(w, http.StatusTooManyRequests, "Слишком много запросов")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setRateLimitHeaders сообщает клиенту состояние лимита: X-RateLimit-Limit, X-RateLimit-Remaining
+// и X-RateLimit-Reset (секунды до полного восстановления); ответ 429 дополняется Retry-After
+func setRateLimitHeaders(w http.ResponseWriter, decision ratelimit.Decision) {
+	header := w.Header()
+	header.Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+	if !decision.Allowed {
+		header.Set("Retry-After", strconv.Itoa(max(ceilSeconds(decision.RetryAfter), 1)))
+	}
+}
+
+// ceilSeconds округляет длительность вверх до целых секунд
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// quotaMiddleware учитывает запрос аутентифицированного пользователя в суточной квоте
+// и отклоняет запросы сверх нее с 429. Административный API шлюза квотой не ограничен,
+// чтобы администратор мог сбросить квоту. Если Redis недоступен, запрос пропускается,
+// а при включенном QUOTA_FAIL_CLOSED отклоняется с 503
+func (g *Gateway) quotaMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Header.Get("X-User-ID")
+		if g.deps.Quotas == nil || userID == "" || strings.HasPrefix(r.URL.Path, adminPathPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		usage, allowed, err := g.deps.Quotas.Consume(r.Context(), userID)
+		if err != nil {
+			log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+			log.Warn("Не удалось проверить квоту пользователя", zap.String("user_id", userID), zap.Error(err))
+			if g.config.Quota.FailClosed {
+				g.respondWithError(w, http.StatusServiceUnavailable, "Не удалось проверить квоту запросов")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		header := w.Header()
+		header.Set("X-Quota-Limit", strconv.FormatInt(usage.Limit, 10))
+		header.Set("X-Quota-Remaining", strconv.FormatInt(usage.Remaining, 10))
+		header.Set("X-Quota-Reset", strconv.Itoa(ceilSeconds(time.Until(usage.ResetAt))))
+
+		if !allowed {
+			header.Set("Retry-After", strconv.Itoa(max(ceilSeconds(time.Until(usage.ResetAt)), 1)))
+
+			log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+			log.Warn("Quota exceeded",
+				zap.String("user_id", userID),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Int64("limit", usage.Limit),
+			)
+
+			g.respondWithError(w, http.StatusTooManyRequests, "Суточная квота запросов исчерпана")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// observeRateLimit учитывает решение ограничителя в метриках по всем классам ключей запроса
+func (g *Gateway) observeRateLimit(r *http.Request, allowed bool) {
+	if g.deps.RateLimitMetrics == nil {
+		return
+	}
+
+	g.deps.RateLimitMetrics.Observe(metrics.KeyClassIP, clientIP(r), allowed)
+
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			g.deps.RateLimitMetrics.Observe(metrics.KeyClassRoute, r.Method+" "+template, allowed)
+		}
+	}
+
+	if apiKey := requestAPIKey(r); apiKey != "" {
+		g.deps.RateLimitMetrics.Observe(metrics.KeyClassAPIKey, apiKey, allowed)
+	}
+
+	// Ограничение выполняется до аутентификации, поэтому пользователь берется из
+	// непроверенного токена — только для метрик, не для принятия решений
+	if userID := unverifiedUserID(r); userID != "" {
+		g.deps.RateLimitMetrics.Observe(metrics.KeyClassUser, userID, allowed)
+	}
+}
+
+// rateLimitKey возвращает ключ лимита для стратегии ip или user. Пользователь берется
+// только из токена с действительной подписью, иначе поддельными токенами можно было бы
+// получать новый лимит на каждый запрос; анонимные запросы ограничиваются по IP
+func (g *Gateway) rateLimitKey(r *http.Request, strategy string) string {
+	if strategy == ratelimit.KeyUser {
+		if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+			claims := &JWTClaims{}
+			token, err := jwt.ParseWithClaims(strings.TrimPrefix(authHeader, "Bearer "), claims, g.jwtKeyFunc, jwt.WithValidMethods(g.config.JWT.Algorithms))
+			if err == nil && token.Valid && claims.UserID != uuid.Nil {
+				return "user:" + claims.UserID.String()
+			}
+		}
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP возвращает IP адрес клиента без порта
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// affinityKey возвращает ключ привязки запроса к реплике сервиса: пользователь из токена,
+// для анонимных запросов — IP клиента. Подпись не проверяется: ключ влияет только
+// на выбор реплики, а не на доступ
+func affinityKey(r *http.Request) string {
+	if userID := unverifiedUserID(r); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + clientIP(r)
+}
+
+// unverifiedUserID извлекает user_id из Bearer токена без проверки подписи
+func unverifiedUserID(r *http.Request) string {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return ""
+	}
+
+	claims := &JWTClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(strings.TrimPrefix(authHeader, "Bearer "), claims); err != nil {
+		return ""
+	}
+	if claims.UserID == uuid.Nil {
+		return ""
+	}
+	return claims.UserID.String()
+}
+
+// loggingMiddleware пишет одну запись о запросе в лог приложения и, если включены,
+// в JSON журнал доступа, профиль трафика и агрегаты SLA. Маршрут, пользователь и
+// upstream заполняются внутренними обработчиками через запись в контексте запроса
+func (g *Gateway) loggingMiddleware() httpmw.Middleware {
+	return httpmw.Logging(httpmw.LoggingConfig{
+		Begin: beginRequestEntry,
+		Log: func(r *http.Request, result httpmw.Result) {
+			entry := accesslog.FromContext(r.Context())
+			entry.Status = result.Status
+			entry.BytesOut = result.BytesOut
+			entry.LatencyMS = milliseconds(result.Duration)
+			entry.Stages = requestStages(servertiming.FromContext(r.Context()), result.Duration)
+			if g.deps.AccessLog != nil {
+				g.deps.AccessLog.Log(entry)
+			}
+			if g.deps.Capture != nil {
+				g.deps.Capture.Record(entry)
+			}
+			if g.deps.SLA != nil {
+				g.deps.SLA.Record(entry)
+			}
+			g.logRequest(entry, result.Duration)
+		},
+	})
+}
+
+// beginRequestEntry создает запись о запросе и Recorder этапов обработки и сохраняет их в контексте
+func beginRequestEntry(r *http.Request) *http.Request {
+	remoteAddr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		remoteAddr = host
+	}
+
+	entry := &accesslog.Entry{
+		Timestamp:  time.Now().UTC(),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		BytesIn:    r.ContentLength,
+		RemoteAddr: remoteAddr,
+		UserAgent:  r.UserAgent(),
+	}
+	if entry.BytesIn < 0 {
+		entry.BytesIn = 0
+	}
+	ctx := accesslog.NewContext(r.Context(), entry)
+	return r.WithContext(servertiming.NewContext(ctx, servertiming.NewRecorder()))
+}
+
+// costCenterMetricsMiddleware учитывает трафик центров затрат. Выполняется внутри
+// loggingMiddleware: центр затрат читается из записи о запросе
+func (g *Gateway) costCenterMetricsMiddleware() httpmw.Middleware {
+	return httpmw.Metrics(httpmw.MetricsConfig{
+		Observe: func(r *http.Request, result httpmw.Result) {
+			entry := accesslog.FromContext(r.Context())
+			if entry == nil || entry.CostCenter == "" {
+				return
+			}
+			g.deps.CostCenterUsage.Record(entry.CostCenter, result.Status, entry.BytesIn, result.BytesOut, result.Duration)
+			g.deps.CostCenterMetrics.Observe(entry.CostCenter, result.Status, entry.BytesIn, result.BytesOut, result.Duration)
+		},
+	})
+}
+
+// protocolMetricsMiddleware учитывает версию HTTP запросов клиентов
+func (g *Gateway) protocolMetricsMiddleware(next http.Handler) http.Handler {
+	if g.deps.ProtocolMetrics == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.deps.ProtocolMetrics.ObserveClientRequest(r)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logRequest пишет запись о запросе с разбивкой по этапам в лог приложения. Медленные
+// запросы помечаются slow и пишутся с уровнем Warn; успешные запросы сэмплируются,
+// ошибки логируются всегда
+func (g *Gateway) logRequest(entry *accesslog.Entry, duration time.Duration) {
+	fields := []zap.Field{
+		zap.String("request_id", entry.RequestID),
+		zap.String("trace_id", entry.TraceID),
+		zap.String("method", entry.Method),
+		zap.String("route", entry.Route),
+		zap.String("path", entry.Path),
+		zap.Int("status", entry.Status),
+		zap.Int64("bytes_in", entry.BytesIn),
+		zap.Int64("bytes_out", entry.BytesOut),
+		zap.Duration("duration", duration),
+		zap.String("upstream", entry.Upstream),
+		zap.String("user_id", entry.UserID),
+		zap.String("cost_center", entry.CostCenter),
+		zap.String("remote_addr", entry.RemoteAddr),
+		zap.Float64("auth_ms", entry.Stages.AuthMS),
+		zap.Float64("gateway_ms", entry.Stages.GatewayMS),
+		zap.Float64("proxy_ms", entry.Stages.ProxyMS),
+		zap.Float64("service_ms", entry.Stages.ServiceMS),
+		zap.Float64("db_ms", entry.Stages.DBMS),
+		zap.Float64("publish_ms", entry.Stages.PublishMS),
+	}
+
+	threshold := g.config.RequestLog.SlowThreshold
+	switch {
+	case threshold > 0 && duration >= threshold:
+		g.logger.Warn("HTTP request", append(fields, zap.Bool("slow", true))...)
+	case entry.Status >= 500:
+		g.logger.Error("HTTP request", fields...)
+	case entry.Status >= 200 && entry.Status < 300:
+		g.sampledLogger.Info("HTTP request", fields...)
+	default:
+		g.logger.Info("HTTP request", fields...)
+	}
+}
+
+// routeTimeout возвращает таймаут маршрута для httpmw.Timeout.
+// Отмена контекста прерывает запрос к сервису, и прокси отвечает 504
+func (g *Gateway) routeTimeout(r *http.Request) time.Duration {
+	if g.deps.Timeouts == nil {
+		return 0
+	}
+	return g.deps.Timeouts.For(r)
+}
+
+// routeMiddleware сохраняет шаблон найденного маршрута (/v1/orders/{id}) в запись о запросе
+func (g *Gateway) routeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if entry := accesslog.FromContext(r.Context()); entry != nil {
+			if route := mux.CurrentRoute(r); route != nil {
+				entry.Route, _ = route.GetPathTemplate()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// securityHeadersMiddleware добавляет заголовки безопасности (HSTS, CSP и др.) ко всем
+// ответам шлюза. Заголовки выставляются до обработки запроса, поэтому присутствуют
+// и в ответах сервисов, и в ошибках самого шлюза
+func (g *Gateway) securityHeadersMiddleware(next http.Handler) http.Handler {
+	headers := g.config.Security.Headers()
+	if len(headers) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, value := range headers {
+			w.Header().Set(name, value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoveryMiddleware перехватывает panic в обработчиках шлюза: стек пишется в лог
+// с X-Request-ID, клиент получает 500 в формате ошибок шлюза
+func (g *Gateway) recoveryMiddleware() httpmw.Middleware {
+	return httpmw.Recovery(httpmw.RecoveryConfig{
+		Body: panicResponse,
+		Report: func(r *http.Request, value interface{}, stack []byte) {
+			log := logger.WithRequestID(g.logger, httpmw.RequestIDFromContext(r.Context()))
+			log.Error("Panic при обработке запроса",
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.Any("panic", value),
+				zap.ByteString("stack", stack),
+			)
+		},
+	})
+}
+
+// costCenterMiddleware относит запрос к центру затрат и передает тег в запись о запросе
+// (лог, журнал доступа, метрики) и в baggage трассы. Тег, присланный клиентом
+// в baggage, заменяется, чтобы его нельзя было подделать
+func (g *Gateway) costCenterMiddleware(next http.Handler) http.Handler {
+	if g.deps.CostCenters == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tag := g.deps.CostCenters.Classify(r)
+		r.Header.Set(tracecontext.HeaderBaggage, tracecontext.SetBaggage(r.Header.Get(tracecontext.HeaderBaggage), "cost_center", tag))
+
+		if entry := accesslog.FromContext(r.Context()); entry != nil {
+			entry.CostCenter = tag
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// traceContextMiddleware назначает запросу traceparent и прокидывает его в исходящие
+// запросы к микросервисам. Выполняется после httpmw.RequestID снаружи роутера, поэтому
+// X-Request-ID и traceparent назначаются всем ответам, включая 404, 405 и CORS preflight
+func (g *Gateway) traceContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Продолжаем трассу клиента или начинаем новую; tracestate без корректного
+		// traceparent по спецификации отбрасывается
+		traceParent, ok := tracecontext.Parse(r.Header.Get(tracecontext.HeaderTraceParent))
+		if ok {
+			traceParent = traceParent.Child()
+		} else {
+			traceParent = tracecontext.New()
+			r.Header.Del(tracecontext.HeaderTraceState)
+		}
+		r.Header.Set(tracecontext.HeaderTraceParent, traceParent.String())
+
+		if entry := accesslog.FromContext(r.Context()); entry != nil {
+			entry.RequestID = httpmw.RequestIDFromContext(r.Context())
+			entry.TraceID = traceParent.TraceIDString()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
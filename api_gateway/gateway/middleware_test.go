@@ -0,0 +1,224 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api_gateway/cache"
+	"api_gateway/config"
+	"api_gateway/quota"
+	"api_gateway/ratelimit"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const testSecret = "middleware-test-secret"
+
+// upstreamStub запоминает заголовки запросов, дошедших до сервиса
+type upstreamStub struct {
+	calls  int
+	header http.Header
+}
+
+func (u *upstreamStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.calls++
+	u.header = r.Header.Clone()
+	w.WriteHeader(http.StatusOK)
+}
+
+// testGateway создает Gateway с заглушками сервисов и логгером, записи которого
+// доступны тесту. Квоты подключены к недоступному Redis с QUOTA_FAIL_CLOSED: дошедший
+// до quotaMiddleware запрос аутентифицированного пользователя отклоняется с 503
+func testGateway(t *testing.T, withQuotas bool) (*Gateway, *upstreamStub, *observer.ObservedLogs) {
+	t.Helper()
+	t.Setenv("ENVIRONMENT", "development")
+	t.Setenv("JWT_SECRET", testSecret)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("ошибка загрузки конфигурации: %v", err)
+	}
+	cfg.Quota.FailClosed = true
+
+	core, logs := observer.New(zap.InfoLevel)
+	stub := &upstreamStub{}
+	deps := Dependencies{
+		UserProxy:     stub,
+		OrderProxy:    stub,
+		ResponseCache: cache.NewResponseCache(nil, cfg.Cache.MaxEntries, nil, zap.New(core)),
+	}
+	if withQuotas {
+		client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond, MaxRetries: -1})
+		t.Cleanup(func() { client.Close() })
+		deps.Quotas = quota.NewStore(client, 10)
+	}
+
+	g := New(cfg, zap.New(core), deps)
+	g.deps.RateLimiter = ratelimit.New(cfg.RateLimit.Routes, cfg.RateLimit.Default(), g.rateLimitKey)
+	return g, stub, logs
+}
+
+// testToken возвращает токен пользователя userID с ролью user, подписанный секретом теста
+func testToken(t *testing.T, userID uuid.UUID) string {
+	t.Helper()
+	return testAPIKeyToken(t, userID)
+}
+
+// testAPIKeyToken возвращает токен пользователя userID, выданный по API ключу с областями
+// scopes; без областей это токен входа
+func testAPIKeyToken(t *testing.T, userID uuid.UUID, scopes ...string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{
+		UserID: userID,
+		Email:  "user@example.com",
+		Roles:  []string{"user"},
+		Scopes: scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("ошибка подписи токена: %v", err)
+	}
+	return token
+}
+
+// forgeUserHeaders добавляет заголовки пользовательского контекста, которые клиент
+// не вправе передавать сервисам
+func forgeUserHeaders(r *http.Request) {
+	r.Header.Set("X-User-ID", uuid.NewString())
+	r.Header.Set("X-User-Roles", "admin")
+	r.Header.Set("X-User-Permissions", "gateway.manage")
+}
+
+func TestHandlerStripsUserHeadersOnPublicRoutes(t *testing.T) {
+	g, stub, logs := testGateway(t, true)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/users/register", nil)
+	forgeUserHeaders(req)
+	rec := httptest.NewRecorder()
+	g.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK || stub.calls != 1 {
+		t.Fatalf("код %d, вызовов сервиса %d: публичный маршрут должен дойти до сервиса", rec.Code, stub.calls)
+	}
+	for _, name := range []string{"X-User-ID", "X-User-Roles", "X-User-Permissions"} {
+		if value := stub.header.Get(name); value != "" {
+			t.Errorf("сервис получил %s: %q", name, value)
+		}
+	}
+	if logs.FilterMessage("Inbound user context headers stripped").Len() != 1 {
+		t.Error("удаление заголовков не записано в логгер Gateway")
+	}
+}
+
+func TestHandlerAuthenticatesBeforeQuota(t *testing.T) {
+	g, stub, _ := testGateway(t, true)
+
+	// Поддельный X-User-ID удаляется до quotaMiddleware: без токена запрос
+	// отклоняет jwtAuthMiddleware, а не квота с недоступным Redis
+	req := httptest.NewRequest(http.MethodGet, "/v1/orders", nil)
+	forgeUserHeaders(req)
+	rec := httptest.NewRecorder()
+	g.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("код %d, ожидался 401 от jwtAuthMiddleware", rec.Code)
+	}
+	if stub.calls != 0 {
+		t.Fatal("запрос без токена дошел до сервиса")
+	}
+}
+
+func TestHandlerChecksQuotaAfterAuth(t *testing.T) {
+	g, stub, logs := testGateway(t, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/orders", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken(t, uuid.New()))
+	rec := httptest.NewRecorder()
+	g.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("код %d, ожидался 503 от quotaMiddleware", rec.Code)
+	}
+	if stub.calls != 0 {
+		t.Fatal("запрос дошел до сервиса без проверки квоты")
+	}
+	if logs.FilterMessage("Пользователь успешно аутентифицирован").Len() != 1 {
+		t.Error("квота проверена до аутентификации")
+	}
+}
+
+func TestHandlerForwardsVerifiedUser(t *testing.T) {
+	g, stub, _ := testGateway(t, false)
+
+	userID := uuid.New()
+	req := httptest.NewRequest(http.MethodGet, "/v1/orders", nil)
+	forgeUserHeaders(req)
+	req.Header.Set("Authorization", "Bearer "+testToken(t, userID))
+	rec := httptest.NewRecorder()
+	g.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("код %d, ожидался 200", rec.Code)
+	}
+	if got := stub.header.Get("X-User-ID"); got != userID.String() {
+		t.Errorf("X-User-ID %q, ожидался пользователь из токена %s", got, userID)
+	}
+	if got := stub.header.Get("X-User-Roles"); got != "user" {
+		t.Errorf("X-User-Roles %q, ожидались роли из токена", got)
+	}
+	if got := stub.header.Get("X-User-Permissions"); got != "" {
+		t.Errorf("X-User-Permissions %q сохранен из запроса клиента", got)
+	}
+}
+
+func TestHandlerRejectsAPIKeyOnAccountRoutes(t *testing.T) {
+	g, stub, _ := testGateway(t, false)
+	token := testAPIKeyToken(t, uuid.New(), "users:read", "users:write")
+
+	routes := []struct{ method, path string }{
+		{http.MethodDelete, "/v1/users/me"},
+		{http.MethodPost, "/v1/users/me/password"},
+		{http.MethodDelete, "/v1/users/me/sessions"},
+		{http.MethodDelete, "/v1/users/me/sessions/" + uuid.NewString()},
+		{http.MethodGet, "/v1/users/me/export"},
+		{http.MethodPost, "/v1/users/profile/api-keys"},
+		{http.MethodDelete, "/v1/users/profile/api-keys/" + uuid.NewString()},
+	}
+	for _, route := range routes {
+		req := httptest.NewRequest(route.method, route.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		g.Handler().ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s: код %d, ожидался 403", route.method, route.path, rec.Code)
+		}
+	}
+	if stub.calls != 0 {
+		t.Fatalf("запросов по API ключу дошло до сервиса: %d", stub.calls)
+	}
+}
+
+func TestHandlerForwardsAPIKeyScopes(t *testing.T) {
+	g, stub, _ := testGateway(t, false)
+
+	req := httptest.NewRequest(http.MethodPatch, "/v1/users/profile", nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIKeyToken(t, uuid.New(), "users:write"))
+	rec := httptest.NewRecorder()
+	g.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("код %d, ожидался 200 для маршрута из области ключа", rec.Code)
+	}
+	if got := stub.header.Get("X-User-Scopes"); got != "users:write" {
+		t.Errorf("X-User-Scopes %q, ожидались области ключа", got)
+	}
+}
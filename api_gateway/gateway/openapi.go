@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"api_gateway/openapi"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// swaggerUIPage страница Swagger UI; скрипты и стили загружаются из SWAGGER_UI_ASSETS_URL
+var swaggerUIPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>API документация</title>
+<link rel="stylesheet" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="{{.Assets}}/swagger-ui-bundle.js" nonce="{{.Nonce}}"></script>
+<script nonce="{{.Nonce}}">
+window.ui = SwaggerUIBundle({url: "{{.SpecURL}}", dom_id: "#swagger-ui", deepLinking: true});
+</script>
+</body>
+</html>
+`))
+
+// validationErrorResponse ответ на запрос, не соответствующий спецификации
+type validationErrorResponse struct {
+	Error  string               `json:"error"`
+	Fields []openapi.FieldError `json:"fields"`
+}
+
+// serveOpenAPISpec отдает объединенную спецификацию API шлюза и сервисов. При
+// OPENAPI_SCOPE_BY_ROLE в спецификации остаются только операции, доступные по правам
+// ролей пользователя из токена запроса (или сессии): без действительного токена — только
+// публичные, с правом gateway.manage — все
+func (g *Gateway) serveOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	spec := g.deps.OpenAPI.Spec()
+	if g.config.OpenAPI.ScopeByRole {
+		var permissions []string
+		claims := g.specClaims(r)
+		if claims != nil {
+			permissions = claims.Permissions
+		}
+
+		var err error
+		spec, err = g.deps.OpenAPI.ScopedSpec(permissions, claims != nil)
+		if err != nil {
+			g.logger.Error("Ошибка фильтрации спецификации OpenAPI", zap.Error(err))
+			g.respondWithError(w, http.StatusInternalServerError, "Не удалось сформировать спецификацию")
+			return
+		}
+		// Содержимое зависит от пользователя: общие кэши не должны его сохранять
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Add("Vary", "Authorization, Cookie")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(spec)
+}
+
+// specClaims возвращает утверждения действительного токена запроса или nil, если токена
+// нет или он недействителен: спецификация публичная, поэтому такой запрос не отклоняется,
+// а получает документацию анонимного пользователя
+func (g *Gateway) specClaims(r *http.Request) *JWTClaims {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil
+	}
+
+	tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, g.jwtKeyFunc, jwt.WithValidMethods(g.config.JWT.Algorithms))
+	if err != nil || !token.Valid {
+		return nil
+	}
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok {
+		return nil
+	}
+	if current, err := g.rolesEpochCurrent(r, claims); err != nil || !current {
+		return nil
+	}
+	return claims
+}
+
+// serveSwaggerUI отдает страницу Swagger UI объединенной спецификации. Страница
+// получает собственную CSP вместо политики шлюза, запрещающей любые ресурсы:
+// разрешены статика swagger-ui-dist и встроенный скрипт с одноразовым nonce
+func (g *Gateway) serveSwaggerUI(w http.ResponseWriter, r *http.Request) {
+	assets := g.config.OpenAPI.SwaggerUIAssetsURL
+	assetsURL, err := url.Parse(assets)
+	if err != nil {
+		g.respondWithError(w, http.StatusInternalServerError, "Некорректный адрес статики Swagger UI")
+		return
+	}
+	origin := assetsURL.Scheme + "://" + assetsURL.Host
+
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		g.respondWithError(w, http.StatusInternalServerError, "Не удалось сформировать страницу документации")
+		return
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+
+	w.Header().Set("Content-Security-Policy", fmt.Sprintf(
+		"default-src 'none'; script-src 'nonce-%s' %s; style-src %s; img-src 'self' data: %s; connect-src 'self'; frame-ancestors 'none'",
+		nonce, origin, origin, origin,
+	))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if err := swaggerUIPage.Execute(w, map[string]string{
+		"Assets":  assets,
+		"Nonce":   nonce,
+		"SpecURL": "/v1/openapi.json",
+	}); err != nil {
+		g.logger.Error("Failed to write Swagger UI page", zap.Error(err))
+	}
+}
+
+// validateRequest проверяет запрос по спецификации OpenAPI перед проксированием.
+// Возвращает false, если запрос отклонен и ответ 400 уже отправлен
+func (g *Gateway) validateRequest(w http.ResponseWriter, r *http.Request) bool {
+	if g.deps.OpenAPI == nil || !g.config.OpenAPI.Validate {
+		return true
+	}
+
+	fields := g.deps.OpenAPI.Validate(r)
+	if len(fields) == 0 {
+		return true
+	}
+
+	g.logger.Warn("Запрос не соответствует спецификации API",
+		zap.String("request_id", r.Header.Get("X-Request-ID")),
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.Any("fields", fields),
+	)
+	g.respondWithJSON(w, http.StatusBadRequest, validationErrorResponse{
+		Error:  "Запрос не соответствует спецификации API",
+		Fields: fields,
+	})
+	return false
+}
@@ -0,0 +1,118 @@
+package gateway
+
+import (
+	"net/http"
+
+	"api_gateway/accesslog"
+	"api_gateway/grpcproxy"
+	"api_gateway/logger"
+
+	"go.uber.org/zap"
+)
+
+// proxyToUsersService проксирует запросы к service_users
+func (g *Gateway) proxyToUsersService(w http.ResponseWriter, r *http.Request) {
+	if !g.validateRequest(w, r) {
+		return
+	}
+	release, ok := g.acquireConcurrency(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	requestID := r.Header.Get("X-Request-ID")
+
+	logger.LogServiceCall(g.logger, requestID, "api_gateway", "service_users", r.URL.Path, true, nil)
+
+	if entry := accesslog.FromContext(r.Context()); entry != nil {
+		entry.Upstream = "service_users"
+	}
+
+	serveUpstream(g.deps.UserProxy, w, r)
+}
+
+// proxyToOrdersService проксирует запросы к service_orders
+func (g *Gateway) proxyToOrdersService(w http.ResponseWriter, r *http.Request) {
+	if !g.validateRequest(w, r) {
+		return
+	}
+	release, ok := g.acquireConcurrency(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	requestID := r.Header.Get("X-Request-ID")
+
+	logger.LogServiceCall(g.logger, requestID, "api_gateway", "service_orders", r.URL.Path, true, nil)
+
+	if entry := accesslog.FromContext(r.Context()); entry != nil {
+		entry.Upstream = "service_orders"
+	}
+
+	serveUpstream(g.deps.OrderProxy, w, r)
+}
+
+// proxyToGRPCService проксирует запрос к gRPC сервису с транскодированием JSON в protobuf
+func (g *Gateway) proxyToGRPCService(endpoint *grpcproxy.Endpoint) http.Handler {
+	upstream := "grpc:" + endpoint.Route.Target
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, ok := g.acquireConcurrency(w, r)
+		if !ok {
+			return
+		}
+		defer release()
+
+		requestID := r.Header.Get("X-Request-ID")
+
+		logger.LogServiceCall(g.logger, requestID, "api_gateway", upstream, endpoint.Route.FullMethod, true, nil)
+
+		if entry := accesslog.FromContext(r.Context()); entry != nil {
+			entry.Upstream = upstream
+		}
+
+		endpoint.ServeHTTP(w, r)
+	})
+}
+
+// proxyToAggregator выполняет запрос агрегирующим обработчиком шлюза (GraphQL,
+// сводка пользователя), который сам параллельно обращается к сервисам
+func (g *Gateway) proxyToAggregator(name string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		release, ok := g.acquireConcurrency(w, r)
+		if !ok {
+			return
+		}
+		defer release()
+
+		requestID := r.Header.Get("X-Request-ID")
+
+		logger.LogServiceCall(g.logger, requestID, "api_gateway", name, r.URL.Path, true, nil)
+
+		if entry := accesslog.FromContext(r.Context()); entry != nil {
+			entry.Upstream = name
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// acquireConcurrency занимает место в общем пределе одновременных запросов к сервисам.
+// Возвращает false, если запрос отклонен и ответ 503 уже отправлен
+func (g *Gateway) acquireConcurrency(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	if g.deps.Concurrency == nil {
+		return func() {}, true
+	}
+
+	release, ok = g.deps.Concurrency.Acquire(r.Context())
+	if !ok {
+		g.logger.Warn("Запрос отклонен: превышен предел одновременных запросов",
+			zap.String("request_id", r.Header.Get("X-Request-ID")),
+			zap.String("path", r.URL.Path),
+		)
+		g.deps.Concurrency.Reject(w)
+	}
+	return release, ok
+}
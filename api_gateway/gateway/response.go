@@ -0,0 +1,40 @@
+package gateway
+
+import (
+	"net/http"
+
+	"pkg/httpresp"
+
+	"go.uber.org/zap"
+)
+
+// jsonWriter записывает JSON ответы шлюза; при ошибке сериализации отправляет ошибку в формате шлюза
+var jsonWriter = httpresp.NewWriter([]byte(`{"error":"Internal server error"}`))
+
+// panicResponse тело ответа 500 при panic в обработчике
+var panicResponse = []byte(`{"error":"Внутренняя ошибка сервера"}`)
+
+// respondWithError отправляет JSON-ответ с ошибкой
+func (g *Gateway) respondWithError(w http.ResponseWriter, code int, message string) {
+	// Логируем ошибки с уровнем ERROR если код >= 500, иначе WARN
+	if code >= 500 {
+		g.logger.Error("HTTP Error Response",
+			zap.Int("status_code", code),
+			zap.String("error_message", message),
+		)
+	} else {
+		g.logger.Warn("HTTP Error Response",
+			zap.Int("status_code", code),
+			zap.String("error_message", message),
+		)
+	}
+
+	g.respondWithJSON(w, code, map[string]string{"error": message})
+}
+
+// respondWithJSON отправляет JSON-ответ
+func (g *Gateway) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	if err := jsonWriter.JSON(w, code, payload); err != nil {
+		g.logger.Error("Failed to write JSON response", zap.Int("status_code", code), zap.Error(err))
+	}
+}
@@ -0,0 +1,158 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"api_gateway/logger"
+	"api_gateway/session"
+
+	"pkg/clients"
+
+	"go.uber.org/zap"
+)
+
+// sessionMiddleware в режиме сессий аутентифицирует запросы с cookie сессии: подставляет
+// access токен сессии в заголовок Authorization, продлевая его при приближении истечения.
+// Запросы с собственным заголовком Authorization (API клиенты) не изменяются.
+// Cookie неизвестной или отозванной сессии удаляется, а запрос обрабатывается
+// как неаутентифицированный
+func (g *Gateway) sessionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.deps.Sessions == nil || r.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cookie, err := r.Cookie(g.config.Auth.SessionCookieName)
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		s, ok, err := g.deps.Sessions.Resolve(clients.WithRequest(r.Context(), r), cookie.Value)
+		if err != nil {
+			log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+			log.Error("Failed to resolve session", zap.Error(err))
+			g.respondWithError(w, http.StatusServiceUnavailable, "Не удалось проверить сессию")
+			return
+		}
+		if !ok {
+			g.clearSessionCookies(w)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r.Header.Set("Authorization", "Bearer "+s.AccessToken)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionLoginMiddleware в режиме сессий создает сессию из успешного ответа входа:
+// пара токенов остается в шлюзе, а клиент получает cookie сессии и CSRF токен
+func (g *Gateway) sessionLoginMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.deps.Sessions == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Ответ разбирается шлюзом, поэтому сжатие со стороны сервиса не нужно
+		r.Header.Del("Accept-Encoding")
+		buffered := newBufferedResponse()
+		next.ServeHTTP(buffered, r)
+
+		body := buffered.body.Bytes()
+		if buffered.status >= 200 && buffered.status < 300 {
+			log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+			fields, stripped, ok := stripDataFields(body, "token", "refresh_token")
+			if !ok {
+				log.Error("Login response does not contain token pair")
+				g.respondWithError(w, http.StatusBadGateway, "Некорректный ответ сервиса пользователей")
+				return
+			}
+
+			id, err := g.deps.Sessions.Create(r.Context(), fields["token"], fields["refresh_token"])
+			if err != nil {
+				log.Error("Failed to create session", zap.Error(err))
+				g.respondWithError(w, http.StatusServiceUnavailable, "Не удалось создать сессию")
+				return
+			}
+
+			maxAge := int(g.deps.Sessions.TTL().Seconds())
+			http.SetCookie(w, g.sessionCookie(id, maxAge))
+			g.setCSRFToken(w, maxAge)
+			body = stripped
+		}
+
+		g.writeBufferedResponse(w, buffered, body)
+	})
+}
+
+// logout завершает сессию: удаляет ее из хранилища вместе с токенами, отзывает refresh
+// токен сессии в сервисе пользователей и удаляет cookie. Ошибка отзыва не отменяет выход:
+// токен хранился только в шлюзе и больше никому не доступен
+func (g *Gateway) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(g.config.Auth.SessionCookieName); err == nil && cookie.Value != "" {
+		log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+		s, ok, err := g.deps.Sessions.Delete(r.Context(), cookie.Value)
+		if err != nil {
+			log.Error("Failed to delete session", zap.Error(err))
+			g.respondWithError(w, http.StatusServiceUnavailable, "Не удалось завершить сессию")
+			return
+		}
+		if ok {
+			if err := g.revokeSessionToken(r, s.RefreshToken); err != nil {
+				log.Warn("Failed to revoke session refresh token", zap.String("user_id", s.UserID), zap.Error(err))
+			}
+		}
+	}
+
+	g.clearSessionCookies(w)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// renewSession обменивает refresh токен сессии на новую пару токенов в сервисе
+// пользователей от имени исходного запроса из контекста
+func (g *Gateway) renewSession(ctx context.Context, refreshToken string) (string, string, error) {
+	pair, err := g.users.RefreshToken(ctx, refreshToken)
+	if err != nil {
+		switch clients.StatusOf(err) {
+		case http.StatusUnauthorized, http.StatusBadRequest:
+			return "", "", session.ErrRenewalRejected
+		case 0:
+			return "", "", err
+		}
+		return "", "", fmt.Errorf("сервис пользователей вернул статус %d", clients.StatusOf(err))
+	}
+	if pair.Token == "" || pair.RefreshToken == "" {
+		return "", "", fmt.Errorf("ответ обновления не содержит пару токенов")
+	}
+	return pair.Token, pair.RefreshToken, nil
+}
+
+// revokeSessionToken отзывает refresh токен завершенной сессии в сервисе пользователей
+func (g *Gateway) revokeSessionToken(r *http.Request, refreshToken string) error {
+	return g.users.RevokeRefreshToken(clients.WithRequest(r.Context(), r), refreshToken)
+}
+
+// clearSessionCookies удаляет cookie сессии и CSRF токена
+func (g *Gateway) clearSessionCookies(w http.ResponseWriter) {
+	http.SetCookie(w, g.sessionCookie("", -1))
+	g.setCSRFToken(w, -1)
+}
+
+// sessionCookie создает cookie сессии для всех путей шлюза; maxAge < 0 удаляет cookie
+func (g *Gateway) sessionCookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     g.config.Auth.SessionCookieName,
+		Value:    value,
+		Path:     "/",
+		Domain:   g.config.Auth.CookieDomain,
+		MaxAge:   maxAge,
+		Secure:   g.config.Auth.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+}
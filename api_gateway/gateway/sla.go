@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"api_gateway/sla"
+
+	"go.uber.org/zap"
+)
+
+// adminSLAReport формирует отчет SLA за месяц month (YYYY-MM, по умолчанию текущий)
+// в JSON или CSV (format=csv). Данные общие для всех экземпляров шлюза
+func (g *Gateway) adminSLAReport(w http.ResponseWriter, r *http.Request) {
+	if g.deps.SLA == nil {
+		g.respondWithError(w, http.StatusNotFound, "Отчеты SLA не настроены")
+		return
+	}
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if value := r.URL.Query().Get("month"); value != "" {
+		parsed, err := time.Parse(sla.MonthLayout, value)
+		if err != nil {
+			g.respondWithError(w, http.StatusBadRequest, "Некорректный месяц: ожидается YYYY-MM")
+			return
+		}
+		if parsed.After(month) {
+			g.respondWithError(w, http.StatusBadRequest, "Отчет за будущий месяц недоступен")
+			return
+		}
+		month = parsed
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		g.respondWithError(w, http.StatusBadRequest, "Некорректный формат: ожидается json или csv")
+		return
+	}
+
+	report, err := g.deps.SLA.Report(r.Context(), month, g.config.SLA.TargetPercent)
+	if err != nil {
+		g.respondWithError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+
+	if format != "csv" {
+		g.respondWithJSON(w, http.StatusOK, report)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "sla-"+report.Month+".csv"))
+	w.WriteHeader(http.StatusOK)
+	if err := report.WriteCSV(w); err != nil {
+		g.logger.Error("Failed to write SLA report", zap.Error(err))
+	}
+}
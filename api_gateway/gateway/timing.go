@@ -0,0 +1,117 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+
+	"api_gateway/accesslog"
+
+	"pkg/servertiming"
+)
+
+// Этапы обработки запроса в шлюзе; этапы сервиса (wait, app, db, publish)
+// приходят в заголовке Server-Timing его ответа
+const (
+	// stageAuth проверка JWT токена и эпохи ролей
+	stageAuth = "auth"
+	// stageUpstream время от отправки запроса сервису до получения заголовков ответа
+	stageUpstream = "upstream"
+)
+
+// upstreamStages этапы сервиса, принимаемые из Server-Timing
+var upstreamStages = map[string]bool{
+	servertiming.MetricWait:    true,
+	servertiming.MetricApp:     true,
+	servertiming.MetricDB:      true,
+	servertiming.MetricPublish: true,
+}
+
+// serveUpstream проксирует запрос к сервису через proxy: передает момент отправки
+// в X-Request-Start и учитывает время ответа и этапы сервиса в Recorder запроса.
+// Server-Timing сервиса клиенту не передается: он раскрывает внутреннее устройство
+func serveUpstream(proxy http.Handler, w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	r.Header.Set(servertiming.HeaderRequestStart, servertiming.FormatRequestStart(start))
+
+	tw := &upstreamTimingWriter{
+		ResponseWriter: w,
+		timing:         servertiming.FromContext(r.Context()),
+		start:          start,
+	}
+	proxy.ServeHTTP(tw, r)
+	tw.record()
+}
+
+// upstreamTimingWriter забирает Server-Timing из ответа сервиса перед отправкой кода ответа
+type upstreamTimingWriter struct {
+	http.ResponseWriter
+	timing   *servertiming.Recorder
+	start    time.Time
+	recorded bool
+}
+
+func (tw *upstreamTimingWriter) WriteHeader(code int) {
+	tw.record()
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *upstreamTimingWriter) Write(p []byte) (int, error) {
+	tw.record()
+	return tw.ResponseWriter.Write(p)
+}
+
+// Unwrap позволяет http.ResponseController получить доступ к исходному ResponseWriter (Flush)
+func (tw *upstreamTimingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// record учитывает время ответа сервиса и его этапы при первом вызове
+func (tw *upstreamTimingWriter) record() {
+	if tw.recorded {
+		return
+	}
+	tw.recorded = true
+	tw.timing.Add(stageUpstream, time.Since(tw.start))
+
+	header := tw.ResponseWriter.Header()
+	for _, metric := range servertiming.Parse(header.Get(servertiming.Header)) {
+		if upstreamStages[metric.Name] {
+			tw.timing.Add(metric.Name, metric.Duration)
+		}
+	}
+	header.Del(servertiming.Header)
+}
+
+// requestStages разбивает время запроса total по этапам. Время ответа сервиса делится
+// на обработку в сервисе (app) и сеть с очередями между шлюзом и сервисом (proxy);
+// обработка в сервисе — на обработчик, БД и публикацию событий. Остаток — шлюз
+func requestStages(timing *servertiming.Recorder, total time.Duration) accesslog.Stages {
+	auth := timing.Get(stageAuth)
+	upstream := timing.Get(stageUpstream)
+	app := timing.Get(servertiming.MetricApp)
+	db := timing.Get(servertiming.MetricDB)
+	publish := timing.Get(servertiming.MetricPublish)
+
+	return accesslog.Stages{
+		AuthMS:    milliseconds(auth),
+		GatewayMS: milliseconds(nonNegative(total - auth - upstream)),
+		ProxyMS:   milliseconds(nonNegative(upstream - app)),
+		ServiceMS: milliseconds(nonNegative(app - db - publish)),
+		DBMS:      milliseconds(db),
+		PublishMS: milliseconds(publish),
+	}
+}
+
+// milliseconds переводит длительность в миллисекунды с точностью до микросекунды
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
+
+// nonNegative заменяет отрицательную длительность нулем: этапы сервиса измерены его часами
+// и при параллельных обращениях к сервису могут превышать время ответа
+func nonNegative(duration time.Duration) time.Duration {
+	if duration < 0 {
+		return 0
+	}
+	return duration
+}
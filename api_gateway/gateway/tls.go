@@ -0,0 +1,189 @@
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"api_gateway/config"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// CertReloader хранит текущий сертификат и перечитывает его с диска при изменении файлов,
+// что позволяет ротировать сертификаты без перезапуска шлюза
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	logger   *zap.Logger
+
+	mutex   sync.RWMutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+// NewCertReloader создает CertReloader и загружает сертификат
+func NewCertReloader(certFile, keyFile string, logger *zap.Logger) (*CertReloader, error) {
+	reloader := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   logger,
+	}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload загружает сертификат и ключ с диска.
+// При ошибке продолжает использоваться ранее загруженный сертификат
+func (cr *CertReloader) Reload() error {
+	certMod, keyMod, err := cr.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки TLS сертификата: %v", err)
+	}
+
+	cr.mutex.Lock()
+	cr.cert = &cert
+	cr.certMod = certMod
+	cr.keyMod = keyMod
+	cr.mutex.Unlock()
+
+	return nil
+}
+
+// GetCertificate возвращает текущий сертификат (для tls.Config.GetCertificate)
+func (cr *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+	return cr.cert, nil
+}
+
+// Watch периодически проверяет время изменения файлов и перезагружает сертификат,
+// пока не будет отменен ctx
+func (cr *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !cr.changed() {
+				continue
+			}
+			if err := cr.Reload(); err != nil {
+				cr.logger.Error("Не удалось перезагрузить TLS сертификат", zap.Error(err))
+				continue
+			}
+			cr.logger.Info("TLS сертификат перезагружен", zap.String("cert_file", cr.certFile))
+		}
+	}
+}
+
+// changed проверяет, изменились ли файлы сертификата или ключа с момента последней загрузки
+func (cr *CertReloader) changed() bool {
+	certMod, keyMod, err := cr.modTimes()
+	if err != nil {
+		cr.logger.Warn("Не удалось проверить файлы TLS сертификата", zap.Error(err))
+		return false
+	}
+
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+	return !certMod.Equal(cr.certMod) || !keyMod.Equal(cr.keyMod)
+}
+
+func (cr *CertReloader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(cr.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("ошибка чтения TLS_CERT_FILE: %v", err)
+	}
+	keyInfo, err := os.Stat(cr.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("ошибка чтения TLS_KEY_FILE: %v", err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// tlsSetup результат настройки TLS: конфигурация для HTTPS сервера,
+// обработчик для HTTP порта и фоновая задача (перезагрузка сертификатов)
+type tlsSetup struct {
+	config      *tls.Config
+	httpHandler http.Handler
+	run         func(ctx context.Context)
+}
+
+// newTLSSetup строит конфигурацию TLS: ACME (Let's Encrypt) или сертификат из файлов.
+// httpsPort используется для редиректа с HTTP порта
+func newTLSSetup(cfg config.TLSConfig, httpsPort string, logger *zap.Logger) (*tlsSetup, error) {
+	redirect := httpsRedirect(httpsPort)
+
+	if cfg.ACME.Enabled {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.ACME.Domains...),
+			Cache:      autocert.DirCache(cfg.ACME.CacheDir),
+			Email:      cfg.ACME.Email,
+		}
+		if cfg.ACME.DirectoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: cfg.ACME.DirectoryURL}
+		}
+
+		tlsConfig := manager.TLSConfig()
+		tlsConfig.MinVersion = tls.VersionTLS12
+
+		return &tlsSetup{
+			config:      tlsConfig,
+			httpHandler: manager.HTTPHandler(redirect),
+		}, nil
+	}
+
+	reloader, err := NewCertReloader(cfg.CertFile, cfg.KeyFile, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tlsSetup{
+		config: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		},
+		httpHandler: redirect,
+		run: func(ctx context.Context) {
+			reloader.Watch(ctx, cfg.ReloadInterval)
+		},
+	}, nil
+}
+
+// httpsRedirect перенаправляет HTTP запросы на тот же адрес по HTTPS
+func httpsRedirect(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
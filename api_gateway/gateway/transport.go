@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"net"
+	"net/http"
+
+	"api_gateway/config"
+)
+
+// newProxyTransport создает HTTP транспорт reverse proxy с настройками из конфигурации
+func newProxyTransport(cfg config.TransportConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	dialer := &net.Dialer{
+		Timeout:   cfg.DialTimeout,
+		KeepAlive: cfg.KeepAlive,
+	}
+	transport.DialContext = dialer.DialContext
+
+	transport.MaxIdleConns = cfg.MaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	transport.IdleConnTimeout = cfg.IdleConnTimeout
+	transport.TLSHandshakeTimeout = cfg.TLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+	transport.DisableKeepAlives = cfg.DisableKeepAlives
+
+	// Clone DefaultTransport сохраняет ForceAttemptHTTP2: для https сервисов HTTP/2
+	// согласуется по ALPN. h2c используется только с http сервисами, поддерживающими
+	// HTTP/2 без TLS, поэтому в этом режиме HTTP/1.1 не предлагается
+	transport.Protocols = new(http.Protocols)
+	switch cfg.HTTP2 {
+	case config.ProxyHTTP2H2C:
+		transport.Protocols.SetUnencryptedHTTP2(true)
+		transport.Protocols.SetHTTP2(true)
+	case config.ProxyHTTP2Off:
+		transport.Protocols.SetHTTP1(true)
+	default:
+		transport.Protocols.SetHTTP1(true)
+		transport.Protocols.SetHTTP2(true)
+	}
+	transport.HTTP2 = &http.HTTP2Config{
+		SendPingTimeout: cfg.HTTP2PingTimeout,
+	}
+
+	return transport
+}
@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"net/http"
+
+	"api_gateway/logger"
+	"api_gateway/waf"
+
+	"go.uber.org/zap"
+)
+
+// wafMiddleware отклоняет запросы, на которых срабатывает правило фильтра.
+// В режиме WAF_DETECT_ONLY срабатывание только записывается в лог и метрики
+func (g *Gateway) wafMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.deps.WAF == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		match, err := g.deps.WAF.Inspect(r)
+		if err != nil {
+			g.respondWithError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if match == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		blocked := !g.config.WAF.DetectOnly
+		g.deps.WAFMetrics.Observe(match.Rule, blocked)
+
+		log := logger.WithRequestID(g.logger, r.Header.Get("X-Request-ID"))
+		log.Warn("WAF rule matched",
+			zap.String("rule", match.Rule),
+			zap.String("location", match.Location),
+			zap.Bool("blocked", blocked),
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.String("client_ip", clientIP(r)),
+		)
+
+		if !blocked {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if match.Rule == waf.RuleHeaderSize {
+			g.respondWithError(w, http.StatusRequestHeaderFieldsTooLarge, "Слишком большие заголовки запроса")
+			return
+		}
+		g.respondWithError(w, http.StatusForbidden, "Запрос отклонен фильтром безопасности")
+	})
+}
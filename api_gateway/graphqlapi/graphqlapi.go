@@ -0,0 +1,148 @@
+// Package graphqlapi реализует агрегирующий GraphQL эндпоинт API Gateway.
+// Запросы, затрагивающие пользователей и заказы (например, профиль вместе с
+// последними N заказами), разрешаются параллельными вызовами service_users и
+// service_orders, поэтому клиенту достаточно одного запроса вместо нескольких.
+// Для клиентов без GraphQL (мобильные приложения) тот же подход используется
+// в REST эндпоинте сводки пользователя /v1/users/{id}/overview
+package graphqlapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"pkg/clients"
+	ordersclient "pkg/clients/orders"
+	usersclient "pkg/clients/users"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// maxRequestBodySize ограничение размера тела GraphQL запроса
+const maxRequestBodySize = 1 << 20
+
+// Request тело GraphQL запроса
+type Request struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// Handler HTTP обработчик GraphQL запросов
+type Handler struct {
+	schema graphql.Schema
+	users  *usersclient.Client
+	orders *ordersclient.Client
+}
+
+// NewHandler создает обработчик, обращающийся к сервисам через клиенты users и orders
+// (поверх прокси Gateway, поэтому используются те же транспорт и адреса upstream)
+func NewHandler(users *usersclient.Client, orders *ordersclient.Client) (*Handler, error) {
+	h := &Handler{users: users, orders: orders}
+
+	schema, err := h.newSchema()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка построения GraphQL схемы: %v", err)
+	}
+	h.schema = schema
+
+	return h, nil
+}
+
+// ServeHTTP выполняет GraphQL запрос (POST с JSON телом или GET с параметром query).
+// Ошибки разрешения полей возвращаются в errors со статусом 200, как принято в GraphQL
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := parseRequest(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Исходный запрос нужен резолверам для передачи заголовков пользователя в сервисы
+	ctx := clients.WithRequest(r.Context(), r)
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+
+	for i := range result.Errors {
+		if result.Errors[i].Extensions == nil {
+			result.Errors[i].Extensions = upstreamExtensions(result.Errors[i].OriginalError())
+		}
+	}
+
+	status := http.StatusOK
+	// Запрос не выполнялся вовсе (синтаксическая ошибка или несоответствие схеме)
+	if result.Data == nil && result.HasErrors() {
+		status = http.StatusBadRequest
+	}
+	writeJSON(w, status, result)
+}
+
+// upstreamExtensions возвращает код и статус ошибки сервиса. Ошибки асинхронных
+// резолверов библиотека оборачивает без расширений, поэтому они извлекаются здесь
+func upstreamExtensions(err error) map[string]interface{} {
+	for err != nil {
+		switch e := err.(type) {
+		case *clients.Error:
+			return errorExtensions(e)
+		case *gqlerrors.Error:
+			err = e.OriginalError
+		case gqlerrors.FormattedError:
+			err = e.OriginalError()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// parseRequest читает GraphQL запрос из тела POST или параметров GET
+func parseRequest(r *http.Request) (*Request, error) {
+	req := &Request{}
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return nil, fmt.Errorf("Некорректный параметр variables: %v", err)
+			}
+		}
+
+	case http.MethodPost:
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize+1))
+		if err != nil {
+			return nil, fmt.Errorf("Ошибка чтения тела запроса: %v", err)
+		}
+		if len(body) > maxRequestBodySize {
+			return nil, fmt.Errorf("Размер запроса превышает %d байт", maxRequestBodySize)
+		}
+		if err := json.Unmarshal(body, req); err != nil {
+			return nil, fmt.Errorf("Некорректный JSON: %v", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("Метод %s не поддерживается", r.Method)
+	}
+
+	if req.Query == "" {
+		return nil, fmt.Errorf("Не указан query")
+	}
+	return req, nil
+}
+
+// writeJSON записывает JSON ответ
+func writeJSON(w http.ResponseWriter, status int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
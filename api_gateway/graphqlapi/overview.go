@@ -0,0 +1,124 @@
+package graphqlapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"pkg/clients"
+	ordersclient "pkg/clients/orders"
+	usersclient "pkg/clients/users"
+	"pkg/rbac"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Overview сводка пользователя: профиль и последние заказы
+type Overview struct {
+	User        *usersclient.User    `json:"user"`
+	Orders      []ordersclient.Order `json:"orders"`
+	OrdersTotal int                  `json:"orders_total"`
+	// Errors ошибки необязательных частей; при их наличии orders пуст
+	Errors []OverviewError `json:"errors,omitempty"`
+}
+
+// OverviewError ошибка получения части сводки
+type OverviewError struct {
+	Source  string `json:"source"`
+	Status  int    `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// OverviewHandler REST эндпоинт GET /v1/users/{id}/overview, объединяющий
+// профиль из service_users и последние заказы из service_orders
+type OverviewHandler struct {
+	users  *usersclient.Client
+	orders *ordersclient.Client
+}
+
+// NewOverviewHandler создает обработчик сводки, обращающийся к сервисам через клиенты users и orders
+func NewOverviewHandler(users *usersclient.Client, orders *ordersclient.Client) *OverviewHandler {
+	return &OverviewHandler{users: users, orders: orders}
+}
+
+// ServeHTTP запрашивает профиль и заказы параллельно. Пользователь видит только
+// свою сводку, администратор — любую. Ошибка профиля возвращается со статусом
+// сервиса, ошибка заказов — в errors при статусе 200
+func (h *OverviewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Некорректный ID пользователя"})
+		return
+	}
+
+	limit := defaultOrdersLast
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxOrdersLast {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit должен быть от 1 до " + strconv.Itoa(maxOrdersLast)})
+			return
+		}
+	}
+
+	self := r.Header.Get("X-User-ID") == userID.String()
+	if !self && !rbac.Has(r, rbac.UsersRead) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "Доступ запрещен"})
+		return
+	}
+
+	ctx := clients.WithRequest(r.Context(), r)
+
+	profile := start(func() (interface{}, error) {
+		return h.users.Get(ctx, userID)
+	})
+
+	orders := start(func() (interface{}, error) {
+		params := ordersclient.ListParams{Limit: limit, Sort: "created_at", Order: "desc"}
+
+		// Чужие заказы доступны только через административный список
+		if !self {
+			params.UserID = userID
+			return h.orders.ListAll(ctx, params)
+		}
+		return h.orders.List(ctx, params)
+	})
+
+	value, err := profile.wait()
+	if err != nil {
+		status, body := overviewError("user", err)
+		writeJSON(w, status, map[string]string{"error": body.Message})
+		return
+	}
+
+	overview := Overview{User: value.(*usersclient.User), Orders: []ordersclient.Order{}}
+
+	if value, err := orders.wait(); err != nil {
+		_, body := overviewError("orders", err)
+		overview.Errors = append(overview.Errors, body)
+	} else {
+		page := value.(*ordersclient.Page)
+		if page.Orders != nil {
+			overview.Orders = page.Orders
+		}
+		overview.OrdersTotal = page.Total
+	}
+
+	writeJSON(w, http.StatusOK, overview)
+}
+
+// overviewError возвращает статус и описание ошибки вызова сервиса. Ошибки,
+// не пришедшие от сервиса (недоступность, некорректный ответ), считаются 502
+func overviewError(source string, err error) (int, OverviewError) {
+	var upstreamErr *clients.Error
+	if errors.As(err, &upstreamErr) {
+		return upstreamErr.Status, OverviewError{
+			Source:  source,
+			Status:  upstreamErr.Status,
+			Code:    upstreamErr.Code,
+			Message: upstreamErr.Message,
+		}
+	}
+	return http.StatusBadGateway, OverviewError{Source: source, Status: http.StatusBadGateway, Message: err.Error()}
+}
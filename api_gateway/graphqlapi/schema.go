@@ -0,0 +1,308 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	ordersclient "pkg/clients/orders"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+const (
+	// defaultOrdersLast количество заказов по умолчанию
+	defaultOrdersLast = 10
+	// maxOrdersLast максимальный размер страницы service_orders
+	maxOrdersLast = 100
+)
+
+// user профиль пользователя из service_users
+type user struct {
+	ID        string   `json:"id"`
+	Email     string   `json:"email"`
+	Name      string   `json:"name"`
+	Roles     []string `json:"roles"`
+	CreatedAt string   `json:"created_at"`
+
+	// prefetched заказы, запрошенные параллельно с профилем
+	prefetched map[ordersArgs]*call
+}
+
+// order заказ из service_orders
+type order struct {
+	ID         string      `json:"id"`
+	UserID     string      `json:"user_id"`
+	Status     string      `json:"status"`
+	StatusName string      `json:"status_name"`
+	TotalSum   float64     `json:"total_sum"`
+	Items      []orderItem `json:"items"`
+	CreatedAt  string      `json:"created_at"`
+	UpdatedAt  string      `json:"updated_at"`
+}
+
+// orderItem позиция заказа
+type orderItem struct {
+	Product  string  `json:"product"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+// ordersArgs аргументы выборки заказов
+type ordersArgs struct {
+	Last   int
+	Status string
+}
+
+// call результат вызова сервиса, выполняемого в отдельной горутине
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// start запускает fn асинхронно
+func start(fn func() (interface{}, error)) *call {
+	c := &call{done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		c.value, c.err = fn()
+	}()
+	return c
+}
+
+// wait дожидается результата; используется как thunk GraphQL резолвера,
+// поэтому независимые поля запроса разрешаются параллельно
+func (c *call) wait() (interface{}, error) {
+	<-c.done
+	return c.value, c.err
+}
+
+// newSchema строит схему:
+//
+//	me: User               — профиль текущего пользователя
+//	orders(last, status)   — последние заказы текущего пользователя
+//	order(id)              — заказ по ID
+//	User.orders(last, status)
+func (h *Handler) newSchema() (graphql.Schema, error) {
+	orderItemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "OrderItem",
+		Fields: graphql.Fields{
+			"product":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"quantity": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"price":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		},
+	})
+
+	orderType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Order",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"user_id":     &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"status":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"status_name": &graphql.Field{Type: graphql.String},
+			"total_sum":   &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+			"items":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(orderItemType)))},
+			"created_at":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"updated_at":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	ordersField := func(resolve graphql.FieldResolveFn) *graphql.Field {
+		return &graphql.Field{
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(orderType))),
+			Args: graphql.FieldConfigArgument{
+				"last": &graphql.ArgumentConfig{
+					Type:         graphql.Int,
+					DefaultValue: defaultOrdersLast,
+					Description:  fmt.Sprintf("Количество последних заказов (1-%d)", maxOrdersLast),
+				},
+				"status": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: resolve,
+		}
+	}
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"email":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"name":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"roles":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+			"created_at": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"orders":     ordersField(h.resolveUserOrders),
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me":     &graphql.Field{Type: userType, Resolve: h.resolveMe},
+			"orders": ordersField(h.resolveOrders),
+			"order": &graphql.Field{
+				Type: orderType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: h.resolveOrder,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// resolveMe запрашивает профиль и одновременно заказы, выбранные в User.orders,
+// чтобы не ждать профиль перед обращением к service_orders
+func (h *Handler) resolveMe(p graphql.ResolveParams) (interface{}, error) {
+	profile := start(func() (interface{}, error) {
+		u, err := h.users.Profile(p.Context)
+		if err != nil {
+			return nil, err
+		}
+		return newUser(u), nil
+	})
+
+	prefetched := make(map[ordersArgs]*call)
+	for _, args := range selectedOrdersArgs(p) {
+		if _, ok := prefetched[args]; !ok {
+			prefetched[args] = h.startOrders(p.Context, args)
+		}
+	}
+
+	return func() (interface{}, error) {
+		value, err := profile.wait()
+		if err != nil {
+			return nil, err
+		}
+		u := value.(*user)
+		u.prefetched = prefetched
+		return u, nil
+	}, nil
+}
+
+// resolveUserOrders возвращает заказы пользователя, используя заранее начатый запрос
+func (h *Handler) resolveUserOrders(p graphql.ResolveParams) (interface{}, error) {
+	args, err := parseOrdersArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	if u, ok := p.Source.(*user); ok {
+		if c, ok := u.prefetched[args]; ok {
+			return c.wait, nil
+		}
+	}
+	return h.startOrders(p.Context, args).wait, nil
+}
+
+// resolveOrders возвращает последние заказы текущего пользователя
+func (h *Handler) resolveOrders(p graphql.ResolveParams) (interface{}, error) {
+	args, err := parseOrdersArgs(p.Args)
+	if err != nil {
+		return nil, err
+	}
+	return h.startOrders(p.Context, args).wait, nil
+}
+
+// resolveOrder возвращает заказ по ID
+func (h *Handler) resolveOrder(p graphql.ResolveParams) (interface{}, error) {
+	id, _ := p.Args["id"].(string)
+
+	orderID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("Некорректный ID заказа")
+	}
+
+	return start(func() (interface{}, error) {
+		o, err := h.orders.Get(p.Context, orderID)
+		if err != nil {
+			return nil, err
+		}
+		return newOrder(o), nil
+	}).wait, nil
+}
+
+// startOrders запускает запрос последних заказов текущего пользователя
+func (h *Handler) startOrders(ctx context.Context, args ordersArgs) *call {
+	return start(func() (interface{}, error) {
+		page, err := h.orders.List(ctx, ordersclient.ListParams{
+			Limit:  args.Last,
+			Status: args.Status,
+			Sort:   "created_at",
+			Order:  "desc",
+		})
+		if err != nil {
+			return nil, err
+		}
+		return newOrders(page.Orders), nil
+	})
+}
+
+// parseOrdersArgs проверяет аргументы выборки заказов
+func parseOrdersArgs(values map[string]interface{}) (ordersArgs, error) {
+	args := ordersArgs{Last: defaultOrdersLast}
+	if last, ok := values["last"].(int); ok {
+		args.Last = last
+	}
+	if status, ok := values["status"].(string); ok {
+		args.Status = status
+	}
+
+	if args.Last < 1 || args.Last > maxOrdersLast {
+		return args, fmt.Errorf("last должен быть от 1 до %d", maxOrdersLast)
+	}
+	return args, nil
+}
+
+// selectedOrdersArgs возвращает аргументы полей orders, выбранных непосредственно
+// в текущем поле. Поля во фрагментах и с некорректными аргументами пропускаются —
+// они будут запрошены обычным резолвером
+func selectedOrdersArgs(p graphql.ResolveParams) []ordersArgs {
+	var result []ordersArgs
+
+	for _, field := range p.Info.FieldASTs {
+		if field.SelectionSet == nil {
+			continue
+		}
+		for _, selection := range field.SelectionSet.Selections {
+			child, ok := selection.(*ast.Field)
+			if !ok || child.Name == nil || child.Name.Value != "orders" {
+				continue
+			}
+
+			values := make(map[string]interface{})
+			for _, argument := range child.Arguments {
+				values[argument.Name.Value] = astValue(argument.Value, p.Info.VariableValues)
+			}
+
+			if args, err := parseOrdersArgs(values); err == nil {
+				result = append(result, args)
+			}
+		}
+	}
+	return result
+}
+
+// astValue возвращает значение литерала или переменной аргумента
+func astValue(value ast.Value, variables map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case *ast.Variable:
+		if v.Name == nil {
+			return nil
+		}
+		return variables[v.Name.Value]
+	case *ast.IntValue:
+		n, err := strconv.Atoi(v.Value)
+		if err != nil {
+			return nil
+		}
+		return n
+	case *ast.StringValue:
+		return v.Value
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package graphqlapi
+
+import (
+	"time"
+
+	"pkg/clients"
+	ordersclient "pkg/clients/orders"
+	usersclient "pkg/clients/users"
+)
+
+// errorExtensions дополняет ошибку GraphQL кодом и статусом сервиса
+func errorExtensions(err *clients.Error) map[string]interface{} {
+	extensions := map[string]interface{}{"status": err.Status}
+	if err.Code != "" {
+		extensions["code"] = err.Code
+	}
+	return extensions
+}
+
+// newUser преобразует профиль клиента service_users в тип схемы
+func newUser(u *usersclient.User) *user {
+	return &user{
+		ID:        u.ID.String(),
+		Email:     u.Email,
+		Name:      u.Name,
+		Roles:     u.Roles,
+		CreatedAt: u.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// newOrders преобразует заказы клиента service_orders в типы схемы
+func newOrders(orders []ordersclient.Order) []*order {
+	result := make([]*order, 0, len(orders))
+	for i := range orders {
+		result = append(result, newOrder(&orders[i]))
+	}
+	return result
+}
+
+// newOrder преобразует заказ клиента service_orders в тип схемы
+func newOrder(o *ordersclient.Order) *order {
+	items := make([]orderItem, 0, len(o.Items))
+	for _, item := range o.Items {
+		items = append(items, orderItem{Product: item.Product, Quantity: item.Quantity, Price: item.Price})
+	}
+	return &order{
+		ID:         o.ID.String(),
+		UserID:     o.UserID.String(),
+		Status:     o.Status,
+		StatusName: o.StatusName,
+		TotalSum:   o.TotalSum,
+		Items:      items,
+		CreatedAt:  o.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt:  o.UpdatedAt.Format(time.RFC3339Nano),
+	}
+}
@@ -0,0 +1,369 @@
+// Package grpcproxy проксирует HTTP/JSON запросы к gRPC сервисам с транскодированием
+// JSON в protobuf. Описания сервисов загружаются из FileDescriptorSet
+// (protoc --include_imports --descriptor_set_out), поэтому шлюзу не нужен
+// сгенерированный код: сервисы переходят на gRPC без изменения публичного API
+package grpcproxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pkg/httpresp"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// maxRequestBodySize максимальный размер JSON тела запроса
+const maxRequestBodySize = 4 << 20
+
+// forwardedHeaders заголовки, передаваемые в gRPC метаданных
+var forwardedHeaders = []string{"X-Request-ID", "traceparent", "tracestate", "X-User-ID", "X-User-Email", "X-User-Roles", "X-User-Permissions", "Authorization", "Accept-Language"}
+
+// Route маршрут HTTP → gRPC
+type Route struct {
+	HTTPMethod string
+	// Path шаблон пути gorilla/mux, например /v1/quotes/{id}
+	Path string
+	// Target адрес gRPC сервиса host:port
+	Target string
+	// FullMethod полное имя метода package.Service/Method
+	FullMethod string
+}
+
+// ParseRoutes разбирает маршруты в формате
+// "POST /v1/quotes=orders-grpc:9090/orders.v1.QuoteService/Create;GET /v1/quotes/{id}=..."
+func ParseRoutes(value string) ([]Route, error) {
+	var routes []Route
+	for _, item := range strings.Split(value, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		left, right, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("некорректный gRPC маршрут %q: ожидается 'METHOD /path=host:port/package.Service/Method'", item)
+		}
+
+		fields := strings.Fields(left)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], "/") {
+			return nil, fmt.Errorf("некорректный gRPC маршрут %q: ожидается 'METHOD /path'", item)
+		}
+
+		target, method, ok := strings.Cut(strings.TrimSpace(right), "/")
+		if !ok || target == "" || !strings.Contains(method, "/") {
+			return nil, fmt.Errorf("некорректный gRPC маршрут %q: ожидается 'host:port/package.Service/Method'", item)
+		}
+
+		routes = append(routes, Route{
+			HTTPMethod: strings.ToUpper(fields[0]),
+			Path:       fields[1],
+			Target:     target,
+			FullMethod: method,
+		})
+	}
+	return routes, nil
+}
+
+// Transcoder создает обработчики маршрутов и хранит соединения с gRPC сервисами
+type Transcoder struct {
+	files   *protoregistry.Files
+	timeout time.Duration
+	creds   credentials.TransportCredentials
+
+	mutex sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewTranscoder загружает FileDescriptorSet из descriptorSetPath.
+// timeout ограничивает время gRPC вызова, useTLS включает TLS до сервисов
+func NewTranscoder(descriptorSetPath string, timeout time.Duration, useTLS bool) (*Transcoder, error) {
+	data, err := os.ReadFile(descriptorSetPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения gRPC дескрипторов: %v", err)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("ошибка разбора gRPC дескрипторов: %v", err)
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки gRPC дескрипторов: %v", err)
+	}
+
+	return newTranscoder(files, timeout, useTLS), nil
+}
+
+func newTranscoder(files *protoregistry.Files, timeout time.Duration, useTLS bool) *Transcoder {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(nil)
+	}
+
+	return &Transcoder{
+		files:   files,
+		timeout: timeout,
+		creds:   creds,
+		conns:   make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Endpoint создает обработчик маршрута. Метод ищется в дескрипторах сразу,
+// чтобы ошибка конфигурации обнаруживалась при запуске, а не на первом запросе
+func (t *Transcoder) Endpoint(route Route) (*Endpoint, error) {
+	name := protoreflect.FullName(strings.Replace(route.FullMethod, "/", ".", 1))
+	desc, err := t.files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("gRPC метод %s не найден в дескрипторах: %v", route.FullMethod, err)
+	}
+
+	method, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s не является gRPC методом", route.FullMethod)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, fmt.Errorf("потоковый gRPC метод %s не поддерживается", route.FullMethod)
+	}
+
+	conn, err := t.conn(route.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Endpoint{
+		Route:   route,
+		method:  method,
+		conn:    conn,
+		timeout: t.timeout,
+	}, nil
+}
+
+// conn возвращает общее соединение с сервисом; соединение устанавливается лениво
+func (t *Transcoder) conn(target string) (*grpc.ClientConn, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if conn, ok := t.conns[target]; ok {
+		return conn, nil
+	}
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(t.creds))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания gRPC клиента %s: %v", target, err)
+	}
+	t.conns[target] = conn
+	return conn, nil
+}
+
+// Close закрывает соединения с gRPC сервисами
+func (t *Transcoder) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var lastErr error
+	for target, conn := range t.conns {
+		if err := conn.Close(); err != nil {
+			lastErr = fmt.Errorf("ошибка закрытия gRPC соединения %s: %v", target, err)
+		}
+		delete(t.conns, target)
+	}
+	return lastErr
+}
+
+// Endpoint HTTP обработчик одного gRPC метода
+type Endpoint struct {
+	Route Route
+
+	method  protoreflect.MethodDescriptor
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+// ServeHTTP транскодирует запрос: JSON тело, параметры запроса и переменные пути
+// (в порядке возрастания приоритета) заполняют входное сообщение метода
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	input := dynamicpb.NewMessage(e.method.Input())
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Некорректное тело запроса")
+		return
+	}
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := protojson.Unmarshal(body, input); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("Некорректный JSON: %v", err))
+			return
+		}
+	}
+
+	for key, values := range r.URL.Query() {
+		if err := setField(input, key, values); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	for key, value := range mux.Vars(r) {
+		if err := setField(input, key, []string{value}); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	ctx := r.Context()
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	md := metadata.MD{}
+	for _, header := range forwardedHeaders {
+		if value := r.Header.Get(header); value != "" {
+			md.Set(strings.ToLower(header), value)
+		}
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	output := dynamicpb.NewMessage(e.method.Output())
+	if err := e.conn.Invoke(ctx, "/"+e.Route.FullMethod, input, output); err != nil {
+		st := status.Convert(err)
+		writeError(w, HTTPStatus(st.Code()), st.Message())
+		return
+	}
+
+	data, err := protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(output)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Ошибка сериализации ответа")
+		return
+	}
+	httpresp.Write(w, http.StatusOK, httpresp.ContentTypeJSON, data)
+}
+
+// setField устанавливает поле сообщения по имени (proto или JSON) из строковых значений.
+// Поддерживаются скалярные и повторяющиеся скалярные поля; неизвестные параметры игнорируются
+func setField(msg *dynamicpb.Message, name string, values []string) error {
+	fields := msg.Descriptor().Fields()
+	field := fields.ByName(protoreflect.Name(name))
+	if field == nil {
+		field = fields.ByJSONName(name)
+	}
+	if field == nil || len(values) == 0 {
+		return nil
+	}
+	if field.Message() != nil || field.IsMap() {
+		return fmt.Errorf("параметр %s не может быть задан в пути или строке запроса", name)
+	}
+
+	if field.IsList() {
+		list := msg.Mutable(field).List()
+		for _, value := range values {
+			v, err := parseScalar(field, value)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+
+	v, err := parseScalar(field, values[len(values)-1])
+	if err != nil {
+		return err
+	}
+	msg.Set(field, v)
+	return nil
+}
+
+// parseScalar преобразует строку в значение скалярного поля
+func parseScalar(field protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	if field.Kind() == protoreflect.EnumKind {
+		if enumValue := field.Enum().Values().ByName(protoreflect.Name(value)); enumValue != nil {
+			return protoreflect.ValueOfEnum(enumValue.Number()), nil
+		}
+	}
+
+	// Остальные типы разбираются по правилам protojson (числа допускаются в виде строк)
+	literal, err := json.Marshal(value)
+	if err != nil {
+		return protoreflect.Value{}, err
+	}
+	if field.Kind() == protoreflect.BoolKind {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("некорректное значение параметра %s: %q", field.Name(), value)
+		}
+		literal = []byte(strconv.FormatBool(b))
+	}
+	if field.IsList() {
+		literal = append(append([]byte("["), literal...), ']')
+	}
+
+	holder := dynamicpb.NewMessage(field.ContainingMessage())
+	document := fmt.Sprintf("{%q:%s}", field.JSONName(), literal)
+	if err := protojson.Unmarshal([]byte(document), holder); err != nil {
+		return protoreflect.Value{}, fmt.Errorf("некорректное значение параметра %s: %q", field.Name(), value)
+	}
+
+	v := holder.Get(field)
+	if field.IsList() {
+		return v.List().Get(0), nil
+	}
+	return v, nil
+}
+
+// HTTPStatus сопоставляет код gRPC статусу HTTP (как в grpc-gateway)
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError отправляет ошибку в формате шлюза
+func writeError(w http.ResponseWriter, code int, message string) {
+	httpresp.JSON(w, code, map[string]string{"error": message})
+}
@@ -0,0 +1,236 @@
+// Package jwks загружает и кеширует публичные ключи JWKS для проверки
+// JWT токенов, подписанных асимметричными алгоритмами (RS256, ES256)
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval минимальный интервал между внеплановыми загрузками JWKS
+// при запросе неизвестного kid (защита от перегрузки провайдера ключей)
+const minRefreshInterval = 30 * time.Second
+
+// maxRetryInterval предельный интервал между повторными загрузками после ошибок:
+// после каждой неудачной загрузки интервал удваивается, начиная с minRefreshInterval
+const maxRetryInterval = 5 * time.Minute
+
+// jsonWebKey публичный ключ в формате JWK (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// KeySet кеш ключей JWKS. Ключи перезагружаются по истечении refreshInterval,
+// а также при запросе неизвестного kid — так поддерживается ротация ключей.
+// Загрузка выполняется вне мьютекса и не более одной одновременно: пока она идет,
+// известные ключи отдаются из кеша, а запросы неизвестного kid ждут ее результата
+type KeySet struct {
+	url             string
+	refreshInterval time.Duration
+	client          *http.Client
+
+	mutex     sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+	// attemptedAt время начала последней загрузки, в том числе неудачной
+	attemptedAt time.Time
+	// failures число неудачных загрузок подряд, lastErr ошибка последней из них
+	failures int
+	lastErr  error
+	// refreshing закрывается по завершении текущей загрузки; nil, если загрузка не идет
+	refreshing chan struct{}
+}
+
+// NewKeySet создает KeySet для указанного JWKS endpoint
+func NewKeySet(url string, refreshInterval time.Duration, client *http.Client) *KeySet {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &KeySet{
+		url:             url,
+		refreshInterval: refreshInterval,
+		client:          client,
+		keys:            make(map[string]interface{}),
+	}
+}
+
+// Key возвращает публичный ключ (*rsa.PublicKey или *ecdsa.PublicKey) по kid
+func (ks *KeySet) Key(kid string) (interface{}, error) {
+	ks.mutex.Lock()
+	key, ok := ks.keys[kid]
+	if ks.refreshDue(ok) {
+		ks.startRefresh()
+	}
+	done := ks.refreshing
+	ks.mutex.Unlock()
+
+	// При недоступности JWKS и во время загрузки используем закешированные ключи
+	if ok {
+		return key, nil
+	}
+	if done != nil {
+		<-done
+	}
+
+	ks.mutex.Lock()
+	defer ks.mutex.Unlock()
+	if key, ok := ks.keys[kid]; ok {
+		return key, nil
+	}
+	if ks.lastErr != nil {
+		return nil, ks.lastErr
+	}
+	return nil, fmt.Errorf("ключ с kid %q не найден в JWKS", kid)
+}
+
+// refreshDue проверяет, пора ли загружать JWKS; вызывается под мьютексом.
+// После неудачной загрузки следующая выполняется не раньше интервала повтора,
+// даже если ключи устарели или запрошен неизвестный kid
+func (ks *KeySet) refreshDue(known bool) bool {
+	sinceAttempt := time.Since(ks.attemptedAt)
+	if ks.failures > 0 {
+		return sinceAttempt >= retryInterval(ks.failures)
+	}
+	if time.Since(ks.fetchedAt) > ks.refreshInterval {
+		return true
+	}
+	return !known && sinceAttempt > minRefreshInterval
+}
+
+// retryInterval возвращает интервал до повторной загрузки после failures ошибок подряд
+func retryInterval(failures int) time.Duration {
+	interval := minRefreshInterval
+	for i := 1; i < failures && interval < maxRetryInterval; i++ {
+		interval *= 2
+	}
+	return min(interval, maxRetryInterval)
+}
+
+// startRefresh запускает загрузку JWKS в фоне, если она еще не идет; вызывается под мьютексом
+func (ks *KeySet) startRefresh() {
+	if ks.refreshing != nil {
+		return
+	}
+	done := make(chan struct{})
+	ks.refreshing = done
+	ks.attemptedAt = time.Now()
+
+	go func() {
+		keys, err := ks.fetch()
+
+		ks.mutex.Lock()
+		defer ks.mutex.Unlock()
+		if err != nil {
+			ks.failures++
+			ks.lastErr = err
+		} else {
+			ks.keys = keys
+			ks.fetchedAt = time.Now()
+			ks.failures = 0
+			ks.lastErr = nil
+		}
+		ks.refreshing = nil
+		close(done)
+	}()
+}
+
+// fetch загружает и разбирает JWKS
+func (ks *KeySet) fetch() (map[string]interface{}, error) {
+	resp, err := ks.client.Get(ks.url)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ошибка загрузки JWKS: статус %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("ошибка разбора JWKS: %v", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Некорректный или неподдерживаемый ключ не должен ломать остальные
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+// publicKey преобразует JWK в публичный ключ
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("неподдерживаемая кривая %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("точка ключа %q не лежит на кривой", jwk.Kid)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("неподдерживаемый тип ключа %q", jwk.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка декодирования параметра ключа: %v", err)
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,178 @@
+package jwks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer JWKS endpoint с подменяемыми ключами и счетчиком запросов
+type jwksServer struct {
+	*httptest.Server
+	hits atomic.Int32
+
+	mutex  sync.Mutex
+	keys   []jsonWebKey
+	status int
+	// block задерживает ответ, пока канал не будет закрыт
+	block chan struct{}
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	s := &jwksServer{status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		s.mutex.Lock()
+		keys, status, block := s.keys, s.status, s.block
+		s.mutex.Unlock()
+
+		if block != nil {
+			<-block
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(status int, block chan struct{}, kids ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status, s.block, s.keys = status, block, nil
+	for _, kid := range kids {
+		s.keys = append(s.keys, ecKey(kid))
+	}
+}
+
+// ecKey возвращает публичный ключ P-256 в формате JWK
+func ecKey(kid string) jsonWebKey {
+	private, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	coordinate := func(value []byte) string { return base64.RawURLEncoding.EncodeToString(value) }
+	return jsonWebKey{
+		Kty: "EC", Kid: kid, Use: "sig", Crv: "P-256",
+		X: coordinate(private.X.FillBytes(make([]byte, 32))),
+		Y: coordinate(private.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func TestKeySetSingleFlight(t *testing.T) {
+	server := newJWKSServer(t)
+	release := make(chan struct{})
+	server.set(http.StatusOK, release, "a")
+	ks := NewKeySet(server.URL, time.Hour, nil)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ks.Key("a")
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("ошибка получения ключа: %v", err)
+		}
+	}
+	if hits := server.hits.Load(); hits != 1 {
+		t.Fatalf("запросов к JWKS %d, ожидался 1", hits)
+	}
+}
+
+func TestKeySetServesCachedKeysDuringRefresh(t *testing.T) {
+	server := newJWKSServer(t)
+	server.set(http.StatusOK, nil, "a")
+	ks := NewKeySet(server.URL, time.Hour, nil)
+	if _, err := ks.Key("a"); err != nil {
+		t.Fatalf("ошибка первой загрузки: %v", err)
+	}
+
+	// Ключи устарели, а провайдер отвечает медленно: известный ключ отдается из кеша
+	release := make(chan struct{})
+	defer close(release)
+	server.set(http.StatusOK, release, "a")
+	ks.mutex.Lock()
+	ks.fetchedAt = time.Now().Add(-2 * time.Hour)
+	ks.mutex.Unlock()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := ks.Key("a")
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("ошибка получения закешированного ключа: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("запрос известного ключа ждет загрузки JWKS")
+	}
+}
+
+func TestKeySetBacksOffAfterFailure(t *testing.T) {
+	server := newJWKSServer(t)
+	server.set(http.StatusOK, nil, "a")
+	ks := NewKeySet(server.URL, time.Hour, nil)
+	if _, err := ks.Key("a"); err != nil {
+		t.Fatalf("ошибка первой загрузки: %v", err)
+	}
+
+	// Неизвестный kid при недоступном провайдере: одна загрузка, дальше — интервал повтора
+	server.set(http.StatusServiceUnavailable, nil)
+	ks.mutex.Lock()
+	ks.attemptedAt = time.Now().Add(-time.Minute)
+	ks.mutex.Unlock()
+
+	for i := 0; i < 5; i++ {
+		if _, err := ks.Key("b"); err == nil {
+			t.Fatal("получен неизвестный ключ при недоступном JWKS")
+		}
+	}
+	if hits := server.hits.Load(); hits != 2 {
+		t.Fatalf("запросов к JWKS %d, ожидалось 2", hits)
+	}
+	if _, err := ks.Key("a"); err != nil {
+		t.Fatalf("закешированный ключ недоступен после ошибки загрузки: %v", err)
+	}
+
+	// По истечении интервала повтора загрузка выполняется снова
+	server.set(http.StatusOK, nil, "a", "b")
+	ks.mutex.Lock()
+	ks.attemptedAt = time.Now().Add(-retryInterval(ks.failures))
+	ks.mutex.Unlock()
+	if _, err := ks.Key("b"); err != nil {
+		t.Fatalf("ключ не загружен после интервала повтора: %v", err)
+	}
+}
+
+func TestRetryInterval(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		4:  4 * time.Minute,
+		5:  5 * time.Minute,
+		20: 5 * time.Minute,
+	} {
+		if got := retryInterval(failures); got != want {
+			t.Errorf("retryInterval(%d) = %v, ожидалось %v", failures, got, want)
+		}
+	}
+}
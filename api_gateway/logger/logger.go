@@ -73,10 +73,8 @@ func WithUserContext(logger *zap.Logger, userID, email string, roles []string) *
 	return logger
 }
 
-// LogHTTPRequest логирует HTTP запрос с контекстом
-func LogHTTPRequest(r *http.Request, statusCode int, method, path, service string) {
-	logger := GetLogger()
-	
+// LogHTTPRequest логирует HTTP запрос с контекстом в переданный логгер
+func LogHTTPRequest(logger *zap.Logger, r *http.Request, statusCode int, method, path, service string) {
 	// Добавляем Request ID если есть
 	if requestID := r.Header.Get("X-Request-ID"); requestID != "" {
 		logger = WithRequestID(logger, requestID)
@@ -106,10 +104,8 @@ func LogHTTPRequest(r *http.Request, statusCode int, method, path, service strin
 	)
 }
 
-// LogServiceCall логирует вызов между сервисами
-func LogServiceCall(requestID, fromService, toService, operation string, success bool, err error) {
-	logger := GetLogger()
-	
+// LogServiceCall логирует вызов между сервисами в переданный логгер
+func LogServiceCall(logger *zap.Logger, requestID, fromService, toService, operation string, success bool, err error) {
 	if requestID != "" {
 		logger = WithRequestID(logger, requestID)
 	}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CostCenterMetrics считает запросы, трафик и длительность по центрам затрат.
+// Теги задаются правилами конфигурации, поэтому кардинальность меток ограничена
+type CostCenterMetrics struct {
+	requests *prometheus.CounterVec
+	bytes    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewCostCenterMetrics создает метрики и регистрирует их в registerer
+func NewCostCenterMetrics(registerer prometheus.Registerer) (*CostCenterMetrics, error) {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gateway",
+		Subsystem: "cost_center",
+		Name:      "requests_total",
+		Help:      "Запросы по центрам затрат и классу кода ответа",
+	}, []string{"cost_center", "code_class"})
+
+	bytes := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gateway",
+		Subsystem: "cost_center",
+		Name:      "bytes_total",
+		Help:      "Объем запросов (in) и ответов (out) по центрам затрат",
+	}, []string{"cost_center", "direction"})
+
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gateway",
+		Subsystem: "cost_center",
+		Name:      "request_duration_seconds",
+		Help:      "Длительность запросов по центрам затрат",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"cost_center"})
+
+	for _, collector := range []prometheus.Collector{requests, bytes, duration} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return &CostCenterMetrics{requests: requests, bytes: bytes, duration: duration}, nil
+}
+
+// Observe учитывает завершенный запрос центра затрат tag
+func (m *CostCenterMetrics) Observe(tag string, statusCode int, bytesIn, bytesOut int64, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(tag, strconv.Itoa(statusCode/100)+"xx").Inc()
+	m.bytes.WithLabelValues(tag, "in").Add(float64(bytesIn))
+	m.bytes.WithLabelValues(tag, "out").Add(float64(bytesOut))
+	m.duration.WithLabelValues(tag).Observe(duration.Seconds())
+}
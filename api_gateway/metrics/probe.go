@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ProbeMetrics результаты синтетических проверок пользовательского сценария
+// (cmd/synthetic_probe): итоги прогонов, длительность и ошибки шагов
+type ProbeMetrics struct {
+	runs         *prometheus.CounterVec
+	stepDuration *prometheus.HistogramVec
+	stepFailures *prometheus.CounterVec
+	up           prometheus.Gauge
+	lastSuccess  prometheus.Gauge
+}
+
+// NewProbeMetrics создает метрики и регистрирует их в registerer
+func NewProbeMetrics(registerer prometheus.Registerer) (*ProbeMetrics, error) {
+	runs := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "synthetic",
+		Subsystem: "probe",
+		Name:      "runs_total",
+		Help:      "Прогоны сценария по результату (success, failure)",
+	}, []string{"result"})
+
+	stepDuration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "synthetic",
+		Subsystem: "probe",
+		Name:      "step_duration_seconds",
+		Help:      "Длительность шагов сценария",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"step"})
+
+	stepFailures := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "synthetic",
+		Subsystem: "probe",
+		Name:      "step_failures_total",
+		Help:      "Ошибки шагов сценария",
+	}, []string{"step"})
+
+	up := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "synthetic",
+		Subsystem: "probe",
+		Name:      "up",
+		Help:      "1, если последний прогон сценария успешен",
+	})
+
+	lastSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "synthetic",
+		Subsystem: "probe",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Время последнего успешного прогона (Unix)",
+	})
+
+	for _, collector := range []prometheus.Collector{runs, stepDuration, stepFailures, up, lastSuccess} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ProbeMetrics{runs: runs, stepDuration: stepDuration, stepFailures: stepFailures, up: up, lastSuccess: lastSuccess}, nil
+}
+
+// ObserveStep учитывает завершенный шаг сценария
+func (m *ProbeMetrics) ObserveStep(step string, duration time.Duration, failed bool) {
+	m.stepDuration.WithLabelValues(step).Observe(duration.Seconds())
+	if failed {
+		m.stepFailures.WithLabelValues(step).Inc()
+	}
+}
+
+// ObserveRun учитывает итог прогона сценария
+func (m *ProbeMetrics) ObserveRun(success bool, finishedAt time.Time) {
+	if !success {
+		m.runs.WithLabelValues("failure").Inc()
+		m.up.Set(0)
+		return
+	}
+	m.runs.WithLabelValues("success").Inc()
+	m.up.Set(1)
+	m.lastSuccess.Set(float64(finishedAt.Unix()))
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ProtocolMetrics версии HTTP и повторное использование соединений: клиентских соединений
+// шлюза и соединений шлюза с сервисами. Отношение числа запросов к числу новых соединений
+// показывает, насколько клиенты и прокси используют keep-alive и мультиплексирование HTTP/2
+type ProtocolMetrics struct {
+	clientRequests    *prometheus.CounterVec
+	clientConnections prometheus.Counter
+	clientOpen        prometheus.Gauge
+	upstreamConns     *prometheus.CounterVec
+	upstreamRequests  *prometheus.CounterVec
+}
+
+// NewProtocolMetrics создает метрики и регистрирует их в registerer
+func NewProtocolMetrics(registerer prometheus.Registerer) (*ProtocolMetrics, error) {
+	m := &ProtocolMetrics{
+		clientRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "client",
+			Name:      "requests_total",
+			Help:      "Запросы клиентов по версии HTTP (HTTP/1.1, HTTP/2.0)",
+		}, []string{"protocol"}),
+		clientConnections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "client",
+			Name:      "connections_total",
+			Help:      "Принятые соединения клиентов",
+		}),
+		clientOpen: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "gateway",
+			Subsystem: "client",
+			Name:      "open_connections",
+			Help:      "Открытые соединения клиентов",
+		}),
+		upstreamConns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "upstream",
+			Name:      "connections_total",
+			Help:      "Соединения, полученные для запросов к сервисам: новые (reused=false) и повторно использованные",
+		}, []string{"upstream", "reused"}),
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gateway",
+			Subsystem: "upstream",
+			Name:      "protocol_requests_total",
+			Help:      "Ответы сервисов по версии HTTP",
+		}, []string{"upstream", "protocol"}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.clientRequests, m.clientConnections, m.clientOpen, m.upstreamConns, m.upstreamRequests} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// ObserveClientRequest учитывает запрос клиента с версией протокола r.Proto
+func (m *ProtocolMetrics) ObserveClientRequest(r *http.Request) {
+	if m == nil {
+		return
+	}
+	m.clientRequests.WithLabelValues(r.Proto).Inc()
+}
+
+// ConnState учитывает открытие и закрытие клиентских соединений (http.Server.ConnState)
+func (m *ProtocolMetrics) ConnState(_ net.Conn, state http.ConnState) {
+	if m == nil {
+		return
+	}
+	switch state {
+	case http.StateNew:
+		m.clientConnections.Inc()
+		m.clientOpen.Inc()
+	case http.StateClosed, http.StateHijacked:
+		m.clientOpen.Dec()
+	}
+}
+
+// ObserveUpstreamConn учитывает соединение, полученное для запроса к upstream
+func (m *ProtocolMetrics) ObserveUpstreamConn(upstream string, reused bool) {
+	if m == nil {
+		return
+	}
+	label := "false"
+	if reused {
+		label = "true"
+	}
+	m.upstreamConns.WithLabelValues(upstream, label).Inc()
+}
+
+// ObserveUpstreamResponse учитывает ответ upstream с версией протокола proto
+func (m *ProtocolMetrics) ObserveUpstreamResponse(upstream, proto string) {
+	if m == nil {
+		return
+	}
+	m.upstreamRequests.WithLabelValues(upstream, proto).Inc()
+}
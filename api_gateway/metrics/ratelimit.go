@@ -0,0 +1,144 @@
+// Package metrics содержит Prometheus метрики API Gateway
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Классы ключей ограничителя частоты запросов
+const (
+	KeyClassIP     = "ip"
+	KeyClassUser   = "user"
+	KeyClassAPIKey = "api_key"
+	KeyClassRoute  = "route"
+)
+
+// OtherKey значение метки для ключей, не входящих в top-K
+const OtherKey = "other"
+
+// Решения ограничителя
+const (
+	DecisionAllowed  = "allowed"
+	DecisionRejected = "rejected"
+)
+
+// RateLimitMetrics считает решения ограничителя частоты запросов по классам ключей.
+// Чтобы число серий не росло неограниченно, отдельная метка key сохраняется
+// только для top-K самых активных ключей каждого класса, остальные попадают в "other".
+// При hashKeys значения ключей (IP, ID пользователя, API ключ) хешируются
+type RateLimitMetrics struct {
+	decisions *prometheus.CounterVec
+	topK      int
+	hashKeys  bool
+
+	mutex    sync.Mutex
+	trackers map[string]*topKTracker
+}
+
+// NewRateLimitMetrics создает метрики и регистрирует их в registerer
+func NewRateLimitMetrics(registerer prometheus.Registerer, topK int, hashKeys bool) (*RateLimitMetrics, error) {
+	decisions := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gateway",
+		Subsystem: "rate_limit",
+		Name:      "decisions_total",
+		Help:      "Решения ограничителя частоты запросов по классам ключей",
+	}, []string{"key_class", "key", "decision"})
+
+	if err := registerer.Register(decisions); err != nil {
+		return nil, err
+	}
+
+	return &RateLimitMetrics{
+		decisions: decisions,
+		topK:      topK,
+		hashKeys:  hashKeys,
+		trackers:  make(map[string]*topKTracker),
+	}, nil
+}
+
+// Observe учитывает решение ограничителя для ключа указанного класса
+func (m *RateLimitMetrics) Observe(keyClass, key string, allowed bool) {
+	if m == nil || key == "" {
+		return
+	}
+
+	decision := DecisionAllowed
+	if !allowed {
+		decision = DecisionRejected
+	}
+
+	// Маршруты не содержат персональных данных и не хешируются
+	if m.hashKeys && keyClass != KeyClassRoute {
+		key = hashKey(key)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	tracker, ok := m.trackers[keyClass]
+	if !ok {
+		tracker = newTopKTracker(m.topK)
+		m.trackers[keyClass] = tracker
+	}
+	tracked, evicted := tracker.add(key)
+
+	// Серии вытесненных ключей удаляются, чтобы ограничить кардинальность
+	for _, k := range evicted {
+		m.decisions.DeleteLabelValues(keyClass, k, DecisionAllowed)
+		m.decisions.DeleteLabelValues(keyClass, k, DecisionRejected)
+	}
+
+	if !tracked {
+		key = OtherKey
+	}
+	m.decisions.WithLabelValues(keyClass, key, decision).Inc()
+}
+
+// hashKey возвращает короткий хеш ключа для использования в метке
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:6])
+}
+
+// topKTracker приближенно отслеживает самые частые ключи (алгоритм Misra-Gries):
+// частые ключи удерживаются в наборе, редкие вытесняются и учитываются как "other"
+type topKTracker struct {
+	capacity int
+	counts   map[string]uint64
+}
+
+func newTopKTracker(capacity int) *topKTracker {
+	return &topKTracker{capacity: capacity, counts: make(map[string]uint64)}
+}
+
+// add учитывает ключ и возвращает, отслеживается ли он, а также вытесненные ключи
+func (t *topKTracker) add(key string) (bool, []string) {
+	if t.capacity <= 0 {
+		return false, nil
+	}
+
+	if _, ok := t.counts[key]; ok {
+		t.counts[key]++
+		return true, nil
+	}
+
+	if len(t.counts) < t.capacity {
+		t.counts[key] = 1
+		return true, nil
+	}
+
+	// Набор заполнен: уменьшаем все счетчики, обнулившиеся ключи освобождают место
+	var evicted []string
+	for k := range t.counts {
+		t.counts[k]--
+		if t.counts[k] == 0 {
+			delete(t.counts, k)
+			evicted = append(evicted, k)
+		}
+	}
+	return false, evicted
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// UpstreamMetrics считает запросы к upstream сервисам с разбивкой по версии (stable/canary),
+// чтобы сравнивать ошибки и задержки канареечной версии с основной
+type UpstreamMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewUpstreamMetrics создает метрики и регистрирует их в registerer
+func NewUpstreamMetrics(registerer prometheus.Registerer) (*UpstreamMetrics, error) {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gateway",
+		Subsystem: "upstream",
+		Name:      "requests_total",
+		Help:      "Запросы к upstream сервисам по версии и коду ответа",
+	}, []string{"service", "target", "code"})
+
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gateway",
+		Subsystem: "upstream",
+		Name:      "request_duration_seconds",
+		Help:      "Длительность запросов к upstream сервисам по версии",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "target"})
+
+	for _, collector := range []prometheus.Collector{requests, duration} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return &UpstreamMetrics{requests: requests, duration: duration}, nil
+}
+
+// Observe учитывает завершенный запрос к версии target сервиса service
+func (m *UpstreamMetrics) Observe(service, target string, statusCode int, duration time.Duration) {
+	m.requests.WithLabelValues(service, target, strconv.Itoa(statusCode)).Inc()
+	m.duration.WithLabelValues(service, target).Observe(duration.Seconds())
+}
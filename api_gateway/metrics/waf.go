@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WAFMetrics срабатывания правил фильтра запросов (пакет waf)
+type WAFMetrics struct {
+	matches *prometheus.CounterVec
+}
+
+// NewWAFMetrics создает метрики и регистрирует их в registerer
+func NewWAFMetrics(registerer prometheus.Registerer) (*WAFMetrics, error) {
+	matches := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gateway",
+		Subsystem: "waf",
+		Name:      "matches_total",
+		Help:      "Срабатывания правил фильтра запросов по действию (blocked, detected)",
+	}, []string{"rule", "action"})
+
+	if err := registerer.Register(matches); err != nil {
+		return nil, err
+	}
+	return &WAFMetrics{matches: matches}, nil
+}
+
+// Observe учитывает срабатывание правила rule; blocked == false в режиме только обнаружения
+func (m *WAFMetrics) Observe(rule string, blocked bool) {
+	if m == nil {
+		return
+	}
+	action := "detected"
+	if blocked {
+		action = "blocked"
+	}
+	m.matches.WithLabelValues(rule, action).Inc()
+}
@@ -0,0 +1,249 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"pkg/rbac"
+)
+
+// Расширения спецификации, ограничивающие видимость операции в документации
+const (
+	// extensionPermissions права, с любым из которых видна операция
+	// (x-permissions: [orders.manage]); те же права проверяет сервис
+	extensionPermissions = "x-permissions"
+	// extensionInternal служебная операция для других систем (x-internal: true)
+	extensionInternal = "x-internal"
+)
+
+// fullSpecPermission право, с которым видна вся спецификация, в том числе служебные
+// операции: оно есть у администраторов шлюза
+const fullSpecPermission = rbac.GatewayManage
+
+// adminPathPrefix префикс административных маршрутов; операции под ним без
+// x-permissions видны только со всей спецификацией
+const adminPathPrefix = "/v1/admin/"
+
+// componentSections разделы components, из которых удаляются компоненты без ссылок.
+// securitySchemes не удаляются: на них ссылаются по имени, а не через $ref
+var componentSections = []string{"schemas", "parameters", "headers", "requestBodies", "responses", "examples"}
+
+// httpMethods ключи Path Item, являющиеся операциями
+var httpMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// ScopedSpec возвращает спецификацию только с операциями, видимыми пользователю с
+// правами permissions. Анонимному пользователю (authenticated = false) видны только
+// операции без аутентификации (security: []). Аутентифицированному — еще и защищенные
+// операции, кроме операций с x-permissions без его прав, неразмеченных маршрутов
+// /v1/admin/ и операций x-internal. С правом gateway.manage видна вся спецификация.
+// Теги и компоненты, на которые не ссылаются оставшиеся операции, удаляются.
+// Результат кэшируется по набору прав
+func (v *Validator) ScopedSpec(permissions []string, authenticated bool) ([]byte, error) {
+	if authenticated && contains(permissions, fullSpecPermission) {
+		return v.spec, nil
+	}
+
+	key := scopeKey(permissions, authenticated)
+	if cached, ok := v.scoped.Load(key); ok {
+		return cached.([]byte), nil
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(v.spec, &doc); err != nil {
+		return nil, fmt.Errorf("ошибка разбора спецификации: %v", err)
+	}
+	filterOperations(doc, permissions, authenticated)
+	pruneComponents(doc)
+
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации спецификации: %v", err)
+	}
+	v.scoped.Store(key, spec)
+	return spec, nil
+}
+
+// scopeKey ключ кэша отфильтрованной спецификации
+func scopeKey(permissions []string, authenticated bool) string {
+	if !authenticated {
+		return ""
+	}
+	sorted := append([]string(nil), permissions...)
+	sort.Strings(sorted)
+	return "user:" + strings.Join(sorted, ",")
+}
+
+// filterOperations удаляет невидимые операции, пути без операций и неиспользуемые теги
+func filterOperations(doc map[string]interface{}, permissions []string, authenticated bool) {
+	usedTags := make(map[string]bool)
+	paths, _ := doc["paths"].(map[string]interface{})
+	for path, rawItem := range paths {
+		item, ok := rawItem.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		operations := 0
+		for method, rawOperation := range item {
+			if !httpMethods[method] {
+				continue
+			}
+			operation, _ := rawOperation.(map[string]interface{})
+			if !operationVisible(doc, path, operation, permissions, authenticated) {
+				delete(item, method)
+				continue
+			}
+			operations++
+			tags, _ := operation["tags"].([]interface{})
+			for _, tag := range tags {
+				if name, ok := tag.(string); ok {
+					usedTags[name] = true
+				}
+			}
+		}
+		if operations == 0 {
+			delete(paths, path)
+		}
+	}
+
+	tags, _ := doc["tags"].([]interface{})
+	visibleTags := make([]interface{}, 0, len(tags))
+	for _, rawTag := range tags {
+		tag, _ := rawTag.(map[string]interface{})
+		if name, _ := tag["name"].(string); usedTags[name] {
+			visibleTags = append(visibleTags, rawTag)
+		}
+	}
+	if tags != nil {
+		doc["tags"] = visibleTags
+	}
+}
+
+// operationVisible проверяет, видна ли операция пользователю без права на всю спецификацию
+func operationVisible(doc map[string]interface{}, path string, operation map[string]interface{}, permissions []string, authenticated bool) bool {
+	if operation == nil {
+		return false
+	}
+	if internal, _ := operation[extensionInternal].(bool); internal {
+		return false
+	}
+	if required, ok := operation[extensionPermissions].([]interface{}); ok {
+		if !authenticated {
+			return false
+		}
+		for _, permission := range required {
+			if name, ok := permission.(string); ok && contains(permissions, name) {
+				return true
+			}
+		}
+		return false
+	}
+	if strings.HasPrefix(path, adminPathPrefix) {
+		return false
+	}
+
+	security, ok := operation["security"]
+	if !ok {
+		security = doc["security"]
+	}
+	return authenticated || isPublic(security)
+}
+
+// isPublic проверяет, что требования безопасности допускают запрос без аутентификации:
+// список пуст или содержит пустое требование ({})
+func isPublic(security interface{}) bool {
+	requirements, ok := security.([]interface{})
+	if !ok {
+		return security == nil
+	}
+	if len(requirements) == 0 {
+		return true
+	}
+	for _, rawRequirement := range requirements {
+		if requirement, ok := rawRequirement.(map[string]interface{}); ok && len(requirement) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneComponents удаляет компоненты, на которые не ведут ссылки из оставшейся
+// части спецификации, в том числе через другие компоненты
+func pruneComponents(doc map[string]interface{}) {
+	components, _ := doc["components"].(map[string]interface{})
+	if components == nil {
+		return
+	}
+
+	used := make(map[string]bool)
+	var pending []string
+	visit := func(ref string) {
+		if !used[ref] {
+			used[ref] = true
+			pending = append(pending, ref)
+		}
+	}
+	for key, value := range doc {
+		if key != "components" {
+			collectRefs(value, visit)
+		}
+	}
+	for len(pending) > 0 {
+		ref := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if target := resolveRef(components, ref); target != nil {
+			collectRefs(target, visit)
+		}
+	}
+
+	for _, section := range componentSections {
+		items, _ := components[section].(map[string]interface{})
+		for name := range items {
+			if !used["#/components/"+section+"/"+name] {
+				delete(items, name)
+			}
+		}
+	}
+}
+
+// collectRefs вызывает visit для каждой ссылки $ref внутри value
+func collectRefs(value interface{}, visit func(ref string)) {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		for key, child := range node {
+			if ref, ok := child.(string); ok && key == "$ref" {
+				visit(ref)
+				continue
+			}
+			collectRefs(child, visit)
+		}
+	case []interface{}:
+		for _, child := range node {
+			collectRefs(child, visit)
+		}
+	}
+}
+
+// resolveRef возвращает компонент по локальной ссылке #/components/<раздел>/<имя>
+func resolveRef(components map[string]interface{}, ref string) interface{} {
+	parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+	if len(parts) != 2 || !strings.HasPrefix(ref, "#/components/") {
+		return nil
+	}
+	items, _ := components[parts[0]].(map[string]interface{})
+	return items[parts[1]]
+}
+
+func contains(values []string, value string) bool {
+	for _, current := range values {
+		if current == value {
+			return true
+		}
+	}
+	return false
+}
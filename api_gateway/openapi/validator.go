@@ -0,0 +1,230 @@
+// Package openapi объединяет спецификации OpenAPI шлюза и сервисов в одну и
+// проверяет по ней входящие запросы до проксирования: параметры пути, запроса,
+// заголовков и тело. Запросы к маршрутам, которых нет в спецификации, не проверяются
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+)
+
+// uuidPattern UUID любой версии: шаблон kin-openapi допускает только версии 1-5
+const uuidPattern = `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
+
+func init() {
+	// Форматы uuid и email не проверяются kin-openapi по умолчанию
+	openapi3.DefineStringFormatValidator("uuid", openapi3.NewRegexpFormatValidator(uuidPattern))
+	openapi3.DefineStringFormatValidator("email", openapi3.NewRegexpFormatValidator(openapi3.FormatOfStringForEmail))
+}
+
+// FieldError ошибка проверки одного поля запроса
+type FieldError struct {
+	// In часть запроса: path, query, header, cookie или body
+	In string `json:"in"`
+	// Field имя параметра или путь к полю тела через точку (items.0.quantity)
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validator проверяет запросы по объединенной спецификации
+type Validator struct {
+	router routers.Router
+	spec   []byte
+	// scoped спецификации, отфильтрованные по правам (см. ScopedSpec)
+	scoped sync.Map
+}
+
+// Load загружает спецификации и объединяет их. Первый файл — основной: из остальных
+// добавляются пути и компоненты, которых в нем нет
+func Load(paths []string) (*Validator, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("не указаны файлы спецификации")
+	}
+
+	loader := openapi3.NewLoader()
+	var doc *openapi3.T
+	for _, path := range paths {
+		current, err := loader.LoadFromFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки спецификации %s: %v", path, err)
+		}
+		if err := current.Validate(context.Background(), openapi3.DisableExamplesValidation()); err != nil {
+			return nil, fmt.Errorf("некорректная спецификация %s: %v", path, err)
+		}
+		if doc == nil {
+			doc = current
+			continue
+		}
+		merge(doc, current)
+	}
+
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации спецификации: %v", err)
+	}
+
+	// Маршруты сопоставляются только по пути: адреса серверов из спецификации
+	// относятся к окружениям разработки и не совпадают с Host запросов к шлюзу
+	routed := *doc
+	routed.Servers = openapi3.Servers{{URL: "/"}}
+	router, err := gorillamux.NewRouter(&routed)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка построения маршрутов спецификации: %v", err)
+	}
+
+	return &Validator{router: router, spec: spec}, nil
+}
+
+// Spec возвращает объединенную спецификацию в формате JSON
+func (v *Validator) Spec() []byte {
+	return v.spec
+}
+
+// Validate проверяет запрос и возвращает найденные ошибки. Тело запроса после
+// проверки остается доступным для проксирования. Аутентификация не проверяется:
+// это делает jwtAuthMiddleware
+func (v *Validator) Validate(r *http.Request) []FieldError {
+	route, pathParams, err := v.router.FindRoute(r)
+	if err != nil {
+		// Маршрута или метода нет в спецификации: решение остается за сервисом
+		return nil
+	}
+
+	err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:         true,
+			AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+		},
+	})
+	if err == nil {
+		return nil
+	}
+
+	var fields []FieldError
+	collect(err, &fields)
+	return fields
+}
+
+// merge добавляет в doc пути и компоненты из other, которых в doc нет
+func merge(doc, other *openapi3.T) {
+	for path, item := range other.Paths.Map() {
+		if doc.Paths.Value(path) == nil {
+			doc.Paths.Set(path, item)
+		}
+	}
+
+	if doc.Components == nil {
+		doc.Components = &openapi3.Components{}
+	}
+	if other.Components == nil {
+		return
+	}
+	doc.Components.Schemas = mergeMap(doc.Components.Schemas, other.Components.Schemas)
+	doc.Components.Parameters = mergeMap(doc.Components.Parameters, other.Components.Parameters)
+	doc.Components.Headers = mergeMap(doc.Components.Headers, other.Components.Headers)
+	doc.Components.RequestBodies = mergeMap(doc.Components.RequestBodies, other.Components.RequestBodies)
+	doc.Components.Responses = mergeMap(doc.Components.Responses, other.Components.Responses)
+	doc.Components.SecuritySchemes = mergeMap(doc.Components.SecuritySchemes, other.Components.SecuritySchemes)
+	doc.Components.Examples = mergeMap(doc.Components.Examples, other.Components.Examples)
+}
+
+func mergeMap[M ~map[string]V, V any](target, source M) M {
+	if target == nil && len(source) > 0 {
+		target = make(M, len(source))
+	}
+	for name, value := range source {
+		if _, ok := target[name]; !ok {
+			target[name] = value
+		}
+	}
+	return target
+}
+
+// collect разворачивает ошибки openapi3filter в список ошибок полей
+func collect(err error, fields *[]FieldError) {
+	// Проверяются конкретные типы, а не errors.As: RequestError оборачивает
+	// MultiError с ошибками схемы, и errors.As нашел бы вложенную ошибку
+	if multi, ok := err.(openapi3.MultiError); ok {
+		for _, item := range multi {
+			collect(item, fields)
+		}
+		return
+	}
+
+	requestErr, ok := err.(*openapi3filter.RequestError)
+	if !ok {
+		*fields = append(*fields, FieldError{In: "request", Message: err.Error()})
+		return
+	}
+
+	in, field := "body", ""
+	if requestErr.Parameter != nil {
+		in, field = requestErr.Parameter.In, requestErr.Parameter.Name
+	}
+
+	var schemaErrors []*openapi3.SchemaError
+	collectSchemaErrors(requestErr.Err, &schemaErrors)
+	if len(schemaErrors) == 0 {
+		message := requestErr.Reason
+		if requestErr.Err != nil {
+			message = errorMessage(requestErr.Err)
+		}
+		*fields = append(*fields, FieldError{In: in, Field: field, Message: message})
+		return
+	}
+
+	for _, schemaErr := range schemaErrors {
+		name := field
+		if pointer := schemaErr.JSONPointer(); len(pointer) > 0 {
+			if name != "" {
+				name += "."
+			}
+			name += strings.Join(pointer, ".")
+		}
+		*fields = append(*fields, FieldError{In: in, Field: name, Message: schemaErr.Reason})
+	}
+}
+
+// collectSchemaErrors собирает ошибки схемы, в том числе вложенные в MultiError
+func collectSchemaErrors(err error, result *[]*openapi3.SchemaError) {
+	if err == nil {
+		return
+	}
+	if multi, ok := err.(openapi3.MultiError); ok {
+		for _, item := range multi {
+			collectSchemaErrors(item, result)
+		}
+		return
+	}
+	var schemaErr *openapi3.SchemaError
+	if errors.As(err, &schemaErr) {
+		*result = append(*result, schemaErr)
+	}
+}
+
+// errorMessage возвращает описание ошибки разбора без повторения значения параметра
+func errorMessage(err error) string {
+	var parseErr *openapi3filter.ParseError
+	if errors.As(err, &parseErr) {
+		if parseErr.Reason != "" {
+			return parseErr.Reason
+		}
+		if parseErr.Cause != nil {
+			return parseErr.Cause.Error()
+		}
+	}
+	return err.Error()
+}
@@ -0,0 +1,127 @@
+// Package quota ведет суточные квоты запросов пользователей в Redis. В отличие от
+// ограничителя частоты (ratelimit), который сглаживает всплески в памяти экземпляра,
+// квота считает все запросы пользователя за сутки (UTC) на всех экземплярах Gateway
+// и сохраняется при их перезапуске
+package quota
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix префикс ключей счетчиков в Redis; ключ — префикс, пользователь и сутки
+const keyPrefix = "gateway:quota:"
+
+// keyRetention время хранения счетчика после окончания суток, чтобы администратор
+// мог посмотреть потребление за завершившиеся сутки
+const keyRetention = 24 * time.Hour
+
+// consume увеличивает счетчик, только если он меньше лимита: отклоненные запросы
+// не расходуют квоту. Возвращает {1, used} для разрешенного запроса и {0, used} для отклоненного
+var consume = redis.NewScript(`
+local used = tonumber(redis.call('GET', KEYS[1]) or '0')
+if used >= tonumber(ARGV[1]) then
+	return {0, used}
+end
+used = redis.call('INCR', KEYS[1])
+if used == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return {1, used}
+`)
+
+// Usage потребление квоты пользователем за сутки
+type Usage struct {
+	UserID string `json:"user_id"`
+	// Day сутки UTC в формате YYYY-MM-DD
+	Day       string `json:"day"`
+	Used      int64  `json:"used"`
+	Limit     int64  `json:"limit"`
+	Remaining int64  `json:"remaining"`
+	// ResetAt начало следующих суток, когда квота восстанавливается
+	ResetAt time.Time `json:"reset_at"`
+}
+
+// Store хранилище суточных квот
+type Store struct {
+	client redis.UniversalClient
+	limit  int64
+	now    func() time.Time
+}
+
+// NewStore создает хранилище квот с лимитом limit запросов в сутки на пользователя
+func NewStore(client redis.UniversalClient, limit int64) *Store {
+	return &Store{client: client, limit: limit, now: time.Now}
+}
+
+// Consume учитывает запрос пользователя. allowed == false, если квота на сутки исчерпана
+func (s *Store) Consume(ctx context.Context, userID string) (Usage, bool, error) {
+	day, resetAt := s.day()
+	ttl := resetAt.Add(keyRetention).Sub(s.now())
+
+	result, err := consume.Run(ctx, s.client, []string{key(userID, day)}, s.limit, ttl.Milliseconds()).Int64Slice()
+	if err != nil {
+		return Usage{}, false, fmt.Errorf("ошибка учета квоты: %v", err)
+	}
+	if len(result) != 2 {
+		return Usage{}, false, fmt.Errorf("некорректный ответ учета квоты: %v", result)
+	}
+	return s.usage(userID, day, resetAt, result[1]), result[0] == 1, nil
+}
+
+// Get возвращает потребление квоты пользователем за текущие сутки
+func (s *Store) Get(ctx context.Context, userID string) (Usage, error) {
+	day, resetAt := s.day()
+	used, err := s.client.Get(ctx, key(userID, day)).Int64()
+	if err != nil && err != redis.Nil {
+		return Usage{}, fmt.Errorf("ошибка получения квоты: %v", err)
+	}
+	return s.usage(userID, day, resetAt, used), nil
+}
+
+// Reset обнуляет потребление пользователя за текущие сутки и возвращает потребление до сброса
+func (s *Store) Reset(ctx context.Context, userID string) (Usage, error) {
+	day, resetAt := s.day()
+	used, err := s.client.GetDel(ctx, key(userID, day)).Int64()
+	if err != nil && err != redis.Nil {
+		return Usage{}, fmt.Errorf("ошибка сброса квоты: %v", err)
+	}
+	return s.usage(userID, day, resetAt, used), nil
+}
+
+// Limit возвращает лимит запросов в сутки
+func (s *Store) Limit() int64 {
+	return s.limit
+}
+
+// Close закрывает соединения с Redis
+func (s *Store) Close() error {
+	return s.client.Close()
+}
+
+// day возвращает текущие сутки UTC и начало следующих
+func (s *Store) day() (string, time.Time) {
+	now := s.now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start.Format(time.DateOnly), start.AddDate(0, 0, 1)
+}
+
+// usage собирает потребление из значения счетчика
+func (s *Store) usage(userID, day string, resetAt time.Time, used int64) Usage {
+	return Usage{
+		UserID:    userID,
+		Day:       day,
+		Used:      used,
+		Limit:     s.limit,
+		Remaining: max(s.limit-used, 0),
+		ResetAt:   resetAt,
+	}
+}
+
+// key возвращает ключ счетчика пользователя за сутки
+func key(userID, day string) string {
+	return keyPrefix + userID + ":" + day
+}
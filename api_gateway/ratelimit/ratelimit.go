@@ -0,0 +1,287 @@
+// Package ratelimit ограничивает частоту запросов по правилам для отдельных маршрутов.
+// Каждое правило задает частоту, burst и стратегию ключа: общий лимит на все запросы
+// маршрута, отдельный лимит на IP или на пользователя
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Стратегии ключа ограничения
+const (
+	// KeyGlobal один лимит на все запросы, попадающие под правило
+	KeyGlobal = "global"
+	// KeyIP отдельный лимит для каждого IP клиента
+	KeyIP = "ip"
+	// KeyUser отдельный лимит для каждого аутентифицированного пользователя;
+	// для анонимных запросов используется IP
+	KeyUser = "user"
+)
+
+// sweepInterval период удаления лимитеров неактивных ключей
+const sweepInterval = time.Minute
+
+// Rule правило ограничения для запросов с методом Method и путем, начинающимся с Prefix
+type Rule struct {
+	// Method HTTP метод; "*" — любой
+	Method string  `json:"method"`
+	Prefix string  `json:"prefix"`
+	RPS    float64 `json:"rps"`
+	Burst  int     `json:"burst"`
+	Key    string  `json:"key"`
+}
+
+// Matches проверяет, подходит ли правило для запроса
+func (rule Rule) Matches(r *http.Request) bool {
+	return (rule.Method == "*" || rule.Method == r.Method) && strings.HasPrefix(r.URL.Path, rule.Prefix)
+}
+
+// String возвращает правило в формате конфигурации
+func (rule Rule) String() string {
+	return fmt.Sprintf("%s %s=%s,%d,%s", rule.Method, rule.Prefix, strconv.FormatFloat(rule.RPS, 'f', -1, 64), rule.Burst, rule.Key)
+}
+
+// Validate проверяет параметры правила
+func (rule Rule) Validate() error {
+	if rule.RPS <= 0 {
+		return fmt.Errorf("правило %s: частота должна быть больше 0", rule.Prefix)
+	}
+	if rule.Burst < 1 {
+		return fmt.Errorf("правило %s: burst должен быть не меньше 1", rule.Prefix)
+	}
+	switch rule.Key {
+	case KeyGlobal, KeyIP, KeyUser:
+	default:
+		return fmt.Errorf("правило %s: неизвестная стратегия ключа %q (global, ip, user)", rule.Prefix, rule.Key)
+	}
+	return nil
+}
+
+// ParseRules разбирает правила в формате
+// "POST /v1/users/login=0.2,5,ip;GET /v1/orders=20,40,user". Метод "*" подходит для любого запроса
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		left, right, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("некорректное правило %q: ожидается 'METHOD /path=rps,burst,key'", item)
+		}
+
+		fields := strings.Fields(left)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], "/") {
+			return nil, fmt.Errorf("некорректное правило %q: ожидается 'METHOD /path'", item)
+		}
+
+		values := strings.Split(right, ",")
+		if len(values) != 3 {
+			return nil, fmt.Errorf("правило %q должно содержать rps, burst и стратегию ключа", item)
+		}
+
+		rps, err := strconv.ParseFloat(strings.TrimSpace(values[0]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная частота в правиле %q: %v", item, err)
+		}
+		burst, err := strconv.Atoi(strings.TrimSpace(values[1]))
+		if err != nil {
+			return nil, fmt.Errorf("некорректный burst в правиле %q: %v", item, err)
+		}
+
+		rule := Rule{
+			Method: strings.ToUpper(fields[0]),
+			Prefix: fields[1],
+			RPS:    rps,
+			Burst:  burst,
+			Key:    strings.ToLower(strings.TrimSpace(values[2])),
+		}
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// KeyFunc возвращает ключ запроса для стратегии (IP клиента или ID пользователя)
+type KeyFunc func(r *http.Request, strategy string) string
+
+// Limiter применяет к запросу первое подходящее правило; запросы, не подходящие
+// ни под одно правило, ограничиваются правилом по умолчанию
+type Limiter struct {
+	keyFunc KeyFunc
+
+	mutex    sync.RWMutex
+	rules    []*ruleLimiter
+	fallback *ruleLimiter
+}
+
+// New создает ограничитель. Правила проверяются по порядку, поэтому более точные
+// префиксы должны идти раньше общих
+func New(rules []Rule, fallback Rule, keyFunc KeyFunc) *Limiter {
+	limiter := &Limiter{
+		fallback: newRuleLimiter(fallback),
+		keyFunc:  keyFunc,
+	}
+	for _, rule := range rules {
+		limiter.rules = append(limiter.rules, newRuleLimiter(rule))
+	}
+	return limiter
+}
+
+// Decision результат проверки лимита для заголовков X-RateLimit-*
+type Decision struct {
+	Allowed bool
+	// Limit burst правила — число запросов, которые можно выполнить подряд
+	Limit int
+	// Remaining число запросов, которые можно выполнить сразу
+	Remaining int
+	// Reset время до полного восстановления лимита
+	Reset time.Duration
+	// RetryAfter время до восстановления одного запроса; 0, если запрос разрешен
+	RetryAfter time.Duration
+}
+
+// Allow проверяет лимит для запроса и возвращает примененное правило
+func (l *Limiter) Allow(r *http.Request) (Rule, Decision) {
+	l.mutex.RLock()
+	rl := l.fallback
+	for _, candidate := range l.rules {
+		if candidate.rule.Matches(r) {
+			rl = candidate
+			break
+		}
+	}
+	l.mutex.RUnlock()
+
+	key := ""
+	if rl.rule.Key != KeyGlobal {
+		key = l.keyFunc(r, rl.rule.Key)
+	}
+	return rl.rule, rl.allow(key, time.Now())
+}
+
+// Rules возвращает действующие правила и правило по умолчанию
+func (l *Limiter) Rules() ([]Rule, Rule) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	rules := make([]Rule, 0, len(l.rules))
+	for _, rl := range l.rules {
+		rules = append(rules, rl.rule)
+	}
+	return rules, l.fallback.rule
+}
+
+// SetRules заменяет правила без перезапуска. Счетчики сохраняются только
+// для правил, которые не изменились
+func (l *Limiter) SetRules(rules []Rule, fallback Rule) error {
+	for _, rule := range append([]Rule{fallback}, rules...) {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	previous := make(map[Rule]*ruleLimiter, len(l.rules)+1)
+	for _, rl := range append([]*ruleLimiter{l.fallback}, l.rules...) {
+		previous[rl.rule] = rl
+	}
+	reuse := func(rule Rule) *ruleLimiter {
+		if rl, ok := previous[rule]; ok {
+			delete(previous, rule)
+			return rl
+		}
+		return newRuleLimiter(rule)
+	}
+
+	l.fallback = reuse(fallback)
+	l.rules = l.rules[:0:0]
+	for _, rule := range rules {
+		l.rules = append(l.rules, reuse(rule))
+	}
+	return nil
+}
+
+// ruleLimiter лимитеры ключей одного правила
+type ruleLimiter struct {
+	rule Rule
+
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// bucket лимитер ключа и время последнего запроса
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newRuleLimiter(rule Rule) *ruleLimiter {
+	return &ruleLimiter{
+		rule:      rule,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow расходует токен ключа key и возвращает оставшийся запас
+func (rl *ruleLimiter) allow(key string, now time.Time) Decision {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if now.Sub(rl.lastSweep) >= sweepInterval {
+		rl.sweep(now)
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(rl.rule.RPS), rl.rule.Burst)}
+		rl.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	decision := Decision{Allowed: b.limiter.AllowN(now, 1), Limit: rl.rule.Burst}
+	tokens := b.limiter.TokensAt(now)
+	if tokens > 0 {
+		decision.Remaining = int(tokens)
+	}
+	decision.Reset = rl.refill(float64(rl.rule.Burst) - tokens)
+	if !decision.Allowed {
+		decision.RetryAfter = rl.refill(1 - tokens)
+	}
+	return decision
+}
+
+// refill возвращает время восстановления tokens токенов
+func (rl *ruleLimiter) refill(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / rl.rule.RPS * float64(time.Second))
+}
+
+// sweep удаляет лимитеры ключей, которые успели полностью восстановиться:
+// новый лимитер для такого ключа ведет себя так же
+func (rl *ruleLimiter) sweep(now time.Time) {
+	refill := time.Duration(float64(rl.rule.Burst) / rl.rule.RPS * float64(time.Second))
+	for key, b := range rl.buckets {
+		if now.Sub(b.lastSeen) > refill {
+			delete(rl.buckets, key)
+		}
+	}
+	rl.lastSweep = now
+}
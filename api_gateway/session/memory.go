@@ -0,0 +1,111 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval период удаления истекших сессий из памяти
+const sweepInterval = time.Minute
+
+// memoryEntry сессия в памяти и время ее истечения
+type memoryEntry struct {
+	session   Session
+	expiresAt time.Time
+}
+
+// memoryStore хранилище сессий в памяти экземпляра Gateway. Сессии теряются при
+// перезапуске и не видны другим экземплярам, поэтому подходит для одного экземпляра
+// или привязки клиента к экземпляру
+type memoryStore struct {
+	mutex     sync.Mutex
+	entries   map[string]memoryEntry
+	locks     map[string]*keyLock
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// keyLock блокировка продления одной сессии и число ее ожидающих
+type keyLock struct {
+	mutex   sync.Mutex
+	waiters int
+}
+
+// NewMemoryStore создает хранилище сессий в памяти
+func NewMemoryStore() Store {
+	return &memoryStore{
+		entries: make(map[string]memoryEntry),
+		locks:   make(map[string]*keyLock),
+		now:     time.Now,
+	}
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) (Session, bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok || !m.now().Before(entry.expiresAt) {
+		return Session{}, false, nil
+	}
+	return entry.session, true, nil
+}
+
+func (m *memoryStore) Save(ctx context.Context, key string, s Session, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.now()
+	m.entries[key] = memoryEntry{session: s, expiresAt: now.Add(ttl)}
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+	return nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
+
+// Lock в памяти экземпляра ttl не нужен: блокировку освобождает вызов возвращенной функции
+func (m *memoryStore) Lock(ctx context.Context, key string, ttl time.Duration) (func(), error) {
+	m.mutex.Lock()
+	lock, ok := m.locks[key]
+	if !ok {
+		lock = &keyLock{}
+		m.locks[key] = lock
+	}
+	lock.waiters++
+	m.mutex.Unlock()
+
+	lock.mutex.Lock()
+	return func() {
+		lock.mutex.Unlock()
+
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		lock.waiters--
+		if lock.waiters == 0 {
+			delete(m.locks, key)
+		}
+	}, nil
+}
+
+func (m *memoryStore) Close() error {
+	return nil
+}
+
+// sweep удаляет истекшие сессии; вызывается под m.mutex
+func (m *memoryStore) sweep(now time.Time) {
+	for key, entry := range m.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+	m.lastSweep = now
+}
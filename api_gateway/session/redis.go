@@ -0,0 +1,82 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pkg/lock"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Префиксы ключей Redis: сессии и блокировки их продления
+const (
+	keyPrefix  = "gateway:session:"
+	lockPrefix = "gateway:session-lock:"
+)
+
+// lockRetryInterval период повторной попытки занять блокировку продления
+const lockRetryInterval = 25 * time.Millisecond
+
+// redisStore хранилище сессий в Redis, общее для всех экземпляров Gateway
+type redisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore создает хранилище сессий в Redis
+func NewRedisStore(client redis.UniversalClient) Store {
+	return &redisStore{client: client}
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) (Session, bool, error) {
+	payload, err := r.client.Get(ctx, keyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return Session{}, false, nil
+	}
+	if err != nil {
+		return Session{}, false, fmt.Errorf("ошибка получения сессии: %v", err)
+	}
+
+	var s Session
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return Session{}, false, fmt.Errorf("ошибка разбора сессии: %v", err)
+	}
+	return s, true, nil
+}
+
+func (r *redisStore) Save(ctx context.Context, key string, s Session, ttl time.Duration) error {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации сессии: %v", err)
+	}
+	if err := r.client.Set(ctx, keyPrefix+key, payload, ttl).Err(); err != nil {
+		return fmt.Errorf("ошибка сохранения сессии: %v", err)
+	}
+	return nil
+}
+
+func (r *redisStore) Delete(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, keyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("ошибка удаления сессии: %v", err)
+	}
+	return nil
+}
+
+// Lock ожидает блокировку, пока она занята другим запросом или экземпляром, либо до отмены ctx.
+// Блокировка продлевается, пока запрос ее держит, а после остановки экземпляра истекает через ttl
+func (r *redisStore) Lock(ctx context.Context, key string, ttl time.Duration) (func(), error) {
+	lease, err := lock.Acquire(ctx, lock.NewRedis(r.client, lockPrefix, ttl), key, lockRetryInterval)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("ошибка блокировки сессии: %v", err)
+	}
+	return func() { lease.Release() }, nil
+}
+
+func (r *redisStore) Close() error {
+	return r.client.Close()
+}
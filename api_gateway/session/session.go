@@ -0,0 +1,211 @@
+// Package session хранит сессии браузерных клиентов шлюза. В режиме сессий клиент
+// получает при входе только непрозрачный идентификатор в HTTP-only cookie, а пара
+// токенов сервиса пользователей хранится шлюзом: access токен подставляется в запросы,
+// а по мере истечения обновляется refresh токеном сессии
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrRenewalRejected сервис пользователей отклонил refresh токен сессии
+// (отозван, истек или пользователь удален); сессия больше не может быть продлена
+var ErrRenewalRejected = errors.New("refresh токен сессии отклонен")
+
+// lockTTL предельное время удержания блокировки продления сессии
+const lockTTL = 10 * time.Second
+
+// Session сессия клиента
+type Session struct {
+	UserID       string `json:"user_id"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	// AccessExpiresAt время истечения access токена (exp); нулевое, если exp не задан
+	AccessExpiresAt time.Time `json:"access_expires_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	RenewedAt       time.Time `json:"renewed_at"`
+}
+
+// Store хранилище сессий. Идентификатор сессии передается хранилищу только в виде хеша
+type Store interface {
+	// Get возвращает сессию; ok == false, если сессии нет или она истекла
+	Get(ctx context.Context, key string) (Session, bool, error)
+	// Save сохраняет сессию на время ttl
+	Save(ctx context.Context, key string, s Session, ttl time.Duration) error
+	// Delete удаляет сессию
+	Delete(ctx context.Context, key string) error
+	// Lock блокирует продление сессии до вызова возвращенной функции или истечения ttl
+	Lock(ctx context.Context, key string, ttl time.Duration) (func(), error)
+	Close() error
+}
+
+// Renewer обменивает refresh токен на новую пару токенов. Возвращает ErrRenewalRejected,
+// если токен отклонен сервисом пользователей
+type Renewer func(ctx context.Context, refreshToken string) (accessToken, newRefreshToken string, err error)
+
+// Manager создает, разрешает и продлевает сессии
+type Manager struct {
+	store Store
+	// ttl время жизни сессии от входа; продление access токена его не увеличивает,
+	// как и срок жизни cookie сессии
+	ttl time.Duration
+	// renewBefore за сколько до истечения access токена сессия продлевается
+	renewBefore time.Duration
+	renew       Renewer
+	now         func() time.Time
+}
+
+// NewManager создает менеджер сессий
+func NewManager(store Store, ttl, renewBefore time.Duration, renew Renewer) *Manager {
+	return &Manager{store: store, ttl: ttl, renewBefore: renewBefore, renew: renew, now: time.Now}
+}
+
+// TTL возвращает время жизни сессии
+func (m *Manager) TTL() time.Duration {
+	return m.ttl
+}
+
+// Create создает сессию для пары токенов, выданной при входе, и возвращает ее идентификатор
+func (m *Manager) Create(ctx context.Context, accessToken, refreshToken string) (string, error) {
+	if accessToken == "" || refreshToken == "" {
+		return "", fmt.Errorf("ответ входа не содержит пару токенов")
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("ошибка генерации идентификатора сессии: %v", err)
+	}
+	id := base64.RawURLEncoding.EncodeToString(buf)
+
+	now := m.now()
+	s := Session{RefreshToken: refreshToken, CreatedAt: now}
+	if err := setAccessToken(&s, accessToken); err != nil {
+		return "", err
+	}
+	if err := m.store.Save(ctx, storeKey(id), s, m.ttl); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Resolve возвращает сессию по идентификатору, при необходимости продлевая ее access токен.
+// ok == false, если сессии нет или ее refresh токен отклонен (сессия при этом удаляется)
+func (m *Manager) Resolve(ctx context.Context, id string) (Session, bool, error) {
+	key := storeKey(id)
+	s, ok, err := m.store.Get(ctx, key)
+	if err != nil || !ok || !m.needsRenewal(s) {
+		return s, ok, err
+	}
+
+	// Refresh токен одноразовый: его повторное использование сервис пользователей считает
+	// кражей и отзывает все токены пользователя, поэтому продлевает один запрос,
+	// а остальные ждут и перечитывают уже продленную сессию
+	unlock, err := m.store.Lock(ctx, key, lockTTL)
+	if err != nil {
+		return m.fallback(s, err)
+	}
+	defer unlock()
+
+	s, ok, err = m.store.Get(ctx, key)
+	if err != nil || !ok || !m.needsRenewal(s) {
+		return s, ok, err
+	}
+
+	accessToken, refreshToken, err := m.renew(ctx, s.RefreshToken)
+	if errors.Is(err, ErrRenewalRejected) {
+		if err := m.store.Delete(ctx, key); err != nil {
+			return Session{}, false, err
+		}
+		return Session{}, false, nil
+	}
+	if err != nil {
+		return m.fallback(s, err)
+	}
+
+	s.RefreshToken = refreshToken
+	s.RenewedAt = m.now()
+	if err := setAccessToken(&s, accessToken); err != nil {
+		return m.fallback(s, err)
+	}
+	remaining := s.CreatedAt.Add(m.ttl).Sub(m.now())
+	if remaining <= 0 {
+		return Session{}, false, m.store.Delete(ctx, key)
+	}
+	if err := m.store.Save(ctx, key, s, remaining); err != nil {
+		return m.fallback(s, err)
+	}
+	return s, true, nil
+}
+
+// Delete удаляет сессию и возвращает ее, чтобы refresh токен сессии можно было отозвать;
+// ok == false, если сессии нет. Блокировка продления не дает параллельному запросу
+// заменить refresh токен после чтения сессии
+func (m *Manager) Delete(ctx context.Context, id string) (Session, bool, error) {
+	key := storeKey(id)
+	unlock, err := m.store.Lock(ctx, key, lockTTL)
+	if err != nil {
+		return Session{}, false, err
+	}
+	defer unlock()
+
+	s, ok, err := m.store.Get(ctx, key)
+	if err != nil {
+		return Session{}, false, err
+	}
+	if err := m.store.Delete(ctx, key); err != nil {
+		return Session{}, false, err
+	}
+	return s, ok, nil
+}
+
+// Close освобождает хранилище сессий
+func (m *Manager) Close() error {
+	return m.store.Close()
+}
+
+// needsRenewal проверяет, истекает ли access токен сессии в течение renewBefore
+func (m *Manager) needsRenewal(s Session) bool {
+	return !s.AccessExpiresAt.IsZero() && !m.now().Add(m.renewBefore).Before(s.AccessExpiresAt)
+}
+
+// fallback при ошибке продления возвращает текущую сессию, пока ее access токен действителен
+func (m *Manager) fallback(s Session, err error) (Session, bool, error) {
+	if m.now().Before(s.AccessExpiresAt) {
+		return s, true, nil
+	}
+	return Session{}, false, fmt.Errorf("ошибка продления сессии: %v", err)
+}
+
+// setAccessToken сохраняет access токен в сессии вместе с пользователем и временем истечения.
+// Подпись не проверяется: токен получен от сервиса пользователей, а не от клиента
+func setAccessToken(s *Session, accessToken string) error {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, claims); err != nil {
+		return fmt.Errorf("некорректный access токен: %v", err)
+	}
+
+	s.AccessToken = accessToken
+	s.AccessExpiresAt = time.Time{}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		s.AccessExpiresAt = exp.Time
+	}
+	if userID, ok := claims["user_id"].(string); ok {
+		s.UserID = userID
+	}
+	return nil
+}
+
+// storeKey хеш идентификатора сессии: содержимое хранилища не позволяет восстановить cookie
+func storeKey(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,332 @@
+package sla
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MonthLayout формат месяца отчета
+const MonthLayout = "2006-01"
+
+// Stats доступность и задержки запросов за период
+type Stats struct {
+	Requests int64 `json:"requests"`
+	// Errors ответы со статусом 5xx, в том числе ответы шлюза 502/503/504
+	Errors int64 `json:"errors"`
+	// AvailabilityPercent доля ответов без ошибки сервера; 100 без запросов
+	AvailabilityPercent float64 `json:"availability_percent"`
+	// P95MS и P99MS оценки перцентилей задержки по гистограмме с точностью до ее
+	// интервала; задержки больше 10 с учитываются как 10 с
+	P95MS float64 `json:"p95_ms"`
+	P99MS float64 `json:"p99_ms"`
+	// ErrorBudgetConsumedPercent израсходованная доля бюджета ошибок: ошибки относительно
+	// допустимого целью числа ошибок (1 − цель) × запросы. Больше 100 — цель не выполнена
+	ErrorBudgetConsumedPercent float64 `json:"error_budget_consumed_percent"`
+}
+
+// ServiceReport показатели сервиса за месяц
+type ServiceReport struct {
+	Service string `json:"service"`
+	// Checks и FailedChecks проверки доступности сервиса всеми экземплярами Gateway
+	Checks       int64 `json:"checks"`
+	FailedChecks int64 `json:"failed_checks"`
+	// UptimePercent доля успешных проверок доступности; null, если проверок не было
+	UptimePercent *float64 `json:"uptime_percent"`
+	Stats
+}
+
+// RouteGroupReport показатели группы маршрутов сервиса за месяц
+type RouteGroupReport struct {
+	Service string `json:"service"`
+	Group   string `json:"group"`
+	Stats
+}
+
+// Report отчет SLA за месяц
+type Report struct {
+	// Month месяц отчета в формате YYYY-MM
+	Month string `json:"month"`
+	// Since и Until период данных: начало месяца и конец месяца или текущий момент
+	// для текущего месяца
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	// TargetPercent цель доступности, от которой считается бюджет ошибок
+	TargetPercent float64            `json:"target_percent"`
+	Services      []ServiceReport    `json:"services"`
+	RouteGroups   []RouteGroupReport `json:"route_groups"`
+}
+
+// histogram число запросов в интервалах latencyBounds и в открытом интервале после них
+type histogram [len(latencyBounds) + 1]int64
+
+// aggregate счетчики запросов группы маршрутов или сервиса
+type aggregate struct {
+	requests int64
+	errors   int64
+	latency  histogram
+}
+
+// add прибавляет счетчики other
+func (a *aggregate) add(other *aggregate) {
+	a.requests += other.requests
+	a.errors += other.errors
+	for i, n := range other.latency {
+		a.latency[i] += n
+	}
+}
+
+// stats считает показатели по счетчикам для цели доступности targetPercent
+func (a *aggregate) stats(targetPercent float64) Stats {
+	stats := Stats{Requests: a.requests, Errors: a.errors, AvailabilityPercent: 100}
+	if a.requests == 0 {
+		return stats
+	}
+
+	stats.AvailabilityPercent = round(float64(a.requests-a.errors) / float64(a.requests) * 100)
+	stats.P95MS = round(a.latency.percentile(0.95))
+	stats.P99MS = round(a.latency.percentile(0.99))
+	if budget := (100 - targetPercent) / 100 * float64(a.requests); budget > 0 {
+		stats.ErrorBudgetConsumedPercent = round(float64(a.errors) / budget * 100)
+	}
+	return stats
+}
+
+// percentile оценивает перцентиль q (0..1) линейной интерполяцией внутри интервала гистограммы
+func (h histogram) percentile(q float64) float64 {
+	var total int64
+	for _, n := range h {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	var cumulative int64
+	for i, n := range h {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		if i == len(latencyBounds) {
+			return latencyBounds[len(latencyBounds)-1]
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = latencyBounds[i-1]
+		}
+		fraction := (rank - float64(cumulative)) / float64(n)
+		return lower + (latencyBounds[i]-lower)*fraction
+	}
+	return latencyBounds[len(latencyBounds)-1]
+}
+
+// bucketIndex возвращает индекс интервала гистограммы по полю le_<граница>
+func bucketIndex(field string) (int, bool) {
+	bound := strings.TrimPrefix(field, "le_")
+	if bound == "inf" {
+		return len(latencyBounds), true
+	}
+	value, err := strconv.ParseFloat(bound, 64)
+	if err != nil {
+		return 0, false
+	}
+	for i, b := range latencyBounds {
+		if b == value {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Report формирует отчет за месяц month (берутся год и месяц UTC) с целью доступности
+// targetPercent. Перед чтением записывает агрегаты этого экземпляра; агрегаты других
+// экземпляров могут отставать на период их записи
+func (r *Recorder) Report(ctx context.Context, month time.Time, targetPercent float64) (*Report, error) {
+	if err := r.Flush(ctx); err != nil {
+		return nil, err
+	}
+
+	month = month.UTC()
+	since := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	until := since.AddDate(0, 1, 0)
+	if now := r.now().UTC(); now.Before(until) {
+		until = now
+	}
+
+	var routes, checks []*redis.MapStringStringCmd
+	_, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for day := since; day.Before(until); day = day.AddDate(0, 0, 1) {
+			routes = append(routes, pipe.HGetAll(ctx, key(kindRoutes, day)))
+			checks = append(checks, pipe.HGetAll(ctx, key(kindChecks, day)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения агрегатов SLA: %v", err)
+	}
+
+	groups := make(map[[2]string]*aggregate)
+	for _, cmd := range routes {
+		for field, value := range cmd.Val() {
+			parts := strings.SplitN(field, "|", 3)
+			n, err := strconv.ParseInt(value, 10, 64)
+			if len(parts) != 3 || err != nil {
+				continue
+			}
+			id := [2]string{parts[0], parts[1]}
+			group, ok := groups[id]
+			if !ok {
+				group = &aggregate{}
+				groups[id] = group
+			}
+			switch parts[2] {
+			case "requests":
+				group.requests += n
+			case "errors":
+				group.errors += n
+			default:
+				if i, ok := bucketIndex(parts[2]); ok {
+					group.latency[i] += n
+				}
+			}
+		}
+	}
+
+	type serviceTotals struct {
+		aggregate
+		checks, failed int64
+	}
+	services := make(map[string]*serviceTotals)
+	service := func(name string) *serviceTotals {
+		totals, ok := services[name]
+		if !ok {
+			totals = &serviceTotals{}
+			services[name] = totals
+		}
+		return totals
+	}
+	for id, group := range groups {
+		service(id[0]).add(group)
+	}
+	for _, cmd := range checks {
+		for field, value := range cmd.Val() {
+			parts := strings.SplitN(field, "|", 2)
+			n, err := strconv.ParseInt(value, 10, 64)
+			if len(parts) != 2 || err != nil {
+				continue
+			}
+			switch parts[1] {
+			case "checks":
+				service(parts[0]).checks += n
+			case "failed":
+				service(parts[0]).failed += n
+			}
+		}
+	}
+
+	report := &Report{
+		Month:         since.Format(MonthLayout),
+		Since:         since,
+		Until:         until,
+		TargetPercent: targetPercent,
+		Services:      make([]ServiceReport, 0, len(services)),
+		RouteGroups:   make([]RouteGroupReport, 0, len(groups)),
+	}
+	for name, totals := range services {
+		service := ServiceReport{
+			Service:      name,
+			Checks:       totals.checks,
+			FailedChecks: totals.failed,
+			Stats:        totals.stats(targetPercent),
+		}
+		if totals.checks > 0 {
+			uptime := round(float64(totals.checks-totals.failed) / float64(totals.checks) * 100)
+			service.UptimePercent = &uptime
+		}
+		report.Services = append(report.Services, service)
+	}
+	for id, group := range groups {
+		report.RouteGroups = append(report.RouteGroups, RouteGroupReport{Service: id[0], Group: id[1], Stats: group.stats(targetPercent)})
+	}
+
+	sort.Slice(report.Services, func(i, j int) bool {
+		return report.Services[i].Service < report.Services[j].Service
+	})
+	sort.Slice(report.RouteGroups, func(i, j int) bool {
+		a, b := report.RouteGroups[i], report.RouteGroups[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Group < b.Group
+	})
+	return report, nil
+}
+
+// csvHeader колонки CSV отчета
+var csvHeader = []string{
+	"month", "scope", "service", "group", "requests", "errors", "availability_percent",
+	"p95_ms", "p99_ms", "error_budget_consumed_percent", "checks", "failed_checks", "uptime_percent",
+}
+
+// WriteCSV записывает отчет в CSV: строка на сервис (scope service) и на группу
+// маршрутов (scope route_group). Колонки проверок доступности заполнены только у сервисов
+func (rep *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, service := range rep.Services {
+		uptime := ""
+		if service.UptimePercent != nil {
+			uptime = formatFloat(*service.UptimePercent)
+		}
+		row := append(rep.statsRow("service", service.Service, "", service.Stats),
+			strconv.FormatInt(service.Checks, 10), strconv.FormatInt(service.FailedChecks, 10), uptime)
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	for _, group := range rep.RouteGroups {
+		row := append(rep.statsRow("route_group", group.Service, group.Group, group.Stats), "", "", "")
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// statsRow возвращает общие колонки строки CSV
+func (rep *Report) statsRow(scope, service, group string, stats Stats) []string {
+	return []string{
+		rep.Month, scope, service, group,
+		strconv.FormatInt(stats.Requests, 10),
+		strconv.FormatInt(stats.Errors, 10),
+		formatFloat(stats.AvailabilityPercent),
+		formatFloat(stats.P95MS),
+		formatFloat(stats.P99MS),
+		formatFloat(stats.ErrorBudgetConsumedPercent),
+	}
+}
+
+// round округляет до сотых
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// formatFloat записывает число без экспоненты и лишних нулей
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
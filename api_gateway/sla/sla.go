@@ -0,0 +1,288 @@
+// Package sla собирает в Redis данные для ежемесячных отчетов об уровне обслуживания:
+// агрегаты запросов по сервисам и группам маршрутов (число запросов, ошибки 5xx,
+// гистограмма задержек) и результаты периодических проверок доступности сервисов.
+// Агрегаты суточные, общие для всех экземпляров Gateway и сохраняются при их
+// перезапуске. Report считает по ним доступность, p95/p99 задержки и расход бюджета
+// ошибок за месяц
+package sla
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"api_gateway/accesslog"
+
+	"pkg/clients"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// keyPrefix префикс ключей в Redis; ключ — префикс, вид данных и сутки UTC
+const keyPrefix = "gateway:sla:"
+
+// Виды суточных агрегатов
+const (
+	kindRoutes = "routes"
+	kindChecks = "checks"
+)
+
+// GatewayService сервис запросов, которые шлюз обработал сам, без обращения к сервисам
+const GatewayService = "api_gateway"
+
+// closeTimeout время на запись накопленных агрегатов при остановке
+const closeTimeout = 5 * time.Second
+
+// latencyBounds верхние границы интервалов гистограммы задержек, мс. Последний
+// интервал гистограммы открытый: задержки больше 10 с
+var latencyBounds = [...]float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// Recorder накапливает агрегаты в памяти и периодически добавляет их к суточным
+// агрегатам в Redis, чтобы запросы клиентов не ждали Redis
+type Recorder struct {
+	client        redis.UniversalClient
+	flushInterval time.Duration
+	retention     time.Duration
+	logger        *zap.Logger
+	now           func() time.Time
+
+	mutex sync.Mutex
+	// pending приращения полей, еще не записанные в Redis: ключ → поле → приращение
+	pending map[string]map[string]int64
+}
+
+// NewRecorder создает Recorder, который записывает агрегаты в Redis каждые flushInterval
+// и хранит их retention
+func NewRecorder(client redis.UniversalClient, flushInterval, retention time.Duration, logger *zap.Logger) *Recorder {
+	return &Recorder{
+		client:        client,
+		flushInterval: flushInterval,
+		retention:     retention,
+		logger:        logger,
+		now:           time.Now,
+		pending:       make(map[string]map[string]int64),
+	}
+}
+
+// Record учитывает завершенный запрос по записи журнала доступа. Запросы, не подошедшие
+// ни под один маршрут, не учитываются: они не относятся ни к одной группе маршрутов
+func (r *Recorder) Record(entry *accesslog.Entry) {
+	if entry.Route == "" {
+		return
+	}
+	service := entry.Upstream
+	if service == "" {
+		service = GatewayService
+	}
+	prefix := service + "|" + Group(entry.Route) + "|"
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	fields := r.fieldsLocked(key(kindRoutes, entry.Timestamp))
+	fields[prefix+"requests"]++
+	if entry.Status >= 500 {
+		fields[prefix+"errors"]++
+	}
+	fields[prefix+bucketField(entry.LatencyMS)]++
+}
+
+// RecordCheck учитывает результат проверки доступности сервиса
+func (r *Recorder) RecordCheck(service string, at time.Time, up bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	fields := r.fieldsLocked(key(kindChecks, at))
+	fields[service+"|checks"]++
+	if !up {
+		fields[service+"|failed"]++
+	}
+}
+
+// fieldsLocked возвращает приращения ключа; вызывается под mutex
+func (r *Recorder) fieldsLocked(key string) map[string]int64 {
+	fields, ok := r.pending[key]
+	if !ok {
+		fields = make(map[string]int64)
+		r.pending[key] = fields
+	}
+	return fields
+}
+
+// Run записывает накопленные агрегаты в Redis каждые flushInterval, пока не будет отменен ctx
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				r.logger.Warn("Агрегаты SLA не записаны, повтор при следующей записи", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Flush добавляет накопленные приращения к агрегатам в Redis одной транзакцией.
+// При ошибке приращения возвращаются в накопитель и записываются следующим вызовом
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mutex.Lock()
+	pending := r.pending
+	r.pending = make(map[string]map[string]int64)
+	r.mutex.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, fields := range pending {
+			for field, increment := range fields {
+				pipe.HIncrBy(ctx, key, field, increment)
+			}
+			pipe.Expire(ctx, key, r.retention)
+		}
+		return nil
+	})
+	if err != nil {
+		r.restore(pending)
+		return fmt.Errorf("ошибка записи агрегатов SLA: %v", err)
+	}
+	return nil
+}
+
+// restore возвращает незаписанные приращения в накопитель
+func (r *Recorder) restore(pending map[string]map[string]int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for key, fields := range pending {
+		current := r.fieldsLocked(key)
+		for field, increment := range fields {
+			current[field] += increment
+		}
+	}
+}
+
+// Close записывает накопленные агрегаты и закрывает соединения с Redis
+func (r *Recorder) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+
+	if err := r.Flush(ctx); err != nil {
+		r.logger.Warn("Агрегаты SLA не записаны при остановке", zap.Error(err))
+	}
+	return r.client.Close()
+}
+
+// Group возвращает группу маршрута: первые два сегмента шаблона (/v1/orders/{id} → /v1/orders),
+// для административных маршрутов — три (/v1/admin/deliveries/{id} → /v1/admin/deliveries)
+func Group(route string) string {
+	segments := strings.Split(strings.TrimPrefix(route, "/"), "/")
+	n := 2
+	if len(segments) > 1 && segments[1] == "admin" {
+		n = 3
+	}
+	if len(segments) > n {
+		segments = segments[:n]
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// key возвращает ключ суточного агрегата вида kind за сутки UTC времени at
+func key(kind string, at time.Time) string {
+	return keyPrefix + kind + ":" + at.UTC().Format(time.DateOnly)
+}
+
+// bucketField возвращает поле интервала гистограммы для задержки latencyMS
+func bucketField(latencyMS float64) string {
+	for _, bound := range latencyBounds {
+		if latencyMS <= bound {
+			return "le_" + strconv.FormatFloat(bound, 'f', -1, 64)
+		}
+	}
+	return "le_inf"
+}
+
+// Prober периодически проверяет доступность сервисов запросом GET через прокси шлюза
+// и записывает результаты в Recorder. Проверка проходит через те же балансировку,
+// breaker и резервирование, что и запросы клиентов: сервис недоступен для проверки,
+// если он недоступен клиентам. Ответ ниже 500 — сервис доступен
+type Prober struct {
+	recorder *Recorder
+	services map[string]clients.Doer
+	path     string
+	interval time.Duration
+	timeout  time.Duration
+	logger   *zap.Logger
+}
+
+// NewProber создает Prober для сервисов services (имя → прокси сервиса) с путем проверки path
+func NewProber(recorder *Recorder, services map[string]http.Handler, path string, interval, timeout time.Duration, logger *zap.Logger) *Prober {
+	doers := make(map[string]clients.Doer, len(services))
+	for name, handler := range services {
+		doers[name] = clients.HandlerDoer(handler)
+	}
+	return &Prober{
+		recorder: recorder,
+		services: doers,
+		path:     path,
+		interval: interval,
+		timeout:  timeout,
+		logger:   logger,
+	}
+}
+
+// Run проверяет сервисы каждые interval, пока не будет отменен ctx
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for name, doer := range p.services {
+				at := time.Now()
+				err := p.probe(ctx, doer)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					p.logger.Warn("Проверка доступности сервиса не пройдена", zap.String("service", name), zap.Error(err))
+				}
+				p.recorder.RecordCheck(name, at, err == nil)
+			}
+		}
+	}
+}
+
+// probe выполняет одну проверку сервиса
+func (p *Prober) probe(ctx context.Context, doer clients.Doer) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := doer.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("статус %d", resp.StatusCode)
+	}
+	return nil
+}
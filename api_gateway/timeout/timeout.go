@@ -0,0 +1,84 @@
+// Package timeout задает предельное время обработки запроса для отдельных маршрутов.
+// По истечении времени контекст запроса отменяется, запрос к сервису прерывается
+// и клиент получает 504, поэтому зависший сервис не удерживает соединения шлюза
+package timeout
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Rule таймаут для запросов с методом Method и путем, начинающимся с Prefix
+type Rule struct {
+	// Method HTTP метод; "*" — любой
+	Method  string        `json:"method"`
+	Prefix  string        `json:"prefix"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+// Matches проверяет, подходит ли правило для запроса
+func (rule Rule) Matches(r *http.Request) bool {
+	return (rule.Method == "*" || rule.Method == r.Method) && strings.HasPrefix(r.URL.Path, rule.Prefix)
+}
+
+// String возвращает правило в формате конфигурации
+func (rule Rule) String() string {
+	return fmt.Sprintf("%s %s=%s", rule.Method, rule.Prefix, rule.Timeout)
+}
+
+// ParseRules разбирает правила в формате
+// "POST /v1/orders=10s;* /v1/admin/directory-sync=5m". Метод "*" подходит для любого запроса
+func ParseRules(spec string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		left, right, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("некорректное правило %q: ожидается 'METHOD /path=timeout'", item)
+		}
+
+		fields := strings.Fields(left)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], "/") {
+			return nil, fmt.Errorf("некорректное правило %q: ожидается 'METHOD /path'", item)
+		}
+
+		value, err := time.ParseDuration(strings.TrimSpace(right))
+		if err != nil {
+			return nil, fmt.Errorf("некорректный таймаут в правиле %q: %v", item, err)
+		}
+		if value <= 0 {
+			return nil, fmt.Errorf("правило %q: таймаут должен быть больше 0", item)
+		}
+
+		rules = append(rules, Rule{Method: strings.ToUpper(fields[0]), Prefix: fields[1], Timeout: value})
+	}
+	return rules, nil
+}
+
+// Table выбирает таймаут запроса по первому подходящему правилу
+type Table struct {
+	rules    []Rule
+	fallback time.Duration
+}
+
+// NewTable создает таблицу таймаутов; fallback применяется к запросам,
+// не подходящим ни под одно правило (0 — без ограничения)
+func NewTable(rules []Rule, fallback time.Duration) *Table {
+	return &Table{rules: rules, fallback: fallback}
+}
+
+// For возвращает таймаут запроса; 0 — без ограничения
+func (t *Table) For(r *http.Request) time.Duration {
+	for _, rule := range t.rules {
+		if rule.Matches(r) {
+			return rule.Timeout
+		}
+	}
+	return t.fallback
+}
@@ -0,0 +1,144 @@
+// Package tracecontext разбирает и создает заголовок traceparent (W3C Trace Context).
+// Шлюз не записывает спаны сам, но продолжает трассу клиента или начинает новую,
+// чтобы сервисы и внешние трассировщики могли связать запросы одной трассы.
+// Атрибуты запроса (центр затрат) передаются в трассу через заголовок baggage
+package tracecontext
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// Заголовки W3C Trace Context и W3C Baggage
+const (
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+	HeaderBaggage     = "baggage"
+)
+
+// traceParentLength длина traceparent версии 00: "00-<32 hex>-<16 hex>-<2 hex>"
+const traceParentLength = 55
+
+// TraceParent значение заголовка traceparent
+type TraceParent struct {
+	TraceID  [16]byte
+	ParentID [8]byte
+	Flags    byte
+}
+
+// Parse разбирает заголовок traceparent. Для версий новее 00 используется префикс
+// известного формата, как требует спецификация. ok == false — заголовок отсутствует
+// или некорректен, и трассу нужно начать заново
+func Parse(header string) (tp TraceParent, ok bool) {
+	if len(header) < traceParentLength {
+		return TraceParent{}, false
+	}
+
+	version, ok := decodeHex(header[0:2])
+	if !ok || version[0] == 0xff {
+		return TraceParent{}, false
+	}
+	if version[0] == 0 && len(header) != traceParentLength {
+		return TraceParent{}, false
+	}
+	if len(header) > traceParentLength && header[traceParentLength] != '-' {
+		return TraceParent{}, false
+	}
+	if header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return TraceParent{}, false
+	}
+
+	traceID, ok := decodeHex(header[3:35])
+	if !ok || isZero(traceID) {
+		return TraceParent{}, false
+	}
+	parentID, ok := decodeHex(header[36:52])
+	if !ok || isZero(parentID) {
+		return TraceParent{}, false
+	}
+	flags, ok := decodeHex(header[53:55])
+	if !ok {
+		return TraceParent{}, false
+	}
+
+	copy(tp.TraceID[:], traceID)
+	copy(tp.ParentID[:], parentID)
+	tp.Flags = flags[0]
+	return tp, true
+}
+
+// New начинает новую трассу без флага sampled: решение о записи остается за сервисами
+func New() TraceParent {
+	var tp TraceParent
+	randomNonZero(tp.TraceID[:])
+	randomNonZero(tp.ParentID[:])
+	return tp
+}
+
+// Child возвращает traceparent для исходящего запроса: та же трасса и флаги,
+// новый идентификатор родителя
+func (tp TraceParent) Child() TraceParent {
+	child := tp
+	randomNonZero(child.ParentID[:])
+	return child
+}
+
+// TraceIDString возвращает идентификатор трассы в hex
+func (tp TraceParent) TraceIDString() string {
+	return hex.EncodeToString(tp.TraceID[:])
+}
+
+// String возвращает значение заголовка traceparent версии 00
+func (tp TraceParent) String() string {
+	return "00-" + hex.EncodeToString(tp.TraceID[:]) + "-" + hex.EncodeToString(tp.ParentID[:]) + "-" + hex.EncodeToString([]byte{tp.Flags})
+}
+
+// SetBaggage возвращает заголовок baggage, в котором член key заменен на value.
+// Одноименные члены, переданные клиентом, удаляются, остальные сохраняются
+func SetBaggage(header, key, value string) string {
+	members := []string{key + "=" + value}
+	for _, member := range strings.Split(header, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(member, "=")
+		if strings.TrimSpace(name) == key {
+			continue
+		}
+		members = append(members, member)
+	}
+	return strings.Join(members, ",")
+}
+
+// decodeHex декодирует hex в нижнем регистре; верхний регистр спецификация запрещает
+func decodeHex(s string) ([]byte, bool) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return nil, false
+		}
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// randomNonZero заполняет b случайными байтами; нулевой идентификатор недопустим
+func randomNonZero(b []byte) {
+	for {
+		// Начиная с Go 1.24 crypto/rand.Read не возвращает ошибку
+		rand.Read(b)
+		if !isZero(b) {
+			return
+		}
+	}
+}
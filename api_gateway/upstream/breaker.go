@@ -0,0 +1,209 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"pkg/httpresp"
+
+	"go.uber.org/zap"
+)
+
+// Состояния circuit breaker
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// BreakerState состояние circuit breaker сервиса
+type BreakerState struct {
+	Service string `json:"service"`
+	State   string `json:"state"`
+	// Failures число ошибок подряд в закрытом состоянии
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	// Forced breaker разомкнут вручную и не закроется до Reset
+	Forced bool `json:"forced"`
+}
+
+// Breaker circuit breaker перед сервисом: после threshold ответов 502/503/504 подряд
+// запросы отклоняются с 503 в течение openTimeout, затем один пробный запрос
+// решает, закрыть breaker или снова разомкнуть
+type Breaker struct {
+	service     string
+	next        http.Handler
+	threshold   int
+	openTimeout time.Duration
+	logger      *zap.Logger
+
+	mutex    sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	forced   bool
+	probing  bool
+}
+
+// NewBreaker создает circuit breaker. threshold 0 отключает автоматическое размыкание,
+// но breaker по-прежнему можно разомкнуть вручную
+func NewBreaker(service string, next http.Handler, threshold int, openTimeout time.Duration, logger *zap.Logger) *Breaker {
+	return &Breaker{
+		service:     service,
+		next:        next,
+		threshold:   threshold,
+		openTimeout: openTimeout,
+		logger:      logger,
+		state:       BreakerClosed,
+	}
+}
+
+// ServeHTTP проксирует запрос, если breaker пропускает его
+func (b *Breaker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	probe, ok := b.allow(time.Now())
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(b.openTimeout.Seconds())))
+		if err := httpresp.JSON(w, http.StatusServiceUnavailable, map[string]string{"error": "Сервис " + b.service + " временно недоступен"}); err != nil {
+			b.logger.Error("Failed to write JSON response", zap.Error(err))
+		}
+		return
+	}
+
+	// record в defer: reverse proxy прерывает обработчик паникой при обрыве ответа,
+	// а пробный запрос должен освободить полуоткрытое состояние в любом случае
+	success := false
+	defer func() {
+		b.record(probe, success, time.Now())
+	}()
+
+	wrapper := &statusWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	b.next.ServeHTTP(wrapper, r)
+	success = !isUnavailable(wrapper.statusCode)
+}
+
+// isUnavailable ответы, означающие недоступность сервиса. Остальные 5xx — ошибки
+// отдельных запросов, и они не должны блокировать весь сервис
+func isUnavailable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Run запускает фоновые задачи обернутого обработчика
+func (b *Breaker) Run(ctx context.Context) {
+	if runner, ok := b.next.(interface{ Run(context.Context) }); ok {
+		runner.Run(ctx)
+	}
+}
+
+// allow решает, пропустить ли запрос; probe — запрос пробный (half-open)
+func (b *Breaker) allow(now time.Time) (probe bool, ok bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return false, true
+	case BreakerOpen:
+		if b.forced || now.Sub(b.openedAt) < b.openTimeout {
+			return false, false
+		}
+		b.state = BreakerHalfOpen
+	}
+
+	// Полуоткрытое состояние: одновременно выполняется только один пробный запрос
+	if b.probing {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// record учитывает результат запроса
+func (b *Breaker) record(probe, success bool, now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if probe {
+		b.probing = false
+		// Breaker могли разомкнуть или сбросить вручную во время пробного запроса
+		if b.state != BreakerHalfOpen {
+			return
+		}
+		if success {
+			b.closeLocked()
+			b.logger.Info("Circuit breaker закрыт", zap.String("service", b.service))
+		} else {
+			b.openLocked(now, false)
+		}
+		return
+	}
+
+	if b.state != BreakerClosed {
+		return
+	}
+	if success {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openLocked(now, false)
+	}
+}
+
+// Trip размыкает breaker вручную до вызова Reset
+func (b *Breaker) Trip() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.openLocked(time.Now(), true)
+}
+
+// Reset закрывает breaker и сбрасывает счетчик ошибок
+func (b *Breaker) Reset() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.closeLocked()
+	b.logger.Info("Circuit breaker сброшен", zap.String("service", b.service))
+}
+
+// State возвращает текущее состояние breaker
+func (b *Breaker) State() BreakerState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	state := BreakerState{
+		Service:  b.service,
+		State:    b.state,
+		Failures: b.failures,
+		Forced:   b.forced,
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt.UTC()
+		state.OpenedAt = &openedAt
+	}
+	return state
+}
+
+func (b *Breaker) openLocked(now time.Time, forced bool) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.forced = forced
+	b.logger.Warn("Circuit breaker разомкнут",
+		zap.String("service", b.service),
+		zap.Int("failures", b.failures),
+		zap.Bool("forced", forced),
+	)
+}
+
+func (b *Breaker) closeLocked() {
+	b.state = BreakerClosed
+	b.failures = 0
+	b.forced = false
+}
@@ -0,0 +1,245 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"api_gateway/accesslog"
+
+	"go.uber.org/zap"
+)
+
+// Экземпляры сервиса при резервировании
+const (
+	TargetPrimary   = "primary"
+	TargetSecondary = "secondary"
+)
+
+// HealthCheck настройки активной проверки основного экземпляра
+type HealthCheck struct {
+	Interval time.Duration
+	Timeout  time.Duration
+	// Path путь HTTP проверки (ответ ниже 500 — экземпляр доступен);
+	// пусто — проверяется только TCP соединение
+	Path string
+}
+
+// FailoverState состояние резервирования сервиса
+type FailoverState struct {
+	Service string `json:"service"`
+	// Active экземпляр, получающий запросы: primary или secondary
+	Active string `json:"active"`
+	// Failures число ответов 502/503/504 основного экземпляра подряд
+	Failures int `json:"failures"`
+	// FailedOverAt время переключения на резервный экземпляр
+	FailedOverAt *time.Time `json:"failed_over_at,omitempty"`
+}
+
+// Failover направляет запросы на резервный экземпляр сервиса, когда основной
+// недоступен: после threshold ответов 502/503/504 подряд или неудачной проверки.
+// Запросы возвращаются на основной экземпляр после первой успешной проверки
+type Failover struct {
+	service   string
+	primary   *Upstream
+	secondary *Upstream
+	threshold int
+	check     HealthCheck
+	client    *http.Client
+	logger    *zap.Logger
+
+	mutex        sync.Mutex
+	active       string
+	failures     int
+	failedOverAt time.Time
+}
+
+// NewFailover создает Failover. threshold 0 отключает переключение по ошибкам запросов:
+// остается только активная проверка
+func NewFailover(service string, primary, secondary *Upstream, threshold int, check HealthCheck, logger *zap.Logger) *Failover {
+	return &Failover{
+		service:   service,
+		primary:   primary,
+		secondary: secondary,
+		threshold: threshold,
+		check:     check,
+		client: &http.Client{
+			Transport: primary.transport,
+			Timeout:   check.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		logger: logger,
+		active: TargetPrimary,
+	}
+}
+
+// ServeHTTP проксирует запрос к активному экземпляру
+func (f *Failover) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.activeTarget() == TargetSecondary {
+		if entry := accesslog.FromContext(r.Context()); entry != nil {
+			entry.Upstream = f.service + "/" + TargetSecondary
+		}
+		f.secondary.ServeHTTP(w, r)
+		return
+	}
+
+	// record в defer по той же причине, что и в Breaker: обрыв ответа прерывает обработчик паникой
+	wrapper := &statusWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	defer func() {
+		f.record(isUnavailable(wrapper.statusCode), r)
+	}()
+	f.primary.ServeHTTP(wrapper, r)
+}
+
+// Run проверяет основной экземпляр с интервалом check.Interval и запускает
+// переразрешение DNS обоих экземпляров, пока не будет отменен ctx
+func (f *Failover) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, runner := range []*Upstream{f.primary, f.secondary} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runner.Run(ctx)
+		}()
+	}
+
+	if f.check.Interval > 0 {
+		ticker := time.NewTicker(f.check.Interval)
+		defer ticker.Stop()
+
+	loop:
+		for {
+			select {
+			case <-ctx.Done():
+				break loop
+			case <-ticker.C:
+				if err := f.probe(ctx); err != nil {
+					f.failover("health_check", err)
+				} else {
+					f.failback()
+				}
+			}
+		}
+	}
+	wg.Wait()
+}
+
+// State возвращает текущее состояние резервирования
+func (f *Failover) State() FailoverState {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	state := FailoverState{Service: f.service, Active: f.active, Failures: f.failures}
+	if f.active == TargetSecondary {
+		failedOverAt := f.failedOverAt.UTC()
+		state.FailedOverAt = &failedOverAt
+	}
+	return state
+}
+
+func (f *Failover) activeTarget() string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.active
+}
+
+// record учитывает ответ основного экземпляра
+func (f *Failover) record(unavailable bool, r *http.Request) {
+	f.mutex.Lock()
+	if !unavailable {
+		f.failures = 0
+		f.mutex.Unlock()
+		return
+	}
+	f.failures++
+	reached := f.threshold > 0 && f.failures >= f.threshold
+	f.mutex.Unlock()
+
+	if reached {
+		f.failover("consecutive_errors", fmt.Errorf("%d ответов 502/503/504 подряд, последний: %s %s", f.threshold, r.Method, r.URL.Path))
+	}
+}
+
+// failover переключает запросы на резервный экземпляр
+func (f *Failover) failover(reason string, cause error) {
+	f.mutex.Lock()
+	if f.active == TargetSecondary {
+		f.mutex.Unlock()
+		return
+	}
+	f.active = TargetSecondary
+	f.failedOverAt = time.Now()
+	failures := f.failures
+	f.mutex.Unlock()
+
+	f.logger.Warn("Основной экземпляр сервиса недоступен, запросы направляются на резервный",
+		zap.String("service", f.service),
+		zap.String("primary", f.primary.target.String()),
+		zap.String("secondary", f.secondary.target.String()),
+		zap.String("reason", reason),
+		zap.Int("failures", failures),
+		zap.Error(cause),
+	)
+}
+
+// failback возвращает запросы на основной экземпляр
+func (f *Failover) failback() {
+	f.mutex.Lock()
+	if f.active == TargetPrimary {
+		f.mutex.Unlock()
+		return
+	}
+	f.active = TargetPrimary
+	f.failures = 0
+	downtime := time.Since(f.failedOverAt)
+	f.mutex.Unlock()
+
+	f.logger.Info("Основной экземпляр сервиса снова доступен, запросы возвращены на него",
+		zap.String("service", f.service),
+		zap.String("primary", f.primary.target.String()),
+		zap.Duration("failover_duration", downtime),
+	)
+}
+
+// probe проверяет основной экземпляр: HTTP запросом к check.Path или TCP соединением
+func (f *Failover) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, f.check.Timeout)
+	defer cancel()
+
+	target := f.primary.target
+	if f.check.Path == "" {
+		port := target.Port()
+		if port == "" {
+			port = "80"
+			if target.Scheme == "https" {
+				port = "443"
+			}
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(target.Hostname(), port))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(target.String(), "/")+f.check.Path, nil)
+	if err != nil {
+		return err
+	}
+	response, err := f.client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("проверка вернула статус %d", response.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"pkg/httpresp"
+
+	"go.uber.org/zap"
+)
+
+// LimiterState состояние ограничителя одновременных запросов
+type LimiterState struct {
+	Name     string `json:"name"`
+	InFlight int    `json:"in_flight"`
+	Limit    int    `json:"limit"`
+	Waiting  int    `json:"waiting"`
+	Queue    int    `json:"queue"`
+	// Rejected число запросов, отклоненных с 503 с момента запуска
+	Rejected int64 `json:"rejected"`
+}
+
+// Limiter ограничивает число одновременно выполняемых запросов. Запрос сверх предела
+// ждет свободного места не дольше maxWait в очереди из queue мест; если очередь
+// заполнена или время вышло, запрос отклоняется, чтобы всплеск нагрузки
+// не накапливался в сервисе и в памяти шлюза
+type Limiter struct {
+	name    string
+	slots   chan struct{}
+	waiting chan struct{}
+	maxWait time.Duration
+	logger  *zap.Logger
+
+	rejected atomic.Int64
+}
+
+// NewLimiter создает ограничитель на limit одновременных запросов с очередью из queue мест
+func NewLimiter(name string, limit, queue int, maxWait time.Duration, logger *zap.Logger) *Limiter {
+	return &Limiter{
+		name:    name,
+		slots:   make(chan struct{}, limit),
+		waiting: make(chan struct{}, queue),
+		maxWait: maxWait,
+		logger:  logger,
+	}
+}
+
+// Acquire занимает место для запроса. Возвращает false, если место не освободилось
+// за maxWait, очередь заполнена или отменен ctx; иначе место нужно освободить release
+func (l *Limiter) Acquire(ctx context.Context) (release func(), ok bool) {
+	release = func() { <-l.slots }
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, true
+	default:
+	}
+
+	select {
+	case l.waiting <- struct{}{}:
+	default:
+		l.rejected.Add(1)
+		return nil, false
+	}
+	defer func() { <-l.waiting }()
+
+	timer := time.NewTimer(l.maxWait)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	l.rejected.Add(1)
+	return nil, false
+}
+
+// Reject отвечает 503 на запрос, для которого не нашлось места
+func (l *Limiter) Reject(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "1")
+	if err := httpresp.JSON(w, http.StatusServiceUnavailable, map[string]string{"error": "Сервис перегружен, повторите запрос позже"}); err != nil {
+		l.logger.Error("Failed to write JSON response", zap.Error(err))
+	}
+}
+
+// Wrap возвращает обработчик, выполняющий next только при свободном месте
+func (l *Limiter) Wrap(next http.Handler) http.Handler {
+	return &limitedHandler{limiter: l, next: next}
+}
+
+// State возвращает текущее состояние ограничителя
+func (l *Limiter) State() LimiterState {
+	return LimiterState{
+		Name:     l.name,
+		InFlight: len(l.slots),
+		Limit:    cap(l.slots),
+		Waiting:  len(l.waiting),
+		Queue:    cap(l.waiting),
+		Rejected: l.rejected.Load(),
+	}
+}
+
+// limitedHandler обработчик сервиса за ограничителем
+type limitedHandler struct {
+	limiter *Limiter
+	next    http.Handler
+}
+
+// ServeHTTP проксирует запрос, если для него нашлось место
+func (h *limitedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	release, ok := h.limiter.Acquire(r.Context())
+	if !ok {
+		h.limiter.Reject(w)
+		return
+	}
+	defer release()
+
+	h.next.ServeHTTP(w, r)
+}
+
+// Run запускает фоновые задачи обернутого обработчика
+func (h *limitedHandler) Run(ctx context.Context) {
+	if runner, ok := h.next.(interface{ Run(context.Context) }); ok {
+		runner.Run(ctx)
+	}
+}
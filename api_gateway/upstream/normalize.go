@@ -0,0 +1,123 @@
+package upstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxErrorBodySize размер тела ошибки сервиса, которое читается для приведения к
+// общему формату; ответы большего размера передаются клиенту без изменений
+const maxErrorBodySize = 64 << 10
+
+// errorEnvelope общий формат ответа с ошибкой микросервисов
+type errorEnvelope struct {
+	Success bool       `json:"success"`
+	Error   *errorBody `json:"error"`
+}
+
+// errorBody ошибка в общем формате ответа
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// normalizeErrorResponse приводит ответы сервиса 4xx/5xx к общему формату
+// {success:false, error:{code,message}}. Ответы уже в этом формате не изменяются;
+// для остальных (текст маршрутизатора, JSON другой структуры, пустое тело) код
+// определяется по статусу, а сообщение ошибок 4xx берется из полей message/error JSON тела
+func normalizeErrorResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest || resp.Request.Method == http.MethodHead {
+		return nil
+	}
+	if encoding := resp.Header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxErrorBodySize {
+		resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return nil
+	}
+	resp.Body.Close()
+
+	message := ""
+	if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		var envelope errorEnvelope
+		if json.Unmarshal(body, &envelope) == nil && !envelope.Success && envelope.Error != nil && envelope.Error.Code != "" {
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			return nil
+		}
+
+		// Текст ошибок 5xx может содержать внутренние подробности и клиенту не передается
+		var fields map[string]interface{}
+		if resp.StatusCode < http.StatusInternalServerError && json.Unmarshal(body, &fields) == nil {
+			message = firstString(fields, "message", "error")
+		}
+	}
+
+	code, defaultMessage := errorCode(resp.StatusCode)
+	if message == "" {
+		message = defaultMessage
+	}
+
+	normalized, err := json.Marshal(errorEnvelope{Error: &errorBody{Code: code, Message: message}})
+	if err != nil {
+		return err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(normalized))
+	resp.ContentLength = int64(len(normalized))
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Set("Content-Length", strconv.Itoa(len(normalized)))
+	return nil
+}
+
+// errorCode возвращает код ошибки общего формата и сообщение по умолчанию для статуса
+func errorCode(status int) (string, string) {
+	switch status {
+	case http.StatusBadRequest:
+		return "VALIDATION_ERROR", "Некорректный запрос"
+	case http.StatusUnauthorized:
+		return "UNAUTHORIZED", "Требуется аутентификация"
+	case http.StatusForbidden:
+		return "FORBIDDEN", "Недостаточно прав доступа"
+	case http.StatusNotFound:
+		return "NOT_FOUND", "Ресурс не найден"
+	case http.StatusMethodNotAllowed:
+		return "METHOD_NOT_ALLOWED", "Метод не поддерживается"
+	case http.StatusConflict:
+		return "CONFLICT", "Конфликт данных"
+	case http.StatusRequestEntityTooLarge:
+		return "VALIDATION_ERROR", "Тело запроса слишком большое"
+	case http.StatusTooManyRequests:
+		return "RATE_LIMIT_EXCEEDED", "Превышен лимит запросов"
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return "SERVICE_UNAVAILABLE", "Сервис временно недоступен"
+	}
+	if status >= http.StatusInternalServerError {
+		return "INTERNAL_SERVER_ERROR", "Внутренняя ошибка сервера"
+	}
+	return "BAD_REQUEST", "Некорректный запрос"
+}
+
+// firstString возвращает первое непустое строковое значение из полей keys
+func firstString(fields map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := fields[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// readCloser тело ответа с уже прочитанным началом
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,84 @@
+package upstream
+
+import (
+	"context"
+	"hash/crc32"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ringVirtualNodes число точек каждой реплики на кольце: чем больше точек,
+// тем равномернее ключи распределяются между репликами
+const ringVirtualNodes = 160
+
+// Ring распределяет запросы между репликами сервиса консистентным хешированием ключа
+// (обычно идентификатора пользователя): запросы с одним ключом попадают на одну реплику,
+// что сохраняет эффективность кешей внутри экземпляров сервиса. При добавлении или
+// удалении реплики на другую реплику переходит только часть ключей
+type Ring struct {
+	replicas []http.Handler
+	points   []ringPoint
+	key      func(r *http.Request) string
+}
+
+// ringPoint точка реплики на кольце
+type ringPoint struct {
+	hash    uint32
+	replica int
+}
+
+// NewRing создает Ring для реплик; names — устойчивые имена реплик (например, URL),
+// по которым строятся точки кольца, key возвращает ключ привязки запроса
+func NewRing(replicas []http.Handler, names []string, key func(r *http.Request) string) *Ring {
+	ring := &Ring{
+		replicas: replicas,
+		points:   make([]ringPoint, 0, len(replicas)*ringVirtualNodes),
+		key:      key,
+	}
+	for i, name := range names {
+		for node := 0; node < ringVirtualNodes; node++ {
+			ring.points = append(ring.points, ringPoint{
+				hash:    crc32.ChecksumIEEE([]byte(name + "#" + strconv.Itoa(node))),
+				replica: i,
+			})
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool {
+		return ring.points[i].hash < ring.points[j].hash
+	})
+	return ring
+}
+
+// ServeHTTP проксирует запрос к реплике, закрепленной за ключом запроса
+func (r *Ring) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.replicas[r.Replica(r.key(req))].ServeHTTP(w, req)
+}
+
+// Replica возвращает номер реплики для ключа: первую точку кольца по часовой стрелке от хеша ключа
+func (r *Ring) Replica(key string) int {
+	hash := crc32.ChecksumIEEE([]byte(key))
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= hash
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].replica
+}
+
+// Run запускает фоновые задачи всех реплик (переразрешение DNS), пока не будет отменен ctx
+func (r *Ring) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, handler := range r.replicas {
+		if runner, ok := handler.(interface{ Run(context.Context) }); ok {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runner.Run(ctx)
+			}()
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,125 @@
+package upstream
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"api_gateway/accesslog"
+	"api_gateway/metrics"
+)
+
+// Версии сервиса при канареечной выкладке
+const (
+	TargetStable = "stable"
+	TargetCanary = "canary"
+)
+
+// TargetHeader заголовок ответа с версией сервиса, обработавшей запрос
+const TargetHeader = "X-Upstream-Target"
+
+// Split распределяет запросы к сервису между основной и канареечной версиями.
+// Доля канарейки задается весом; заголовок override позволяет тестировщикам
+// явно выбрать версию независимо от веса
+type Split struct {
+	service  string
+	stable   http.Handler
+	canary   http.Handler
+	weight   int
+	override string
+	metrics  *metrics.UpstreamMetrics
+}
+
+// NewSplit создает Split: weight процентов запросов (0-100) направляется на canary.
+// canary может быть nil — тогда все запросы идут на stable, а Split только пишет метрики;
+// metrics может быть nil, если метрики отключены
+func NewSplit(service string, stable, canary http.Handler, weight int, override string, metrics *metrics.UpstreamMetrics) *Split {
+	return &Split{
+		service:  service,
+		stable:   stable,
+		canary:   canary,
+		weight:   weight,
+		override: override,
+		metrics:  metrics,
+	}
+}
+
+// ServeHTTP проксирует запрос к выбранной версии сервиса
+func (s *Split) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := s.choose(r)
+
+	handler := s.stable
+	if target == TargetCanary {
+		handler = s.canary
+	}
+
+	if s.canary != nil {
+		if entry := accesslog.FromContext(r.Context()); entry != nil {
+			entry.Upstream = s.service + "/" + target
+		}
+		w.Header().Set(TargetHeader, target)
+	}
+
+	if s.metrics == nil {
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	wrapper := &statusWriter{ResponseWriter: w, statusCode: http.StatusOK}
+	start := time.Now()
+	handler.ServeHTTP(wrapper, r)
+	s.metrics.Observe(s.service, target, wrapper.statusCode, time.Since(start))
+}
+
+// Run запускает фоновые задачи обеих версий (переразрешение DNS), пока не будет отменен ctx
+func (s *Split) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, handler := range []http.Handler{s.stable, s.canary} {
+		if runner, ok := handler.(interface{ Run(context.Context) }); ok {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runner.Run(ctx)
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// choose выбирает версию: по заголовку override, иначе случайно с учетом веса
+func (s *Split) choose(r *http.Request) string {
+	if s.canary == nil {
+		return TargetStable
+	}
+
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get(s.override))) {
+	case TargetCanary:
+		return TargetCanary
+	case TargetStable:
+		return TargetStable
+	}
+
+	if s.weight > 0 && rand.IntN(100) < s.weight {
+		return TargetCanary
+	}
+	return TargetStable
+}
+
+// statusWriter запоминает код ответа для метрик
+type statusWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	sw.statusCode = code
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap позволяет http.ResponseController получить доступ к исходному ResponseWriter (Flush)
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,239 @@
+// Package upstream содержит reverse proxy к микросервисам с периодическим
+// переразрешением DNS имени upstream и пересозданием пула соединений
+package upstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"api_gateway/metrics"
+
+	"pkg/httpresp"
+
+	"go.uber.org/zap"
+)
+
+// Upstream reverse proxy к сервису. Keep-alive соединения продолжают
+// использовать старый IP, даже если адрес за именем хоста изменился, поэтому
+// Upstream периодически переразрешает имя и при изменении адресов закрывает
+// простаивающие соединения. Ошибки соединения вызывают внеплановую проверку
+type Upstream struct {
+	name      string
+	target    *url.URL
+	transport *http.Transport
+	proxy     *httputil.ReverseProxy
+	resolver  *net.Resolver
+	interval  time.Duration
+	logger    *zap.Logger
+	metrics   *metrics.ProtocolMetrics
+
+	mutex      sync.Mutex
+	addrs      []string
+	refreshing bool
+}
+
+// New создает Upstream для сервиса name с адресом rawURL.
+// refreshInterval <= 0 отключает периодическое переразрешение DNS.
+// protocolMetrics может быть nil, если метрики отключены
+func New(name, rawURL string, transport *http.Transport, refreshInterval time.Duration, protocolMetrics *metrics.ProtocolMetrics, logger *zap.Logger) (*Upstream, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный URL сервиса %s: %v", name, err)
+	}
+	if target.Host == "" {
+		return nil, fmt.Errorf("некорректный URL сервиса %s: не указан хост", name)
+	}
+
+	if transport == nil {
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+
+	u := &Upstream{
+		name:      name,
+		target:    target,
+		transport: transport,
+		resolver:  net.DefaultResolver,
+		interval:  refreshInterval,
+		logger:    logger,
+		metrics:   protocolMetrics,
+	}
+
+	u.proxy = httputil.NewSingleHostReverseProxy(target)
+	u.proxy.Transport = transport
+	u.proxy.ErrorHandler = u.handleError
+	u.proxy.ModifyResponse = u.modifyResponse
+
+	return u, nil
+}
+
+// ServeHTTP проксирует запрос к сервису. При включенных метриках учитывается, получено ли
+// для запроса новое соединение или повторно использовано keep-alive соединение
+func (u *Upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if u.metrics != nil {
+		trace := &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				u.metrics.ObserveUpstreamConn(u.name, info.Reused)
+			},
+		}
+		r = r.WithContext(httptrace.WithClientTrace(r.Context(), trace))
+	}
+	u.proxy.ServeHTTP(w, r)
+}
+
+// modifyResponse учитывает версию HTTP ответа сервиса и приводит ответ с ошибкой к общему формату
+func (u *Upstream) modifyResponse(resp *http.Response) error {
+	u.metrics.ObserveUpstreamResponse(u.name, resp.Proto)
+	return normalizeErrorResponse(resp)
+}
+
+// Run периодически переразрешает DNS имя сервиса, пока не будет отменен ctx
+func (u *Upstream) Run(ctx context.Context) {
+	if u.interval <= 0 || net.ParseIP(u.target.Hostname()) != nil {
+		return
+	}
+
+	u.refresh(ctx)
+
+	ticker := time.NewTicker(u.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.refresh(ctx)
+		}
+	}
+}
+
+// refresh разрешает имя хоста и закрывает простаивающие соединения при изменении адресов
+func (u *Upstream) refresh(ctx context.Context) {
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	addrs, err := u.resolver.LookupHost(lookupCtx, u.target.Hostname())
+	if err != nil {
+		u.logger.Warn("Не удалось разрешить адрес upstream сервиса",
+			zap.String("service", u.name),
+			zap.String("host", u.target.Hostname()),
+			zap.Error(err),
+		)
+		return
+	}
+	sort.Strings(addrs)
+
+	u.mutex.Lock()
+	previous := u.addrs
+	u.addrs = addrs
+	u.mutex.Unlock()
+
+	if previous == nil || strings.Join(previous, ",") == strings.Join(addrs, ",") {
+		return
+	}
+
+	u.logger.Info("Адрес upstream сервиса изменился, пул соединений пересоздается",
+		zap.String("service", u.name),
+		zap.Strings("old_addrs", previous),
+		zap.Strings("new_addrs", addrs),
+	)
+	u.transport.CloseIdleConnections()
+}
+
+// clientClosedRequest статус запроса, отмененного клиентом до ответа сервиса
+// (нестандартный, как в nginx): в журнале доступа такие запросы не выглядят как 502
+const clientClosedRequest = 499
+
+// handleError отвечает 502 и запускает внеплановую проверку адреса сервиса.
+// Если истек таймаут запроса, отвечает 504: адрес сервиса при этом не проверяется,
+// так как соединение было установлено. Запрос, отмененный клиентом, не считается
+// ошибкой сервиса
+func (u *Upstream) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	requestID := r.Header.Get("X-Request-ID")
+
+	if errors.Is(err, context.Canceled) && errors.Is(r.Context().Err(), context.Canceled) {
+		u.logger.Info("Клиент отменил запрос до ответа сервиса",
+			zap.String("service", u.name),
+			zap.String("request_id", requestID),
+			zap.String("path", r.URL.Path),
+		)
+		w.WriteHeader(clientClosedRequest)
+		return
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		u.logger.Warn("Сервис не ответил за отведенное время",
+			zap.String("service", u.name),
+			zap.String("request_id", requestID),
+			zap.String("path", r.URL.Path),
+			zap.Error(err),
+		)
+		u.writeError(w, http.StatusGatewayTimeout, "Сервис "+u.name+" не ответил вовремя", requestID)
+		return
+	}
+
+	u.logger.Error("Ошибка проксирования к сервису",
+		zap.String("service", u.name),
+		zap.String("request_id", requestID),
+		zap.String("path", r.URL.Path),
+		zap.Error(err),
+	)
+
+	// Соединения могли остаться к старому адресу: закрываем их и переразрешаем имя
+	u.transport.CloseIdleConnections()
+	u.refreshAsync()
+
+	u.writeError(w, http.StatusBadGateway, "Сервис "+u.name+" недоступен", requestID)
+}
+
+// proxyError тело ответа 502/504: request_id связывает ответ с записями логов Gateway
+type proxyError struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+}
+
+// writeError отправляет ошибку проксирования с идентификатором запроса для обращения в поддержку
+func (u *Upstream) writeError(w http.ResponseWriter, status int, message, requestID string) {
+	body := proxyError{Error: message, RequestID: requestID}
+	if requestID != "" {
+		body.Hint = "Укажите request_id при обращении в поддержку"
+	}
+	if err := httpresp.JSON(w, status, body); err != nil {
+		u.logger.Error("Failed to write JSON response", zap.Error(err))
+	}
+}
+
+// refreshAsync запускает переразрешение в фоне, не более одного одновременно
+func (u *Upstream) refreshAsync() {
+	if u.interval <= 0 {
+		return
+	}
+
+	u.mutex.Lock()
+	if u.refreshing {
+		u.mutex.Unlock()
+		return
+	}
+	u.refreshing = true
+	u.mutex.Unlock()
+
+	go func() {
+		defer func() {
+			u.mutex.Lock()
+			u.refreshing = false
+			u.mutex.Unlock()
+		}()
+		u.refresh(context.Background())
+	}()
+}
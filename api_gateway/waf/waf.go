@@ -0,0 +1,291 @@
+// Package waf отсеивает запросы с очевидно вредоносным содержимым до проксирования
+// к сервисам: SQL инъекции, XSS, обход каталогов и слишком большие заголовки.
+// Правила намеренно простые и не заменяют параметризованные запросы и экранирование
+// в сервисах — фильтр лишь снимает массовые автоматические атаки на входе
+package waf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Правила фильтра
+const (
+	RuleSQLi          = "sqli"
+	RuleXSS           = "xss"
+	RulePathTraversal = "path_traversal"
+	RuleHeaderSize    = "header_size"
+)
+
+// AllRules все правила фильтра
+var AllRules = []string{RuleSQLi, RuleXSS, RulePathTraversal, RuleHeaderSize}
+
+// patterns шаблоны правил, проверяемые в пути, параметрах запроса и теле.
+// Строки проверяются после декодирования URL и приведения к нижнему регистру
+var patterns = map[string][]*regexp.Regexp{
+	RuleSQLi: {
+		regexp.MustCompile(`\bunion(\s|/\*.*?\*/)+(all(\s|/\*.*?\*/)+)?select\b`),
+		regexp.MustCompile(`['"]\s*(or|and)\s+['"]?\w+['"]?\s*(=|like)\s*['"]?\w+`),
+		regexp.MustCompile(`\bor\s+1\s*=\s*1\b`),
+		regexp.MustCompile(`'\s*(--|#|;\s*(select|drop|delete|insert|update|shutdown|exec)\b)`),
+		regexp.MustCompile(`;\s*(drop|truncate|alter)\s+(table|database|schema)\b`),
+		regexp.MustCompile(`;\s*(delete\s+from|insert\s+into|update\s+\w+\s+set)\b`),
+		regexp.MustCompile(`\b(sleep|pg_sleep|benchmark)\s*\(`),
+		regexp.MustCompile(`\bwaitfor\s+delay\b`),
+		regexp.MustCompile(`\binformation_schema\b|\bpg_catalog\b`),
+	},
+	RuleXSS: {
+		regexp.MustCompile(`<\s*/?\s*script\b`),
+		regexp.MustCompile(`\b(javascript|vbscript)\s*:`),
+		regexp.MustCompile(`<[^>]*\bon[a-z]+\s*=`),
+		regexp.MustCompile(`<\s*(iframe|object|embed|frameset)\b`),
+		regexp.MustCompile(`\bdocument\s*\.\s*(cookie|domain)\b`),
+		regexp.MustCompile(`\bexpression\s*\(`),
+	},
+	RulePathTraversal: {
+		regexp.MustCompile(`(^|[/\\])\.\.([/\\]|$)`),
+		regexp.MustCompile(`/etc/(passwd|shadow|hosts)\b`),
+		regexp.MustCompile(`c:\\windows\\`),
+		regexp.MustCompile(`\x00`),
+	},
+}
+
+// overlongDot UTF-8 overlong кодировки точки и слеша; ищутся в пути и параметрах до декодирования
+var overlongDot = regexp.MustCompile(`(?i)%c0%ae|%c0%af|%c1%9c|%e0%80%ae`)
+
+// ParseRules разбирает список правил через запятую; пустая строка — все правила
+func ParseRules(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return append([]string(nil), AllRules...), nil
+	}
+
+	var rules []string
+	for _, rule := range strings.Split(raw, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		if !knownRule(rule) {
+			return nil, fmt.Errorf("неизвестное правило %q (допустимы %s)", rule, strings.Join(AllRules, ", "))
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func knownRule(rule string) bool {
+	for _, known := range AllRules {
+		if rule == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Match сработавшее правило
+type Match struct {
+	Rule string
+	// Location где найдено совпадение: path, query:<параметр>, body, body:<поле> или headers.
+	// Значение не сохраняется: оно может содержать персональные данные
+	Location string
+}
+
+// Filter фильтр запросов
+type Filter struct {
+	rules map[string]bool
+	// maxHeaderBytes предельный суммарный размер заголовков запроса
+	maxHeaderBytes int
+	// maxBodyBytes сколько байт тела проверяется; остаток тела передается без проверки
+	maxBodyBytes int
+	// skipFields поля JSON и форм, которые не проверяются (пароли могут содержать что угодно)
+	skipFields map[string]bool
+}
+
+// NewFilter создает фильтр с включенными правилами rules
+func NewFilter(rules []string, maxHeaderBytes, maxBodyBytes int, skipFields []string) *Filter {
+	f := &Filter{
+		rules:          make(map[string]bool, len(rules)),
+		maxHeaderBytes: maxHeaderBytes,
+		maxBodyBytes:   maxBodyBytes,
+		skipFields:     make(map[string]bool, len(skipFields)),
+	}
+	for _, rule := range rules {
+		f.rules[rule] = true
+	}
+	for _, field := range skipFields {
+		f.skipFields[strings.ToLower(field)] = true
+	}
+	return f
+}
+
+// Rules возвращает включенные правила в порядке AllRules
+func (f *Filter) Rules() []string {
+	rules := make([]string, 0, len(f.rules))
+	for _, rule := range AllRules {
+		if f.rules[rule] {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// Inspect проверяет запрос и возвращает первое сработавшее правило или nil.
+// Проверенная часть тела возвращается в r.Body, поэтому запрос можно проксировать дальше
+func (f *Filter) Inspect(r *http.Request) (*Match, error) {
+	if f.rules[RuleHeaderSize] && headerSize(r) > f.maxHeaderBytes {
+		return &Match{Rule: RuleHeaderSize, Location: "headers"}, nil
+	}
+
+	if match := f.inspectRaw("path", r.URL.EscapedPath()); match != nil {
+		return match, nil
+	}
+	if match := f.inspectString("path", r.URL.Path); match != nil {
+		return match, nil
+	}
+	if match := f.inspectRaw("query", r.URL.RawQuery); match != nil {
+		return match, nil
+	}
+	if match := f.inspectValues("query", r.URL.RawQuery); match != nil {
+		return match, nil
+	}
+
+	return f.inspectBody(r)
+}
+
+// inspectBody проверяет начало тела запросов JSON, форм и текста
+func (f *Filter) inspectBody(r *http.Request) (*Match, error) {
+	if r.Body == nil || r.Body == http.NoBody || f.maxBodyBytes <= 0 {
+		return nil, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" && mediaType != "application/x-www-form-urlencoded" && !strings.HasPrefix(mediaType, "text/") {
+		return nil, nil
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(r.Body, int64(f.maxBodyBytes)))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения тела запроса: %v", err)
+	}
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(prefix), r.Body), Closer: r.Body}
+
+	switch mediaType {
+	case "application/json":
+		var payload interface{}
+		if err := json.Unmarshal(prefix, &payload); err == nil {
+			return f.inspectJSON("body", payload), nil
+		}
+	case "application/x-www-form-urlencoded":
+		return f.inspectValues("body", string(prefix)), nil
+	}
+	// Текст и JSON, не поместившийся в проверяемую часть, проверяются как есть
+	return f.inspectString("body", string(prefix)), nil
+}
+
+// inspectJSON проверяет ключи и строковые значения JSON документа
+func (f *Filter) inspectJSON(location string, value interface{}) *Match {
+	switch v := value.(type) {
+	case string:
+		return f.inspectString(location, v)
+	case []interface{}:
+		for _, item := range v {
+			if match := f.inspectJSON(location, item); match != nil {
+				return match
+			}
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			if f.skipFields[strings.ToLower(key)] {
+				continue
+			}
+			if match := f.inspectString("body:"+key, key); match != nil {
+				return match
+			}
+			if match := f.inspectJSON("body:"+key, item); match != nil {
+				return match
+			}
+		}
+	}
+	return nil
+}
+
+// inspectValues проверяет имена и значения параметров в формате query string
+func (f *Filter) inspectValues(location, raw string) *Match {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return f.inspectString(location, raw)
+	}
+	for name, list := range values {
+		if f.skipFields[strings.ToLower(name)] {
+			continue
+		}
+		if match := f.inspectString(location+":"+name, name); match != nil {
+			return match
+		}
+		for _, value := range list {
+			if match := f.inspectString(location+":"+name, value); match != nil {
+				return match
+			}
+		}
+	}
+	return nil
+}
+
+// inspectRaw проверяет строку до декодирования URL
+func (f *Filter) inspectRaw(location, raw string) *Match {
+	if f.rules[RulePathTraversal] && overlongDot.MatchString(raw) {
+		return &Match{Rule: RulePathTraversal, Location: location}
+	}
+	return nil
+}
+
+// inspectString проверяет строку шаблонами включенных правил. Значение дополнительно
+// декодируется, чтобы двойное URL кодирование не скрывало шаблон
+func (f *Filter) inspectString(location, value string) *Match {
+	if value == "" {
+		return nil
+	}
+	candidates := []string{strings.ToLower(value)}
+	if strings.Contains(value, "%") {
+		if decoded, err := url.PathUnescape(value); err == nil && decoded != value {
+			candidates = append(candidates, strings.ToLower(decoded))
+		}
+	}
+
+	for _, rule := range AllRules {
+		if !f.rules[rule] {
+			continue
+		}
+		for _, pattern := range patterns[rule] {
+			for _, candidate := range candidates {
+				if pattern.MatchString(candidate) {
+					return &Match{Rule: rule, Location: location}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// headerSize суммарный размер имен и значений заголовков запроса
+func headerSize(r *http.Request) int {
+	size := len(r.Host)
+	for name, values := range r.Header {
+		for _, value := range values {
+			size += len(name) + len(value)
+		}
+	}
+	return size
+}
+
+// readCloser тело запроса с уже прочитанной проверенной частью
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
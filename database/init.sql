@@ -1,6 +1,24 @@
 -- Инициализация базы данных для системы управления задачами
 -- Создание расширений
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Часовой пояс сессий по умолчанию — UTC, в том числе для psql и скриптов
+DO $$
+BEGIN
+    EXECUTE format('ALTER DATABASE %I SET timezone TO ''UTC''', current_database());
+END
+$$;
+
+-- Версия схемы: номера примененных миграций database/migrations. Каждая миграция
+-- добавляет свой номер, а init.sql — номер последней миграции, изменения которой
+-- он уже содержит. Версию проверяет cmd/doctor
+CREATE TABLE schema_version (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_version (version) VALUES (34);
 
 -- Создание таблицы пользователей
 CREATE TABLE users (
@@ -9,24 +27,127 @@ CREATE TABLE users (
     password_hash VARCHAR(255) NOT NULL,
     name VARCHAR(255) NOT NULL,
     roles TEXT[] DEFAULT ARRAY['user'],
+    roles_epoch BIGINT NOT NULL DEFAULT 0,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    directory_source VARCHAR(64),
+    external_id VARCHAR(255),
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    deactivated_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    -- Слова email и имени для поиска GET /v1/users?q=; email разбивается по знакам препинания
+    search_vector tsvector GENERATED ALWAYS AS (
+        to_tsvector('simple', regexp_replace(email, '[^[:alnum:]]+', ' ', 'g') || ' ' || name)
+    ) STORED
 );
 
 -- Создание индексов для таблицы пользователей
 CREATE INDEX idx_users_email ON users(email);
 CREATE INDEX idx_users_roles ON users USING GIN(roles);
+CREATE UNIQUE INDEX idx_users_directory ON users(directory_source, external_id) WHERE external_id IS NOT NULL;
+CREATE INDEX idx_users_active_created_at ON users(created_at DESC) WHERE deactivated_at IS NULL;
+CREATE INDEX idx_users_search_vector ON users USING GIN(search_vector);
+CREATE INDEX idx_users_email_trgm ON users USING GIN(email gin_trgm_ops);
+CREATE INDEX idx_users_name_trgm ON users USING GIN(name gin_trgm_ops);
+
+-- Создание справочника ролей. builtin — встроенная роль, которая не удаляется;
+-- permissions_locked — права роли не изменяются (admin всегда имеет все права)
+CREATE TABLE roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    builtin BOOLEAN NOT NULL DEFAULT FALSE,
+    permissions_locked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
 
--- Создание типа для статуса заказа
-CREATE TYPE order_status AS ENUM ('создан', 'в работе', 'выполнен', 'отменён');
+-- Создание справочника прав; права проверяются обработчиками сервисов (pkg/rbac)
+CREATE TABLE permissions (
+    name VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+-- Создание таблицы прав ролей
+CREATE TABLE role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description, builtin, permissions_locked) VALUES
+('user', 'Пользователь: работа со своим профилем и заказами', TRUE, FALSE),
+('admin', 'Администратор: все права', TRUE, TRUE),
+('support', 'Поддержка: просмотр заказов и пользователей с маскированием персональных данных', FALSE, FALSE);
+
+INSERT INTO permissions (name, description) VALUES
+('users.read', 'Просмотр профилей, списка и истории входов других пользователей'),
+('users.manage', 'Деактивация, разблокировка и удаление учетных записей'),
+('roles.assign', 'Назначение ролей пользователям'),
+('roles.manage', 'Создание ролей и изменение их прав'),
+('access_review.read', 'Отчет о пересмотре доступа и его выгрузки'),
+('directory.sync', 'Синхронизация с корпоративным каталогом'),
+('email_domains.manage', 'Запрещенные и одноразовые домены email'),
+('blacklist.manage', 'Черный список покупателей'),
+('orders.read_all', 'Просмотр заказов всех пользователей, тегов, наборов фильтров, выгрузок и сборочных листов'),
+('orders.manage', 'Изменение чужих заказов, теги, очередь работ, проверка помеченных заказов, названия статусов'),
+('deliveries.manage', 'Исходящие доставки уведомлений'),
+('broadcasts.manage', 'Рассылки объявлений'),
+('events.manage', 'Обработчики событий и их ошибки'),
+('gateway.manage', 'Административный API шлюза'),
+('support.view', 'Просмотр заказов и пользователей для поддержки с маскированием персональных данных'),
+('pii.reveal', 'Просмотр для поддержки без маскирования персональных данных (с записью в журнал аудита)');
+
+INSERT INTO role_permissions (role, permission)
+SELECT 'admin', name FROM permissions;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('support', 'support.view');
+
+-- Создание справочника статусов заказа (машинные коды)
+CREATE TABLE order_statuses (
+    code VARCHAR(32) PRIMARY KEY,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    is_final BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO order_statuses (code, sort_order, is_final) VALUES
+('flagged', 5, FALSE),
+('created', 10, FALSE),
+('in_work', 20, FALSE),
+('completed', 30, TRUE),
+('cancelled', 40, TRUE);
+
+-- Создание таблицы локализованных названий статусов
+CREATE TABLE order_status_translations (
+    status_code VARCHAR(32) NOT NULL REFERENCES order_statuses(code) ON DELETE CASCADE,
+    locale VARCHAR(8) NOT NULL,
+    display_name VARCHAR(64) NOT NULL,
+    PRIMARY KEY (status_code, locale)
+);
+
+INSERT INTO order_status_translations (status_code, locale, display_name) VALUES
+('created', 'ru', 'создан'),
+('in_work', 'ru', 'в работе'),
+('completed', 'ru', 'выполнен'),
+('cancelled', 'ru', 'отменён'),
+('flagged', 'ru', 'на проверке'),
+('created', 'en', 'created'),
+('in_work', 'en', 'in progress'),
+('completed', 'en', 'completed'),
+('cancelled', 'en', 'cancelled'),
+('flagged', 'en', 'under review');
 
 -- Создание таблицы заказов
 CREATE TABLE orders (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     items JSONB NOT NULL,
-    status order_status DEFAULT 'создан',
+    status VARCHAR(32) NOT NULL DEFAULT 'created' REFERENCES order_statuses(code),
     total_sum DECIMAL(10,2) NOT NULL DEFAULT 0.00,
+    assigned_to UUID REFERENCES users(id) ON DELETE SET NULL,
+    assigned_at TIMESTAMP WITH TIME ZONE,
+    -- region регион доставки для сборочных листов склада; NULL — не задан
+    region VARCHAR(100),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
@@ -35,6 +156,350 @@ CREATE TABLE orders (
 CREATE INDEX idx_orders_user_id ON orders(user_id);
 CREATE INDEX idx_orders_status ON orders(status);
 CREATE INDEX idx_orders_created_at ON orders(created_at);
+CREATE INDEX idx_orders_status_region ON orders(status, region);
+CREATE INDEX idx_orders_work_queue ON orders(created_at, id) WHERE status = 'created' AND assigned_to IS NULL;
+
+-- Создание таблицы истории состояний заказов (заполняется триггером record_order_history)
+CREATE TABLE order_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    status VARCHAR(32) NOT NULL,
+    items JSONB NOT NULL,
+    total_sum DECIMAL(10,2) NOT NULL,
+    assigned_to UUID,
+    assigned_at TIMESTAMP WITH TIME ZONE,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_history_order_id ON order_history(order_id, recorded_at, id);
+
+-- Создание таблицы позиций заказов (нормализованная копия orders.items для отчетов и статусов позиций)
+CREATE TABLE order_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    product VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (order_id, position)
+);
+
+CREATE INDEX idx_order_items_product ON order_items(product);
+CREATE INDEX idx_order_items_status ON order_items(status);
+
+-- Создание таблицы складских остатков (обновляется webhook складских систем)
+CREATE TABLE stock_levels (
+    product VARCHAR(255) PRIMARY KEY,
+    available INTEGER NOT NULL CHECK (available >= 0),
+    warehouse VARCHAR(100) NOT NULL DEFAULT '',
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Создание таблиц состояния обработчиков доменных событий и их последних ошибок
+CREATE TABLE event_handler_states (
+    name VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    reason TEXT NOT NULL DEFAULT '',
+    updated_by UUID,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE event_handler_errors (
+    id BIGSERIAL PRIMARY KEY,
+    handler VARCHAR(100) NOT NULL,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    error TEXT NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_event_handler_errors_handler ON event_handler_errors(handler, occurred_at DESC);
+
+-- Создание таблицы настроек уведомлений пользователей
+CREATE TABLE notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    email_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    sms_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    telegram_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    order_created BOOLEAN NOT NULL DEFAULT TRUE,
+    order_status_changed BOOLEAN NOT NULL DEFAULT TRUE,
+    marketing BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Создание таблицы запрещенных доменов email (регистрация и смена email)
+CREATE TABLE banned_email_domains (
+    domain VARCHAR(253) PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Создание таблицы попыток входа (для детектора аномалий service_orders и истории входов).
+-- Попытки с неизвестным email хранятся без user_id
+CREATE TABLE login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    method VARCHAR(16) NOT NULL DEFAULT 'password',
+    provider VARCHAR(32) NOT NULL DEFAULT '',
+    success BOOLEAN NOT NULL,
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    attempted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_login_attempts_attempted_at ON login_attempts(attempted_at);
+CREATE INDEX idx_login_attempts_user_id ON login_attempts(user_id, attempted_at DESC) WHERE user_id IS NOT NULL;
+
+-- Создание таблицы refresh токенов (хранятся только хеши)
+-- session_id общий для токена, выданного при входе, и всех его ротаций
+CREATE TABLE refresh_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id UUID NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    replaced_by UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+
+-- Создание таблицы одноразовых токенов сброса пароля (хранятся только хеши)
+CREATE TABLE password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+
+-- Создание таблицы токенов подтверждения смены email (хранятся только хеши): новый адрес
+-- вступает в силу после перехода по ссылке из письма на него
+CREATE TABLE email_change_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_email VARCHAR(255) NOT NULL,
+    new_email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_change_tokens_user_id ON email_change_tokens(user_id);
+
+-- Создание таблицы персональных API ключей (хранятся только хеши)
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+
+-- Создание таблицы исходящих доставок (неудачные уведомления и webhook для повторной отправки)
+CREATE TABLE deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('notification', 'webhook')),
+    channel VARCHAR(50) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    target TEXT NOT NULL DEFAULT '',
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'processing', 'failed', 'delivered', 'discarded')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_deliveries_status_created_at ON deliveries(status, created_at);
+CREATE INDEX idx_deliveries_user_id ON deliveries(user_id);
+
+-- Создание таблицы операций удаления данных пользователей (строка users обезличивается, заказы сохраняются)
+CREATE TABLE user_deletions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    summary JSONB,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_user_deletions_active ON user_deletions(user_id) WHERE status IN ('pending', 'running');
+CREATE INDEX idx_user_deletions_status_created_at ON user_deletions(status, created_at);
+
+-- Создание таблицы токенов подтверждения удаления своих данных (хранится хеш последнего токена)
+CREATE TABLE user_deletion_confirmations (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Создание таблицы времени последнего входа (отдельно от users, чтобы вход не изменял updated_at)
+CREATE TABLE user_logins (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    last_login_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Создание журнала аудита действий администраторов (отчет о пересмотре доступа)
+CREATE TABLE admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    action VARCHAR(100) NOT NULL,
+    target VARCHAR(255) NOT NULL DEFAULT '',
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_admin_audit_log_actor_created_at ON admin_audit_log(actor_id, created_at);
+
+-- Создание таблицы учетных записей внешних провайдеров OAuth2, связанных с пользователями
+CREATE TABLE user_identities (
+    provider VARCHAR(32) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE UNIQUE INDEX idx_user_identities_user_provider ON user_identities(user_id, provider);
+
+-- Создание таблицы счетчиков неудачных входов и блокировок входа (учетная запись или IP адрес)
+CREATE TABLE login_lockouts (
+    scope VARCHAR(16) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    lock_count INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_failure_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (scope, subject)
+);
+
+CREATE INDEX idx_login_lockouts_last_failure_at ON login_lockouts(last_failure_at);
+
+-- Создание таблицы тегов заказов
+CREATE TABLE order_tags (
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    tag VARCHAR(32) NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (order_id, tag)
+);
+
+CREATE INDEX idx_order_tags_tag ON order_tags(tag);
+
+-- Создание таблицы сохраненных наборов фильтров списка заказов администраторов
+CREATE TABLE order_filter_presets (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+-- Создание таблицы сформированных выгрузок для повторного скачивания до истечения срока хранения
+CREATE TABLE export_artifacts (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    content BYTEA NOT NULL,
+    size BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_export_artifacts_expires_at ON export_artifacts(expires_at);
+
+-- Создание журнала решений проверок заказа перед сохранением (антифрод, лимиты, черные
+-- списки); order_id без внешнего ключа: отклоненные заказы не сохраняются в orders
+CREATE TABLE order_hook_decisions (
+    id BIGSERIAL PRIMARY KEY,
+    order_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hook VARCHAR(64) NOT NULL,
+    verdict VARCHAR(16) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_hook_decisions_order_id ON order_hook_decisions(order_id);
+CREATE INDEX idx_order_hook_decisions_verdict ON order_hook_decisions(verdict, id);
+
+-- Создание черного списка покупателей: пользователи, домены email и IP адреса (или сети
+-- CIDR). block запрещает вход и создание заказов, flag отправляет заказы на проверку
+CREATE TABLE blacklist_entries (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('user', 'email_domain', 'ip')),
+    value VARCHAR(253) NOT NULL,
+    action VARCHAR(16) NOT NULL DEFAULT 'block' CHECK (action IN ('block', 'flag')),
+    reason TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (kind, value)
+);
+
+-- Создание таблицы рассылок объявлений сегментам пользователей (BROADCAST_*)
+CREATE TABLE broadcasts (
+    id UUID PRIMARY KEY,
+    message TEXT NOT NULL,
+    segment JSONB NOT NULL DEFAULT '{}',
+    marketing BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'completed', 'cancelled')),
+    total INTEGER NOT NULL DEFAULT 0,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    cancelled_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_broadcasts_active ON broadcasts(created_at) WHERE status IN ('pending', 'running');
+
+-- Создание таблицы получателей рассылок: список фиксируется при создании рассылки
+CREATE TABLE broadcast_recipients (
+    broadcast_id UUID NOT NULL REFERENCES broadcasts(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'sent', 'skipped', 'failed', 'cancelled')),
+    error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (broadcast_id, user_id)
+);
+
+CREATE INDEX idx_broadcast_recipients_status ON broadcast_recipients(broadcast_id, status);
 
 -- Создание функции для автоматического обновления updated_at
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -52,6 +517,27 @@ CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users
 CREATE TRIGGER update_orders_updated_at BEFORE UPDATE ON orders
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+-- Создание триггера истории состояний заказов: снимок при создании и при изменении
+-- статуса, позиций, суммы или назначения
+CREATE OR REPLACE FUNCTION record_order_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+       AND NEW.status IS NOT DISTINCT FROM OLD.status
+       AND NEW.items IS NOT DISTINCT FROM OLD.items
+       AND NEW.total_sum IS NOT DISTINCT FROM OLD.total_sum
+       AND NEW.assigned_to IS NOT DISTINCT FROM OLD.assigned_to THEN
+        RETURN NEW;
+    END IF;
+    INSERT INTO order_history (order_id, status, items, total_sum, assigned_to, assigned_at, recorded_at)
+    VALUES (NEW.id, NEW.status, NEW.items, NEW.total_sum, NEW.assigned_to, NEW.assigned_at, COALESCE(NEW.updated_at, NOW()));
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER record_order_history AFTER INSERT OR UPDATE ON orders
+    FOR EACH ROW EXECUTE FUNCTION record_order_history();
+
 -- Вставка тестового администратора
 -- Пароль: admin123 (хеш bcrypt)
 INSERT INTO users (email, password_hash, name, roles) VALUES 
@@ -0,0 +1,54 @@
+-- Миграция статусов заказов с русских строк (ENUM order_status) на машинные коды
+-- со справочником и локализованными названиями.
+-- Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS order_statuses (
+    code VARCHAR(32) PRIMARY KEY,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    is_final BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO order_statuses (code, sort_order, is_final) VALUES
+('created', 10, FALSE),
+('in_work', 20, FALSE),
+('completed', 30, TRUE),
+('cancelled', 40, TRUE)
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS order_status_translations (
+    status_code VARCHAR(32) NOT NULL REFERENCES order_statuses(code) ON DELETE CASCADE,
+    locale VARCHAR(8) NOT NULL,
+    display_name VARCHAR(64) NOT NULL,
+    PRIMARY KEY (status_code, locale)
+);
+
+INSERT INTO order_status_translations (status_code, locale, display_name) VALUES
+('created', 'ru', 'создан'),
+('in_work', 'ru', 'в работе'),
+('completed', 'ru', 'выполнен'),
+('cancelled', 'ru', 'отменён'),
+('created', 'en', 'created'),
+('in_work', 'en', 'in progress'),
+('completed', 'en', 'completed'),
+('cancelled', 'en', 'cancelled')
+ON CONFLICT (status_code, locale) DO NOTHING;
+
+-- Перевод колонки orders.status с ENUM на машинные коды
+ALTER TABLE orders ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE orders ALTER COLUMN status TYPE VARCHAR(32) USING (
+    CASE status::text
+        WHEN 'создан' THEN 'created'
+        WHEN 'в работе' THEN 'in_work'
+        WHEN 'выполнен' THEN 'completed'
+        WHEN 'отменён' THEN 'cancelled'
+        ELSE status::text
+    END
+);
+ALTER TABLE orders ALTER COLUMN status SET DEFAULT 'created';
+ALTER TABLE orders ALTER COLUMN status SET NOT NULL;
+ALTER TABLE orders ADD CONSTRAINT orders_status_fkey FOREIGN KEY (status) REFERENCES order_statuses(code);
+
+DROP TYPE IF EXISTS order_status;
+
+COMMIT;
@@ -0,0 +1,23 @@
+-- Таблица исходящих доставок для повторной отправки неудачных уведомлений и webhook.
+-- Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('notification', 'webhook')),
+    channel VARCHAR(50) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    target TEXT NOT NULL DEFAULT '',
+    payload TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'processing', 'failed', 'delivered', 'discarded')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_deliveries_status_created_at ON deliveries(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_deliveries_user_id ON deliveries(user_id);
+
+COMMIT;
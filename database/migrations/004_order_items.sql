@@ -0,0 +1,22 @@
+-- Таблица позиций заказов. Применяется к базам, созданным предыдущей версией init.sql.
+-- После применения перенесите позиции существующих заказов:
+--   cd service_orders && go run ./cmd/backfill_order_items
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS order_items (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    product VARCHAR(255) NOT NULL,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (order_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_product ON order_items(product);
+CREATE INDEX IF NOT EXISTS idx_order_items_status ON order_items(status);
+
+COMMIT;
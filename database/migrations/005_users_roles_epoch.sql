@@ -0,0 +1,10 @@
+-- Эпоха ролей пользователя: увеличивается при изменении ролей, чтобы API Gateway
+-- отклонял access токены, выданные до изменения.
+-- Пустой список ролей означает блокировку, поэтому NULL заменяется ролью по умолчанию.
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS roles_epoch BIGINT NOT NULL DEFAULT 0;
+
+UPDATE users SET roles = ARRAY['user'] WHERE roles IS NULL;
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- Таблица складских остатков, обновляемая webhook складских систем.
+-- Применяется к базам, созданным предыдущей версией init.sql.
+-- Товары без записи в таблице не ограничиваются при создании заказа.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS stock_levels (
+    product VARCHAR(255) PRIMARY KEY,
+    available INTEGER NOT NULL CHECK (available >= 0),
+    warehouse VARCHAR(100) NOT NULL DEFAULT '',
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- Состояние обработчиков доменных событий, отключенных администратором,
+-- и последние ошибки обработчиков. Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS event_handler_states (
+    name VARCHAR(100) PRIMARY KEY,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    reason TEXT NOT NULL DEFAULT '',
+    updated_by UUID,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS event_handler_errors (
+    id BIGSERIAL PRIMARY KEY,
+    handler VARCHAR(100) NOT NULL,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    error TEXT NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_handler_errors_handler ON event_handler_errors(handler, occurred_at DESC);
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- Часовой пояс пользователя (имя IANA). Применяется только при представлении
+-- дат в выгрузках и счетах; хранение и ответы API остаются в UTC.
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- Часовой пояс сессий по умолчанию — UTC, в том числе для psql и скриптов
+DO $$
+BEGIN
+    EXECUTE format('ALTER DATABASE %I SET timezone TO ''UTC''', current_database());
+END
+$$;
+
+COMMIT;
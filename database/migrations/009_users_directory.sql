@@ -0,0 +1,11 @@
+-- Связь пользователей с учетными записями внешнего каталога (SCIM/LDAP)
+-- для идемпотентной синхронизации пользователей и ролей.
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS directory_source VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_directory ON users(directory_source, external_id)
+    WHERE external_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,12 @@
+-- Домены email, с которых запрещены регистрация и смена email.
+-- Список ведут администраторы через /v1/admin/email-domains.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS banned_email_domains (
+    domain VARCHAR(253) PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Итоги попыток входа для расчета доли неудачных входов детектором аномалий
+-- service_orders. Хранятся только время и результат, без email и адреса клиента.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    success BOOLEAN NOT NULL,
+    attempted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_attempted_at ON login_attempts(attempted_at);
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- Назначение заказов операторам очереди работ (POST /v1/admin/orders/claim).
+-- Частичный индекс покрывает выборку самого старого неназначенного заказа.
+BEGIN;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS assigned_to UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_work_queue ON orders(created_at, id)
+    WHERE status = 'created' AND assigned_to IS NULL;
+
+COMMIT;
@@ -0,0 +1,26 @@
+-- Удаление данных пользователя по его запросу или запросу администратора
+-- (DELETE /v1/users/me, DELETE /v1/admin/users/{id}). Строка users сохраняется обезличенной,
+-- чтобы заказы пользователя остались в отчетности; операция выполняется асинхронно.
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS user_deletions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    requested_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    summary JSONB,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_deletions_active ON user_deletions(user_id)
+    WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_user_deletions_status_created_at ON user_deletions(status, created_at);
+
+COMMIT;
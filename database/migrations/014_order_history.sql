@@ -0,0 +1,45 @@
+-- История состояний заказов для запросов на момент времени (GET /v1/orders/{id}?as_of=...).
+-- Снимок сохраняется триггером при создании заказа и каждом изменении статуса, позиций,
+-- суммы или назначения, поэтому его не может пропустить ни один путь изменения заказа.
+-- Для существующих заказов история начинается с текущего состояния на момент updated_at.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS order_history (
+    id BIGSERIAL PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    status VARCHAR(32) NOT NULL,
+    items JSONB NOT NULL,
+    total_sum DECIMAL(10,2) NOT NULL,
+    assigned_to UUID,
+    assigned_at TIMESTAMP WITH TIME ZONE,
+    recorded_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_history_order_id ON order_history(order_id, recorded_at, id);
+
+CREATE OR REPLACE FUNCTION record_order_history()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+       AND NEW.status IS NOT DISTINCT FROM OLD.status
+       AND NEW.items IS NOT DISTINCT FROM OLD.items
+       AND NEW.total_sum IS NOT DISTINCT FROM OLD.total_sum
+       AND NEW.assigned_to IS NOT DISTINCT FROM OLD.assigned_to THEN
+        RETURN NEW;
+    END IF;
+    INSERT INTO order_history (order_id, status, items, total_sum, assigned_to, assigned_at, recorded_at)
+    VALUES (NEW.id, NEW.status, NEW.items, NEW.total_sum, NEW.assigned_to, NEW.assigned_at, COALESCE(NEW.updated_at, NOW()));
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER IF EXISTS record_order_history ON orders;
+CREATE TRIGGER record_order_history AFTER INSERT OR UPDATE ON orders
+    FOR EACH ROW EXECUTE FUNCTION record_order_history();
+
+INSERT INTO order_history (order_id, status, items, total_sum, assigned_to, assigned_at, recorded_at)
+SELECT o.id, o.status, o.items, o.total_sum, o.assigned_to, o.assigned_at, COALESCE(o.updated_at, NOW())
+FROM orders o
+WHERE NOT EXISTS (SELECT 1 FROM order_history h WHERE h.order_id = o.id);
+
+COMMIT;
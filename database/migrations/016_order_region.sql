@@ -0,0 +1,9 @@
+-- Регион доставки заказа для сборочных листов склада (GET /v1/admin/orders/picking-list).
+-- У существующих заказов регион не задан. Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS region VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_orders_status_region ON orders(status, region);
+
+COMMIT;
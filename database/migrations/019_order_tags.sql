@@ -0,0 +1,25 @@
+-- Теги заказов (PUT /v1/admin/orders/{id}/tags) и сохраненные наборы фильтров списка
+-- заказов администраторов (/v1/admin/order-filters). Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS order_tags (
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    tag VARCHAR(32) NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (order_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_tags_tag ON order_tags(tag);
+
+CREATE TABLE IF NOT EXISTS order_filter_presets (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    filter JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, name)
+);
+
+COMMIT;
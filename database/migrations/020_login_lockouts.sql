@@ -0,0 +1,17 @@
+-- Счетчики неудачных входов и временные блокировки входа учетных записей и IP адресов
+-- (LOGIN_LOCKOUT_*). Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS login_lockouts (
+    scope VARCHAR(16) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    lock_count INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_failure_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (scope, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_lockouts_last_failure_at ON login_lockouts(last_failure_at);
+
+COMMIT;
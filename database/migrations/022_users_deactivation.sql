@@ -0,0 +1,11 @@
+-- Деактивация учетных записей администратором (POST /v1/admin/users/{id}/deactivate).
+-- Деактивированный пользователь не может войти и не попадает в список пользователей
+-- по умолчанию, но строка users сохраняется для целостности связанных заказов.
+BEGIN;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_users_active_created_at ON users(created_at DESC)
+    WHERE deactivated_at IS NULL;
+
+COMMIT;
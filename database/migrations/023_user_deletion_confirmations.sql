@@ -0,0 +1,13 @@
+-- Подтверждение удаления своих данных (DELETE /v1/users/me): первый запрос выдает
+-- одноразовый токен, повторный запрос с токеном создает операцию удаления.
+-- Хранится только хеш последнего токена пользователя.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_deletion_confirmations (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,20 @@
+-- Журнал решений проверок заказа перед сохранением (ORDER_HOOKS_*, ORDER_HOOK_*).
+-- order_id без внешнего ключа: решения отклоненных заказов тоже сохраняются.
+-- Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS order_hook_decisions (
+    id BIGSERIAL PRIMARY KEY,
+    order_id UUID NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hook VARCHAR(64) NOT NULL,
+    verdict VARCHAR(16) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_hook_decisions_order_id ON order_hook_decisions(order_id);
+CREATE INDEX IF NOT EXISTS idx_order_hook_decisions_verdict ON order_hook_decisions(verdict, id);
+
+COMMIT;
@@ -0,0 +1,26 @@
+-- Черный список покупателей (пользователи, домены email, IP адреса и сети) и статус
+-- заказа flagged: заказ ждет решения администратора и не попадает в очередь работ.
+-- Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS blacklist_entries (
+    id UUID PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('user', 'email_domain', 'ip')),
+    value VARCHAR(253) NOT NULL,
+    action VARCHAR(16) NOT NULL DEFAULT 'block' CHECK (action IN ('block', 'flag')),
+    reason TEXT NOT NULL DEFAULT '',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (kind, value)
+);
+
+INSERT INTO order_statuses (code, sort_order, is_final) VALUES
+('flagged', 5, FALSE)
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO order_status_translations (status_code, locale, display_name) VALUES
+('flagged', 'ru', 'на проверке'),
+('flagged', 'en', 'under review')
+ON CONFLICT (status_code, locale) DO NOTHING;
+
+COMMIT;
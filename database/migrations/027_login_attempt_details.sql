@@ -0,0 +1,16 @@
+-- Подробности попыток входа для истории входов пользователей (GET /v1/users/profile/logins):
+-- пользователь, способ входа, адрес и User-Agent клиента. Ранее сохраненные попытки
+-- остаются без пользователя. Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+ALTER TABLE login_attempts
+    ADD COLUMN IF NOT EXISTS user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    ADD COLUMN IF NOT EXISTS method VARCHAR(16) NOT NULL DEFAULT 'password',
+    ADD COLUMN IF NOT EXISTS provider VARCHAR(32) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS ip_address VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_user_id
+    ON login_attempts(user_id, attempted_at DESC) WHERE user_id IS NOT NULL;
+
+COMMIT;
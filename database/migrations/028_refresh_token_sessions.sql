@@ -0,0 +1,33 @@
+-- Сессии пользователей (/v1/users/me/sessions): session_id объединяет refresh токен,
+-- выданный при входе, и его ротации; User-Agent и адрес клиента последней ротации.
+-- Сессии существующих токенов восстанавливаются по цепочкам replaced_by.
+-- Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+ALTER TABLE refresh_tokens
+    ADD COLUMN IF NOT EXISTS session_id UUID,
+    ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS ip_address VARCHAR(64) NOT NULL DEFAULT '';
+
+WITH RECURSIVE chains AS (
+    SELECT t.id, t.id AS session_id, t.replaced_by
+    FROM refresh_tokens t
+    WHERE NOT EXISTS (SELECT 1 FROM refresh_tokens p WHERE p.replaced_by = t.id)
+    UNION ALL
+    SELECT t.id, c.session_id, t.replaced_by
+    FROM refresh_tokens t
+    JOIN chains c ON c.replaced_by = t.id
+)
+UPDATE refresh_tokens t
+SET session_id = c.session_id
+FROM chains c
+WHERE t.id = c.id AND t.session_id IS NULL;
+
+-- Токены, не попавшие ни в одну цепочку, становятся отдельными сессиями
+UPDATE refresh_tokens SET session_id = id WHERE session_id IS NULL;
+
+ALTER TABLE refresh_tokens ALTER COLUMN session_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+
+COMMIT;
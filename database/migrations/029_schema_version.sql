@@ -0,0 +1,13 @@
+-- Версия схемы для проверки окружения (cmd/doctor). Начиная с этой миграции каждая
+-- миграция добавляет свой номер в schema_version последним оператором перед COMMIT.
+-- Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS schema_version (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_version (version) VALUES (29) ON CONFLICT DO NOTHING;
+
+COMMIT;
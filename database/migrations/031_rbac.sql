@@ -0,0 +1,57 @@
+-- Права доступа ролей: справочники ролей и прав и права встроенных ролей.
+-- Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+-- Создание справочника ролей. builtin — встроенная роль, которая не удаляется;
+-- permissions_locked — права роли не изменяются (admin всегда имеет все права)
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    builtin BOOLEAN NOT NULL DEFAULT FALSE,
+    permissions_locked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Создание справочника прав; права проверяются обработчиками сервисов (pkg/rbac)
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL
+);
+
+-- Создание таблицы прав ролей
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(50) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description, builtin, permissions_locked) VALUES
+('user', 'Пользователь: работа со своим профилем и заказами', TRUE, FALSE),
+('admin', 'Администратор: все права', TRUE, TRUE)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+('users.read', 'Просмотр профилей, списка и истории входов других пользователей'),
+('users.manage', 'Деактивация, разблокировка и удаление учетных записей'),
+('roles.assign', 'Назначение ролей пользователям'),
+('roles.manage', 'Создание ролей и изменение их прав'),
+('access_review.read', 'Отчет о пересмотре доступа и его выгрузки'),
+('directory.sync', 'Синхронизация с корпоративным каталогом'),
+('email_domains.manage', 'Запрещенные и одноразовые домены email'),
+('blacklist.manage', 'Черный список покупателей'),
+('orders.read_all', 'Просмотр заказов всех пользователей, тегов, наборов фильтров, выгрузок и сборочных листов'),
+('orders.manage', 'Изменение чужих заказов, теги, очередь работ, проверка помеченных заказов, названия статусов'),
+('deliveries.manage', 'Исходящие доставки уведомлений'),
+('broadcasts.manage', 'Рассылки объявлений'),
+('events.manage', 'Обработчики событий и их ошибки'),
+('gateway.manage', 'Административный API шлюза')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission)
+SELECT 'admin', name FROM permissions
+ON CONFLICT DO NOTHING;
+
+INSERT INTO schema_version (version) VALUES (31) ON CONFLICT DO NOTHING;
+
+COMMIT;
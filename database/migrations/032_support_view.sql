@@ -0,0 +1,26 @@
+-- Просмотр заказов и пользователей для поддержки: права support.view и pii.reveal
+-- и роль support с маскированным просмотром.
+-- Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+INSERT INTO permissions (name, description) VALUES
+('support.view', 'Просмотр заказов и пользователей для поддержки с маскированием персональных данных'),
+('pii.reveal', 'Просмотр для поддержки без маскирования персональных данных (с записью в журнал аудита)')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('admin', 'support.view'),
+('admin', 'pii.reveal')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO roles (name, description) VALUES
+('support', 'Поддержка: просмотр заказов и пользователей с маскированием персональных данных')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+('support', 'support.view')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO schema_version (version) VALUES (32) ON CONFLICT DO NOTHING;
+
+COMMIT;
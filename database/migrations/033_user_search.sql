@@ -0,0 +1,21 @@
+-- Полнотекстовый поиск пользователей для администраторов (GET /v1/users?q=):
+-- tsvector по email и имени для поиска по префиксам слов и триграммные индексы для
+-- поиска с опечатками и фильтров email/name (ILIKE).
+-- Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Email разбивается на слова по знакам препинания: ivan.petrov@example.com → ivan petrov example com
+ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        to_tsvector('simple', regexp_replace(email, '[^[:alnum:]]+', ' ', 'g') || ' ' || name)
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN(search_vector);
+CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING GIN(email gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_name_trgm ON users USING GIN(name gin_trgm_ops);
+
+INSERT INTO schema_version (version) VALUES (33) ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,21 @@
+-- Подтверждение смены email (PUT/PATCH /v1/users/profile и POST /v1/users/profile/email/confirm):
+-- новый адрес вступает в силу только после перехода по ссылке, отправленной на него.
+-- Хранятся только хеши токенов. Применяется к базам, созданным предыдущей версией init.sql.
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS email_change_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_email VARCHAR(255) NOT NULL,
+    new_email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_change_tokens_user_id ON email_change_tokens(user_id);
+
+INSERT INTO schema_version (version) VALUES (34) ON CONFLICT DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,48 @@
+// Package buildinfo содержит версию сборки сервиса. Версия задается при сборке:
+//
+//	go build -ldflags "-X pkg/buildinfo.Version=1.4.2" .
+//
+// API Gateway и сервисы отдают ее на GET /version, а cmd/doctor сравнивает версии
+// компонентов развертывания
+package buildinfo
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"pkg/httpresp"
+)
+
+// Version версия сборки; "dev", если не задана при сборке
+var Version = "dev"
+
+// Info сведения о сборке компонента (ответ GET /version)
+type Info struct {
+	Service string `json:"service"`
+	Version string `json:"version"`
+	// Revision коммит VCS, из которого собран бинарный файл; пусто, если неизвестен
+	Revision  string `json:"revision,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get возвращает сведения о сборке компонента service
+func Get(service string) Info {
+	info := Info{Service: service, Version: Version, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Revision = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+// Handler отдает сведения о сборке компонента service в JSON
+func Handler(service string) http.HandlerFunc {
+	info := Get(service)
+	return func(w http.ResponseWriter, r *http.Request) {
+		httpresp.JSON(w, http.StatusOK, info)
+	}
+}
@@ -0,0 +1,355 @@
+// Package clients содержит общую основу типизированных клиентов внутренних сервисов
+// (pkg/clients/users, pkg/clients/orders): сериализацию запросов, разбор ответов
+// в формате APIResponse, передачу пользовательского контекста, повторы и трассировку.
+// Клиент работает как по сети (BaseURL сервиса), так и через http.Handler в том же
+// процессе (HandlerDoer) — например, через прокси API Gateway с его breaker и таймаутами
+package clients
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxResponseSize ограничение размера ответа сервиса на один вызов
+	MaxResponseSize = 8 << 20
+	// DefaultTimeout таймаут HTTP клиента по умолчанию
+	DefaultTimeout = 10 * time.Second
+	// DefaultRetryBackoff пауза перед первым повтором по умолчанию; далее удваивается
+	DefaultRetryBackoff = 100 * time.Millisecond
+)
+
+// Заголовки трассировки, передаваемые из исходного запроса
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+	HeaderBaggage     = "baggage"
+)
+
+// userHeaders заголовки пользовательского контекста, заполняемые API Gateway
+// по проверенному токену
+var userHeaders = []string{"X-User-ID", "X-User-Email", "X-User-Roles", "X-User-Scopes", "X-User-Permissions"}
+
+// Doer выполняет HTTP запрос; реализуется *http.Client и HandlerDoer
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Auth добавляет к исходящему запросу req учетные данные; original — исходный
+// запрос из контекста (WithRequest) или nil
+type Auth func(req *http.Request, original *http.Request) error
+
+// Config настройки клиента сервиса
+type Config struct {
+	// BaseURL адрес сервиса (http://service_users:8081); пусто — пути передаются
+	// как есть, что нужно для HandlerDoer
+	BaseURL string
+	// HTTP выполняет запросы; по умолчанию http.Client с DefaultTimeout
+	HTTP Doer
+	// Auth учетные данные запросов; nil — запросы без учетных данных
+	Auth Auth
+	// Retries число повторов идемпотентных запросов (GET, HEAD, PUT, DELETE) при сетевых
+	// ошибках и ответах 502, 503, 504; 0 — без повторов
+	Retries int
+	// RetryBackoff пауза перед первым повтором; по умолчанию DefaultRetryBackoff
+	RetryBackoff time.Duration
+}
+
+// Client выполняет запросы к одному сервису
+type Client struct {
+	baseURL string
+	http    Doer
+	auth    Auth
+	retries int
+	backoff time.Duration
+}
+
+// New создает клиент сервиса
+func New(config Config) *Client {
+	if config.HTTP == nil {
+		config.HTTP = &http.Client{Timeout: DefaultTimeout}
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+	return &Client{
+		baseURL: strings.TrimRight(config.BaseURL, "/"),
+		http:    config.HTTP,
+		auth:    config.Auth,
+		retries: config.Retries,
+		backoff: config.RetryBackoff,
+	}
+}
+
+// Error ошибка, возвращенная сервисом: статус ответа и код и сообщение из error
+// ответа APIResponse. Для ответов не в формате сервисов (ошибки прокси шлюза)
+// Message — текст статуса
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// StatusOf возвращает статус ответа сервиса из ошибки err или 0, если ошибка
+// возникла не в сервисе (недоступность, некорректный ответ)
+func StatusOf(err error) int {
+	if apiErr, ok := err.(*Error); ok {
+		return apiErr.Status
+	}
+	return 0
+}
+
+// requestKey ключ контекста исходного запроса
+type requestKey struct{}
+
+// WithRequest сохраняет в контексте входящий запрос r, от имени которого выполняются
+// вызовы: из него берутся X-Request-ID, заголовки трассировки, язык и адрес клиента,
+// а Auth — пользовательский контекст
+func WithRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+// RequestFromContext возвращает запрос, сохраненный WithRequest, или nil
+func RequestFromContext(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(requestKey{}).(*http.Request)
+	return r
+}
+
+// BearerToken передает фиксированный токен в заголовке Authorization
+func BearerToken(token string) Auth {
+	return func(req *http.Request, _ *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+// ForwardUser передает заголовки пользовательского контекста X-User-* исходного
+// запроса: сервисы доверяют им так же, как при обращении через API Gateway
+func ForwardUser(req *http.Request, original *http.Request) error {
+	if original == nil {
+		return fmt.Errorf("отсутствует исходный запрос в контексте")
+	}
+	for _, name := range userHeaders {
+		if value := original.Header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	return nil
+}
+
+// ForwardRequest передает все заголовки исходного запроса, кроме описывающих тело.
+// Предназначен для HandlerDoer с прокси API Gateway, который сам применяет политику
+// заголовков upstream; для сетевых вызовов используется ForwardUser
+func ForwardRequest(req *http.Request, original *http.Request) error {
+	if original == nil {
+		return fmt.Errorf("отсутствует исходный запрос в контексте")
+	}
+	contentType := req.Header.Get("Content-Type")
+	req.Header = original.Header.Clone()
+	req.Header.Del("Content-Type")
+	req.Header.Del("Content-Length")
+	// Ответ разбирается клиентом, сжатие не нужно
+	req.Header.Del("Accept-Encoding")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return nil
+}
+
+// Do выполняет запрос method к path с параметрами query и телом body (сериализуется
+// в JSON, nil — без тела) и декодирует data ответа в out (nil — ответ не разбирается).
+// Ответ с ошибкой возвращается как *Error
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("ошибка сериализации запроса %s: %v", path, err)
+		}
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	attempts := 1
+	if idempotent(method) {
+		attempts += c.retries
+	}
+
+	var status int
+	var data []byte
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff<<(attempt-1)); err != nil {
+				return fmt.Errorf("запрос %s отменен: %v", path, err)
+			}
+		}
+		status, data, err = c.send(ctx, method, target, payload)
+		if !retryable(status, err) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка запроса %s: %v", path, err)
+	}
+
+	return decode(path, status, data, out)
+}
+
+// send выполняет одну попытку запроса и читает ответ
+func (c *Client) send(ctx context.Context, method, target string, payload []byte) (int, []byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	original := RequestFromContext(ctx)
+	if c.auth != nil {
+		if err := c.auth(req, original); err != nil {
+			return 0, nil, err
+		}
+	}
+	propagate(req, original)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxResponseSize+1))
+	if err != nil {
+		return 0, nil, fmt.Errorf("ошибка чтения ответа: %v", err)
+	}
+	if len(data) > MaxResponseSize {
+		return 0, nil, fmt.Errorf("ответ превышает %d байт", MaxResponseSize)
+	}
+	return resp.StatusCode, data, nil
+}
+
+// apiResponse формат ответа микросервисов
+type apiResponse struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// decode разбирает ответ сервиса в формате APIResponse
+func decode(path string, status int, data []byte, out interface{}) error {
+	if status == http.StatusNoContent {
+		return nil
+	}
+
+	var envelope apiResponse
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		// Ошибки прокси шлюза (таймаут, разомкнутый breaker) приходят не в формате сервисов
+		if status >= http.StatusBadRequest {
+			return &Error{Status: status, Message: http.StatusText(status)}
+		}
+		return fmt.Errorf("некорректный ответ %s (статус %d)", path, status)
+	}
+
+	if status >= http.StatusBadRequest || !envelope.Success {
+		if envelope.Error != nil {
+			return &Error{Status: status, Code: envelope.Error.Code, Message: envelope.Error.Message}
+		}
+		return &Error{Status: status, Message: http.StatusText(status)}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("ошибка разбора ответа %s: %v", path, err)
+	}
+	return nil
+}
+
+// propagate передает X-Request-ID, язык и трассу исходного запроса. traceparent
+// получает новый идентификатор родителя, чтобы вызов был отдельным звеном трассы
+func propagate(req *http.Request, original *http.Request) {
+	if original == nil {
+		return
+	}
+	for _, name := range []string{HeaderRequestID, HeaderTraceState, HeaderBaggage, "Accept-Language"} {
+		if value := original.Header.Get(name); value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+	if traceParent, ok := childTraceParent(original.Header.Get(HeaderTraceParent)); ok {
+		req.Header.Set(HeaderTraceParent, traceParent)
+	} else {
+		req.Header.Del(HeaderTraceParent)
+	}
+	req.RemoteAddr = original.RemoteAddr
+}
+
+// childTraceParent заменяет идентификатор родителя в traceparent версии 00
+// ("00-<32 hex>-<16 hex>-<2 hex>"); ok == false — заголовок отсутствует или другой версии
+func childTraceParent(header string) (string, bool) {
+	if len(header) != 55 || !strings.HasPrefix(header, "00-") || header[35] != '-' || header[52] != '-' {
+		return "", false
+	}
+	var parent [8]byte
+	if _, err := rand.Read(parent[:]); err != nil {
+		return "", false
+	}
+	parent[0] |= 1 // идентификатор из одних нулей недопустим
+	return header[:36] + hex.EncodeToString(parent[:]) + header[52:], true
+}
+
+// idempotent проверяет, что запрос можно безопасно повторить
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryable проверяет, что попытка завершилась временной ошибкой
+func retryable(status int, err error) bool {
+	if err != nil {
+		return true
+	}
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// sleep ожидает d или отмены ctx
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package clients
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HandlerDoer выполняет запросы обработчиком handler в том же процессе, без сети.
+// API Gateway передает свои прокси, поэтому вызовы проходят через те же транспорт,
+// балансировку и breaker, что и запросы клиентов
+func HandlerDoer(handler http.Handler) Doer {
+	return handlerDoer{handler: handler}
+}
+
+type handlerDoer struct {
+	handler http.Handler
+}
+
+func (d handlerDoer) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "" {
+		return nil, fmt.Errorf("не указан путь запроса")
+	}
+	if req.Body == nil {
+		req.Body = http.NoBody
+	}
+	req.RequestURI = req.URL.RequestURI()
+
+	recorder := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+	d.handler.ServeHTTP(recorder, req)
+	if recorder.overflow {
+		return nil, fmt.Errorf("ответ превышает %d байт", MaxResponseSize)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorder.status, http.StatusText(recorder.status)),
+		StatusCode:    recorder.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorder.header,
+		Body:          io.NopCloser(&recorder.body),
+		ContentLength: int64(recorder.body.Len()),
+		Request:       req,
+	}, nil
+}
+
+// responseRecorder накапливает ответ обработчика в памяти
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = code
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	if r.body.Len()+len(p) > MaxResponseSize {
+		r.overflow = true
+		return 0, fmt.Errorf("ответ превышает %d байт", MaxResponseSize)
+	}
+	return r.body.Write(p)
+}
@@ -0,0 +1,191 @@
+// Package orders типизированный клиент service_orders для внутренних вызовов
+package orders
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"pkg/clients"
+
+	"github.com/google/uuid"
+)
+
+// Пути service_orders
+const (
+	PathOrders    = "/v1/orders"
+	PathAllOrders = "/v1/orders/all"
+)
+
+// Статусы заказа
+const (
+	StatusCreated   = "created"
+	StatusInWork    = "in_work"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	// StatusFlagged заказ покупателя из черного списка, ожидающий решения администратора
+	StatusFlagged = "flagged"
+)
+
+// Item позиция заказа
+type Item struct {
+	Product  string  `json:"product"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+// Customer покупатель заказа (параметр include=customer)
+type Customer struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+	Name  string    `json:"name"`
+}
+
+// DisplayAmounts суммы заказа в валюте отображения (параметр display_currency)
+type DisplayAmounts struct {
+	Currency       string    `json:"currency"`
+	BaseCurrency   string    `json:"base_currency"`
+	Rate           float64   `json:"rate"`
+	RatesUpdatedAt time.Time `json:"rates_updated_at"`
+	TotalSum       float64   `json:"total_sum"`
+	ItemPrices     []float64 `json:"item_prices"`
+}
+
+// Cancellation условия отмены заказа (только в ответе на отмену)
+type Cancellation struct {
+	Policy string  `json:"policy"`
+	Rule   string  `json:"rule"`
+	Fee    float64 `json:"fee"`
+}
+
+// Order заказ
+type Order struct {
+	ID           uuid.UUID       `json:"id"`
+	UserID       uuid.UUID       `json:"user_id"`
+	Items        []Item          `json:"items"`
+	Status       string          `json:"status"`
+	StatusName   string          `json:"status_name,omitempty"`
+	TotalSum     float64         `json:"total_sum"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+	Customer     *Customer       `json:"customer,omitempty"`
+	Display      *DisplayAmounts `json:"display,omitempty"`
+	Cancellation *Cancellation   `json:"cancellation,omitempty"`
+	AssignedTo   *uuid.UUID      `json:"assigned_to,omitempty"`
+	AssignedAt   *time.Time      `json:"assigned_at,omitempty"`
+	Region       string          `json:"region,omitempty"`
+	// Tags заполняются только в ответах администраторам
+	Tags []string `json:"tags,omitempty"`
+}
+
+// ListParams параметры списка заказов. UserID учитывается только в административном
+// списке (ListAll)
+type ListParams struct {
+	Limit  int
+	Offset int
+	Status string
+	// Sort поле сортировки (created_at, updated_at, total_sum), Order — asc или desc
+	Sort    string
+	Order   string
+	UserID  uuid.UUID
+	Include []string
+	Tags    []string
+}
+
+// Page страница списка заказов
+type Page struct {
+	Orders []Order `json:"orders"`
+	Total  int     `json:"total"`
+	Limit  int     `json:"limit"`
+	Offset int     `json:"offset"`
+}
+
+// CreateRequest запрос на создание заказа
+type CreateRequest struct {
+	Items  []Item `json:"items"`
+	Region string `json:"region,omitempty"`
+}
+
+// Client клиент service_orders
+type Client struct {
+	c *clients.Client
+}
+
+// New создает клиент service_orders
+func New(config clients.Config) *Client {
+	return &Client{c: clients.New(config)}
+}
+
+// List возвращает заказы пользователя, от имени которого выполняется запрос
+func (c *Client) List(ctx context.Context, params ListParams) (*Page, error) {
+	return c.list(ctx, PathOrders, params)
+}
+
+// ListAll возвращает заказы всех пользователей или пользователя params.UserID;
+// доступно администраторам
+func (c *Client) ListAll(ctx context.Context, params ListParams) (*Page, error) {
+	return c.list(ctx, PathAllOrders, params)
+}
+
+// Get возвращает заказ по ID
+func (c *Client) Get(ctx context.Context, id uuid.UUID) (*Order, error) {
+	order := &Order{}
+	if err := c.c.Do(ctx, http.MethodGet, PathOrders+"/"+id.String(), nil, nil, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Create создает заказ от имени пользователя запроса. Запрос не повторяется:
+// повтор после потерянного ответа создал бы второй заказ
+func (c *Client) Create(ctx context.Context, req CreateRequest) (*Order, error) {
+	order := &Order{}
+	if err := c.c.Do(ctx, http.MethodPost, PathOrders, nil, req, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// UpdateStatus изменяет статус заказа
+func (c *Client) UpdateStatus(ctx context.Context, id uuid.UUID, status string) (*Order, error) {
+	order := &Order{}
+	body := map[string]string{"status": status}
+	if err := c.c.Do(ctx, http.MethodPut, PathOrders+"/"+id.String()+"/status", nil, body, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// list запрашивает страницу заказов по пути path
+func (c *Client) list(ctx context.Context, path string, params ListParams) (*Page, error) {
+	query := url.Values{}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Offset > 0 {
+		query.Set("offset", strconv.Itoa(params.Offset))
+	}
+	for name, value := range map[string]string{"status": params.Status, "sort": params.Sort, "order": params.Order} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if params.UserID != uuid.Nil && path == PathAllOrders {
+		query.Set("user_id", params.UserID.String())
+	}
+	if len(params.Include) > 0 {
+		query.Set("include", strings.Join(params.Include, ","))
+	}
+	if len(params.Tags) > 0 {
+		query.Set("tags", strings.Join(params.Tags, ","))
+	}
+
+	page := &Page{}
+	if err := c.c.Do(ctx, http.MethodGet, path, query, nil, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
@@ -0,0 +1,146 @@
+// Package users типизированный клиент service_users для внутренних вызовов
+package users
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"pkg/clients"
+
+	"github.com/google/uuid"
+)
+
+// Пути service_users
+const (
+	PathProfile    = "/v1/users/profile"
+	PathUsers      = "/v1/users"
+	PathRefresh    = "/v1/auth/refresh"
+	PathRevoke     = "/v1/auth/revoke"
+	PathAPIKeyAuth = "/v1/auth/api-key"
+)
+
+// User профиль пользователя
+type User struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Roles     []string  `json:"roles"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Timezone  string    `json:"timezone"`
+	// DeactivatedAt время деактивации учетной записи; nil — учетная запись активна
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+}
+
+// ListParams параметры списка пользователей (только для администраторов)
+type ListParams struct {
+	Limit  int
+	Offset int
+	// Email, Name и Role фильтры списка; Status — active, deactivated или all
+	Email  string
+	Name   string
+	Role   string
+	Status string
+}
+
+// Page страница списка пользователей
+type Page struct {
+	Users  []User `json:"users"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+// TokenPair пара токенов, выданная при обновлении
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	User         User   `json:"user"`
+}
+
+// APIKeyToken access токен, выданный по API ключу
+type APIKeyToken struct {
+	Token string `json:"token"`
+	// ExpiresIn время жизни токена в секундах
+	ExpiresIn int      `json:"expires_in"`
+	Scopes    []string `json:"scopes"`
+}
+
+// Client клиент service_users
+type Client struct {
+	c *clients.Client
+}
+
+// New создает клиент service_users
+func New(config clients.Config) *Client {
+	return &Client{c: clients.New(config)}
+}
+
+// Profile возвращает профиль пользователя, от имени которого выполняется запрос
+func (c *Client) Profile(ctx context.Context) (*User, error) {
+	user := &User{}
+	if err := c.c.Do(ctx, http.MethodGet, PathProfile, nil, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Get возвращает пользователя по ID: свой профиль или любой для администратора
+func (c *Client) Get(ctx context.Context, id uuid.UUID) (*User, error) {
+	user := &User{}
+	if err := c.c.Do(ctx, http.MethodGet, PathUsers+"/"+id.String(), nil, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// List возвращает страницу пользователей; доступно администраторам
+func (c *Client) List(ctx context.Context, params ListParams) (*Page, error) {
+	query := url.Values{}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Offset > 0 {
+		query.Set("offset", strconv.Itoa(params.Offset))
+	}
+	for name, value := range map[string]string{"email": params.Email, "name": params.Name, "role": params.Role, "status": params.Status} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	page := &Page{}
+	if err := c.c.Do(ctx, http.MethodGet, PathUsers, query, nil, page); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// RefreshToken обменивает refresh токен на новую пару токенов
+func (c *Client) RefreshToken(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	pair := &TokenPair{}
+	body := map[string]string{"refresh_token": refreshToken}
+	if err := c.c.Do(ctx, http.MethodPost, PathRefresh, nil, body, pair); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// RevokeRefreshToken отзывает refresh токен
+func (c *Client) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	body := map[string]string{"refresh_token": refreshToken}
+	return c.c.Do(ctx, http.MethodPost, PathRevoke, nil, body, nil)
+}
+
+// AuthenticateAPIKey обменивает персональный API ключ на access токен с областями ключа
+func (c *Client) AuthenticateAPIKey(ctx context.Context, key string) (*APIKeyToken, error) {
+	token := &APIKeyToken{}
+	body := map[string]string{"api_key": key}
+	if err := c.c.Do(ctx, http.MethodPost, PathAPIKeyAuth, nil, body, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -0,0 +1,97 @@
+// Package fakes содержит общую основу тестовых двойников сервисов: управляемую
+// инъекцию ошибок и счетчики вызовов. Сами in-memory фейки лежат в пакетах fakes
+// сервисов рядом с интерфейсами, которые они реализуют, поэтому pkg не зависит
+// от типов сервисов
+package fakes
+
+import "sync"
+
+// AnyMethod имя метода, правило для которого применяется ко всем вызовам
+const AnyMethod = "*"
+
+// failureRule правило инъекции ошибки для метода
+type failureRule struct {
+	err error
+	// remaining сколько вызовов еще завершится ошибкой; -1 — без ограничения
+	remaining int
+}
+
+// Failures управляет инъекцией ошибок и считает вызовы методов фейка.
+// Нулевое значение готово к использованию и безопасно для конкурентного доступа
+type Failures struct {
+	mutex sync.Mutex
+	rules map[string]*failureRule
+	calls map[string]int
+}
+
+// FailWith заставляет все последующие вызовы метода возвращать err
+func (f *Failures) FailWith(method string, err error) {
+	f.setRule(method, &failureRule{err: err, remaining: -1})
+}
+
+// FailNext заставляет следующие n вызовов метода вернуть err, после чего
+// метод снова работает штатно
+func (f *Failures) FailNext(method string, n int, err error) {
+	if n <= 0 {
+		return
+	}
+	f.setRule(method, &failureRule{err: err, remaining: n})
+}
+
+// Clear снимает правило инъекции ошибки с метода
+func (f *Failures) Clear(method string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.rules, method)
+}
+
+// Reset снимает все правила и обнуляет счетчики вызовов
+func (f *Failures) Reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.rules = nil
+	f.calls = nil
+}
+
+// Calls возвращает количество вызовов метода, включая завершившиеся ошибкой
+func (f *Failures) Calls(method string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.calls[method]
+}
+
+// Check регистрирует вызов метода и возвращает внедренную ошибку, если она
+// задана. Правило конкретного метода имеет приоритет над AnyMethod
+func (f *Failures) Check(method string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[method]++
+
+	for _, name := range []string{method, AnyMethod} {
+		rule, ok := f.rules[name]
+		if !ok {
+			continue
+		}
+		if rule.remaining > 0 {
+			rule.remaining--
+			if rule.remaining == 0 {
+				delete(f.rules, name)
+			}
+		}
+		return rule.err
+	}
+	return nil
+}
+
+func (f *Failures) setRule(method string, rule *failureRule) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.rules == nil {
+		f.rules = make(map[string]*failureRule)
+	}
+	f.rules[method] = rule
+}
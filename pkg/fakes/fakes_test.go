@@ -0,0 +1,46 @@
+package fakes
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFailuresFailNext(t *testing.T) {
+	var f Failures
+	errDown := errors.New("db down")
+	f.FailNext("Create", 2, errDown)
+
+	for i, want := range []error{errDown, errDown, nil} {
+		if err := f.Check("Create"); err != want {
+			t.Errorf("вызов %d: ошибка %v, ожидалась %v", i+1, err, want)
+		}
+	}
+	if calls := f.Calls("Create"); calls != 3 {
+		t.Errorf("вызовов %d, ожидалось 3", calls)
+	}
+}
+
+func TestFailuresMethodRuleOverridesAnyMethod(t *testing.T) {
+	var f Failures
+	errAny := errors.New("any")
+	errGet := errors.New("get")
+	f.FailWith(AnyMethod, errAny)
+	f.FailWith("GetByID", errGet)
+
+	if err := f.Check("GetByID"); err != errGet {
+		t.Errorf("GetByID: ошибка %v, ожидалась %v", err, errGet)
+	}
+	if err := f.Check("List"); err != errAny {
+		t.Errorf("List: ошибка %v, ожидалась %v", err, errAny)
+	}
+
+	f.Clear(AnyMethod)
+	if err := f.Check("List"); err != nil {
+		t.Errorf("после Clear: ошибка %v", err)
+	}
+
+	f.Reset()
+	if err := f.Check("GetByID"); err != nil || f.Calls("GetByID") != 1 {
+		t.Errorf("после Reset: ошибка %v, вызовов %d", err, f.Calls("GetByID"))
+	}
+}
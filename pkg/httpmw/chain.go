@@ -0,0 +1,52 @@
+// Package httpmw содержит HTTP middleware, общие для API Gateway и микросервисов:
+// X-Request-ID, этапы обработки (Server-Timing), лог запросов, метрики, перехват panic
+// и таймаут. Поведение каждого middleware задается структурой конфигурации, а специфичная
+// для сервиса часть (формат лога, ответ 500, источник таймаута) передается функциями.
+//
+//	handler := httpmw.NewChain(
+//		httpmw.RequestID(httpmw.RequestIDConfig{}),
+//		httpmw.ServerTiming(),
+//		httpmw.Logging(httpmw.LoggingConfig{Log: logRequest}),
+//		httpmw.Recovery(httpmw.RecoveryConfig{Body: body, Report: reportPanic}),
+//	).Then(router)
+package httpmw
+
+import "net/http"
+
+// Middleware оборачивает обработчик; совместим с mux.MiddlewareFunc
+type Middleware func(http.Handler) http.Handler
+
+// Chain упорядоченный набор middleware: первый элемент получает запрос первым
+type Chain []Middleware
+
+// NewChain создает цепочку; nil элементы пропускаются, что позволяет включать
+// middleware по условию конфигурации
+func NewChain(middlewares ...Middleware) Chain {
+	return Chain(nil).Append(middlewares...)
+}
+
+// Append возвращает новую цепочку с middleware, добавленными в конец (ближе к обработчику).
+// Исходная цепочка не изменяется
+func (c Chain) Append(middlewares ...Middleware) Chain {
+	chain := make(Chain, 0, len(c)+len(middlewares))
+	chain = append(chain, c...)
+	for _, middleware := range middlewares {
+		if middleware != nil {
+			chain = append(chain, middleware)
+		}
+	}
+	return chain
+}
+
+// Then оборачивает handler всеми middleware цепочки
+func (c Chain) Then(handler http.Handler) http.Handler {
+	for i := len(c) - 1; i >= 0; i-- {
+		handler = c[i](handler)
+	}
+	return handler
+}
+
+// ThenFunc оборачивает функцию-обработчик всеми middleware цепочки
+func (c Chain) ThenFunc(handler http.HandlerFunc) http.Handler {
+	return c.Then(handler)
+}
@@ -0,0 +1,60 @@
+package httpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// tag возвращает middleware, записывающее name в order до и после обработчика
+func tag(order *[]string, name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*order = append(*order, name)
+			next.ServeHTTP(w, r)
+			*order = append(*order, "/"+name)
+		})
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	handler := NewChain(tag(&order, "a"), nil, tag(&order, "b")).
+		Append(nil, tag(&order, "c")).
+		ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "handler")
+		})
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := []string{"a", "b", "c", "handler", "/c", "/b", "/a"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("порядок вызова %v, ожидался %v", order, want)
+	}
+}
+
+func TestChainSkipsNil(t *testing.T) {
+	chain := NewChain(nil, nil)
+	if len(chain) != 0 {
+		t.Fatalf("длина цепочки %d, ожидалась 0", len(chain))
+	}
+
+	called := false
+	chain.ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !called {
+		t.Fatal("пустая цепочка не вызвала обработчик")
+	}
+}
+
+func TestChainAppendDoesNotModifyOriginal(t *testing.T) {
+	var order []string
+	base := NewChain(tag(&order, "a"))
+	base.Append(tag(&order, "b"))
+
+	if len(base) != 1 {
+		t.Fatalf("длина исходной цепочки %d, ожидалась 1", len(base))
+	}
+}
@@ -0,0 +1,87 @@
+package httpmw
+
+import (
+	"net/http"
+	"time"
+)
+
+// Result итог обработки запроса
+type Result struct {
+	Start time.Time
+	// Status код ответа; 200, если обработчик не вызвал WriteHeader
+	Status int
+	// BytesOut размер тела ответа
+	BytesOut int64
+	Duration time.Duration
+}
+
+// LoggingConfig настройки middleware лога запросов
+type LoggingConfig struct {
+	// Begin вызывается до обработки и может вернуть запрос с дополненным контекстом
+	// (например, записью, которую заполняют внутренние обработчики); nil — не используется
+	Begin func(r *http.Request) *http.Request
+	// Log получает запрос, возвращенный Begin, и итог обработки
+	Log func(r *http.Request, result Result)
+}
+
+// Logging передает итог каждого запроса в config.Log. Запрос, прерванный panic,
+// не логируется: Recovery внутри Logging превращает panic в обычный ответ 500
+func Logging(config LoggingConfig) Middleware {
+	return observe(config.Begin, config.Log)
+}
+
+// MetricsConfig настройки middleware метрик
+type MetricsConfig struct {
+	// Observe получает итог каждого запроса для записи в метрики
+	Observe func(r *http.Request, result Result)
+}
+
+// Metrics передает итог каждого запроса в config.Observe
+func Metrics(config MetricsConfig) Middleware {
+	return observe(nil, config.Observe)
+}
+
+// observe выполняет запрос с учетом кода и размера ответа и передает итог в done
+func observe(begin func(r *http.Request) *http.Request, done func(r *http.Request, result Result)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			if begin != nil {
+				r = begin(r)
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			done(r, Result{
+				Start:    start,
+				Status:   recorder.status,
+				BytesOut: recorder.bytes,
+				Duration: time.Since(start),
+			})
+		})
+	}
+}
+
+// statusRecorder запоминает код и размер ответа
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.status = code
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(p []byte) (int, error) {
+	n, err := sr.ResponseWriter.Write(p)
+	sr.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap позволяет http.ResponseController получить доступ к исходному ResponseWriter (Flush)
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
@@ -0,0 +1,77 @@
+package httpmw
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"pkg/httpresp"
+)
+
+// ReportFunc получает значение panic и стек горутины для записи в лог
+type ReportFunc func(r *http.Request, value interface{}, stack []byte)
+
+// RecoveryConfig настройки перехвата panic
+type RecoveryConfig struct {
+	// Body JSON тело ответа 500 в формате ошибок сервиса
+	Body []byte
+	// Report получает значение panic и стек для записи в лог
+	Report ReportFunc
+}
+
+// Recovery перехватывает panic обработчика: вместо разрыва соединения клиент получает
+// ответ 500 с config.Body, а panic передается в config.Report. Если ответ уже начал
+// отправляться, исправить его нельзя, и соединение разрывается (http.ErrAbortHandler)
+func Recovery(config RecoveryConfig) Middleware {
+	body, report := config.Body, config.Report
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tracker := &trackingWriter{ResponseWriter: w}
+
+			defer func() {
+				value := recover()
+				if value == nil {
+					return
+				}
+				// net/http использует ErrAbortHandler для намеренного прерывания ответа
+				if value == http.ErrAbortHandler {
+					panic(value)
+				}
+
+				report(r, value, debug.Stack())
+
+				if tracker.started {
+					panic(http.ErrAbortHandler)
+				}
+				// Заголовки, выставленные обработчиком до panic, могут не подходить для тела ошибки
+				w.Header().Del("Content-Encoding")
+				httpresp.Write(w, http.StatusInternalServerError, httpresp.ContentTypeJSON, body)
+			}()
+
+			next.ServeHTTP(tracker, r)
+		})
+	}
+}
+
+// trackingWriter отмечает начало отправки ответа
+type trackingWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (tw *trackingWriter) WriteHeader(code int) {
+	// Информационные ответы 1xx не завершают заголовки ответа
+	if code >= http.StatusOK {
+		tw.started = true
+	}
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *trackingWriter) Write(p []byte) (int, error) {
+	tw.started = true
+	return tw.ResponseWriter.Write(p)
+}
+
+// Unwrap позволяет http.ResponseController получить доступ к исходному ResponseWriter (Flush)
+func (tw *trackingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,80 @@
+package httpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const recoveryBody = `{"error":"Внутренняя ошибка сервера"}`
+
+func TestRecoveryWritesErrorBody(t *testing.T) {
+	var reported interface{}
+	handler := Recovery(RecoveryConfig{
+		Body: []byte(recoveryBody),
+		Report: func(r *http.Request, value interface{}, stack []byte) {
+			reported = value
+			if len(stack) == 0 {
+				t.Error("стек panic не передан")
+			}
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		panic("сбой")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("код %d, ожидался 500", rec.Code)
+	}
+	if rec.Body.String() != recoveryBody {
+		t.Fatalf("тело %q, ожидалось %q", rec.Body.String(), recoveryBody)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type %q, ожидался application/json", got)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("Content-Encoding %q сохранен в ответе об ошибке", got)
+	}
+	if reported != "сбой" {
+		t.Fatalf("в Report передано %v", reported)
+	}
+}
+
+func TestRecoveryAbortsStartedResponse(t *testing.T) {
+	handler := Recovery(RecoveryConfig{
+		Body:   []byte(recoveryBody),
+		Report: func(r *http.Request, value interface{}, stack []byte) {},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("сбой после начала ответа")
+	}))
+
+	defer func() {
+		if value := recover(); value != http.ErrAbortHandler {
+			t.Fatalf("panic %v, ожидался http.ErrAbortHandler", value)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestRecoveryPassesThroughAbortHandler(t *testing.T) {
+	reported := false
+	handler := Recovery(RecoveryConfig{
+		Report: func(r *http.Request, value interface{}, stack []byte) { reported = true },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if value := recover(); value != http.ErrAbortHandler {
+			t.Fatalf("panic %v, ожидался http.ErrAbortHandler", value)
+		}
+		if reported {
+			t.Fatal("намеренное прерывание передано в Report")
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
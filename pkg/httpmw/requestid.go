@@ -0,0 +1,87 @@
+package httpmw
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// HeaderRequestID заголовок идентификатора запроса
+const HeaderRequestID = "X-Request-ID"
+
+// DefaultRequestIDMaxLength максимальная длина X-Request-ID клиента по умолчанию
+const DefaultRequestIDMaxLength = 128
+
+// RequestIDConfig настройки middleware X-Request-ID
+type RequestIDConfig struct {
+	// Header имя заголовка; по умолчанию X-Request-ID
+	Header string
+	// MaxLength максимальная длина принимаемого значения; по умолчанию 128
+	MaxLength int
+	// Generate создает идентификатор для запросов без корректного заголовка; по умолчанию UUIDv4
+	Generate func() string
+}
+
+// requestIDKey ключ контекста для X-Request-ID
+type requestIDKey struct{}
+
+// RequestID назначает запросу идентификатор: корректное значение заголовка клиента
+// сохраняется, иначе генерируется новое. Идентификатор записывается в заголовок
+// запроса (для обработчиков и исходящих запросов), в заголовок ответа и в контекст
+func RequestID(config RequestIDConfig) Middleware {
+	if config.Header == "" {
+		config.Header = HeaderRequestID
+	}
+	if config.MaxLength <= 0 {
+		config.MaxLength = DefaultRequestIDMaxLength
+	}
+	if config.Generate == nil {
+		config.Generate = NewUUID
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(config.Header)
+			if !ValidRequestID(requestID, config.MaxLength) {
+				requestID = config.Generate()
+			}
+			r.Header.Set(config.Header, requestID)
+			w.Header().Set(config.Header, requestID)
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID)))
+		})
+	}
+}
+
+// RequestIDFromContext возвращает идентификатор, назначенный RequestID, или пустую строку
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// ValidRequestID проверяет идентификатор клиента: ограниченная длина и только символы,
+// безопасные для логов и заголовков (латинские буквы, цифры, -_.:)
+func ValidRequestID(id string, maxLength int) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && c != '-' && c != '_' && c != '.' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// NewUUID возвращает случайный UUIDv4
+func NewUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("httpmw: ошибка генерации UUID: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,67 @@
+package httpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// serveRequestID выполняет запрос через RequestID и возвращает идентификаторы
+// из заголовка запроса, контекста и заголовка ответа
+func serveRequestID(t *testing.T, config RequestIDConfig, incoming string) (header, fromContext, response string) {
+	t.Helper()
+	handler := RequestID(config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(HeaderRequestID)
+		fromContext = RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if incoming != "" {
+		req.Header.Set(HeaderRequestID, incoming)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return header, fromContext, rec.Header().Get(HeaderRequestID)
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	header, fromContext, response := serveRequestID(t, RequestIDConfig{}, "")
+
+	if !uuidPattern.MatchString(header) {
+		t.Fatalf("сгенерирован %q, ожидался UUIDv4", header)
+	}
+	if fromContext != header || response != header {
+		t.Fatalf("идентификатор не совпадает: запрос %q, контекст %q, ответ %q", header, fromContext, response)
+	}
+}
+
+func TestRequestIDPropagated(t *testing.T) {
+	header, fromContext, response := serveRequestID(t, RequestIDConfig{}, "client-id:42")
+
+	for name, got := range map[string]string{"запрос": header, "контекст": fromContext, "ответ": response} {
+		if got != "client-id:42" {
+			t.Errorf("%s: %q, ожидался идентификатор клиента", name, got)
+		}
+	}
+}
+
+func TestRequestIDRejectsInvalid(t *testing.T) {
+	config := RequestIDConfig{MaxLength: 8, Generate: func() string { return "generated" }}
+
+	for _, incoming := range []string{"bad value", "id\r\nX-Injected: 1", strings.Repeat("a", 9)} {
+		header, _, response := serveRequestID(t, config, incoming)
+		if header != "generated" || response != "generated" {
+			t.Errorf("%q: запрос %q, ответ %q, ожидался новый идентификатор", incoming, header, response)
+		}
+	}
+}
+
+func TestRequestIDFromContextEmpty(t *testing.T) {
+	if got := RequestIDFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()); got != "" {
+		t.Fatalf("идентификатор вне middleware %q, ожидалась пустая строка", got)
+	}
+}
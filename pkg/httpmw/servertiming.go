@@ -0,0 +1,64 @@
+package httpmw
+
+import (
+	"net/http"
+	"time"
+
+	"pkg/servertiming"
+)
+
+// ServerTiming учитывает этапы обработки запроса сервисом и сообщает их вызывающей
+// стороне в заголовке Server-Timing: wait (по X-Request-Start), app — время до начала
+// ответа, а также этапы, которые обработчики добавили в servertiming.Recorder
+// из контекста (db, publish). Заголовок выставляется перед отправкой кода ответа,
+// поэтому этапы, завершившиеся после начала ответа, в него не попадают
+func ServerTiming() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := servertiming.NewRecorder()
+			if sent, ok := servertiming.ParseRequestStart(r.Header.Get(servertiming.HeaderRequestStart)); ok && start.After(sent) {
+				recorder.Add(servertiming.MetricWait, start.Sub(sent))
+			}
+
+			tw := &timingWriter{ResponseWriter: w, recorder: recorder, start: start}
+			next.ServeHTTP(tw, r.WithContext(servertiming.NewContext(r.Context(), recorder)))
+
+			// Обработчик без ответа: net/http отправит 200 после возврата
+			tw.writeTiming()
+		})
+	}
+}
+
+// timingWriter добавляет Server-Timing перед отправкой кода ответа
+type timingWriter struct {
+	http.ResponseWriter
+	recorder *servertiming.Recorder
+	start    time.Time
+	written  bool
+}
+
+func (tw *timingWriter) WriteHeader(code int) {
+	tw.writeTiming()
+	tw.ResponseWriter.WriteHeader(code)
+}
+
+func (tw *timingWriter) Write(p []byte) (int, error) {
+	tw.writeTiming()
+	return tw.ResponseWriter.Write(p)
+}
+
+// Unwrap позволяет http.ResponseController получить доступ к исходному ResponseWriter (Flush)
+func (tw *timingWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// writeTiming завершает этап app и выставляет заголовок при первом вызове
+func (tw *timingWriter) writeTiming() {
+	if tw.written {
+		return
+	}
+	tw.written = true
+	tw.recorder.Add(servertiming.MetricApp, time.Since(tw.start))
+	tw.ResponseWriter.Header().Set(servertiming.Header, servertiming.Format(tw.recorder.Metrics()))
+}
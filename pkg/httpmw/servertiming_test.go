@@ -0,0 +1,59 @@
+package httpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pkg/servertiming"
+)
+
+// serveTiming выполняет запрос через ServerTiming и возвращает разобранный заголовок ответа
+func serveTiming(t *testing.T, req *http.Request, handler http.HandlerFunc) map[string]time.Duration {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	ServerTiming()(handler).ServeHTTP(rec, req)
+
+	metrics := make(map[string]time.Duration)
+	for _, metric := range servertiming.Parse(rec.Header().Get(servertiming.Header)) {
+		metrics[metric.Name] = metric.Duration
+	}
+	return metrics
+}
+
+func TestServerTimingStages(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(servertiming.HeaderRequestStart, servertiming.FormatRequestStart(time.Now().Add(-50*time.Millisecond)))
+
+	metrics := serveTiming(t, req, func(w http.ResponseWriter, r *http.Request) {
+		servertiming.FromContext(r.Context()).Add(servertiming.MetricDB, 5*time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+		// Этап после начала ответа в заголовок не попадает
+		servertiming.FromContext(r.Context()).Add(servertiming.MetricPublish, time.Millisecond)
+	})
+
+	if metrics[servertiming.MetricDB] != 5*time.Millisecond {
+		t.Errorf("db %v, ожидалось 5ms", metrics[servertiming.MetricDB])
+	}
+	if metrics[servertiming.MetricWait] < 50*time.Millisecond {
+		t.Errorf("wait %v, ожидалось не меньше 50ms", metrics[servertiming.MetricWait])
+	}
+	if _, ok := metrics[servertiming.MetricApp]; !ok {
+		t.Error("нет этапа app")
+	}
+	if _, ok := metrics[servertiming.MetricPublish]; ok {
+		t.Error("этап publish, завершенный после начала ответа, попал в заголовок")
+	}
+}
+
+func TestServerTimingWithoutResponse(t *testing.T) {
+	metrics := serveTiming(t, httptest.NewRequest(http.MethodGet, "/", nil), func(w http.ResponseWriter, r *http.Request) {})
+
+	if _, ok := metrics[servertiming.MetricApp]; !ok {
+		t.Error("нет этапа app для обработчика без ответа")
+	}
+	if _, ok := metrics[servertiming.MetricWait]; ok {
+		t.Error("этап wait без X-Request-Start")
+	}
+}
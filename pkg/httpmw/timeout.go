@@ -0,0 +1,31 @@
+package httpmw
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// TimeoutConfig настройки ограничения времени обработки запроса
+type TimeoutConfig struct {
+	// Timeout возвращает предельное время обработки запроса; 0 — без ограничения
+	Timeout func(r *http.Request) time.Duration
+}
+
+// Timeout отменяет контекст запроса по истечении config.Timeout. Ответ не прерывается:
+// обработчики и исходящие запросы должны учитывать отмену контекста
+func Timeout(config TimeoutConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := config.Timeout(r)
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), limit)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
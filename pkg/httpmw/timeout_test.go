@@ -0,0 +1,61 @@
+package httpmw
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// contextAwareHandler отвечает 504, если контекст запроса истек раньше ответа,
+// как это делают прокси шлюза
+func contextAwareHandler(work time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(work):
+			w.WriteHeader(http.StatusOK)
+		case <-r.Context().Done():
+			if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+				w.WriteHeader(http.StatusGatewayTimeout)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+}
+
+func serveTimeout(limit, work time.Duration) int {
+	handler := Timeout(TimeoutConfig{
+		Timeout: func(r *http.Request) time.Duration { return limit },
+	})(contextAwareHandler(work))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec.Code
+}
+
+func TestTimeoutExpired(t *testing.T) {
+	if code := serveTimeout(10*time.Millisecond, time.Second); code != http.StatusGatewayTimeout {
+		t.Fatalf("код %d, ожидался 504", code)
+	}
+}
+
+func TestTimeoutNotExpired(t *testing.T) {
+	if code := serveTimeout(time.Second, 0); code != http.StatusOK {
+		t.Fatalf("код %d, ожидался 200", code)
+	}
+}
+
+func TestTimeoutDisabled(t *testing.T) {
+	handler := Timeout(TimeoutConfig{
+		Timeout: func(r *http.Request) time.Duration { return 0 },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("при таймауте 0 у контекста установлен срок")
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
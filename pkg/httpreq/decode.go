@@ -0,0 +1,158 @@
+// Package httpreq содержит общие функции разбора HTTP запросов
+// для микросервисов
+package httpreq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// DefaultMaxBodySize максимальный размер JSON тела запроса по умолчанию
+const DefaultMaxBodySize = 1 << 20
+
+// Причины ошибки разбора тела запроса
+const (
+	ReasonEmptyBody     = "empty_body"
+	ReasonMalformedJSON = "malformed_json"
+	ReasonUnknownField  = "unknown_field"
+	ReasonTypeMismatch  = "type_mismatch"
+	ReasonTrailingData  = "trailing_data"
+	ReasonBodyTooLarge  = "body_too_large"
+	// ReasonInvalidValue значение отклонено при разборе типа (например, некорректный UUID или дата)
+	ReasonInvalidValue = "invalid_value"
+)
+
+// DecodeError ошибка разбора тела запроса с HTTP статусом и причиной для клиента
+type DecodeError struct {
+	// Status 413 для слишком большого тела, иначе 400
+	Status int
+	Reason string
+	// Field поле JSON, к которому относится ошибка (для unknown_field и type_mismatch)
+	Field   string
+	Message string
+}
+
+func (e *DecodeError) Error() string {
+	return e.Message
+}
+
+// DecodeJSON разбирает тело запроса в dst. Тело ограничено maxBytes байт
+// (<= 0 — DefaultMaxBodySize), неизвестные поля и данные после JSON значения
+// отклоняются. Ошибка всегда имеет тип *DecodeError
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodySize
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		return decodeError(err, maxBytes)
+	}
+
+	// Второе значение в теле — признак склеенных или поддельных запросов
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return decodeError(err, maxBytes)
+		}
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Reason:  ReasonTrailingData,
+			Message: "Тело запроса должно содержать один JSON объект",
+		}
+	}
+	return nil
+}
+
+// decodeError сопоставляет ошибку encoding/json причине и сообщению для клиента
+func decodeError(err error, maxBytes int64) *DecodeError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &DecodeError{
+			Status:  http.StatusRequestEntityTooLarge,
+			Reason:  ReasonBodyTooLarge,
+			Message: fmt.Sprintf("Тело запроса превышает %d байт", maxBytes),
+		}
+	case errors.Is(err, io.EOF):
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Reason:  ReasonEmptyBody,
+			Message: "Тело запроса пустое",
+		}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Reason:  ReasonMalformedJSON,
+			Message: "Некорректный JSON: неожиданный конец тела запроса",
+		}
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Reason:  ReasonMalformedJSON,
+			Message: fmt.Sprintf("Некорректный JSON в позиции %d", syntaxErr.Offset),
+		}
+	case errors.As(err, &typeErr):
+		field := typeErr.Field
+		if field == "" {
+			return &DecodeError{
+				Status:  http.StatusBadRequest,
+				Reason:  ReasonTypeMismatch,
+				Message: fmt.Sprintf("Тело запроса должно быть %s", jsonType(typeErr.Type.Kind())),
+			}
+		}
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Reason:  ReasonTypeMismatch,
+			Field:   field,
+			Message: fmt.Sprintf("поле '%s' должно быть %s, получено %s", field, jsonType(typeErr.Type.Kind()), typeErr.Value),
+		}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json не экспортирует тип ошибки неизвестного поля
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Reason:  ReasonUnknownField,
+			Field:   field,
+			Message: fmt.Sprintf("неизвестное поле '%s'", field),
+		}
+	default:
+		// Ошибки UnmarshalJSON/UnmarshalText типов полей (uuid.UUID, time.Time)
+		return &DecodeError{
+			Status:  http.StatusBadRequest,
+			Reason:  ReasonInvalidValue,
+			Message: fmt.Sprintf("Некорректное значение в теле запроса: %v", err),
+		}
+	}
+}
+
+// jsonType возвращает название типа JSON для вида значения Go
+func jsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "строкой"
+	case reflect.Bool:
+		return "логическим значением"
+	case reflect.Slice, reflect.Array:
+		return "массивом"
+	case reflect.Struct, reflect.Map:
+		return "объектом"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "целым числом"
+	case reflect.Float32, reflect.Float64:
+		return "числом"
+	default:
+		return "значением другого типа"
+	}
+}
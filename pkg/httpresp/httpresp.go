@@ -0,0 +1,68 @@
+// Package httpresp содержит общие функции записи HTTP ответов
+// для микросервисов и API Gateway
+package httpresp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// ContentTypeJSON тип содержимого JSON ответов
+const ContentTypeJSON = "application/json"
+
+// Writer записывает JSON ответы. Fallback — тело ответа 500, которое
+// отправляется, если payload не удалось сериализовать
+type Writer struct {
+	Fallback []byte
+}
+
+// Default Writer с fallback в формате APIResponse микросервисов
+var Default = NewWriter([]byte(`{"success":false,"error":{"code":"INTERNAL_SERVER_ERROR","message":"Ошибка формирования ответа"}}`))
+
+// NewWriter создает Writer с заданным телом ответа при ошибке сериализации
+func NewWriter(fallback []byte) *Writer {
+	return &Writer{Fallback: fallback}
+}
+
+// JSON отправляет payload через Default
+func JSON(w http.ResponseWriter, statusCode int, payload interface{}) error {
+	return Default.JSON(w, statusCode, payload)
+}
+
+// JSON сериализует payload до записи заголовков и отправляет его со статусом statusCode.
+// При ошибке сериализации клиент получает 500 с Fallback вместо обрезанного тела
+// с успешным статусом. Возвращаемая ошибка предназначена для логирования
+func (wr *Writer) JSON(w http.ResponseWriter, statusCode int, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		if writeErr := Write(w, http.StatusInternalServerError, ContentTypeJSON, wr.Fallback); writeErr != nil {
+			return fmt.Errorf("ошибка сериализации ответа: %v; %v", err, writeErr)
+		}
+		return fmt.Errorf("ошибка сериализации ответа: %v", err)
+	}
+
+	return Write(w, statusCode, ContentTypeJSON, body)
+}
+
+// Write отправляет готовое тело ответа с заголовками Content-Type и Content-Length
+func Write(w http.ResponseWriter, statusCode int, contentType string, body []byte) error {
+	header := w.Header()
+	header.Set("Content-Type", contentType)
+
+	// Для 204 и 304 тело запрещено
+	if statusCode == http.StatusNoContent || statusCode == http.StatusNotModified {
+		header.Del("Content-Length")
+		w.WriteHeader(statusCode)
+		return nil
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(statusCode)
+
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("ошибка записи ответа: %v", err)
+	}
+	return nil
+}
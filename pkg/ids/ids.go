@@ -0,0 +1,133 @@
+// Package ids создает идентификаторы новых записей (пользователей, заказов, событий).
+// Стратегия выбирается при запуске сервиса: случайные UUIDv4 равномерно разбрасывают
+// вставки по B-tree индексу первичного ключа, а UUIDv7 и ULID начинаются с метки
+// времени, поэтому новые записи попадают в конец индекса. Все стратегии дают 128-битные
+// значения в формате UUID: они хранятся в тех же колонках UUID и разбираются uuid.Parse
+// вместе с уже выданными идентификаторами v4
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Стратегии генерации идентификаторов
+const (
+	StrategyUUIDv4 = "uuidv4"
+	StrategyUUIDv7 = "uuidv7"
+	StrategyULID   = "ulid"
+)
+
+// Generator создает идентификаторы новых записей
+type Generator interface {
+	New() uuid.UUID
+}
+
+// NewGenerator возвращает генератор стратегии; пустое имя соответствует StrategyUUIDv4
+func NewGenerator(strategy string) (Generator, error) {
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case "", StrategyUUIDv4:
+		return uuidV4{}, nil
+	case StrategyUUIDv7:
+		return uuidV7{}, nil
+	case StrategyULID:
+		return ulid{}, nil
+	default:
+		return nil, fmt.Errorf("неизвестная стратегия идентификаторов: %s (допустимы %s, %s, %s)",
+			strategy, StrategyUUIDv4, StrategyUUIDv7, StrategyULID)
+	}
+}
+
+// defaultGenerator обертка генератора New: atomic.Value принимает значения только
+// одного конкретного типа, а генераторы стратегий — разные типы
+type defaultGenerator struct {
+	Generator
+}
+
+// current генератор, используемый New
+var current atomic.Value
+
+func init() {
+	current.Store(defaultGenerator{uuidV4{}})
+}
+
+// SetDefault задает генератор для New; безопасен при одновременных вызовах New
+func SetDefault(generator Generator) {
+	current.Store(defaultGenerator{generator})
+}
+
+// New возвращает новый идентификатор по стратегии, заданной SetDefault (по умолчанию UUIDv4)
+func New() uuid.UUID {
+	return current.Load().(defaultGenerator).New()
+}
+
+// uuidV4 случайный UUID
+type uuidV4 struct{}
+
+func (uuidV4) New() uuid.UUID {
+	return uuid.New()
+}
+
+// uuidV7 UUID с меткой времени в миллисекундах (RFC 9562); порядок внутри
+// миллисекунды сохраняется счетчиком библиотеки
+type uuidV7 struct{}
+
+func (uuidV7) New() uuid.UUID {
+	return uuid.Must(uuid.NewV7())
+}
+
+// ulid 48 бит метки времени в миллисекундах и 80 случайных бит. В отличие от UUIDv7
+// биты версии и варианта не выделяются, поэтому значение отдается в формате UUID,
+// но не является UUID какой-либо версии. Внутри миллисекунды случайная часть
+// предыдущего значения увеличивается на единицу (монотонный ULID), поэтому, как и
+// у UUIDv7, идентификаторы процесса строго возрастают
+type ulid struct{}
+
+// ulidLast последний выданный ULID и его метка времени
+var ulidLast struct {
+	sync.Mutex
+	id uuid.UUID
+	ms uint64
+}
+
+func (ulid) New() uuid.UUID {
+	ulidLast.Lock()
+	defer ulidLast.Unlock()
+
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= ulidLast.ms {
+		// Та же миллисекунда или часы переведены назад: продолжаем последовательность
+		if incrementRandom(&ulidLast.id) {
+			return ulidLast.id
+		}
+		ms = ulidLast.ms + 1
+	}
+
+	var id uuid.UUID
+	var stamp [8]byte
+	binary.BigEndian.PutUint64(stamp[:], ms)
+	copy(id[:6], stamp[2:])
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(fmt.Sprintf("ids: ошибка генерации ULID: %v", err))
+	}
+	ulidLast.id, ulidLast.ms = id, ms
+	return id
+}
+
+// incrementRandom увеличивает 80 случайных бит ULID на единицу; false при переполнении
+func incrementRandom(id *uuid.UUID) bool {
+	for i := len(id) - 1; i >= 6; i-- {
+		id[i]++
+		if id[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
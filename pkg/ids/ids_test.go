@@ -0,0 +1,143 @@
+package ids
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// mustGenerator возвращает генератор стратегии strategy
+func mustGenerator(tb testing.TB, strategy string) Generator {
+	tb.Helper()
+	generator, err := NewGenerator(strategy)
+	if err != nil {
+		tb.Fatalf("стратегия %s: %v", strategy, err)
+	}
+	return generator
+}
+
+// timestamp возвращает метку времени в миллисекундах из первых 48 бит идентификатора
+func timestamp(id uuid.UUID) int64 {
+	var stamp [8]byte
+	copy(stamp[2:], id[:6])
+	return int64(binary.BigEndian.Uint64(stamp[:]))
+}
+
+func TestNewGeneratorStrategies(t *testing.T) {
+	for strategy, version := range map[string]uuid.Version{"": 4, " UUIDv4 ": 4, StrategyUUIDv7: 7} {
+		id := mustGenerator(t, strategy).New()
+		if id.Version() != version || id.Variant() != uuid.RFC4122 {
+			t.Errorf("стратегия %q: %s версии %d варианта %s, ожидался UUIDv%d", strategy, id, id.Version(), id.Variant(), version)
+		}
+	}
+
+	if _, err := NewGenerator("snowflake"); err == nil {
+		t.Error("неизвестная стратегия принята")
+	}
+}
+
+func TestTimeOrderedFormat(t *testing.T) {
+	for _, strategy := range []string{StrategyUUIDv7, StrategyULID} {
+		before := time.Now().UnixMilli()
+		id := mustGenerator(t, strategy).New()
+		after := time.Now().UnixMilli()
+
+		if ms := timestamp(id); ms < before || ms > after {
+			t.Errorf("%s: метка времени %d вне [%d, %d]", strategy, ms, before, after)
+		}
+		// Идентификаторы хранятся в колонках UUID и разбираются вместе с v4
+		if parsed, err := uuid.Parse(id.String()); err != nil || parsed != id {
+			t.Errorf("%s: %s не разбирается uuid.Parse: %v", strategy, id, err)
+		}
+	}
+}
+
+func TestTimeOrderedMonotonic(t *testing.T) {
+	for _, strategy := range []string{StrategyUUIDv7, StrategyULID} {
+		generator := mustGenerator(t, strategy)
+		previous := generator.New()
+		// Десятки тысяч значений заведомо попадают в одну миллисекунду
+		for i := 0; i < 50000; i++ {
+			id := generator.New()
+			if bytes.Compare(id[:], previous[:]) <= 0 {
+				t.Fatalf("%s: %s выдан после %s", strategy, id, previous)
+			}
+			previous = id
+		}
+	}
+}
+
+func TestULIDContinuesAfterClockMovesBack(t *testing.T) {
+	future := uint64(time.Now().Add(time.Hour).UnixMilli())
+	var last uuid.UUID
+	var stamp [8]byte
+	binary.BigEndian.PutUint64(stamp[:], future)
+	copy(last[:6], stamp[2:])
+
+	ulidLast.Lock()
+	ulidLast.id, ulidLast.ms = last, future
+	ulidLast.Unlock()
+	t.Cleanup(func() {
+		ulidLast.Lock()
+		ulidLast.ms, ulidLast.id = 0, uuid.UUID{}
+		ulidLast.Unlock()
+	})
+
+	id := ulid{}.New()
+	if timestamp(id) != int64(future) || id[15] != 1 {
+		t.Errorf("%s: ожидалось продолжение последовательности последнего ULID", id)
+	}
+}
+
+func TestSetDefaultConcurrentWithNew(t *testing.T) {
+	t.Cleanup(func() { SetDefault(uuidV4{}) })
+
+	// Проверка имеет смысл с go test -race
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if New() == uuid.Nil {
+					t.Error("New вернул пустой идентификатор")
+					return
+				}
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			strategies := []string{StrategyUUIDv4, StrategyUUIDv7, StrategyULID}
+			for j := 0; j < 100; j++ {
+				SetDefault(mustGenerator(t, strategies[(i+j)%len(strategies)]))
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkNewUUIDv4(b *testing.B) {
+	benchmarkNew(b, StrategyUUIDv4)
+}
+
+func BenchmarkNewUUIDv7(b *testing.B) {
+	benchmarkNew(b, StrategyUUIDv7)
+}
+
+func BenchmarkNewULID(b *testing.B) {
+	benchmarkNew(b, StrategyULID)
+}
+
+func benchmarkNew(b *testing.B, strategy string) {
+	generator := mustGenerator(b, strategy)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			generator.New()
+		}
+	})
+}
@@ -0,0 +1,74 @@
+// Package jwtkeys содержит ключи подписи JWT с ротацией без выхода пользователей:
+// набор HMAC секретов (Keyring) или асимметричные ключи RS256/ES256 (SigningKeys).
+// service_users подписывает токены текущим ключом и указывает его идентификатор
+// в заголовке kid, а Gateway принимает токены, подписанные текущим или предыдущими
+// ключами, пока не истечет срок их действия
+package jwtkeys
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Keyring текущий секрет подписи и предыдущие секреты, принимаемые при проверке
+type Keyring struct {
+	currentID string
+	// ids идентификаторы в порядке проверки: текущий первым
+	ids     []string
+	secrets map[string][]byte
+}
+
+// KeyID возвращает идентификатор секрета для заголовка kid: первые 8 байт SHA-256
+// секрета в hex. Идентификатор не зависит от настройки и одинаков во всех сервисах
+func KeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// New создает Keyring из текущего секрета и предыдущих секретов
+func New(current string, previous []string) (*Keyring, error) {
+	if current == "" {
+		return nil, fmt.Errorf("не задан текущий секрет JWT")
+	}
+
+	keyring := &Keyring{
+		currentID: KeyID(current),
+		secrets:   make(map[string][]byte, len(previous)+1),
+	}
+	for _, secret := range append([]string{current}, previous...) {
+		id := KeyID(secret)
+		if _, ok := keyring.secrets[id]; ok {
+			continue
+		}
+		keyring.ids = append(keyring.ids, id)
+		keyring.secrets[id] = []byte(secret)
+	}
+	return keyring, nil
+}
+
+// Current возвращает идентификатор и текущий секрет подписи
+func (k *Keyring) Current() (string, []byte) {
+	return k.currentID, k.secrets[k.currentID]
+}
+
+// Secret возвращает секрет по идентификатору kid
+func (k *Keyring) Secret(kid string) ([]byte, bool) {
+	secret, ok := k.secrets[kid]
+	return secret, ok
+}
+
+// Secrets возвращает все секреты, текущий первым, — для токенов без kid,
+// выпущенных до включения идентификаторов ключей
+func (k *Keyring) Secrets() [][]byte {
+	secrets := make([][]byte, 0, len(k.ids))
+	for _, id := range k.ids {
+		secrets = append(secrets, k.secrets[id])
+	}
+	return secrets
+}
+
+// IDs возвращает идентификаторы секретов, текущий первым
+func (k *Keyring) IDs() []string {
+	return append([]string(nil), k.ids...)
+}
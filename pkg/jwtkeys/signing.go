@@ -0,0 +1,231 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// Алгоритмы подписи JWT
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+)
+
+// minRSABits минимальный размер ключа RSA
+const minRSABits = 2048
+
+// Signer источник ключа подписи токенов: Keyring (HMAC) или SigningKeys (RS256, ES256)
+type Signer interface {
+	// SigningKey возвращает алгоритм, идентификатор ключа для заголовка kid и ключ подписи
+	SigningKey() (algorithm, kid string, key interface{})
+}
+
+// SigningKey возвращает HS256, идентификатор и текущий секрет
+func (k *Keyring) SigningKey() (string, string, interface{}) {
+	kid, secret := k.Current()
+	return AlgorithmHS256, kid, secret
+}
+
+// SigningKeys асимметричные ключи JWT: service_users подписывает токены текущим закрытым
+// ключом, а публичные ключи текущего и предыдущих публикуются в JWKS, чтобы Gateway
+// и другие сервисы проверяли токены без общего секрета. Предыдущие ключи остаются
+// в JWKS, пока не истекут подписанные ими токены
+type SigningKeys struct {
+	algorithm string
+	currentID string
+	current   crypto.Signer
+	// ids идентификаторы публичных ключей в порядке публикации: текущий первым
+	ids    []string
+	public map[string]crypto.PublicKey
+}
+
+// NewSigningKeys создает SigningKeys из PEM закрытого ключа current и PEM предыдущих
+// ключей previous (закрытых или публичных). Тип текущего ключа должен соответствовать
+// algorithm (RS256 — RSA от 2048 бит, ES256 — ECDSA P-256); предыдущие ключи могут
+// относиться к другому алгоритму на время смены алгоритма
+func NewSigningKeys(algorithm string, current []byte, previous [][]byte) (*SigningKeys, error) {
+	_, signer, err := parsePEM(current)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения текущего ключа: %v", err)
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("текущий ключ должен быть закрытым")
+	}
+	keyAlgorithm, err := publicKeyAlgorithm(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	if keyAlgorithm != algorithm {
+		return nil, fmt.Errorf("ключ подходит для %s, а не для %s", keyAlgorithm, algorithm)
+	}
+
+	keys := &SigningKeys{
+		algorithm: algorithm,
+		current:   signer,
+		public:    make(map[string]crypto.PublicKey, len(previous)+1),
+	}
+	if keys.currentID, err = keys.add(signer.Public()); err != nil {
+		return nil, err
+	}
+	for i, data := range previous {
+		public, _, err := parsePEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения предыдущего ключа %d: %v", i+1, err)
+		}
+		if _, err := publicKeyAlgorithm(public); err != nil {
+			return nil, fmt.Errorf("предыдущий ключ %d: %v", i+1, err)
+		}
+		if _, err := keys.add(public); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// add добавляет публичный ключ, пропуская повторы
+func (k *SigningKeys) add(public crypto.PublicKey) (string, error) {
+	kid, err := PublicKeyID(public)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := k.public[kid]; !ok {
+		k.ids = append(k.ids, kid)
+		k.public[kid] = public
+	}
+	return kid, nil
+}
+
+// SigningKey возвращает алгоритм, идентификатор и текущий закрытый ключ
+func (k *SigningKeys) SigningKey() (string, string, interface{}) {
+	return k.algorithm, k.currentID, k.current
+}
+
+// PublicKey возвращает публичный ключ по kid
+func (k *SigningKeys) PublicKey(kid string) (crypto.PublicKey, bool) {
+	public, ok := k.public[kid]
+	return public, ok
+}
+
+// JSONWebKey публичный ключ в формате JWK (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet набор ключей JWKS
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS возвращает публичные ключи для публикации, текущий первым
+func (k *SigningKeys) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(k.ids))}
+	for _, kid := range k.ids {
+		jwk := JSONWebKey{Kid: kid, Use: "sig"}
+		switch public := k.public[kid].(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.Alg = AlgorithmRS256
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case *ecdsa.PublicKey:
+			// Несжатая точка P-256: 0x04 || X || Y по 32 байта
+			point, err := public.ECDH()
+			if err != nil {
+				continue
+			}
+			raw := point.Bytes()
+			jwk.Kty = "EC"
+			jwk.Alg = AlgorithmES256
+			jwk.Crv = "P-256"
+			jwk.X = base64.RawURLEncoding.EncodeToString(raw[1:33])
+			jwk.Y = base64.RawURLEncoding.EncodeToString(raw[33:])
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// PublicKeyID возвращает идентификатор публичного ключа для заголовка kid: первые 8 байт
+// SHA-256 ключа в формате PKIX (DER) в hex, по аналогии с KeyID для секретов
+func PublicKeyID(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", fmt.Errorf("ошибка кодирования публичного ключа: %v", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// publicKeyAlgorithm возвращает алгоритм подписи, для которого подходит ключ
+func publicKeyAlgorithm(public crypto.PublicKey) (string, error) {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSABits {
+			return "", fmt.Errorf("ключ RSA должен быть не короче %d бит", minRSABits)
+		}
+		return AlgorithmRS256, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return "", fmt.Errorf("ключ ECDSA должен использовать кривую P-256")
+		}
+		return AlgorithmES256, nil
+	}
+	return "", fmt.Errorf("неподдерживаемый тип ключа %T: допустимы RSA и ECDSA P-256", public)
+}
+
+// parsePEM разбирает ключ в PEM: закрытый (PKCS #1, SEC 1, PKCS #8) или публичный
+// (PKIX, PKCS #1). Для публичного ключа signer равен nil
+func parsePEM(data []byte) (crypto.PublicKey, crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, fmt.Errorf("PEM блок не найден")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, nil, fmt.Errorf("неподдерживаемый тип PEM блока %q", block.Type)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return key.Public(), key, nil
+	case *ecdsa.PrivateKey:
+		return key.Public(), key, nil
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil, nil
+	}
+	return nil, nil, fmt.Errorf("неподдерживаемый тип ключа %T: допустимы RSA и ECDSA P-256", parsed)
+}
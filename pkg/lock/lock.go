@@ -0,0 +1,149 @@
+// Package lock распределенные блокировки для нескольких экземпляров сервисов:
+// рекомендательные блокировки PostgreSQL и блокировки Redis с продлением аренды.
+// Захваченная блокировка (Lease) продлевается в фоне, а ее контекст отменяется, если
+// блокировка потеряна, поэтому работа под блокировкой прерывается, а не продолжается
+// одновременно с другим экземпляром
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotAcquired возвращается TryAcquire, если блокировку держит другой владелец
+var ErrNotAcquired = errors.New("блокировка занята")
+
+// ErrLeaseLost причина отмены контекста Lease, если блокировку не удалось продлить
+var ErrLeaseLost = errors.New("блокировка потеряна")
+
+// errReleased причина отмены контекста Lease при Release
+var errReleased = errors.New("блокировка освобождена")
+
+// releaseTimeout ограничивает освобождение блокировки: Release вызывается и после
+// отмены контекста работы, поэтому использует собственный таймаут
+const releaseTimeout = 5 * time.Second
+
+// Locker захватывает блокировки по ключу
+type Locker interface {
+	// TryAcquire захватывает блокировку key без ожидания. Возвращает ErrNotAcquired,
+	// если блокировка занята. Контекст Lease наследует ctx
+	TryAcquire(ctx context.Context, key string) (*Lease, error)
+}
+
+// Acquire ожидает блокировку key, повторяя попытку раз в retry, до отмены ctx
+func Acquire(ctx context.Context, locker Locker, key string, retry time.Duration) (*Lease, error) {
+	for {
+		lease, err := locker.TryAcquire(ctx, key)
+		if !errors.Is(err, ErrNotAcquired) {
+			return lease, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(retry):
+		}
+	}
+}
+
+// Leader выполняет run, пока экземпляр владеет блокировкой key, чтобы фоновую задачу
+// выполнял только один экземпляр сервиса. Остальные пытаются захватить блокировку раз
+// в retry, в том числе при недоступности хранилища блокировок. run получает контекст,
+// отменяемый при потере блокировки; после возврата run блокировка освобождается.
+// Leader возвращается после отмены ctx
+func Leader(ctx context.Context, locker Locker, key string, retry time.Duration, run func(ctx context.Context)) {
+	for ctx.Err() == nil {
+		lease, err := locker.TryAcquire(ctx, key)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(retry):
+			}
+			continue
+		}
+
+		run(lease.Context())
+		lease.Release()
+	}
+}
+
+// Lease захваченная блокировка. Освобождается вызовом Release
+type Lease struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	release func(ctx context.Context) error
+	// stopped закрывается по завершении продления
+	stopped chan struct{}
+
+	once sync.Once
+	err  error
+}
+
+// newLease запускает продление блокировки renew раз в every. Ошибка renew означает
+// потерю блокировки и отменяет контекст Lease с причиной ErrLeaseLost
+func newLease(parent context.Context, every time.Duration, renew, release func(ctx context.Context) error) *Lease {
+	ctx, cancel := context.WithCancelCause(parent)
+	lease := &Lease{
+		ctx:     ctx,
+		cancel:  cancel,
+		release: release,
+		stopped: make(chan struct{}),
+	}
+	go lease.keepAlive(every, renew)
+	return lease
+}
+
+// keepAlive продлевает блокировку до отмены контекста Lease
+func (l *Lease) keepAlive(every time.Duration, renew func(ctx context.Context) error) {
+	defer close(l.stopped)
+
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		renewCtx, cancel := context.WithTimeout(l.ctx, every)
+		err := renew(renewCtx)
+		cancel()
+		if err != nil && l.ctx.Err() == nil {
+			l.cancel(fmt.Errorf("%w: %v", ErrLeaseLost, err))
+			return
+		}
+	}
+}
+
+// Context возвращает контекст работы под блокировкой. Он отменяется при потере
+// блокировки, вызове Release или отмене контекста, переданного TryAcquire
+func (l *Lease) Context() context.Context {
+	return l.ctx
+}
+
+// Err возвращает ErrLeaseLost с причиной, если блокировку не удалось продлить, иначе nil
+func (l *Lease) Err() error {
+	if cause := context.Cause(l.ctx); errors.Is(cause, ErrLeaseLost) {
+		return cause
+	}
+	return nil
+}
+
+// Release останавливает продление и освобождает блокировку. Повторные вызовы
+// возвращают результат первого
+func (l *Lease) Release() error {
+	l.once.Do(func() {
+		l.cancel(errReleased)
+		<-l.stopped
+
+		ctx, cancel := context.WithTimeout(context.Background(), releaseTimeout)
+		defer cancel()
+		l.err = l.release(ctx)
+	})
+	return l.err
+}
@@ -0,0 +1,71 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// Пространства ключей рекомендательных блокировок PostgreSQL (первый аргумент
+// pg_try_advisory_lock). База общая для сервисов, поэтому пространства перечислены здесь
+const (
+	// NamespaceExports блокировки выгрузок пользователя в service_users и service_orders
+	NamespaceExports int32 = 21001
+	// NamespaceJobs блокировки фоновых задач, выполняемых одним экземпляром сервиса
+	NamespaceJobs int32 = 21002
+)
+
+// postgresCheckInterval период проверки соединения, на котором держится блокировка
+const postgresCheckInterval = 10 * time.Second
+
+// Postgres блокировки на сессионных рекомендательных блокировках PostgreSQL. Каждая
+// блокировка занимает отдельное соединение пула до Release и снимается сервером при
+// обрыве соединения, поэтому аренда не истекает по времени: продление лишь проверяет,
+// что соединение живо
+type Postgres struct {
+	db        *sql.DB
+	namespace int32
+}
+
+// NewPostgres создает блокировки в пространстве namespace; ключ блокировки —
+// hashtext(key)
+func NewPostgres(db *sql.DB, namespace int32) *Postgres {
+	return &Postgres{db: db, namespace: namespace}
+}
+
+// TryAcquire захватывает блокировку key без ожидания
+func (p *Postgres) TryAcquire(ctx context.Context, key string) (*Lease, error) {
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения соединения с БД: %v", err)
+	}
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))", p.namespace, key).Scan(&locked)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ошибка захвата блокировки %s: %v", key, err)
+	}
+	if !locked {
+		conn.Close()
+		return nil, ErrNotAcquired
+	}
+
+	check := func(ctx context.Context) error {
+		_, err := conn.ExecContext(ctx, "SELECT 1")
+		return err
+	}
+	release := func(ctx context.Context) error {
+		defer conn.Close()
+		_, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1, hashtext($2))", p.namespace, key)
+		if err != nil {
+			// Соединение с неснятой блокировкой закрывается, а не возвращается в пул
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			return fmt.Errorf("ошибка освобождения блокировки %s: %v", key, err)
+		}
+		return nil
+	}
+	return newLease(ctx, postgresCheckInterval, check, release), nil
+}
@@ -0,0 +1,86 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// extend продлевает блокировку, только если она все еще принадлежит владельцу
+var extend = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// unlock удаляет блокировку, только если она все еще принадлежит владельцу:
+// истекшую и занятую другим экземпляром блокировку удалять нельзя
+var unlock = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// errNotOwner блокировка истекла или занята другим владельцем
+var errNotOwner = errors.New("блокировка истекла или принадлежит другому владельцу")
+
+// Redis блокировки в Redis: ключ с владельцем и сроком ttl, который продлевается
+// каждую треть ttl. Если продлить не удалось, блокировка считается потерянной
+type Redis struct {
+	client redis.UniversalClient
+	prefix string
+	ttl    time.Duration
+}
+
+// NewRedis создает блокировки с ключами prefix+key и сроком аренды ttl
+func NewRedis(client redis.UniversalClient, prefix string, ttl time.Duration) *Redis {
+	return &Redis{client: client, prefix: prefix, ttl: ttl}
+}
+
+// TryAcquire захватывает блокировку key без ожидания
+func (r *Redis) TryAcquire(ctx context.Context, key string) (*Lease, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("ошибка генерации владельца блокировки: %v", err)
+	}
+	owner := hex.EncodeToString(buf)
+	lockKey := r.prefix + key
+
+	acquired, err := r.client.SetNX(ctx, lockKey, owner, r.ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка захвата блокировки %s: %v", key, err)
+	}
+	if !acquired {
+		return nil, ErrNotAcquired
+	}
+
+	renew := func(ctx context.Context) error {
+		extended, err := extend.Run(ctx, r.client, []string{lockKey}, owner, r.ttl.Milliseconds()).Int()
+		if err != nil {
+			return err
+		}
+		if extended == 0 {
+			return errNotOwner
+		}
+		return nil
+	}
+	release := func(ctx context.Context) error {
+		if err := unlock.Run(ctx, r.client, []string{lockKey}, owner).Err(); err != nil {
+			return fmt.Errorf("ошибка освобождения блокировки %s: %v", key, err)
+		}
+		return nil
+	}
+
+	every := r.ttl / 3
+	if every <= 0 {
+		every = time.Millisecond
+	}
+	return newLease(ctx, every, renew, release), nil
+}
@@ -0,0 +1,157 @@
+// Package profile описывает профили окружений (development, test, staging, production).
+// Профиль задает значения по умолчанию для настроек безопасности — CORS, ограничения
+// частоты запросов, время жизни токенов и отладочные эндпоинты — и выбирается
+// переменной ENVIRONMENT. Строгие профили запрещают переопределять эти настройки
+// значениями, допустимыми только при разработке
+package profile
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Имена профилей
+const (
+	Development = "development"
+	Test        = "test"
+	Staging     = "staging"
+	Production  = "production"
+)
+
+// Profile набор настроек окружения
+type Profile struct {
+	Name string
+	// CORSAllowedOrigins разрешенные источники; пусто — список обязателен в конфигурации
+	CORSAllowedOrigins []string
+	RateLimitRPS       float64
+	RateLimitBurst     int
+	AccessTokenTTL     time.Duration
+	RefreshTokenTTL    time.Duration
+	// DebugEndpoints открывать /debug/pprof на API Gateway
+	DebugEndpoints bool
+	// APIDocs открывать Swagger UI /docs на API Gateway
+	APIDocs bool
+	// HSTSMaxAge срок Strict-Transport-Security; 0 — заголовок не отправляется,
+	// чтобы браузер не запоминал HTTPS для localhost при разработке
+	HSTSMaxAge time.Duration
+	// Strict запрещает небезопасные переопределения (см. Check*)
+	Strict bool
+}
+
+var profiles = map[string]Profile{
+	Development: {
+		Name:               Development,
+		CORSAllowedOrigins: []string{"*"},
+		RateLimitRPS:       10,
+		RateLimitBurst:     20,
+		AccessTokenTTL:     24 * time.Hour,
+		RefreshTokenTTL:    720 * time.Hour,
+		DebugEndpoints:     true,
+		APIDocs:            true,
+	},
+	Test: {
+		Name:               Test,
+		CORSAllowedOrigins: []string{"*"},
+		RateLimitRPS:       100,
+		RateLimitBurst:     200,
+		AccessTokenTTL:     24 * time.Hour,
+		RefreshTokenTTL:    720 * time.Hour,
+		APIDocs:            true,
+	},
+	Staging: {
+		Name:            Staging,
+		RateLimitRPS:    5,
+		RateLimitBurst:  10,
+		AccessTokenTTL:  time.Hour,
+		RefreshTokenTTL: 168 * time.Hour,
+		HSTSMaxAge:      24 * time.Hour,
+		APIDocs:         true,
+		Strict:          true,
+	},
+	Production: {
+		Name:            Production,
+		RateLimitRPS:    5,
+		RateLimitBurst:  10,
+		AccessTokenTTL:  time.Hour,
+		RefreshTokenTTL: 720 * time.Hour,
+		HSTSMaxAge:      8760 * time.Hour,
+		Strict:          true,
+	},
+}
+
+// Get возвращает профиль по имени окружения
+func Get(name string) (Profile, error) {
+	p, ok := profiles[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Profile{}, fmt.Errorf("неизвестное окружение %q, допустимые значения: %s", name, strings.Join(Names(), ", "))
+	}
+	p.CORSAllowedOrigins = append([]string(nil), p.CORSAllowedOrigins...)
+	return p, nil
+}
+
+// Names возвращает имена всех профилей
+func Names() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckCORSOrigins проверяет список разрешенных источников: строгий профиль требует
+// явный список без "*"
+func (p Profile) CheckCORSOrigins(origins []string) error {
+	if !p.Strict {
+		return nil
+	}
+	if len(origins) == 0 {
+		return fmt.Errorf("окружение %s требует явный список CORS источников", p.Name)
+	}
+	for _, origin := range origins {
+		if strings.Contains(origin, "*") {
+			return fmt.Errorf("окружение %s не допускает CORS источник %q", p.Name, origin)
+		}
+	}
+	return nil
+}
+
+// CheckDebugEndpoints запрещает отладочные эндпоинты в строгом профиле
+func (p Profile) CheckDebugEndpoints(enabled bool) error {
+	if p.Strict && enabled {
+		return fmt.Errorf("окружение %s не допускает отладочные эндпоинты", p.Name)
+	}
+	return nil
+}
+
+// CheckTokenTTL запрещает в строгом профиле время жизни токенов больше заданного профилем
+func (p Profile) CheckTokenTTL(access, refresh time.Duration) error {
+	if !p.Strict {
+		return nil
+	}
+	if access > p.AccessTokenTTL {
+		return fmt.Errorf("окружение %s допускает время жизни access токена не более %v", p.Name, p.AccessTokenTTL)
+	}
+	if refresh > p.RefreshTokenTTL {
+		return fmt.Errorf("окружение %s допускает время жизни refresh токена не более %v", p.Name, p.RefreshTokenTTL)
+	}
+	return nil
+}
+
+// CheckSecret запрещает в строгом профиле секрет JWT по умолчанию
+func (p Profile) CheckSecret(secret, insecureDefault string) error {
+	if p.Strict && (secret == "" || secret == insecureDefault) {
+		return fmt.Errorf("окружение %s требует задать JWT_SECRET", p.Name)
+	}
+	return nil
+}
+
+// CheckCookieSecure запрещает в строгом профиле cookie без флага Secure
+func (p Profile) CheckCookieSecure(secure bool) error {
+	if p.Strict && !secure {
+		return fmt.Errorf("окружение %s требует Secure cookie", p.Name)
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+// Package redact частичное маскирование персональных данных в JSON ответах.
+// Используется административными endpoints просмотра для поддержки: обработчик
+// формирует полный ответ, а Handler маскирует поля с персональными данными. Раскрытие
+// без маскирования запрашивается параметрами reveal и reason (RevealRequested);
+// право на раскрытие и его аудит проверяет сервис
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"pkg/httpresp"
+)
+
+// HeaderRedacted заголовок ответа со значением true, если персональные данные замаскированы
+const HeaderRedacted = "X-PII-Redacted"
+
+// mask заменитель скрытой части значения
+const mask = "***"
+
+// MaxReasonLength максимальная длина причины раскрытия
+const MaxReasonLength = 500
+
+// ErrRevealReason запрос раскрытия без причины или со слишком длинной причиной
+var ErrRevealReason = errors.New("для раскрытия персональных данных укажите причину в параметре reason (до 500 символов)")
+
+// Policy правила маскирования: имя поля JSON → функция маскирования его строкового
+// значения. Поле маскируется на любом уровне вложенности
+type Policy map[string]func(string) string
+
+// Default правила маскирования ответов для поддержки
+var Default = Policy{
+	"email":      Email,
+	"name":       Name,
+	"phone":      Phone,
+	"address":    Address,
+	"ip_address": IP,
+}
+
+// fallback тело ответа, если замаскировать ответ не удалось: исходный ответ не отправляется
+var fallback = []byte(`{"success":false,"error":{"code":"INTERNAL_SERVER_ERROR","message":"Ошибка маскирования ответа"}}`)
+
+// Email оставляет первый символ имени ящика и домен: i***@example.com
+func Email(value string) string {
+	at := strings.LastIndex(value, "@")
+	if at <= 0 {
+		return maskAll(value)
+	}
+	first, _ := utf8.DecodeRuneInString(value)
+	return string(first) + mask + value[at:]
+}
+
+// Name оставляет первую букву каждого слова: И*** П***
+func Name(value string) string {
+	words := strings.Fields(value)
+	for i, word := range words {
+		first, _ := utf8.DecodeRuneInString(word)
+		words[i] = string(first) + mask
+	}
+	return strings.Join(words, " ")
+}
+
+// Phone скрывает все цифры, кроме двух последних, сохраняя разделители: +* *** ***-**-89
+func Phone(value string) string {
+	digits := 0
+	for _, r := range value {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range value {
+		if unicode.IsDigit(r) {
+			digits--
+			if digits >= 2 {
+				r = '*'
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Address оставляет первую часть адреса до запятой (обычно город): Москва, ***
+func Address(value string) string {
+	comma := strings.Index(value, ",")
+	if comma <= 0 {
+		return maskAll(value)
+	}
+	return value[:comma] + ", " + mask
+}
+
+// IP оставляет сеть адреса: два первых октета IPv4 (203.0.*.*) или две первые группы IPv6
+func IP(value string) string {
+	ip := net.ParseIP(value)
+	switch {
+	case ip == nil:
+		return maskAll(value)
+	case ip.To4() != nil:
+		parts := strings.Split(ip.To4().String(), ".")
+		return parts[0] + "." + parts[1] + ".*.*"
+	default:
+		parts := strings.Split(ip.String(), ":")
+		return parts[0] + ":" + parts[1] + "::*"
+	}
+}
+
+// maskAll скрывает непустое значение целиком
+func maskAll(value string) string {
+	if value == "" {
+		return ""
+	}
+	return mask
+}
+
+// Transform маскирует строковые значения полей политики в JSON документе body
+func (p Policy) Transform(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(p.walk(document))
+}
+
+// walk маскирует значение и вложенные в него объекты и массивы
+func (p Policy) walk(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if masker, ok := p[key]; ok {
+				if s, ok := field.(string); ok && s != "" {
+					v[key] = masker(s)
+					continue
+				}
+			}
+			v[key] = p.walk(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = p.walk(item)
+		}
+	}
+	return value
+}
+
+// RevealRequested разбирает запрос раскрытия персональных данных: reveal=true и
+// непустая причина reason. Без reveal возвращает requested=false
+func RevealRequested(r *http.Request) (reason string, requested bool, err error) {
+	query := r.URL.Query()
+	if query.Get("reveal") != "true" {
+		return "", false, nil
+	}
+	reason = strings.TrimSpace(query.Get("reason"))
+	if reason == "" || utf8.RuneCountInString(reason) > MaxReasonLength {
+		return "", true, ErrRevealReason
+	}
+	return reason, true, nil
+}
+
+// Handler возвращает обработчик, маскирующий успешные JSON ответы next по политике p.
+// Если замаскировать ответ не удалось, клиент получает 500 вместо исходного ответа
+func (p Policy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		for key, values := range recorder.header {
+			w.Header()[key] = values
+		}
+		w.Header().Del("Content-Length")
+
+		body := recorder.body.Bytes()
+		if recorder.status < 200 || recorder.status >= 300 || !isJSON(recorder.header.Get("Content-Type")) {
+			w.WriteHeader(recorder.status)
+			w.Write(body)
+			return
+		}
+
+		masked, err := p.Transform(body)
+		if err != nil {
+			httpresp.Write(w, http.StatusInternalServerError, httpresp.ContentTypeJSON, fallback)
+			return
+		}
+		w.Header().Set(HeaderRedacted, "true")
+		httpresp.Write(w, recorder.status, recorder.header.Get("Content-Type"), masked)
+	})
+}
+
+// isJSON проверяет, что тип содержимого — JSON
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == httpresp.ContentTypeJSON || strings.HasSuffix(mediaType, "+json"))
+}
+
+// responseRecorder накапливает ответ обработчика для маскирования
+type responseRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.wroteHeader = true
+	r.status = code
+}
+
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(p)
+}
@@ -0,0 +1,84 @@
+// Package rolesepoch хранит в Redis эпоху ролей пользователя — счетчик, который
+// service_users увеличивает при изменении ролей или блокировке. Access токен содержит
+// эпоху на момент выдачи, и API Gateway отклоняет токены с устаревшей эпохой,
+// поэтому изменения ролей вступают в силу за секунды, а не по истечении токена
+package rolesepoch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix префикс ключей эпох в Redis
+const keyPrefix = "auth:roles_epoch:"
+
+// setIfGreater записывает эпоху, только если она больше сохраненной: при одновременных
+// изменениях ролей более старое значение не перезапишет новое
+var setIfGreater = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if tonumber(ARGV[1]) > current then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// Store хранилище эпох ролей
+type Store struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+}
+
+// NewStore создает хранилище. ttl должен быть не меньше времени жизни access токена:
+// после истечения ключа все токены, выданные до изменения ролей, уже недействительны
+func NewStore(client redis.UniversalClient, ttl time.Duration) *Store {
+	return &Store{client: client, ttl: ttl}
+}
+
+// NewRedisClient создает клиент Redis с единым таймаутом на подключение и операции
+func NewRedisClient(addr, password string, db int, timeout time.Duration) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:         addr,
+		Password:     password,
+		DB:           db,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+	})
+}
+
+// Publish сохраняет новую эпоху ролей пользователя
+func (s *Store) Publish(ctx context.Context, userID string, epoch int64) error {
+	err := setIfGreater.Run(ctx, s.client, []string{keyPrefix + userID}, epoch, s.ttl.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения эпохи ролей: %v", err)
+	}
+	return nil
+}
+
+// Get возвращает текущую эпоху ролей пользователя; 0, если роли не изменялись
+// в течение времени жизни токена
+func (s *Store) Get(ctx context.Context, userID string) (int64, error) {
+	value, err := s.client.Get(ctx, keyPrefix+userID).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения эпохи ролей: %v", err)
+	}
+
+	epoch, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("некорректная эпоха ролей %q: %v", value, err)
+	}
+	return epoch, nil
+}
+
+// Close закрывает соединения с Redis
+func (s *Store) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,177 @@
+// Package servertiming собирает длительность этапов обработки запроса (ожидание,
+// обработчик, БД, публикация событий) и передает ее между сервисами в заголовке
+// Server-Timing (W3C). API Gateway передает сервису момент отправки запроса
+// в X-Request-Start и по ответу сервиса определяет, на каком участке пути
+// был потрачен бюджет времени медленного запроса
+package servertiming
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Header заголовок ответа с длительностью этапов
+const Header = "Server-Timing"
+
+// HeaderRequestStart заголовок запроса с моментом его отправки: t=<микросекунды Unix>
+const HeaderRequestStart = "X-Request-Start"
+
+// Этапы обработки запроса в сервисе
+const (
+	// MetricWait время от отправки запроса шлюзом до начала обработки в сервисе
+	// (сеть и очередь приема соединений); зависит от синхронизации часов
+	MetricWait = "wait"
+	// MetricApp время обработки запроса в сервисе до начала ответа, включая db и publish
+	MetricApp = "app"
+	// MetricDB суммарное время запросов к БД
+	MetricDB = "db"
+	// MetricPublish суммарное время публикации событий
+	MetricPublish = "publish"
+)
+
+// Metric длительность этапа
+type Metric struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Recorder накапливает длительность этапов одного запроса. Повторные замеры этапа
+// суммируются. Методы безопасны для nil Recorder и параллельного вызова
+type Recorder struct {
+	mutex     sync.Mutex
+	names     []string
+	durations map[string]time.Duration
+}
+
+// NewRecorder создает пустой Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{durations: make(map[string]time.Duration)}
+}
+
+// Add добавляет duration к этапу name
+func (r *Recorder) Add(name string, duration time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.durations[name]; !ok {
+		r.names = append(r.names, name)
+	}
+	r.durations[name] += duration
+}
+
+// Start начинает замер этапа name; возвращаемая функция завершает его:
+//
+//	defer recorder.Start(servertiming.MetricDB)()
+func (r *Recorder) Start(name string) func() {
+	if r == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		r.Add(name, time.Since(start))
+	}
+}
+
+// Get возвращает накопленную длительность этапа name
+func (r *Recorder) Get(name string) time.Duration {
+	if r == nil {
+		return 0
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.durations[name]
+}
+
+// Metrics возвращает этапы в порядке первого замера
+func (r *Recorder) Metrics() []Metric {
+	if r == nil {
+		return nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	metrics := make([]Metric, 0, len(r.names))
+	for _, name := range r.names {
+		metrics = append(metrics, Metric{Name: name, Duration: r.durations[name]})
+	}
+	return metrics
+}
+
+type recorderKey struct{}
+
+// NewContext возвращает контекст с Recorder запроса
+func NewContext(ctx context.Context, recorder *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, recorder)
+}
+
+// FromContext возвращает Recorder запроса или nil; nil Recorder ничего не учитывает
+func FromContext(ctx context.Context) *Recorder {
+	recorder, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return recorder
+}
+
+// Format формирует значение Server-Timing: "db;dur=12.5, app;dur=40.1" (миллисекунды)
+func Format(metrics []Metric) string {
+	parts := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		parts = append(parts, fmt.Sprintf("%s;dur=%s", metric.Name, formatMS(metric.Duration)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Parse разбирает значение Server-Timing. Параметры, кроме dur, игнорируются;
+// этап без dur имеет нулевую длительность, некорректные элементы пропускаются
+func Parse(value string) []Metric {
+	var metrics []Metric
+	for _, part := range strings.Split(value, ",") {
+		params := strings.Split(part, ";")
+		name := strings.TrimSpace(params[0])
+		if name == "" || strings.ContainsAny(name, " \t\"=") {
+			continue
+		}
+
+		metric := Metric{Name: name}
+		for _, param := range params[1:] {
+			key, raw, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(key), "dur") {
+				continue
+			}
+			ms, err := strconv.ParseFloat(strings.Trim(strings.TrimSpace(raw), `"`), 64)
+			if err == nil && ms >= 0 {
+				metric.Duration = time.Duration(ms * float64(time.Millisecond))
+			}
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
+
+// FormatRequestStart формирует значение X-Request-Start для момента t
+func FormatRequestStart(t time.Time) string {
+	return "t=" + strconv.FormatInt(t.UnixMicro(), 10)
+}
+
+// ParseRequestStart разбирает значение X-Request-Start
+func ParseRequestStart(value string) (time.Time, bool) {
+	raw, ok := strings.CutPrefix(strings.TrimSpace(value), "t=")
+	if !ok {
+		return time.Time{}, false
+	}
+	micros, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || micros <= 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMicro(micros), true
+}
+
+// formatMS форматирует длительность в миллисекундах с точностью до микросекунды
+func formatMS(duration time.Duration) string {
+	return strconv.FormatFloat(float64(duration.Microseconds())/1000, 'f', -1, 64)
+}
@@ -0,0 +1,63 @@
+// Package timeutil задает единые правила работы со временем в сервисах: все
+// метки времени сохраняются и отдаются в API в UTC с явным смещением, а часовой
+// пояс пользователя применяется только при представлении (выгрузки, счета)
+package timeutil
+
+import (
+	"fmt"
+	"time"
+	// встроенная база часовых поясов: в образах сервисов нет tzdata
+	_ "time/tzdata"
+)
+
+// DefaultTimezone часовой пояс пользователя по умолчанию
+const DefaultTimezone = "UTC"
+
+// Now возвращает текущее время в UTC, усеченное до микросекунд — точности
+// TIMESTAMP WITH TIME ZONE в PostgreSQL, чтобы значение после записи и чтения
+// из БД совпадало с исходным
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// LoadLocation возвращает часовой пояс по имени IANA (например, Europe/Moscow).
+// Пустое имя соответствует DefaultTimezone
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		name = DefaultTimezone
+	}
+	// time.LoadLocation принимает "Local", но часовой пояс сервера не должен
+	// влиять на представление данных пользователя
+	if name == "Local" {
+		return nil, fmt.Errorf("неизвестный часовой пояс: %s", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("неизвестный часовой пояс: %s", name)
+	}
+	return loc, nil
+}
+
+// IsValidTimezone проверяет, что имя является известным часовым поясом IANA
+func IsValidTimezone(name string) bool {
+	if name == "" {
+		return false
+	}
+	_, err := LoadLocation(name)
+	return err == nil
+}
+
+// In переводит время в часовой пояс пользователя для представления.
+// При неизвестном часовом поясе время возвращается в UTC
+func In(t time.Time, timezone string) time.Time {
+	loc, err := LoadLocation(timezone)
+	if err != nil {
+		return t.UTC()
+	}
+	return t.In(loc)
+}
+
+// Format форматирует время в часовом поясе пользователя в RFC 3339 с явным смещением
+func Format(t time.Time, timezone string) string {
+	return In(t, timezone).Format(time.RFC3339)
+}
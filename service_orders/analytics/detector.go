@@ -0,0 +1,190 @@
+// Package analytics отслеживает бизнес-метрики в скользящем окне: частоту создания
+// заказов, долю отмен и долю неудачных входов. Выход метрики за заданные границы
+// публикуется событием alert.<метрика>, по которому отправляются уведомления
+package analytics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"service_orders/events"
+	"service_orders/logger"
+
+	"go.uber.org/zap"
+)
+
+// Metric бизнес-метрика детектора
+type Metric string
+
+const (
+	// MetricOrderCreationRate заказов в час
+	MetricOrderCreationRate Metric = "order_creation_rate"
+	// MetricCancellationRate доля отмен к созданным заказам (0..1)
+	MetricCancellationRate Metric = "cancellation_rate"
+	// MetricLoginFailureRate доля неудачных попыток входа (0..1)
+	MetricLoginFailureRate Metric = "login_failure_rate"
+)
+
+// Rule границы значения метрики
+type Rule struct {
+	Metric Metric
+	// Min, Max допустимые границы; 0 — граница не задана
+	Min float64
+	Max float64
+	// MinSamples минимальное число событий в окне для долей: при малой выборке
+	// одна отмена или ошибка входа не должна вызывать оповещение
+	MinSamples int
+}
+
+// Config настройки детектора
+type Config struct {
+	// Window длительность скользящего окна
+	Window time.Duration
+	// Interval период проверки
+	Interval time.Duration
+	// Cooldown пауза между повторными оповещениями по метрике, пока аномалия сохраняется
+	Cooldown time.Duration
+	Rules    []Rule
+}
+
+// AlertPublisher публикует события об аномалиях; реализуется events.EventService
+type AlertPublisher interface {
+	PublishAlert(ctx context.Context, data events.AlertEventData) error
+}
+
+// Detector периодически сравнивает метрики с границами правил
+type Detector struct {
+	source    Source
+	publisher AlertPublisher
+	config    Config
+
+	mutex sync.Mutex
+	// alerted время последнего оповещения по метрике, пока аномалия сохраняется
+	alerted map[Metric]time.Time
+}
+
+// NewDetector создает Detector
+func NewDetector(source Source, publisher AlertPublisher, config Config) *Detector {
+	return &Detector{
+		source:    source,
+		publisher: publisher,
+		config:    config,
+		alerted:   make(map[Metric]time.Time),
+	}
+}
+
+// Run проверяет метрики каждые Interval, пока не будет отменен ctx
+func (d *Detector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.checkAndPublish(ctx, time.Now().UTC())
+		}
+	}
+}
+
+// checkAndPublish публикует аномалии, по которым не действует пауза между оповещениями
+func (d *Detector) checkAndPublish(ctx context.Context, now time.Time) {
+	anomalies, err := d.Check(ctx, now)
+	if err != nil {
+		logger.GetLogger().Warn("Ошибка проверки бизнес-метрик", zap.Error(err))
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	active := make(map[Metric]bool, len(anomalies))
+	for _, anomaly := range anomalies {
+		metric := Metric(anomaly.Metric)
+		active[metric] = true
+		if last, ok := d.alerted[metric]; ok && now.Sub(last) < d.config.Cooldown {
+			continue
+		}
+
+		logger.GetLogger().Warn("Аномалия бизнес-метрики",
+			zap.String("metric", anomaly.Metric),
+			zap.Float64("value", anomaly.Value),
+			zap.String("bound", anomaly.Bound),
+			zap.Float64("threshold", anomaly.Threshold),
+			zap.Int("samples", anomaly.Samples),
+		)
+		if err := d.publisher.PublishAlert(ctx, anomaly); err != nil {
+			logger.GetLogger().Error("Не удалось опубликовать событие об аномалии",
+				zap.String("metric", anomaly.Metric), zap.Error(err))
+			continue
+		}
+		d.alerted[metric] = now
+	}
+
+	// Метрика вернулась в норму: следующая аномалия оповещается сразу
+	for metric := range d.alerted {
+		if !active[metric] {
+			delete(d.alerted, metric)
+		}
+	}
+}
+
+// Check рассчитывает метрики за окно, заканчивающееся в now, и возвращает
+// нарушения границ правил
+func (d *Detector) Check(ctx context.Context, now time.Time) ([]events.AlertEventData, error) {
+	counts, err := d.source.Counts(ctx, now.Add(-d.config.Window), now)
+	if err != nil {
+		return nil, err
+	}
+
+	var anomalies []events.AlertEventData
+	for _, rule := range d.config.Rules {
+		value, samples, ok := d.value(rule, counts)
+		if !ok {
+			continue
+		}
+
+		anomaly := events.AlertEventData{
+			Metric:     string(rule.Metric),
+			Value:      value,
+			Window:     d.config.Window.String(),
+			Samples:    samples,
+			DetectedAt: now,
+		}
+		switch {
+		case rule.Max > 0 && value > rule.Max:
+			anomaly.Bound, anomaly.Threshold = "max", rule.Max
+		case rule.Min > 0 && value < rule.Min:
+			anomaly.Bound, anomaly.Threshold = "min", rule.Min
+		default:
+			continue
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies, nil
+}
+
+// value возвращает значение метрики правила и размер выборки; ok = false,
+// если событий в окне меньше MinSamples и доля не показательна
+func (d *Detector) value(rule Rule, counts Counts) (float64, int, bool) {
+	switch rule.Metric {
+	case MetricOrderCreationRate:
+		return float64(counts.OrdersCreated) / d.config.Window.Hours(), counts.OrdersCreated, true
+	case MetricCancellationRate:
+		return ratio(counts.OrdersCancelled, counts.OrdersCreated, rule.MinSamples)
+	case MetricLoginFailureRate:
+		return ratio(counts.LoginFailures, counts.LoginAttempts, rule.MinSamples)
+	default:
+		return 0, 0, false
+	}
+}
+
+// ratio возвращает долю part от total, если total не меньше minSamples
+func ratio(part, total, minSamples int) (float64, int, bool) {
+	if total == 0 || total < minSamples {
+		return 0, total, false
+	}
+	return float64(part) / float64(total), total, true
+}
@@ -0,0 +1,58 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"service_orders/models"
+)
+
+// Counts счетчики бизнес-событий за окно
+type Counts struct {
+	OrdersCreated   int
+	OrdersCancelled int
+	LoginAttempts   int
+	LoginFailures   int
+}
+
+// Source источник счетчиков за период [since, until)
+type Source interface {
+	Counts(ctx context.Context, since, until time.Time) (Counts, error)
+}
+
+// SQLSource считает события по общей базе данных: заказы — по таблице orders,
+// попытки входа — по таблице login_attempts, которую ведет service_users
+type SQLSource struct {
+	db *sql.DB
+}
+
+// NewSQLSource создает SQLSource
+func NewSQLSource(db *sql.DB) *SQLSource {
+	return &SQLSource{db: db}
+}
+
+// Counts возвращает счетчики за период. Отмена учитывается по времени последнего
+// изменения заказа: отмененный заказ больше не изменяется
+func (s *SQLSource) Counts(ctx context.Context, since, until time.Time) (Counts, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM orders WHERE created_at >= $1 AND created_at < $2),
+			(SELECT COUNT(*) FROM orders WHERE status = $3 AND updated_at >= $1 AND updated_at < $2),
+			(SELECT COUNT(*) FROM login_attempts WHERE attempted_at >= $1 AND attempted_at < $2),
+			(SELECT COUNT(*) FROM login_attempts WHERE NOT success AND attempted_at >= $1 AND attempted_at < $2)
+	`
+
+	var counts Counts
+	err := s.db.QueryRowContext(ctx, query, since, until, models.OrderStatusCancelled).Scan(
+		&counts.OrdersCreated,
+		&counts.OrdersCancelled,
+		&counts.LoginAttempts,
+		&counts.LoginFailures,
+	)
+	if err != nil {
+		return Counts{}, fmt.Errorf("ошибка расчета бизнес-метрик: %v", err)
+	}
+	return counts, nil
+}
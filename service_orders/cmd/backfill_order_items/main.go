@@ -0,0 +1,57 @@
+// Команда backfill_order_items переносит позиции существующих заказов из JSONB
+// колонки orders.items в таблицу order_items. Повторный запуск безопасен:
+// обрабатываются только заказы, у которых еще нет строк в order_items.
+//
+//	go run ./cmd/backfill_order_items -batch 500 -dry-run
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"log"
+
+	"service_orders/config"
+	"service_orders/repository"
+
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 500, "количество заказов в одной порции")
+	dryRun := flag.Bool("dry-run", false, "только подсчитать заказы и позиции без записи")
+	flag.Parse()
+
+	if *batchSize <= 0 {
+		log.Fatalf("Некорректный размер порции: %d", *batchSize)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+
+	db, err := sql.Open("postgres", cfg.DB.DSN())
+	if err != nil {
+		log.Fatalf("Ошибка подключения к базе данных: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Ошибка проверки подключения к БД: %v", err)
+	}
+
+	backfill := repository.NewOrderItemsBackfill(db)
+	result, err := backfill.Run(*batchSize, *dryRun, func(orderID uuid.UUID, err error) {
+		log.Printf("Заказ %s пропущен: некорректные позиции: %v", orderID, err)
+	})
+	if err != nil {
+		log.Fatalf("Ошибка переноса позиций (перенесено заказов: %d): %v", result.Orders, err)
+	}
+
+	mode := "перенесено"
+	if *dryRun {
+		mode = "будет перенесено"
+	}
+	log.Printf("Готово: %s заказов: %d, позиций: %d, пропущено: %d", mode, result.Orders, result.Items, result.Skipped)
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"service_orders/config"
+
+	"pkg/buildinfo"
+	"pkg/profile"
+	"pkg/rolesepoch"
+
+	"github.com/lib/pq"
+)
+
+// insecureDefaults значения секретов по умолчанию из конфигурации сервисов
+var insecureDefaults = map[string][]string{
+	"JWT_SECRET":  {"your_secret_key"},
+	"DB_PASSWORD": {"postgres", "1234"},
+}
+
+// migrationFile имя файла миграции database/migrations/NNN_*.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_.+\.sql$`)
+
+// firstVersionedMigration первая миграция, которая записывает свой номер в schema_version
+const firstVersionedMigration = 29
+
+// doctor выполняет проверки окружения
+type doctor struct {
+	// strict профиль окружения строгий (staging, production): отклонения от
+	// требований профиля считаются ошибками, а не предупреждениями
+	strict  bool
+	timeout time.Duration
+	client  *http.Client
+}
+
+// component компонент развертывания, версия которого проверяется по GET /version
+type component struct {
+	Name string
+	URL  string
+}
+
+// loadEnvFile загружает переменные из файла KEY=VALUE (формат config/environments/*.env).
+// Ссылки ${VAR} раскрываются из окружения, как в config/env_loader.go: незаданная
+// переменная дает пустое значение и отмечается проверкой env.required.
+// Уже заданные переменные окружения не переопределяются
+func loadEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: ожидается KEY=VALUE", path, line)
+		}
+		key = strings.TrimSpace(key)
+		value = os.ExpandEnv(strings.Trim(strings.TrimSpace(value), `"'`))
+		if _, exists := os.LookupEnv(key); !exists {
+			os.Setenv(key, value)
+		}
+	}
+	return scanner.Err()
+}
+
+// checkEnvironment проверяет, что ENVIRONMENT задает известный профиль.
+// Для неизвестного значения возвращается профиль production, чтобы остальные
+// проверки выполнялись по строгим правилам
+func checkEnvironment(name string) (profile.Profile, check) {
+	env, err := profile.Get(name)
+	if err != nil {
+		strictEnv, _ := profile.Get(profile.Production)
+		return strictEnv, check{Name: "environment", Status: statusFail, Detail: err.Error()}
+	}
+	return env, check{Name: "environment", Status: statusOK, Detail: "профиль " + env.Name}
+}
+
+// severity возвращает статус отклонения от требований: ошибка в строгом профиле,
+// предупреждение в остальных
+func (d *doctor) severity() status {
+	if d.strict {
+		return statusFail
+	}
+	return statusWarn
+}
+
+// checkRequiredEnv проверяет, что заданы переменные, которые должны отличаться
+// между окружениями и не должны браться из значений по умолчанию
+func (d *doctor) checkRequiredEnv() check {
+	required := []string{
+		"ENVIRONMENT", "JWT_SECRET", "DB_HOST", "DB_NAME", "DB_USER", "DB_PASSWORD",
+		"USERS_SERVICE_URL", "ORDERS_SERVICE_URL",
+	}
+	if d.strict {
+		required = append(required, "CORS_ALLOWED_ORIGINS", "REDIS_HOST")
+	}
+
+	var missing []string
+	for _, key := range required {
+		if strings.TrimSpace(os.Getenv(key)) == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return check{Name: "env.required", Status: d.severity(), Detail: "не заданы: " + strings.Join(missing, ", ")}
+	}
+	return check{Name: "env.required", Status: statusOK}
+}
+
+// checkSecrets проверяет, что секреты не оставлены со значениями по умолчанию.
+// В отчет попадают только имена переменных
+func (d *doctor) checkSecrets() check {
+	var insecure []string
+	keys := make([]string, 0, len(insecureDefaults))
+	for key := range insecureDefaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := os.Getenv(key)
+		for _, insecureValue := range insecureDefaults[key] {
+			if value == insecureValue {
+				insecure = append(insecure, key)
+				break
+			}
+		}
+	}
+
+	// Без отдельного TRACKING_TOKEN_SECRET ссылки отслеживания подписываются JWT_SECRET
+	tracking := os.Getenv("TRACKING_TOKEN_SECRET")
+	if tracking == "" || tracking == os.Getenv("JWT_SECRET") {
+		insecure = append(insecure, "TRACKING_TOKEN_SECRET")
+	}
+
+	if len(insecure) > 0 {
+		return check{Name: "secrets", Status: d.severity(), Detail: "значения по умолчанию или совпадают с JWT_SECRET: " + strings.Join(insecure, ", ")}
+	}
+	return check{Name: "secrets", Status: statusOK}
+}
+
+// checkOrdersConfig проверяет, что конфигурация service_orders загружается
+func (d *doctor) checkOrdersConfig() (*config.Config, check) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, check{Name: "config.service_orders", Status: statusFail, Detail: err.Error()}
+	}
+	return cfg, check{Name: "config.service_orders", Status: statusOK}
+}
+
+// checkDatabase проверяет подключение к БД и сравнивает примененные миграции
+// (таблица schema_version) с каталогом migrationsDir
+func (d *doctor) checkDatabase(ctx context.Context, cfg *config.Config, migrationsDir string) []check {
+	if cfg == nil {
+		return []check{
+			{Name: "database", Status: statusSkip, Detail: "конфигурация не загружена"},
+			{Name: "database.schema", Status: statusSkip, Detail: "конфигурация не загружена"},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	db, err := sql.Open("postgres", cfg.DB.DSN())
+	if err != nil {
+		return []check{
+			{Name: "database", Status: statusFail, Detail: err.Error()},
+			{Name: "database.schema", Status: statusSkip, Detail: "нет подключения к БД"},
+		}
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return []check{
+			{Name: "database", Status: statusFail, Detail: err.Error()},
+			{Name: "database.schema", Status: statusSkip, Detail: "нет подключения к БД"},
+		}
+	}
+	dbCheck := check{Name: "database", Status: statusOK, Detail: fmt.Sprintf("%s:%d/%s", cfg.DB.Host, cfg.DB.Port, cfg.DB.Name)}
+
+	return []check{dbCheck, d.checkSchema(ctx, db, migrationsDir)}
+}
+
+// checkSchema сравнивает версию схемы из schema_version с номерами миграций в каталоге
+func (d *doctor) checkSchema(ctx context.Context, db *sql.DB, migrationsDir string) check {
+	current, err := schemaVersion(ctx, db)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "42P01" {
+			return check{Name: "database.schema", Status: statusFail,
+				Detail: fmt.Sprintf("таблица schema_version отсутствует: миграции с %03d не применены", firstVersionedMigration)}
+		}
+		return check{Name: "database.schema", Status: statusFail, Detail: err.Error()}
+	}
+
+	expected, err := migrationVersions(migrationsDir)
+	if err != nil {
+		return check{Name: "database.schema", Status: statusWarn,
+			Detail: fmt.Sprintf("версия схемы %d, ожидаемая неизвестна: %v", current, err)}
+	}
+
+	var missing []string
+	latest := 0
+	for _, version := range expected {
+		if version > latest {
+			latest = version
+		}
+		// init.sql записывает только номер последней миграции, поэтому пропуски
+		// ниже текущей версии не считаются непримененными миграциями
+		if version >= firstVersionedMigration && version > current {
+			missing = append(missing, fmt.Sprintf("%03d", version))
+		}
+	}
+	if len(missing) > 0 {
+		return check{Name: "database.schema", Status: statusFail,
+			Detail: fmt.Sprintf("версия схемы %d, ожидается %d; не применены: %s", current, latest, strings.Join(missing, ", "))}
+	}
+	if current > latest {
+		return check{Name: "database.schema", Status: statusWarn,
+			Detail: fmt.Sprintf("версия схемы %d новее каталога миграций (%d)", current, latest)}
+	}
+	return check{Name: "database.schema", Status: statusOK, Detail: fmt.Sprintf("версия схемы %d", current)}
+}
+
+// schemaVersion возвращает номер последней примененной миграции из schema_version
+func schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// migrationVersions возвращает номера миграций из каталога dir по возрастанию
+func migrationVersions(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("в каталоге %s нет миграций", filepath.Clean(dir))
+	}
+	sort.Ints(versions)
+	return versions, nil
+}
+
+// checkEvents проверяет бэкенд событий EVENTS_PUBLISHER: для kafka — доступность
+// каждого брокера из KAFKA_BROKERS по TCP
+func (d *doctor) checkEvents() check {
+	publisher := strings.ToLower(getEnv("EVENTS_PUBLISHER", "inmemory"))
+	switch publisher {
+	case "inmemory":
+		return check{Name: "events.backend", Status: statusOK, Detail: "inmemory"}
+	case "kafka":
+	default:
+		return check{Name: "events.backend", Status: statusFail,
+			Detail: fmt.Sprintf("неизвестный EVENTS_PUBLISHER %q, допустимые значения: inmemory, kafka", publisher)}
+	}
+
+	var brokers []string
+	for _, broker := range strings.Split(os.Getenv("KAFKA_BROKERS"), ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	if len(brokers) == 0 {
+		return check{Name: "events.backend", Status: statusFail, Detail: "EVENTS_PUBLISHER=kafka требует KAFKA_BROKERS"}
+	}
+
+	var unreachable []string
+	for _, broker := range brokers {
+		conn, err := net.DialTimeout("tcp", broker, d.timeout)
+		if err != nil {
+			unreachable = append(unreachable, broker)
+			continue
+		}
+		conn.Close()
+	}
+	if len(unreachable) > 0 {
+		return check{Name: "events.backend", Status: statusFail, Detail: "kafka, недоступны брокеры: " + strings.Join(unreachable, ", ")}
+	}
+	return check{Name: "events.backend", Status: statusOK, Detail: "kafka, брокеров: " + strconv.Itoa(len(brokers))}
+}
+
+// checkRedis проверяет доступность Redis, если он настроен (REDIS_HOST)
+func (d *doctor) checkRedis(ctx context.Context) check {
+	host := strings.TrimSpace(os.Getenv("REDIS_HOST"))
+	if host == "" {
+		return check{Name: "redis", Status: statusSkip, Detail: "REDIS_HOST не задан"}
+	}
+	redisDB, err := strconv.Atoi(getEnv("REDIS_DB", "0"))
+	if err != nil {
+		return check{Name: "redis", Status: statusFail, Detail: fmt.Sprintf("invalid REDIS_DB: %v", err)}
+	}
+
+	addr := net.JoinHostPort(host, getEnv("REDIS_PORT", "6379"))
+	client := rolesepoch.NewRedisClient(addr, os.Getenv("REDIS_PASSWORD"), redisDB, d.timeout)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return check{Name: "redis", Status: statusFail, Detail: fmt.Sprintf("%s: %v", addr, err)}
+	}
+	return check{Name: "redis", Status: statusOK, Detail: addr}
+}
+
+// checkVersions запрашивает GET /version компонентов и записывает их версии в versions.
+// Недоступный компонент или разные версии компонентов — ошибка
+func (d *doctor) checkVersions(ctx context.Context, components []component, versions map[string]string) check {
+	var problems []string
+	distinct := make(map[string]bool)
+	for _, c := range components {
+		info, err := d.fetchVersion(ctx, c.URL)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s недоступен: %v", c.Name, err))
+			continue
+		}
+		versions[c.Name] = info.Version
+		distinct[info.Version] = true
+	}
+
+	if len(distinct) > 1 {
+		parts := make([]string, 0, len(components))
+		for _, c := range components {
+			if version, ok := versions[c.Name]; ok {
+				parts = append(parts, c.Name+"="+version)
+			}
+		}
+		problems = append(problems, "расхождение версий: "+strings.Join(parts, ", "))
+	}
+
+	if len(problems) > 0 {
+		return check{Name: "versions", Status: statusFail, Detail: strings.Join(problems, "; ")}
+	}
+	for version := range distinct {
+		return check{Name: "versions", Status: statusOK, Detail: version}
+	}
+	return check{Name: "versions", Status: statusSkip, Detail: "компоненты не заданы"}
+}
+
+// fetchVersion запрашивает сведения о сборке компонента по baseURL + /version
+func (d *doctor) fetchVersion(ctx context.Context, baseURL string) (*buildinfo.Info, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(baseURL, "/")+"/version", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("статус %d", resp.StatusCode)
+	}
+	var info buildinfo.Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("некорректный ответ: %v", err)
+	}
+	return &info, nil
+}
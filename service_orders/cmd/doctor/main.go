@@ -0,0 +1,135 @@
+// Команда doctor проверяет окружение развертывания и печатает машиночитаемый отчет
+// в JSON, чтобы находить расхождения между staging и production: обязательные
+// переменные окружения, секреты со значениями по умолчанию, версию схемы БД,
+// доступность брокера событий и Redis и расхождение версий API Gateway и сервисов
+// (GET /version каждого компонента).
+//
+// Команда читает переменные окружения развертывания, поэтому запускается рядом
+// с сервисами с тем же окружением, например в контейнере service_orders:
+//
+//	docker compose -f docker-compose.production.yml run --rm service_orders_prod /doctor -gateway http://api_gateway_prod:8080
+//
+// или локально с файлом окружения:
+//
+//	go run ./cmd/doctor -env-file ../config/environments/staging.env -gateway https://staging.example.com
+//
+// Отчеты двух окружений сравниваются обычным diff. Код выхода 1, если хотя бы одна
+// проверка не пройдена (status fail)
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"pkg/buildinfo"
+	"pkg/profile"
+)
+
+// status результат проверки
+type status string
+
+const (
+	statusOK   status = "ok"
+	statusWarn status = "warn"
+	statusFail status = "fail"
+	// statusSkip проверка не выполнялась: компонент не настроен или не прошла
+	// проверка, от которой она зависит
+	statusSkip status = "skip"
+)
+
+// check результат одной проверки. Detail не содержит значений секретов
+type check struct {
+	Name   string `json:"name"`
+	Status status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// report отчет о проверке окружения
+type report struct {
+	Environment string    `json:"environment"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Status fail, если не пройдена хотя бы одна проверка, warn — если есть предупреждения
+	Status status `json:"status"`
+	// Versions версии компонентов по GET /version и версия самой команды (doctor)
+	Versions map[string]string `json:"versions"`
+	Checks   []check           `json:"checks"`
+}
+
+func main() {
+	envFile := flag.String("env-file", "", "файл KEY=VALUE с окружением развертывания; уже заданные переменные не переопределяются")
+	migrations := flag.String("migrations", "../database/migrations", "каталог миграций, по которому определяется ожидаемая версия схемы")
+	gateway := flag.String("gateway", "http://localhost:8080", "базовый URL API Gateway")
+	timeout := flag.Duration("timeout", 5*time.Second, "таймаут одной проверки")
+	flag.Parse()
+
+	if *timeout <= 0 {
+		log.Fatalf("-timeout должен быть больше 0")
+	}
+	if *envFile != "" {
+		if err := loadEnvFile(*envFile); err != nil {
+			log.Fatalf("Ошибка загрузки файла окружения: %v", err)
+		}
+	}
+
+	environment := getEnv("ENVIRONMENT", profile.Development)
+	env, envCheck := checkEnvironment(environment)
+	d := &doctor{
+		strict:  env.Strict,
+		timeout: *timeout,
+		client:  &http.Client{Timeout: *timeout},
+	}
+
+	rep := &report{
+		Environment: environment,
+		GeneratedAt: time.Now().UTC(),
+		Versions:    map[string]string{"doctor": buildinfo.Version},
+	}
+	rep.Checks = append(rep.Checks, envCheck, d.checkRequiredEnv(), d.checkSecrets())
+
+	ctx := context.Background()
+	cfg, cfgCheck := d.checkOrdersConfig()
+	rep.Checks = append(rep.Checks, cfgCheck)
+	rep.Checks = append(rep.Checks, d.checkDatabase(ctx, cfg, *migrations)...)
+	rep.Checks = append(rep.Checks, d.checkEvents(), d.checkRedis(ctx))
+
+	components := []component{
+		{Name: "api_gateway", URL: *gateway},
+		{Name: "service_users", URL: getEnv("USERS_SERVICE_URL", "http://service_users:8081")},
+		{Name: "service_orders", URL: getEnv("ORDERS_SERVICE_URL", "http://service_orders:8082")},
+	}
+	rep.Checks = append(rep.Checks, d.checkVersions(ctx, components, rep.Versions))
+
+	rep.Status = statusOK
+	for _, c := range rep.Checks {
+		if c.Status == statusFail {
+			rep.Status = statusFail
+			break
+		}
+		if c.Status == statusWarn {
+			rep.Status = statusWarn
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(rep); err != nil {
+		log.Fatalf("Ошибка вывода отчета: %v", err)
+	}
+	if rep.Status == statusFail {
+		os.Exit(1)
+	}
+}
+
+// getEnv возвращает значение переменной окружения или defaultValue, если она не задана
+func getEnv(key, defaultValue string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -3,15 +3,33 @@ package config
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+
+	"service_orders/models"
+
+	"pkg/ids"
+
+	"github.com/google/uuid"
 )
 
 // Config содержит конфигурацию приложения
 type Config struct {
-	DB     DBConfig
-	Server ServerConfig
-	JWT    JWTConfig
-	Users  UsersServiceConfig
+	DB            DBConfig
+	Server        ServerConfig
+	JWT           JWTConfig
+	Users         UsersServiceConfig
+	Notifications NotificationsConfig
+	Events        EventsConfig
+	Tracking      TrackingConfig
+	Inventory     InventoryConfig
+	Currency      CurrencyConfig
+	Anomaly       AnomalyConfig
+	Cancellation  CancellationConfig
+	Export        ExportConfig
+	OrderHooks    OrderHooksConfig
 }
 
 // DBConfig содержит конфигурацию базы данных
@@ -21,11 +39,19 @@ type DBConfig struct {
 	Name     string
 	User     string
 	Password string
+	// IDStrategy стратегия идентификаторов новых записей: uuidv4, uuidv7 или ulid
+	IDStrategy string
 }
 
 // ServerConfig содержит конфигурацию сервера
 type ServerConfig struct {
 	Port string
+	// H2C принимать HTTP/2 без TLS (h2c) от API Gateway (PROXY_HTTP2=h2c)
+	H2C bool
+	// IdleTimeout время ожидания следующего запроса в keep-alive соединении. Должно быть
+	// больше PROXY_IDLE_CONN_TIMEOUT шлюза, чтобы сервис не закрывал соединение, которое
+	// шлюз считает свободным
+	IdleTimeout time.Duration
 }
 
 // JWTConfig содержит конфигурацию JWT
@@ -38,6 +64,120 @@ type UsersServiceConfig struct {
 	URL string
 }
 
+// NotificationsConfig содержит конфигурацию повторной отправки уведомлений и рассылок объявлений
+type NotificationsConfig struct {
+	// RedeliveryInterval период обработки доставок, возвращенных в очередь (0 — отключено)
+	RedeliveryInterval time.Duration
+	RedeliveryBatch    int
+	// BroadcastBatch число получателей рассылки, выбираемых за один запрос
+	BroadcastBatch int
+	// BroadcastRate предельная скорость рассылки, получателей в секунду
+	BroadcastRate float64
+	// BroadcastPollInterval период проверки новых рассылок
+	BroadcastPollInterval time.Duration
+}
+
+// EventsConfig содержит конфигурацию системы событий
+type EventsConfig struct {
+	// DrainTimeout время обработки оставшихся событий при остановке (0 — без ограничения)
+	DrainTimeout time.Duration
+	// Workers число горутин, выполняющих обработчики событий
+	Workers int
+	// WorkerQueue число вызовов обработчиков, ожидающих свободную горутину
+	WorkerQueue int
+	// OverflowPolicy поведение при заполненной очереди: queue (ждать) или drop (отбросить)
+	OverflowPolicy string
+}
+
+// TrackingConfig содержит конфигурацию ссылок отслеживания заказа без входа
+type TrackingConfig struct {
+	// Secret ключ подписи токенов; смена ключа делает недействительными все выданные ссылки
+	Secret string
+	TTL    time.Duration
+}
+
+// InventoryConfig содержит конфигурацию приема складских остатков от складских систем
+type InventoryConfig struct {
+	// WebhookSecret ключ подписи webhook складских систем (пусто — прием отключен)
+	WebhookSecret string
+	// SignatureTolerance допустимое расхождение времени подписи с временем сервера
+	SignatureTolerance time.Duration
+}
+
+// ExportConfig содержит конфигурацию выгрузок (сборочный лист в CSV и PDF)
+type ExportConfig struct {
+	// TTL срок хранения сформированной выгрузки для повторного и частичного скачивания
+	TTL time.Duration
+}
+
+// OrderHooksConfig содержит настройки проверок заказа перед сохранением
+type OrderHooksConfig struct {
+	// Timeout предельное время одной проверки
+	Timeout time.Duration
+	// OnError решение при ошибке или превышении времени проверки: flag или veto
+	OnError string
+	// FlagTotalAbove сумма, выше которой заказ помечается для проверки (0 — не проверяется)
+	FlagTotalAbove float64
+	// MaxTotal сумма, выше которой заказ отклоняется (0 — не проверяется)
+	MaxTotal float64
+}
+
+// CancellationConfig содержит правила отмены заказа клиентом. Администраторы
+// отменяют заказы без ограничений и платы
+type CancellationConfig struct {
+	// FreeWindow время после создания, в течение которого заказ created отменяется
+	// бесплатно (0 — без ограничения, AfterWindow не применяется)
+	FreeWindow time.Duration
+	// AfterWindow правило для заказа created после FreeWindow
+	AfterWindow models.CancellationRule
+	// InWork правило для заказа в статусе in_work
+	InWork models.CancellationRule
+	// Fee фиксированная плата за отмену, FeePercent — процент от суммы заказа;
+	// плата не превышает сумму заказа
+	Fee        float64
+	FeePercent float64
+}
+
+// CurrencyConfig содержит конфигурацию пересчета сумм заказов в валюту отображения
+type CurrencyConfig struct {
+	// Base валюта, в которой хранятся суммы заказов
+	Base string
+	// RatesURL адрес источника курсов ({base} заменяется базовой валютой);
+	// пусто — используются фиксированные курсы Rates
+	RatesURL string
+	// Rates фиксированные курсы: сколько единиц валюты стоит единица Base
+	Rates        map[string]float64
+	CacheTTL     time.Duration
+	FetchTimeout time.Duration
+}
+
+// Enabled сообщает, настроен ли источник курсов
+func (c CurrencyConfig) Enabled() bool {
+	return c.RatesURL != "" || len(c.Rates) > 0
+}
+
+// AnomalyConfig содержит конфигурацию детектора аномалий бизнес-метрик
+type AnomalyConfig struct {
+	Enabled  bool
+	Window   time.Duration
+	Interval time.Duration
+	// Cooldown пауза между повторными оповещениями по метрике
+	Cooldown time.Duration
+	// OrderRateMin, OrderRateMax границы числа заказов в час (0 — не проверяется)
+	OrderRateMin float64
+	OrderRateMax float64
+	// CancellationRateMax, LoginFailureRateMax предельные доли отмен и неудачных входов (0 — не проверяется)
+	CancellationRateMax float64
+	LoginFailureRateMax float64
+	// MinSamples минимальное число заказов или попыток входа в окне для проверки долей
+	MinSamples int
+	// Recipients пользователи, получающие уведомления об аномалиях
+	Recipients []uuid.UUID
+}
+
+// currencyCode код валюты ISO 4217
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
 // Load загружает конфигурацию из переменных окружения
 func Load() (*Config, error) {
 	config := &Config{}
@@ -54,9 +194,21 @@ func Load() (*Config, error) {
 	config.DB.Name = getEnv("DB_NAME", "system_control")
 	config.DB.User = getEnv("DB_USER", "postgres")
 	config.DB.Password = getEnv("DB_PASSWORD", "postgres")
+	config.DB.IDStrategy = getEnv("ID_STRATEGY", ids.StrategyUUIDv4)
+	if _, err := ids.NewGenerator(config.DB.IDStrategy); err != nil {
+		return nil, fmt.Errorf("invalid ID_STRATEGY: %v", err)
+	}
 
 	// Конфигурация сервера
 	config.Server.Port = getEnv("SERVER_PORT", "8082")
+	h2c, err := strconv.ParseBool(getEnv("SERVER_H2C_ENABLED", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SERVER_H2C_ENABLED: %v", err)
+	}
+	config.Server.H2C = h2c
+	if config.Server.IdleTimeout, err = time.ParseDuration(getEnv("SERVER_IDLE_TIMEOUT", "120s")); err != nil {
+		return nil, fmt.Errorf("invalid SERVER_IDLE_TIMEOUT: %v", err)
+	}
 
 	// Конфигурация JWT
 	config.JWT.Secret = getEnv("JWT_SECRET", "your_secret_key")
@@ -64,15 +216,309 @@ func Load() (*Config, error) {
 	// Конфигурация сервиса пользователей
 	config.Users.URL = getEnv("USERS_SERVICE_URL", "http://localhost:8081")
 
+	// Конфигурация повторной отправки уведомлений
+	redeliveryInterval, err := time.ParseDuration(getEnv("NOTIFICATIONS_REDELIVERY_INTERVAL", "10s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOTIFICATIONS_REDELIVERY_INTERVAL: %v", err)
+	}
+	config.Notifications.RedeliveryInterval = redeliveryInterval
+
+	redeliveryBatch, err := strconv.Atoi(getEnv("NOTIFICATIONS_REDELIVERY_BATCH", "50"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid NOTIFICATIONS_REDELIVERY_BATCH: %v", err)
+	}
+	config.Notifications.RedeliveryBatch = redeliveryBatch
+
+	// Конфигурация рассылок объявлений
+	if config.Notifications.BroadcastBatch, err = strconv.Atoi(getEnv("BROADCAST_BATCH_SIZE", "100")); err != nil {
+		return nil, fmt.Errorf("invalid BROADCAST_BATCH_SIZE: %v", err)
+	}
+	if config.Notifications.BroadcastBatch <= 0 {
+		return nil, fmt.Errorf("invalid BROADCAST_BATCH_SIZE: must be positive")
+	}
+	if config.Notifications.BroadcastRate, err = strconv.ParseFloat(getEnv("BROADCAST_RATE", "20"), 64); err != nil {
+		return nil, fmt.Errorf("invalid BROADCAST_RATE: %v", err)
+	}
+	if config.Notifications.BroadcastRate <= 0 {
+		return nil, fmt.Errorf("invalid BROADCAST_RATE: must be positive")
+	}
+	if config.Notifications.BroadcastPollInterval, err = time.ParseDuration(getEnv("BROADCAST_POLL_INTERVAL", "5s")); err != nil {
+		return nil, fmt.Errorf("invalid BROADCAST_POLL_INTERVAL: %v", err)
+	}
+	if config.Notifications.BroadcastPollInterval <= 0 {
+		return nil, fmt.Errorf("invalid BROADCAST_POLL_INTERVAL: must be positive")
+	}
+
+	// Конфигурация ссылок отслеживания заказа
+	config.Tracking.Secret = getEnv("TRACKING_TOKEN_SECRET", config.JWT.Secret)
+	trackingTTL, err := time.ParseDuration(getEnv("TRACKING_TOKEN_TTL", "720h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACKING_TOKEN_TTL: %v", err)
+	}
+	if trackingTTL <= 0 {
+		return nil, fmt.Errorf("invalid TRACKING_TOKEN_TTL: must be positive")
+	}
+	config.Tracking.TTL = trackingTTL
+
+	// Конфигурация приема складских остатков
+	config.Inventory.WebhookSecret = getEnv("INVENTORY_WEBHOOK_SECRET", "")
+	signatureTolerance, err := time.ParseDuration(getEnv("INVENTORY_WEBHOOK_TOLERANCE", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid INVENTORY_WEBHOOK_TOLERANCE: %v", err)
+	}
+	if signatureTolerance <= 0 {
+		return nil, fmt.Errorf("invalid INVENTORY_WEBHOOK_TOLERANCE: must be positive")
+	}
+	config.Inventory.SignatureTolerance = signatureTolerance
+
+	// Конфигурация пересчета валют
+	config.Currency.Base = strings.ToUpper(getEnv("ORDER_CURRENCY", "RUB"))
+	if !currencyCode.MatchString(config.Currency.Base) {
+		return nil, fmt.Errorf("invalid ORDER_CURRENCY: expected ISO 4217 code")
+	}
+	config.Currency.RatesURL = getEnv("EXCHANGE_RATES_URL", "")
+	if config.Currency.Rates, err = parseRates(getEnv("EXCHANGE_RATES", "")); err != nil {
+		return nil, fmt.Errorf("invalid EXCHANGE_RATES: %v", err)
+	}
+	cacheTTL, err := time.ParseDuration(getEnv("EXCHANGE_RATES_CACHE_TTL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXCHANGE_RATES_CACHE_TTL: %v", err)
+	}
+	if cacheTTL <= 0 {
+		return nil, fmt.Errorf("invalid EXCHANGE_RATES_CACHE_TTL: must be positive")
+	}
+	config.Currency.CacheTTL = cacheTTL
+	fetchTimeout, err := time.ParseDuration(getEnv("EXCHANGE_RATES_TIMEOUT", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXCHANGE_RATES_TIMEOUT: %v", err)
+	}
+	config.Currency.FetchTimeout = fetchTimeout
+
+	// Конфигурация детектора аномалий
+	if err := loadAnomalyConfig(&config.Anomaly); err != nil {
+		return nil, err
+	}
+
+	// Правила отмены заказов
+	if err := loadCancellationConfig(&config.Cancellation); err != nil {
+		return nil, err
+	}
+
+	// Проверки заказа перед сохранением
+	if err := loadOrderHooksConfig(&config.OrderHooks); err != nil {
+		return nil, err
+	}
+
+	// Конфигурация выгрузок
+	exportTTL, err := time.ParseDuration(getEnv("EXPORT_TTL", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EXPORT_TTL: %v", err)
+	}
+	if exportTTL <= 0 {
+		return nil, fmt.Errorf("invalid EXPORT_TTL: must be positive")
+	}
+	config.Export.TTL = exportTTL
+
+	// Конфигурация системы событий
+	drainTimeout, err := time.ParseDuration(getEnv("EVENTS_DRAIN_TIMEOUT", "10s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EVENTS_DRAIN_TIMEOUT: %v", err)
+	}
+	config.Events.DrainTimeout = drainTimeout
+	if config.Events.Workers, err = strconv.Atoi(getEnv("EVENTS_WORKERS", "8")); err != nil {
+		return nil, fmt.Errorf("invalid EVENTS_WORKERS: %v", err)
+	}
+	if config.Events.Workers < 1 {
+		return nil, fmt.Errorf("invalid EVENTS_WORKERS: must be positive")
+	}
+	if config.Events.WorkerQueue, err = strconv.Atoi(getEnv("EVENTS_WORKER_QUEUE", "256")); err != nil {
+		return nil, fmt.Errorf("invalid EVENTS_WORKER_QUEUE: %v", err)
+	}
+	if config.Events.WorkerQueue < 0 {
+		return nil, fmt.Errorf("invalid EVENTS_WORKER_QUEUE: must not be negative")
+	}
+	config.Events.OverflowPolicy = strings.ToLower(getEnv("EVENTS_OVERFLOW_POLICY", "queue"))
+	if config.Events.OverflowPolicy != "queue" && config.Events.OverflowPolicy != "drop" {
+		return nil, fmt.Errorf("invalid EVENTS_OVERFLOW_POLICY: expected queue or drop")
+	}
+
 	return config, nil
 }
 
-// DSN возвращает строку подключения к PostgreSQL
+// Provider интерфейс доступа к текущей конфигурации
+type Provider interface {
+	Current() *Config
+}
+
+// Current возвращает саму конфигурацию; *Config реализует Provider
+func (c *Config) Current() *Config {
+	return c
+}
+
+// DSN возвращает строку подключения к PostgreSQL. Сессия работает в UTC, чтобы
+// метки времени читались из БД без смещения часового пояса сервера
 func (db *DBConfig) DSN() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable timezone=UTC",
 		db.Host, db.Port, db.User, db.Password, db.Name)
 }
 
+// loadAnomalyConfig загружает конфигурацию детектора аномалий
+func loadAnomalyConfig(anomaly *AnomalyConfig) error {
+	var err error
+	if anomaly.Enabled, err = strconv.ParseBool(getEnv("ANOMALY_DETECTION_ENABLED", "false")); err != nil {
+		return fmt.Errorf("invalid ANOMALY_DETECTION_ENABLED: %v", err)
+	}
+
+	durations := []struct {
+		name      string
+		value     string
+		target    *time.Duration
+		allowZero bool
+	}{
+		{"ANOMALY_WINDOW", "15m", &anomaly.Window, false},
+		{"ANOMALY_CHECK_INTERVAL", "1m", &anomaly.Interval, false},
+		{"ANOMALY_ALERT_COOLDOWN", "1h", &anomaly.Cooldown, true},
+	}
+	for _, d := range durations {
+		if *d.target, err = time.ParseDuration(getEnv(d.name, d.value)); err != nil {
+			return fmt.Errorf("invalid %s: %v", d.name, err)
+		}
+		if *d.target < 0 || (*d.target == 0 && !d.allowZero) {
+			return fmt.Errorf("invalid %s: must be positive", d.name)
+		}
+	}
+
+	thresholds := []struct {
+		name   string
+		value  string
+		target *float64
+		max    float64
+	}{
+		{"ANOMALY_ORDER_RATE_MIN", "0", &anomaly.OrderRateMin, 0},
+		{"ANOMALY_ORDER_RATE_MAX", "0", &anomaly.OrderRateMax, 0},
+		{"ANOMALY_CANCELLATION_RATE_MAX", "0.3", &anomaly.CancellationRateMax, 1},
+		{"ANOMALY_LOGIN_FAILURE_RATE_MAX", "0.5", &anomaly.LoginFailureRateMax, 1},
+	}
+	for _, t := range thresholds {
+		if *t.target, err = strconv.ParseFloat(getEnv(t.name, t.value), 64); err != nil {
+			return fmt.Errorf("invalid %s: %v", t.name, err)
+		}
+		if *t.target < 0 || (t.max > 0 && *t.target > t.max) {
+			return fmt.Errorf("invalid %s: out of range", t.name)
+		}
+	}
+	if anomaly.OrderRateMax > 0 && anomaly.OrderRateMin >= anomaly.OrderRateMax {
+		return fmt.Errorf("invalid ANOMALY_ORDER_RATE_MIN: must be less than ANOMALY_ORDER_RATE_MAX")
+	}
+
+	if anomaly.MinSamples, err = strconv.Atoi(getEnv("ANOMALY_MIN_SAMPLES", "20")); err != nil {
+		return fmt.Errorf("invalid ANOMALY_MIN_SAMPLES: %v", err)
+	}
+	if anomaly.MinSamples < 1 {
+		return fmt.Errorf("invalid ANOMALY_MIN_SAMPLES: must be positive")
+	}
+
+	for _, value := range strings.Split(getEnv("ANOMALY_ALERT_RECIPIENTS", ""), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		recipient, err := uuid.Parse(value)
+		if err != nil {
+			return fmt.Errorf("invalid ANOMALY_ALERT_RECIPIENTS: %v", err)
+		}
+		anomaly.Recipients = append(anomaly.Recipients, recipient)
+	}
+	return nil
+}
+
+// loadCancellationConfig загружает правила отмены заказов
+func loadCancellationConfig(cancellation *CancellationConfig) error {
+	var err error
+	if cancellation.FreeWindow, err = time.ParseDuration(getEnv("CANCELLATION_FREE_WINDOW", "0")); err != nil {
+		return fmt.Errorf("invalid CANCELLATION_FREE_WINDOW: %v", err)
+	}
+	if cancellation.FreeWindow < 0 {
+		return fmt.Errorf("invalid CANCELLATION_FREE_WINDOW: must not be negative")
+	}
+
+	rules := []struct {
+		name   string
+		value  string
+		target *models.CancellationRule
+	}{
+		{"CANCELLATION_AFTER_WINDOW", "fee", &cancellation.AfterWindow},
+		{"CANCELLATION_IN_WORK", "free", &cancellation.InWork},
+	}
+	for _, rule := range rules {
+		*rule.target = models.CancellationRule(strings.ToLower(getEnv(rule.name, rule.value)))
+		if !rule.target.IsValid() {
+			return fmt.Errorf("invalid %s: expected free, fee or forbid", rule.name)
+		}
+	}
+
+	if cancellation.Fee, err = strconv.ParseFloat(getEnv("CANCELLATION_FEE", "0"), 64); err != nil {
+		return fmt.Errorf("invalid CANCELLATION_FEE: %v", err)
+	}
+	if cancellation.FeePercent, err = strconv.ParseFloat(getEnv("CANCELLATION_FEE_PERCENT", "10"), 64); err != nil {
+		return fmt.Errorf("invalid CANCELLATION_FEE_PERCENT: %v", err)
+	}
+	if cancellation.Fee < 0 || cancellation.FeePercent < 0 || cancellation.FeePercent > 100 {
+		return fmt.Errorf("invalid CANCELLATION_FEE/CANCELLATION_FEE_PERCENT: out of range")
+	}
+	return nil
+}
+
+// loadOrderHooksConfig загружает настройки проверок заказа перед сохранением
+func loadOrderHooksConfig(hooks *OrderHooksConfig) error {
+	var err error
+	if hooks.Timeout, err = time.ParseDuration(getEnv("ORDER_HOOKS_TIMEOUT", "2s")); err != nil {
+		return fmt.Errorf("invalid ORDER_HOOKS_TIMEOUT: %v", err)
+	}
+	if hooks.Timeout <= 0 {
+		return fmt.Errorf("invalid ORDER_HOOKS_TIMEOUT: must be positive")
+	}
+
+	hooks.OnError = strings.ToLower(getEnv("ORDER_HOOKS_ON_ERROR", "flag"))
+	if hooks.OnError != "flag" && hooks.OnError != "veto" {
+		return fmt.Errorf("invalid ORDER_HOOKS_ON_ERROR: expected flag or veto")
+	}
+
+	if hooks.FlagTotalAbove, err = strconv.ParseFloat(getEnv("ORDER_HOOK_FLAG_TOTAL_ABOVE", "0"), 64); err != nil {
+		return fmt.Errorf("invalid ORDER_HOOK_FLAG_TOTAL_ABOVE: %v", err)
+	}
+	if hooks.MaxTotal, err = strconv.ParseFloat(getEnv("ORDER_HOOK_MAX_TOTAL", "0"), 64); err != nil {
+		return fmt.Errorf("invalid ORDER_HOOK_MAX_TOTAL: %v", err)
+	}
+	if hooks.FlagTotalAbove < 0 || hooks.MaxTotal < 0 {
+		return fmt.Errorf("invalid ORDER_HOOK_FLAG_TOTAL_ABOVE/ORDER_HOOK_MAX_TOTAL: must not be negative")
+	}
+
+	return nil
+}
+
+// parseRates разбирает фиксированные курсы вида EUR=0.0102,USD=0.011
+func parseRates(value string) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		code, rateValue, ok := strings.Cut(item, "=")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !ok || !currencyCode.MatchString(code) {
+			return nil, fmt.Errorf("expected CODE=rate, got %q", item)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rateValue), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("rate for %s must be a positive number", code)
+		}
+		rates[code] = rate
+	}
+	return rates, nil
+}
+
 // getEnv возвращает значение переменной окружения или значение по умолчанию
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
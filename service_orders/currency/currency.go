@@ -0,0 +1,233 @@
+// Package currency пересчитывает суммы заказов из базовой валюты в валюту
+// отображения по курсам внешнего источника с кешированием
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"service_orders/logger"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnsupportedCurrency для валюты нет курса
+	ErrUnsupportedCurrency = errors.New("валюта не поддерживается")
+	// ErrRatesUnavailable источник курсов недоступен и сохраненных курсов нет
+	ErrRatesUnavailable = errors.New("курсы валют недоступны")
+)
+
+const (
+	// maxResponseSize максимальный размер ответа источника курсов
+	maxResponseSize = 1 << 20
+	// retryInterval пауза перед повторным обращением к источнику после ошибки,
+	// чтобы при его недоступности запросы не ждали таймаут один за другим
+	retryInterval = time.Minute
+)
+
+// codePattern код валюты ISO 4217
+var codePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// NormalizeCode приводит код валюты к верхнему регистру и проверяет формат ISO 4217
+func NormalizeCode(value string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(value))
+	if !codePattern.MatchString(code) {
+		return "", fmt.Errorf("некорректный код валюты %q: ожидается код ISO 4217, например EUR", value)
+	}
+	return code, nil
+}
+
+// Provider источник курсов валют
+type Provider interface {
+	// Rates возвращает курсы валют к base: сколько единиц валюты стоит единица base
+	Rates(ctx context.Context, base string) (map[string]float64, error)
+}
+
+// StaticProvider фиксированные курсы из конфигурации
+type StaticProvider map[string]float64
+
+// Rates возвращает фиксированные курсы
+func (p StaticProvider) Rates(ctx context.Context, base string) (map[string]float64, error) {
+	return p, nil
+}
+
+// HTTPProvider загружает курсы по HTTP. Ответ — JSON вида
+// {"base": "RUB", "rates": {"EUR": 0.0102, "USD": 0.011}}; такой формат отдают
+// распространенные сервисы курсов (например, Frankfurter)
+type HTTPProvider struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPProvider создает HTTPProvider. Подстрока {base} в url заменяется кодом базовой валюты
+func NewHTTPProvider(url string, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// ratesResponse ответ источника курсов
+type ratesResponse struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// Rates загружает курсы к base
+func (p *HTTPProvider) Rates(ctx context.Context, base string) (map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(p.url, "{base}", base), nil)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный адрес источника курсов: %v", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса курсов: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("источник курсов вернул статус %d", resp.StatusCode)
+	}
+
+	var body ratesResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("ошибка разбора курсов: %v", err)
+	}
+	if body.Base != "" && !strings.EqualFold(body.Base, base) {
+		return nil, fmt.Errorf("источник вернул курсы к %s вместо %s", body.Base, base)
+	}
+	if len(body.Rates) == 0 {
+		return nil, fmt.Errorf("источник не вернул ни одного курса")
+	}
+	return body.Rates, nil
+}
+
+// Rate курс валюты отображения к базовой
+type Rate struct {
+	Currency string
+	Value    float64
+	// UpdatedAt время получения курсов от источника
+	UpdatedAt time.Time
+}
+
+// Converter кеширует курсы на время ttl. Если источник недоступен, продолжают
+// действовать последние полученные курсы
+type Converter struct {
+	provider Provider
+	base     string
+	ttl      time.Duration
+
+	// refresh не дает нескольким запросам одновременно обращаться к источнику
+	refresh   sync.Mutex
+	mutex     sync.RWMutex
+	rates     map[string]float64
+	fetchedAt time.Time
+	retryAt   time.Time
+	lastErr   error
+}
+
+// NewConverter создает Converter для сумм в валюте base
+func NewConverter(provider Provider, base string, ttl time.Duration) *Converter {
+	return &Converter{
+		provider: provider,
+		base:     base,
+		ttl:      ttl,
+	}
+}
+
+// Base возвращает валюту, в которой хранятся суммы заказов
+func (c *Converter) Base() string {
+	return c.base
+}
+
+// Rate возвращает курс currency к базовой валюте
+func (c *Converter) Rate(ctx context.Context, currency string) (Rate, error) {
+	if currency == c.base {
+		return Rate{Currency: currency, Value: 1, UpdatedAt: time.Now().UTC()}, nil
+	}
+
+	rates, fetchedAt, err := c.current(ctx)
+	if err != nil {
+		return Rate{}, err
+	}
+	value, ok := rates[currency]
+	if !ok || value <= 0 {
+		return Rate{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, currency)
+	}
+	return Rate{Currency: currency, Value: value, UpdatedAt: fetchedAt}, nil
+}
+
+// current возвращает кешированные курсы, при необходимости обновляя их
+func (c *Converter) current(ctx context.Context) (map[string]float64, time.Time, error) {
+	if rates, fetchedAt, ok, err := c.cached(); ok {
+		return rates, fetchedAt, err
+	}
+
+	c.refresh.Lock()
+	defer c.refresh.Unlock()
+
+	// Курсы могли обновиться, пока запрос ждал блокировку
+	if rates, fetchedAt, ok, err := c.cached(); ok {
+		return rates, fetchedAt, err
+	}
+
+	fetched, err := c.provider.Rates(ctx, c.base)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err != nil {
+		c.retryAt = time.Now().Add(retryInterval)
+		c.lastErr = err
+		if c.rates == nil {
+			return nil, time.Time{}, fmt.Errorf("%w: %v", ErrRatesUnavailable, err)
+		}
+		logger.GetLogger().Warn("Не удалось обновить курсы валют, используются сохраненные",
+			zap.Time("fetched_at", c.fetchedAt),
+			zap.Error(err),
+		)
+		return c.rates, c.fetchedAt, nil
+	}
+
+	c.rates = make(map[string]float64, len(fetched))
+	for code, value := range fetched {
+		c.rates[strings.ToUpper(code)] = value
+	}
+	c.fetchedAt = time.Now().UTC()
+	c.retryAt = time.Time{}
+	c.lastErr = nil
+	return c.rates, c.fetchedAt, nil
+}
+
+// cached возвращает сохраненные курсы, если обращаться к источнику не нужно:
+// курсы не устарели или после ошибки источника еще не прошел retryInterval
+func (c *Converter) cached() (map[string]float64, time.Time, bool, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.rates != nil && time.Since(c.fetchedAt) < c.ttl {
+		return c.rates, c.fetchedAt, true, nil
+	}
+	if time.Now().Before(c.retryAt) {
+		if c.rates == nil {
+			return nil, time.Time{}, true, fmt.Errorf("%w: %v", ErrRatesUnavailable, c.lastErr)
+		}
+		return c.rates, c.fetchedAt, true, nil
+	}
+	return nil, time.Time{}, false, nil
+}
+
+// Convert пересчитывает сумму по курсу с округлением до сотых
+func (r Rate) Convert(amount float64) float64 {
+	return math.Round(amount*r.Value*100) / 100
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"time"
 
+	"pkg/ids"
+	"pkg/timeutil"
+
 	"service_orders/models"
 
 	"github.com/google/uuid"
@@ -17,8 +20,24 @@ const (
 	OrderCreatedEvent EventType = "order.created"
 	// OrderStatusUpdatedEvent событие обновления статуса заказа
 	OrderStatusUpdatedEvent EventType = "order.status.updated"
+	// OrderFlaggedEvent заказ создан в статусе flagged и ждет решения администратора
+	OrderFlaggedEvent EventType = "order.flagged"
+	// StockUpdatedEvent событие изменения складского остатка товара
+	StockUpdatedEvent EventType = "stock.updated"
+	// AlertOrderCreationRateEvent аномальная частота создания заказов
+	AlertOrderCreationRateEvent EventType = "alert.order_creation_rate"
+	// AlertCancellationRateEvent аномальная доля отмен заказов
+	AlertCancellationRateEvent EventType = "alert.cancellation_rate"
+	// AlertLoginFailureRateEvent аномальная доля неудачных входов
+	AlertLoginFailureRateEvent EventType = "alert.login_failure_rate"
 )
 
+// AlertEventPrefix префикс типов событий об аномалиях бизнес-метрик: alert.<метрика>
+const AlertEventPrefix = "alert."
+
+// AlertEventTypes типы событий об аномалиях бизнес-метрик
+var AlertEventTypes = []EventType{AlertOrderCreationRateEvent, AlertCancellationRateEvent, AlertLoginFailureRateEvent}
+
 // DomainEvent представляет базовую структуру доменного события
 type DomainEvent struct {
 	ID          uuid.UUID   `json:"id"`
@@ -58,16 +77,52 @@ type OrderStatusUpdatedEventData struct {
 	NewStatus   models.OrderStatus `json:"new_status"`
 	UpdatedAt   time.Time          `json:"updated_at"`
 	UpdatedBy   uuid.UUID          `json:"updated_by"` // Кто обновил (может отличаться от владельца)
+	// Cancellation условия отмены; только для перехода в статус cancelled
+	Cancellation *models.OrderCancellation `json:"cancellation,omitempty"`
+}
+
+// OrderFlaggedEventData данные события заказа, помеченного проверкой перед созданием
+type OrderFlaggedEventData struct {
+	OrderID  uuid.UUID `json:"order_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	TotalSum float64   `json:"total_sum"`
+	// Hooks проверки с решением, отличным от allow, в виде hook=verdict
+	Hooks     string    `json:"hooks"`
+	FlaggedAt time.Time `json:"flagged_at"`
+}
+
+// StockUpdatedEventData данные события изменения складского остатка
+type StockUpdatedEventData struct {
+	Product   string `json:"product"`
+	Warehouse string `json:"warehouse"`
+	// PreviousAvailable прежний остаток; отсутствует для нового товара
+	PreviousAvailable *int      `json:"previous_available,omitempty"`
+	Available         int       `json:"available"`
+	ChangedAt         time.Time `json:"changed_at"`
+}
+
+// AlertEventData данные события об аномалии бизнес-метрики
+type AlertEventData struct {
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+	// Bound нарушенная граница: max или min
+	Bound     string  `json:"bound"`
+	Threshold float64 `json:"threshold"`
+	// Window длительность скользящего окна, например 15m0s
+	Window string `json:"window"`
+	// Samples число событий в окне, по которым рассчитано значение
+	Samples    int       `json:"samples"`
+	DetectedAt time.Time `json:"detected_at"`
 }
 
 // NewOrderCreatedEvent создает новое событие создания заказа
 func NewOrderCreatedEvent(order *models.Order, metadata Metadata) *DomainEvent {
 	return &DomainEvent{
-		ID:          uuid.New(),
+		ID:          ids.New(),
 		Type:        OrderCreatedEvent,
 		AggregateID: order.ID,
 		UserID:      order.UserID,
-		Timestamp:   time.Now(),
+		Timestamp:   timeutil.Now(),
 		Version:     1,
 		Data: OrderCreatedEventData{
 			OrderID:   order.ID,
@@ -84,24 +139,86 @@ func NewOrderCreatedEvent(order *models.Order, metadata Metadata) *DomainEvent {
 // NewOrderStatusUpdatedEvent создает новое событие обновления статуса заказа
 func NewOrderStatusUpdatedEvent(orderID, userID, updatedBy uuid.UUID, oldStatus, newStatus models.OrderStatus, metadata Metadata) *DomainEvent {
 	return &DomainEvent{
-		ID:          uuid.New(),
+		ID:          ids.New(),
 		Type:        OrderStatusUpdatedEvent,
 		AggregateID: orderID,
 		UserID:      userID,
-		Timestamp:   time.Now(),
+		Timestamp:   timeutil.Now(),
 		Version:     1,
 		Data: OrderStatusUpdatedEventData{
 			OrderID:   orderID,
 			UserID:    userID,
 			OldStatus: oldStatus,
 			NewStatus: newStatus,
-			UpdatedAt: time.Now(),
+			UpdatedAt: timeutil.Now(),
 			UpdatedBy: updatedBy,
 		},
 		Metadata: metadata,
 	}
 }
 
+// NewOrderCancelledEvent создает событие обновления статуса заказа на cancelled
+// с условиями отмены
+func NewOrderCancelledEvent(orderID, userID, cancelledBy uuid.UUID, oldStatus models.OrderStatus, cancellation models.OrderCancellation, metadata Metadata) *DomainEvent {
+	event := NewOrderStatusUpdatedEvent(orderID, userID, cancelledBy, oldStatus, models.OrderStatusCancelled, metadata)
+	data := event.Data.(OrderStatusUpdatedEventData)
+	data.Cancellation = &cancellation
+	event.Data = data
+	return event
+}
+
+// NewOrderFlaggedEvent создает событие заказа, помеченного проверками hooks
+func NewOrderFlaggedEvent(order *models.Order, hooks string, metadata Metadata) *DomainEvent {
+	return &DomainEvent{
+		ID:          ids.New(),
+		Type:        OrderFlaggedEvent,
+		AggregateID: order.ID,
+		UserID:      order.UserID,
+		Timestamp:   timeutil.Now(),
+		Version:     1,
+		Data: OrderFlaggedEventData{
+			OrderID:   order.ID,
+			UserID:    order.UserID,
+			TotalSum:  order.TotalSum,
+			Hooks:     hooks,
+			FlaggedAt: order.CreatedAt,
+		},
+		Metadata: metadata,
+	}
+}
+
+// NewStockUpdatedEvent создает событие изменения складского остатка.
+// Остаток не связан с заказом и пользователем, поэтому AggregateID и UserID пустые
+func NewStockUpdatedEvent(level models.StockLevel, previous *int, metadata Metadata) *DomainEvent {
+	return &DomainEvent{
+		ID:        ids.New(),
+		Type:      StockUpdatedEvent,
+		Timestamp: timeutil.Now(),
+		Version:   1,
+		Data: StockUpdatedEventData{
+			Product:           level.Product,
+			Warehouse:         level.Warehouse,
+			PreviousAvailable: previous,
+			Available:         level.Available,
+			ChangedAt:         level.ChangedAt,
+		},
+		Metadata: metadata,
+	}
+}
+
+// NewAlertEvent создает событие об аномалии бизнес-метрики типа alert.<метрика>.
+// Аномалия не связана с заказом и пользователем, поэтому AggregateID и UserID пустые
+func NewAlertEvent(data AlertEventData, metadata Metadata) *DomainEvent {
+	return &DomainEvent{
+		ID:        ids.New(),
+		Type:      EventType(AlertEventPrefix + data.Metric),
+		Timestamp: timeutil.Now(),
+		Version:   1,
+		Data:      data,
+		Metadata:  metadata,
+	}
+}
+
 // ToJSON сериализует событие в JSON
 func (e *DomainEvent) ToJSON() ([]byte, error) {
 	return json.Marshal(e)
@@ -124,6 +241,14 @@ func (t EventType) GetEventName() string {
 		return "Заказ создан"
 	case OrderStatusUpdatedEvent:
 		return "Статус заказа обновлен"
+	case StockUpdatedEvent:
+		return "Остаток товара обновлен"
+	case AlertOrderCreationRateEvent:
+		return "Аномальная частота создания заказов"
+	case AlertCancellationRateEvent:
+		return "Аномальная доля отмен заказов"
+	case AlertLoginFailureRateEvent:
+		return "Аномальная доля неудачных входов"
 	default:
 		return "Неизвестное событие"
 	}
@@ -0,0 +1,39 @@
+package events
+
+import (
+	"context"
+	"net/http"
+
+	"service_orders/models"
+
+	"github.com/google/uuid"
+)
+
+// EventPublisherFacade интерфейс публикации доменных событий заказов, используемый обработчиками.
+// Реализуется EventService; позволяет подменять публикацию событий в тестах
+type EventPublisherFacade interface {
+	PublishOrderCreated(ctx context.Context, order *models.Order, r *http.Request) error
+	PublishOrderStatusUpdated(ctx context.Context, orderID, userID, updatedBy uuid.UUID,
+		oldStatus, newStatus models.OrderStatus, r *http.Request) error
+	PublishOrderCancelled(ctx context.Context, orderID, userID, cancelledBy uuid.UUID,
+		oldStatus models.OrderStatus, cancellation models.OrderCancellation, r *http.Request) error
+	PublishOrderFlagged(ctx context.Context, order *models.Order, hooks string, r *http.Request) error
+}
+
+// StockEventPublisher интерфейс публикации событий складских остатков
+type StockEventPublisher interface {
+	PublishStockUpdated(ctx context.Context, level models.StockLevel, previous *int, r *http.Request) error
+}
+
+// HandlerManager интерфейс управления обработчиками событий из административного API
+type HandlerManager interface {
+	Handlers() ([]HandlerInfo, error)
+	SetHandlerEnabled(name string, enabled bool, reason string, actor uuid.UUID) (HandlerInfo, error)
+	HandlerErrors(name string, limit int) ([]HandlerError, error)
+}
+
+var (
+	_ EventPublisherFacade = (*EventService)(nil)
+	_ StockEventPublisher  = (*EventService)(nil)
+	_ HandlerManager       = (*EventService)(nil)
+)
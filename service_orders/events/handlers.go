@@ -8,6 +8,9 @@ import (
 	"sync/atomic"
 
 	"service_orders/models"
+	"service_orders/notifications"
+
+	"github.com/google/uuid"
 )
 
 // EventStats для отслеживания статистики событий
@@ -15,6 +18,10 @@ var eventStats struct {
 	OrdersCreated       int64
 	StatusUpdates       int64
 	OrdersCancelled     int64
+	OrdersFlagged       int64
+	FlaggedApproved     int64
+	FlaggedRejected     int64
+	StockUpdates        int64
 	EventsPublished     int64
 	EventProcessingErrors int64
 }
@@ -30,23 +37,55 @@ func AnalyticsEventHandler(ctx context.Context, event *DomainEvent) error {
 	case OrderStatusUpdatedEvent:
 		atomic.AddInt64(&eventStats.StatusUpdates, 1)
 		return handleOrderStatusAnalytics(event)
+	case OrderFlaggedEvent:
+		atomic.AddInt64(&eventStats.OrdersFlagged, 1)
+		return nil
+	case StockUpdatedEvent:
+		atomic.AddInt64(&eventStats.StockUpdates, 1)
+		return nil
 	default:
 		log.Printf("Неизвестный тип события для аналитики: %s", event.Type)
 	}
 	return nil
 }
 
-// NotificationEventHandler обработчик событий для уведомлений
-func NotificationEventHandler(ctx context.Context, event *DomainEvent) error {
-	switch event.Type {
-	case OrderCreatedEvent:
-		return handleOrderCreatedNotification(event)
-	case OrderStatusUpdatedEvent:
-		return handleOrderStatusNotification(event)
-	default:
-		log.Printf("Неизвестный тип события для уведомлений: %s", event.Type)
+// NewNotificationEventHandler создает обработчик событий для уведомлений;
+// отправка выполняется через notifier с учетом настроек пользователя
+func NewNotificationEventHandler(notifier *notifications.Notifier) EventHandler {
+	return func(ctx context.Context, event *DomainEvent) error {
+		switch event.Type {
+		case OrderCreatedEvent:
+			return handleOrderCreatedNotification(ctx, notifier, event)
+		case OrderStatusUpdatedEvent:
+			return handleOrderStatusNotification(ctx, notifier, event)
+		default:
+			log.Printf("Неизвестный тип события для уведомлений: %s", event.Type)
+		}
+		return nil
+	}
+}
+
+// NewAlertNotificationHandler создает обработчик событий об аномалиях: сообщение
+// отправляется каждому получателю из recipients по его включенным каналам
+func NewAlertNotificationHandler(notifier *notifications.Notifier, recipients []uuid.UUID) EventHandler {
+	return func(ctx context.Context, event *DomainEvent) error {
+		data, ok := event.Data.(AlertEventData)
+		if !ok {
+			atomic.AddInt64(&eventStats.EventProcessingErrors, 1)
+			return fmt.Errorf("неверный тип данных для события %s", event.Type)
+		}
+
+		message := fmt.Sprintf("%s: %s = %.4g (граница %s %.4g, окно %s, событий %d)",
+			event.Type.GetEventName(), data.Metric, data.Value, data.Bound, data.Threshold, data.Window, data.Samples)
+
+		var lastErr error
+		for _, recipient := range recipients {
+			if err := notifier.NotifyOperational(ctx, recipient, message); err != nil {
+				lastErr = err
+			}
+		}
+		return lastErr
 	}
-	return nil
 }
 
 // AuditEventHandler обработчик событий для аудита (логирование в БД/файл)
@@ -121,6 +160,15 @@ func handleOrderStatusAnalytics(event *DomainEvent) error {
 	if data.NewStatus == models.OrderStatusCancelled {
 		atomic.AddInt64(&eventStats.OrdersCancelled, 1)
 	}
+
+	// Решения администратора по заказам, помеченным проверками перед созданием
+	if data.OldStatus == models.OrderStatusFlagged {
+		if data.NewStatus == models.OrderStatusCancelled {
+			atomic.AddInt64(&eventStats.FlaggedRejected, 1)
+		} else {
+			atomic.AddInt64(&eventStats.FlaggedApproved, 1)
+		}
+	}
 	
 	// Здесь можно добавить логику для:
 	// - Отслеживания времени выполнения заказов
@@ -133,7 +181,7 @@ func handleOrderStatusAnalytics(event *DomainEvent) error {
 }
 
 // handleOrderCreatedNotification отправляет уведомление о создании заказа
-func handleOrderCreatedNotification(event *DomainEvent) error {
+func handleOrderCreatedNotification(ctx context.Context, notifier *notifications.Notifier, event *DomainEvent) error {
 	data, ok := event.Data.(OrderCreatedEventData)
 	if !ok {
 		// Попробуем десериализовать из map[string]interface{}
@@ -149,18 +197,12 @@ func handleOrderCreatedNotification(event *DomainEvent) error {
 		}
 	}
 	
-	// Здесь можно добавить логику для:
-	// - Отправки email уведомлений
-	// - Push-уведомлений в мобильном приложении
-	// - SMS уведомлений
-	log.Printf("📧 УВЕДОМЛЕНИЕ: Пользователю %s отправлено уведомление о создании заказа %s", 
-		data.UserID, data.OrderID)
-	
-	return nil
+	message := fmt.Sprintf("Заказ %s создан на сумму %.2f руб.", data.OrderID, data.TotalSum)
+	return notifier.Notify(ctx, data.UserID, notifications.EventOrderCreated, message)
 }
 
 // handleOrderStatusNotification отправляет уведомление об изменении статуса
-func handleOrderStatusNotification(event *DomainEvent) error {
+func handleOrderStatusNotification(ctx context.Context, notifier *notifications.Notifier, event *DomainEvent) error {
 	data, ok := event.Data.(OrderStatusUpdatedEventData)
 	if !ok {
 		// Попробуем десериализовать из map[string]interface{}
@@ -178,8 +220,8 @@ func handleOrderStatusNotification(event *DomainEvent) error {
 	
 	// Отправляем уведомления только для определенных статусов
 	if data.NewStatus == models.OrderStatusCompleted || data.NewStatus == models.OrderStatusCancelled {
-		log.Printf("📧 УВЕДОМЛЕНИЕ: Пользователю %s отправлено уведомление об изменении статуса заказа %s на '%s'", 
-			data.UserID, data.OrderID, data.NewStatus)
+		message := fmt.Sprintf("Статус заказа %s изменен на '%s'", data.OrderID, data.NewStatus)
+		return notifier.Notify(ctx, data.UserID, notifications.EventOrderStatusChanged, message)
 	}
 	
 	return nil
@@ -191,6 +233,10 @@ func GetEventStats() map[string]int64 {
 		"orders_created":         atomic.LoadInt64(&eventStats.OrdersCreated),
 		"status_updates":         atomic.LoadInt64(&eventStats.StatusUpdates),
 		"orders_cancelled":       atomic.LoadInt64(&eventStats.OrdersCancelled),
+		"orders_flagged":         atomic.LoadInt64(&eventStats.OrdersFlagged),
+		"flagged_approved":       atomic.LoadInt64(&eventStats.FlaggedApproved),
+		"flagged_rejected":       atomic.LoadInt64(&eventStats.FlaggedRejected),
+		"stock_updates":          atomic.LoadInt64(&eventStats.StockUpdates),
 		"events_published":       atomic.LoadInt64(&eventStats.EventsPublished),
 		"event_processing_errors": atomic.LoadInt64(&eventStats.EventProcessingErrors),
 	}
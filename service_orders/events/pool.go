@@ -0,0 +1,140 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// Политики переполнения очереди пула обработчиков (EVENTS_OVERFLOW_POLICY)
+const (
+	// OverflowQueue диспетчер ждет места в очереди пула. Пока он ждет, события копятся
+	// в буфере publisher, а при его заполнении Publish возвращает ошибку
+	OverflowQueue = "queue"
+	// OverflowDrop вызов обработчика отбрасывается и учитывается в метрике dropped
+	OverflowDrop = "drop"
+)
+
+// PoolConfig параметры пула обработчиков событий
+type PoolConfig struct {
+	// Workers число горутин, выполняющих обработчики
+	Workers int
+	// QueueSize число вызовов обработчиков, ожидающих свободную горутину
+	QueueSize int
+	// Overflow политика при заполненной очереди: OverflowQueue или OverflowDrop
+	Overflow string
+}
+
+// PoolStats метрики пула обработчиков событий
+type PoolStats struct {
+	Workers  int
+	Busy     int64
+	Queued   int
+	Executed int64
+	// Dropped вызовы, отброшенные при переполнении очереди или при закрытии по таймауту
+	Dropped int64
+	// Overflows сколько раз очередь пула оказывалась заполненной
+	Overflows int64
+}
+
+// handlerTask вызов одного обработчика для одного события; done вызывается,
+// когда вызов выполнен или отброшен по переполнению
+type handlerTask struct {
+	handler EventHandler
+	event   *DomainEvent
+	done    func()
+}
+
+// workerPool ограниченный пул горутин для вызова обработчиков событий
+type workerPool struct {
+	config  PoolConfig
+	tasks   chan handlerTask
+	workers sync.WaitGroup
+
+	busy      atomic.Int64
+	executed  atomic.Int64
+	dropped   atomic.Int64
+	overflows atomic.Int64
+}
+
+// newWorkerPool запускает config.Workers горутин; они выполняют обработчики с контекстом ctx
+// и завершаются после close
+func newWorkerPool(ctx context.Context, config PoolConfig) *workerPool {
+	pool := &workerPool{
+		config: config,
+		tasks:  make(chan handlerTask, config.QueueSize),
+	}
+	pool.workers.Add(config.Workers)
+	for i := 0; i < config.Workers; i++ {
+		go pool.run(ctx)
+	}
+	return pool
+}
+
+// submit ставит вызов в очередь по политике переполнения. Вызов, не попавший в очередь
+// из-за отмены ctx, не считается выполненным: событие учитывается как потерянное при закрытии
+func (p *workerPool) submit(ctx context.Context, task handlerTask) {
+	select {
+	case p.tasks <- task:
+		return
+	default:
+	}
+
+	p.overflows.Add(1)
+	if p.config.Overflow == OverflowDrop {
+		p.dropped.Add(1)
+		log.Printf("Очередь обработчиков переполнена, вызов обработчика события %s (ID: %s) отброшен",
+			task.event.Type, task.event.ID)
+		task.done()
+		return
+	}
+
+	select {
+	case p.tasks <- task:
+	case <-ctx.Done():
+		p.dropped.Add(1)
+	}
+}
+
+// run выполняет вызовы из очереди. После отмены ctx оставшиеся вызовы отбрасываются
+func (p *workerPool) run(ctx context.Context) {
+	defer p.workers.Done()
+
+	for task := range p.tasks {
+		if ctx.Err() != nil {
+			p.dropped.Add(1)
+			continue
+		}
+
+		p.busy.Add(1)
+		if err := task.handler(ctx, task.event); err != nil {
+			log.Printf("Ошибка обработки события %s: %v", task.event.Type, err)
+		}
+		p.busy.Add(-1)
+		p.executed.Add(1)
+		task.done()
+	}
+}
+
+// close закрывает очередь: горутины завершаются, выполнив оставшиеся вызовы
+func (p *workerPool) close() {
+	close(p.tasks)
+}
+
+// wait ожидает завершения всех горутин пула
+func (p *workerPool) wait() {
+	p.workers.Wait()
+}
+
+// stats возвращает текущие метрики пула
+func (p *workerPool) stats() PoolStats {
+	return PoolStats{
+		Workers:   p.config.Workers,
+		Busy:      p.busy.Load(),
+		Queued:    len(p.tasks),
+		Executed:  p.executed.Load(),
+		Dropped:   p.dropped.Load(),
+		Overflows: p.overflows.Load(),
+	}
+}
@@ -2,9 +2,12 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // EventPublisher интерфейс для публикации доменных событий
@@ -17,46 +20,85 @@ type EventPublisher interface {
 // EventHandler функция-обработчик события
 type EventHandler func(ctx context.Context, event *DomainEvent) error
 
+// ErrPublisherClosed возвращается Publish после вызова Close
+var ErrPublisherClosed = errors.New("publisher закрыт")
+
+// DrainReport итог закрытия publisher: сколько необработанных на момент Close событий
+// успели обработать подписчики и сколько было потеряно по истечении таймаута
+type DrainReport struct {
+	Pending  int64
+	Flushed  int64
+	Dropped  int64
+	Duration time.Duration
+	TimedOut bool
+}
+
 // InMemoryEventPublisher простая реализация для разработки и тестирования
 // В будущем будет заменена на Kafka/RabbitMQ
 type InMemoryEventPublisher struct {
 	subscribers map[EventType][]EventHandler
 	mutex       sync.RWMutex
 	events      chan *DomainEvent
-	ctx         context.Context
-	cancel      context.CancelFunc
-	wg          sync.WaitGroup
+	// ctx отменяется по истечении таймаута закрытия и прерывает обработку
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// pool ограниченный пул горутин, выполняющих подписчиков
+	pool *workerPool
+
+	drainTimeout time.Duration
+
+	// closeMutex защищает events от записи после закрытия канала
+	closeMutex sync.RWMutex
+	closed     bool
+	closeOnce  sync.Once
+	report     DrainReport
+
+	// accepted и completed считают принятые и полностью обработанные события;
+	// их разность — события в очереди и в обработке
+	accepted  atomic.Int64
+	completed atomic.Int64
 }
 
-// NewInMemoryEventPublisher создает новый in-memory publisher
-func NewInMemoryEventPublisher() *InMemoryEventPublisher {
+// NewInMemoryEventPublisher создает новый in-memory publisher. drainTimeout ограничивает
+// время обработки оставшихся событий при закрытии (0 — без ограничения), pool задает
+// число горутин подписчиков и поведение при переполнении их очереди
+func NewInMemoryEventPublisher(drainTimeout time.Duration, pool PoolConfig) *InMemoryEventPublisher {
 	ctx, cancel := context.WithCancel(context.Background())
-	
+
 	publisher := &InMemoryEventPublisher{
-		subscribers: make(map[EventType][]EventHandler),
-		events:      make(chan *DomainEvent, 100), // Буфер для 100 событий
-		ctx:         ctx,
-		cancel:      cancel,
+		subscribers:  make(map[EventType][]EventHandler),
+		events:       make(chan *DomainEvent, 100), // Буфер для 100 событий
+		ctx:          ctx,
+		cancel:       cancel,
+		pool:         newWorkerPool(ctx, pool),
+		drainTimeout: drainTimeout,
 	}
-	
+
 	// Запускаем горутину для обработки событий
 	publisher.wg.Add(1)
 	go publisher.processEvents()
-	
+
 	return publisher
 }
 
-// Publish публикует событие
+// Publish публикует событие. После Close возвращает ErrPublisherClosed
 func (p *InMemoryEventPublisher) Publish(ctx context.Context, event *DomainEvent) error {
+	p.closeMutex.RLock()
+	defer p.closeMutex.RUnlock()
+
+	if p.closed {
+		return ErrPublisherClosed
+	}
+
 	select {
 	case p.events <- event:
-		log.Printf("Событие опубликовано: %s (ID: %s, AggregateID: %s)", 
+		p.accepted.Add(1)
+		log.Printf("Событие опубликовано: %s (ID: %s, AggregateID: %s)",
 			event.Type, event.ID, event.AggregateID)
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-p.ctx.Done():
-		return fmt.Errorf("publisher закрыт")
 	default:
 		return fmt.Errorf("очередь событий переполнена")
 	}
@@ -66,65 +108,130 @@ func (p *InMemoryEventPublisher) Publish(ctx context.Context, event *DomainEvent
 func (p *InMemoryEventPublisher) Subscribe(eventType EventType, handler EventHandler) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
-	
+
 	p.subscribers[eventType] = append(p.subscribers[eventType], handler)
 	log.Printf("Добавлен обработчик для событий типа: %s", eventType)
-	
+
 	return nil
 }
 
-// processEvents обрабатывает события в отдельной горутине
+// processEvents передает события пулу подписчиков до закрытия канала или отмены
+// по таймауту закрытия, после чего закрывает очередь пула
 func (p *InMemoryEventPublisher) processEvents() {
 	defer p.wg.Done()
-	
+	defer p.pool.close()
+
 	for {
 		select {
-		case event := <-p.events:
+		case event, ok := <-p.events:
+			if !ok {
+				return
+			}
 			p.handleEvent(event)
 		case <-p.ctx.Done():
-			// Обрабатываем оставшиеся события перед закрытием
-			for {
-				select {
-				case event := <-p.events:
-					p.handleEvent(event)
-				default:
-					return
-				}
-			}
+			return
 		}
 	}
 }
 
-// handleEvent обрабатывает одно событие
+// handleEvent обрабатывает одно событие. Событие считается обработанным,
+// когда завершились все его подписчики
 func (p *InMemoryEventPublisher) handleEvent(event *DomainEvent) {
 	p.mutex.RLock()
 	handlers := p.subscribers[event.Type]
 	p.mutex.RUnlock()
-	
+
 	if len(handlers) == 0 {
 		log.Printf("Нет обработчиков для события: %s", event.Type)
+		p.completed.Add(1)
 		return
 	}
-	
-	// Обрабатываем событие всеми подписчиками
+
+	remaining := int32(len(handlers))
+	done := func() {
+		if atomic.AddInt32(&remaining, -1) == 0 {
+			p.completed.Add(1)
+		}
+	}
+
+	// Обрабатываем событие всеми подписчиками в пуле
 	for _, handler := range handlers {
-		go func(h EventHandler) {
-			ctx := context.Background()
-			if err := h(ctx, event); err != nil {
-				log.Printf("Ошибка обработки события %s: %v", event.Type, err)
-			}
-		}(handler)
+		p.pool.submit(p.ctx, handlerTask{handler: handler, event: event, done: done})
 	}
 }
 
-// Close закрывает publisher
+// PoolStats возвращает метрики пула подписчиков
+func (p *InMemoryEventPublisher) PoolStats() PoolStats {
+	return p.pool.stats()
+}
+
+// Close прекращает прием событий и дожидается обработки оставшихся в пределах таймаута.
+// Возвращает ошибку, если часть событий не успела обработаться
 func (p *InMemoryEventPublisher) Close() error {
+	report := p.Drain()
+	if report.Dropped > 0 {
+		return fmt.Errorf("при закрытии не обработано %d из %d событий", report.Dropped, report.Pending)
+	}
+	return nil
+}
+
+// Drain закрывает publisher и возвращает отчет; повторные вызовы возвращают тот же отчет
+func (p *InMemoryEventPublisher) Drain() DrainReport {
+	p.closeOnce.Do(func() {
+		p.report = p.drain()
+	})
+	return p.report
+}
+
+// drain прекращает прием событий и ожидает обработку очереди и подписчиков
+func (p *InMemoryEventPublisher) drain() DrainReport {
+	start := time.Now()
+
+	// После установки closed ни один Publish не пишет в канал, поэтому его можно закрыть
+	p.closeMutex.Lock()
+	p.closed = true
+	close(p.events)
+	p.closeMutex.Unlock()
+
+	pending := p.accepted.Load() - p.completed.Load()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		p.pool.wait()
+		close(done)
+	}()
+
+	var timeout <-chan time.Time
+	if p.drainTimeout > 0 {
+		timer := time.NewTimer(p.drainTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	timedOut := false
+	select {
+	case <-done:
+	case <-timeout:
+		timedOut = true
+	}
+
+	// Отмена прерывает обработку очереди и контекст подписчиков, не успевших завершиться
 	p.cancel()
 	p.wg.Wait()
-	close(p.events)
-	
-	log.Println("EventPublisher закрыт")
-	return nil
+
+	dropped := p.accepted.Load() - p.completed.Load()
+	report := DrainReport{
+		Pending:  pending,
+		Flushed:  pending - dropped,
+		Dropped:  dropped,
+		Duration: time.Since(start),
+		TimedOut: timedOut,
+	}
+
+	log.Printf("EventPublisher закрыт: обработано %d из %d событий, потеряно %d, за %v",
+		report.Flushed, report.Pending, report.Dropped, report.Duration)
+	return report
 }
 
 // KafkaEventPublisher заготовка для Kafka (для будущих итераций)
@@ -6,6 +6,9 @@ import (
 	"net/http"
 
 	"service_orders/models"
+	"service_orders/notifications"
+
+	"pkg/servertiming"
 
 	"github.com/google/uuid"
 )
@@ -13,12 +16,17 @@ import (
 // EventService сервис для работы с доменными событиями
 type EventService struct {
 	publisher EventPublisher
+	notifier  *notifications.Notifier
+	registry  *HandlerRegistry
 }
 
-// NewEventService создает новый сервис событий
-func NewEventService(publisher EventPublisher) *EventService {
+// NewEventService создает новый сервис событий. Обработчики регистрируются по имени,
+// их отключение администратором сохраняется в handlerRepo
+func NewEventService(publisher EventPublisher, notifier *notifications.Notifier, handlerRepo HandlerStateRepository) *EventService {
 	service := &EventService{
 		publisher: publisher,
+		notifier:  notifier,
+		registry:  NewHandlerRegistry(publisher, handlerRepo),
 	}
 	
 	// Регистрируем стандартные обработчики
@@ -29,29 +37,30 @@ func NewEventService(publisher EventPublisher) *EventService {
 
 // registerDefaultHandlers регистрирует стандартные обработчики событий
 func (s *EventService) registerDefaultHandlers() {
-	// Регистрируем базовые обработчики для логирования
-	for eventType, handler := range DefaultEventHandlers {
-		if err := s.publisher.Subscribe(eventType, handler); err != nil {
-			fmt.Printf("Ошибка регистрации базового обработчика для %s: %v\n", eventType, err)
+	// Базовые обработчики для логирования объединены в один именованный обработчик
+	logging := func(ctx context.Context, event *DomainEvent) error {
+		if handler, ok := DefaultEventHandlers[event.Type]; ok {
+			return handler(ctx, event)
 		}
+		return nil
 	}
 	
-	// Регистрируем дополнительные обработчики
+	// Регистрируем дополнительные обработчики; уведомления покупателям
+	// относятся только к событиям заказов
 	handlers := []struct {
-		name    string
-		handler EventHandler
+		name       string
+		handler    EventHandler
+		eventTypes []EventType
 	}{
-		{"analytics", AnalyticsEventHandler},
-		{"notifications", NotificationEventHandler},
-		{"audit", AuditEventHandler},
+		{"logging", logging, []EventType{OrderCreatedEvent, OrderStatusUpdatedEvent}},
+		{"analytics", AnalyticsEventHandler, []EventType{OrderCreatedEvent, OrderStatusUpdatedEvent, OrderFlaggedEvent, StockUpdatedEvent}},
+		{"notifications", NewNotificationEventHandler(s.notifier), []EventType{OrderCreatedEvent, OrderStatusUpdatedEvent}},
+		{"audit", AuditEventHandler, append([]EventType{OrderCreatedEvent, OrderStatusUpdatedEvent, OrderFlaggedEvent, StockUpdatedEvent}, AlertEventTypes...)},
 	}
 	
 	for _, h := range handlers {
-		// Подписываемся на все типы событий
-		for _, eventType := range []EventType{OrderCreatedEvent, OrderStatusUpdatedEvent} {
-			if err := s.publisher.Subscribe(eventType, h.handler); err != nil {
-				fmt.Printf("Ошибка регистрации %s обработчика для %s: %v\n", h.name, eventType, err)
-			}
+		if err := s.registry.Register(h.name, h.handler, h.eventTypes...); err != nil {
+			fmt.Printf("Ошибка регистрации обработчика %s: %v\n", h.name, err)
 		}
 	}
 	
@@ -63,7 +72,7 @@ func (s *EventService) PublishOrderCreated(ctx context.Context, order *models.Or
 	metadata := s.extractMetadata(r, "order.create")
 	event := NewOrderCreatedEvent(order, metadata)
 	
-	return s.publisher.Publish(ctx, event)
+	return s.publish(ctx, event, r)
 }
 
 // PublishOrderStatusUpdated публикует событие обновления статуса заказа
@@ -73,14 +82,55 @@ func (s *EventService) PublishOrderStatusUpdated(ctx context.Context, orderID, u
 	metadata := s.extractMetadata(r, "order.status.update")
 	event := NewOrderStatusUpdatedEvent(orderID, userID, updatedBy, oldStatus, newStatus, metadata)
 	
-	return s.publisher.Publish(ctx, event)
+	return s.publish(ctx, event, r)
 }
 
 // PublishOrderCancelled публикует событие отмены заказа (специальный случай обновления статуса)
 func (s *EventService) PublishOrderCancelled(ctx context.Context, orderID, userID, cancelledBy uuid.UUID, 
-	oldStatus models.OrderStatus, r *http.Request) error {
+	oldStatus models.OrderStatus, cancellation models.OrderCancellation, r *http.Request) error {
 	
-	return s.PublishOrderStatusUpdated(ctx, orderID, userID, cancelledBy, oldStatus, models.OrderStatusCancelled, r)
+	metadata := s.extractMetadata(r, "order.status.update")
+	event := NewOrderCancelledEvent(orderID, userID, cancelledBy, oldStatus, cancellation, metadata)
+
+	return s.publish(ctx, event, r)
+}
+
+// PublishOrderFlagged публикует событие заказа, помеченного проверками hooks перед созданием
+func (s *EventService) PublishOrderFlagged(ctx context.Context, order *models.Order, hooks string, r *http.Request) error {
+	metadata := s.extractMetadata(r, "order.create")
+	event := NewOrderFlaggedEvent(order, hooks, metadata)
+
+	return s.publish(ctx, event, r)
+}
+
+// PublishStockUpdated публикует событие изменения складского остатка
+func (s *EventService) PublishStockUpdated(ctx context.Context, level models.StockLevel, previous *int, r *http.Request) error {
+	metadata := s.extractMetadata(r, "stock.update")
+	event := NewStockUpdatedEvent(level, previous, metadata)
+
+	return s.publish(ctx, event, r)
+}
+
+// PublishAlert публикует событие об аномалии бизнес-метрики
+func (s *EventService) PublishAlert(ctx context.Context, data AlertEventData) error {
+	metadata := s.extractMetadata(nil, "alert")
+	event := NewAlertEvent(data, metadata)
+
+	return s.publisher.Publish(ctx, event)
+}
+
+// publish публикует событие; время публикации учитывается в Server-Timing запроса r
+func (s *EventService) publish(ctx context.Context, event *DomainEvent, r *http.Request) error {
+	if r != nil {
+		defer servertiming.FromContext(r.Context()).Start(servertiming.MetricPublish)()
+	}
+	return s.publisher.Publish(ctx, event)
+}
+
+// EnableAlertNotifications регистрирует обработчик alert_notifications, который
+// отправляет уведомления об аномалиях получателям recipients
+func (s *EventService) EnableAlertNotifications(recipients []uuid.UUID) error {
+	return s.registry.Register("alert_notifications", NewAlertNotificationHandler(s.notifier, recipients), AlertEventTypes...)
 }
 
 // extractMetadata извлекает метаданные из HTTP запроса
@@ -109,9 +159,25 @@ func generateCorrelationID(r *http.Request, operation string) string {
 	return fmt.Sprintf("%s-%s", requestID, operation)
 }
 
-// AddCustomHandler добавляет кастомный обработчик событий
-func (s *EventService) AddCustomHandler(eventType EventType, handler EventHandler) error {
-	return s.publisher.Subscribe(eventType, handler)
+// AddCustomHandler добавляет именованный обработчик событий, которым можно
+// управлять через административный API
+func (s *EventService) AddCustomHandler(name string, handler EventHandler, eventTypes ...EventType) error {
+	return s.registry.Register(name, handler, eventTypes...)
+}
+
+// Handlers возвращает зарегистрированные обработчики событий
+func (s *EventService) Handlers() ([]HandlerInfo, error) {
+	return s.registry.List()
+}
+
+// SetHandlerEnabled включает или отключает обработчик событий
+func (s *EventService) SetHandlerEnabled(name string, enabled bool, reason string, actor uuid.UUID) (HandlerInfo, error) {
+	return s.registry.SetEnabled(name, enabled, reason, actor)
+}
+
+// HandlerErrors возвращает последние ошибки обработчика событий
+func (s *EventService) HandlerErrors(name string, limit int) ([]HandlerError, error) {
+	return s.registry.Errors(name, limit)
 }
 
 // Close закрывает сервис событий
@@ -119,7 +185,17 @@ func (s *EventService) Close() error {
 	return s.publisher.Close()
 }
 
-// GetStats возвращает статистику событий
+// GetStats возвращает статистику событий и метрики пула подписчиков, если publisher их ведет
 func (s *EventService) GetStats() map[string]int64 {
-	return GetEventStats()
+	stats := GetEventStats()
+	if pooled, ok := s.publisher.(interface{ PoolStats() PoolStats }); ok {
+		pool := pooled.PoolStats()
+		stats["pool_workers"] = int64(pool.Workers)
+		stats["pool_busy"] = pool.Busy
+		stats["pool_queued"] = int64(pool.Queued)
+		stats["pool_executed"] = pool.Executed
+		stats["pool_dropped"] = pool.Dropped
+		stats["pool_overflows"] = pool.Overflows
+	}
+	return stats
 }
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxHandlerErrorSamples число последних ошибок, хранимых для каждого обработчика
+const maxHandlerErrorSamples = 50
+
+// maxHandlerErrorLength максимальная длина сохраняемого текста ошибки
+const maxHandlerErrorLength = 1000
+
+// HandlerState состояние обработчика, заданное администратором
+type HandlerState struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Reason причина отключения (например, сбой почтового провайдера)
+	Reason    string     `json:"reason,omitempty"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// HandlerError образец ошибки обработчика
+type HandlerError struct {
+	Handler    string    `json:"handler"`
+	EventID    uuid.UUID `json:"event_id"`
+	EventType  EventType `json:"event_type"`
+	Error      string    `json:"error"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// HandlerStateRepository интерфейс хранения состояния обработчиков и их ошибок,
+// чтобы отключение обработчика сохранялось после перезапуска сервиса
+type HandlerStateRepository interface {
+	LoadStates() (map[string]HandlerState, error)
+	SaveState(state HandlerState) error
+	RecordError(sample HandlerError) error
+	RecentErrors(handler string, limit int) ([]HandlerError, error)
+}
+
+// handlerStateRepository реализация HandlerStateRepository
+type handlerStateRepository struct {
+	db *sql.DB
+}
+
+// NewHandlerStateRepository создает новый экземпляр HandlerStateRepository
+func NewHandlerStateRepository(db *sql.DB) HandlerStateRepository {
+	return &handlerStateRepository{db: db}
+}
+
+// LoadStates возвращает сохраненные состояния обработчиков по имени
+func (r *handlerStateRepository) LoadStates() (map[string]HandlerState, error) {
+	rows, err := r.db.Query(`SELECT name, enabled, reason, updated_by, updated_at FROM event_handler_states`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения состояний обработчиков: %v", err)
+	}
+	defer rows.Close()
+
+	states := make(map[string]HandlerState)
+	for rows.Next() {
+		var state HandlerState
+		var updatedBy uuid.NullUUID
+		var updatedAt sql.NullTime
+		if err := rows.Scan(&state.Name, &state.Enabled, &state.Reason, &updatedBy, &updatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования состояния обработчика: %v", err)
+		}
+		if updatedBy.Valid {
+			state.UpdatedBy = &updatedBy.UUID
+		}
+		if updatedAt.Valid {
+			state.UpdatedAt = &updatedAt.Time
+		}
+		states[state.Name] = state
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения состояний обработчиков: %v", err)
+	}
+	return states, nil
+}
+
+// SaveState сохраняет состояние обработчика
+func (r *handlerStateRepository) SaveState(state HandlerState) error {
+	query := `
+		INSERT INTO event_handler_states (name, enabled, reason, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (name) DO UPDATE
+		SET enabled = EXCLUDED.enabled, reason = EXCLUDED.reason,
+		    updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := r.db.Exec(query, state.Name, state.Enabled, state.Reason, state.UpdatedBy, state.UpdatedAt); err != nil {
+		return fmt.Errorf("ошибка сохранения состояния обработчика: %v", err)
+	}
+	return nil
+}
+
+// RecordError сохраняет ошибку обработчика и удаляет образцы сверх maxHandlerErrorSamples
+func (r *handlerStateRepository) RecordError(sample HandlerError) error {
+	if len(sample.Error) > maxHandlerErrorLength {
+		sample.Error = sample.Error[:maxHandlerErrorLength]
+	}
+
+	_, err := r.db.Exec(`
+		INSERT INTO event_handler_errors (handler, event_id, event_type, error, occurred_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, sample.Handler, sample.EventID, string(sample.EventType), sample.Error, sample.OccurredAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения ошибки обработчика: %v", err)
+	}
+
+	_, err = r.db.Exec(`
+		DELETE FROM event_handler_errors
+		WHERE handler = $1 AND id NOT IN (
+			SELECT id FROM event_handler_errors WHERE handler = $1
+			ORDER BY occurred_at DESC, id DESC LIMIT $2
+		)
+	`, sample.Handler, maxHandlerErrorSamples)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления старых ошибок обработчика: %v", err)
+	}
+	return nil
+}
+
+// RecentErrors возвращает последние ошибки обработчика, новые первыми
+func (r *handlerStateRepository) RecentErrors(handler string, limit int) ([]HandlerError, error) {
+	rows, err := r.db.Query(`
+		SELECT handler, event_id, event_type, error, occurred_at
+		FROM event_handler_errors
+		WHERE handler = $1
+		ORDER BY occurred_at DESC, id DESC
+		LIMIT $2
+	`, handler, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ошибок обработчика: %v", err)
+	}
+	defer rows.Close()
+
+	samples := []HandlerError{}
+	for rows.Next() {
+		var sample HandlerError
+		var eventType string
+		if err := rows.Scan(&sample.Handler, &sample.EventID, &eventType, &sample.Error, &sample.OccurredAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования ошибки обработчика: %v", err)
+		}
+		sample.EventType = EventType(eventType)
+		samples = append(samples, sample)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения ошибок обработчика: %v", err)
+	}
+	return samples, nil
+}
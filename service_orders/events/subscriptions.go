@@ -0,0 +1,226 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+)
+
+// ErrHandlerNotFound обработчик с указанным именем не зарегистрирован
+var ErrHandlerNotFound = errors.New("обработчик событий не найден")
+
+// recentErrorsInList число последних ошибок в списке обработчиков
+const recentErrorsInList = 3
+
+// HandlerInfo состояние и статистика обработчика с момента запуска сервиса
+type HandlerInfo struct {
+	HandlerState
+	EventTypes []EventType `json:"event_types"`
+	Processed  int64       `json:"processed"`
+	Failed     int64       `json:"failed"`
+	// Skipped события, пропущенные, пока обработчик был отключен
+	Skipped      int64          `json:"skipped"`
+	RecentErrors []HandlerError `json:"recent_errors"`
+}
+
+// HandlerRegistry именованные подписки на события, которые администратор может
+// временно отключать. Отключенный обработчик пропускает события, а не откладывает их
+type HandlerRegistry struct {
+	publisher EventPublisher
+	repo      HandlerStateRepository
+
+	mutex    sync.RWMutex
+	handlers []*registeredHandler
+	// saved состояния, загруженные при запуске; применяются при регистрации
+	saved map[string]HandlerState
+}
+
+// registeredHandler зарегистрированный обработчик и его счетчики
+type registeredHandler struct {
+	name       string
+	eventTypes []EventType
+	enabled    atomic.Bool
+	processed  atomic.Int64
+	failed     atomic.Int64
+	skipped    atomic.Int64
+
+	// state изменяется под mutex реестра
+	state HandlerState
+}
+
+// NewHandlerRegistry создает реестр и загружает сохраненные состояния обработчиков.
+// Если состояния загрузить не удалось, все обработчики запускаются включенными
+func NewHandlerRegistry(publisher EventPublisher, repo HandlerStateRepository) *HandlerRegistry {
+	saved, err := repo.LoadStates()
+	if err != nil {
+		log.Printf("Не удалось загрузить состояния обработчиков событий, все обработчики включены: %v", err)
+		saved = map[string]HandlerState{}
+	}
+
+	return &HandlerRegistry{
+		publisher: publisher,
+		repo:      repo,
+		saved:     saved,
+	}
+}
+
+// Register подписывает обработчик с именем name на типы событий
+func (r *HandlerRegistry) Register(name string, handler EventHandler, eventTypes ...EventType) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.handlers {
+		if existing.name == name {
+			return fmt.Errorf("обработчик %s уже зарегистрирован", name)
+		}
+	}
+
+	h := &registeredHandler{
+		name:       name,
+		eventTypes: eventTypes,
+		state:      HandlerState{Name: name, Enabled: true},
+	}
+	if state, ok := r.saved[name]; ok {
+		h.state = state
+		if !state.Enabled {
+			log.Printf("Обработчик событий %s отключен администратором: %s", name, state.Reason)
+		}
+	}
+	h.enabled.Store(h.state.Enabled)
+
+	wrapped := r.wrap(h, handler)
+	for _, eventType := range eventTypes {
+		if err := r.publisher.Subscribe(eventType, wrapped); err != nil {
+			return fmt.Errorf("ошибка подписки обработчика %s на %s: %v", name, eventType, err)
+		}
+	}
+
+	r.handlers = append(r.handlers, h)
+	return nil
+}
+
+// wrap учитывает состояние и результат обработчика
+func (r *HandlerRegistry) wrap(h *registeredHandler, handler EventHandler) EventHandler {
+	return func(ctx context.Context, event *DomainEvent) error {
+		if !h.enabled.Load() {
+			h.skipped.Add(1)
+			return nil
+		}
+
+		h.processed.Add(1)
+		err := handler(ctx, event)
+		if err != nil {
+			h.failed.Add(1)
+			sample := HandlerError{
+				Handler:    h.name,
+				EventID:    event.ID,
+				EventType:  event.Type,
+				Error:      err.Error(),
+				OccurredAt: timeutil.Now(),
+			}
+			if recordErr := r.repo.RecordError(sample); recordErr != nil {
+				log.Printf("Не удалось сохранить ошибку обработчика %s: %v", sample.Handler, recordErr)
+			}
+		}
+		return err
+	}
+}
+
+// List возвращает обработчики в порядке регистрации с последними ошибками
+func (r *HandlerRegistry) List() ([]HandlerInfo, error) {
+	r.mutex.RLock()
+	handlers := append([]*registeredHandler(nil), r.handlers...)
+	r.mutex.RUnlock()
+
+	infos := make([]HandlerInfo, 0, len(handlers))
+	for _, h := range handlers {
+		info, err := r.info(h, recentErrorsInList)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// SetEnabled включает или отключает обработчик. Состояние сохраняется до применения,
+// поэтому ответ об успехе означает, что оно переживет перезапуск
+func (r *HandlerRegistry) SetEnabled(name string, enabled bool, reason string, actor uuid.UUID) (HandlerInfo, error) {
+	r.mutex.Lock()
+	h := r.find(name)
+	if h == nil {
+		r.mutex.Unlock()
+		return HandlerInfo{}, ErrHandlerNotFound
+	}
+
+	now := timeutil.Now()
+	if enabled {
+		reason = ""
+	}
+	state := HandlerState{
+		Name:      name,
+		Enabled:   enabled,
+		Reason:    reason,
+		UpdatedBy: &actor,
+		UpdatedAt: &now,
+	}
+	if err := r.repo.SaveState(state); err != nil {
+		r.mutex.Unlock()
+		return HandlerInfo{}, err
+	}
+	h.state = state
+	h.enabled.Store(enabled)
+	r.mutex.Unlock()
+
+	log.Printf("Обработчик событий %s: enabled=%t, администратор %s, причина: %s", name, enabled, actor, reason)
+	return r.info(h, recentErrorsInList)
+}
+
+// Errors возвращает последние ошибки обработчика
+func (r *HandlerRegistry) Errors(name string, limit int) ([]HandlerError, error) {
+	r.mutex.RLock()
+	h := r.find(name)
+	r.mutex.RUnlock()
+	if h == nil {
+		return nil, ErrHandlerNotFound
+	}
+	return r.repo.RecentErrors(name, limit)
+}
+
+// find ищет обработчик по имени; вызывается под mutex
+func (r *HandlerRegistry) find(name string) *registeredHandler {
+	for _, h := range r.handlers {
+		if h.name == name {
+			return h
+		}
+	}
+	return nil
+}
+
+// info собирает состояние, счетчики и последние ошибки обработчика
+func (r *HandlerRegistry) info(h *registeredHandler, errorsLimit int) (HandlerInfo, error) {
+	r.mutex.RLock()
+	state := h.state
+	r.mutex.RUnlock()
+
+	recent, err := r.repo.RecentErrors(h.name, errorsLimit)
+	if err != nil {
+		return HandlerInfo{}, err
+	}
+
+	return HandlerInfo{
+		HandlerState: state,
+		EventTypes:   h.eventTypes,
+		Processed:    h.processed.Load(),
+		Failed:       h.failed.Load(),
+		Skipped:      h.skipped.Load(),
+		RecentErrors: recent,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Минимальный генератор PDF для печатных выгрузок: моноширинный текст постранично.
+// Используется стандартный шрифт Courier, который не встраивается в документ;
+// кириллица кодируется однобайтово по именам глифов Adobe (afii10017...), поэтому
+// внешние зависимости не нужны. Символы вне латиницы и кириллицы заменяются на "?"
+const (
+	pdfPageWidth  = 595 // A4 в пунктах
+	pdfPageHeight = 842
+	pdfMargin     = 40
+	pdfFontSize   = 9
+	pdfLeading    = 12
+	// pdfLineWidth символов в строке: ширина символа Courier — 0.6 кегля
+	pdfLineWidth = (pdfPageWidth - 2*pdfMargin) * 10 / (pdfFontSize * 6)
+	// pdfPageLines строк на странице без строки с номером страницы
+	pdfPageLines = (pdfPageHeight-2*pdfMargin)/pdfLeading - 2
+)
+
+// writePDF пишет строки текста в PDF документ, разбивая их на страницы
+func writePDF(w io.Writer, lines []string) error {
+	var pages [][]string
+	for start := 0; start < len(lines) || start == 0; start += pdfPageLines {
+		end := start + pdfPageLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		pages = append(pages, lines[start:end])
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding " + pdfCyrillicEncoding() + " >>")
+
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+2*i))
+
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin-pdfFontSize)
+		for _, line := range page {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", pdfText(line))
+		}
+		footer := fmt.Sprintf("Стр. %d из %d", i+1, len(pages))
+		fmt.Fprintf(&content, "ET\nBT\n/F1 %d Tf\n%d %d Td\n(%s) Tj\nET\n", pdfFontSize, pdfMargin, pdfMargin, pdfText(footer))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfCyrillicEncoding кодировка шрифта: WinAnsi с кириллицей на месте кодов Windows-1251
+func pdfCyrillicEncoding() string {
+	var differences strings.Builder
+	differences.WriteString("[168 /afii10023 184 /afii10071 192")
+	for i := 0; i < 32; i++ {
+		fmt.Fprintf(&differences, " /afii%d", cyrillicGlyph(10017, i))
+	}
+	for i := 0; i < 32; i++ {
+		fmt.Fprintf(&differences, " /afii%d", cyrillicGlyph(10065, i))
+	}
+	differences.WriteString("]")
+	return "<< /Type /Encoding /BaseEncoding /WinAnsiEncoding /Differences " + differences.String() + " >>"
+}
+
+// cyrillicGlyph номер глифа Adobe для i-й буквы алфавита без Ё (А-Я или а-я):
+// в нумерации Adobe Ё стоит после Е
+func cyrillicGlyph(first, i int) int {
+	if i >= 6 {
+		return first + i + 1
+	}
+	return first + i
+}
+
+// pdfText кодирует строку для текстового оператора PDF с экранированием скобок
+func pdfText(s string) string {
+	var buf bytes.Buffer
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(byte(r))
+		case r >= 0x20 && r < 0x7f:
+			buf.WriteByte(byte(r))
+		case r >= 'А' && r <= 'Я':
+			buf.WriteByte(byte(0xC0 + r - 'А'))
+		case r >= 'а' && r <= 'я':
+			buf.WriteByte(byte(0xE0 + r - 'а'))
+		case r == 'Ё':
+			buf.WriteByte(0xA8)
+		case r == 'ё':
+			buf.WriteByte(0xB8)
+		case r == '—' || r == '–':
+			buf.WriteByte('-')
+		default:
+			buf.WriteByte('?')
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,85 @@
+// Package export выгружает сборочные листы склада в CSV и PDF
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"service_orders/models"
+)
+
+// PickingListCSV пишет сборочный лист в CSV: строка на каждую пару товар — заказ,
+// общее количество товара повторяется в каждой его строке
+func PickingListCSV(w io.Writer, list *models.PickingList) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"product", "total_quantity", "order_id", "quantity"}); err != nil {
+		return err
+	}
+	for _, item := range list.Items {
+		total := strconv.Itoa(item.TotalQuantity)
+		for _, order := range item.Orders {
+			if err := writer.Write([]string{item.Product, total, order.OrderID.String(), strconv.Itoa(order.Quantity)}); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// PickingListPDF пишет сборочный лист в PDF для печати: товар с общим количеством
+// и отметкой сборки, под ним заказы с количеством товара в каждом
+func PickingListPDF(w io.Writer, list *models.PickingList) error {
+	status := string(list.Status)
+	if list.StatusName != "" {
+		status = list.StatusName
+	}
+	region := list.Region
+	if region == "" {
+		region = "все"
+	}
+
+	lines := []string{
+		"СБОРОЧНЫЙ ЛИСТ",
+		"",
+		fmt.Sprintf("Статус заказов: %s", status),
+		fmt.Sprintf("Регион: %s", region),
+		fmt.Sprintf("Сформирован: %s UTC", list.GeneratedAt.UTC().Format(time.DateTime)),
+		fmt.Sprintf("Заказов: %d, товаров: %d", list.OrdersCount, len(list.Items)),
+		"",
+		padRight("    Товар", pdfLineWidth-10) + fmt.Sprintf("%10s", "Кол-во"),
+		repeat('-', pdfLineWidth),
+	}
+	for _, item := range list.Items {
+		lines = append(lines, padRight("[ ] "+item.Product, pdfLineWidth-10)+fmt.Sprintf("%10d", item.TotalQuantity))
+		for _, order := range item.Orders {
+			lines = append(lines, padRight("      заказ "+order.OrderID.String(), pdfLineWidth-10)+fmt.Sprintf("%10d", order.Quantity))
+		}
+	}
+	if len(list.Items) == 0 {
+		lines = append(lines, "Нет заказов для сборки")
+	}
+
+	return writePDF(w, lines)
+}
+
+// padRight дополняет строку пробелами или обрезает ее до width символов
+func padRight(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "~"
+	}
+	return s + repeat(' ', width-len(runes))
+}
+
+// repeat возвращает строку из n символов r
+func repeat(r rune, n int) string {
+	runes := make([]rune, n)
+	for i := range runes {
+		runes[i] = r
+	}
+	return string(runes)
+}
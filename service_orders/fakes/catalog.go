@@ -0,0 +1,220 @@
+package fakes
+
+import (
+	"sync"
+
+	"service_orders/models"
+	"service_orders/repository"
+
+	"pkg/fakes"
+
+	"github.com/google/uuid"
+)
+
+var (
+	_ repository.StatusRepository   = (*StatusRepository)(nil)
+	_ repository.CustomerRepository = (*CustomerRepository)(nil)
+	_ repository.StockRepository    = (*StockRepository)(nil)
+)
+
+// orderStatuses статусы справочника с порядком сортировки, как в init.sql
+var orderStatuses = []struct {
+	code      models.OrderStatus
+	sortOrder int
+}{
+	{models.OrderStatusFlagged, 5},
+	{models.OrderStatusCreated, 10},
+	{models.OrderStatusInWork, 20},
+	{models.OrderStatusCompleted, 30},
+	{models.OrderStatusCancelled, 40},
+}
+
+// StatusRepository in-memory реализация repository.StatusRepository: коды статусов
+// фиксированы, названия хранятся по локалям. Как и SQL-реализация, при отсутствии
+// перевода возвращает название на repository.DefaultStatusLocale, иначе код
+type StatusRepository struct {
+	fakes.Failures
+
+	mutex sync.RWMutex
+	names map[string]map[models.OrderStatus]string
+}
+
+// NewStatusRepository создает справочник статусов без переводов
+func NewStatusRepository() *StatusRepository {
+	return &StatusRepository{names: make(map[string]map[models.OrderStatus]string)}
+}
+
+// SeedNames добавляет названия статусов на локали в обход инъекции ошибок
+func (r *StatusRepository) SeedNames(locale string, names map[models.OrderStatus]string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for code, name := range names {
+		r.setName(code, locale, name)
+	}
+}
+
+// List возвращает справочник статусов с названиями на указанной локали
+func (r *StatusRepository) List(locale string) ([]models.OrderStatusInfo, error) {
+	if err := r.Check("List"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	statuses := make([]models.OrderStatusInfo, 0, len(orderStatuses))
+	for _, status := range orderStatuses {
+		name, nameLocale := r.displayName(status.code, locale)
+		statuses = append(statuses, models.OrderStatusInfo{
+			Code:        status.code,
+			DisplayName: name,
+			Locale:      nameLocale,
+			SortOrder:   status.sortOrder,
+			IsFinal:     status.code == models.OrderStatusCompleted || status.code == models.OrderStatusCancelled,
+		})
+	}
+	return statuses, nil
+}
+
+// DisplayNames возвращает названия всех статусов на указанной локали
+func (r *StatusRepository) DisplayNames(locale string) (map[models.OrderStatus]string, error) {
+	if err := r.Check("DisplayNames"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := make(map[models.OrderStatus]string, len(orderStatuses))
+	for _, status := range orderStatuses {
+		names[status.code], _ = r.displayName(status.code, locale)
+	}
+	return names, nil
+}
+
+// Exists проверяет наличие статуса в справочнике
+func (r *StatusRepository) Exists(code models.OrderStatus) (bool, error) {
+	if err := r.Check("Exists"); err != nil {
+		return false, err
+	}
+	return code.IsValid(), nil
+}
+
+// UpsertTranslation создает или обновляет локализованное название статуса
+func (r *StatusRepository) UpsertTranslation(code models.OrderStatus, locale, displayName string) error {
+	if err := r.Check("UpsertTranslation"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.setName(code, locale, displayName)
+	return nil
+}
+
+// displayName возвращает название и его локаль с откатом на локаль по умолчанию,
+// а без перевода — код и пустую локаль; вызывается под блокировкой
+func (r *StatusRepository) displayName(code models.OrderStatus, locale string) (string, string) {
+	for _, candidate := range []string{locale, repository.DefaultStatusLocale} {
+		if name, ok := r.names[candidate][code]; ok {
+			return name, candidate
+		}
+	}
+	return string(code), ""
+}
+
+// setName сохраняет название; вызывается под блокировкой
+func (r *StatusRepository) setName(code models.OrderStatus, locale, name string) {
+	if r.names[locale] == nil {
+		r.names[locale] = make(map[models.OrderStatus]string)
+	}
+	r.names[locale][code] = name
+}
+
+// CustomerRepository in-memory реализация repository.CustomerRepository.
+// Число пакетных запросов доступно через Calls("GetByIDs")
+type CustomerRepository struct {
+	fakes.Failures
+
+	mutex     sync.RWMutex
+	customers map[uuid.UUID]models.Customer
+}
+
+// NewCustomerRepository создает пустой фейк репозитория покупателей
+func NewCustomerRepository() *CustomerRepository {
+	return &CustomerRepository{customers: make(map[uuid.UUID]models.Customer)}
+}
+
+// Seed добавляет покупателей в обход инъекции ошибок
+func (r *CustomerRepository) Seed(customers map[uuid.UUID]models.Customer) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for id, customer := range customers {
+		r.customers[id] = customer
+	}
+}
+
+// GetByIDs возвращает известных покупателей из списка ID
+func (r *CustomerRepository) GetByIDs(ids []uuid.UUID) (map[uuid.UUID]models.Customer, error) {
+	if err := r.Check("GetByIDs"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	customers := make(map[uuid.UUID]models.Customer, len(ids))
+	for _, id := range ids {
+		if customer, ok := r.customers[id]; ok {
+			customers[id] = customer
+		}
+	}
+	return customers, nil
+}
+
+// StockRepository in-memory реализация repository.StockRepository
+type StockRepository struct {
+	fakes.Failures
+
+	mutex  sync.RWMutex
+	levels map[string]models.StockLevel
+}
+
+// NewStockRepository создает фейк складских остатков без записей
+func NewStockRepository() *StockRepository {
+	return &StockRepository{levels: make(map[string]models.StockLevel)}
+}
+
+// Apply сохраняет остаток, если он новее сохраненного, и возвращает предыдущее значение
+func (r *StockRepository) Apply(level models.StockLevel) (*int, bool, error) {
+	if err := r.Check("Apply"); err != nil {
+		return nil, false, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	current, ok := r.levels[level.Product]
+	if ok && !current.ChangedAt.Before(level.ChangedAt) {
+		return nil, false, nil
+	}
+	r.levels[level.Product] = level
+	if !ok {
+		return nil, true, nil
+	}
+	previous := current.Available
+	return &previous, true, nil
+}
+
+// Available возвращает остатки товаров, для которых есть запись
+func (r *StockRepository) Available(products []string) (map[string]int, error) {
+	if err := r.Check("Available"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	available := make(map[string]int, len(products))
+	for _, product := range products {
+		if level, ok := r.levels[product]; ok {
+			available[product] = level.Available
+		}
+	}
+	return available, nil
+}
@@ -0,0 +1,64 @@
+package fakes
+
+import (
+	"context"
+	"net/http"
+
+	"service_orders/events"
+	"service_orders/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	_ events.EventPublisherFacade = (*EventFacade)(nil)
+	_ events.StockEventPublisher  = (*EventFacade)(nil)
+)
+
+// EventFacade реализация events.EventPublisherFacade и events.StockEventPublisher
+// поверх фейка EventPublisher: строит доменные события теми же конструкторами, что
+// EventService, и публикует их синхронно. Опубликованные события и инъекция ошибок
+// доступны через встроенный EventPublisher (метод Publish)
+type EventFacade struct {
+	*EventPublisher
+}
+
+// NewEventFacade создает фасад публикации с пустым фейком публикатора
+func NewEventFacade() *EventFacade {
+	return &EventFacade{EventPublisher: NewEventPublisher()}
+}
+
+// PublishOrderCreated публикует событие создания заказа
+func (f *EventFacade) PublishOrderCreated(ctx context.Context, order *models.Order, r *http.Request) error {
+	return f.Publish(ctx, events.NewOrderCreatedEvent(order, metadata(r)))
+}
+
+// PublishOrderStatusUpdated публикует событие обновления статуса заказа
+func (f *EventFacade) PublishOrderStatusUpdated(ctx context.Context, orderID, userID, updatedBy uuid.UUID,
+	oldStatus, newStatus models.OrderStatus, r *http.Request) error {
+	return f.Publish(ctx, events.NewOrderStatusUpdatedEvent(orderID, userID, updatedBy, oldStatus, newStatus, metadata(r)))
+}
+
+// PublishOrderCancelled публикует событие отмены заказа
+func (f *EventFacade) PublishOrderCancelled(ctx context.Context, orderID, userID, cancelledBy uuid.UUID,
+	oldStatus models.OrderStatus, cancellation models.OrderCancellation, r *http.Request) error {
+	return f.Publish(ctx, events.NewOrderCancelledEvent(orderID, userID, cancelledBy, oldStatus, cancellation, metadata(r)))
+}
+
+// PublishOrderFlagged публикует событие заказа, помеченного проверками перед созданием
+func (f *EventFacade) PublishOrderFlagged(ctx context.Context, order *models.Order, hooks string, r *http.Request) error {
+	return f.Publish(ctx, events.NewOrderFlaggedEvent(order, hooks, metadata(r)))
+}
+
+// PublishStockUpdated публикует событие изменения складского остатка
+func (f *EventFacade) PublishStockUpdated(ctx context.Context, level models.StockLevel, previous *int, r *http.Request) error {
+	return f.Publish(ctx, events.NewStockUpdatedEvent(level, previous, metadata(r)))
+}
+
+// metadata метаданные события из запроса r
+func metadata(r *http.Request) events.Metadata {
+	if r == nil {
+		return events.Metadata{Source: "service_orders"}
+	}
+	return events.Metadata{RequestID: r.Header.Get("X-Request-ID"), Source: "service_orders"}
+}
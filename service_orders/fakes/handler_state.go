@@ -0,0 +1,81 @@
+package fakes
+
+import (
+	"sync"
+
+	"service_orders/events"
+
+	"pkg/fakes"
+)
+
+var _ events.HandlerStateRepository = (*HandlerStateRepository)(nil)
+
+// HandlerStateRepository in-memory реализация events.HandlerStateRepository
+type HandlerStateRepository struct {
+	fakes.Failures
+
+	mutex  sync.Mutex
+	states map[string]events.HandlerState
+	errors []events.HandlerError
+}
+
+// NewHandlerStateRepository создает хранилище без сохраненных состояний
+func NewHandlerStateRepository() *HandlerStateRepository {
+	return &HandlerStateRepository{states: make(map[string]events.HandlerState)}
+}
+
+// LoadStates возвращает сохраненные состояния обработчиков
+func (r *HandlerStateRepository) LoadStates() (map[string]events.HandlerState, error) {
+	if err := r.Check("LoadStates"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	states := make(map[string]events.HandlerState, len(r.states))
+	for name, state := range r.states {
+		states[name] = state
+	}
+	return states, nil
+}
+
+// SaveState сохраняет состояние обработчика
+func (r *HandlerStateRepository) SaveState(state events.HandlerState) error {
+	if err := r.Check("SaveState"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.states[state.Name] = state
+	return nil
+}
+
+// RecordError сохраняет ошибку обработчика
+func (r *HandlerStateRepository) RecordError(sample events.HandlerError) error {
+	if err := r.Check("RecordError"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.errors = append(r.errors, sample)
+	return nil
+}
+
+// RecentErrors возвращает последние ошибки обработчика, новые первыми
+func (r *HandlerStateRepository) RecentErrors(handler string, limit int) ([]events.HandlerError, error) {
+	if err := r.Check("RecentErrors"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	samples := []events.HandlerError{}
+	for i := len(r.errors) - 1; i >= 0 && len(samples) < limit; i-- {
+		if r.errors[i].Handler == handler {
+			samples = append(samples, r.errors[i])
+		}
+	}
+	return samples, nil
+}
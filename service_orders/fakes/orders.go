@@ -0,0 +1,277 @@
+// Package fakes содержит in-memory фейки репозиториев и публикатора событий
+// service_orders для тестов обработчиков. Ошибки внедряются через
+// pkg/fakes.Failures по имени метода
+package fakes
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"service_orders/models"
+	"service_orders/repository"
+
+	"pkg/fakes"
+
+	"github.com/google/uuid"
+)
+
+var _ repository.OrderRepository = (*OrderRepository)(nil)
+
+// OrderRepository in-memory реализация repository.OrderRepository. Как и таблица
+// orders, требует существования пользователя: перед созданием заказа его ID нужно
+// зарегистрировать через AddUsers. Ошибки внедряются через встроенный
+// fakes.Failures по имени метода
+type OrderRepository struct {
+	fakes.Failures
+
+	mutex  sync.RWMutex
+	orders map[uuid.UUID]*models.Order
+	users  map[uuid.UUID]struct{}
+	// history снимки состояний заказов, как в order_history
+	history map[uuid.UUID][]*models.Order
+}
+
+// NewOrderRepository создает пустой фейк репозитория заказов
+func NewOrderRepository() *OrderRepository {
+	return &OrderRepository{
+		orders:  make(map[uuid.UUID]*models.Order),
+		users:   make(map[uuid.UUID]struct{}),
+		history: make(map[uuid.UUID][]*models.Order),
+	}
+}
+
+// AddUsers регистрирует пользователей, для которых можно создавать заказы
+func (r *OrderRepository) AddUsers(ids ...uuid.UUID) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, id := range ids {
+		r.users[id] = struct{}{}
+	}
+}
+
+// Seed добавляет заказы в обход проверок и инъекции ошибок; владельцы заказов
+// регистрируются автоматически
+func (r *OrderRepository) Seed(orders ...*models.Order) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, order := range orders {
+		r.users[order.UserID] = struct{}{}
+		r.orders[order.ID] = cloneOrder(order)
+		r.record(r.orders[order.ID])
+	}
+}
+
+// Create создает новый заказ
+func (r *OrderRepository) Create(order *models.Order) error {
+	if err := r.Check("Create"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.users[order.UserID]; !ok {
+		return fmt.Errorf("пользователь с ID %s не существует", order.UserID)
+	}
+	if _, ok := r.orders[order.ID]; ok {
+		return fmt.Errorf("ошибка создания заказа: заказ с ID %s уже существует", order.ID)
+	}
+	r.orders[order.ID] = cloneOrder(order)
+	r.record(r.orders[order.ID])
+	return nil
+}
+
+// GetByID получает заказ по ID
+func (r *OrderRepository) GetByID(id uuid.UUID) (*models.Order, error) {
+	if err := r.Check("GetByID"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, fmt.Errorf("заказ с ID %s не найден", id)
+	}
+	return cloneOrder(order), nil
+}
+
+// GetByUserID получает заказы пользователя с фильтрацией и пагинацией
+func (r *OrderRepository) GetByUserID(userID uuid.UUID, req *models.ListOrdersRequest) (*models.ListOrdersResponse, error) {
+	if err := r.Check("GetByUserID"); err != nil {
+		return nil, err
+	}
+	return r.list(func(order *models.Order) bool { return order.UserID == userID }, req), nil
+}
+
+// List получает заказы всех пользователей с фильтрацией и пагинацией
+func (r *OrderRepository) List(req *models.ListOrdersRequest) (*models.ListOrdersResponse, error) {
+	if err := r.Check("List"); err != nil {
+		return nil, err
+	}
+	return r.list(func(*models.Order) bool { return true }, req), nil
+}
+
+// Update обновляет позиции, статус и сумму заказа
+func (r *OrderRepository) Update(order *models.Order) error {
+	if err := r.Check("Update"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored, ok := r.orders[order.ID]
+	if !ok {
+		return fmt.Errorf("заказ с ID %s не найден", order.ID)
+	}
+	stored.Items = append([]models.OrderItem(nil), order.Items...)
+	stored.Status = order.Status
+	stored.TotalSum = order.TotalSum
+	stored.UpdatedAt = time.Now()
+	r.record(stored)
+	return nil
+}
+
+// UpdateStatus обновляет статус заказа
+func (r *OrderRepository) UpdateStatus(id uuid.UUID, status models.OrderStatus) error {
+	if err := r.Check("UpdateStatus"); err != nil {
+		return err
+	}
+	return r.setStatus(id, status)
+}
+
+// Cancel отменяет заказ
+func (r *OrderRepository) Cancel(id uuid.UUID) error {
+	if err := r.Check("Cancel"); err != nil {
+		return err
+	}
+	return r.setStatus(id, models.OrderStatusCancelled)
+}
+
+// setStatus меняет статус заказа без учета вызова в счетчиках
+func (r *OrderRepository) setStatus(id uuid.UUID, status models.OrderStatus) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored, ok := r.orders[id]
+	if !ok {
+		return fmt.Errorf("заказ с ID %s не найден", id)
+	}
+	stored.Status = status
+	stored.UpdatedAt = time.Now()
+	r.record(stored)
+	return nil
+}
+
+// GetStateAt возвращает состояние заказа из последнего снимка истории не позже asOf
+func (r *OrderRepository) GetStateAt(id uuid.UUID, asOf time.Time) (*models.Order, error) {
+	if err := r.Check("GetStateAt"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	history := r.history[id]
+	for i := len(history) - 1; i >= 0; i-- {
+		if !history[i].UpdatedAt.After(asOf) {
+			return cloneOrder(history[i]), nil
+		}
+	}
+	return nil, repository.ErrOrderStateUnknown
+}
+
+// record сохраняет снимок состояния заказа; вызывается под блокировкой.
+// Время снимка — UpdatedAt заказа или текущее время, как в триггере record_order_history
+func (r *OrderRepository) record(order *models.Order) {
+	snapshot := cloneOrder(order)
+	if snapshot.UpdatedAt.IsZero() {
+		snapshot.UpdatedAt = time.Now()
+	}
+	r.history[order.ID] = append(r.history[order.ID], snapshot)
+}
+
+// UserExists проверяет, зарегистрирован ли пользователь
+func (r *OrderRepository) UserExists(userID uuid.UUID) (bool, error) {
+	if err := r.Check("UserExists"); err != nil {
+		return false, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	_, ok := r.users[userID]
+	return ok, nil
+}
+
+// list фильтрует, сортирует и постранично выбирает заказы так же, как SQL-реализация
+func (r *OrderRepository) list(match func(*models.Order) bool, req *models.ListOrdersRequest) *models.ListOrdersResponse {
+	r.mutex.RLock()
+	var matched []*models.Order
+	for _, order := range r.orders {
+		if !match(order) || (req.Status != "" && order.Status != req.Status) || !hasAllTags(order, req.Tags) {
+			continue
+		}
+		matched = append(matched, cloneOrder(order))
+	}
+	r.mutex.RUnlock()
+
+	less := orderLess(req.Sort)
+	sort.Slice(matched, func(i, j int) bool {
+		if req.Order == "asc" {
+			return less(matched[i], matched[j])
+		}
+		return less(matched[j], matched[i])
+	})
+
+	var orders []models.Order
+	for i := req.Offset; i < len(matched) && i < req.Offset+req.Limit; i++ {
+		orders = append(orders, *matched[i])
+	}
+
+	return &models.ListOrdersResponse{
+		Orders: orders,
+		Total:  len(matched),
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	}
+}
+
+// hasAllTags проверяет, что у заказа есть все теги фильтра
+func hasAllTags(order *models.Order, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, orderTag := range order.Tags {
+			if orderTag == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// orderLess возвращает функцию сравнения по полю сортировки списка заказов
+func orderLess(field string) func(a, b *models.Order) bool {
+	switch field {
+	case "updated_at":
+		return func(a, b *models.Order) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	case "total_sum":
+		return func(a, b *models.Order) bool { return a.TotalSum < b.TotalSum }
+	default:
+		return func(a, b *models.Order) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	}
+}
+
+// cloneOrder копирует заказ, чтобы вызывающий код не менял состояние фейка
+func cloneOrder(order *models.Order) *models.Order {
+	clone := *order
+	clone.Items = append([]models.OrderItem(nil), order.Items...)
+	clone.Tags = append([]string(nil), order.Tags...)
+	if order.Customer != nil {
+		customer := *order.Customer
+		clone.Customer = &customer
+	}
+	return &clone
+}
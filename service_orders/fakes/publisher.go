@@ -0,0 +1,98 @@
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"service_orders/events"
+
+	"pkg/fakes"
+)
+
+var _ events.EventPublisher = (*EventPublisher)(nil)
+
+// EventPublisher синхронная реализация events.EventPublisher: запоминает
+// опубликованные события и сразу вызывает подписчиков в горутине Publish, поэтому
+// тестам не нужно ждать асинхронной обработки. Ошибки подписчиков возвращаются
+// из Publish. Ошибки публикации внедряются через встроенный fakes.Failures
+type EventPublisher struct {
+	fakes.Failures
+
+	mutex       sync.Mutex
+	published   []*events.DomainEvent
+	subscribers map[events.EventType][]events.EventHandler
+	closed      bool
+}
+
+// NewEventPublisher создает фейк публикатора событий
+func NewEventPublisher() *EventPublisher {
+	return &EventPublisher{subscribers: make(map[events.EventType][]events.EventHandler)}
+}
+
+// Publish запоминает событие и передает его подписчикам. После Close
+// возвращает events.ErrPublisherClosed
+func (p *EventPublisher) Publish(ctx context.Context, event *events.DomainEvent) error {
+	if err := p.Check("Publish"); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return events.ErrPublisherClosed
+	}
+	p.published = append(p.published, event)
+	handlers := append([]events.EventHandler(nil), p.subscribers[event.Type]...)
+	p.mutex.Unlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe подписывается на события определенного типа
+func (p *EventPublisher) Subscribe(eventType events.EventType, handler events.EventHandler) error {
+	if err := p.Check("Subscribe"); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.subscribers[eventType] = append(p.subscribers[eventType], handler)
+	return nil
+}
+
+// Close закрывает публикатор
+func (p *EventPublisher) Close() error {
+	if err := p.Check("Close"); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.closed = true
+	return nil
+}
+
+// Published возвращает опубликованные события в порядке публикации
+func (p *EventPublisher) Published() []*events.DomainEvent {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]*events.DomainEvent(nil), p.published...)
+}
+
+// PublishedOfType возвращает опубликованные события указанного типа
+func (p *EventPublisher) PublishedOfType(eventType events.EventType) []*events.DomainEvent {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var result []*events.DomainEvent
+	for _, event := range p.published {
+		if event.Type == eventType {
+			result = append(result, event)
+		}
+	}
+	return result
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"service_orders/logger"
+	"service_orders/models"
+	"service_orders/notifications"
+	"service_orders/utils"
+
+	"pkg/ids"
+	"pkg/rbac"
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maxBroadcastsLimit максимальное число рассылок или получателей в одном ответе
+const maxBroadcastsLimit = 200
+
+// BroadcastHandler административный обработчик рассылок объявлений сегментам
+// пользователей. Рассылку отправляет фоновый Broadcaster через сервис уведомлений
+type BroadcastHandler struct {
+	*OrderHandler
+	broadcastRepo notifications.BroadcastRepository
+}
+
+// NewBroadcastHandler создает новый обработчик рассылок
+func NewBroadcastHandler(orderHandler *OrderHandler, broadcastRepo notifications.BroadcastRepository) *BroadcastHandler {
+	return &BroadcastHandler{
+		OrderHandler:  orderHandler,
+		broadcastRepo: broadcastRepo,
+	}
+}
+
+// CreateBroadcast создает рассылку: получатели выбираются по сегменту в момент создания,
+// отправка выполняется в фоне. Ответ 202 с числом получателей
+func (h *BroadcastHandler) CreateBroadcast(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.BroadcastsManage)
+	if !ok {
+		return
+	}
+
+	var req models.CreateBroadcastRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Текст объявления не может быть пустым")
+		return
+	}
+
+	broadcast := &notifications.Broadcast{
+		ID:      ids.New(),
+		Message: message,
+		Segment: notifications.Segment{
+			Roles:            req.Segment.Roles,
+			ActiveWithinDays: req.Segment.ActiveWithinDays,
+			Region:           strings.TrimSpace(req.Segment.Region),
+		},
+		Marketing: req.Marketing,
+		Status:    notifications.BroadcastStatusPending,
+		CreatedBy: &userCtx.UserID,
+		CreatedAt: timeutil.Now(),
+	}
+	if err := h.broadcastRepo.Create(broadcast); err != nil {
+		logger.LogOrderAction(r, "create_broadcast", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания рассылки")
+		return
+	}
+
+	details := fmt.Sprintf("broadcast_id=%s, recipients=%d, marketing=%t", broadcast.ID, broadcast.Total, broadcast.Marketing)
+	logger.LogOrderAction(r, "create_broadcast", userCtx.UserID.String(), details, true)
+	h.sendSuccessResponse(w, http.StatusAccepted, broadcast)
+}
+
+// ListBroadcasts возвращает последние рассылки со счетчиками доставки (параметр limit)
+func (h *BroadcastHandler) ListBroadcasts(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.BroadcastsManage)
+	if !ok {
+		return
+	}
+
+	limit, err := parseBroadcastLimit(r.URL.Query().Get("limit"), 20)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	broadcasts, err := h.broadcastRepo.List(limit)
+	if err != nil {
+		logger.LogOrderAction(r, "list_broadcasts", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения списка рассылок")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, broadcasts)
+}
+
+// GetBroadcast возвращает рассылку с числом получателей по статусам доставки
+func (h *BroadcastHandler) GetBroadcast(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.BroadcastsManage)
+	if !ok {
+		return
+	}
+
+	broadcastID, ok := h.broadcastID(w, r)
+	if !ok {
+		return
+	}
+
+	broadcast, err := h.broadcastRepo.GetByID(broadcastID)
+	if err == notifications.ErrBroadcastNotFound {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Рассылка не найдена")
+		return
+	}
+	if err != nil {
+		logger.LogOrderAction(r, "get_broadcast", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения рассылки")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, broadcast)
+}
+
+// ListBroadcastRecipients возвращает получателей рассылки со статусом доставки.
+// Параметры: status (pending, sent, skipped, failed или cancelled), limit, offset
+func (h *BroadcastHandler) ListBroadcastRecipients(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.BroadcastsManage)
+	if !ok {
+		return
+	}
+
+	broadcastID, ok := h.broadcastID(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	status := notifications.RecipientStatus(query.Get("status"))
+	switch status {
+	case "", notifications.RecipientStatusPending, notifications.RecipientStatusSent, notifications.RecipientStatusSkipped,
+		notifications.RecipientStatusFailed, notifications.RecipientStatusCancelled:
+	default:
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation,
+			"Параметр status должен быть одним из: pending, sent, skipped, failed, cancelled")
+		return
+	}
+
+	limit, err := parseBroadcastLimit(query.Get("limit"), 50)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+	offset := 0
+	if value := query.Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil || offset < 0 {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Параметр offset должен быть неотрицательным числом")
+			return
+		}
+	}
+
+	if _, err := h.broadcastRepo.GetByID(broadcastID); err != nil {
+		if err == notifications.ErrBroadcastNotFound {
+			h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Рассылка не найдена")
+			return
+		}
+		logger.LogOrderAction(r, "list_broadcast_recipients", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения рассылки")
+		return
+	}
+
+	recipients, err := h.broadcastRepo.Recipients(broadcastID, status, limit, offset)
+	if err != nil {
+		logger.LogOrderAction(r, "list_broadcast_recipients", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения получателей рассылки")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, recipients)
+}
+
+// CancelBroadcast отменяет ожидающую или выполняемую рассылку: неотправленные получатели
+// отменяются, уже взятая в отправку порция завершается. Завершенная рассылка — 409
+func (h *BroadcastHandler) CancelBroadcast(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.BroadcastsManage)
+	if !ok {
+		return
+	}
+
+	broadcastID, ok := h.broadcastID(w, r)
+	if !ok {
+		return
+	}
+
+	broadcast, err := h.broadcastRepo.GetByID(broadcastID)
+	if err == notifications.ErrBroadcastNotFound {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Рассылка не найдена")
+		return
+	}
+	if err != nil {
+		logger.LogOrderAction(r, "cancel_broadcast", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения рассылки")
+		return
+	}
+
+	cancelled, err := h.broadcastRepo.Cancel(broadcastID)
+	if err != nil {
+		logger.LogOrderAction(r, "cancel_broadcast", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка отмены рассылки")
+		return
+	}
+	if !cancelled {
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict,
+			fmt.Sprintf("Рассылку со статусом '%s' нельзя отменить", broadcast.Status))
+		return
+	}
+
+	broadcast, err = h.broadcastRepo.GetByID(broadcastID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения рассылки")
+		return
+	}
+
+	logger.LogOrderAction(r, "cancel_broadcast", userCtx.UserID.String(), "broadcast_id="+broadcastID.String(), true)
+	h.sendSuccessResponse(w, http.StatusOK, broadcast)
+}
+
+// broadcastID разбирает ID рассылки из пути; при ошибке отправляет 400
+func (h *BroadcastHandler) broadcastID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	broadcastID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID рассылки")
+		return uuid.Nil, false
+	}
+	return broadcastID, true
+}
+
+// parseBroadcastLimit разбирает параметр limit; пустое значение заменяется fallback
+func parseBroadcastLimit(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxBroadcastsLimit {
+		return 0, fmt.Errorf("Параметр limit должен быть от 1 до %d", maxBroadcastsLimit)
+	}
+	return limit, nil
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"service_orders/logger"
+	"service_orders/models"
+	"service_orders/notifications"
+	"service_orders/utils"
+
+	"pkg/rbac"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryHandler административный обработчик исходящих доставок (уведомлений и webhook)
+type DeliveryHandler struct {
+	*OrderHandler
+	deliveryRepo notifications.DeliveryRepository
+}
+
+// NewDeliveryHandler создает новый обработчик доставок
+func NewDeliveryHandler(orderHandler *OrderHandler, deliveryRepo notifications.DeliveryRepository) *DeliveryHandler {
+	return &DeliveryHandler{
+		OrderHandler: orderHandler,
+		deliveryRepo: deliveryRepo,
+	}
+}
+
+// ListDeliveries возвращает доставки по фильтрам (по умолчанию неудачные)
+func (h *DeliveryHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.DeliveriesManage)
+	if !ok {
+		return
+	}
+
+	req, err := parseListDeliveriesRequest(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	response, err := h.deliveryRepo.List(notifications.DeliveryFilter{
+		Status:  notifications.DeliveryStatus(req.Status),
+		Kind:    notifications.DeliveryKind(req.Kind),
+		Channel: req.Channel,
+		UserID:  req.UserID,
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+	})
+	if err != nil {
+		logger.LogOrderAction(r, "list_deliveries", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения списка доставок")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, response)
+}
+
+// RequeueDeliveries возвращает неудачные доставки в очередь на повторную отправку
+func (h *DeliveryHandler) RequeueDeliveries(w http.ResponseWriter, r *http.Request) {
+	h.bulkAction(w, r, "requeue_deliveries", h.deliveryRepo.Requeue)
+}
+
+// DiscardDeliveries окончательно отменяет неудачные или ожидающие доставки
+func (h *DeliveryHandler) DiscardDeliveries(w http.ResponseWriter, r *http.Request) {
+	h.bulkAction(w, r, "discard_deliveries", h.deliveryRepo.Discard)
+}
+
+// bulkAction выполняет массовое действие над доставками из тела запроса.
+// Доставки в неподходящем статусе пропускаются, в ответе возвращается число измененных
+func (h *DeliveryHandler) bulkAction(w http.ResponseWriter, r *http.Request, action string, apply func([]uuid.UUID) (int64, error)) {
+	userCtx, ok := h.requirePermission(w, r, rbac.DeliveriesManage)
+	if !ok {
+		return
+	}
+
+	var req models.BulkDeliveryRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	affected, err := apply(req.IDs)
+	if err != nil {
+		logger.LogOrderAction(r, action, userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка обработки доставок")
+		return
+	}
+
+	details := fmt.Sprintf("requested=%d, affected=%d", len(req.IDs), affected)
+	logger.LogOrderAction(r, action, userCtx.UserID.String(), details, true)
+
+	h.sendSuccessResponse(w, http.StatusOK, models.BulkDeliveryResponse{
+		Requested: len(req.IDs),
+		Affected:  affected,
+	})
+}
+
+// parseListDeliveriesRequest разбирает и валидирует параметры списка доставок
+func parseListDeliveriesRequest(r *http.Request) (*models.ListDeliveriesRequest, error) {
+	query := r.URL.Query()
+
+	req := &models.ListDeliveriesRequest{
+		Status:  string(notifications.DeliveryStatusFailed),
+		Kind:    query.Get("kind"),
+		Channel: query.Get("channel"),
+		Limit:   20,
+	}
+
+	// status=all снимает фильтр по статусу
+	if status := query.Get("status"); status == "all" {
+		req.Status = ""
+	} else if status != "" {
+		req.Status = status
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= 100 {
+			req.Limit = limit
+		}
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
+			req.Offset = offset
+		}
+	}
+
+	if userIDStr := query.Get("user_id"); userIDStr != "" {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный user_id")
+		}
+		req.UserID = &userID
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"service_orders/events"
+	"service_orders/logger"
+	"service_orders/models"
+	"service_orders/utils"
+
+	"pkg/rbac"
+
+	"github.com/gorilla/mux"
+)
+
+// maxHandlerErrorsLimit максимальное число ошибок обработчика в одном ответе
+const maxHandlerErrorsLimit = 50
+
+// EventHandlersHandler административный обработчик подписок на доменные события:
+// позволяет временно отключить обработчик (например, уведомления при сбое почтового провайдера)
+type EventHandlersHandler struct {
+	*OrderHandler
+	manager events.HandlerManager
+}
+
+// NewEventHandlersHandler создает новый обработчик управления подписками
+func NewEventHandlersHandler(orderHandler *OrderHandler, manager events.HandlerManager) *EventHandlersHandler {
+	return &EventHandlersHandler{
+		OrderHandler: orderHandler,
+		manager:      manager,
+	}
+}
+
+// ListEventHandlers возвращает обработчики событий с состоянием, счетчиками и последними ошибками
+func (h *EventHandlersHandler) ListEventHandlers(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.EventsManage)
+	if !ok {
+		return
+	}
+
+	handlers, err := h.manager.Handlers()
+	if err != nil {
+		logger.LogOrderAction(r, "list_event_handlers", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения обработчиков событий")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, handlers)
+}
+
+// UpdateEventHandler включает или отключает обработчик событий. Пока обработчик
+// отключен, события для него пропускаются и не обрабатываются после включения
+func (h *EventHandlersHandler) UpdateEventHandler(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.EventsManage)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateEventHandlerRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	info, err := h.manager.SetHandlerEnabled(name, *req.Enabled, req.Reason, userCtx.UserID)
+	if errors.Is(err, events.ErrHandlerNotFound) {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		logger.LogOrderAction(r, "update_event_handler", name, err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка изменения обработчика событий")
+		return
+	}
+
+	details := fmt.Sprintf("enabled=%t, reason=%q, admin_id=%s", *req.Enabled, req.Reason, userCtx.UserID)
+	logger.LogOrderAction(r, "update_event_handler", name, details, true)
+	h.sendSuccessResponse(w, http.StatusOK, info)
+}
+
+// ListEventHandlerErrors возвращает последние ошибки обработчика событий
+func (h *EventHandlersHandler) ListEventHandlerErrors(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.EventsManage)
+	if !ok {
+		return
+	}
+
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxHandlerErrorsLimit {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation,
+				fmt.Sprintf("Параметр limit должен быть от 1 до %d", maxHandlerErrorsLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	name := mux.Vars(r)["name"]
+	samples, err := h.manager.HandlerErrors(name, limit)
+	if errors.Is(err, events.ErrHandlerNotFound) {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		logger.LogOrderAction(r, "list_event_handler_errors", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения ошибок обработчика")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, samples)
+}
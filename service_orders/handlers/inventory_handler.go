@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"service_orders/events"
+	"service_orders/logger"
+	"service_orders/models"
+	"service_orders/utils"
+
+	"go.uber.org/zap"
+)
+
+// maxStockWebhookBody максимальный размер тела webhook складской системы
+const maxStockWebhookBody = 1 << 20
+
+// Заголовки подписи webhook складской системы
+const (
+	stockWebhookTimestampHeader = "X-Webhook-Timestamp"
+	stockWebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// InventoryHandler обработчик изменений складских остатков от внешних складских систем
+type InventoryHandler struct {
+	*OrderHandler
+	stockEvents events.StockEventPublisher
+}
+
+// NewInventoryHandler создает новый обработчик складских остатков
+func NewInventoryHandler(orderHandler *OrderHandler, stockEvents events.StockEventPublisher) *InventoryHandler {
+	return &InventoryHandler{
+		OrderHandler: orderHandler,
+		stockEvents:  stockEvents,
+	}
+}
+
+// ReceiveStockWebhook принимает подписанные изменения остатков. Запрос аутентифицируется
+// только подписью, поэтому доступен без JWT. Повторные и устаревшие изменения
+// пропускаются, и складская система может безопасно повторять запрос
+func (h *InventoryHandler) ReceiveStockWebhook(w http.ResponseWriter, r *http.Request) {
+	cfg := h.config.Current().Inventory
+	if cfg.WebhookSecret == "" {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Прием складских остатков не настроен")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxStockWebhookBody))
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, models.ErrorCodeValidation, "Слишком большое тело запроса")
+		return
+	}
+
+	if err := utils.VerifyWebhookSignature(body,
+		r.Header.Get(stockWebhookTimestampHeader), r.Header.Get(stockWebhookSignatureHeader),
+		cfg.WebhookSecret, time.Now(), cfg.SignatureTolerance); err != nil {
+		logger.WithRequestID(logger.GetLogger(), r.Header.Get("X-Request-ID")).Warn("Отклонен webhook складских остатков",
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.Error(err),
+		)
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, err.Error())
+		return
+	}
+
+	var req models.StockWebhookRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный JSON")
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	var response models.StockWebhookResponse
+	for _, change := range req.Items {
+		level := models.StockLevel{
+			Product:   change.Product,
+			Available: change.Available,
+			Warehouse: req.Warehouse,
+			ChangedAt: change.ChangedAt.UTC(),
+		}
+
+		previous, applied, err := h.stock(r).Apply(level)
+		if err != nil {
+			// Уже примененные изменения не откатываются: повтор запроса их пропустит
+			logger.LogBusinessEvent(r, "stock_update_failed", level.Product, "stock", err.Error())
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка сохранения остатков")
+			return
+		}
+		if !applied {
+			response.Skipped++
+			continue
+		}
+		response.Applied++
+
+		if err := h.stockEvents.PublishStockUpdated(context.Background(), level, previous, r); err != nil {
+			logger.LogBusinessEvent(r, "publish_event_failed", level.Product, "stock", "StockUpdatedEvent failed: "+err.Error())
+		}
+	}
+
+	logger.LogBusinessEvent(r, "stock_webhook_received", req.Warehouse, "warehouse",
+		fmt.Sprintf("applied=%d, skipped=%d", response.Applied, response.Skipped))
+	h.sendSuccessResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"service_orders/logger"
+	"service_orders/models"
+	"service_orders/repository"
+	"service_orders/utils"
+
+	"pkg/ids"
+	"pkg/rbac"
+	"pkg/servertiming"
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// OrderFilterHandler обработчик сохраненных наборов фильтров списка заказов. Каждый
+// администратор видит и изменяет только свои наборы
+type OrderFilterHandler struct {
+	*OrderHandler
+	filterRepo repository.OrderFilterRepository
+}
+
+// NewOrderFilterHandler создает новый обработчик наборов фильтров
+func NewOrderFilterHandler(orderHandler *OrderHandler, filterRepo repository.OrderFilterRepository) *OrderFilterHandler {
+	return &OrderFilterHandler{
+		OrderHandler: orderHandler,
+		filterRepo:   filterRepo,
+	}
+}
+
+// filters возвращает репозиторий наборов фильтров, учитывающий время запросов к БД запроса r
+func (h *OrderFilterHandler) filters(r *http.Request) repository.OrderFilterRepository {
+	return repository.TimedOrderFilterRepository(h.filterRepo, servertiming.FromContext(r.Context()))
+}
+
+// ListOrderFilters возвращает наборы фильтров вызывающего администратора
+func (h *OrderFilterHandler) ListOrderFilters(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersReadAll)
+	if !ok {
+		return
+	}
+
+	presets, err := h.filters(r).List(userCtx.UserID)
+	if err != nil {
+		logger.LogOrderAction(r, "list_order_filters", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения наборов фильтров")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, presets)
+}
+
+// CreateOrderFilter сохраняет новый именованный набор фильтров
+func (h *OrderFilterHandler) CreateOrderFilter(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersReadAll)
+	if !ok {
+		return
+	}
+
+	req, ok := h.decodePreset(w, r)
+	if !ok {
+		return
+	}
+
+	now := timeutil.Now()
+	preset := &models.OrderFilterPreset{
+		ID:        ids.New(),
+		UserID:    userCtx.UserID,
+		Name:      req.Name,
+		Filter:    req.Filter,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.filters(r).Create(preset); err != nil {
+		h.sendPresetError(w, r, "create_order_filter", preset.Name, err)
+		return
+	}
+
+	logger.LogOrderAction(r, "create_order_filter", preset.ID.String(), "name="+preset.Name, true)
+	h.sendSuccessResponse(w, http.StatusCreated, preset)
+}
+
+// UpdateOrderFilter заменяет имя и фильтры набора
+func (h *OrderFilterHandler) UpdateOrderFilter(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersReadAll)
+	if !ok {
+		return
+	}
+
+	presetID, ok := h.presetID(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := h.decodePreset(w, r)
+	if !ok {
+		return
+	}
+
+	preset := &models.OrderFilterPreset{
+		ID:        presetID,
+		UserID:    userCtx.UserID,
+		Name:      req.Name,
+		Filter:    req.Filter,
+		UpdatedAt: timeutil.Now(),
+	}
+	updated, err := h.filters(r).Update(preset)
+	if err != nil {
+		h.sendPresetError(w, r, "update_order_filter", preset.Name, err)
+		return
+	}
+	if !updated {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Набор фильтров не найден")
+		return
+	}
+
+	logger.LogOrderAction(r, "update_order_filter", presetID.String(), "name="+preset.Name, true)
+	h.sendSuccessResponse(w, http.StatusOK, preset)
+}
+
+// DeleteOrderFilter удаляет набор фильтров
+func (h *OrderFilterHandler) DeleteOrderFilter(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersReadAll)
+	if !ok {
+		return
+	}
+
+	presetID, ok := h.presetID(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.filters(r).Delete(presetID, userCtx.UserID)
+	if err != nil {
+		logger.LogOrderAction(r, "delete_order_filter", presetID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка удаления набора фильтров")
+		return
+	}
+	if !deleted {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Набор фильтров не найден")
+		return
+	}
+
+	logger.LogOrderAction(r, "delete_order_filter", presetID.String(), "", true)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListFilteredOrders возвращает административный список заказов по набору фильтров.
+// Фильтры и сортировка берутся из набора, а limit, offset, include, display_currency
+// и lang — из параметров запроса, как в GET /v1/orders/all
+func (h *OrderFilterHandler) ListFilteredOrders(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersReadAll)
+	if !ok {
+		return
+	}
+
+	presetID, ok := h.presetID(w, r)
+	if !ok {
+		return
+	}
+
+	preset, found, err := h.filters(r).Get(presetID, userCtx.UserID)
+	if err != nil {
+		logger.LogOrderAction(r, "list_filtered_orders", presetID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения набора фильтров")
+		return
+	}
+	if !found {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Набор фильтров не найден")
+		return
+	}
+
+	req, err := parseListOrdersRequest(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+	req.Status = preset.Filter.Status
+	req.Tags = preset.Filter.Tags
+	req.Sort, req.Order = "created_at", "desc"
+	if preset.Filter.Sort != "" {
+		req.Sort = preset.Filter.Sort
+	}
+	if preset.Filter.Order != "" {
+		req.Order = preset.Filter.Order
+	}
+	// Сортировка подставляется в SQL, поэтому сохраненный набор проверяется повторно
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный набор фильтров: "+err.Error())
+		return
+	}
+
+	h.respondAllOrders(w, r, userCtx, req, preset.Filter.UserID)
+}
+
+// decodePreset разбирает и проверяет набор фильтров из тела запроса. Теги нормализуются.
+// Возвращает false, если ответ с ошибкой уже отправлен
+func (h *OrderFilterHandler) decodePreset(w http.ResponseWriter, r *http.Request) (*models.SaveOrderFilterPresetRequest, bool) {
+	var req models.SaveOrderFilterPresetRequest
+	if !h.decodeJSON(w, r, &req) {
+		return nil, false
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return nil, false
+	}
+
+	tags, err := models.NormalizeTags(req.Filter.Tags)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return nil, false
+	}
+	req.Filter.Tags = tags
+	return &req, true
+}
+
+// presetID разбирает ID набора фильтров из пути. Возвращает false, если ответ уже отправлен
+func (h *OrderFilterHandler) presetID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID набора фильтров")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// sendPresetError отправляет ошибку сохранения набора name: 409 для повторяющегося имени, иначе 500
+func (h *OrderFilterHandler) sendPresetError(w http.ResponseWriter, r *http.Request, action, name string, err error) {
+	logger.LogOrderAction(r, action, name, err.Error(), false)
+	if errors.Is(err, repository.ErrFilterPresetConflict) {
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, fmt.Sprintf("Набор фильтров с именем '%s' уже существует", name))
+		return
+	}
+	h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка сохранения набора фильтров")
+}
@@ -2,19 +2,31 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"service_orders/config"
+	"service_orders/currency"
 	"service_orders/events"
 	"service_orders/logger"
 	"service_orders/models"
+	"service_orders/precreate"
 	"service_orders/repository"
 	"service_orders/utils"
 
+	"pkg/httpreq"
+	"pkg/httpresp"
+	"pkg/ids"
+	"pkg/rbac"
+	"pkg/servertiming"
+	"pkg/timeutil"
+
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
@@ -23,19 +35,65 @@ import (
 // OrderHandler обработчик для заказов
 type OrderHandler struct {
 	orderRepo    repository.OrderRepository
-	config       *config.Config
-	eventService *events.EventService
+	statusRepo   repository.StatusRepository
+	customerRepo repository.CustomerRepository
+	stockRepo    repository.StockRepository
+	tagRepo      repository.OrderTagRepository
+	config       config.Provider
+	eventService events.EventPublisherFacade
+	// converter пересчет сумм в валюту отображения; nil, если курсы не настроены
+	converter *currency.Converter
+	// hooks проверки заказа перед сохранением; nil — заказы не проверяются
+	hooks    *precreate.Chain
+	hookRepo repository.OrderHookRepository
 }
 
 // NewOrderHandler создает новый обработчик заказов
-func NewOrderHandler(orderRepo repository.OrderRepository, config *config.Config, eventService *events.EventService) *OrderHandler {
+func NewOrderHandler(orderRepo repository.OrderRepository, statusRepo repository.StatusRepository, customerRepo repository.CustomerRepository, stockRepo repository.StockRepository, tagRepo repository.OrderTagRepository, config config.Provider, eventService events.EventPublisherFacade, converter *currency.Converter, hooks *precreate.Chain, hookRepo repository.OrderHookRepository) *OrderHandler {
 	return &OrderHandler{
 		orderRepo:    orderRepo,
+		statusRepo:   statusRepo,
+		customerRepo: customerRepo,
+		stockRepo:    stockRepo,
+		tagRepo:      tagRepo,
 		config:       config,
 		eventService: eventService,
+		converter:    converter,
+		hooks:        hooks,
+		hookRepo:     hookRepo,
 	}
 }
 
+// orders возвращает репозиторий заказов, учитывающий время запросов к БД в Server-Timing запроса r
+func (h *OrderHandler) orders(r *http.Request) repository.OrderRepository {
+	return repository.TimedOrderRepository(h.orderRepo, servertiming.FromContext(r.Context()))
+}
+
+// statuses возвращает репозиторий статусов, учитывающий время запросов к БД запроса r
+func (h *OrderHandler) statuses(r *http.Request) repository.StatusRepository {
+	return repository.TimedStatusRepository(h.statusRepo, servertiming.FromContext(r.Context()))
+}
+
+// customers возвращает репозиторий покупателей, учитывающий время запросов к БД запроса r
+func (h *OrderHandler) customers(r *http.Request) repository.CustomerRepository {
+	return repository.TimedCustomerRepository(h.customerRepo, servertiming.FromContext(r.Context()))
+}
+
+// stock возвращает репозиторий остатков, учитывающий время запросов к БД запроса r
+func (h *OrderHandler) stock(r *http.Request) repository.StockRepository {
+	return repository.TimedStockRepository(h.stockRepo, servertiming.FromContext(r.Context()))
+}
+
+// tags возвращает репозиторий тегов заказов, учитывающий время запросов к БД запроса r
+func (h *OrderHandler) tags(r *http.Request) repository.OrderTagRepository {
+	return repository.TimedOrderTagRepository(h.tagRepo, servertiming.FromContext(r.Context()))
+}
+
+// hookDecisions возвращает журнал решений проверок заказа, учитывающий время запросов к БД запроса r
+func (h *OrderHandler) hookDecisions(r *http.Request) repository.OrderHookRepository {
+	return repository.TimedOrderHookRepository(h.hookRepo, servertiming.FromContext(r.Context()))
+}
+
 // CreateOrder обрабатывает создание нового заказа
 func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	// Получение пользовательского контекста
@@ -46,8 +104,7 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req models.CreateOrderRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный JSON")
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
@@ -58,7 +115,7 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Проверка существования пользователя
-	exists, err := h.orderRepo.UserExists(userCtx.UserID)
+	exists, err := h.orders(r).UserExists(userCtx.UserID)
 	if err != nil {
 		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки пользователя")
 		return
@@ -68,27 +125,58 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Проверка складских остатков
+	shortage, err := h.checkStock(r, req.Items)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки остатков")
+		return
+	}
+	if shortage != "" {
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, shortage)
+		return
+	}
+
 	// Создание заказа
+	now := timeutil.Now()
 	order := &models.Order{
-		ID:        uuid.New(),
+		ID:        ids.New(),
 		UserID:    userCtx.UserID,
 		Items:     req.Items,
 		Status:    models.OrderStatusCreated,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Region:    strings.TrimSpace(req.Region),
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
 	// Вычисление общей стоимости
 	order.CalculateTotal()
 
-	if err := h.orderRepo.Create(order); err != nil {
+	// Проверки перед сохранением (антифрод, лимиты, черные списки). Причина отказа
+	// покупателю не сообщается, она есть только в журнале решений. Помеченный заказ
+	// создается в статусе flagged и ждет решения администратора
+	checks := h.hooks.Run(precreate.WithClientIP(r.Context(), clientIP(r)), order)
+	if checks.Vetoed() {
+		h.recordHookDecisions(r, checks)
+		logger.LogOrderAction(r, "create_order", order.ID.String(), "rejected by hooks: "+checks.Summary(), false)
+		h.sendErrorResponse(w, http.StatusUnprocessableEntity, models.ErrorCodeOrderRejected, "Заказ отклонен проверкой перед созданием")
+		return
+	}
+	if checks.Flagged() {
+		order.Status = models.OrderStatusFlagged
+	}
+
+	if err := h.orders(r).Create(order); err != nil {
 		logger.LogOrderAction(r, "create_order", order.ID.String(), err.Error(), false)
 		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания заказа")
 		return
 	}
+	h.recordHookDecisions(r, checks)
 
 	// Логируем успешное создание заказа
 	details := fmt.Sprintf("items_count=%d, total_sum=%.2f", len(order.Items), order.TotalSum)
+	if checks.Flagged() {
+		details += ", flagged=" + checks.Summary()
+	}
 	logger.LogOrderAction(r, "create_order", order.ID.String(), details, true)
 	logger.LogBusinessEvent(r, "order_created", order.ID.String(), "order", details)
 
@@ -98,10 +186,42 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		// Логируем ошибку, но не прерываем обработку - заказ уже создан
 		logger.LogOrderAction(r, "publish_event", order.ID.String(), "OrderCreatedEvent failed: "+err.Error(), false)
 	}
+	if checks.Flagged() {
+		if err := h.eventService.PublishOrderFlagged(ctx, order, checks.Summary(), r); err != nil {
+			logger.LogOrderAction(r, "publish_event", order.ID.String(), "OrderFlaggedEvent failed: "+err.Error(), false)
+		}
+	}
 
+	h.localizeStatuses(r, order)
 	h.sendSuccessResponse(w, http.StatusCreated, order)
 }
 
+// clientIP возвращает IP адрес клиента: последний адрес X-Forwarded-For, добавленный
+// API Gateway, или адрес соединения
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		parts := strings.Split(forwarded, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// recordHookDecisions сохраняет решения проверок заказа в журнал. Ошибка журнала
+// не влияет на ответ: заказ уже создан или отклонен
+func (h *OrderHandler) recordHookDecisions(r *http.Request, result precreate.Result) {
+	if h.hookRepo == nil || len(result.Decisions) == 0 {
+		return
+	}
+	if err := h.hookDecisions(r).Record(result.Decisions); err != nil {
+		logger.LogOrderAction(r, "record_hook_decisions", result.Decisions[0].OrderID.String(), err.Error(), false)
+	}
+}
+
 // GetOrder возвращает заказ по идентификатору
 func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	// Получение пользовательского контекста
@@ -118,7 +238,7 @@ func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	order, err := h.orderRepo.GetByID(orderID)
+	order, err := h.orders(r).GetByID(orderID)
 	if err != nil {
 		logger.LogOrderAction(r, "get_order", orderID.String(), "Order not found", false)
 		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Заказ не найден")
@@ -126,12 +246,51 @@ func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Проверка прав доступа
-	if err := userCtx.ValidateOrderOwnership(order.UserID); err != nil {
+	if err := userCtx.ValidateOrderOwnership(order.UserID, rbac.OrdersReadAll); err != nil {
 		logger.LogOrderAction(r, "get_order", orderID.String(), "Access denied: "+err.Error(), false)
 		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, err.Error())
 		return
 	}
 
+	// Состояние заказа на момент в прошлом (разбор споров и обращений в поддержку)
+	if value := r.URL.Query().Get("as_of"); value != "" {
+		asOf, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный параметр as_of: ожидается время в формате RFC 3339")
+			return
+		}
+		if asOf.After(timeutil.Now()) {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Параметр as_of не может быть в будущем")
+			return
+		}
+
+		order, err = h.orders(r).GetStateAt(orderID, asOf.UTC())
+		if errors.Is(err, repository.ErrOrderStateUnknown) {
+			logger.LogOrderAction(r, "get_order", orderID.String(), fmt.Sprintf("as_of=%s: state unknown", value), false)
+			h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Состояние заказа на этот момент неизвестно: заказ еще не был создан или история не охватывает этот момент")
+			return
+		}
+		if err != nil {
+			logger.LogOrderAction(r, "get_order", orderID.String(), err.Error(), false)
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения истории заказа")
+			return
+		}
+	}
+
+	// Теги — внутренняя разметка операторов, покупателю они не показываются
+	if userCtx.Can(rbac.OrdersReadAll) {
+		if err := h.attachTags(r, []*models.Order{order}); err != nil {
+			logger.LogOrderAction(r, "get_order", orderID.String(), err.Error(), false)
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения тегов заказа")
+			return
+		}
+	}
+
+	h.localizeStatuses(r, order)
+	if !h.convertAmounts(w, r, order) {
+		return
+	}
+
 	logger.LogOrderAction(r, "get_order", orderID.String(), fmt.Sprintf("status=%s", order.Status), true)
 	h.sendSuccessResponse(w, http.StatusOK, order)
 }
@@ -145,59 +304,168 @@ func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Парсинг параметров запроса
-	req := &models.ListOrdersRequest{
-		Limit:  10, // значение по умолчанию
-		Offset: 0,
-		Sort:   "created_at",
-		Order:  "desc",
+	req, err := parseListOrdersRequest(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
 	}
 
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= 100 {
-			req.Limit = limit
-		}
+	// Данные покупателя и теги доступны только в административном списке
+	if len(req.Include) > 0 {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Параметр include доступен только администраторам в /v1/orders/all")
+		return
+	}
+	if len(req.Tags) > 0 {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Параметр tags доступен только администраторам в /v1/orders/all")
+		return
 	}
 
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
-			req.Offset = offset
-		}
+	// Получение списка заказов
+	response, err := h.orders(r).GetByUserID(userCtx.UserID, req)
+	if err != nil {
+		logger.LogOrderAction(r, "list_orders", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения списка заказов")
+		return
 	}
 
-	if status := r.URL.Query().Get("status"); status != "" {
-		req.Status = models.OrderStatus(status)
+	// Логируем успешное получение списка заказов
+	listDetails := fmt.Sprintf("found=%d, limit=%d, offset=%d", len(response.Orders), req.Limit, req.Offset)
+	logger.LogOrderAction(r, "list_orders", userCtx.UserID.String(), listDetails, true)
+
+	orders := make([]*models.Order, len(response.Orders))
+	for i := range response.Orders {
+		orders[i] = &response.Orders[i]
+	}
+	h.localizeStatuses(r, orders...)
+	if !h.convertAmounts(w, r, orders...) {
+		return
 	}
 
-	if sort := r.URL.Query().Get("sort"); sort != "" {
-		req.Sort = sort
+	h.sendSuccessResponse(w, http.StatusOK, response)
+}
+
+// ListAllOrders возвращает заказы всех пользователей (право orders.read_all).
+// С параметром include=customer каждый заказ дополняется email и именем покупателя,
+// параметр user_id ограничивает выборку заказами одного пользователя, а параметр tags —
+// заказами со всеми перечисленными тегами
+func (h *OrderHandler) ListAllOrders(w http.ResponseWriter, r *http.Request) {
+	userCtx, err := utils.GetUserContextFromHeaders(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, err.Error())
+		return
 	}
 
-	if order := r.URL.Query().Get("order"); order != "" {
-		req.Order = order
+	if !userCtx.Can(rbac.OrdersReadAll) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
 	}
 
-	// Валидация параметров
-	if err := utils.ValidateStruct(req); err != nil {
+	req, err := parseListOrdersRequest(r)
+	if err != nil {
 		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
 		return
 	}
 
-	// Получение списка заказов
-	response, err := h.orderRepo.GetByUserID(userCtx.UserID, req)
+	// Необязательный фильтр по пользователю
+	var userID *uuid.UUID
+	if userIDStr := r.URL.Query().Get("user_id"); userIDStr != "" {
+		parsed, parseErr := uuid.Parse(userIDStr)
+		if parseErr != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный user_id")
+			return
+		}
+		userID = &parsed
+	}
+
+	h.respondAllOrders(w, r, userCtx, req, userID)
+}
+
+// respondAllOrders отправляет административный список заказов по параметрам req,
+// при userID != nil — заказы одного пользователя. Заказы дополняются тегами
+func (h *OrderHandler) respondAllOrders(w http.ResponseWriter, r *http.Request, userCtx *utils.UserContext, req *models.ListOrdersRequest, userID *uuid.UUID) {
+	var response *models.ListOrdersResponse
+	var err error
+	if userID != nil {
+		response, err = h.orders(r).GetByUserID(*userID, req)
+	} else {
+		response, err = h.orders(r).List(req)
+	}
 	if err != nil {
-		logger.LogOrderAction(r, "list_orders", userCtx.UserID.String(), err.Error(), false)
+		logger.LogOrderAction(r, "list_all_orders", userCtx.UserID.String(), err.Error(), false)
 		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения списка заказов")
 		return
 	}
 
-	// Логируем успешное получение списка заказов
-	listDetails := fmt.Sprintf("found=%d, limit=%d, offset=%d", len(response.Orders), req.Limit, req.Offset)
-	logger.LogOrderAction(r, "list_orders", userCtx.UserID.String(), listDetails, true)
+	orders := make([]*models.Order, len(response.Orders))
+	for i := range response.Orders {
+		orders[i] = &response.Orders[i]
+	}
+	h.localizeStatuses(r, orders...)
+	if !h.convertAmounts(w, r, orders...) {
+		return
+	}
+
+	if includes(req.Include, models.IncludeCustomer) {
+		if err := h.attachCustomers(r, orders); err != nil {
+			logger.LogOrderAction(r, "list_all_orders", userCtx.UserID.String(), err.Error(), false)
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения данных покупателей")
+			return
+		}
+	}
+	if err := h.attachTags(r, orders); err != nil {
+		logger.LogOrderAction(r, "list_all_orders", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения тегов заказов")
+		return
+	}
+
+	listDetails := fmt.Sprintf("found=%d, limit=%d, offset=%d, include=%s, tags=%s", len(response.Orders), req.Limit, req.Offset, strings.Join(req.Include, ","), strings.Join(req.Tags, ","))
+	logger.LogOrderAction(r, "list_all_orders", userCtx.UserID.String(), listDetails, true)
 
 	h.sendSuccessResponse(w, http.StatusOK, response)
 }
 
+// attachCustomers дополняет заказы данными покупателей одним пакетным запросом
+func (h *OrderHandler) attachCustomers(r *http.Request, orders []*models.Order) error {
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	for _, order := range orders {
+		if !seen[order.UserID] {
+			seen[order.UserID] = true
+			ids = append(ids, order.UserID)
+		}
+	}
+
+	customers, err := h.customers(r).GetByIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		if customer, ok := customers[order.UserID]; ok {
+			order.Customer = &customer
+		}
+	}
+	return nil
+}
+
+// attachTags дополняет заказы тегами одним пакетным запросом
+func (h *OrderHandler) attachTags(r *http.Request, orders []*models.Order) error {
+	ids := make([]uuid.UUID, len(orders))
+	for i, order := range orders {
+		ids[i] = order.ID
+	}
+
+	tags, err := h.tags(r).GetByOrderIDs(ids)
+	if err != nil {
+		return err
+	}
+
+	for _, order := range orders {
+		order.Tags = tags[order.ID]
+	}
+	return nil
+}
+
 // UpdateOrderStatus обновляет статус заказа
 func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
 	// Получение пользовательского контекста
@@ -215,8 +483,7 @@ func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request)
 	}
 
 	var req models.UpdateOrderStatusRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный JSON")
+	if !h.decodeJSON(w, r, &req) {
 		return
 	}
 
@@ -227,19 +494,24 @@ func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Получение текущего заказа
-	order, err := h.orderRepo.GetByID(orderID)
+	order, err := h.orders(r).GetByID(orderID)
 	if err != nil {
 		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Заказ не найден")
 		return
 	}
 
 	// Проверка прав доступа
-	if err := userCtx.ValidateOrderOwnership(order.UserID); err != nil {
+	if err := userCtx.ValidateOrderOwnership(order.UserID, rbac.OrdersManage); err != nil {
 		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, err.Error())
 		return
 	}
 
 	// Проверка возможности обновления
+	// (заказ flagged переводится только одобрением или отклонением администратора)
+	if order.Status == models.OrderStatusFlagged {
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, "Заказ ожидает проверки администратором")
+		return
+	}
 	if !order.CanBeUpdated() {
 		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, 
 			fmt.Sprintf("Нельзя обновить заказ со статусом '%s'", order.Status))
@@ -250,7 +522,7 @@ func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request)
 	oldStatus := order.Status
 
 	// Обновление статуса
-	if err := h.orderRepo.UpdateStatus(orderID, req.Status); err != nil {
+	if err := h.orders(r).UpdateStatus(orderID, req.Status); err != nil {
 		logger.LogOrderAction(r, "update_status", orderID.String(), err.Error(), false)
 		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка обновления статуса заказа")
 		return
@@ -269,12 +541,13 @@ func (h *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Получение обновленного заказа
-	updatedOrder, err := h.orderRepo.GetByID(orderID)
+	updatedOrder, err := h.orders(r).GetByID(orderID)
 	if err != nil {
 		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения обновленного заказа")
 		return
 	}
 
+	h.localizeStatuses(r, updatedOrder)
 	h.sendSuccessResponse(w, http.StatusOK, updatedOrder)
 }
 
@@ -295,14 +568,14 @@ func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Получение текущего заказа
-	order, err := h.orderRepo.GetByID(orderID)
+	order, err := h.orders(r).GetByID(orderID)
 	if err != nil {
 		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Заказ не найден")
 		return
 	}
 
 	// Проверка прав доступа
-	if err := userCtx.ValidateOrderOwnership(order.UserID); err != nil {
+	if err := userCtx.ValidateOrderOwnership(order.UserID, rbac.OrdersManage); err != nil {
 		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, err.Error())
 		return
 	}
@@ -314,45 +587,331 @@ func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Проверка правил отмены: бесплатное окно, плата или запрет
+	policy := h.config.Current().Cancellation
+	cancellation := cancellationTerms(order, policy, userCtx.Can(rbac.OrdersManage), timeutil.Now())
+	if cancellation.Rule == models.CancellationForbid {
+		message := "Заказ в работе нельзя отменить"
+		if cancellation.Policy == models.CancellationPolicyAfterWindow {
+			message = fmt.Sprintf("Заказ можно отменить только в течение %s после создания", policy.FreeWindow)
+		}
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeCancellationForbidden, message)
+		return
+	}
+
 	// Сохраняем старый статус для события
 	oldStatus := order.Status
 
 	// Отмена заказа
-	if err := h.orderRepo.Cancel(orderID); err != nil {
+	if err := h.orders(r).Cancel(orderID); err != nil {
 		logger.LogOrderAction(r, "cancel_order", orderID.String(), err.Error(), false)
 		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка отмены заказа")
 		return
 	}
 
 	// Логируем успешную отмену заказа
-	cancelDetails := fmt.Sprintf("cancelled from status: %s", oldStatus)
+	cancelDetails := fmt.Sprintf("cancelled from status: %s, policy: %s, fee: %.2f", oldStatus, cancellation.Policy, cancellation.Fee)
 	logger.LogOrderAction(r, "cancel_order", orderID.String(), cancelDetails, true)
 	logger.LogBusinessEvent(r, "order_cancelled", orderID.String(), "order", cancelDetails)
 
 	// Публикуем событие отмены заказа
 	ctx := context.Background()
-	if err := h.eventService.PublishOrderCancelled(ctx, orderID, order.UserID, userCtx.UserID, oldStatus, r); err != nil {
+	if err := h.eventService.PublishOrderCancelled(ctx, orderID, order.UserID, userCtx.UserID, oldStatus, cancellation, r); err != nil {
 		// Логируем ошибку, но не прерываем обработку - заказ уже отменен
 		logger.LogOrderAction(r, "publish_event", orderID.String(), "OrderCancelledEvent failed: "+err.Error(), false)
 	}
 
 	// Получение обновленного заказа
-	cancelledOrder, err := h.orderRepo.GetByID(orderID)
+	cancelledOrder, err := h.orders(r).GetByID(orderID)
 	if err != nil {
 		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения отмененного заказа")
 		return
 	}
+	cancelledOrder.Cancellation = &cancellation
 
+	h.localizeStatuses(r, cancelledOrder)
 	h.sendSuccessResponse(w, http.StatusOK, cancelledOrder)
 }
 
+// ListStatuses возвращает справочник статусов заказов с локализованными названиями
+func (h *OrderHandler) ListStatuses(w http.ResponseWriter, r *http.Request) {
+	statuses, err := h.statuses(r).List(requestLocale(r))
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения справочника статусов")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, statuses)
+}
+
+// UpdateStatusTranslation изменяет локализованное название статуса (право orders.manage)
+func (h *OrderHandler) UpdateStatusTranslation(w http.ResponseWriter, r *http.Request) {
+	userCtx, err := utils.GetUserContextFromHeaders(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, err.Error())
+		return
+	}
+	if !userCtx.Can(rbac.OrdersManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Недостаточно прав доступа")
+		return
+	}
+
+	code := models.ParseOrderStatus(mux.Vars(r)["code"])
+	exists, err := h.statuses(r).Exists(code)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки статуса")
+		return
+	}
+	if !exists {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Статус не найден")
+		return
+	}
+
+	var req models.UpdateStatusTranslationRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	locale := strings.ToLower(req.Locale)
+	if err := h.statuses(r).UpsertTranslation(code, locale, req.DisplayName); err != nil {
+		logger.LogOrderAction(r, "update_status_translation", string(code), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка сохранения названия статуса")
+		return
+	}
+
+	logger.LogOrderAction(r, "update_status_translation", string(code), fmt.Sprintf("%s=%s", locale, req.DisplayName), true)
+
+	statuses, err := h.statuses(r).List(locale)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения справочника статусов")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, statuses)
+}
+
+// requirePermission проверяет, что у пользователя запроса есть право permission, иначе отправляет ошибку
+func (h *OrderHandler) requirePermission(w http.ResponseWriter, r *http.Request, permission string) (*utils.UserContext, bool) {
+	userCtx, err := utils.GetUserContextFromHeaders(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, err.Error())
+		return nil, false
+	}
+
+	if !userCtx.Can(permission) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return nil, false
+	}
+
+	return userCtx, true
+}
+
+// checkStock сравнивает количество товаров заказа со складскими остатками и возвращает
+// сообщение о нехватке. Товары без записи об остатке не ограничиваются
+func (h *OrderHandler) checkStock(r *http.Request, items []models.OrderItem) (string, error) {
+	requested := make(map[string]int, len(items))
+	var products []string
+	for _, item := range items {
+		if _, ok := requested[item.Product]; !ok {
+			products = append(products, item.Product)
+		}
+		requested[item.Product] += item.Quantity
+	}
+
+	available, err := h.stock(r).Available(products)
+	if err != nil {
+		return "", err
+	}
+
+	var shortages []string
+	for _, product := range products {
+		if count, ok := available[product]; ok && count < requested[product] {
+			shortages = append(shortages, fmt.Sprintf("%s (доступно %d, запрошено %d)", product, count, requested[product]))
+		}
+	}
+	if len(shortages) == 0 {
+		return "", nil
+	}
+	return "Недостаточно товара на складе: " + strings.Join(shortages, ", "), nil
+}
+
+// parseListOrdersRequest разбирает и валидирует параметры списка заказов
+func parseListOrdersRequest(r *http.Request) (*models.ListOrdersRequest, error) {
+	// Парсинг параметров запроса
+	req := &models.ListOrdersRequest{
+		Limit:  10, // значение по умолчанию
+		Offset: 0,
+		Sort:   "created_at",
+		Order:  "desc",
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= 100 {
+			req.Limit = limit
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
+			req.Offset = offset
+		}
+	}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		req.Status = models.ParseOrderStatus(status)
+	}
+
+	if sort := r.URL.Query().Get("sort"); sort != "" {
+		req.Sort = sort
+	}
+
+	if order := r.URL.Query().Get("order"); order != "" {
+		req.Order = order
+	}
+
+	tags, err := models.ParseTags(r.URL.Query().Get("tags"))
+	if err != nil {
+		return nil, err
+	}
+	req.Tags = tags
+
+	if include := r.URL.Query().Get("include"); include != "" {
+		for _, value := range strings.Split(include, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				req.Include = append(req.Include, value)
+			}
+		}
+	}
+
+	// Валидация параметров
+	if err := utils.ValidateStruct(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// includes проверяет наличие значения в списке параметра include
+func includes(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// cancellationTerms определяет условия отмены заказа по правилам policy: заказ created
+// в бесплатном окне после создания отменяется без платы, после окна и в статусе in_work
+// применяются настроенные правила. Пользователь с правом orders.manage отменяет заказ
+// без ограничений и платы
+func cancellationTerms(order *models.Order, policy config.CancellationConfig, canManage bool, now time.Time) models.OrderCancellation {
+	if canManage {
+		return models.OrderCancellation{Policy: models.CancellationPolicyAdmin, Rule: models.CancellationFree}
+	}
+
+	terms := models.OrderCancellation{Policy: models.CancellationPolicyFreeWindow, Rule: models.CancellationFree}
+	switch {
+	case order.Status == models.OrderStatusInWork:
+		terms = models.OrderCancellation{Policy: models.CancellationPolicyInWork, Rule: policy.InWork}
+	case order.Status == models.OrderStatusFlagged:
+		// Заказ ждет решения администратора и еще не принят: отмена бесплатна
+	case policy.FreeWindow > 0 && now.Sub(order.CreatedAt) > policy.FreeWindow:
+		terms = models.OrderCancellation{Policy: models.CancellationPolicyAfterWindow, Rule: policy.AfterWindow}
+	}
+
+	if terms.Rule == models.CancellationFee {
+		fee := policy.Fee + order.TotalSum*policy.FeePercent/100
+		terms.Fee = math.Round(math.Min(fee, order.TotalSum)*100) / 100
+	}
+	return terms
+}
+
+// localizeStatuses заполняет локализованные названия статусов заказов.
+// Ошибка справочника не прерывает ответ: клиент получит машинные коды
+func (h *OrderHandler) localizeStatuses(r *http.Request, orders ...*models.Order) {
+	names, err := h.statuses(r).DisplayNames(requestLocale(r))
+	if err != nil {
+		logger.LogOrderAction(r, "localize_statuses", "", err.Error(), false)
+		return
+	}
+
+	for _, order := range orders {
+		order.StatusName = names[order.Status]
+	}
+}
+
+// convertAmounts добавляет к заказам суммы в валюте параметра display_currency.
+// Без параметра ничего не делает. Возвращает false, если ответ с ошибкой уже отправлен
+func (h *OrderHandler) convertAmounts(w http.ResponseWriter, r *http.Request, orders ...*models.Order) bool {
+	value := r.URL.Query().Get("display_currency")
+	if value == "" {
+		return true
+	}
+
+	if h.converter == nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Пересчет в другую валюту не настроен")
+		return false
+	}
+
+	code, err := currency.NormalizeCode(value)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return false
+	}
+
+	rate, err := h.converter.Rate(r.Context(), code)
+	if errors.Is(err, currency.ErrUnsupportedCurrency) {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, fmt.Sprintf("Валюта %s не поддерживается", code))
+		return false
+	}
+	if err != nil {
+		logger.LogOrderAction(r, "convert_amounts", code, err.Error(), false)
+		h.sendErrorResponse(w, http.StatusServiceUnavailable, models.ErrorCodeInternalServer, "Курсы валют временно недоступны")
+		return false
+	}
+
+	for _, order := range orders {
+		display := &models.DisplayAmounts{
+			Currency:       rate.Currency,
+			BaseCurrency:   h.converter.Base(),
+			Rate:           rate.Value,
+			RatesUpdatedAt: rate.UpdatedAt,
+			TotalSum:       rate.Convert(order.TotalSum),
+			ItemPrices:     make([]float64, len(order.Items)),
+		}
+		for i, item := range order.Items {
+			display.ItemPrices[i] = rate.Convert(item.Price)
+		}
+		order.Display = display
+	}
+	return true
+}
+
+// requestLocale определяет локаль запроса: параметр lang, затем Accept-Language
+func requestLocale(r *http.Request) string {
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		return strings.ToLower(lang)
+	}
+
+	if accept := r.Header.Get("Accept-Language"); accept != "" {
+		tag := strings.Split(strings.Split(accept, ",")[0], ";")[0]
+		if primary := strings.Split(strings.TrimSpace(tag), "-")[0]; primary != "" && primary != "*" {
+			return strings.ToLower(primary)
+		}
+	}
+
+	return repository.DefaultStatusLocale
+}
+
 // sendSuccessResponse отправляет успешный ответ
 func (h *OrderHandler) sendSuccessResponse(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	
-	response := models.NewSuccessResponse(data)
-	json.NewEncoder(w).Encode(response)
+	h.writeJSON(w, statusCode, models.NewSuccessResponse(data))
 }
 
 // sendErrorResponse отправляет ответ с ошибкой
@@ -375,9 +934,46 @@ func (h *OrderHandler) sendErrorResponse(w http.ResponseWriter, statusCode int,
 		)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	
-	response := models.NewErrorResponse(code, message)
-	json.NewEncoder(w).Encode(response)
+	h.writeJSON(w, statusCode, models.NewErrorResponse(code, message))
+}
+
+// decodeJSON разбирает JSON тело запроса в dst через общий httpreq. При ошибке отправляет
+// 400 (413 для слишком большого тела) с причиной и полем и возвращает false
+func (h *OrderHandler) decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	err := httpreq.DecodeJSON(w, r, dst, httpreq.DefaultMaxBodySize)
+	if err == nil {
+		return true
+	}
+
+	var decodeErr *httpreq.DecodeError
+	if !errors.As(err, &decodeErr) {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return false
+	}
+
+	logger.GetLogger().Warn("HTTP Error Response",
+		zap.Int("status_code", decodeErr.Status),
+		zap.String("error_code", models.ErrorCodeValidation),
+		zap.String("error_message", decodeErr.Message),
+		zap.String("reason", decodeErr.Reason),
+		zap.String("field", decodeErr.Field),
+		zap.String("service", "service_orders"),
+	)
+
+	response := models.NewErrorResponse(models.ErrorCodeValidation, decodeErr.Message)
+	response.Error.Reason = decodeErr.Reason
+	response.Error.Field = decodeErr.Field
+	h.writeJSON(w, decodeErr.Status, response)
+	return false
+}
+
+// writeJSON отправляет JSON ответ через общий httpresp и логирует ошибки сериализации и записи
+func (h *OrderHandler) writeJSON(w http.ResponseWriter, statusCode int, response models.APIResponse) {
+	if err := httpresp.JSON(w, statusCode, response); err != nil {
+		logger.GetLogger().Error("Failed to write JSON response",
+			zap.Int("status_code", statusCode),
+			zap.Error(err),
+			zap.String("service", "service_orders"),
+		)
+	}
 }
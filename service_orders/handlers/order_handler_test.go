@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"service_orders/config"
+	"service_orders/events"
+	"service_orders/fakes"
+	"service_orders/models"
+
+	"pkg/rbac"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// orderHandlerFixture обработчик заказов с зависимостями в памяти
+type orderHandlerFixture struct {
+	handler *OrderHandler
+	orders  *fakes.OrderRepository
+	stock   *fakes.StockRepository
+	events  *fakes.EventFacade
+	owner   uuid.UUID
+}
+
+func newOrderHandlerFixture() *orderHandlerFixture {
+	f := &orderHandlerFixture{
+		orders: fakes.NewOrderRepository(),
+		stock:  fakes.NewStockRepository(),
+		events: fakes.NewEventFacade(),
+		owner:  uuid.New(),
+	}
+	f.orders.AddUsers(f.owner)
+	statuses := fakes.NewStatusRepository()
+	statuses.SeedNames("ru", map[models.OrderStatus]string{
+		models.OrderStatusCreated: "Создан", models.OrderStatusInWork: "В работе", models.OrderStatusCancelled: "Отменен",
+	})
+	cfg := &config.Config{Cancellation: config.CancellationConfig{InWork: models.CancellationForbid}}
+	f.handler = NewOrderHandler(f.orders, statuses, fakes.NewCustomerRepository(), f.stock, nil, cfg, f.events, nil, nil, nil)
+	return f
+}
+
+// statusUpdates возвращает данные опубликованных событий обновления статуса
+func (f *orderHandlerFixture) statusUpdates() []events.OrderStatusUpdatedEventData {
+	var updates []events.OrderStatusUpdatedEventData
+	for _, event := range f.events.PublishedOfType(events.OrderStatusUpdatedEvent) {
+		updates = append(updates, event.Data.(events.OrderStatusUpdatedEventData))
+	}
+	return updates
+}
+
+// addOrder сохраняет заказ владельца fixture в статусе status
+func (f *orderHandlerFixture) addOrder(status models.OrderStatus) uuid.UUID {
+	order := models.Order{
+		ID:        uuid.New(),
+		UserID:    f.owner,
+		Items:     []models.OrderItem{{Product: "book", Quantity: 2, Price: 10}},
+		Status:    status,
+		TotalSum:  20,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	f.orders.Seed(&order)
+	return order.ID
+}
+
+// orderRequest создает запрос от пользователя userID с правами permissions, как его
+// передает API Gateway; uuid.Nil — запрос без пользовательского контекста
+func orderRequest(method, target string, body interface{}, userID uuid.UUID, orderID string, permissions ...string) *http.Request {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+
+	req := httptest.NewRequest(method, target, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if userID != uuid.Nil {
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("X-User-ID", userID.String())
+		req.Header.Set("X-User-Email", "user@example.com")
+		req.Header.Set("X-User-Roles", "user")
+	}
+	if len(permissions) > 0 {
+		req.Header.Set(rbac.HeaderPermissions, strings.Join(permissions, ","))
+	}
+	if orderID != "" {
+		req = mux.SetURLVars(req, map[string]string{"id": orderID})
+	}
+	return req
+}
+
+// orderResponse разбирает ответ обработчика: заказ при успехе, код ошибки иначе
+func orderResponse(t *testing.T, rec *httptest.ResponseRecorder) (models.Order, string) {
+	t.Helper()
+	var response struct {
+		Success bool             `json:"success"`
+		Data    models.Order     `json:"data"`
+		Error   *models.APIError `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("некорректный JSON ответа %q: %v", rec.Body.String(), err)
+	}
+	if response.Error != nil {
+		return response.Data, response.Error.Code
+	}
+	return response.Data, ""
+}
+
+func TestCreateOrder(t *testing.T) {
+	f := newOrderHandlerFixture()
+	body := models.CreateOrderRequest{Items: []models.OrderItem{{Product: "book", Quantity: 2, Price: 10}}}
+
+	rec := httptest.NewRecorder()
+	f.handler.CreateOrder(rec, orderRequest(http.MethodPost, "/v1/orders", body, f.owner, ""))
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("код %d, ожидался 201: %s", rec.Code, rec.Body.String())
+	}
+	order, _ := orderResponse(t, rec)
+	if order.UserID != f.owner || order.Status != models.OrderStatusCreated || order.TotalSum != 20 {
+		t.Fatalf("создан заказ %+v", order)
+	}
+	if order.StatusName != "Создан" {
+		t.Errorf("status_name %q, ожидалось локализованное название", order.StatusName)
+	}
+	if _, err := f.orders.GetByID(order.ID); err != nil {
+		t.Errorf("заказ не сохранен: %v", err)
+	}
+	if published := f.events.Published(); len(published) != 1 || published[0].Type != events.OrderCreatedEvent || published[0].AggregateID != order.ID {
+		t.Errorf("опубликованы события %+v", published)
+	}
+}
+
+func TestCreateOrderErrors(t *testing.T) {
+	valid := models.CreateOrderRequest{Items: []models.OrderItem{{Product: "book", Quantity: 2, Price: 10}}}
+
+	tests := []struct {
+		name    string
+		body    interface{}
+		user    func(f *orderHandlerFixture) uuid.UUID
+		prepare func(f *orderHandlerFixture)
+		status  int
+		code    string
+	}{
+		{
+			name:   "без пользовательского контекста",
+			body:   valid,
+			user:   func(f *orderHandlerFixture) uuid.UUID { return uuid.Nil },
+			status: http.StatusUnauthorized,
+			code:   models.ErrorCodeUnauthorized,
+		},
+		{
+			name:   "пустой заказ",
+			body:   models.CreateOrderRequest{},
+			status: http.StatusBadRequest,
+			code:   models.ErrorCodeValidation,
+		},
+		{
+			name:   "неизвестное поле",
+			body:   map[string]interface{}{"items": valid.Items, "discount": 50},
+			status: http.StatusBadRequest,
+			code:   models.ErrorCodeValidation,
+		},
+		{
+			name:   "неизвестный пользователь",
+			body:   valid,
+			user:   func(f *orderHandlerFixture) uuid.UUID { return uuid.New() },
+			status: http.StatusBadRequest,
+			code:   models.ErrorCodeValidation,
+		},
+		{
+			name: "нехватка товара",
+			body: valid,
+			prepare: func(f *orderHandlerFixture) {
+				f.stock.Apply(models.StockLevel{Product: "book", Available: 1, ChangedAt: time.Now()})
+			},
+			status: http.StatusConflict,
+			code:   models.ErrorCodeConflict,
+		},
+		{
+			name:    "ошибка проверки остатков",
+			body:    valid,
+			prepare: func(f *orderHandlerFixture) { f.stock.FailWith("Available", errors.New("db down")) },
+			status:  http.StatusInternalServerError,
+			code:    models.ErrorCodeInternalServer,
+		},
+		{
+			name:    "ошибка сохранения",
+			body:    valid,
+			prepare: func(f *orderHandlerFixture) { f.orders.FailWith("Create", errors.New("db down")) },
+			status:  http.StatusInternalServerError,
+			code:    models.ErrorCodeInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrderHandlerFixture()
+			user := f.owner
+			if tt.user != nil {
+				user = tt.user(f)
+			}
+			if tt.prepare != nil {
+				tt.prepare(f)
+			}
+
+			rec := httptest.NewRecorder()
+			f.handler.CreateOrder(rec, orderRequest(http.MethodPost, "/v1/orders", tt.body, user, ""))
+
+			if rec.Code != tt.status {
+				t.Fatalf("код %d, ожидался %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if _, code := orderResponse(t, rec); code != tt.code {
+				t.Errorf("код ошибки %q, ожидался %q", code, tt.code)
+			}
+			if published := f.events.Published(); len(published) != 0 {
+				t.Errorf("при ошибке опубликованы события %+v", published)
+			}
+		})
+	}
+}
+
+func TestCreateOrderPublishFailure(t *testing.T) {
+	f := newOrderHandlerFixture()
+	f.events.FailWith("Publish", errors.New("broker down"))
+	body := models.CreateOrderRequest{Items: []models.OrderItem{{Product: "book", Quantity: 1, Price: 10}}}
+
+	rec := httptest.NewRecorder()
+	f.handler.CreateOrder(rec, orderRequest(http.MethodPost, "/v1/orders", body, f.owner, ""))
+
+	// Заказ уже сохранен: ошибка публикации не меняет ответ
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("код %d, ожидался 201", rec.Code)
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	f := newOrderHandlerFixture()
+	orderID := f.addOrder(models.OrderStatusInWork)
+
+	tests := []struct {
+		name    string
+		user    uuid.UUID
+		orderID string
+		status  int
+		code    string
+	}{
+		{name: "владелец", user: f.owner, orderID: orderID.String(), status: http.StatusOK},
+		{name: "чужой заказ", user: uuid.New(), orderID: orderID.String(), status: http.StatusForbidden, code: models.ErrorCodeForbidden},
+		{name: "некорректный ID", user: f.owner, orderID: "not-a-uuid", status: http.StatusBadRequest, code: models.ErrorCodeValidation},
+		{name: "заказ не найден", user: f.owner, orderID: uuid.NewString(), status: http.StatusNotFound, code: models.ErrorCodeNotFound},
+		{name: "без пользовательского контекста", user: uuid.Nil, orderID: orderID.String(), status: http.StatusUnauthorized, code: models.ErrorCodeUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			f.handler.GetOrder(rec, orderRequest(http.MethodGet, "/v1/orders/"+tt.orderID, nil, tt.user, tt.orderID))
+
+			if rec.Code != tt.status {
+				t.Fatalf("код %d, ожидался %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			order, code := orderResponse(t, rec)
+			if code != tt.code {
+				t.Errorf("код ошибки %q, ожидался %q", code, tt.code)
+			}
+			if tt.status == http.StatusOK && (order.ID != orderID || order.StatusName != "В работе") {
+				t.Errorf("получен заказ %+v", order)
+			}
+		})
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	f := newOrderHandlerFixture()
+	orderID := f.addOrder(models.OrderStatusCreated)
+	manager := uuid.New()
+
+	rec := httptest.NewRecorder()
+	body := models.UpdateOrderStatusRequest{Status: models.OrderStatusInWork}
+	f.handler.UpdateOrderStatus(rec, orderRequest(http.MethodPut, "/v1/orders/"+orderID.String()+"/status", body, manager, orderID.String(), rbac.OrdersManage))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("код %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+	if order, _ := orderResponse(t, rec); order.Status != models.OrderStatusInWork {
+		t.Fatalf("статус %q, ожидался in_work", order.Status)
+	}
+	updates := f.statusUpdates()
+	if len(updates) != 1 || updates[0].OldStatus != models.OrderStatusCreated || updates[0].NewStatus != models.OrderStatusInWork || updates[0].UpdatedBy != manager {
+		t.Errorf("опубликованы события %+v", updates)
+	}
+}
+
+func TestUpdateOrderStatusErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial models.OrderStatus
+		body    interface{}
+		user    func(f *orderHandlerFixture) uuid.UUID
+		orderID func(id uuid.UUID) string
+		prepare func(f *orderHandlerFixture)
+		status  int
+		code    string
+	}{
+		{
+			name:    "недопустимый статус",
+			initial: models.OrderStatusCreated,
+			body:    map[string]string{"status": "shipped"},
+			status:  http.StatusBadRequest,
+			code:    models.ErrorCodeValidation,
+		},
+		{
+			name:    "чужой заказ",
+			initial: models.OrderStatusCreated,
+			user:    func(f *orderHandlerFixture) uuid.UUID { return uuid.New() },
+			status:  http.StatusForbidden,
+			code:    models.ErrorCodeForbidden,
+		},
+		{
+			name:    "заказ не найден",
+			initial: models.OrderStatusCreated,
+			orderID: func(uuid.UUID) string { return uuid.NewString() },
+			status:  http.StatusNotFound,
+			code:    models.ErrorCodeNotFound,
+		},
+		{
+			name:    "завершенный заказ",
+			initial: models.OrderStatusCompleted,
+			status:  http.StatusBadRequest,
+			code:    models.ErrorCodeValidation,
+		},
+		{
+			name:    "заказ на проверке",
+			initial: models.OrderStatusFlagged,
+			status:  http.StatusConflict,
+			code:    models.ErrorCodeConflict,
+		},
+		{
+			name:    "ошибка сохранения",
+			initial: models.OrderStatusCreated,
+			prepare: func(f *orderHandlerFixture) { f.orders.FailWith("UpdateStatus", errors.New("db down")) },
+			status:  http.StatusInternalServerError,
+			code:    models.ErrorCodeInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrderHandlerFixture()
+			id := f.addOrder(tt.initial)
+			user, orderID := f.owner, id.String()
+			if tt.user != nil {
+				user = tt.user(f)
+			}
+			if tt.orderID != nil {
+				orderID = tt.orderID(id)
+			}
+			if tt.prepare != nil {
+				tt.prepare(f)
+			}
+			body := tt.body
+			if body == nil {
+				body = models.UpdateOrderStatusRequest{Status: models.OrderStatusInWork}
+			}
+
+			rec := httptest.NewRecorder()
+			f.handler.UpdateOrderStatus(rec, orderRequest(http.MethodPut, "/v1/orders/"+orderID+"/status", body, user, orderID))
+
+			if rec.Code != tt.status {
+				t.Fatalf("код %d, ожидался %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if _, code := orderResponse(t, rec); code != tt.code {
+				t.Errorf("код ошибки %q, ожидался %q", code, tt.code)
+			}
+			if stored, err := f.orders.GetByID(id); err != nil || stored.Status != tt.initial {
+				t.Errorf("статус сохраненного заказа изменен: %+v, %v", stored, err)
+			}
+			if published := f.events.Published(); len(published) != 0 {
+				t.Errorf("при ошибке опубликованы события %+v", published)
+			}
+		})
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	f := newOrderHandlerFixture()
+	orderID := f.addOrder(models.OrderStatusCreated)
+
+	rec := httptest.NewRecorder()
+	f.handler.CancelOrder(rec, orderRequest(http.MethodPost, "/v1/orders/"+orderID.String()+"/cancel", nil, f.owner, orderID.String()))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("код %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+	order, _ := orderResponse(t, rec)
+	if order.Status != models.OrderStatusCancelled || order.StatusName != "Отменен" {
+		t.Fatalf("отмененный заказ %+v", order)
+	}
+	if order.Cancellation == nil || order.Cancellation.Rule != models.CancellationFree {
+		t.Errorf("условия отмены %+v, ожидалась бесплатная отмена", order.Cancellation)
+	}
+	updates := f.statusUpdates()
+	if len(updates) != 1 || updates[0].NewStatus != models.OrderStatusCancelled || updates[0].Cancellation == nil {
+		t.Errorf("опубликованы события %+v", updates)
+	}
+}
+
+func TestCancelOrderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial models.OrderStatus
+		user    func(f *orderHandlerFixture) uuid.UUID
+		prepare func(f *orderHandlerFixture)
+		status  int
+		code    string
+	}{
+		{
+			name:    "чужой заказ",
+			initial: models.OrderStatusCreated,
+			user:    func(f *orderHandlerFixture) uuid.UUID { return uuid.New() },
+			status:  http.StatusForbidden,
+			code:    models.ErrorCodeForbidden,
+		},
+		{
+			name:    "уже отменен",
+			initial: models.OrderStatusCancelled,
+			status:  http.StatusBadRequest,
+			code:    models.ErrorCodeValidation,
+		},
+		{
+			name:    "заказ в работе запрещено отменять",
+			initial: models.OrderStatusInWork,
+			status:  http.StatusConflict,
+			code:    models.ErrorCodeCancellationForbidden,
+		},
+		{
+			name:    "ошибка сохранения",
+			initial: models.OrderStatusCreated,
+			prepare: func(f *orderHandlerFixture) { f.orders.FailWith("Cancel", errors.New("db down")) },
+			status:  http.StatusInternalServerError,
+			code:    models.ErrorCodeInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOrderHandlerFixture()
+			id := f.addOrder(tt.initial)
+			user := f.owner
+			if tt.user != nil {
+				user = tt.user(f)
+			}
+			if tt.prepare != nil {
+				tt.prepare(f)
+			}
+
+			rec := httptest.NewRecorder()
+			f.handler.CancelOrder(rec, orderRequest(http.MethodPost, "/v1/orders/"+id.String()+"/cancel", nil, user, id.String()))
+
+			if rec.Code != tt.status {
+				t.Fatalf("код %d, ожидался %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if _, code := orderResponse(t, rec); code != tt.code {
+				t.Errorf("код ошибки %q, ожидался %q", code, tt.code)
+			}
+			if stored, _ := f.orders.GetByID(id); stored.Status != tt.initial {
+				t.Errorf("статус сохраненного заказа изменен на %q", stored.Status)
+			}
+		})
+	}
+}
+
+func TestCancelOrderInWorkByManager(t *testing.T) {
+	f := newOrderHandlerFixture()
+	orderID := f.addOrder(models.OrderStatusInWork)
+
+	rec := httptest.NewRecorder()
+	f.handler.CancelOrder(rec, orderRequest(http.MethodPost, "/v1/orders/"+orderID.String()+"/cancel", nil, uuid.New(), orderID.String(), rbac.OrdersManage))
+
+	// Право orders.manage снимает ограничения правил отмены
+	if rec.Code != http.StatusOK {
+		t.Fatalf("код %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+	if order, _ := orderResponse(t, rec); order.Cancellation == nil || order.Cancellation.Policy != models.CancellationPolicyAdmin {
+		t.Errorf("условия отмены %+v, ожидалась отмена администратором", order.Cancellation)
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"service_orders/logger"
+	"service_orders/models"
+
+	"pkg/rbac"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maxHookDecisionsLimit максимальное число решений проверок в одном ответе
+const maxHookDecisionsLimit = 200
+
+// OrderHookHandler административный просмотр журнала проверок заказа перед сохранением:
+// помеченные для ручной проверки и отклоненные заказы
+type OrderHookHandler struct {
+	*OrderHandler
+}
+
+// NewOrderHookHandler создает новый обработчик журнала проверок заказа
+func NewOrderHookHandler(orderHandler *OrderHandler) *OrderHookHandler {
+	return &OrderHookHandler{OrderHandler: orderHandler}
+}
+
+// ListHookDecisions возвращает последние решения проверок, новые первыми.
+// Параметры: verdict (allow, flag, veto или error), limit
+func (h *OrderHookHandler) ListHookDecisions(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersReadAll)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	verdict := models.HookVerdict(query.Get("verdict"))
+	if verdict != "" && !verdict.IsValid() {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Параметр verdict должен быть одним из: allow, flag, veto, error")
+		return
+	}
+
+	limit := 50
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxHookDecisionsLimit {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation,
+				fmt.Sprintf("Параметр limit должен быть от 1 до %d", maxHookDecisionsLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	decisions, err := h.hookDecisions(r).ListRecent(verdict, limit)
+	if err != nil {
+		logger.LogOrderAction(r, "list_hook_decisions", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения решений проверок")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, decisions)
+}
+
+// ListOrderHookDecisions возвращает решения проверок заказа в порядке выполнения.
+// Заказ может отсутствовать: решения отклоненных заказов тоже хранятся
+func (h *OrderHookHandler) ListOrderHookDecisions(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersReadAll)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID заказа")
+		return
+	}
+
+	decisions, err := h.hookDecisions(r).ListForOrder(orderID)
+	if err != nil {
+		logger.LogOrderAction(r, "list_order_hook_decisions", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения решений проверок")
+		return
+	}
+	if len(decisions) == 0 {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Решения проверок заказа не найдены")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, decisions)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"service_orders/logger"
+	"service_orders/models"
+
+	"pkg/rbac"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// OrderTagHandler обработчик тегов заказов: операторы (администраторы) размечают заказы
+// тегами (например fragile, corporate, problem-customer) для организации очередей работ
+type OrderTagHandler struct {
+	*OrderHandler
+}
+
+// NewOrderTagHandler создает новый обработчик тегов заказов
+func NewOrderTagHandler(orderHandler *OrderHandler) *OrderTagHandler {
+	return &OrderTagHandler{OrderHandler: orderHandler}
+}
+
+// UpdateOrderTags заменяет теги заказа списком из тела запроса и возвращает заказ с тегами
+func (h *OrderTagHandler) UpdateOrderTags(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersManage)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID заказа")
+		return
+	}
+
+	var req models.UpdateOrderTagsRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	tags, err := models.NormalizeTags(req.Tags)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	order, err := h.orders(r).GetByID(orderID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Заказ не найден")
+		return
+	}
+
+	if err := h.tags(r).SetTags(orderID, tags, userCtx.UserID); err != nil {
+		logger.LogOrderAction(r, "update_order_tags", orderID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка изменения тегов заказа")
+		return
+	}
+	order.Tags = tags
+
+	logger.LogOrderAction(r, "update_order_tags", orderID.String(), fmt.Sprintf("tags=%s, operator=%s", strings.Join(tags, ","), userCtx.UserID), true)
+
+	h.localizeStatuses(r, order)
+	h.sendSuccessResponse(w, http.StatusOK, order)
+}
+
+// GetTagStats возвращает сводку по тегам: число заказов, сумму и распределение по статусам.
+// Параметры: tags (теги сводки, по умолчанию все), status, from и to (период создания
+// заказов в RFC 3339, to не включается)
+func (h *OrderTagHandler) GetTagStats(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersReadAll)
+	if !ok {
+		return
+	}
+
+	req, err := parseTagStatsRequest(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	stats, err := h.tags(r).Stats(req)
+	if err != nil {
+		logger.LogOrderAction(r, "tag_stats", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка расчета сводки по тегам")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, models.TagStatsResponse{
+		Status: req.Status,
+		From:   req.From,
+		To:     req.To,
+		Tags:   stats,
+	})
+}
+
+// parseTagStatsRequest разбирает и проверяет параметры сводки по тегам
+func parseTagStatsRequest(r *http.Request) (*models.TagStatsRequest, error) {
+	query := r.URL.Query()
+	req := &models.TagStatsRequest{}
+
+	tags, err := models.ParseTags(query.Get("tags"))
+	if err != nil {
+		return nil, err
+	}
+	req.Tags = tags
+
+	if value := query.Get("status"); value != "" {
+		req.Status = models.ParseOrderStatus(value)
+		if !req.Status.IsValid() {
+			return nil, fmt.Errorf("параметр status должен быть одним из: created, in_work, completed, cancelled, flagged")
+		}
+	}
+
+	if req.From, err = parseTimeParam(query.Get("from"), "from"); err != nil {
+		return nil, err
+	}
+	if req.To, err = parseTimeParam(query.Get("to"), "to"); err != nil {
+		return nil, err
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return nil, fmt.Errorf("параметр from должен быть раньше to")
+	}
+	return req, nil
+}
+
+// parseTimeParam разбирает время параметра name в формате RFC 3339; пусто — nil
+func parseTimeParam(value, name string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный параметр %s: ожидается время в формате RFC 3339", name)
+	}
+	parsed = parsed.UTC()
+	return &parsed, nil
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"service_orders/export"
+	"service_orders/logger"
+	"service_orders/models"
+	"service_orders/repository"
+
+	"pkg/rbac"
+	"pkg/servertiming"
+	"pkg/timeutil"
+)
+
+// PickingListHandler обработчик сборочных листов склада
+type PickingListHandler struct {
+	*ExportHandler
+	pickingRepo repository.PickingListRepository
+}
+
+// NewPickingListHandler создает новый обработчик сборочных листов
+func NewPickingListHandler(exportHandler *ExportHandler, pickingRepo repository.PickingListRepository) *PickingListHandler {
+	return &PickingListHandler{
+		ExportHandler: exportHandler,
+		pickingRepo:   pickingRepo,
+	}
+}
+
+// pickingLists возвращает репозиторий сборочных листов, учитывающий время запросов к БД
+// в Server-Timing запроса r
+func (h *PickingListHandler) pickingLists(r *http.Request) repository.PickingListRepository {
+	return repository.TimedPickingListRepository(h.pickingRepo, servertiming.FromContext(r.Context()))
+}
+
+// GetPickingList формирует сводный сборочный лист по заказам (право orders.read_all).
+// Параметры: status (created или in_work, по умолчанию in_work; принимаются и русские
+// названия), region (пусто — все регионы), format (json, csv или pdf). Администратор
+// формирует не более одной выгрузки одновременно; файлы CSV и PDF сохраняются для докачки
+func (h *PickingListHandler) GetPickingList(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersReadAll)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	status := models.OrderStatusInWork
+	if value := query.Get("status"); value != "" {
+		status = models.ParseOrderStatus(value)
+	}
+	if status != models.OrderStatusCreated && status != models.OrderStatusInWork {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Сборочный лист формируется только по заказам со статусом created или in_work")
+		return
+	}
+
+	region := strings.TrimSpace(query.Get("region"))
+	if utf8.RuneCountInString(region) > 100 {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Регион должен содержать не более 100 символов")
+		return
+	}
+
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = models.PickingListFormatJSON
+	}
+	if format != models.PickingListFormatJSON && format != models.PickingListFormatCSV && format != models.PickingListFormatPDF {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Параметр format должен быть json, csv или pdf")
+		return
+	}
+
+	unlock, ok := h.beginExport(w, r, "picking_list", userCtx.UserID)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	list, err := h.pickingLists(r).Build(status, region)
+	if err != nil {
+		logger.LogOrderAction(r, "picking_list", "", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка формирования сборочного листа")
+		return
+	}
+	list.GeneratedAt = timeutil.Now()
+	if names, err := h.statuses(r).DisplayNames(requestLocale(r)); err == nil {
+		list.StatusName = names[status]
+	}
+
+	logger.LogOrderAction(r, "picking_list", "",
+		fmt.Sprintf("status=%s, region=%s, format=%s, orders=%d, products=%d, admin=%s",
+			status, region, format, list.OrdersCount, len(list.Items), userCtx.UserID), true)
+
+	if format == models.PickingListFormatJSON {
+		h.sendSuccessResponse(w, http.StatusOK, list)
+		return
+	}
+
+	// Выгрузка формируется целиком до отправки, чтобы при ошибке вернуть 500 в формате API
+	var body bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	render := export.PickingListCSV
+	if format == models.PickingListFormatPDF {
+		contentType = "application/pdf"
+		render = export.PickingListPDF
+	}
+	if err := render(&body, list); err != nil {
+		logger.LogOrderAction(r, "picking_list", "", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка формирования сборочного листа")
+		return
+	}
+
+	h.sendExport(w, r, &models.ExportArtifact{
+		UserID:      userCtx.UserID,
+		Kind:        "picking_list",
+		Filename:    fmt.Sprintf("picking-list-%s-%s.%s", status, list.GeneratedAt.UTC().Format("20060102-150405"), format),
+		ContentType: contentType,
+		Content:     body.Bytes(),
+	})
+}
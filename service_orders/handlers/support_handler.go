@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"service_orders/logger"
+	"service_orders/models"
+	"service_orders/repository"
+
+	"pkg/rbac"
+	"pkg/redact"
+	"pkg/servertiming"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// SupportHandler обработчик просмотра заказов для поддержки: email и имя покупателя
+// маскируются, полные данные раскрываются только с правом pii.reveal и записью в журнал аудита
+type SupportHandler struct {
+	*OrderHandler
+	auditRepo repository.AdminAuditRepository
+}
+
+// NewSupportHandler создает новый обработчик просмотра для поддержки
+func NewSupportHandler(orderHandler *OrderHandler, auditRepo repository.AdminAuditRepository) *SupportHandler {
+	return &SupportHandler{
+		OrderHandler: orderHandler,
+		auditRepo:    auditRepo,
+	}
+}
+
+// audit возвращает журнал действий администраторов, учитывающий время запросов к БД запроса r
+func (h *SupportHandler) audit(r *http.Request) repository.AdminAuditRepository {
+	return repository.TimedAdminAuditRepository(h.auditRepo, servertiming.FromContext(r.Context()))
+}
+
+// Redacted оборачивает обработчик просмотра для поддержки: проверяет право support.view
+// и маскирует персональные данные в ответе. Запрос с reveal=true и причиной reason
+// от пользователя с правом pii.reveal получает ответ без маскирования; раскрытие
+// записывается в журнал аудита до отправки ответа
+func (h *SupportHandler) Redacted(next http.HandlerFunc) http.Handler {
+	masked := redact.Default.Handler(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userCtx, ok := h.requirePermission(w, r, rbac.SupportView)
+		if !ok {
+			return
+		}
+
+		reason, reveal, err := redact.RevealRequested(r)
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+			return
+		}
+		if !reveal {
+			masked.ServeHTTP(w, r)
+			return
+		}
+
+		target := mux.Vars(r)["id"]
+		if !userCtx.Can(rbac.PIIReveal) {
+			logger.LogOrderAction(r, "pii_reveal_order", target, "denied", false)
+			h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Недостаточно прав для раскрытия персональных данных")
+			return
+		}
+		// Раскрытие без записи в журнал аудита не выполняется
+		if err := h.audit(r).Record(userCtx.UserID, "pii_reveal_order", target, "reason="+reason); err != nil {
+			logger.LogOrderAction(r, "pii_reveal_order", target, err.Error(), false)
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка записи раскрытия в журнал аудита")
+			return
+		}
+		logger.LogOrderAction(r, "pii_reveal_order", target, fmt.Sprintf("reason=%s", reason), true)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetSupportOrder возвращает заказ для поддержки вместе с покупателем и тегами
+func (h *SupportHandler) GetSupportOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID заказа")
+		return
+	}
+
+	order, err := h.orders(r).GetByID(orderID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Заказ не найден")
+		return
+	}
+
+	orders := []*models.Order{order}
+	if err := h.attachCustomers(r, orders); err != nil {
+		logger.LogOrderAction(r, "get_support_order", orderID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения данных покупателя")
+		return
+	}
+	if err := h.attachTags(r, orders); err != nil {
+		logger.LogOrderAction(r, "get_support_order", orderID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения тегов заказа")
+		return
+	}
+
+	h.localizeStatuses(r, order)
+	h.sendSuccessResponse(w, http.StatusOK, order)
+}
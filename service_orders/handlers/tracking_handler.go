@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"service_orders/logger"
+	"service_orders/models"
+	"service_orders/utils"
+
+	"pkg/rbac"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// TrackingHandler обработчик ссылок отслеживания заказа без входа (подарки, гостевая доставка)
+type TrackingHandler struct {
+	*OrderHandler
+}
+
+// NewTrackingHandler создает новый обработчик ссылок отслеживания
+func NewTrackingHandler(orderHandler *OrderHandler) *TrackingHandler {
+	return &TrackingHandler{OrderHandler: orderHandler}
+}
+
+// CreateTrackingToken выдает владельцу заказа или администратору ссылку отслеживания.
+// Каждый вызов выдает новый токен; ранее выданные действуют до истечения срока
+func (h *TrackingHandler) CreateTrackingToken(w http.ResponseWriter, r *http.Request) {
+	userCtx, err := utils.GetUserContextFromHeaders(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, err.Error())
+		return
+	}
+
+	orderID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID заказа")
+		return
+	}
+
+	order, err := h.orders(r).GetByID(orderID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Заказ не найден")
+		return
+	}
+
+	if err := userCtx.ValidateOrderOwnership(order.UserID, rbac.OrdersReadAll); err != nil {
+		logger.LogOrderAction(r, "create_tracking_token", orderID.String(), "Access denied: "+err.Error(), false)
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, err.Error())
+		return
+	}
+
+	cfg := h.config.Current().Tracking
+	expiresAt := time.Now().UTC().Add(cfg.TTL).Truncate(time.Second)
+	token := utils.GenerateTrackingToken(order.ID, expiresAt, cfg.Secret)
+
+	logger.LogOrderAction(r, "create_tracking_token", orderID.String(), fmt.Sprintf("expires_at=%s", expiresAt.Format(time.RFC3339)), true)
+
+	h.sendSuccessResponse(w, http.StatusCreated, models.TrackingTokenResponse{
+		Token:     token,
+		Path:      "/v1/track/" + token,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// TrackOrder возвращает статус заказа по токену отслеживания без аутентификации.
+// Поврежденный, истекший токен и удаленный заказ неразличимы для клиента
+func (h *TrackingHandler) TrackOrder(w http.ResponseWriter, r *http.Request) {
+	// Токен в URL: ответ не должен кешироваться и передаваться в Referer
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+
+	orderID, err := utils.ParseTrackingToken(mux.Vars(r)["token"], h.config.Current().Tracking.Secret, time.Now())
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Ссылка недействительна или истекла")
+		return
+	}
+
+	order, err := h.orders(r).GetByID(orderID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Ссылка недействительна или истекла")
+		return
+	}
+
+	h.localizeStatuses(r, order)
+	h.sendSuccessResponse(w, http.StatusOK, models.TrackingResponse{
+		Status:     order.Status,
+		StatusName: order.StatusName,
+		ItemsCount: len(order.Items),
+		CreatedAt:  order.CreatedAt,
+		UpdatedAt:  order.UpdatedAt,
+	})
+}
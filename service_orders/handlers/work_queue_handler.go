@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"service_orders/logger"
+	"service_orders/models"
+	"service_orders/repository"
+	"service_orders/utils"
+
+	"pkg/rbac"
+	"pkg/servertiming"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// WorkQueueHandler обработчик очереди заказов операторов: оператор (администратор)
+// берет самый старый необработанный заказ, а затем завершает его или возвращает в очередь
+type WorkQueueHandler struct {
+	*OrderHandler
+	queueRepo repository.WorkQueueRepository
+}
+
+// NewWorkQueueHandler создает новый обработчик очереди заказов
+func NewWorkQueueHandler(orderHandler *OrderHandler, queueRepo repository.WorkQueueRepository) *WorkQueueHandler {
+	return &WorkQueueHandler{
+		OrderHandler: orderHandler,
+		queueRepo:    queueRepo,
+	}
+}
+
+// queue возвращает репозиторий очереди, учитывающий время запросов к БД в Server-Timing запроса r
+func (h *WorkQueueHandler) queue(r *http.Request) repository.WorkQueueRepository {
+	return repository.TimedWorkQueueRepository(h.queueRepo, servertiming.FromContext(r.Context()))
+}
+
+// ClaimOrder назначает вызывающему оператору самый старый заказ очереди и переводит его
+// в работу. Параметр tags ограничивает очередь заказами со всеми перечисленными тегами
+// (например, отдельная очередь для fragile). Пустая очередь — 204 без тела
+func (h *WorkQueueHandler) ClaimOrder(w http.ResponseWriter, r *http.Request) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersManage)
+	if !ok {
+		return
+	}
+
+	tags, err := models.ParseTags(r.URL.Query().Get("tags"))
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	orderID, claimed, err := h.queue(r).Claim(userCtx.UserID, tags)
+	if err != nil {
+		logger.LogOrderAction(r, "claim_order", userCtx.UserID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка назначения заказа")
+		return
+	}
+	if !claimed {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h.respondTransition(w, r, userCtx, "claim_order", orderID, models.OrderStatusCreated, models.OrderStatusInWork)
+}
+
+// ReleaseOrder снимает назначение заказа с вызывающего оператора и возвращает заказ в очередь
+func (h *WorkQueueHandler) ReleaseOrder(w http.ResponseWriter, r *http.Request) {
+	h.finishAssignment(w, r, "release_order", h.queue(r).Release, models.OrderStatusCreated)
+}
+
+// CompleteOrder завершает заказ, назначенный вызывающему оператору
+func (h *WorkQueueHandler) CompleteOrder(w http.ResponseWriter, r *http.Request) {
+	h.finishAssignment(w, r, "complete_order", h.queue(r).Complete, models.OrderStatusCompleted)
+}
+
+// ApproveOrder одобряет заказ, помеченный проверкой перед созданием: заказ переходит
+// в статус created и попадает в очередь. Заказ не в статусе flagged — 409
+func (h *WorkQueueHandler) ApproveOrder(w http.ResponseWriter, r *http.Request) {
+	h.reviewFlagged(w, r, "approve_order", h.queue(r).Approve, models.OrderStatusCreated)
+}
+
+// RejectOrder отклоняет заказ, помеченный проверкой перед созданием: заказ отменяется
+func (h *WorkQueueHandler) RejectOrder(w http.ResponseWriter, r *http.Request) {
+	h.reviewFlagged(w, r, "reject_order", h.queue(r).Reject, models.OrderStatusCancelled)
+}
+
+// reviewFlagged применяет решение администратора к заказу в статусе flagged,
+// переводящее его в newStatus
+func (h *WorkQueueHandler) reviewFlagged(w http.ResponseWriter, r *http.Request, action string,
+	apply func(orderID uuid.UUID) (bool, error), newStatus models.OrderStatus) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersManage)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID заказа")
+		return
+	}
+
+	order, err := h.orders(r).GetByID(orderID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Заказ не найден")
+		return
+	}
+
+	applied, err := apply(orderID)
+	if err != nil {
+		logger.LogOrderAction(r, action, orderID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка обработки заказа")
+		return
+	}
+	if !applied {
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict,
+			fmt.Sprintf("Заказ со статусом '%s' не ожидает проверки", order.Status))
+		return
+	}
+
+	h.respondTransition(w, r, userCtx, action, orderID, models.OrderStatusFlagged, newStatus)
+}
+
+// finishAssignment применяет к назначенному оператору заказу действие, переводящее его
+// из in_work в newStatus. Заказ, не назначенный оператору или уже не в работе, — 409
+func (h *WorkQueueHandler) finishAssignment(w http.ResponseWriter, r *http.Request, action string,
+	apply func(orderID, operatorID uuid.UUID) (bool, error), newStatus models.OrderStatus) {
+	userCtx, ok := h.requirePermission(w, r, rbac.OrdersManage)
+	if !ok {
+		return
+	}
+
+	orderID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID заказа")
+		return
+	}
+
+	order, err := h.orders(r).GetByID(orderID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Заказ не найден")
+		return
+	}
+
+	applied, err := apply(orderID, userCtx.UserID)
+	if err != nil {
+		logger.LogOrderAction(r, action, orderID.String(), err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка обработки заказа")
+		return
+	}
+	if !applied {
+		message := "Заказ назначен другому оператору"
+		switch {
+		case order.Status != models.OrderStatusInWork:
+			message = fmt.Sprintf("Заказ со статусом '%s' не находится в работе", order.Status)
+		case order.AssignedTo == nil:
+			message = "Заказ не назначен оператору"
+		}
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, message)
+		return
+	}
+
+	h.respondTransition(w, r, userCtx, action, orderID, models.OrderStatusInWork, newStatus)
+}
+
+// respondTransition логирует переход статуса заказа из очереди, публикует событие
+// изменения статуса и возвращает обновленный заказ
+func (h *WorkQueueHandler) respondTransition(w http.ResponseWriter, r *http.Request, userCtx *utils.UserContext,
+	action string, orderID uuid.UUID, oldStatus, newStatus models.OrderStatus) {
+	order, err := h.orders(r).GetByID(orderID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения обновленного заказа")
+		return
+	}
+
+	if err := h.attachTags(r, []*models.Order{order}); err != nil {
+		// Статус уже изменен, заказ возвращается без тегов
+		logger.LogOrderAction(r, action, orderID.String(), err.Error(), false)
+	}
+
+	statusDetails := fmt.Sprintf("%s -> %s, operator=%s", oldStatus, newStatus, userCtx.UserID)
+	logger.LogOrderAction(r, action, orderID.String(), statusDetails, true)
+	logger.LogBusinessEvent(r, "order_status_updated", orderID.String(), "order", statusDetails)
+
+	if err := h.eventService.PublishOrderStatusUpdated(context.Background(), orderID, order.UserID, userCtx.UserID, oldStatus, newStatus, r); err != nil {
+		// Статус уже изменен, ошибка публикации только логируется
+		logger.LogOrderAction(r, "publish_event", orderID.String(), "OrderStatusUpdatedEvent failed: "+err.Error(), false)
+	}
+
+	h.localizeStatuses(r, order)
+	h.sendSuccessResponse(w, http.StatusOK, order)
+}
@@ -1,22 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"service_orders/config"
-	"service_orders/events"
-	"service_orders/handlers"
 	"service_orders/logger"
-	"service_orders/repository"
+	"service_orders/server"
+
+	"pkg/ids"
 
-	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 )
@@ -38,6 +36,13 @@ func main() {
 		zapLogger.Fatal("Ошибка загрузки конфигурации", zap.Error(err))
 	}
 
+	// Стратегия идентификаторов новых записей
+	idGenerator, err := ids.NewGenerator(cfg.DB.IDStrategy)
+	if err != nil {
+		zapLogger.Fatal("Ошибка настройки идентификаторов", zap.Error(err))
+	}
+	ids.SetDefault(idGenerator)
+
 	// Подключение к базе данных
 	db, err := sql.Open("postgres", cfg.DB.DSN())
 	if err != nil {
@@ -52,108 +57,50 @@ func main() {
 
 	zapLogger.Info("Успешное подключение к базе данных")
 
-	// Инициализация системы событий
-	eventPublisher := events.NewInMemoryEventPublisher()
-	eventService := events.NewEventService(eventPublisher)
-	
+	// Система событий, репозитории, обработчики и маршруты сервиса
+	srv, err := server.New(cfg, db)
+	if err != nil {
+		zapLogger.Fatal("Ошибка инициализации сервиса", zap.Error(err))
+	}
+
+	// Фоновые задачи останавливаются при завершении сервиса
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	srv.Start(backgroundCtx)
+
 	// Настройка graceful shutdown для корректного закрытия системы событий
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
 		log.Println("Получен сигнал завершения, закрываем сервис...")
-		
-		if err := eventService.Close(); err != nil {
+		stopBackground()
+
+		if err := srv.Close(); err != nil {
 			log.Printf("Ошибка закрытия сервиса событий: %v", err)
 		}
-		
+
 		if err := db.Close(); err != nil {
 			log.Printf("Ошибка закрытия БД: %v", err)
 		}
-		
+
 		log.Println("Сервис корректно завершен")
 		os.Exit(0)
 	}()
 
 	log.Println("Система событий инициализирована")
 
-	// Инициализация репозитория и обработчиков
-	orderRepo := repository.NewOrderRepository(db)
-	orderHandler := handlers.NewOrderHandler(orderRepo, cfg, eventService)
-
-	// Настройка маршрутов
-	router := mux.NewRouter()
-
-	// Маршруты для сервиса заказов
-	router.HandleFunc("/v1/orders", orderHandler.CreateOrder).Methods("POST")
-	router.HandleFunc("/v1/orders/{id}", orderHandler.GetOrder).Methods("GET")
-	router.HandleFunc("/v1/orders", orderHandler.ListOrders).Methods("GET")
-	router.HandleFunc("/v1/orders/{id}/status", orderHandler.UpdateOrderStatus).Methods("PUT")
-	router.HandleFunc("/v1/orders/{id}/cancel", orderHandler.CancelOrder).Methods("PUT")
-	// Совместимость с тестами: поддерживаем также POST для отмены заказа
-	router.HandleFunc("/v1/orders/{id}/cancel", orderHandler.CancelOrder).Methods("POST")
-
-	// Дополнительный endpoint для статистики событий (для мониторинга)
-	router.HandleFunc("/v1/events/stats", func(w http.ResponseWriter, r *http.Request) {
-		stats := eventService.GetStats()
-		
-		response := map[string]interface{}{
-			"success": true,
-			"data": map[string]interface{}{
-				"statistics":    stats,
-				"service":       "service_orders",
-				"timestamp":     time.Now().Format(time.RFC3339),
-				"description":   "Статистика доменных событий",
-			},
-		}
-		
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(response)
-	}).Methods("GET")
-
-	// Middleware для логирования
-	router.Use(loggingMiddleware)
-
 	zapLogger.Info("Service Orders с системой событий запущен", zap.String("port", cfg.Server.Port))
-	log.Fatal(http.ListenAndServe(":"+cfg.Server.Port, router))
-}
-
-// loggingMiddleware middleware для логирования запросов
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Создаем wrapper для захвата статус кода
-		wrapper := &responseWrapper{ResponseWriter: w, statusCode: http.StatusOK}
-		
-		start := time.Now()
-		next.ServeHTTP(wrapper, r)
-		duration := time.Since(start)
-
-		// Используем структурированный логгер
-		logger.LogHTTPRequest(r, wrapper.statusCode, r.Method, r.URL.Path, "service_orders")
-		
-		// Дополнительные метрики
-		zapLogger := logger.GetLogger()
-		if requestID := r.Header.Get("X-Request-ID"); requestID != "" {
-			zapLogger = logger.WithRequestID(zapLogger, requestID)
-		}
-		
-		zapLogger.Info("Request completed",
-			zap.Duration("duration", duration),
-			zap.Int64("content_length", r.ContentLength),
-		)
-	})
-}
-
-// responseWrapper для захвата HTTP статус кода
-type responseWrapper struct {
-	http.ResponseWriter
-	statusCode int
-}
-
-func (rw *responseWrapper) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
+	// h2c: API Gateway может мультиплексировать запросы в одном соединении HTTP/2 без TLS
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(cfg.Server.H2C)
+	httpServer := &http.Server{
+		Addr:        ":" + cfg.Server.Port,
+		Handler:     srv.Handler(),
+		IdleTimeout: cfg.Server.IdleTimeout,
+		Protocols:   protocols,
+	}
+	log.Fatal(httpServer.ListenAndServe())
 }
 
 // getEnv возвращает значение переменной окружения или значение по умолчанию
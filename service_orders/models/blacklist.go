@@ -0,0 +1,21 @@
+package models
+
+// BlacklistAction действие при совпадении с записью черного списка покупателей
+type BlacklistAction string
+
+const (
+	// BlacklistActionBlock заказ отклоняется
+	BlacklistActionBlock BlacklistAction = "block"
+	// BlacklistActionFlag заказ создается в статусе flagged и ждет решения администратора
+	BlacklistActionFlag BlacklistAction = "flag"
+)
+
+// BlacklistEntry запись черного списка покупателей. Список ведет service_users
+// (/v1/admin/blacklist); service_orders только проверяет по нему заказы
+type BlacklistEntry struct {
+	// Kind вид записи: user, email_domain или ip
+	Kind   string          `json:"kind"`
+	Value  string          `json:"value"`
+	Action BlacklistAction `json:"action"`
+	Reason string          `json:"reason"`
+}
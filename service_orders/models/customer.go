@@ -0,0 +1,13 @@
+package models
+
+import "github.com/google/uuid"
+
+// Customer краткие данные покупателя для обогащения списка заказов
+type Customer struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+	Name  string    `json:"name"`
+}
+
+// IncludeCustomer значение параметра include для добавления данных покупателя к заказам
+const IncludeCustomer = "customer"
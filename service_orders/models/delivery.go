@@ -0,0 +1,44 @@
+package models
+
+import "github.com/google/uuid"
+
+// ListDeliveriesRequest представляет параметры списка исходящих доставок
+type ListDeliveriesRequest struct {
+	Status  string     `json:"status" validate:"omitempty,oneof=pending processing failed delivered discarded"`
+	Kind    string     `json:"kind" validate:"omitempty,oneof=notification webhook"`
+	Channel string     `json:"channel"`
+	UserID  *uuid.UUID `json:"user_id"`
+	Limit   int        `json:"limit" validate:"min=1,max=100"`
+	Offset  int        `json:"offset" validate:"min=0"`
+}
+
+// BulkDeliveryRequest представляет массовое действие над доставками (не более 500 за раз)
+type BulkDeliveryRequest struct {
+	IDs []uuid.UUID `json:"ids" validate:"required,min=1,max=500"`
+}
+
+// BulkDeliveryResponse представляет результат массового действия
+type BulkDeliveryResponse struct {
+	Requested int   `json:"requested"`
+	Affected  int64 `json:"affected"`
+}
+
+// UpdateEventHandlerRequest представляет включение или отключение обработчика событий
+type UpdateEventHandlerRequest struct {
+	Enabled *bool  `json:"enabled" validate:"required"`
+	Reason  string `json:"reason" validate:"max=500"`
+}
+
+// BroadcastSegmentRequest представляет условия выбора получателей рассылки
+type BroadcastSegmentRequest struct {
+	Roles            []string `json:"roles" validate:"max=10,dive,required,max=50"`
+	ActiveWithinDays int      `json:"active_within_days" validate:"min=0,max=3650"`
+	Region           string   `json:"region" validate:"max=100"`
+}
+
+// CreateBroadcastRequest представляет запрос на рассылку объявления сегменту пользователей
+type CreateBroadcastRequest struct {
+	Message   string                  `json:"message" validate:"required,max=2000"`
+	Segment   BroadcastSegmentRequest `json:"segment"`
+	Marketing bool                    `json:"marketing"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExportArtifact сформированная выгрузка (файл CSV или PDF). Хранится до ExpiresAt,
+// чтобы прерванное скачивание можно было продолжить запросом с заголовком Range
+type ExportArtifact struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	// Kind вид выгрузки, например picking_list или access_review
+	Kind        string
+	Filename    string
+	ContentType string
+	Content     []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
@@ -1,19 +1,61 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// OrderStatus представляет статус заказа
+// OrderStatus представляет машинный код статуса заказа
 type OrderStatus string
 
 const (
-	OrderStatusCreated   OrderStatus = "создан"
-	OrderStatusInWork    OrderStatus = "в работе"
-	OrderStatusCompleted OrderStatus = "выполнен"
-	OrderStatusCancelled OrderStatus = "отменён"
+	OrderStatusCreated   OrderStatus = "created"
+	OrderStatusInWork    OrderStatus = "in_work"
+	OrderStatusCompleted OrderStatus = "completed"
+	OrderStatusCancelled OrderStatus = "cancelled"
+	// OrderStatusFlagged заказ помечен проверкой перед созданием и ждет решения
+	// администратора: одобренный переходит в created, отклоненный — в cancelled
+	OrderStatusFlagged OrderStatus = "flagged"
+)
+
+// legacyOrderStatuses соответствие устаревших русских значений статусов машинным кодам
+var legacyOrderStatuses = map[string]OrderStatus{
+	"создан":   OrderStatusCreated,
+	"в работе": OrderStatusInWork,
+	"выполнен": OrderStatusCompleted,
+	"отменён":  OrderStatusCancelled,
+	"отменен":  OrderStatusCancelled,
+}
+
+// ParseOrderStatus разбирает статус заказа, принимая как машинные коды,
+// так и устаревшие русские значения для обратной совместимости
+func ParseOrderStatus(value string) OrderStatus {
+	value = strings.TrimSpace(value)
+	if status, ok := legacyOrderStatuses[strings.ToLower(value)]; ok {
+		return status
+	}
+	return OrderStatus(value)
+}
+
+// UnmarshalJSON нормализует устаревшие значения статуса при разборе запросов
+func (s *OrderStatus) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*s = ParseOrderStatus(value)
+	return nil
+}
+
+// OrderItemStatus статус позиции заказа в таблице order_items
+type OrderItemStatus string
+
+const (
+	OrderItemStatusPending   OrderItemStatus = "pending"
+	OrderItemStatusCancelled OrderItemStatus = "cancelled"
 )
 
 // OrderItem представляет позицию в заказе
@@ -25,32 +67,66 @@ type OrderItem struct {
 
 // Order представляет модель заказа
 type Order struct {
-	ID        uuid.UUID   `json:"id" db:"id"`
-	UserID    uuid.UUID   `json:"user_id" db:"user_id"`
-	Items     []OrderItem `json:"items" db:"items"`
-	Status    OrderStatus `json:"status" db:"status"`
-	TotalSum  float64     `json:"total_sum" db:"total_sum"`
-	CreatedAt time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+	ID         uuid.UUID   `json:"id" db:"id"`
+	UserID     uuid.UUID   `json:"user_id" db:"user_id"`
+	Items      []OrderItem `json:"items" db:"items"`
+	Status     OrderStatus `json:"status" db:"status"`
+	StatusName string      `json:"status_name,omitempty" db:"-"`
+	TotalSum   float64     `json:"total_sum" db:"total_sum"`
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at" db:"updated_at"`
+	Customer   *Customer   `json:"customer,omitempty" db:"-"`
+	// Display суммы в валюте отображения (параметр display_currency)
+	Display *DisplayAmounts `json:"display,omitempty" db:"-"`
+	// Cancellation условия отмены; заполняется только в ответе на отмену заказа
+	Cancellation *OrderCancellation `json:"cancellation,omitempty" db:"-"`
+	// AssignedTo оператор, взявший заказ из очереди работ; nil, если заказ не назначен
+	AssignedTo *uuid.UUID `json:"assigned_to,omitempty" db:"assigned_to"`
+	AssignedAt *time.Time `json:"assigned_at,omitempty" db:"assigned_at"`
+	// Region регион доставки; по нему склад формирует сборочные листы
+	Region string `json:"region,omitempty" db:"region"`
+	// Tags теги заказа для организации работы операторов; заполняются только в ответах
+	// администраторам
+	Tags []string `json:"tags,omitempty" db:"-"`
+	// AsOf момент, на который восстановлено состояние заказа (параметр as_of); updated_at
+	// при этом — время последнего изменения до этого момента
+	AsOf *time.Time `json:"as_of,omitempty" db:"-"`
+}
+
+// DisplayAmounts суммы заказа, пересчитанные в валюту отображения. Хранимые суммы
+// (total_sum, items[].price) остаются в базовой валюте и не изменяются
+type DisplayAmounts struct {
+	Currency     string  `json:"currency"`
+	BaseCurrency string  `json:"base_currency"`
+	Rate         float64 `json:"rate"`
+	// RatesUpdatedAt время получения курса от источника
+	RatesUpdatedAt time.Time `json:"rates_updated_at"`
+	TotalSum       float64   `json:"total_sum"`
+	// ItemPrices цены позиций в порядке items
+	ItemPrices []float64 `json:"item_prices"`
 }
 
 // CreateOrderRequest представляет запрос на создание заказа
 type CreateOrderRequest struct {
-	Items []OrderItem `json:"items" validate:"required,min=1,dive"`
+	Items  []OrderItem `json:"items" validate:"required,min=1,dive"`
+	Region string      `json:"region,omitempty" validate:"max=100"`
 }
 
 // UpdateOrderStatusRequest представляет запрос на обновление статуса заказа
 type UpdateOrderStatusRequest struct {
-	Status OrderStatus `json:"status" validate:"required,oneof=создан 'в работе' выполнен отменён"`
+	Status OrderStatus `json:"status" validate:"required,oneof=created in_work completed cancelled"`
 }
 
 // ListOrdersRequest представляет параметры для получения списка заказов
 type ListOrdersRequest struct {
-	Limit  int         `json:"limit" validate:"min=1,max=100"`
-	Offset int         `json:"offset" validate:"min=0"`
-	Status OrderStatus `json:"status"`
-	Sort   string      `json:"sort" validate:"omitempty,oneof=created_at updated_at total_sum"`
-	Order  string      `json:"order" validate:"omitempty,oneof=asc desc"`
+	Limit   int         `json:"limit" validate:"min=1,max=100"`
+	Offset  int         `json:"offset" validate:"min=0"`
+	Status  OrderStatus `json:"status" validate:"omitempty,oneof=created in_work completed cancelled flagged"`
+	Sort    string      `json:"sort" validate:"omitempty,oneof=created_at updated_at total_sum"`
+	Order   string      `json:"order" validate:"omitempty,oneof=asc desc"`
+	Include []string    `json:"include" validate:"omitempty,dive,oneof=customer"`
+	// Tags заказ должен иметь все перечисленные теги (нормализованные NormalizeTags)
+	Tags []string `json:"tags"`
 }
 
 // ListOrdersResponse представляет ответ со списком заказов
@@ -77,11 +153,60 @@ func (o *Order) CanBeUpdated() bool {
 
 // CanBeCancelled проверяет, можно ли отменить заказ
 func (o *Order) CanBeCancelled() bool {
-	return o.Status == OrderStatusCreated || o.Status == OrderStatusInWork
+	return o.Status == OrderStatusCreated || o.Status == OrderStatusInWork || o.Status == OrderStatusFlagged
 }
 
 // ValidateStatus проверяет корректность статуса
 func (s OrderStatus) IsValid() bool {
 	return s == OrderStatusCreated || s == OrderStatusInWork ||
-		s == OrderStatusCompleted || s == OrderStatusCancelled
+		s == OrderStatusCompleted || s == OrderStatusCancelled || s == OrderStatusFlagged
+}
+
+// OrderStatusInfo представляет статус из справочника с локализованным названием
+type OrderStatusInfo struct {
+	Code        OrderStatus `json:"code" db:"code"`
+	DisplayName string      `json:"display_name" db:"display_name"`
+	Locale      string      `json:"locale" db:"locale"`
+	SortOrder   int         `json:"sort_order" db:"sort_order"`
+	IsFinal     bool        `json:"is_final" db:"is_final"`
+}
+
+// UpdateStatusTranslationRequest представляет запрос на изменение локализованного названия статуса
+type UpdateStatusTranslationRequest struct {
+	Locale      string `json:"locale" validate:"required,min=2,max=8"`
+	DisplayName string `json:"display_name" validate:"required,min=1,max=64"`
+}
+
+// CancellationRule правило отмены заказа клиентом
+type CancellationRule string
+
+const (
+	CancellationFree   CancellationRule = "free"
+	CancellationFee    CancellationRule = "fee"
+	CancellationForbid CancellationRule = "forbid"
+)
+
+// IsValid проверяет, что правило отмены известно
+func (r CancellationRule) IsValid() bool {
+	return r == CancellationFree || r == CancellationFee || r == CancellationForbid
+}
+
+// Политики, по которым определяются условия отмены заказа
+const (
+	// CancellationPolicyFreeWindow заказ created отменен в течение бесплатного окна после создания
+	CancellationPolicyFreeWindow = "free_window"
+	// CancellationPolicyAfterWindow заказ created отменен после бесплатного окна
+	CancellationPolicyAfterWindow = "after_window"
+	// CancellationPolicyInWork заказ отменен в статусе in_work
+	CancellationPolicyInWork = "in_work"
+	// CancellationPolicyAdmin заказ отменен администратором без ограничений и платы
+	CancellationPolicyAdmin = "admin"
+)
+
+// OrderCancellation условия, на которых отменен заказ
+type OrderCancellation struct {
+	Policy string           `json:"policy"`
+	Rule   CancellationRule `json:"rule"`
+	// Fee плата за отмену в базовой валюте; 0 — отмена бесплатная
+	Fee float64 `json:"fee"`
 }
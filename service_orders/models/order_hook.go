@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HookVerdict решение проверки заказа перед сохранением
+type HookVerdict string
+
+const (
+	// HookVerdictAllow проверка пройдена
+	HookVerdictAllow HookVerdict = "allow"
+	// HookVerdictFlag заказ создается, но помечается для ручной проверки
+	HookVerdictFlag HookVerdict = "flag"
+	// HookVerdictVeto заказ отклоняется и не сохраняется
+	HookVerdictVeto HookVerdict = "veto"
+	// HookVerdictError проверка завершилась ошибкой или не уложилась во время;
+	// на заказ влияет как flag или veto по ORDER_HOOKS_ON_ERROR
+	HookVerdictError HookVerdict = "error"
+)
+
+// IsValid проверяет, что решение входит в список известных
+func (v HookVerdict) IsValid() bool {
+	switch v {
+	case HookVerdictAllow, HookVerdictFlag, HookVerdictVeto, HookVerdictError:
+		return true
+	}
+	return false
+}
+
+// OrderHookDecision решение одной проверки по заказу. Решения сохраняются и для
+// отклоненных заказов, которых нет в таблице orders
+type OrderHookDecision struct {
+	ID         int64       `json:"id"`
+	OrderID    uuid.UUID   `json:"order_id"`
+	UserID     uuid.UUID   `json:"user_id"`
+	Hook       string      `json:"hook"`
+	Verdict    HookVerdict `json:"verdict"`
+	Reason     string      `json:"reason,omitempty"`
+	DurationMs int64       `json:"duration_ms"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Форматы выгрузки сборочного листа (параметр format)
+const (
+	PickingListFormatJSON = "json"
+	PickingListFormatCSV  = "csv"
+	PickingListFormatPDF  = "pdf"
+)
+
+// PickingList сводный сборочный лист склада: количество каждого товара по всем
+// выбранным заказам со ссылками на заказы
+type PickingList struct {
+	Status     OrderStatus `json:"status"`
+	StatusName string      `json:"status_name,omitempty"`
+	// Region регион заказов; пусто — заказы всех регионов
+	Region      string            `json:"region,omitempty"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	OrdersCount int               `json:"orders_count"`
+	Items       []PickingListItem `json:"items"`
+}
+
+// PickingListItem товар сборочного листа
+type PickingListItem struct {
+	Product       string `json:"product"`
+	TotalQuantity int    `json:"total_quantity"`
+	// Orders заказы с этим товаром в порядке создания
+	Orders []PickingListOrder `json:"orders"`
+}
+
+// PickingListOrder количество товара в одном заказе
+type PickingListOrder struct {
+	OrderID  uuid.UUID `json:"order_id"`
+	Quantity int       `json:"quantity"`
+}
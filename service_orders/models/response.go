@@ -11,6 +11,10 @@ type APIResponse struct {
 type APIError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	// Reason и Field уточняют ошибку разбора тела запроса: причина (unknown_field,
+	// type_mismatch, ...) и поле JSON, к которому она относится
+	Reason string `json:"reason,omitempty"`
+	Field  string `json:"field,omitempty"`
 }
 
 // NewSuccessResponse создает успешный ответ
@@ -40,4 +44,12 @@ const (
 	ErrorCodeForbidden      = "FORBIDDEN"
 	ErrorCodeConflict       = "CONFLICT"
 	ErrorCodeInternalServer = "INTERNAL_SERVER_ERROR"
+	// ErrorCodeMethodNotAllowed маршрут не поддерживает метод запроса
+	ErrorCodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	// ErrorCodeCancellationForbidden отмена заказа в текущем состоянии запрещена правилами отмены
+	ErrorCodeCancellationForbidden = "CANCELLATION_FORBIDDEN"
+	// ErrorCodeExportInProgress у пользователя уже формируется другая выгрузка
+	ErrorCodeExportInProgress = "EXPORT_IN_PROGRESS"
+	// ErrorCodeOrderRejected заказ отклонен проверкой перед созданием
+	ErrorCodeOrderRejected = "ORDER_REJECTED"
 )
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// StockLevel представляет доступный остаток товара на складе
+type StockLevel struct {
+	Product   string `json:"product"`
+	Available int    `json:"available"`
+	Warehouse string `json:"warehouse"`
+	// ChangedAt время изменения остатка в складской системе; более старые изменения игнорируются
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// StockWebhookRequest представляет изменения остатков от складской системы (не более 500 за раз)
+type StockWebhookRequest struct {
+	Warehouse string        `json:"warehouse" validate:"required,max=100"`
+	Items     []StockChange `json:"items" validate:"required,min=1,max=500,dive"`
+}
+
+// StockChange представляет новый остаток одного товара
+type StockChange struct {
+	Product   string    `json:"product" validate:"required,max=255"`
+	Available int       `json:"available" validate:"min=0"`
+	ChangedAt time.Time `json:"changed_at" validate:"required"`
+}
+
+// StockWebhookResponse представляет результат обработки изменений остатков
+type StockWebhookResponse struct {
+	Applied int `json:"applied"`
+	// Skipped изменения, устаревшие относительно уже сохраненных
+	Skipped int `json:"skipped"`
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxOrderTags максимальное число тегов заказа и тегов в фильтре
+	MaxOrderTags = 20
+	// MaxTagLength максимальная длина тега в символах
+	MaxTagLength = 32
+)
+
+// NormalizeTags приводит теги к нижнему регистру, удаляет пробелы по краям, повторы
+// и пустые значения и сортирует их. Тег — буквы, цифры, '-' и '_', начинается с буквы
+// или цифры (например fragile, problem-customer, хрупкое)
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if err := validateTag(tag); err != nil {
+			return nil, err
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxOrderTags {
+		return nil, fmt.Errorf("допускается не более %d тегов", MaxOrderTags)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// ParseTags разбирает список тегов через запятую из параметра запроса
+func ParseTags(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	return NormalizeTags(strings.Split(value, ","))
+}
+
+// validateTag проверяет длину и символы нормализованного тега
+func validateTag(tag string) error {
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return fmt.Errorf("тег '%s' длиннее %d символов", tag, MaxTagLength)
+	}
+	for i, r := range tag {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			continue
+		}
+		if i > 0 && (r == '-' || r == '_') {
+			continue
+		}
+		return fmt.Errorf("тег '%s' может содержать только буквы, цифры, '-' и '_' и должен начинаться с буквы или цифры", tag)
+	}
+	return nil
+}
+
+// UpdateOrderTagsRequest запрос на замену тегов заказа; пустой список удаляет все теги
+type UpdateOrderTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// TagStatsRequest параметры сводки по тегам
+type TagStatsRequest struct {
+	// Tags теги сводки; пусто — все теги
+	Tags   []string
+	Status OrderStatus
+	// From, To период создания заказов [From, To); nil — без ограничения
+	From *time.Time
+	To   *time.Time
+}
+
+// TagStats сводка по заказам с тегом
+type TagStats struct {
+	Tag      string  `json:"tag"`
+	Orders   int     `json:"orders"`
+	TotalSum float64 `json:"total_sum"`
+	// ByStatus число заказов с тегом по статусам
+	ByStatus map[OrderStatus]int `json:"by_status"`
+}
+
+// TagStatsResponse ответ сводки по тегам, теги по убыванию числа заказов
+type TagStatsResponse struct {
+	Status OrderStatus `json:"status,omitempty"`
+	From   *time.Time  `json:"from,omitempty"`
+	To     *time.Time  `json:"to,omitempty"`
+	Tags   []TagStats  `json:"tags"`
+}
+
+// OrderFilter параметры административного списка заказов, сохраняемые в наборе фильтров.
+// Поля соответствуют параметрам GET /v1/orders/all
+type OrderFilter struct {
+	Status OrderStatus `json:"status,omitempty" validate:"omitempty,oneof=created in_work completed cancelled flagged"`
+	// Tags заказ должен иметь все перечисленные теги
+	Tags   []string   `json:"tags,omitempty"`
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+	Sort   string     `json:"sort,omitempty" validate:"omitempty,oneof=created_at updated_at total_sum"`
+	Order  string     `json:"order,omitempty" validate:"omitempty,oneof=asc desc"`
+}
+
+// OrderFilterPreset именованный набор фильтров списка заказов администратора
+type OrderFilterPreset struct {
+	ID        uuid.UUID   `json:"id" db:"id"`
+	UserID    uuid.UUID   `json:"user_id" db:"user_id"`
+	Name      string      `json:"name" db:"name"`
+	Filter    OrderFilter `json:"filter" db:"filter"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// SaveOrderFilterPresetRequest запрос на создание или изменение набора фильтров
+type SaveOrderFilterPresetRequest struct {
+	Name   string      `json:"name" validate:"required,min=1,max=100"`
+	Filter OrderFilter `json:"filter"`
+}
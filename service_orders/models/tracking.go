@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// TrackingTokenResponse ссылка отслеживания заказа для получателя без учетной записи
+type TrackingTokenResponse struct {
+	Token string `json:"token"`
+	// Path путь на API Gateway, по которому доступен статус заказа
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// TrackingResponse статус заказа по ссылке отслеживания. Содержит только то, что нужно
+// получателю: без владельца, покупателя, состава и стоимости заказа
+type TrackingResponse struct {
+	Status     OrderStatus `json:"status"`
+	StatusName string      `json:"status_name,omitempty"`
+	ItemsCount int         `json:"items_count"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
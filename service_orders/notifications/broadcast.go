@@ -0,0 +1,397 @@
+package notifications
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// BroadcastStatus статус рассылки объявления
+type BroadcastStatus string
+
+const (
+	BroadcastStatusPending   BroadcastStatus = "pending"
+	BroadcastStatusRunning   BroadcastStatus = "running"
+	BroadcastStatusCompleted BroadcastStatus = "completed"
+	BroadcastStatusCancelled BroadcastStatus = "cancelled"
+)
+
+// RecipientStatus статус доставки объявления одному получателю
+type RecipientStatus string
+
+const (
+	RecipientStatusPending RecipientStatus = "pending"
+	RecipientStatusSent    RecipientStatus = "sent"
+	// RecipientStatusSkipped у получателя отключены все каналы (или маркетинговые уведомления)
+	RecipientStatusSkipped   RecipientStatus = "skipped"
+	RecipientStatusFailed    RecipientStatus = "failed"
+	RecipientStatusCancelled RecipientStatus = "cancelled"
+)
+
+// ErrBroadcastNotFound рассылка не найдена
+var ErrBroadcastNotFound = errors.New("рассылка не найдена")
+
+// Segment условия выбора получателей рассылки; пустые поля не ограничивают выборку.
+// Удаленные и деактивированные пользователи не получают рассылок
+type Segment struct {
+	// Roles пользователь должен иметь хотя бы одну из ролей
+	Roles []string `json:"roles,omitempty"`
+	// ActiveWithinDays пользователь входил в систему за последние дни
+	ActiveWithinDays int `json:"active_within_days,omitempty"`
+	// Region у пользователя есть заказ с доставкой в регион
+	Region string `json:"region,omitempty"`
+}
+
+// Broadcast рассылка объявления сегменту пользователей
+type Broadcast struct {
+	ID      uuid.UUID `json:"id"`
+	Message string    `json:"message"`
+	Segment Segment   `json:"segment"`
+	// Marketing объявление отправляется только пользователям, подписанным на маркетинговые уведомления
+	Marketing bool            `json:"marketing"`
+	Status    BroadcastStatus `json:"status"`
+	// Total число получателей, выбранных при создании
+	Total int `json:"total"`
+	// Counts число получателей по статусам доставки
+	Counts map[RecipientStatus]int `json:"counts"`
+	// CreatedBy администратор, создавший рассылку; пусто, если он удален
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+}
+
+// BroadcastRecipient получатель рассылки и статус доставки ему
+type BroadcastRecipient struct {
+	UserID    uuid.UUID       `json:"user_id"`
+	Status    RecipientStatus `json:"status"`
+	Error     string          `json:"error,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// BroadcastRepository интерфейс для работы с рассылками объявлений
+type BroadcastRepository interface {
+	// Create сохраняет рассылку и список получателей сегмента; заполняет Total
+	Create(broadcast *Broadcast) error
+	// GetByID возвращает рассылку со счетчиками получателей или ErrBroadcastNotFound
+	GetByID(id uuid.UUID) (*Broadcast, error)
+	// List возвращает последние рассылки со счетчиками, новые первыми
+	List(limit int) ([]Broadcast, error)
+	// Recipients возвращает получателей рассылки в статусе status (пустой — в любом)
+	Recipients(id uuid.UUID, status RecipientStatus, limit, offset int) ([]BroadcastRecipient, error)
+	// Cancel отменяет незавершенную рассылку: неотправленные получатели отменяются.
+	// cancelled == false, если рассылка уже завершена или отменена
+	Cancel(id uuid.UUID) (cancelled bool, err error)
+	// NextActive возвращает самую старую ожидающую или выполняемую рассылку или nil
+	NextActive() (*Broadcast, error)
+	// Start переводит ожидающую рассылку в выполнение
+	Start(id uuid.UUID) error
+	// PendingRecipients возвращает до limit получателей, которым объявление еще не отправлено
+	PendingRecipients(id uuid.UUID, limit int) ([]uuid.UUID, error)
+	// MarkRecipient сохраняет результат доставки получателю
+	MarkRecipient(id, userID uuid.UUID, status RecipientStatus, errText string) error
+	// Complete завершает выполняемую рассылку без ожидающих получателей
+	Complete(id uuid.UUID) error
+}
+
+// broadcastRepository реализация BroadcastRepository
+type broadcastRepository struct {
+	db *sql.DB
+}
+
+// NewBroadcastRepository создает новый экземпляр BroadcastRepository
+func NewBroadcastRepository(db *sql.DB) BroadcastRepository {
+	return &broadcastRepository{db: db}
+}
+
+// broadcastColumns столбцы рассылки со счетчиками получателей по статусам
+const broadcastColumns = `
+	b.id, b.message, b.segment, b.marketing, b.status, b.total, b.created_by, b.created_at,
+	b.started_at, b.completed_at, b.cancelled_at,
+	(SELECT COALESCE(json_object_agg(status, count), '{}')
+	 FROM (SELECT status, COUNT(*) AS count FROM broadcast_recipients
+	       WHERE broadcast_id = b.id GROUP BY status) counts)`
+
+// Create сохраняет рассылку и выбирает получателей в одной транзакции
+func (r *broadcastRepository) Create(broadcast *Broadcast) error {
+	segment, err := json.Marshal(broadcast.Segment)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации сегмента: %v", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO broadcasts (id, message, segment, marketing, status, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, broadcast.ID, broadcast.Message, segment, broadcast.Marketing, broadcast.Status,
+		broadcast.CreatedBy, broadcast.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения рассылки: %v", err)
+	}
+
+	conditions := []string{"u.deleted_at IS NULL", "u.deactivated_at IS NULL"}
+	args := []interface{}{broadcast.ID}
+	if len(broadcast.Segment.Roles) > 0 {
+		args = append(args, pq.Array(broadcast.Segment.Roles))
+		conditions = append(conditions, fmt.Sprintf("u.roles && $%d::text[]", len(args)))
+	}
+	if broadcast.Segment.ActiveWithinDays > 0 {
+		args = append(args, broadcast.Segment.ActiveWithinDays)
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM user_logins l WHERE l.user_id = u.id AND l.last_login_at > NOW() - make_interval(days => $%d))", len(args)))
+	}
+	if broadcast.Segment.Region != "" {
+		args = append(args, broadcast.Segment.Region)
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id AND o.region = $%d)", len(args)))
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO broadcast_recipients (broadcast_id, user_id)
+		SELECT $1, u.id FROM users u
+		WHERE `+strings.Join(conditions, " AND "), args...)
+	if err != nil {
+		return fmt.Errorf("ошибка выбора получателей рассылки: %v", err)
+	}
+	total, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества получателей: %v", err)
+	}
+
+	if _, err := tx.Exec("UPDATE broadcasts SET total = $2 WHERE id = $1", broadcast.ID, total); err != nil {
+		return fmt.Errorf("ошибка сохранения рассылки: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+
+	broadcast.Total = int(total)
+	broadcast.Counts = map[RecipientStatus]int{}
+	if total > 0 {
+		broadcast.Counts[RecipientStatusPending] = int(total)
+	}
+	return nil
+}
+
+// GetByID возвращает рассылку по ID
+func (r *broadcastRepository) GetByID(id uuid.UUID) (*Broadcast, error) {
+	row := r.db.QueryRow("SELECT "+broadcastColumns+" FROM broadcasts b WHERE b.id = $1", id)
+	broadcast, err := scanBroadcast(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrBroadcastNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения рассылки: %v", err)
+	}
+	return broadcast, nil
+}
+
+// List возвращает последние рассылки
+func (r *broadcastRepository) List(limit int) ([]Broadcast, error) {
+	rows, err := r.db.Query("SELECT "+broadcastColumns+" FROM broadcasts b ORDER BY b.created_at DESC LIMIT $1", limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка рассылок: %v", err)
+	}
+	defer rows.Close()
+
+	broadcasts := []Broadcast{}
+	for rows.Next() {
+		broadcast, err := scanBroadcast(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения рассылки: %v", err)
+		}
+		broadcasts = append(broadcasts, *broadcast)
+	}
+	return broadcasts, rows.Err()
+}
+
+// Recipients возвращает получателей рассылки
+func (r *broadcastRepository) Recipients(id uuid.UUID, status RecipientStatus, limit, offset int) ([]BroadcastRecipient, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id, status, error, updated_at
+		FROM broadcast_recipients
+		WHERE broadcast_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY updated_at DESC, user_id
+		LIMIT $3 OFFSET $4
+	`, id, string(status), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения получателей рассылки: %v", err)
+	}
+	defer rows.Close()
+
+	recipients := []BroadcastRecipient{}
+	for rows.Next() {
+		var recipient BroadcastRecipient
+		if err := rows.Scan(&recipient.UserID, &recipient.Status, &recipient.Error, &recipient.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка чтения получателя рассылки: %v", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
+}
+
+// Cancel отменяет рассылку и ее неотправленных получателей в одной транзакции
+func (r *broadcastRepository) Cancel(id uuid.UUID) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE broadcasts
+		SET status = 'cancelled', cancelled_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'running')
+	`, id)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отмены рассылки: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения количества обновленных строк: %v", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE broadcast_recipients
+		SET status = 'cancelled', updated_at = NOW()
+		WHERE broadcast_id = $1 AND status = 'pending'
+	`, id); err != nil {
+		return false, fmt.Errorf("ошибка отмены получателей рассылки: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return true, nil
+}
+
+// NextActive возвращает рассылку для отправки: рассылки выполняются по очереди
+func (r *broadcastRepository) NextActive() (*Broadcast, error) {
+	row := r.db.QueryRow("SELECT " + broadcastColumns + `
+		FROM broadcasts b
+		WHERE b.status IN ('pending', 'running')
+		ORDER BY b.created_at
+		LIMIT 1`)
+	broadcast, err := scanBroadcast(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения рассылки для отправки: %v", err)
+	}
+	return broadcast, nil
+}
+
+// Start переводит рассылку в выполнение
+func (r *broadcastRepository) Start(id uuid.UUID) error {
+	_, err := r.db.Exec(`
+		UPDATE broadcasts
+		SET status = 'running', started_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка запуска рассылки: %v", err)
+	}
+	return nil
+}
+
+// PendingRecipients возвращает очередную порцию получателей
+func (r *broadcastRepository) PendingRecipients(id uuid.UUID, limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(`
+		SELECT user_id FROM broadcast_recipients
+		WHERE broadcast_id = $1 AND status = 'pending'
+		ORDER BY user_id
+		LIMIT $2
+	`, id, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения получателей рассылки: %v", err)
+	}
+	defer rows.Close()
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var userID uuid.UUID
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("ошибка чтения получателя рассылки: %v", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// MarkRecipient сохраняет результат доставки, в том числе получателю, отмененному
+// во время отправки: объявление ему уже отправлено
+func (r *broadcastRepository) MarkRecipient(id, userID uuid.UUID, status RecipientStatus, errText string) error {
+	_, err := r.db.Exec(`
+		UPDATE broadcast_recipients
+		SET status = $3, error = $4, updated_at = NOW()
+		WHERE broadcast_id = $1 AND user_id = $2
+	`, id, userID, string(status), errText)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения статуса получателя рассылки: %v", err)
+	}
+	return nil
+}
+
+// Complete завершает рассылку, если все получатели обработаны
+func (r *broadcastRepository) Complete(id uuid.UUID) error {
+	_, err := r.db.Exec(`
+		UPDATE broadcasts
+		SET status = 'completed', completed_at = NOW()
+		WHERE id = $1 AND status = 'running'
+		  AND NOT EXISTS (SELECT 1 FROM broadcast_recipients WHERE broadcast_id = $1 AND status = 'pending')
+	`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка завершения рассылки: %v", err)
+	}
+	return nil
+}
+
+// scanBroadcast читает строку рассылки со счетчиками
+func scanBroadcast(row interface{ Scan(...interface{}) error }) (*Broadcast, error) {
+	var broadcast Broadcast
+	var segment, counts []byte
+	var createdBy uuid.NullUUID
+	var startedAt, completedAt, cancelledAt sql.NullTime
+	err := row.Scan(&broadcast.ID, &broadcast.Message, &segment, &broadcast.Marketing, &broadcast.Status,
+		&broadcast.Total, &createdBy, &broadcast.CreatedAt, &startedAt, &completedAt, &cancelledAt, &counts)
+	if err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		broadcast.CreatedBy = &createdBy.UUID
+	}
+	if err := json.Unmarshal(segment, &broadcast.Segment); err != nil {
+		return nil, fmt.Errorf("ошибка чтения сегмента рассылки: %v", err)
+	}
+	if err := json.Unmarshal(counts, &broadcast.Counts); err != nil {
+		return nil, fmt.Errorf("ошибка чтения счетчиков рассылки: %v", err)
+	}
+	broadcast.StartedAt = nullTime(startedAt)
+	broadcast.CompletedAt = nullTime(completedAt)
+	broadcast.CancelledAt = nullTime(cancelledAt)
+	return &broadcast, nil
+}
+
+// nullTime возвращает время или nil для NULL
+func nullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
@@ -0,0 +1,100 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Broadcaster отправляет рассылки объявлений по очереди порциями с ограничением
+// скорости. Отмененная рассылка останавливается после текущей порции
+type Broadcaster struct {
+	repo     BroadcastRepository
+	notifier *Notifier
+	// batchSize число получателей, выбираемых за один запрос
+	batchSize int
+	// interval минимальный интервал между отправками получателям
+	interval time.Duration
+	// poll период проверки новых рассылок
+	poll time.Duration
+}
+
+// NewBroadcaster создает отправитель рассылок со скоростью rate получателей в секунду
+func NewBroadcaster(repo BroadcastRepository, notifier *Notifier, batchSize int, rate float64, poll time.Duration) *Broadcaster {
+	return &Broadcaster{
+		repo:      repo,
+		notifier:  notifier,
+		batchSize: batchSize,
+		interval:  time.Duration(float64(time.Second) / rate),
+		poll:      poll,
+	}
+}
+
+// Run отправляет рассылки, пока не будет отменен ctx
+func (b *Broadcaster) Run(ctx context.Context) {
+	limiter := time.NewTicker(b.interval)
+	defer limiter.Stop()
+
+	for ctx.Err() == nil {
+		busy, err := b.sendBatch(ctx, limiter.C)
+		if err != nil {
+			log.Printf("Ошибка отправки рассылки: %v", err)
+		}
+		if busy && err == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(b.poll):
+		}
+	}
+}
+
+// sendBatch отправляет очередную порцию самой старой активной рассылки.
+// busy == false, если активных рассылок нет
+func (b *Broadcaster) sendBatch(ctx context.Context, limiter <-chan time.Time) (busy bool, err error) {
+	broadcast, err := b.repo.NextActive()
+	if err != nil || broadcast == nil {
+		return false, err
+	}
+	if broadcast.Status == BroadcastStatusPending {
+		if err := b.repo.Start(broadcast.ID); err != nil {
+			return true, err
+		}
+		log.Printf("Рассылка %s запущена: получателей %d", broadcast.ID, broadcast.Total)
+	}
+
+	userIDs, err := b.repo.PendingRecipients(broadcast.ID, b.batchSize)
+	if err != nil {
+		return true, err
+	}
+	if len(userIDs) == 0 {
+		if err := b.repo.Complete(broadcast.ID); err != nil {
+			return true, err
+		}
+		log.Printf("Рассылка %s завершена", broadcast.ID)
+		return true, nil
+	}
+
+	for _, userID := range userIDs {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case <-limiter:
+		}
+
+		status, errText := RecipientStatusSent, ""
+		sent, err := b.notifier.Announce(ctx, userID, broadcast.Message, broadcast.Marketing)
+		switch {
+		case err != nil:
+			status, errText = RecipientStatusFailed, err.Error()
+		case !sent:
+			status = RecipientStatusSkipped
+		}
+		if err := b.repo.MarkRecipient(broadcast.ID, userID, status, errText); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
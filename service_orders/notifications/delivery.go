@@ -0,0 +1,284 @@
+package notifications
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// DeliveryKind тип исходящей доставки
+type DeliveryKind string
+
+const (
+	DeliveryKindNotification DeliveryKind = "notification"
+	DeliveryKindWebhook      DeliveryKind = "webhook"
+)
+
+// DeliveryStatus статус исходящей доставки
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending    DeliveryStatus = "pending"
+	DeliveryStatusProcessing DeliveryStatus = "processing"
+	DeliveryStatusFailed     DeliveryStatus = "failed"
+	DeliveryStatusDelivered  DeliveryStatus = "delivered"
+	DeliveryStatusDiscarded  DeliveryStatus = "discarded"
+)
+
+// processingTimeout время, после которого зависшая в processing доставка
+// (например, при падении сервиса во время отправки) снова забирается в работу
+const processingTimeout = 5 * time.Minute
+
+// Delivery исходящая доставка уведомления или webhook, сохраненная для повторной отправки
+type Delivery struct {
+	ID        uuid.UUID      `json:"id"`
+	Kind      DeliveryKind   `json:"kind"`
+	Channel   string         `json:"channel"`
+	UserID    *uuid.UUID     `json:"user_id,omitempty"`
+	Target    string         `json:"target,omitempty"`
+	Payload   string         `json:"payload"`
+	Status    DeliveryStatus `json:"status"`
+	Attempts  int            `json:"attempts"`
+	LastError string         `json:"last_error,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// DeliveryFilter фильтры списка доставок; пустые поля не ограничивают выборку
+type DeliveryFilter struct {
+	Status  DeliveryStatus
+	Kind    DeliveryKind
+	Channel string
+	UserID  *uuid.UUID
+	Limit   int
+	Offset  int
+}
+
+// ListDeliveriesResponse ответ со списком доставок
+type ListDeliveriesResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+	Total      int        `json:"total"`
+	Limit      int        `json:"limit"`
+	Offset     int        `json:"offset"`
+}
+
+// DeliveryRepository интерфейс для работы с исходящими доставками
+type DeliveryRepository interface {
+	Create(delivery *Delivery) error
+	List(filter DeliveryFilter) (*ListDeliveriesResponse, error)
+	Requeue(ids []uuid.UUID) (int64, error)
+	Discard(ids []uuid.UUID) (int64, error)
+	ClaimPending(limit int) ([]Delivery, error)
+	MarkDelivered(id uuid.UUID) error
+	MarkFailed(id uuid.UUID, lastError string) error
+}
+
+// deliveryRepository реализация DeliveryRepository
+type deliveryRepository struct {
+	db *sql.DB
+}
+
+// NewDeliveryRepository создает новый экземпляр DeliveryRepository
+func NewDeliveryRepository(db *sql.DB) DeliveryRepository {
+	return &deliveryRepository{db: db}
+}
+
+const deliveryColumns = `id, kind, channel, user_id, target, payload, status, attempts, last_error, created_at, updated_at`
+
+// Create сохраняет доставку
+func (r *deliveryRepository) Create(delivery *Delivery) error {
+	query := `
+		INSERT INTO deliveries (id, kind, channel, user_id, target, payload, status, attempts, last_error, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.Exec(query,
+		delivery.ID, delivery.Kind, delivery.Channel, delivery.UserID, delivery.Target, delivery.Payload,
+		delivery.Status, delivery.Attempts, delivery.LastError, delivery.CreatedAt, delivery.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения доставки: %v", err)
+	}
+	return nil
+}
+
+// List возвращает доставки по фильтрам, новые первыми
+func (r *deliveryRepository) List(filter DeliveryFilter) (*ListDeliveriesResponse, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.Kind != "" {
+		args = append(args, filter.Kind)
+		conditions = append(conditions, fmt.Sprintf("kind = $%d", len(args)))
+	}
+	if filter.Channel != "" {
+		args = append(args, filter.Channel)
+		conditions = append(conditions, fmt.Sprintf("channel = $%d", len(args)))
+	}
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM deliveries "+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("ошибка подсчета доставок: %v", err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM deliveries %s ORDER BY created_at DESC LIMIT $%d OFFSET $%d",
+		deliveryColumns, where, len(args)+1, len(args)+2)
+
+	rows, err := r.db.Query(query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка доставок: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListDeliveriesResponse{
+		Deliveries: deliveries,
+		Total:      total,
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+	}, nil
+}
+
+// Requeue возвращает неудачные доставки в очередь на повторную отправку
+func (r *deliveryRepository) Requeue(ids []uuid.UUID) (int64, error) {
+	query := `
+		UPDATE deliveries
+		SET status = 'pending', updated_at = NOW()
+		WHERE id = ANY($1::uuid[]) AND status = 'failed'
+	`
+	return r.updateMany(query, ids, "ошибка повторной постановки доставок в очередь")
+}
+
+// Discard окончательно отменяет неудачные или ожидающие доставки
+func (r *deliveryRepository) Discard(ids []uuid.UUID) (int64, error) {
+	query := `
+		UPDATE deliveries
+		SET status = 'discarded', updated_at = NOW()
+		WHERE id = ANY($1::uuid[]) AND status IN ('failed', 'pending')
+	`
+	return r.updateMany(query, ids, "ошибка отмены доставок")
+}
+
+// updateMany выполняет массовое обновление по списку ID и возвращает число затронутых строк
+func (r *deliveryRepository) updateMany(query string, ids []uuid.UUID, errMessage string) (int64, error) {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+
+	result, err := r.db.Exec(query, pq.Array(values))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", errMessage, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка получения количества обновленных строк: %v", err)
+	}
+	return affected, nil
+}
+
+// ClaimPending атомарно забирает в работу до limit ожидающих доставок.
+// SKIP LOCKED позволяет нескольким экземплярам сервиса не отправлять одну доставку дважды
+func (r *deliveryRepository) ClaimPending(limit int) ([]Delivery, error) {
+	query := fmt.Sprintf(`
+		UPDATE deliveries
+		SET status = 'processing', attempts = attempts + 1, updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM deliveries
+			WHERE status = 'pending'
+			   OR (status = 'processing' AND updated_at < NOW() - INTERVAL '%d seconds')
+			ORDER BY created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %s
+	`, int(processingTimeout.Seconds()), deliveryColumns)
+
+	rows, err := r.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения доставок для отправки: %v", err)
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+// MarkDelivered отмечает доставку успешной
+func (r *deliveryRepository) MarkDelivered(id uuid.UUID) error {
+	query := `
+		UPDATE deliveries
+		SET status = 'delivered', last_error = '', updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id); err != nil {
+		return fmt.Errorf("ошибка обновления доставки: %v", err)
+	}
+	return nil
+}
+
+// MarkFailed отмечает доставку неудачной с текстом ошибки
+func (r *deliveryRepository) MarkFailed(id uuid.UUID, lastError string) error {
+	query := `
+		UPDATE deliveries
+		SET status = 'failed', last_error = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id, lastError); err != nil {
+		return fmt.Errorf("ошибка обновления доставки: %v", err)
+	}
+	return nil
+}
+
+// scanDeliveries читает доставки из результата запроса
+func scanDeliveries(rows *sql.Rows) ([]Delivery, error) {
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		err := rows.Scan(
+			&d.ID,
+			&d.Kind,
+			&d.Channel,
+			&d.UserID,
+			&d.Target,
+			&d.Payload,
+			&d.Status,
+			&d.Attempts,
+			&d.LastError,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования доставки: %v", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return deliveries, nil
+}
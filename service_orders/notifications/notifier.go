@@ -0,0 +1,207 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"pkg/ids"
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+)
+
+// Sender отправляет уведомление по конкретному каналу
+type Sender interface {
+	Send(ctx context.Context, userID uuid.UUID, message string) error
+}
+
+// LogSender заглушка отправителя, пишущая уведомления в лог
+// В будущем будет заменена на интеграции с email/SMS/Telegram провайдерами
+type LogSender struct {
+	Channel Channel
+}
+
+// Send логирует уведомление
+func (s LogSender) Send(ctx context.Context, userID uuid.UUID, message string) error {
+	log.Printf("📧 УВЕДОМЛЕНИЕ [%s]: Пользователю %s: %s", s.Channel, userID, message)
+	return nil
+}
+
+// Notifier сервис уведомлений: перед любой отправкой проверяет настройки пользователя.
+// Неудачные отправки сохраняются в deliveries для повторной отправки или отмены администратором
+type Notifier struct {
+	prefs      PreferencesRepository
+	deliveries DeliveryRepository
+	senders    map[Channel]Sender
+}
+
+// NewNotifier создает новый сервис уведомлений; deliveries может быть nil,
+// тогда неудачные отправки только логируются
+func NewNotifier(prefs PreferencesRepository, deliveries DeliveryRepository, senders map[Channel]Sender) *Notifier {
+	return &Notifier{
+		prefs:      prefs,
+		deliveries: deliveries,
+		senders:    senders,
+	}
+}
+
+// NewLogNotifier создает сервис уведомлений с отправителями-заглушками для всех каналов
+func NewLogNotifier(prefs PreferencesRepository, deliveries DeliveryRepository) *Notifier {
+	return NewNotifier(prefs, deliveries, map[Channel]Sender{
+		ChannelEmail:    LogSender{Channel: ChannelEmail},
+		ChannelSMS:      LogSender{Channel: ChannelSMS},
+		ChannelTelegram: LogSender{Channel: ChannelTelegram},
+	})
+}
+
+// Notify отправляет уведомление по всем каналам, разрешенным пользователем для данной категории
+func (n *Notifier) Notify(ctx context.Context, userID uuid.UUID, kind EventKind, message string) error {
+	prefs, err := n.prefs.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("невозможно проверить настройки уведомлений: %v", err)
+	}
+
+	channels := prefs.EnabledChannels(kind)
+	if len(channels) == 0 {
+		log.Printf("Уведомление %s для пользователя %s пропущено: отключено в настройках", kind, userID)
+		return nil
+	}
+	return n.send(ctx, userID, channels, message)
+}
+
+// NotifyOperational отправляет служебное уведомление (например, об аномалии метрик)
+// получателю, назначенному в конфигурации. Категории уведомлений не проверяются,
+// используются каналы, включенные пользователем
+func (n *Notifier) NotifyOperational(ctx context.Context, userID uuid.UUID, message string) error {
+	prefs, err := n.prefs.GetByUserID(userID)
+	if err != nil {
+		return fmt.Errorf("невозможно проверить настройки уведомлений: %v", err)
+	}
+
+	channels := prefs.ActiveChannels()
+	if len(channels) == 0 {
+		log.Printf("Служебное уведомление для пользователя %s пропущено: все каналы отключены", userID)
+		return nil
+	}
+	return n.send(ctx, userID, channels, message)
+}
+
+// Announce отправляет объявление рассылки по каналам, включенным пользователем.
+// Маркетинговое объявление (marketing) отправляется только подписанным на маркетинговые
+// уведомления. sent == false, если подходящих каналов нет
+func (n *Notifier) Announce(ctx context.Context, userID uuid.UUID, message string, marketing bool) (sent bool, err error) {
+	prefs, err := n.prefs.GetByUserID(userID)
+	if err != nil {
+		return false, fmt.Errorf("невозможно проверить настройки уведомлений: %v", err)
+	}
+
+	channels := prefs.ActiveChannels()
+	if marketing {
+		channels = prefs.EnabledChannels(EventMarketing)
+	}
+	if len(channels) == 0 {
+		return false, nil
+	}
+	return true, n.send(ctx, userID, channels, message)
+}
+
+// send отправляет уведомление по каналам channels; неудачные отправки сохраняются для повторной доставки
+func (n *Notifier) send(ctx context.Context, userID uuid.UUID, channels []Channel, message string) error {
+	var lastErr error
+	for _, ch := range channels {
+		sender, ok := n.senders[ch]
+		if !ok {
+			continue
+		}
+		if err := sender.Send(ctx, userID, message); err != nil {
+			lastErr = fmt.Errorf("ошибка отправки уведомления через %s: %v", ch, err)
+			n.recordFailure(userID, ch, message, err)
+		}
+	}
+	return lastErr
+}
+
+// recordFailure сохраняет неудачную отправку для повторной доставки
+func (n *Notifier) recordFailure(userID uuid.UUID, ch Channel, message string, sendErr error) {
+	if n.deliveries == nil {
+		return
+	}
+
+	now := timeutil.Now()
+	delivery := &Delivery{
+		ID:        ids.New(),
+		Kind:      DeliveryKindNotification,
+		Channel:   string(ch),
+		UserID:    &userID,
+		Payload:   message,
+		Status:    DeliveryStatusFailed,
+		Attempts:  1,
+		LastError: sendErr.Error(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := n.deliveries.Create(delivery); err != nil {
+		log.Printf("Не удалось сохранить неудачную доставку для пользователя %s: %v", userID, err)
+	}
+}
+
+// RunRedelivery периодически отправляет доставки, возвращенные в очередь, пока не будет отменен ctx
+func (n *Notifier) RunRedelivery(ctx context.Context, interval time.Duration, batchSize int) {
+	if n.deliveries == nil || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := n.Redeliver(ctx, batchSize); err != nil {
+				log.Printf("Ошибка повторной отправки доставок: %v", err)
+			}
+		}
+	}
+}
+
+// Redeliver отправляет очередную порцию ожидающих доставок
+func (n *Notifier) Redeliver(ctx context.Context, batchSize int) error {
+	deliveries, err := n.deliveries.ClaimPending(batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		if err := n.redeliver(ctx, delivery); err != nil {
+			if markErr := n.deliveries.MarkFailed(delivery.ID, err.Error()); markErr != nil {
+				return markErr
+			}
+			continue
+		}
+		if err := n.deliveries.MarkDelivered(delivery.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redeliver отправляет одну доставку через отправитель ее канала.
+// Настройки пользователя повторно не проверяются: доставка уже была разрешена
+func (n *Notifier) redeliver(ctx context.Context, delivery Delivery) error {
+	if delivery.Kind != DeliveryKindNotification {
+		return fmt.Errorf("отправитель для доставок типа %s не настроен", delivery.Kind)
+	}
+	if delivery.UserID == nil {
+		return fmt.Errorf("у доставки уведомления не указан пользователь")
+	}
+
+	sender, ok := n.senders[Channel(delivery.Channel)]
+	if !ok {
+		return fmt.Errorf("отправитель для канала %s не настроен", delivery.Channel)
+	}
+	return sender.Send(ctx, *delivery.UserID, delivery.Payload)
+}
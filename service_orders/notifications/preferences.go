@@ -0,0 +1,109 @@
+package notifications
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Channel канал доставки уведомлений
+type Channel string
+
+const (
+	ChannelEmail    Channel = "email"
+	ChannelSMS      Channel = "sms"
+	ChannelTelegram Channel = "telegram"
+)
+
+// EventKind категория уведомления, на которую пользователь может подписаться
+type EventKind string
+
+const (
+	EventOrderCreated       EventKind = "order_created"
+	EventOrderStatusChanged EventKind = "order_status_changed"
+	EventMarketing          EventKind = "marketing"
+)
+
+// Preferences настройки уведомлений пользователя (управляются service_users)
+type Preferences struct {
+	Channels map[Channel]bool
+	Events   map[EventKind]bool
+}
+
+// DefaultPreferences возвращает настройки по умолчанию, совпадающие с service_users
+func DefaultPreferences() *Preferences {
+	return &Preferences{
+		Channels: map[Channel]bool{ChannelEmail: true},
+		Events: map[EventKind]bool{
+			EventOrderCreated:       true,
+			EventOrderStatusChanged: true,
+		},
+	}
+}
+
+// EnabledChannels возвращает каналы, по которым разрешено отправить уведомление данной категории
+func (p *Preferences) EnabledChannels(kind EventKind) []Channel {
+	if !p.Events[kind] {
+		return nil
+	}
+	return p.ActiveChannels()
+}
+
+// ActiveChannels возвращает каналы, включенные пользователем, без учета категорий
+func (p *Preferences) ActiveChannels() []Channel {
+	var channels []Channel
+	for _, ch := range []Channel{ChannelEmail, ChannelSMS, ChannelTelegram} {
+		if p.Channels[ch] {
+			channels = append(channels, ch)
+		}
+	}
+	return channels
+}
+
+// PreferencesRepository интерфейс для чтения настроек уведомлений
+type PreferencesRepository interface {
+	GetByUserID(userID uuid.UUID) (*Preferences, error)
+}
+
+// preferencesRepository реализация PreferencesRepository
+type preferencesRepository struct {
+	db *sql.DB
+}
+
+// NewPreferencesRepository создает новый экземпляр PreferencesRepository
+func NewPreferencesRepository(db *sql.DB) PreferencesRepository {
+	return &preferencesRepository{db: db}
+}
+
+// GetByUserID получает настройки уведомлений пользователя
+func (r *preferencesRepository) GetByUserID(userID uuid.UUID) (*Preferences, error) {
+	query := `
+		SELECT email_enabled, sms_enabled, telegram_enabled,
+		       order_created, order_status_changed, marketing
+		FROM notification_preferences
+		WHERE user_id = $1
+	`
+
+	var email, sms, telegram, orderCreated, statusChanged, marketing bool
+	err := r.db.QueryRow(query, userID).Scan(&email, &sms, &telegram, &orderCreated, &statusChanged, &marketing)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return DefaultPreferences(), nil
+		}
+		return nil, fmt.Errorf("ошибка получения настроек уведомлений: %v", err)
+	}
+
+	return &Preferences{
+		Channels: map[Channel]bool{
+			ChannelEmail:    email,
+			ChannelSMS:      sms,
+			ChannelTelegram: telegram,
+		},
+		Events: map[EventKind]bool{
+			EventOrderCreated:       orderCreated,
+			EventOrderStatusChanged: statusChanged,
+			EventMarketing:          marketing,
+		},
+	}, nil
+}
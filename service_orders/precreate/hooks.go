@@ -0,0 +1,96 @@
+package precreate
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"service_orders/models"
+	"service_orders/repository"
+
+	"pkg/servertiming"
+)
+
+// TotalLimit проверка суммы заказа: выше flagAbove заказ помечается для ручной
+// проверки, выше maxTotal отклоняется. Нулевой порог не проверяется
+type TotalLimit struct {
+	flagAbove float64
+	maxTotal  float64
+}
+
+// NewTotalLimit создает проверку суммы заказа
+func NewTotalLimit(flagAbove, maxTotal float64) *TotalLimit {
+	return &TotalLimit{flagAbove: flagAbove, maxTotal: maxTotal}
+}
+
+// Name возвращает имя проверки
+func (h *TotalLimit) Name() string {
+	return "total_limit"
+}
+
+// Check сравнивает сумму заказа с порогами
+func (h *TotalLimit) Check(_ context.Context, order *models.Order) (Decision, error) {
+	if h.maxTotal > 0 && order.TotalSum > h.maxTotal {
+		return Veto(fmt.Sprintf("сумма %.2f превышает предел %.2f", order.TotalSum, h.maxTotal)), nil
+	}
+	if h.flagAbove > 0 && order.TotalSum > h.flagAbove {
+		return Flag(fmt.Sprintf("сумма %.2f превышает порог проверки %.2f", order.TotalSum, h.flagAbove)), nil
+	}
+	return Allow(), nil
+}
+
+// Blacklist проверка покупателя по черному списку, который ведет service_users: пользователь,
+// домен его email или IP адрес клиента. Запись block отклоняет заказ, flag — помечает для
+// проверки администратором
+type Blacklist struct {
+	repo repository.BlacklistRepository
+}
+
+// NewBlacklist создает проверку по черному списку покупателей
+func NewBlacklist(repo repository.BlacklistRepository) *Blacklist {
+	return &Blacklist{repo: repo}
+}
+
+// Name возвращает имя проверки
+func (h *Blacklist) Name() string {
+	return "blacklist"
+}
+
+// Check ищет покупателя и IP адрес клиента из ctx в черном списке
+func (h *Blacklist) Check(ctx context.Context, order *models.Order) (Decision, error) {
+	repo := repository.TimedBlacklistRepository(h.repo, servertiming.FromContext(ctx))
+	entry, err := repo.Match(order.UserID, ClientIPFromContext(ctx))
+	if err != nil {
+		return Decision{}, err
+	}
+	if entry == nil {
+		return Allow(), nil
+	}
+
+	reason := fmt.Sprintf("черный список: %s=%s", entry.Kind, entry.Value)
+	if entry.Reason != "" {
+		reason += " (" + entry.Reason + ")"
+	}
+	if entry.Action == models.BlacklistActionBlock {
+		return Veto(reason), nil
+	}
+	return Flag(reason), nil
+}
+
+type clientIPKey struct{}
+
+// WithClientIP возвращает контекст с IP адресом клиента, создающего заказ.
+// Некорректный адрес не сохраняется
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, clientIPKey{}, parsed.String())
+}
+
+// ClientIPFromContext возвращает IP адрес клиента из ctx или пустую строку
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
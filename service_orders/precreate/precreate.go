@@ -0,0 +1,208 @@
+// Package precreate выполняет цепочку проверок заказа перед сохранением (антифрод,
+// кредитные лимиты, черные списки). Проверка может пропустить заказ, пометить его для
+// ручной проверки или отклонить; решение каждой проверки сохраняется в журнал
+package precreate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"service_orders/models"
+
+	"pkg/timeutil"
+)
+
+// Hook проверка заказа перед сохранением
+type Hook interface {
+	// Name уникальное имя проверки в журнале решений
+	Name() string
+	// Check проверяет заказ с рассчитанной суммой. Ошибка означает, что проверку
+	// выполнить не удалось (например, недоступен внешний сервис), а не отказ
+	Check(ctx context.Context, order *models.Order) (Decision, error)
+}
+
+// Decision решение проверки
+type Decision struct {
+	Verdict models.HookVerdict
+	// Reason причина для журнала; покупателю не показывается
+	Reason string
+}
+
+// Allow решение «проверка пройдена»
+func Allow() Decision {
+	return Decision{Verdict: models.HookVerdictAllow}
+}
+
+// Flag решение «пометить заказ для ручной проверки»
+func Flag(reason string) Decision {
+	return Decision{Verdict: models.HookVerdictFlag, Reason: reason}
+}
+
+// Veto решение «отклонить заказ»
+func Veto(reason string) Decision {
+	return Decision{Verdict: models.HookVerdictVeto, Reason: reason}
+}
+
+// Chain цепочка проверок, выполняемых в порядке регистрации
+type Chain struct {
+	mu    sync.RWMutex
+	hooks []Hook
+	// timeout предельное время одной проверки
+	timeout time.Duration
+	// onError итоговое решение при ошибке проверки: flag или veto
+	onError models.HookVerdict
+}
+
+// NewChain создает пустую цепочку проверок. onError — flag или veto
+func NewChain(timeout time.Duration, onError string) *Chain {
+	verdict := models.HookVerdictFlag
+	if onError == string(models.HookVerdictVeto) {
+		verdict = models.HookVerdictVeto
+	}
+	return &Chain{timeout: timeout, onError: verdict}
+}
+
+// Register добавляет проверку в конец цепочки; имена проверок не повторяются
+func (c *Chain) Register(hook Hook) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := hook.Name()
+	if name == "" {
+		return fmt.Errorf("имя проверки не задано")
+	}
+	for _, registered := range c.hooks {
+		if registered.Name() == name {
+			return fmt.Errorf("проверка %s уже зарегистрирована", name)
+		}
+	}
+	c.hooks = append(c.hooks, hook)
+	return nil
+}
+
+// Names возвращает имена проверок в порядке выполнения
+func (c *Chain) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, 0, len(c.hooks))
+	for _, hook := range c.hooks {
+		names = append(names, hook.Name())
+	}
+	return names
+}
+
+// Result итог цепочки проверок
+type Result struct {
+	// Verdict итоговое решение: allow, flag или veto
+	Verdict models.HookVerdict
+	// Decisions решения выполненных проверок для журнала
+	Decisions []models.OrderHookDecision
+}
+
+// Vetoed сообщает, что заказ отклонен
+func (r Result) Vetoed() bool {
+	return r.Verdict == models.HookVerdictVeto
+}
+
+// Flagged сообщает, что заказ помечен для ручной проверки
+func (r Result) Flagged() bool {
+	return r.Verdict == models.HookVerdictFlag
+}
+
+// Summary перечисляет проверки с решением, отличным от allow, в виде hook=verdict
+func (r Result) Summary() string {
+	var parts []string
+	for _, decision := range r.Decisions {
+		if decision.Verdict != models.HookVerdictAllow {
+			parts = append(parts, decision.Hook+"="+string(decision.Verdict))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Run выполняет проверки заказа по очереди. Первое отклонение останавливает цепочку:
+// остальные проверки не выполняются. Ошибка или превышение времени проверки
+// записывается с решением error и влияет на итог как onError. Пустая или nil цепочка
+// пропускает заказ
+func (c *Chain) Run(ctx context.Context, order *models.Order) Result {
+	result := Result{Verdict: models.HookVerdictAllow}
+	if c == nil {
+		return result
+	}
+
+	c.mu.RLock()
+	hooks := append([]Hook(nil), c.hooks...)
+	c.mu.RUnlock()
+
+	for _, hook := range hooks {
+		started := timeutil.Now()
+		decision, err := c.check(ctx, hook, order)
+		effective := decision.Verdict
+		if err != nil {
+			decision = Decision{Verdict: models.HookVerdictError, Reason: err.Error()}
+			effective = c.onError
+		}
+
+		result.Decisions = append(result.Decisions, models.OrderHookDecision{
+			OrderID:    order.ID,
+			UserID:     order.UserID,
+			Hook:       hook.Name(),
+			Verdict:    decision.Verdict,
+			Reason:     decision.Reason,
+			DurationMs: timeutil.Now().Sub(started).Milliseconds(),
+			CreatedAt:  started,
+		})
+
+		switch effective {
+		case models.HookVerdictVeto:
+			result.Verdict = models.HookVerdictVeto
+			return result
+		case models.HookVerdictFlag:
+			result.Verdict = models.HookVerdictFlag
+		}
+	}
+	return result
+}
+
+// check выполняет одну проверку с ограничением времени. Паника проверки и решение
+// неизвестного вида считаются ошибкой
+func (c *Chain) check(ctx context.Context, hook Hook, order *models.Order) (decision Decision, err error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		decision Decision
+		err      error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- outcome{err: fmt.Errorf("паника проверки: %v", recovered)}
+			}
+		}()
+		decision, err := hook.Check(ctx, order)
+		done <- outcome{decision: decision, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		if out.err != nil {
+			return Decision{}, out.err
+		}
+		switch out.decision.Verdict {
+		case models.HookVerdictAllow, models.HookVerdictFlag, models.HookVerdictVeto:
+			return out.decision, nil
+		}
+		return Decision{}, fmt.Errorf("неизвестное решение проверки: %q", out.decision.Verdict)
+	case <-ctx.Done():
+		return Decision{}, fmt.Errorf("проверка не завершилась: %v", ctx.Err())
+	}
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// AdminAuditRepository интерфейс журнала действий администраторов. Таблица admin_audit_log
+// общая с service_users и попадает в отчет о пересмотре доступа
+type AdminAuditRepository interface {
+	// Record сохраняет действие пользователя actorID над объектом target
+	Record(actorID uuid.UUID, action, target, details string) error
+}
+
+// adminAuditRepository реализация AdminAuditRepository
+type adminAuditRepository struct {
+	db *sql.DB
+}
+
+// NewAdminAuditRepository создает новый экземпляр AdminAuditRepository
+func NewAdminAuditRepository(db *sql.DB) AdminAuditRepository {
+	return &adminAuditRepository{db: db}
+}
+
+// Record сохраняет действие в admin_audit_log
+func (r *adminAuditRepository) Record(actorID uuid.UUID, action, target, details string) error {
+	query := `
+		INSERT INTO admin_audit_log (actor_id, action, target, details)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	if _, err := r.db.Exec(query, actorID, action, target, details); err != nil {
+		return fmt.Errorf("ошибка сохранения действия администратора: %v", err)
+	}
+	return nil
+}
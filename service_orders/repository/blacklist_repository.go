@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"service_orders/models"
+
+	"github.com/google/uuid"
+)
+
+// BlacklistRepository интерфейс чтения черного списка покупателей. Записи добавляет
+// и удаляет service_users
+type BlacklistRepository interface {
+	// Match возвращает запись, совпавшую с пользователем, доменом его email или IP
+	// адресом клиента (пустой ip не проверяется), или nil. Записи block имеют приоритет
+	// перед flag
+	Match(userID uuid.UUID, ip string) (*models.BlacklistEntry, error)
+}
+
+// blacklistRepository реализация BlacklistRepository
+type blacklistRepository struct {
+	db *sql.DB
+}
+
+// NewBlacklistRepository создает новый экземпляр BlacklistRepository
+func NewBlacklistRepository(db *sql.DB) BlacklistRepository {
+	return &blacklistRepository{db: db}
+}
+
+// Match ищет запись черного списка; email пользователя берется из таблицы users
+func (r *blacklistRepository) Match(userID uuid.UUID, ip string) (*models.BlacklistEntry, error) {
+	row := r.db.QueryRow(`
+		WITH customer AS (
+			SELECT COALESCE((SELECT lower(split_part(email, '@', 2)) FROM users WHERE id = $1), '') AS domain
+		)
+		SELECT kind, value, action, reason
+		FROM blacklist_entries, customer
+		WHERE (kind = 'user' AND value = $1::text)
+		   OR (kind = 'email_domain' AND customer.domain <> '' AND (customer.domain = value
+		       OR customer.domain LIKE '%.' || value))
+		   OR (kind = 'ip' AND $2 <> '' AND $2::inet <<= value::inet)
+		ORDER BY action = 'block' DESC, created_at
+		LIMIT 1
+	`, userID, ip)
+
+	var entry models.BlacklistEntry
+	err := row.Scan(&entry.Kind, &entry.Value, &entry.Action, &entry.Reason)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки черного списка: %v", err)
+	}
+	return &entry, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"service_orders/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// CustomerRepository интерфейс для получения данных покупателей.
+// Данные читаются из таблицы users общей базы данных
+type CustomerRepository interface {
+	GetByIDs(ids []uuid.UUID) (map[uuid.UUID]models.Customer, error)
+}
+
+// customerRepository реализация CustomerRepository
+type customerRepository struct {
+	db *sql.DB
+}
+
+// NewCustomerRepository создает новый экземпляр CustomerRepository
+func NewCustomerRepository(db *sql.DB) CustomerRepository {
+	return &customerRepository{db: db}
+}
+
+// GetByIDs получает покупателей одним запросом по списку ID
+func (r *customerRepository) GetByIDs(ids []uuid.UUID) (map[uuid.UUID]models.Customer, error) {
+	customers := make(map[uuid.UUID]models.Customer, len(ids))
+	if len(ids) == 0 {
+		return customers, nil
+	}
+
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+
+	query := "SELECT id, email, name FROM users WHERE id = ANY($1::uuid[])"
+
+	rows, err := r.db.Query(query, pq.Array(values))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения покупателей: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var customer models.Customer
+		if err := rows.Scan(&customer.ID, &customer.Email, &customer.Name); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования покупателя: %v", err)
+		}
+		customers[customer.ID] = customer
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+
+	return customers, nil
+}
@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"service_orders/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrFilterPresetConflict возвращается, если у администратора уже есть набор фильтров с таким именем
+var ErrFilterPresetConflict = errors.New("набор фильтров с таким именем уже существует")
+
+// OrderFilterRepository интерфейс для работы с сохраненными наборами фильтров списка
+// заказов. Набор принадлежит администратору: все методы ограничены его userID
+type OrderFilterRepository interface {
+	List(userID uuid.UUID) ([]models.OrderFilterPreset, error)
+	// Get возвращает набор администратора; found == false, если набора нет
+	Get(id, userID uuid.UUID) (preset *models.OrderFilterPreset, found bool, err error)
+	Create(preset *models.OrderFilterPreset) error
+	// Update изменяет имя и фильтры набора; updated == false, если набора нет
+	Update(preset *models.OrderFilterPreset) (updated bool, err error)
+	// Delete удаляет набор; deleted == false, если набора нет
+	Delete(id, userID uuid.UUID) (deleted bool, err error)
+}
+
+// orderFilterRepository реализация OrderFilterRepository
+type orderFilterRepository struct {
+	db *sql.DB
+}
+
+// NewOrderFilterRepository создает новый экземпляр OrderFilterRepository
+func NewOrderFilterRepository(db *sql.DB) OrderFilterRepository {
+	return &orderFilterRepository{db: db}
+}
+
+// List возвращает наборы фильтров администратора по имени
+func (r *orderFilterRepository) List(userID uuid.UUID) ([]models.OrderFilterPreset, error) {
+	query := `
+		SELECT id, user_id, name, filter, created_at, updated_at
+		FROM order_filter_presets
+		WHERE user_id = $1
+		ORDER BY name
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения наборов фильтров: %v", err)
+	}
+	defer rows.Close()
+
+	presets := []models.OrderFilterPreset{}
+	for rows.Next() {
+		preset, err := scanFilterPreset(rows)
+		if err != nil {
+			return nil, err
+		}
+		presets = append(presets, *preset)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return presets, nil
+}
+
+// Get возвращает набор фильтров администратора по ID
+func (r *orderFilterRepository) Get(id, userID uuid.UUID) (*models.OrderFilterPreset, bool, error) {
+	query := `
+		SELECT id, user_id, name, filter, created_at, updated_at
+		FROM order_filter_presets
+		WHERE id = $1 AND user_id = $2
+	`
+
+	preset, err := scanFilterPreset(r.db.QueryRow(query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return preset, true, nil
+}
+
+// Create сохраняет новый набор фильтров
+func (r *orderFilterRepository) Create(preset *models.OrderFilterPreset) error {
+	filterJSON, err := json.Marshal(preset.Filter)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации фильтров: %v", err)
+	}
+
+	query := `
+		INSERT INTO order_filter_presets (id, user_id, name, filter, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = r.db.Exec(query, preset.ID, preset.UserID, preset.Name, filterJSON, preset.CreatedAt, preset.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrFilterPresetConflict
+		}
+		return fmt.Errorf("ошибка сохранения набора фильтров: %v", err)
+	}
+	return nil
+}
+
+// Update изменяет имя и фильтры набора администратора
+func (r *orderFilterRepository) Update(preset *models.OrderFilterPreset) (bool, error) {
+	filterJSON, err := json.Marshal(preset.Filter)
+	if err != nil {
+		return false, fmt.Errorf("ошибка сериализации фильтров: %v", err)
+	}
+
+	query := `
+		UPDATE order_filter_presets
+		SET name = $3, filter = $4, updated_at = $5
+		WHERE id = $1 AND user_id = $2
+		RETURNING created_at
+	`
+	err = r.db.QueryRow(query, preset.ID, preset.UserID, preset.Name, filterJSON, preset.UpdatedAt).Scan(&preset.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return false, ErrFilterPresetConflict
+		}
+		return false, fmt.Errorf("ошибка изменения набора фильтров: %v", err)
+	}
+	return true, nil
+}
+
+// Delete удаляет набор фильтров администратора
+func (r *orderFilterRepository) Delete(id, userID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec("DELETE FROM order_filter_presets WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка удаления набора фильтров: %v", err)
+	}
+	return affectedOne(result)
+}
+
+// rowScanner строка результата: *sql.Row или *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanFilterPreset читает набор фильтров из строки результата
+func scanFilterPreset(row rowScanner) (*models.OrderFilterPreset, error) {
+	preset := &models.OrderFilterPreset{}
+	var filterJSON []byte
+	err := row.Scan(&preset.ID, &preset.UserID, &preset.Name, &filterJSON, &preset.CreatedAt, &preset.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сканирования набора фильтров: %v", err)
+	}
+	if err := json.Unmarshal(filterJSON, &preset.Filter); err != nil {
+		return nil, fmt.Errorf("ошибка десериализации фильтров: %v", err)
+	}
+	return preset, nil
+}
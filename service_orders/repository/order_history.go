@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"service_orders/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrOrderStateUnknown возвращается, если история состояний заказа не охватывает
+// запрошенный момент: заказ еще не был создан или создан до появления истории
+var ErrOrderStateUnknown = errors.New("состояние заказа на этот момент неизвестно")
+
+// История состояний хранится в order_history и заполняется триггером record_order_history
+// при создании заказа и каждом изменении статуса, позиций, суммы или назначения
+
+// GetStateAt возвращает состояние заказа на момент asOf: статус, позиции, сумму и назначение
+// из последнего снимка истории не позже asOf. UpdatedAt заказа — время этого снимка.
+// Регион заказа не меняется и берется из orders
+func (r *orderRepository) GetStateAt(id uuid.UUID, asOf time.Time) (*models.Order, error) {
+	query := `
+		SELECT o.id, o.user_id, o.created_at, COALESCE(o.region, ''), h.items, h.status, h.total_sum, h.assigned_to, h.assigned_at, h.recorded_at
+		FROM order_history h
+		JOIN orders o ON o.id = h.order_id
+		WHERE h.order_id = $1 AND h.recorded_at <= $2
+		ORDER BY h.recorded_at DESC, h.id DESC
+		LIMIT 1
+	`
+
+	order := &models.Order{}
+	var itemsJSON []byte
+	var status string
+	var assignedTo uuid.NullUUID
+	var assignedAt sql.NullTime
+
+	err := r.db.QueryRow(query, id, asOf).Scan(
+		&order.ID,
+		&order.UserID,
+		&order.CreatedAt,
+		&order.Region,
+		&itemsJSON,
+		&status,
+		&order.TotalSum,
+		&assignedTo,
+		&assignedAt,
+		&order.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOrderStateUnknown
+		}
+		return nil, fmt.Errorf("ошибка получения истории заказа: %v", err)
+	}
+
+	if err := json.Unmarshal(itemsJSON, &order.Items); err != nil {
+		return nil, fmt.Errorf("ошибка десериализации items: %v", err)
+	}
+
+	order.Status = models.OrderStatus(status)
+	setAssignment(order, assignedTo, assignedAt)
+	order.AsOf = &asOf
+
+	return order, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"service_orders/models"
+
+	"github.com/google/uuid"
+)
+
+// OrderHookRepository интерфейс журнала решений проверок заказа перед сохранением.
+// order_id не ссылается на orders: решения отклоненных заказов тоже сохраняются
+type OrderHookRepository interface {
+	// Record сохраняет решения одной вставкой
+	Record(decisions []models.OrderHookDecision) error
+	// ListForOrder возвращает решения по заказу в порядке выполнения проверок
+	ListForOrder(orderID uuid.UUID) ([]models.OrderHookDecision, error)
+	// ListRecent возвращает последние решения вида verdict (пустой — любого), новые первыми
+	ListRecent(verdict models.HookVerdict, limit int) ([]models.OrderHookDecision, error)
+}
+
+// orderHookRepository реализация OrderHookRepository
+type orderHookRepository struct {
+	db *sql.DB
+}
+
+// NewOrderHookRepository создает новый экземпляр OrderHookRepository
+func NewOrderHookRepository(db *sql.DB) OrderHookRepository {
+	return &orderHookRepository{db: db}
+}
+
+// Record сохраняет решения проверок
+func (r *orderHookRepository) Record(decisions []models.OrderHookDecision) error {
+	if len(decisions) == 0 {
+		return nil
+	}
+
+	const columns = 7
+	placeholders := make([]string, 0, len(decisions))
+	args := make([]interface{}, 0, len(decisions)*columns)
+	for i, decision := range decisions {
+		base := i * columns
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7))
+		args = append(args, decision.OrderID, decision.UserID, decision.Hook, string(decision.Verdict),
+			decision.Reason, decision.DurationMs, decision.CreatedAt)
+	}
+
+	query := `
+		INSERT INTO order_hook_decisions (order_id, user_id, hook, verdict, reason, duration_ms, created_at)
+		VALUES ` + strings.Join(placeholders, ", ")
+	if _, err := r.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("ошибка сохранения решений проверок заказа: %v", err)
+	}
+	return nil
+}
+
+// ListForOrder возвращает решения проверок заказа
+func (r *orderHookRepository) ListForOrder(orderID uuid.UUID) ([]models.OrderHookDecision, error) {
+	query := `
+		SELECT id, order_id, user_id, hook, verdict, reason, duration_ms, created_at
+		FROM order_hook_decisions
+		WHERE order_id = $1
+		ORDER BY id
+	`
+	return r.query(query, orderID)
+}
+
+// ListRecent возвращает последние решения проверок
+func (r *orderHookRepository) ListRecent(verdict models.HookVerdict, limit int) ([]models.OrderHookDecision, error) {
+	query := `
+		SELECT id, order_id, user_id, hook, verdict, reason, duration_ms, created_at
+		FROM order_hook_decisions
+		WHERE $1 = '' OR verdict = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`
+	return r.query(query, string(verdict), limit)
+}
+
+// query выполняет запрос решений проверок
+func (r *orderHookRepository) query(query string, args ...interface{}) ([]models.OrderHookDecision, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения решений проверок заказа: %v", err)
+	}
+	defer rows.Close()
+
+	decisions := []models.OrderHookDecision{}
+	for rows.Next() {
+		var decision models.OrderHookDecision
+		if err := rows.Scan(&decision.ID, &decision.OrderID, &decision.UserID, &decision.Hook,
+			&decision.Verdict, &decision.Reason, &decision.DurationMs, &decision.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования решения проверки: %v", err)
+		}
+		decisions = append(decisions, decision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return decisions, nil
+}
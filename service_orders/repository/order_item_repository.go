@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"service_orders/models"
+
+	"pkg/ids"
+
+	"github.com/google/uuid"
+)
+
+// Позиции заказа хранятся одновременно в JSONB колонке orders.items (используется API)
+// и в нормализованной таблице order_items (отчеты, статусы позиций, ссылочная целостность).
+// Таблица обновляется в той же транзакции, что и заказ
+
+// insertOrderItems сохраняет позиции заказа в order_items
+func insertOrderItems(tx *sql.Tx, orderID uuid.UUID, items []models.OrderItem, status models.OrderItemStatus) error {
+	query := `
+		INSERT INTO order_items (id, order_id, position, product, quantity, price, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (order_id, position) DO NOTHING
+	`
+
+	for i, item := range items {
+		_, err := tx.Exec(query, ids.New(), orderID, i, item.Product, item.Quantity, item.Price, string(status))
+		if err != nil {
+			return fmt.Errorf("ошибка сохранения позиции заказа: %v", err)
+		}
+	}
+	return nil
+}
+
+// replaceOrderItems заменяет позиции заказа в order_items
+func replaceOrderItems(tx *sql.Tx, orderID uuid.UUID, items []models.OrderItem, status models.OrderItemStatus) error {
+	if _, err := tx.Exec("DELETE FROM order_items WHERE order_id = $1", orderID); err != nil {
+		return fmt.Errorf("ошибка удаления позиций заказа: %v", err)
+	}
+	return insertOrderItems(tx, orderID, items, status)
+}
+
+// orderItemStatus возвращает статус позиций, соответствующий статусу заказа
+func orderItemStatus(status models.OrderStatus) models.OrderItemStatus {
+	if status == models.OrderStatusCancelled {
+		return models.OrderItemStatusCancelled
+	}
+	return models.OrderItemStatusPending
+}
+
+// BackfillResult итоги переноса позиций существующих заказов в order_items
+type BackfillResult struct {
+	Orders  int `json:"orders"`
+	Items   int `json:"items"`
+	Skipped int `json:"skipped"`
+}
+
+// OrderItemsBackfill переносит позиции заказов, созданных до появления order_items.
+// Обрабатываются только заказы без строк в order_items, поэтому перенос можно
+// безопасно прерывать и запускать повторно
+type OrderItemsBackfill struct {
+	db *sql.DB
+}
+
+// NewOrderItemsBackfill создает новый перенос позиций заказов
+func NewOrderItemsBackfill(db *sql.DB) *OrderItemsBackfill {
+	return &OrderItemsBackfill{db: db}
+}
+
+// Run переносит позиции порциями по batchSize заказов. При dryRun изменения не сохраняются.
+// onSkip вызывается для заказов с некорректным JSON позиций (может быть nil)
+func (b *OrderItemsBackfill) Run(batchSize int, dryRun bool, onSkip func(orderID uuid.UUID, err error)) (*BackfillResult, error) {
+	result := &BackfillResult{}
+	after := uuid.Nil
+
+	for {
+		orders, err := b.nextBatch(after, batchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(orders) == 0 {
+			return result, nil
+		}
+
+		for _, order := range orders {
+			after = order.id
+
+			var items []models.OrderItem
+			if err := json.Unmarshal(order.items, &items); err != nil {
+				result.Skipped++
+				if onSkip != nil {
+					onSkip(order.id, err)
+				}
+				continue
+			}
+
+			if !dryRun {
+				if err := b.backfillOrder(order.id, items, orderItemStatus(order.status)); err != nil {
+					return result, err
+				}
+			}
+			result.Orders++
+			result.Items += len(items)
+		}
+	}
+}
+
+// backfillRow заказ, ожидающий переноса позиций
+type backfillRow struct {
+	id     uuid.UUID
+	items  []byte
+	status models.OrderStatus
+}
+
+// nextBatch выбирает следующую порцию заказов без позиций в order_items (keyset пагинация по id)
+func (b *OrderItemsBackfill) nextBatch(after uuid.UUID, limit int) ([]backfillRow, error) {
+	query := `
+		SELECT o.id, o.items, o.status
+		FROM orders o
+		WHERE o.id > $1
+		  AND NOT EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id)
+		ORDER BY o.id
+		LIMIT $2
+	`
+
+	rows, err := b.db.Query(query, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения заказов для переноса: %v", err)
+	}
+	defer rows.Close()
+
+	var orders []backfillRow
+	for rows.Next() {
+		var row backfillRow
+		var status string
+		if err := rows.Scan(&row.id, &row.items, &status); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования заказа: %v", err)
+		}
+		row.status = models.OrderStatus(status)
+		orders = append(orders, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return orders, nil
+}
+
+// backfillOrder сохраняет позиции одного заказа в отдельной транзакции
+func (b *OrderItemsBackfill) backfillOrder(orderID uuid.UUID, items []models.OrderItem, status models.OrderItemStatus) error {
+	tx, err := b.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertOrderItems(tx, orderID, items, status); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"service_orders/models"
 
@@ -17,9 +18,12 @@ type OrderRepository interface {
 	Create(order *models.Order) error
 	GetByID(id uuid.UUID) (*models.Order, error)
 	GetByUserID(userID uuid.UUID, req *models.ListOrdersRequest) (*models.ListOrdersResponse, error)
+	List(req *models.ListOrdersRequest) (*models.ListOrdersResponse, error)
 	Update(order *models.Order) error
 	UpdateStatus(id uuid.UUID, status models.OrderStatus) error
 	Cancel(id uuid.UUID) error
+	// GetStateAt возвращает состояние заказа на момент asOf по истории состояний
+	GetStateAt(id uuid.UUID, asOf time.Time) (*models.Order, error)
 	UserExists(userID uuid.UUID) (bool, error)
 }
 
@@ -41,17 +45,24 @@ func (r *orderRepository) Create(order *models.Order) error {
 		return fmt.Errorf("ошибка сериализации items: %v", err)
 	}
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
 	query := `
-		INSERT INTO orders (id, user_id, items, status, total_sum, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO orders (id, user_id, items, status, total_sum, region, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)
 	`
 	
-	_, err = r.db.Exec(query,
+	_, err = tx.Exec(query,
 		order.ID,
 		order.UserID,
 		itemsJSON,
 		string(order.Status),
 		order.TotalSum,
+		order.Region,
 		order.CreatedAt,
 		order.UpdatedAt,
 	)
@@ -62,6 +73,14 @@ func (r *orderRepository) Create(order *models.Order) error {
 		}
 		return fmt.Errorf("ошибка создания заказа: %v", err)
 	}
+
+	if err := insertOrderItems(tx, order.ID, order.Items, orderItemStatus(order.Status)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
 	
 	return nil
 }
@@ -69,7 +88,7 @@ func (r *orderRepository) Create(order *models.Order) error {
 // GetByID получает заказ по ID
 func (r *orderRepository) GetByID(id uuid.UUID) (*models.Order, error) {
 	query := `
-		SELECT id, user_id, items, status, total_sum, created_at, updated_at
+		SELECT id, user_id, items, status, total_sum, created_at, updated_at, assigned_to, assigned_at, COALESCE(region, '')
 		FROM orders
 		WHERE id = $1
 	`
@@ -77,6 +96,8 @@ func (r *orderRepository) GetByID(id uuid.UUID) (*models.Order, error) {
 	order := &models.Order{}
 	var itemsJSON []byte
 	var status string
+	var assignedTo uuid.NullUUID
+	var assignedAt sql.NullTime
 	
 	err := r.db.QueryRow(query, id).Scan(
 		&order.ID,
@@ -86,6 +107,9 @@ func (r *orderRepository) GetByID(id uuid.UUID) (*models.Order, error) {
 		&order.TotalSum,
 		&order.CreatedAt,
 		&order.UpdatedAt,
+		&assignedTo,
+		&assignedAt,
+		&order.Region,
 	)
 	
 	if err != nil {
@@ -101,16 +125,25 @@ func (r *orderRepository) GetByID(id uuid.UUID) (*models.Order, error) {
 	}
 	
 	order.Status = models.OrderStatus(status)
+	setAssignment(order, assignedTo, assignedAt)
 	
 	return order, nil
 }
 
 // GetByUserID получает заказы пользователя с фильтрацией и пагинацией
 func (r *orderRepository) GetByUserID(userID uuid.UUID, req *models.ListOrdersRequest) (*models.ListOrdersResponse, error) {
+	return r.list([]string{"user_id = $1"}, []interface{}{userID}, req)
+}
+
+// List получает заказы всех пользователей с фильтрацией и пагинацией
+func (r *orderRepository) List(req *models.ListOrdersRequest) (*models.ListOrdersResponse, error) {
+	return r.list(nil, nil, req)
+}
+
+// list выполняет выборку заказов с дополнительными условиями WHERE
+func (r *orderRepository) list(conditions []string, args []interface{}, req *models.ListOrdersRequest) (*models.ListOrdersResponse, error) {
 	// Построение WHERE условий
-	conditions := []string{"user_id = $1"}
-	args := []interface{}{userID}
-	argIndex := 2
+	argIndex := len(args) + 1
 
 	if req.Status != "" {
 		conditions = append(conditions, fmt.Sprintf("status = $%d", argIndex))
@@ -118,7 +151,17 @@ func (r *orderRepository) GetByUserID(userID uuid.UUID, req *models.ListOrdersRe
 		argIndex++
 	}
 
-	whereClause := "WHERE " + strings.Join(conditions, " AND ")
+	// Заказ должен иметь все теги фильтра
+	if len(req.Tags) > 0 {
+		conditions = append(conditions, fmt.Sprintf("id IN (%s)", taggedOrdersQuery(argIndex)))
+		args = append(args, pq.Array(req.Tags))
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
 
 	// Построение ORDER BY
 	sortField := "created_at"
@@ -143,7 +186,7 @@ func (r *orderRepository) GetByUserID(userID uuid.UUID, req *models.ListOrdersRe
 
 	// Получение списка заказов
 	query := fmt.Sprintf(`
-		SELECT id, user_id, items, status, total_sum, created_at, updated_at
+		SELECT id, user_id, items, status, total_sum, created_at, updated_at, assigned_to, assigned_at, COALESCE(region, '')
 		FROM orders
 		%s
 		%s
@@ -163,6 +206,8 @@ func (r *orderRepository) GetByUserID(userID uuid.UUID, req *models.ListOrdersRe
 		var order models.Order
 		var itemsJSON []byte
 		var status string
+		var assignedTo uuid.NullUUID
+		var assignedAt sql.NullTime
 		
 		err := rows.Scan(
 			&order.ID,
@@ -172,6 +217,9 @@ func (r *orderRepository) GetByUserID(userID uuid.UUID, req *models.ListOrdersRe
 			&order.TotalSum,
 			&order.CreatedAt,
 			&order.UpdatedAt,
+			&assignedTo,
+			&assignedAt,
+			&order.Region,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("ошибка сканирования заказа: %v", err)
@@ -183,6 +231,7 @@ func (r *orderRepository) GetByUserID(userID uuid.UUID, req *models.ListOrdersRe
 		}
 		
 		order.Status = models.OrderStatus(status)
+		setAssignment(&order, assignedTo, assignedAt)
 		orders = append(orders, order)
 	}
 
@@ -206,13 +255,19 @@ func (r *orderRepository) Update(order *models.Order) error {
 		return fmt.Errorf("ошибка сериализации items: %v", err)
 	}
 
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE orders
 		SET items = $2, status = $3, total_sum = $4, updated_at = NOW()
 		WHERE id = $1
 	`
 	
-	result, err := r.db.Exec(query,
+	result, err := tx.Exec(query,
 		order.ID,
 		itemsJSON,
 		string(order.Status),
@@ -231,19 +286,33 @@ func (r *orderRepository) Update(order *models.Order) error {
 	if rowsAffected == 0 {
 		return fmt.Errorf("заказ с ID %s не найден", order.ID)
 	}
+
+	if err := replaceOrderItems(tx, order.ID, order.Items, orderItemStatus(order.Status)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
 	
 	return nil
 }
 
 // UpdateStatus обновляет статус заказа
 func (r *orderRepository) UpdateStatus(id uuid.UUID, status models.OrderStatus) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
 	query := `
 		UPDATE orders
 		SET status = $2, updated_at = NOW()
 		WHERE id = $1
 	`
 	
-	result, err := r.db.Exec(query, id, string(status))
+	result, err := tx.Exec(query, id, string(status))
 	if err != nil {
 		return fmt.Errorf("ошибка обновления статуса заказа: %v", err)
 	}
@@ -256,6 +325,22 @@ func (r *orderRepository) UpdateStatus(id uuid.UUID, status models.OrderStatus)
 	if rowsAffected == 0 {
 		return fmt.Errorf("заказ с ID %s не найден", id)
 	}
+
+	// При отмене заказа отменяются и все его позиции
+	if status == models.OrderStatusCancelled {
+		itemsQuery := `
+			UPDATE order_items
+			SET status = $2, updated_at = NOW()
+			WHERE order_id = $1
+		`
+		if _, err := tx.Exec(itemsQuery, id, string(models.OrderItemStatusCancelled)); err != nil {
+			return fmt.Errorf("ошибка отмены позиций заказа: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
 	
 	return nil
 }
@@ -277,3 +362,13 @@ func (r *orderRepository) UserExists(userID uuid.UUID) (bool, error) {
 	
 	return exists, nil
 }
+
+// setAssignment заполняет назначение заказа оператору из nullable колонок
+func setAssignment(order *models.Order, assignedTo uuid.NullUUID, assignedAt sql.NullTime) {
+	if assignedTo.Valid {
+		order.AssignedTo = &assignedTo.UUID
+	}
+	if assignedAt.Valid {
+		order.AssignedAt = &assignedAt.Time
+	}
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"service_orders/config"
+	"service_orders/models"
+
+	"pkg/ids"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// BenchmarkOrderRepositoryCreate сравнивает скорость вставки заказов с идентификаторами
+// каждой стратегии ID_STRATEGY. Нужна тестовая БД (переменные DB_*, см. docs/README.md),
+// поэтому без DB_HOST бенчмарк пропускается; созданные заказы удаляются:
+//
+//	ENVIRONMENT=test DB_HOST=localhost DB_PORT=5433 DB_NAME=system_control_test \
+//	  go test -run '^$' -bench OrderRepositoryCreate ./repository
+func BenchmarkOrderRepositoryCreate(b *testing.B) {
+	if os.Getenv("DB_HOST") == "" {
+		b.Skip("нужна тестовая БД: задайте ENVIRONMENT=test и DB_* (docker-compose.test.yml, см. docs/README.md)")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		b.Fatalf("ошибка загрузки конфигурации: %v", err)
+	}
+	db, err := sql.Open("postgres", cfg.DB.DSN())
+	if err != nil {
+		b.Fatalf("ошибка подключения к базе данных: %v", err)
+	}
+	defer db.Close()
+
+	var userID uuid.UUID
+	if err := db.QueryRow(`SELECT id FROM users ORDER BY created_at LIMIT 1`).Scan(&userID); err != nil {
+		b.Fatalf("ошибка получения пользователя для заказов: %v", err)
+	}
+	repo := NewOrderRepository(db)
+	b.Cleanup(func() { ids.SetDefault(generatorOf(b, ids.StrategyUUIDv4)) })
+
+	for _, strategy := range []string{ids.StrategyUUIDv4, ids.StrategyUUIDv7, ids.StrategyULID} {
+		b.Run(strategy, func(b *testing.B) {
+			// Позиции заказа получают идентификаторы через ids.New
+			generator := generatorOf(b, strategy)
+			ids.SetDefault(generator)
+
+			created := make([]string, 0, b.N)
+			b.Cleanup(func() {
+				if _, err := db.Exec(`DELETE FROM orders WHERE id = ANY($1::uuid[])`, pq.Array(created)); err != nil {
+					b.Errorf("ошибка удаления созданных заказов: %v", err)
+				}
+			})
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				now := time.Now().UTC()
+				order := &models.Order{
+					ID:        generator.New(),
+					UserID:    userID,
+					Items:     []models.OrderItem{{Product: "benchmark", Quantity: 1, Price: 100}},
+					Status:    models.OrderStatusCreated,
+					TotalSum:  100,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+				if err := repo.Create(order); err != nil {
+					b.Fatalf("ошибка создания заказа: %v", err)
+				}
+				created = append(created, order.ID.String())
+			}
+		})
+	}
+}
+
+// generatorOf возвращает генератор идентификаторов стратегии strategy
+func generatorOf(b *testing.B, strategy string) ids.Generator {
+	b.Helper()
+	generator, err := ids.NewGenerator(strategy)
+	if err != nil {
+		b.Fatalf("стратегия %s: %v", strategy, err)
+	}
+	return generator
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"service_orders/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// OrderTagRepository интерфейс для работы с тегами заказов. Теги хранятся в таблице
+// order_tags, а не в orders: их изменение не меняет updated_at заказа и не попадает
+// в историю состояний
+type OrderTagRepository interface {
+	// SetTags заменяет теги заказа на tags; actorID — администратор, добавивший новые теги
+	SetTags(orderID uuid.UUID, tags []string, actorID uuid.UUID) error
+	// GetByOrderIDs возвращает отсортированные теги заказов одним запросом;
+	// заказов без тегов в результате нет
+	GetByOrderIDs(ids []uuid.UUID) (map[uuid.UUID][]string, error)
+	// Stats возвращает число заказов и сумму по каждому тегу
+	Stats(req *models.TagStatsRequest) ([]models.TagStats, error)
+}
+
+// orderTagRepository реализация OrderTagRepository
+type orderTagRepository struct {
+	db *sql.DB
+}
+
+// NewOrderTagRepository создает новый экземпляр OrderTagRepository
+func NewOrderTagRepository(db *sql.DB) OrderTagRepository {
+	return &orderTagRepository{db: db}
+}
+
+// taggedOrdersQuery возвращает подзапрос ID заказов, имеющих все теги из параметра
+// $argIndex (массив без повторов)
+func taggedOrdersQuery(argIndex int) string {
+	return fmt.Sprintf("SELECT order_id FROM order_tags WHERE tag = ANY($%d) GROUP BY order_id HAVING COUNT(*) = cardinality($%d::text[])", argIndex, argIndex)
+}
+
+// SetTags заменяет теги заказа в одной транзакции. Сохранившиеся теги не изменяются,
+// поэтому у них остаются исходные автор и время добавления
+func (r *orderTagRepository) SetTags(orderID uuid.UUID, tags []string, actorID uuid.UUID) error {
+	// nil передается в запрос как NULL, и DELETE не удалил бы ни одного тега
+	if tags == nil {
+		tags = []string{}
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	deleteQuery := `
+		DELETE FROM order_tags
+		WHERE order_id = $1 AND NOT (tag = ANY($2))
+	`
+	if _, err := tx.Exec(deleteQuery, orderID, pq.Array(tags)); err != nil {
+		return fmt.Errorf("ошибка удаления тегов заказа: %v", err)
+	}
+
+	if len(tags) > 0 {
+		insertQuery := `
+			INSERT INTO order_tags (order_id, tag, created_by, created_at)
+			SELECT $1, tag, $3, NOW() FROM unnest($2::text[]) AS tag
+			ON CONFLICT (order_id, tag) DO NOTHING
+		`
+		if _, err := tx.Exec(insertQuery, orderID, pq.Array(tags), actorID); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				return fmt.Errorf("заказ с ID %s не найден", orderID)
+			}
+			return fmt.Errorf("ошибка добавления тегов заказа: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return nil
+}
+
+// GetByOrderIDs получает теги заказов одним запросом по списку ID
+func (r *orderTagRepository) GetByOrderIDs(ids []uuid.UUID) (map[uuid.UUID][]string, error) {
+	tags := make(map[uuid.UUID][]string)
+	if len(ids) == 0 {
+		return tags, nil
+	}
+
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = id.String()
+	}
+
+	query := `
+		SELECT order_id, tag
+		FROM order_tags
+		WHERE order_id = ANY($1::uuid[])
+		ORDER BY order_id, tag
+	`
+	rows, err := r.db.Query(query, pq.Array(values))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения тегов заказов: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID uuid.UUID
+		var tag string
+		if err := rows.Scan(&orderID, &tag); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования тега заказа: %v", err)
+		}
+		tags[orderID] = append(tags[orderID], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return tags, nil
+}
+
+// Stats группирует заказы с тегами по тегу и статусу. Заказ с несколькими тегами
+// учитывается в сводке каждого из них
+func (r *orderTagRepository) Stats(req *models.TagStatsRequest) ([]models.TagStats, error) {
+	var conditions []string
+	var args []interface{}
+	if len(req.Tags) > 0 {
+		args = append(args, pq.Array(req.Tags))
+		conditions = append(conditions, fmt.Sprintf("t.tag = ANY($%d)", len(args)))
+	}
+	if req.Status != "" {
+		args = append(args, string(req.Status))
+		conditions = append(conditions, fmt.Sprintf("o.status = $%d", len(args)))
+	}
+	if req.From != nil {
+		args = append(args, *req.From)
+		conditions = append(conditions, fmt.Sprintf("o.created_at >= $%d", len(args)))
+	}
+	if req.To != nil {
+		args = append(args, *req.To)
+		conditions = append(conditions, fmt.Sprintf("o.created_at < $%d", len(args)))
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT t.tag, o.status, COUNT(*), COALESCE(SUM(o.total_sum), 0)
+		FROM order_tags t
+		JOIN orders o ON o.id = t.order_id
+		%s
+		GROUP BY t.tag, o.status
+	`, whereClause)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка расчета сводки по тегам: %v", err)
+	}
+	defer rows.Close()
+
+	byTag := make(map[string]*models.TagStats)
+	for rows.Next() {
+		var tag, status string
+		var count int
+		var sum float64
+		if err := rows.Scan(&tag, &status, &count, &sum); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования сводки по тегам: %v", err)
+		}
+		stats, ok := byTag[tag]
+		if !ok {
+			stats = &models.TagStats{Tag: tag, ByStatus: make(map[models.OrderStatus]int)}
+			byTag[tag] = stats
+		}
+		stats.Orders += count
+		stats.TotalSum += sum
+		stats.ByStatus[models.OrderStatus(status)] += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+
+	result := make([]models.TagStats, 0, len(byTag))
+	for _, stats := range byTag {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Orders != result[j].Orders {
+			return result[i].Orders > result[j].Orders
+		}
+		return result[i].Tag < result[j].Tag
+	})
+	return result, nil
+}
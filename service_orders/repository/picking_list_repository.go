@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"service_orders/models"
+
+	"github.com/google/uuid"
+)
+
+// PickingListRepository интерфейс для формирования сборочных листов склада
+type PickingListRepository interface {
+	// Build суммирует количество товаров по заказам со статусом status и регионом region
+	// (пустой region — все регионы)
+	Build(status models.OrderStatus, region string) (*models.PickingList, error)
+}
+
+// pickingListRepository реализация PickingListRepository
+type pickingListRepository struct {
+	db *sql.DB
+}
+
+// NewPickingListRepository создает новый экземпляр PickingListRepository
+func NewPickingListRepository(db *sql.DB) PickingListRepository {
+	return &pickingListRepository{db: db}
+}
+
+// Build формирует сборочный лист по нормализованным позициям order_items, не выбирая
+// JSON позиций каждого заказа. Отмененные позиции не собираются. Товары упорядочены
+// по названию, заказы внутри товара — по времени создания
+func (r *pickingListRepository) Build(status models.OrderStatus, region string) (*models.PickingList, error) {
+	query := `
+		SELECT oi.product, o.id, SUM(oi.quantity)
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		WHERE o.status = $1
+		  AND ($2::text = '' OR o.region = $2::text)
+		  AND oi.status = $3
+		GROUP BY oi.product, o.id, o.created_at
+		ORDER BY oi.product, o.created_at, o.id
+	`
+
+	rows, err := r.db.Query(query, string(status), region, string(models.OrderItemStatusPending))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка формирования сборочного листа: %v", err)
+	}
+	defer rows.Close()
+
+	list := &models.PickingList{
+		Status: status,
+		Region: region,
+		Items:  []models.PickingListItem{},
+	}
+	orders := make(map[uuid.UUID]struct{})
+	for rows.Next() {
+		var product string
+		var ref models.PickingListOrder
+		if err := rows.Scan(&product, &ref.OrderID, &ref.Quantity); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования позиции сборочного листа: %v", err)
+		}
+
+		if n := len(list.Items); n == 0 || list.Items[n-1].Product != product {
+			list.Items = append(list.Items, models.PickingListItem{Product: product})
+		}
+		item := &list.Items[len(list.Items)-1]
+		item.TotalQuantity += ref.Quantity
+		item.Orders = append(item.Orders, ref)
+		orders[ref.OrderID] = struct{}{}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+
+	list.OrdersCount = len(orders)
+	return list, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"service_orders/models"
+)
+
+// DefaultStatusLocale локаль, используемая при отсутствии перевода
+const DefaultStatusLocale = "ru"
+
+// StatusRepository интерфейс для работы со справочником статусов заказов. Коды статусов
+// заполняются миграциями и соответствуют константам models.OrderStatus; изменяются
+// только локализованные названия
+type StatusRepository interface {
+	List(locale string) ([]models.OrderStatusInfo, error)
+	DisplayNames(locale string) (map[models.OrderStatus]string, error)
+	Exists(code models.OrderStatus) (bool, error)
+	UpsertTranslation(code models.OrderStatus, locale, displayName string) error
+}
+
+// statusRepository реализация StatusRepository
+type statusRepository struct {
+	db *sql.DB
+}
+
+// NewStatusRepository создает новый экземпляр StatusRepository
+func NewStatusRepository(db *sql.DB) StatusRepository {
+	return &statusRepository{db: db}
+}
+
+// List возвращает справочник статусов с названиями на указанной локали
+// (с откатом на локаль по умолчанию и машинный код)
+func (r *statusRepository) List(locale string) ([]models.OrderStatusInfo, error) {
+	query := `
+		SELECT s.code, s.sort_order, s.is_final,
+		       COALESCE(t.display_name, d.display_name, s.code),
+		       COALESCE(t.locale, d.locale, '')
+		FROM order_statuses s
+		LEFT JOIN order_status_translations t ON t.status_code = s.code AND t.locale = $1
+		LEFT JOIN order_status_translations d ON d.status_code = s.code AND d.locale = $2
+		ORDER BY s.sort_order
+	`
+
+	rows, err := r.db.Query(query, locale, DefaultStatusLocale)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения справочника статусов: %v", err)
+	}
+	defer rows.Close()
+
+	var statuses []models.OrderStatusInfo
+	for rows.Next() {
+		var info models.OrderStatusInfo
+		var code string
+		if err := rows.Scan(&code, &info.SortOrder, &info.IsFinal, &info.DisplayName, &info.Locale); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования статуса: %v", err)
+		}
+		info.Code = models.OrderStatus(code)
+		statuses = append(statuses, info)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+
+	return statuses, nil
+}
+
+// DisplayNames возвращает соответствие кодов статусов их названиям на указанной локали
+func (r *statusRepository) DisplayNames(locale string) (map[models.OrderStatus]string, error) {
+	statuses, err := r.List(locale)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[models.OrderStatus]string, len(statuses))
+	for _, s := range statuses {
+		names[s.Code] = s.DisplayName
+	}
+	return names, nil
+}
+
+// Exists проверяет наличие статуса в справочнике
+func (r *statusRepository) Exists(code models.OrderStatus) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM order_statuses WHERE code = $1)", string(code)).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("ошибка проверки статуса: %v", err)
+	}
+	return exists, nil
+}
+
+// UpsertTranslation создает или обновляет локализованное название статуса
+func (r *statusRepository) UpsertTranslation(code models.OrderStatus, locale, displayName string) error {
+	query := `
+		INSERT INTO order_status_translations (status_code, locale, display_name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (status_code, locale) DO UPDATE SET display_name = EXCLUDED.display_name
+	`
+
+	if _, err := r.db.Exec(query, string(code), locale, displayName); err != nil {
+		return fmt.Errorf("ошибка сохранения названия статуса: %v", err)
+	}
+	return nil
+}
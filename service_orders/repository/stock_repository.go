@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"service_orders/models"
+
+	"github.com/lib/pq"
+)
+
+// StockRepository интерфейс для работы со складскими остатками
+type StockRepository interface {
+	// Apply сохраняет остаток, если он новее сохраненного. previous — прежний остаток
+	// (nil для нового товара), applied == false для устаревшего изменения
+	Apply(level models.StockLevel) (previous *int, applied bool, err error)
+	// Available возвращает остатки товаров; товары без записи в результат не попадают
+	Available(products []string) (map[string]int, error)
+}
+
+// stockRepository реализация StockRepository
+type stockRepository struct {
+	db *sql.DB
+}
+
+// NewStockRepository создает новый экземпляр StockRepository
+func NewStockRepository(db *sql.DB) StockRepository {
+	return &stockRepository{db: db}
+}
+
+// Apply сохраняет остаток товара. Складские системы могут повторять и переупорядочивать
+// webhook, поэтому изменение применяется только если оно новее сохраненного
+func (r *stockRepository) Apply(level models.StockLevel) (*int, bool, error) {
+	query := `
+		WITH prev AS (
+			SELECT available FROM stock_levels WHERE product = $1 FOR UPDATE
+		)
+		INSERT INTO stock_levels (product, available, warehouse, changed_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (product) DO UPDATE
+		SET available = EXCLUDED.available, warehouse = EXCLUDED.warehouse,
+		    changed_at = EXCLUDED.changed_at, updated_at = NOW()
+		WHERE stock_levels.changed_at < EXCLUDED.changed_at
+		RETURNING (SELECT available FROM prev)
+	`
+
+	var previous sql.NullInt64
+	err := r.db.QueryRow(query, level.Product, level.Available, level.Warehouse, level.ChangedAt).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка сохранения остатка товара: %v", err)
+	}
+
+	if !previous.Valid {
+		return nil, true, nil
+	}
+	value := int(previous.Int64)
+	return &value, true, nil
+}
+
+// Available возвращает остатки товаров одним запросом
+func (r *stockRepository) Available(products []string) (map[string]int, error) {
+	available := make(map[string]int, len(products))
+	if len(products) == 0 {
+		return available, nil
+	}
+
+	rows, err := r.db.Query(`SELECT product, available FROM stock_levels WHERE product = ANY($1)`, pq.Array(products))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения остатков товаров: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product string
+		var count int
+		if err := rows.Scan(&product, &count); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования остатка: %v", err)
+		}
+		available[product] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения остатков товаров: %v", err)
+	}
+	return available, nil
+}
@@ -0,0 +1,366 @@
+package repository
+
+import (
+	"time"
+
+	"service_orders/models"
+
+	"pkg/servertiming"
+
+	"github.com/google/uuid"
+)
+
+// Декораторы репозиториев учитывают время запросов к БД в этапе db запроса
+// (заголовок Server-Timing). Репозитории не принимают контекст, поэтому обработчики
+// оборачивают их Recorder'ом своего запроса; nil Recorder возвращает репозиторий как есть
+
+// TimedOrderRepository возвращает OrderRepository, учитывающий время запросов в timing
+func TimedOrderRepository(repo OrderRepository, timing *servertiming.Recorder) OrderRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedOrderRepository{next: repo, timing: timing}
+}
+
+type timedOrderRepository struct {
+	next   OrderRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedOrderRepository) Create(order *models.Order) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(order)
+}
+
+func (r *timedOrderRepository) GetByID(id uuid.UUID) (*models.Order, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetByID(id)
+}
+
+func (r *timedOrderRepository) GetByUserID(userID uuid.UUID, req *models.ListOrdersRequest) (*models.ListOrdersResponse, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetByUserID(userID, req)
+}
+
+func (r *timedOrderRepository) List(req *models.ListOrdersRequest) (*models.ListOrdersResponse, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.List(req)
+}
+
+func (r *timedOrderRepository) Update(order *models.Order) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Update(order)
+}
+
+func (r *timedOrderRepository) UpdateStatus(id uuid.UUID, status models.OrderStatus) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.UpdateStatus(id, status)
+}
+
+func (r *timedOrderRepository) Cancel(id uuid.UUID) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Cancel(id)
+}
+
+func (r *timedOrderRepository) GetStateAt(id uuid.UUID, asOf time.Time) (*models.Order, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetStateAt(id, asOf)
+}
+
+func (r *timedOrderRepository) UserExists(userID uuid.UUID) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.UserExists(userID)
+}
+
+// TimedStatusRepository возвращает StatusRepository, учитывающий время запросов в timing
+func TimedStatusRepository(repo StatusRepository, timing *servertiming.Recorder) StatusRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedStatusRepository{next: repo, timing: timing}
+}
+
+type timedStatusRepository struct {
+	next   StatusRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedStatusRepository) List(locale string) ([]models.OrderStatusInfo, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.List(locale)
+}
+
+func (r *timedStatusRepository) DisplayNames(locale string) (map[models.OrderStatus]string, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.DisplayNames(locale)
+}
+
+func (r *timedStatusRepository) Exists(code models.OrderStatus) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Exists(code)
+}
+
+func (r *timedStatusRepository) UpsertTranslation(code models.OrderStatus, locale, displayName string) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.UpsertTranslation(code, locale, displayName)
+}
+
+// TimedCustomerRepository возвращает CustomerRepository, учитывающий время запросов в timing
+func TimedCustomerRepository(repo CustomerRepository, timing *servertiming.Recorder) CustomerRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedCustomerRepository{next: repo, timing: timing}
+}
+
+type timedCustomerRepository struct {
+	next   CustomerRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedCustomerRepository) GetByIDs(ids []uuid.UUID) (map[uuid.UUID]models.Customer, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetByIDs(ids)
+}
+
+// TimedStockRepository возвращает StockRepository, учитывающий время запросов в timing
+func TimedStockRepository(repo StockRepository, timing *servertiming.Recorder) StockRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedStockRepository{next: repo, timing: timing}
+}
+
+type timedStockRepository struct {
+	next   StockRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedStockRepository) Apply(level models.StockLevel) (*int, bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Apply(level)
+}
+
+func (r *timedStockRepository) Available(products []string) (map[string]int, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Available(products)
+}
+
+// TimedWorkQueueRepository возвращает WorkQueueRepository, учитывающий время запросов в timing
+func TimedWorkQueueRepository(repo WorkQueueRepository, timing *servertiming.Recorder) WorkQueueRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedWorkQueueRepository{next: repo, timing: timing}
+}
+
+type timedWorkQueueRepository struct {
+	next   WorkQueueRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedWorkQueueRepository) Claim(operatorID uuid.UUID, tags []string) (uuid.UUID, bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Claim(operatorID, tags)
+}
+
+func (r *timedWorkQueueRepository) Release(orderID, operatorID uuid.UUID) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Release(orderID, operatorID)
+}
+
+func (r *timedWorkQueueRepository) Complete(orderID, operatorID uuid.UUID) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Complete(orderID, operatorID)
+}
+
+func (r *timedWorkQueueRepository) Approve(orderID uuid.UUID) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Approve(orderID)
+}
+
+func (r *timedWorkQueueRepository) Reject(orderID uuid.UUID) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Reject(orderID)
+}
+
+// TimedPickingListRepository возвращает PickingListRepository, учитывающий время запросов в timing
+func TimedPickingListRepository(repo PickingListRepository, timing *servertiming.Recorder) PickingListRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedPickingListRepository{next: repo, timing: timing}
+}
+
+type timedPickingListRepository struct {
+	next   PickingListRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedPickingListRepository) Build(status models.OrderStatus, region string) (*models.PickingList, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Build(status, region)
+}
+
+// TimedOrderTagRepository возвращает OrderTagRepository, учитывающий время запросов в timing
+func TimedOrderTagRepository(repo OrderTagRepository, timing *servertiming.Recorder) OrderTagRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedOrderTagRepository{next: repo, timing: timing}
+}
+
+type timedOrderTagRepository struct {
+	next   OrderTagRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedOrderTagRepository) SetTags(orderID uuid.UUID, tags []string, actorID uuid.UUID) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.SetTags(orderID, tags, actorID)
+}
+
+func (r *timedOrderTagRepository) GetByOrderIDs(ids []uuid.UUID) (map[uuid.UUID][]string, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetByOrderIDs(ids)
+}
+
+func (r *timedOrderTagRepository) Stats(req *models.TagStatsRequest) ([]models.TagStats, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Stats(req)
+}
+
+// TimedOrderFilterRepository возвращает OrderFilterRepository, учитывающий время запросов в timing
+func TimedOrderFilterRepository(repo OrderFilterRepository, timing *servertiming.Recorder) OrderFilterRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedOrderFilterRepository{next: repo, timing: timing}
+}
+
+type timedOrderFilterRepository struct {
+	next   OrderFilterRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedOrderFilterRepository) List(userID uuid.UUID) ([]models.OrderFilterPreset, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.List(userID)
+}
+
+func (r *timedOrderFilterRepository) Get(id, userID uuid.UUID) (*models.OrderFilterPreset, bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Get(id, userID)
+}
+
+func (r *timedOrderFilterRepository) Create(preset *models.OrderFilterPreset) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(preset)
+}
+
+func (r *timedOrderFilterRepository) Update(preset *models.OrderFilterPreset) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Update(preset)
+}
+
+func (r *timedOrderFilterRepository) Delete(id, userID uuid.UUID) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Delete(id, userID)
+}
+
+// TimedExportRepository возвращает ExportRepository, учитывающий время запросов в timing
+func TimedExportRepository(repo ExportRepository, timing *servertiming.Recorder) ExportRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedExportRepository{next: repo, timing: timing}
+}
+
+type timedExportRepository struct {
+	next   ExportRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedExportRepository) Lock(userID uuid.UUID) (func(), error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Lock(userID)
+}
+
+func (r *timedExportRepository) Create(artifact *models.ExportArtifact) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(artifact)
+}
+
+func (r *timedExportRepository) Get(id, userID uuid.UUID, now time.Time) (*models.ExportArtifact, bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Get(id, userID, now)
+}
+
+func (r *timedExportRepository) DeleteExpired(now time.Time) (int64, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.DeleteExpired(now)
+}
+
+// TimedOrderHookRepository возвращает OrderHookRepository, учитывающий время запросов в timing
+func TimedOrderHookRepository(repo OrderHookRepository, timing *servertiming.Recorder) OrderHookRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedOrderHookRepository{next: repo, timing: timing}
+}
+
+type timedOrderHookRepository struct {
+	next   OrderHookRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedOrderHookRepository) Record(decisions []models.OrderHookDecision) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Record(decisions)
+}
+
+func (r *timedOrderHookRepository) ListForOrder(orderID uuid.UUID) ([]models.OrderHookDecision, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.ListForOrder(orderID)
+}
+
+func (r *timedOrderHookRepository) ListRecent(verdict models.HookVerdict, limit int) ([]models.OrderHookDecision, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.ListRecent(verdict, limit)
+}
+
+// TimedBlacklistRepository возвращает BlacklistRepository, учитывающий время запросов в timing
+func TimedBlacklistRepository(repo BlacklistRepository, timing *servertiming.Recorder) BlacklistRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedBlacklistRepository{next: repo, timing: timing}
+}
+
+type timedBlacklistRepository struct {
+	next   BlacklistRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedBlacklistRepository) Match(userID uuid.UUID, ip string) (*models.BlacklistEntry, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Match(userID, ip)
+}
+
+// TimedAdminAuditRepository возвращает AdminAuditRepository, учитывающий время запросов в timing
+func TimedAdminAuditRepository(repo AdminAuditRepository, timing *servertiming.Recorder) AdminAuditRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedAdminAuditRepository{next: repo, timing: timing}
+}
+
+type timedAdminAuditRepository struct {
+	next   AdminAuditRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedAdminAuditRepository) Record(actorID uuid.UUID, action, target, details string) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Record(actorID, action, target, details)
+}
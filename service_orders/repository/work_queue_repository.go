@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"service_orders/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// WorkQueueRepository интерфейс очереди заказов операторов. Очередь — заказы в статусе
+// created без назначенного оператора, в порядке создания
+type WorkQueueRepository interface {
+	// Claim назначает оператору самый старый заказ очереди, имеющий все теги tags (пусто —
+	// любой заказ), и переводит его в статус in_work. claimed == false, если таких заказов нет
+	Claim(operatorID uuid.UUID, tags []string) (orderID uuid.UUID, claimed bool, err error)
+	// Release снимает назначение и возвращает заказ в очередь (статус created).
+	// released == false, если заказ не назначен оператору или уже не в работе
+	Release(orderID, operatorID uuid.UUID) (released bool, err error)
+	// Complete переводит назначенный оператору заказ в статус completed.
+	// completed == false, если заказ не назначен оператору или уже не в работе
+	Complete(orderID, operatorID uuid.UUID) (completed bool, err error)
+	// Approve одобряет заказ, помеченный проверкой перед созданием (статус flagged),
+	// и ставит его в очередь (статус created). approved == false, если заказ не в flagged
+	Approve(orderID uuid.UUID) (approved bool, err error)
+	// Reject отменяет заказ в статусе flagged вместе с позициями.
+	// rejected == false, если заказ не в flagged
+	Reject(orderID uuid.UUID) (rejected bool, err error)
+}
+
+// workQueueRepository реализация WorkQueueRepository
+type workQueueRepository struct {
+	db *sql.DB
+}
+
+// NewWorkQueueRepository создает новый экземпляр WorkQueueRepository
+func NewWorkQueueRepository(db *sql.DB) WorkQueueRepository {
+	return &workQueueRepository{db: db}
+}
+
+// Claim назначает оператору самый старый заказ очереди. Строки, заблокированные
+// параллельными Claim других операторов, пропускаются (SKIP LOCKED), поэтому операторы
+// не ждут друг друга и один заказ не назначается двоим
+func (r *workQueueRepository) Claim(operatorID uuid.UUID, tags []string) (uuid.UUID, bool, error) {
+	args := []interface{}{operatorID, string(models.OrderStatusCreated), string(models.OrderStatusInWork)}
+	tagCondition := ""
+	if len(tags) > 0 {
+		args = append(args, pq.Array(tags))
+		tagCondition = fmt.Sprintf("AND id IN (%s)", taggedOrdersQuery(len(args)))
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE orders
+		SET assigned_to = $1, assigned_at = NOW(), status = $3, updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM orders
+			WHERE status = $2 AND assigned_to IS NULL %s
+			ORDER BY created_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`, tagCondition)
+
+	var orderID uuid.UUID
+	err := r.db.QueryRow(query, args...).Scan(&orderID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("ошибка назначения заказа оператору: %v", err)
+	}
+	return orderID, true, nil
+}
+
+// Release снимает назначение заказа оператору и возвращает заказ в очередь
+func (r *workQueueRepository) Release(orderID, operatorID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE orders
+		SET assigned_to = NULL, assigned_at = NULL, status = $4, updated_at = NOW()
+		WHERE id = $1 AND assigned_to = $2 AND status = $3
+	`
+
+	result, err := r.db.Exec(query, orderID, operatorID, string(models.OrderStatusInWork), string(models.OrderStatusCreated))
+	if err != nil {
+		return false, fmt.Errorf("ошибка снятия назначения заказа: %v", err)
+	}
+	return affectedOne(result)
+}
+
+// Complete завершает заказ, назначенный оператору. Назначение сохраняется,
+// чтобы было видно, кто выполнил заказ
+func (r *workQueueRepository) Complete(orderID, operatorID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE orders
+		SET status = $4, updated_at = NOW()
+		WHERE id = $1 AND assigned_to = $2 AND status = $3
+	`
+
+	result, err := r.db.Exec(query, orderID, operatorID, string(models.OrderStatusInWork), string(models.OrderStatusCompleted))
+	if err != nil {
+		return false, fmt.Errorf("ошибка завершения заказа: %v", err)
+	}
+	return affectedOne(result)
+}
+
+// Approve переводит заказ из flagged в очередь
+func (r *workQueueRepository) Approve(orderID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE orders
+		SET status = $3, updated_at = NOW()
+		WHERE id = $1 AND status = $2
+	`
+
+	result, err := r.db.Exec(query, orderID, string(models.OrderStatusFlagged), string(models.OrderStatusCreated))
+	if err != nil {
+		return false, fmt.Errorf("ошибка одобрения заказа: %v", err)
+	}
+	return affectedOne(result)
+}
+
+// Reject отменяет заказ в статусе flagged в одной транзакции с его позициями
+func (r *workQueueRepository) Reject(orderID uuid.UUID) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE orders
+		SET status = $3, updated_at = NOW()
+		WHERE id = $1 AND status = $2
+	`
+	result, err := tx.Exec(query, orderID, string(models.OrderStatusFlagged), string(models.OrderStatusCancelled))
+	if err != nil {
+		return false, fmt.Errorf("ошибка отклонения заказа: %v", err)
+	}
+	rejected, err := affectedOne(result)
+	if err != nil || !rejected {
+		return false, err
+	}
+
+	itemsQuery := `
+		UPDATE order_items
+		SET status = $2, updated_at = NOW()
+		WHERE order_id = $1
+	`
+	if _, err := tx.Exec(itemsQuery, orderID, string(models.OrderItemStatusCancelled)); err != nil {
+		return false, fmt.Errorf("ошибка отмены позиций заказа: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return true, nil
+}
+
+// affectedOne сообщает, изменил ли запрос строку
+func affectedOne(result sql.Result) (bool, error) {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения количества обновленных строк: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
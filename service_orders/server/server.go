@@ -0,0 +1,368 @@
+// Package server собирает service_orders из конфигурации и подключения к БД: систему
+// событий, репозитории, обработчики, маршруты и middleware. Фоновые задачи запускаются
+// отдельно (Start), поэтому команды, которым нужен сервис в процессе (например, fuzz_api),
+// получают обработчик запросов без фоновых задач
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"service_orders/analytics"
+	"service_orders/config"
+	"service_orders/currency"
+	"service_orders/events"
+	"service_orders/handlers"
+	"service_orders/logger"
+	"service_orders/models"
+	"service_orders/notifications"
+	"service_orders/precreate"
+	"service_orders/repository"
+
+	"pkg/buildinfo"
+	"pkg/httpmw"
+	"pkg/httpresp"
+	"pkg/lock"
+	"pkg/servertiming"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Server собранный сервис: обработчик запросов, система событий и фоновые задачи
+type Server struct {
+	handler http.Handler
+	events  *events.EventService
+	// jobs фоновые задачи; выполняются до отмены контекста Start
+	jobs []func(ctx context.Context)
+}
+
+// New создает систему событий, репозитории и обработчики сервиса и регистрирует маршруты.
+// Фоновые задачи не запускаются до вызова Start
+func New(cfg *config.Config, db *sql.DB) (*Server, error) {
+	zapLogger := logger.GetLogger()
+
+	// Инициализация системы событий
+	eventPublisher := events.NewInMemoryEventPublisher(cfg.Events.DrainTimeout, events.PoolConfig{
+		Workers:   cfg.Events.Workers,
+		QueueSize: cfg.Events.WorkerQueue,
+		Overflow:  cfg.Events.OverflowPolicy,
+	})
+	deliveryRepo := notifications.NewDeliveryRepository(db)
+	notifier := notifications.NewLogNotifier(notifications.NewPreferencesRepository(db), deliveryRepo)
+	eventService := events.NewEventService(eventPublisher, notifier, events.NewHandlerStateRepository(db))
+
+	s := &Server{events: eventService}
+
+	// Повторная отправка доставок, возвращенных в очередь администратором
+	s.jobs = append(s.jobs, func(ctx context.Context) {
+		notifier.RunRedelivery(ctx, cfg.Notifications.RedeliveryInterval, cfg.Notifications.RedeliveryBatch)
+	})
+
+	// Рассылки объявлений отправляет один экземпляр сервиса, чтобы ограничение скорости
+	// действовало на все экземпляры
+	broadcastRepo := notifications.NewBroadcastRepository(db)
+	broadcaster := notifications.NewBroadcaster(broadcastRepo, notifier, cfg.Notifications.BroadcastBatch,
+		cfg.Notifications.BroadcastRate, cfg.Notifications.BroadcastPollInterval)
+	s.jobs = append(s.jobs, func(ctx context.Context) {
+		lock.Leader(ctx, lock.NewPostgres(db, lock.NamespaceJobs), "broadcaster", leaderRetryInterval, broadcaster.Run)
+	})
+
+	// Детектор аномалий бизнес-метрик: события alert.* и уведомления получателям
+	if cfg.Anomaly.Enabled {
+		if err := eventService.EnableAlertNotifications(cfg.Anomaly.Recipients); err != nil {
+			zapLogger.Error("Ошибка регистрации уведомлений об аномалиях", zap.Error(err))
+		}
+		// Метрики проверяет один экземпляр сервиса, чтобы оповещения не повторялись
+		detector := newAnomalyDetector(cfg.Anomaly, db, eventService)
+		s.jobs = append(s.jobs, func(ctx context.Context) {
+			lock.Leader(ctx, lock.NewPostgres(db, lock.NamespaceJobs), "anomaly-detector", leaderRetryInterval, detector.Run)
+		})
+		zapLogger.Info("Детектор аномалий бизнес-метрик включен",
+			zap.Duration("window", cfg.Anomaly.Window),
+			zap.Int("recipients", len(cfg.Anomaly.Recipients)))
+	}
+
+	// Инициализация репозитория и обработчиков
+	orderRepo := repository.NewOrderRepository(db)
+	statusRepo := repository.NewStatusRepository(db)
+	customerRepo := repository.NewCustomerRepository(db)
+	stockRepo := repository.NewStockRepository(db)
+	tagRepo := repository.NewOrderTagRepository(db)
+	orderHooks, err := newOrderHooks(cfg.OrderHooks, repository.NewBlacklistRepository(db))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка регистрации проверок заказа: %v", err)
+	}
+	zapLogger.Info("Проверки заказа перед созданием", zap.Strings("hooks", orderHooks.Names()))
+	orderHandler := handlers.NewOrderHandler(orderRepo, statusRepo, customerRepo, stockRepo, tagRepo, cfg, eventService,
+		newCurrencyConverter(cfg.Currency), orderHooks, repository.NewOrderHookRepository(db))
+	deliveryHandler := handlers.NewDeliveryHandler(orderHandler, deliveryRepo)
+	broadcastHandler := handlers.NewBroadcastHandler(orderHandler, broadcastRepo)
+	trackingHandler := handlers.NewTrackingHandler(orderHandler)
+	inventoryHandler := handlers.NewInventoryHandler(orderHandler, eventService)
+	eventHandlersHandler := handlers.NewEventHandlersHandler(orderHandler, eventService)
+	workQueueHandler := handlers.NewWorkQueueHandler(orderHandler, repository.NewWorkQueueRepository(db))
+	exportRepo := repository.NewExportRepository(db)
+	exportHandler := handlers.NewExportHandler(orderHandler, exportRepo)
+	s.jobs = append(s.jobs, func(ctx context.Context) {
+		pruneExports(ctx, exportRepo)
+	})
+	pickingListHandler := handlers.NewPickingListHandler(exportHandler, repository.NewPickingListRepository(db))
+	orderTagHandler := handlers.NewOrderTagHandler(orderHandler)
+	orderFilterHandler := handlers.NewOrderFilterHandler(orderHandler, repository.NewOrderFilterRepository(db))
+	orderHookHandler := handlers.NewOrderHookHandler(orderHandler)
+	supportHandler := handlers.NewSupportHandler(orderHandler, repository.NewAdminAuditRepository(db))
+
+	// Настройка маршрутов
+	router := mux.NewRouter()
+	// Неизвестные маршруты и методы отвечают в формате APIResponse, как и обработчики
+	router.NotFoundHandler = errorHandler(http.StatusNotFound, models.ErrorCodeNotFound, "Маршрут не найден")
+	router.MethodNotAllowedHandler = errorHandler(http.StatusMethodNotAllowed, models.ErrorCodeMethodNotAllowed, "Метод не поддерживается")
+
+	router.HandleFunc("/version", buildinfo.Handler("service_orders")).Methods("GET")
+
+	// Справочник статусов (регистрируется до /v1/orders/{id})
+	router.HandleFunc("/v1/orders/statuses", orderHandler.ListStatuses).Methods("GET")
+	router.HandleFunc("/v1/orders/statuses/{code}", orderHandler.UpdateStatusTranslation).Methods("PUT")
+
+	// Административный список всех заказов (регистрируется до /v1/orders/{id})
+	router.HandleFunc("/v1/orders/all", orderHandler.ListAllOrders).Methods("GET")
+
+	// Маршруты для сервиса заказов
+	router.HandleFunc("/v1/orders", orderHandler.CreateOrder).Methods("POST")
+	router.HandleFunc("/v1/orders/{id}", orderHandler.GetOrder).Methods("GET")
+	router.HandleFunc("/v1/orders", orderHandler.ListOrders).Methods("GET")
+	router.HandleFunc("/v1/orders/{id}/status", orderHandler.UpdateOrderStatus).Methods("PUT")
+	router.HandleFunc("/v1/orders/{id}/cancel", orderHandler.CancelOrder).Methods("PUT")
+	// Совместимость с тестами: поддерживаем также POST для отмены заказа
+	router.HandleFunc("/v1/orders/{id}/cancel", orderHandler.CancelOrder).Methods("POST")
+
+	// Ссылки отслеживания заказа: выдача владельцем и публичный просмотр статуса
+	router.HandleFunc("/v1/orders/{id}/tracking-token", trackingHandler.CreateTrackingToken).Methods("POST")
+	router.HandleFunc("/v1/track/{token}", trackingHandler.TrackOrder).Methods("GET")
+
+	// Изменения складских остатков от складских систем (аутентификация по подписи)
+	router.HandleFunc("/v1/inventory/stock-webhook", inventoryHandler.ReceiveStockWebhook).Methods("POST")
+
+	// Администрирование исходящих доставок (уведомления и webhook)
+	router.HandleFunc("/v1/admin/deliveries", deliveryHandler.ListDeliveries).Methods("GET")
+	router.HandleFunc("/v1/admin/deliveries/requeue", deliveryHandler.RequeueDeliveries).Methods("POST")
+	router.HandleFunc("/v1/admin/deliveries/discard", deliveryHandler.DiscardDeliveries).Methods("POST")
+
+	// Рассылки объявлений сегментам пользователей
+	router.HandleFunc("/v1/admin/broadcasts", broadcastHandler.ListBroadcasts).Methods("GET")
+	router.HandleFunc("/v1/admin/broadcasts", broadcastHandler.CreateBroadcast).Methods("POST")
+	router.HandleFunc("/v1/admin/broadcasts/{id}", broadcastHandler.GetBroadcast).Methods("GET")
+	router.HandleFunc("/v1/admin/broadcasts/{id}/recipients", broadcastHandler.ListBroadcastRecipients).Methods("GET")
+	router.HandleFunc("/v1/admin/broadcasts/{id}/cancel", broadcastHandler.CancelBroadcast).Methods("POST")
+
+	// Очередь заказов операторов: взятие самого старого заказа, возврат в очередь и завершение
+	router.HandleFunc("/v1/admin/orders/claim", workQueueHandler.ClaimOrder).Methods("POST")
+	router.HandleFunc("/v1/admin/orders/{id}/release", workQueueHandler.ReleaseOrder).Methods("POST")
+	router.HandleFunc("/v1/admin/orders/{id}/complete", workQueueHandler.CompleteOrder).Methods("POST")
+
+	// Сводный сборочный лист склада по заказам (JSON, CSV или PDF) и докачка выгрузок
+	router.HandleFunc("/v1/admin/orders/picking-list", pickingListHandler.GetPickingList).Methods("GET")
+	router.HandleFunc("/v1/admin/orders/exports/{id}", exportHandler.DownloadExport).Methods("GET")
+
+	// Теги заказов и сводка по тегам
+	router.HandleFunc("/v1/admin/orders/tags", orderTagHandler.GetTagStats).Methods("GET")
+	router.HandleFunc("/v1/admin/orders/{id}/tags", orderTagHandler.UpdateOrderTags).Methods("PUT")
+
+	// Журнал проверок заказа перед созданием: помеченные и отклоненные заказы
+	router.HandleFunc("/v1/admin/orders/hook-decisions", orderHookHandler.ListHookDecisions).Methods("GET")
+	router.HandleFunc("/v1/admin/orders/{id}/hook-decisions", orderHookHandler.ListOrderHookDecisions).Methods("GET")
+
+	// Решение администратора по заказу, помеченному проверками (статус flagged)
+	router.HandleFunc("/v1/admin/orders/{id}/approve", workQueueHandler.ApproveOrder).Methods("POST")
+	router.HandleFunc("/v1/admin/orders/{id}/reject", workQueueHandler.RejectOrder).Methods("POST")
+
+	// Сохраненные наборы фильтров списка заказов администратора
+	router.HandleFunc("/v1/admin/order-filters", orderFilterHandler.ListOrderFilters).Methods("GET")
+	router.HandleFunc("/v1/admin/order-filters", orderFilterHandler.CreateOrderFilter).Methods("POST")
+	router.HandleFunc("/v1/admin/order-filters/{id}", orderFilterHandler.UpdateOrderFilter).Methods("PUT")
+	router.HandleFunc("/v1/admin/order-filters/{id}", orderFilterHandler.DeleteOrderFilter).Methods("DELETE")
+	router.HandleFunc("/v1/admin/order-filters/{id}/orders", orderFilterHandler.ListFilteredOrders).Methods("GET")
+
+	// Просмотр заказов для поддержки с маскированием персональных данных
+	router.Handle("/v1/support/orders/{id}", supportHandler.Redacted(supportHandler.GetSupportOrder)).Methods("GET")
+
+	// Администрирование обработчиков доменных событий
+	router.HandleFunc("/v1/admin/event-handlers", eventHandlersHandler.ListEventHandlers).Methods("GET")
+	router.HandleFunc("/v1/admin/event-handlers/{name}", eventHandlersHandler.UpdateEventHandler).Methods("PUT")
+	router.HandleFunc("/v1/admin/event-handlers/{name}/errors", eventHandlersHandler.ListEventHandlerErrors).Methods("GET")
+
+	// Дополнительный endpoint для статистики событий (для мониторинга)
+	router.HandleFunc("/v1/events/stats", func(w http.ResponseWriter, r *http.Request) {
+		stats := eventService.GetStats()
+
+		response := map[string]interface{}{
+			"success": true,
+			"data": map[string]interface{}{
+				"statistics":  stats,
+				"service":     "service_orders",
+				"timestamp":   time.Now().UTC().Format(time.RFC3339),
+				"description": "Статистика доменных событий",
+			},
+		}
+
+		if err := httpresp.JSON(w, http.StatusOK, response); err != nil {
+			zapLogger.Error("Failed to write JSON response", zap.Error(err))
+		}
+	}).Methods("GET")
+
+	// X-Request-ID, этапы обработки (Server-Timing), лог запросов и перехват panic внутри лога,
+	// чтобы ответ 500 был залогирован
+	s.handler = httpmw.NewChain(
+		httpmw.RequestID(httpmw.RequestIDConfig{}),
+		httpmw.ServerTiming(),
+		httpmw.Logging(httpmw.LoggingConfig{Log: logRequest}),
+		httpmw.Recovery(httpmw.RecoveryConfig{
+			Body:   mustMarshal(models.NewErrorResponse(models.ErrorCodeInternalServer, "Внутренняя ошибка сервера")),
+			Report: reportPanic,
+		}),
+	).Then(router)
+
+	return s, nil
+}
+
+// Handler возвращает обработчик запросов сервиса с middleware
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Start запускает фоновые задачи сервиса; задачи завершаются при отмене ctx
+func (s *Server) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go job(ctx)
+	}
+}
+
+// Close закрывает систему событий, дожидаясь обработки опубликованных событий
+func (s *Server) Close() error {
+	return s.events.Close()
+}
+
+// newAnomalyDetector создает детектор аномалий с правилами из конфигурации;
+// нулевые границы правил не проверяются
+func newAnomalyDetector(cfg config.AnomalyConfig, db *sql.DB, eventService *events.EventService) *analytics.Detector {
+	return analytics.NewDetector(analytics.NewSQLSource(db), eventService, analytics.Config{
+		Window:   cfg.Window,
+		Interval: cfg.Interval,
+		Cooldown: cfg.Cooldown,
+		Rules: []analytics.Rule{
+			{Metric: analytics.MetricOrderCreationRate, Min: cfg.OrderRateMin, Max: cfg.OrderRateMax},
+			{Metric: analytics.MetricCancellationRate, Max: cfg.CancellationRateMax, MinSamples: cfg.MinSamples},
+			{Metric: analytics.MetricLoginFailureRate, Max: cfg.LoginFailureRateMax, MinSamples: cfg.MinSamples},
+		},
+	})
+}
+
+// newCurrencyConverter создает пересчет сумм заказов в валюту отображения; nil, если
+// не заданы ни EXCHANGE_RATES_URL, ни EXCHANGE_RATES
+func newCurrencyConverter(cfg config.CurrencyConfig) *currency.Converter {
+	if !cfg.Enabled() {
+		return nil
+	}
+	var provider currency.Provider = currency.StaticProvider(cfg.Rates)
+	if cfg.RatesURL != "" {
+		provider = currency.NewHTTPProvider(cfg.RatesURL, cfg.FetchTimeout)
+	}
+	return currency.NewConverter(provider, cfg.Base, cfg.CacheTTL)
+}
+
+// newOrderHooks регистрирует проверки заказа перед созданием. Новые проверки (например,
+// внешний скоринг) добавляются здесь вызовом Register; порядок регистрации — порядок выполнения
+func newOrderHooks(cfg config.OrderHooksConfig, blacklist repository.BlacklistRepository) (*precreate.Chain, error) {
+	chain := precreate.NewChain(cfg.Timeout, cfg.OnError)
+	if err := chain.Register(precreate.NewBlacklist(blacklist)); err != nil {
+		return nil, err
+	}
+	if cfg.FlagTotalAbove > 0 || cfg.MaxTotal > 0 {
+		if err := chain.Register(precreate.NewTotalLimit(cfg.FlagTotalAbove, cfg.MaxTotal)); err != nil {
+			return nil, err
+		}
+	}
+	return chain, nil
+}
+
+// leaderRetryInterval период попытки захватить блокировку фоновой задачи, которую
+// выполняет другой экземпляр сервиса
+const leaderRetryInterval = 30 * time.Second
+
+// pruneExportsInterval период удаления выгрузок с истекшим сроком хранения
+const pruneExportsInterval = 10 * time.Minute
+
+// pruneExports удаляет выгрузки с истекшим сроком хранения, пока не будет отменен ctx
+func pruneExports(ctx context.Context, repo repository.ExportRepository) {
+	ticker := time.NewTicker(pruneExportsInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := repo.DeleteExpired(time.Now())
+		if err != nil {
+			logger.GetLogger().Warn("Ошибка удаления истекших выгрузок", zap.Error(err))
+		} else if deleted > 0 {
+			logger.GetLogger().Info("Удалены истекшие выгрузки", zap.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// logRequest пишет в лог итог обработки запроса
+func logRequest(r *http.Request, result httpmw.Result) {
+	// Используем структурированный логгер
+	logger.LogHTTPRequest(r, result.Status, r.Method, r.URL.Path, "service_orders")
+
+	// Дополнительные метрики
+	zapLogger := logger.WithRequestID(logger.GetLogger(), httpmw.RequestIDFromContext(r.Context()))
+	// Этапы обработки из Server-Timing: ожидание, обработчик, БД, публикация событий
+	timing := servertiming.FromContext(r.Context())
+	zapLogger.Info("Request completed",
+		zap.Duration("duration", result.Duration),
+		zap.Int64("content_length", r.ContentLength),
+		zap.Int64("bytes_out", result.BytesOut),
+		zap.Duration("wait_duration", timing.Get(servertiming.MetricWait)),
+		zap.Duration("app_duration", timing.Get(servertiming.MetricApp)),
+		zap.Duration("db_duration", timing.Get(servertiming.MetricDB)),
+		zap.Duration("publish_duration", timing.Get(servertiming.MetricPublish)),
+	)
+}
+
+// reportPanic пишет в лог panic обработчика со стеком и X-Request-ID;
+// клиент получает 500 в формате APIResponse
+func reportPanic(r *http.Request, value interface{}, stack []byte) {
+	zapLogger := logger.WithRequestID(logger.GetLogger(), httpmw.RequestIDFromContext(r.Context()))
+	zapLogger.Error("Panic при обработке запроса",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.Any("panic", value),
+		zap.ByteString("stack", stack),
+	)
+}
+
+// errorHandler отвечает на любой запрос ошибкой в формате APIResponse
+func errorHandler(status int, code, message string) http.Handler {
+	body := mustMarshal(models.NewErrorResponse(code, message))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpresp.Write(w, status, httpresp.ContentTypeJSON, body)
+	})
+}
+
+// mustMarshal сериализует постоянное тело ответа при запуске
+func mustMarshal(v interface{}) []byte {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
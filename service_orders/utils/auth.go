@@ -5,6 +5,8 @@ import (
 	"net/http"
 	"strings"
 
+	"pkg/rbac"
+
 	"github.com/google/uuid"
 )
 
@@ -13,6 +15,8 @@ type UserContext struct {
 	UserID uuid.UUID
 	Email  string
 	Roles  []string
+	// Permissions права ролей пользователя из заголовка X-User-Permissions
+	Permissions rbac.Set
 }
 
 // GetUserContextFromHeaders извлекает пользовательский контекст из заголовков HTTP
@@ -48,9 +52,10 @@ func GetUserContextFromHeaders(r *http.Request) (*UserContext, error) {
 	}
 
 	return &UserContext{
-		UserID: userID,
-		Email:  email,
-		Roles:  roles,
+		UserID:      userID,
+		Email:       email,
+		Roles:       roles,
+		Permissions: rbac.FromRequest(r),
 	}, nil
 }
 
@@ -64,15 +69,16 @@ func (uc *UserContext) HasRole(role string) bool {
 	return false
 }
 
-// IsAdmin проверяет, является ли пользователь администратором
-func (uc *UserContext) IsAdmin() bool {
-	return uc.HasRole("admin")
+// Can проверяет, есть ли у пользователя право permission
+func (uc *UserContext) Can(permission string) bool {
+	return uc.Permissions.Has(permission)
 }
 
-// ValidateOrderOwnership проверяет, может ли пользователь работать с заказом
-func (uc *UserContext) ValidateOrderOwnership(orderUserID uuid.UUID) error {
-	// Администратор может работать со всеми заказами
-	if uc.IsAdmin() {
+// ValidateOrderOwnership проверяет, может ли пользователь работать с заказом:
+// со своими заказами — всегда, с чужими — при наличии права permission
+func (uc *UserContext) ValidateOrderOwnership(orderUserID uuid.UUID, permission string) error {
+	// Пользователь с правом permission может работать со всеми заказами
+	if uc.Can(permission) {
 		return nil
 	}
 	
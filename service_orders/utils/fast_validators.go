@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"service_orders/models"
+)
+
+// fastValidate валидирует самые нагруженные типы запросов без рефлексии.
+// Правила повторяют теги validate соответствующих структур и возвращают те же
+// сообщения, что и ValidateStruct; при изменении тегов нужно обновить и эти функции.
+// Результат handled == false означает, что для типа нет быстрой валидации
+func fastValidate(s interface{}) (handled bool, err error) {
+	switch req := s.(type) {
+	case models.CreateOrderRequest:
+		return true, validateCreateOrderRequest(&req)
+	case *models.CreateOrderRequest:
+		if req == nil {
+			return false, nil
+		}
+		return true, validateCreateOrderRequest(req)
+	}
+	return false, nil
+}
+
+// validateCreateOrderRequest соответствует тегам CreateOrderRequest и OrderItem:
+// Items required,min=1,dive; Product required; Quantity required,min=1; Price required,min=0;
+// Region max=100
+func validateCreateOrderRequest(req *models.CreateOrderRequest) error {
+	var messages []string
+	switch {
+	case req.Items == nil:
+		messages = append(messages, fieldErrorMessage("Items", "required", ""))
+	case len(req.Items) < 1:
+		messages = append(messages, fieldErrorMessage("Items", "min", "1"))
+	}
+
+	for i := range req.Items {
+		item := &req.Items[i]
+
+		if item.Product == "" {
+			messages = append(messages, fieldErrorMessage("Product", "required", ""))
+		}
+
+		switch {
+		case item.Quantity == 0:
+			messages = append(messages, fieldErrorMessage("Quantity", "required", ""))
+		case item.Quantity < 1:
+			messages = append(messages, fieldErrorMessage("Quantity", "min", "1"))
+		}
+
+		switch {
+		case item.Price == 0:
+			messages = append(messages, fieldErrorMessage("Price", "required", ""))
+		case item.Price < 0:
+			messages = append(messages, fieldErrorMessage("Price", "min", "0"))
+		}
+	}
+
+	if utf8.RuneCountInString(req.Region) > 100 {
+		messages = append(messages, fieldErrorMessage("Region", "max", "100"))
+	}
+
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "; "))
+	}
+	return nil
+}
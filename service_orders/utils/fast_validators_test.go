@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"service_orders/models"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// reflectValidate валидирует s только по тегам validate, минуя fastValidate,
+// и форматирует ошибки так же, как ValidateStruct
+func reflectValidate(s interface{}) error {
+	err := Validator.Struct(s)
+	if err == nil {
+		return nil
+	}
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	var messages []string
+	for _, fe := range validationErrors {
+		messages = append(messages, getErrorMessage(fe))
+	}
+	return fmt.Errorf("%s", strings.Join(messages, "; "))
+}
+
+// createOrderRequests запросы на создание заказа на границах правил валидации
+var createOrderRequests = []struct {
+	name string
+	req  models.CreateOrderRequest
+}{
+	{"корректный", models.CreateOrderRequest{Items: []models.OrderItem{{Product: "book", Quantity: 1, Price: 10}}}},
+	{"несколько товаров", models.CreateOrderRequest{Items: []models.OrderItem{
+		{Product: "book", Quantity: 3, Price: 10.5}, {Product: "pen", Quantity: 1, Price: 0.01},
+	}, Region: "Москва"}},
+	{"без товаров", models.CreateOrderRequest{}},
+	{"пустой список товаров", models.CreateOrderRequest{Items: []models.OrderItem{}}},
+	{"без названия товара", models.CreateOrderRequest{Items: []models.OrderItem{{Quantity: 1, Price: 10}}}},
+	{"нулевое количество", models.CreateOrderRequest{Items: []models.OrderItem{{Product: "book", Price: 10}}}},
+	{"отрицательное количество", models.CreateOrderRequest{Items: []models.OrderItem{{Product: "book", Quantity: -1, Price: 10}}}},
+	{"нулевая цена", models.CreateOrderRequest{Items: []models.OrderItem{{Product: "book", Quantity: 1}}}},
+	{"отрицательная цена", models.CreateOrderRequest{Items: []models.OrderItem{{Product: "book", Quantity: 1, Price: -5}}}},
+	{"пустой товар", models.CreateOrderRequest{Items: []models.OrderItem{{}}}},
+	{"ошибки в нескольких товарах", models.CreateOrderRequest{Items: []models.OrderItem{
+		{Product: "book", Quantity: 0, Price: 10}, {Product: "", Quantity: 1, Price: -1},
+	}}},
+	{"регион 100 символов", models.CreateOrderRequest{
+		Items:  []models.OrderItem{{Product: "book", Quantity: 1, Price: 10}},
+		Region: strings.Repeat("я", 100),
+	}},
+	{"регион 101 символ", models.CreateOrderRequest{
+		Items:  []models.OrderItem{{Product: "book", Quantity: 1, Price: 10}},
+		Region: strings.Repeat("я", 101),
+	}},
+	{"все ошибки", models.CreateOrderRequest{
+		Items:  []models.OrderItem{{Quantity: -2, Price: -1}},
+		Region: strings.Repeat("r", 101),
+	}},
+}
+
+func TestFastValidateMatchesValidator(t *testing.T) {
+	for _, tt := range createOrderRequests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := reflectValidate(tt.req)
+
+			for _, input := range []interface{}{tt.req, &tt.req} {
+				handled, got := fastValidate(input)
+				if !handled {
+					t.Fatalf("%T не обработан fastValidate", input)
+				}
+				if (got == nil) != (want == nil) {
+					t.Fatalf("%T: fastValidate %v, validator %v", input, got, want)
+				}
+				if got != nil && got.Error() != want.Error() {
+					t.Errorf("%T: сообщение fastValidate %q, validator %q", input, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFastValidateSkipsOtherTypes(t *testing.T) {
+	var nilRequest *models.CreateOrderRequest
+	for _, input := range []interface{}{models.UpdateOrderStatusRequest{}, nilRequest} {
+		if handled, _ := fastValidate(input); handled {
+			t.Errorf("%T обработан fastValidate", input)
+		}
+	}
+}
+
+func BenchmarkValidateCreateOrderRequest(b *testing.B) {
+	req := models.CreateOrderRequest{
+		Items: []models.OrderItem{
+			{Product: "book", Quantity: 2, Price: 10.5},
+			{Product: "pen", Quantity: 10, Price: 1.2},
+			{Product: "notebook", Quantity: 1, Price: 4},
+		},
+		Region: "Москва",
+	}
+
+	b.Run("fast", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := ValidateStruct(&req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("reflect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := reflectValidate(&req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
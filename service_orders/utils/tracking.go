@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidTrackingToken токен отслеживания поврежден, подделан или истек
+var ErrInvalidTrackingToken = errors.New("недействительный токен отслеживания")
+
+// trackingTokenPayloadSize размер данных токена: ID заказа и срок действия в секундах Unix
+const trackingTokenPayloadSize = 16 + 8
+
+// GenerateTrackingToken создает подписанный токен для просмотра статуса заказа без входа.
+// Токен не хранится в БД: ID заказа и срок действия проверяются по HMAC подписи
+func GenerateTrackingToken(orderID uuid.UUID, expiresAt time.Time, secret string) string {
+	payload := make([]byte, trackingTokenPayloadSize)
+	copy(payload, orderID[:])
+	binary.BigEndian.PutUint64(payload[16:], uint64(expiresAt.Unix()))
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signTrackingPayload(payload, secret))
+}
+
+// ParseTrackingToken проверяет подпись и срок действия токена и возвращает ID заказа
+func ParseTrackingToken(token, secret string, now time.Time) (uuid.UUID, error) {
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, ErrInvalidTrackingToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != trackingTokenPayloadSize {
+		return uuid.Nil, ErrInvalidTrackingToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, signTrackingPayload(payload, secret)) {
+		return uuid.Nil, ErrInvalidTrackingToken
+	}
+
+	expiresAt := time.Unix(int64(binary.BigEndian.Uint64(payload[16:])), 0)
+	if !now.Before(expiresAt) {
+		return uuid.Nil, ErrInvalidTrackingToken
+	}
+
+	orderID, err := uuid.FromBytes(payload[:16])
+	if err != nil {
+		return uuid.Nil, ErrInvalidTrackingToken
+	}
+	return orderID, nil
+}
+
+// signTrackingPayload подписывает данные токена; префикс отделяет эти подписи
+// от других HMAC с тем же секретом
+func signTrackingPayload(payload []byte, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("order-tracking:"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -14,32 +14,47 @@ func init() {
 	Validator = validator.New()
 }
 
-// ValidateStruct валидирует структуру и возвращает читаемые ошибки
+// ValidateStruct валидирует структуру и возвращает читаемые ошибки.
+// Для самых нагруженных типов запросов используется валидация без рефлексии (fastValidate)
 func ValidateStruct(s interface{}) error {
+	if handled, err := fastValidate(s); handled {
+		return err
+	}
+
 	err := Validator.Struct(s)
 	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			return err
+		}
+
 		var errors []string
-		for _, err := range err.(validator.ValidationErrors) {
+		for _, err := range validationErrors {
 			errors = append(errors, getErrorMessage(err))
 		}
-		return fmt.Errorf(strings.Join(errors, "; "))
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
 	}
 	return nil
 }
 
 // getErrorMessage возвращает читаемое сообщение об ошибке валидации
 func getErrorMessage(fe validator.FieldError) string {
-	field := strings.ToLower(fe.Field())
+	return fieldErrorMessage(fe.Field(), fe.Tag(), fe.Param())
+}
+
+// fieldErrorMessage возвращает сообщение об ошибке правила tag для поля
+func fieldErrorMessage(field, tag, param string) string {
+	field = strings.ToLower(field)
 	
-	switch fe.Tag() {
+	switch tag {
 	case "required":
 		return fmt.Sprintf("поле '%s' обязательно для заполнения", field)
 	case "min":
-		return fmt.Sprintf("поле '%s' должно содержать минимум %s", field, fe.Param())
+		return fmt.Sprintf("поле '%s' должно содержать минимум %s", field, param)
 	case "max":
-		return fmt.Sprintf("поле '%s' должно содержать максимум %s", field, fe.Param())
+		return fmt.Sprintf("поле '%s' должно содержать максимум %s", field, param)
 	case "oneof":
-		return fmt.Sprintf("поле '%s' должно содержать одно из значений: %s", field, fe.Param())
+		return fmt.Sprintf("поле '%s' должно содержать одно из значений: %s", field, param)
 	case "dive":
 		return fmt.Sprintf("элементы массива '%s' содержат ошибки валидации", field)
 	default:
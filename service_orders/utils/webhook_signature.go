@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Ошибки проверки подписи входящего webhook
+var (
+	ErrMissingWebhookSignature = errors.New("отсутствует подпись запроса")
+	ErrInvalidWebhookSignature = errors.New("недействительная подпись запроса")
+	ErrExpiredWebhookSignature = errors.New("время подписи запроса вне допустимого интервала")
+)
+
+// webhookSignaturePrefix префикс значения заголовка подписи
+const webhookSignaturePrefix = "sha256="
+
+// SignWebhookPayload возвращает значение заголовка подписи для тела запроса:
+// "sha256=" и hex HMAC-SHA256 от "<timestamp>.<body>"
+func SignWebhookPayload(body []byte, timestamp int64, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature проверяет подпись входящего webhook. Время подписи входит в
+// подписанные данные и должно отличаться от now не более чем на tolerance, чтобы
+// перехваченный запрос нельзя было повторить позже
+func VerifyWebhookSignature(body []byte, timestamp, signature, secret string, now time.Time, tolerance time.Duration) error {
+	if timestamp == "" || signature == "" {
+		return ErrMissingWebhookSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidWebhookSignature
+	}
+
+	if !strings.HasPrefix(signature, webhookSignaturePrefix) ||
+		!hmac.Equal([]byte(signature), []byte(SignWebhookPayload(body, unix, secret))) {
+		return ErrInvalidWebhookSignature
+	}
+
+	skew := now.Sub(time.Unix(unix, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > tolerance {
+		return ErrExpiredWebhookSignature
+	}
+	return nil
+}
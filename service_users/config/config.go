@@ -2,15 +2,45 @@ package config
 
 import (
 	"fmt"
+	"net/mail"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"pkg/ids"
+	"pkg/jwtkeys"
+	"pkg/profile"
 )
 
 // Config содержит конфигурацию приложения
 type Config struct {
-	DB     DBConfig
-	Server ServerConfig
-	JWT    JWTConfig
+	DB           DBConfig
+	Server       ServerConfig
+	JWT          JWTConfig
+	Redis        RedisConfig
+	Directory    DirectoryConfig
+	Registration RegistrationConfig
+	Login        LoginConfig
+	Mail         MailConfig
+	// PasswordReset настройки сброса пароля по email
+	PasswordReset PasswordResetConfig
+	// EmailChange настройки подтверждения смены email
+	EmailChange EmailChangeConfig
+	// AccessReview настройки отчета о пересмотре доступа
+	AccessReview AccessReviewConfig
+	// OAuth настройки входа через Google и GitHub
+	OAuth OAuthConfig
+	// Lockout настройки временной блокировки входа после неудачных попыток
+	Lockout LockoutConfig
+	// Password политика паролей при регистрации, сбросе и смене пароля
+	Password PasswordPolicyConfig
+	// Export настройки выгрузок (отчет о пересмотре доступа в CSV)
+	Export ExportConfig
+	// Deletion настройки удаления данных пользователем
+	Deletion DeletionConfig
+	// APIKeys настройки персональных API ключей
+	APIKeys APIKeysConfig
 }
 
 // DBConfig содержит конфигурацию базы данных
@@ -20,16 +50,209 @@ type DBConfig struct {
 	Name     string
 	User     string
 	Password string
+	// IDStrategy стратегия идентификаторов новых записей: uuidv4, uuidv7 или ulid
+	IDStrategy string
 }
 
 // ServerConfig содержит конфигурацию сервера
 type ServerConfig struct {
 	Port string
+	// H2C принимать HTTP/2 без TLS (h2c) от API Gateway (PROXY_HTTP2=h2c)
+	H2C bool
+	// IdleTimeout время ожидания следующего запроса в keep-alive соединении. Должно быть
+	// больше PROXY_IDLE_CONN_TIMEOUT шлюза, чтобы сервис не закрывал соединение, которое
+	// шлюз считает свободным
+	IdleTimeout time.Duration
 }
 
 // JWTConfig содержит конфигурацию JWT
 type JWTConfig struct {
+	// Algorithm алгоритм подписи: HS256 (общий секрет) или RS256/ES256 (закрытый ключ,
+	// публичные ключи публикуются в /.well-known/jwks.json)
+	Algorithm string
+	// Secret текущий секрет подписи токенов (HS256)
 	Secret string
+	// PreviousSecrets предыдущие секреты, токены которых еще принимаются
+	// на время ротации JWT_SECRET
+	PreviousSecrets []string
+	// Keys набор секретов: подпись текущим, проверка текущим и предыдущими; nil для RS256/ES256
+	Keys *jwtkeys.Keyring
+	// PrivateKeyFile PEM файл текущего закрытого ключа (RS256/ES256)
+	PrivateKeyFile string
+	// PreviousKeyFiles PEM файлы предыдущих ключей, публикуемых в JWKS на время ротации
+	PreviousKeyFiles []string
+	// SigningKeys асимметричные ключи; nil для HS256
+	SigningKeys *jwtkeys.SigningKeys
+	// Signer ключ подписи выдаваемых токенов: Keys или SigningKeys
+	Signer jwtkeys.Signer
+	// AccessTTL время жизни access токена
+	AccessTTL time.Duration
+	// RefreshTTL время жизни refresh токена
+	RefreshTTL time.Duration
+}
+
+// RedisConfig содержит конфигурацию Redis для публикации эпох ролей.
+// Пустой Host отключает мгновенный отзыв токенов при изменении ролей
+type RedisConfig struct {
+	Host     string
+	Port     int
+	Password string
+	DB       int
+	Timeout  time.Duration
+}
+
+// DirectoryConfig содержит настройки синхронизации пользователей с внешним каталогом
+type DirectoryConfig struct {
+	// GroupRoles соответствие групп каталога (в нижнем регистре) ролям сервиса;
+	// все активные пользователи каталога получают роль user
+	GroupRoles map[string]string
+	// Source имя источника по умолчанию, с которым связываются учетные записи
+	Source string
+	// SyncURL адрес выгрузки каталога для периодической синхронизации (пусто — только через API)
+	SyncURL    string
+	SyncFormat string
+	// SyncToken bearer токен для запроса выгрузки
+	SyncToken    string
+	SyncInterval time.Duration
+	// DeactivateMissing деактивировать при периодической синхронизации связанных
+	// с источником пользователей, отсутствующих в выгрузке
+	DeactivateMissing bool
+}
+
+// RegistrationConfig содержит настройки проверки одноразовых почтовых доменов
+// при регистрации и смене email
+type RegistrationConfig struct {
+	BlockDisposable bool
+	// DisposableURL адрес актуального списка одноразовых доменов, дополняющего
+	// встроенный (пусто — только встроенный список)
+	DisposableURL   string
+	RefreshInterval time.Duration
+}
+
+// LoginConfig содержит настройки учета попыток входа для детектора аномалий service_orders
+type LoginConfig struct {
+	// AttemptsRetention срок хранения попыток входа (0 — попытки не сохраняются)
+	AttemptsRetention time.Duration
+}
+
+// LockoutConfig содержит настройки временной блокировки входа после неудачных попыток.
+// Счетчики учетной записи и IP адреса клиента ведутся независимо
+type LockoutConfig struct {
+	// MaxFailures неудачных входов учетной записи до ее блокировки (0 — не блокировать)
+	MaxFailures int
+	// IPMaxFailures неудачных входов с одного IP адреса до его блокировки (0 — не блокировать)
+	IPMaxFailures int
+	// Window неудачные входы старше окна не учитываются
+	Window time.Duration
+	// Duration длительность первой блокировки; каждая следующая подряд вдвое длиннее,
+	// но не более MaxDuration
+	Duration    time.Duration
+	MaxDuration time.Duration
+	// ResetAfter без неудачных входов дольше этого срока длительность блокировки
+	// возвращается к Duration, а счетчик удаляется
+	ResetAfter time.Duration
+}
+
+// Enabled проверяет, включена ли блокировка учетных записей или IP адресов
+func (l *LockoutConfig) Enabled() bool {
+	return l.MaxFailures > 0 || l.IPMaxFailures > 0
+}
+
+// PasswordPolicyConfig содержит настройки политики паролей
+type PasswordPolicyConfig struct {
+	// MinLength минимальная длина пароля в символах
+	MinLength int
+	// MaxLength максимальная длина пароля в байтах; bcrypt не принимает пароли длиннее 72 байт
+	MaxLength int
+	// MinClasses сколько видов символов (строчные и заглавные буквы, цифры, прочие символы)
+	// должен содержать пароль; 0 — не проверять
+	MinClasses int
+	// DenylistFile файл запрещенных паролей, дополняющий встроенный словарь
+	DenylistFile string
+	// BreachCheck проверять пароли по базе утекших паролей через k-anonymity API
+	BreachCheck   bool
+	BreachURL     string
+	BreachTimeout time.Duration
+}
+
+// MailConfig содержит настройки отправки писем. Пустой SMTPHost — письма
+// не отправляются, а пишутся в лог
+type MailConfig struct {
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	// From адрес отправителя
+	From string
+	// LogBody писать в лог текст писем при отправке в лог; в строгих профилях
+	// текст скрывается, так как содержит одноразовые ссылки
+	LogBody bool
+}
+
+// PasswordResetConfig содержит настройки сброса пароля
+type PasswordResetConfig struct {
+	// TokenTTL срок действия одноразового токена сброса
+	TokenTTL time.Duration
+	// URL шаблон ссылки в письме с подстановкой {token}; пусто — в письме только токен
+	URL string
+}
+
+// EmailChangeConfig содержит настройки подтверждения смены email
+type EmailChangeConfig struct {
+	// TokenTTL срок действия одноразового токена, отправленного на новый email
+	TokenTTL time.Duration
+	// URL шаблон ссылки в письме с подстановкой {token}; пусто — в письме только токен
+	URL string
+}
+
+// AccessReviewConfig содержит настройки отчета о пересмотре доступа
+type AccessReviewConfig struct {
+	// Roles привилегированные роли, пользователи с которыми попадают в отчет
+	Roles []string
+	// DormantAfter срок без входа, после которого привилегированный пользователь считается неактивным
+	DormantAfter time.Duration
+}
+
+// ExportConfig содержит настройки выгрузок
+type ExportConfig struct {
+	// TTL срок хранения сформированной выгрузки для повторного и частичного скачивания
+	TTL time.Duration
+}
+
+// DeletionConfig содержит настройки удаления своих данных пользователем
+type DeletionConfig struct {
+	// ConfirmationTTL срок действия токена подтверждения удаления (DELETE /v1/users/me)
+	ConfirmationTTL time.Duration
+}
+
+// APIKeysConfig содержит настройки персональных API ключей. API Gateway обменивает
+// ключ на короткоживущий access токен с областями ключа
+type APIKeysConfig struct {
+	// MaxPerUser активных ключей у одного пользователя
+	MaxPerUser int
+	// TokenTTL время жизни access токена, выданного по ключу: отозванный ключ
+	// перестает действовать не позже чем через TokenTTL
+	TokenTTL time.Duration
+}
+
+// OAuthConfig содержит настройки входа через внешних провайдеров OAuth2.
+// Провайдер включен, если задан его client ID
+type OAuthConfig struct {
+	GoogleClientID     string
+	GoogleClientSecret string
+	GitHubClientID     string
+	GitHubClientSecret string
+	// RedirectURL шаблон адреса возврата от провайдера с подстановкой {provider}
+	RedirectURL string
+	// StateTTL время на вход у провайдера: срок действия cookie со state и code verifier
+	StateTTL time.Duration
+	// CookieSecure флаг Secure cookie со state
+	CookieSecure bool
+}
+
+// Enabled проверяет, что включен хотя бы один провайдер
+func (o *OAuthConfig) Enabled() bool {
+	return o.GoogleClientID != "" || o.GitHubClientID != ""
 }
 
 // Load загружает конфигурацию из переменных окружения
@@ -48,22 +271,357 @@ func Load() (*Config, error) {
 	config.DB.Name = getEnv("DB_NAME", "system_control")
 	config.DB.User = getEnv("DB_USER", "postgres")
 	config.DB.Password = getEnv("DB_PASSWORD", "1234")
+	config.DB.IDStrategy = getEnv("ID_STRATEGY", ids.StrategyUUIDv4)
+	if _, err := ids.NewGenerator(config.DB.IDStrategy); err != nil {
+		return nil, fmt.Errorf("invalid ID_STRATEGY: %v", err)
+	}
 
 	// Конфигурация сервера
 	config.Server.Port = getEnv("SERVER_PORT", "8081")
+	h2c, err := strconv.ParseBool(getEnv("SERVER_H2C_ENABLED", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SERVER_H2C_ENABLED: %v", err)
+	}
+	config.Server.H2C = h2c
+	if config.Server.IdleTimeout, err = time.ParseDuration(getEnv("SERVER_IDLE_TIMEOUT", "120s")); err != nil {
+		return nil, fmt.Errorf("invalid SERVER_IDLE_TIMEOUT: %v", err)
+	}
+
+	// Конфигурация JWT: время жизни токенов по умолчанию из профиля окружения
+	env, err := profile.Get(getEnv("ENVIRONMENT", "development"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ENVIRONMENT: %v", err)
+	}
+
+	config.JWT.Algorithm = strings.ToUpper(getEnv("JWT_ALGORITHM", jwtkeys.AlgorithmHS256))
+	switch config.JWT.Algorithm {
+	case jwtkeys.AlgorithmHS256:
+		config.JWT.Secret = getEnv("JWT_SECRET", "your_secret_key")
+		if err := env.CheckSecret(config.JWT.Secret, "your_secret_key"); err != nil {
+			return nil, err
+		}
+		for _, secret := range strings.Split(getEnv("JWT_PREVIOUS_SECRETS", ""), ",") {
+			if secret = strings.TrimSpace(secret); secret == "" {
+				continue
+			}
+			if err := env.CheckSecret(secret, "your_secret_key"); err != nil {
+				return nil, fmt.Errorf("invalid JWT_PREVIOUS_SECRETS: %v", err)
+			}
+			config.JWT.PreviousSecrets = append(config.JWT.PreviousSecrets, secret)
+		}
+		if config.JWT.Keys, err = jwtkeys.New(config.JWT.Secret, config.JWT.PreviousSecrets); err != nil {
+			return nil, fmt.Errorf("invalid JWT_SECRET: %v", err)
+		}
+		config.JWT.Signer = config.JWT.Keys
+	case jwtkeys.AlgorithmRS256, jwtkeys.AlgorithmES256:
+		if config.JWT.SigningKeys, err = loadSigningKeys(&config.JWT); err != nil {
+			return nil, err
+		}
+		config.JWT.Signer = config.JWT.SigningKeys
+	default:
+		return nil, fmt.Errorf("invalid JWT_ALGORITHM: must be HS256, RS256 or ES256")
+	}
+
+	accessTTL, err := time.ParseDuration(getEnv("JWT_ACCESS_TTL", env.AccessTokenTTL.String()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_ACCESS_TTL: %v", err)
+	}
+	config.JWT.AccessTTL = accessTTL
+
+	refreshTTL, err := time.ParseDuration(getEnv("JWT_REFRESH_TTL", env.RefreshTokenTTL.String()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_REFRESH_TTL: %v", err)
+	}
+	config.JWT.RefreshTTL = refreshTTL
+
+	if err := env.CheckTokenTTL(accessTTL, refreshTTL); err != nil {
+		return nil, err
+	}
+
+	// Конфигурация Redis
+	config.Redis.Host = getEnv("REDIS_HOST", "")
+	config.Redis.Password = getEnv("REDIS_PASSWORD", "")
+
+	if config.Redis.Port, err = strconv.Atoi(getEnv("REDIS_PORT", "6379")); err != nil {
+		return nil, fmt.Errorf("invalid REDIS_PORT: %v", err)
+	}
+	if config.Redis.DB, err = strconv.Atoi(getEnv("REDIS_DB", "0")); err != nil {
+		return nil, fmt.Errorf("invalid REDIS_DB: %v", err)
+	}
+	if config.Redis.Timeout, err = time.ParseDuration(getEnv("REDIS_TIMEOUT", "200ms")); err != nil {
+		return nil, fmt.Errorf("invalid REDIS_TIMEOUT: %v", err)
+	}
+
+	// Конфигурация синхронизации с внешним каталогом
+	if config.Directory.GroupRoles, err = parseGroupRoles(getEnv("DIRECTORY_GROUP_ROLES", "")); err != nil {
+		return nil, fmt.Errorf("invalid DIRECTORY_GROUP_ROLES: %v", err)
+	}
+	config.Directory.Source = getEnv("DIRECTORY_SOURCE", "directory")
+	config.Directory.SyncURL = getEnv("DIRECTORY_SYNC_URL", "")
+	config.Directory.SyncFormat = strings.ToLower(getEnv("DIRECTORY_SYNC_FORMAT", "scim"))
+	if config.Directory.SyncFormat != "scim" && config.Directory.SyncFormat != "ldap" {
+		return nil, fmt.Errorf("invalid DIRECTORY_SYNC_FORMAT: must be scim or ldap")
+	}
+	config.Directory.SyncToken = getEnv("DIRECTORY_SYNC_TOKEN", "")
+	if config.Directory.SyncInterval, err = time.ParseDuration(getEnv("DIRECTORY_SYNC_INTERVAL", "1h")); err != nil {
+		return nil, fmt.Errorf("invalid DIRECTORY_SYNC_INTERVAL: %v", err)
+	}
+	if config.Directory.SyncURL != "" && config.Directory.SyncInterval <= 0 {
+		return nil, fmt.Errorf("invalid DIRECTORY_SYNC_INTERVAL: must be positive")
+	}
+	if config.Directory.DeactivateMissing, err = strconv.ParseBool(getEnv("DIRECTORY_SYNC_DEACTIVATE_MISSING", "true")); err != nil {
+		return nil, fmt.Errorf("invalid DIRECTORY_SYNC_DEACTIVATE_MISSING: %v", err)
+	}
+
+	// Политика доменов email при регистрации
+	if config.Registration.BlockDisposable, err = strconv.ParseBool(getEnv("BLOCK_DISPOSABLE_EMAILS", "true")); err != nil {
+		return nil, fmt.Errorf("invalid BLOCK_DISPOSABLE_EMAILS: %v", err)
+	}
+	config.Registration.DisposableURL = getEnv("DISPOSABLE_DOMAINS_URL", "")
+	if config.Registration.RefreshInterval, err = time.ParseDuration(getEnv("DISPOSABLE_DOMAINS_REFRESH_INTERVAL", "24h")); err != nil {
+		return nil, fmt.Errorf("invalid DISPOSABLE_DOMAINS_REFRESH_INTERVAL: %v", err)
+	}
+	if config.Registration.DisposableURL != "" && config.Registration.RefreshInterval <= 0 {
+		return nil, fmt.Errorf("invalid DISPOSABLE_DOMAINS_REFRESH_INTERVAL: must be positive")
+	}
+
+	// Учет попыток входа
+	if config.Login.AttemptsRetention, err = time.ParseDuration(getEnv("LOGIN_ATTEMPTS_RETENTION", "168h")); err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_ATTEMPTS_RETENTION: %v", err)
+	}
+	if config.Login.AttemptsRetention < 0 {
+		return nil, fmt.Errorf("invalid LOGIN_ATTEMPTS_RETENTION: must not be negative")
+	}
+
+	// Блокировка входа после неудачных попыток
+	if config.Lockout.MaxFailures, err = strconv.Atoi(getEnv("LOGIN_LOCKOUT_MAX_FAILURES", "5")); err != nil || config.Lockout.MaxFailures < 0 {
+		return nil, fmt.Errorf("invalid LOGIN_LOCKOUT_MAX_FAILURES: must be a non-negative integer")
+	}
+	if config.Lockout.IPMaxFailures, err = strconv.Atoi(getEnv("LOGIN_LOCKOUT_IP_MAX_FAILURES", "50")); err != nil || config.Lockout.IPMaxFailures < 0 {
+		return nil, fmt.Errorf("invalid LOGIN_LOCKOUT_IP_MAX_FAILURES: must be a non-negative integer")
+	}
+	lockoutDurations := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"LOGIN_LOCKOUT_WINDOW", "15m", &config.Lockout.Window},
+		{"LOGIN_LOCKOUT_DURATION", "1m", &config.Lockout.Duration},
+		{"LOGIN_LOCKOUT_MAX_DURATION", "1h", &config.Lockout.MaxDuration},
+		{"LOGIN_LOCKOUT_RESET_AFTER", "24h", &config.Lockout.ResetAfter},
+	}
+	for _, d := range lockoutDurations {
+		if *d.dest, err = time.ParseDuration(getEnv(d.name, d.value)); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", d.name, err)
+		}
+		if *d.dest <= 0 {
+			return nil, fmt.Errorf("invalid %s: must be positive", d.name)
+		}
+	}
+	if config.Lockout.MaxDuration < config.Lockout.Duration {
+		return nil, fmt.Errorf("invalid LOGIN_LOCKOUT_MAX_DURATION: must not be less than LOGIN_LOCKOUT_DURATION")
+	}
+
+	// Политика паролей; в строгих профилях пароли по умолчанию проверяются по базе утечек
+	if config.Password.MinLength, err = strconv.Atoi(getEnv("PASSWORD_MIN_LENGTH", "8")); err != nil || config.Password.MinLength < 1 {
+		return nil, fmt.Errorf("invalid PASSWORD_MIN_LENGTH: must be a positive integer")
+	}
+	if config.Password.MaxLength, err = strconv.Atoi(getEnv("PASSWORD_MAX_LENGTH", "72")); err != nil || config.Password.MaxLength < config.Password.MinLength || config.Password.MaxLength > 72 {
+		return nil, fmt.Errorf("invalid PASSWORD_MAX_LENGTH: must be between PASSWORD_MIN_LENGTH and 72")
+	}
+	if config.Password.MinClasses, err = strconv.Atoi(getEnv("PASSWORD_MIN_CHAR_CLASSES", "2")); err != nil || config.Password.MinClasses < 0 || config.Password.MinClasses > 4 {
+		return nil, fmt.Errorf("invalid PASSWORD_MIN_CHAR_CLASSES: must be between 0 and 4")
+	}
+	config.Password.DenylistFile = getEnv("PASSWORD_DENYLIST_FILE", "")
+	if config.Password.BreachCheck, err = strconv.ParseBool(getEnv("PASSWORD_BREACH_CHECK", strconv.FormatBool(env.Strict))); err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_BREACH_CHECK: %v", err)
+	}
+	config.Password.BreachURL = getEnv("PASSWORD_BREACH_API_URL", "https://api.pwnedpasswords.com/range/")
+	if config.Password.BreachTimeout, err = time.ParseDuration(getEnv("PASSWORD_BREACH_TIMEOUT", "2s")); err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_BREACH_TIMEOUT: %v", err)
+	}
+	if config.Password.BreachTimeout <= 0 {
+		return nil, fmt.Errorf("invalid PASSWORD_BREACH_TIMEOUT: must be positive")
+	}
+
+	// Отправка писем
+	config.Mail.SMTPHost = getEnv("SMTP_HOST", "")
+	if config.Mail.SMTPPort, err = strconv.Atoi(getEnv("SMTP_PORT", "587")); err != nil {
+		return nil, fmt.Errorf("invalid SMTP_PORT: %v", err)
+	}
+	config.Mail.SMTPUsername = getEnv("SMTP_USERNAME", "")
+	config.Mail.SMTPPassword = getEnv("SMTP_PASSWORD", "")
+	config.Mail.From = getEnv("MAIL_FROM", "no-reply@system-control.local")
+	if _, err := mail.ParseAddress(config.Mail.From); err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM: %v", err)
+	}
+	config.Mail.LogBody = !env.Strict
+
+	// Сброс пароля
+	if config.PasswordReset.TokenTTL, err = time.ParseDuration(getEnv("PASSWORD_RESET_TOKEN_TTL", "30m")); err != nil {
+		return nil, fmt.Errorf("invalid PASSWORD_RESET_TOKEN_TTL: %v", err)
+	}
+	if config.PasswordReset.TokenTTL <= 0 {
+		return nil, fmt.Errorf("invalid PASSWORD_RESET_TOKEN_TTL: must be positive")
+	}
+	config.PasswordReset.URL = getEnv("PASSWORD_RESET_URL", "")
+	if config.PasswordReset.URL != "" && !strings.Contains(config.PasswordReset.URL, "{token}") {
+		return nil, fmt.Errorf("invalid PASSWORD_RESET_URL: must contain {token}")
+	}
+
+	// Подтверждение смены email
+	if config.EmailChange.TokenTTL, err = time.ParseDuration(getEnv("EMAIL_CHANGE_TOKEN_TTL", "24h")); err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_CHANGE_TOKEN_TTL: %v", err)
+	}
+	if config.EmailChange.TokenTTL <= 0 {
+		return nil, fmt.Errorf("invalid EMAIL_CHANGE_TOKEN_TTL: must be positive")
+	}
+	config.EmailChange.URL = getEnv("EMAIL_CHANGE_URL", "")
+	if config.EmailChange.URL != "" && !strings.Contains(config.EmailChange.URL, "{token}") {
+		return nil, fmt.Errorf("invalid EMAIL_CHANGE_URL: must contain {token}")
+	}
+
+	// Отчет о пересмотре доступа
+	for _, role := range strings.Split(getEnv("ACCESS_REVIEW_ROLES", "admin"), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			config.AccessReview.Roles = append(config.AccessReview.Roles, role)
+		}
+	}
+	if len(config.AccessReview.Roles) == 0 {
+		return nil, fmt.Errorf("invalid ACCESS_REVIEW_ROLES: must not be empty")
+	}
+	if config.AccessReview.DormantAfter, err = time.ParseDuration(getEnv("ACCESS_REVIEW_DORMANT_AFTER", "2160h")); err != nil {
+		return nil, fmt.Errorf("invalid ACCESS_REVIEW_DORMANT_AFTER: %v", err)
+	}
+	if config.AccessReview.DormantAfter <= 0 {
+		return nil, fmt.Errorf("invalid ACCESS_REVIEW_DORMANT_AFTER: must be positive")
+	}
 
-	// Конфигурация JWT
-	config.JWT.Secret = getEnv("JWT_SECRET", "your_secret_key")
+	// Выгрузки
+	if config.Export.TTL, err = time.ParseDuration(getEnv("EXPORT_TTL", "1h")); err != nil {
+		return nil, fmt.Errorf("invalid EXPORT_TTL: %v", err)
+	}
+	if config.Export.TTL <= 0 {
+		return nil, fmt.Errorf("invalid EXPORT_TTL: must be positive")
+	}
+
+	// Удаление данных
+	if config.Deletion.ConfirmationTTL, err = time.ParseDuration(getEnv("USER_DELETION_CONFIRMATION_TTL", "15m")); err != nil {
+		return nil, fmt.Errorf("invalid USER_DELETION_CONFIRMATION_TTL: %v", err)
+	}
+	if config.Deletion.ConfirmationTTL <= 0 {
+		return nil, fmt.Errorf("invalid USER_DELETION_CONFIRMATION_TTL: must be positive")
+	}
+
+	// Персональные API ключи
+	if config.APIKeys.MaxPerUser, err = strconv.Atoi(getEnv("API_KEYS_MAX_PER_USER", "10")); err != nil || config.APIKeys.MaxPerUser < 1 {
+		return nil, fmt.Errorf("invalid API_KEYS_MAX_PER_USER: must be a positive integer")
+	}
+	if config.APIKeys.TokenTTL, err = time.ParseDuration(getEnv("API_KEY_TOKEN_TTL", "5m")); err != nil {
+		return nil, fmt.Errorf("invalid API_KEY_TOKEN_TTL: %v", err)
+	}
+	if config.APIKeys.TokenTTL <= 0 {
+		return nil, fmt.Errorf("invalid API_KEY_TOKEN_TTL: must be positive")
+	}
+
+	// Вход через внешних провайдеров
+	config.OAuth.GoogleClientID = getEnv("OAUTH_GOOGLE_CLIENT_ID", "")
+	config.OAuth.GoogleClientSecret = getEnv("OAUTH_GOOGLE_CLIENT_SECRET", "")
+	if config.OAuth.GoogleClientID != "" && config.OAuth.GoogleClientSecret == "" {
+		return nil, fmt.Errorf("invalid OAUTH_GOOGLE_CLIENT_SECRET: required with OAUTH_GOOGLE_CLIENT_ID")
+	}
+	config.OAuth.GitHubClientID = getEnv("OAUTH_GITHUB_CLIENT_ID", "")
+	config.OAuth.GitHubClientSecret = getEnv("OAUTH_GITHUB_CLIENT_SECRET", "")
+	if config.OAuth.GitHubClientID != "" && config.OAuth.GitHubClientSecret == "" {
+		return nil, fmt.Errorf("invalid OAUTH_GITHUB_CLIENT_SECRET: required with OAUTH_GITHUB_CLIENT_ID")
+	}
+	config.OAuth.RedirectURL = getEnv("OAUTH_REDIRECT_URL", "")
+	if config.OAuth.Enabled() && !strings.Contains(config.OAuth.RedirectURL, "{provider}") {
+		return nil, fmt.Errorf("invalid OAUTH_REDIRECT_URL: must contain {provider}")
+	}
+	if config.OAuth.StateTTL, err = time.ParseDuration(getEnv("OAUTH_STATE_TTL", "10m")); err != nil {
+		return nil, fmt.Errorf("invalid OAUTH_STATE_TTL: %v", err)
+	}
+	if config.OAuth.StateTTL <= 0 {
+		return nil, fmt.Errorf("invalid OAUTH_STATE_TTL: must be positive")
+	}
+	if config.OAuth.CookieSecure, err = strconv.ParseBool(getEnv("OAUTH_COOKIE_SECURE", "true")); err != nil {
+		return nil, fmt.Errorf("invalid OAUTH_COOKIE_SECURE: %v", err)
+	}
+	if config.OAuth.Enabled() {
+		if err := env.CheckCookieSecure(config.OAuth.CookieSecure); err != nil {
+			return nil, fmt.Errorf("invalid OAUTH_COOKIE_SECURE: %v", err)
+		}
+	}
 
 	return config, nil
 }
 
-// DSN возвращает строку подключения к PostgreSQL
+// DSN возвращает строку подключения к PostgreSQL. Сессия работает в UTC, чтобы
+// метки времени читались из БД без смещения часового пояса сервера
 func (db *DBConfig) DSN() string {
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable timezone=UTC",
 		db.Host, db.Port, db.User, db.Password, db.Name)
 }
 
+// Addr возвращает адрес Redis в формате host:port
+func (r *RedisConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", r.Host, r.Port)
+}
+
+// loadSigningKeys читает закрытый ключ JWT_PRIVATE_KEY_FILE и предыдущие ключи
+// JWT_PREVIOUS_KEY_FILES для подписи RS256/ES256
+func loadSigningKeys(cfg *JWTConfig) (*jwtkeys.SigningKeys, error) {
+	cfg.PrivateKeyFile = getEnv("JWT_PRIVATE_KEY_FILE", "")
+	if cfg.PrivateKeyFile == "" {
+		return nil, fmt.Errorf("invalid JWT_PRIVATE_KEY_FILE: required for JWT_ALGORITHM %s", cfg.Algorithm)
+	}
+	current, err := os.ReadFile(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_PRIVATE_KEY_FILE: %v", err)
+	}
+
+	var previous [][]byte
+	for _, path := range strings.Split(getEnv("JWT_PREVIOUS_KEY_FILES", ""), ",") {
+		if path = strings.TrimSpace(path); path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_PREVIOUS_KEY_FILES: %v", err)
+		}
+		cfg.PreviousKeyFiles = append(cfg.PreviousKeyFiles, path)
+		previous = append(previous, data)
+	}
+
+	keys, err := jwtkeys.NewSigningKeys(cfg.Algorithm, current, previous)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_PRIVATE_KEY_FILE or JWT_PREVIOUS_KEY_FILES: %v", err)
+	}
+	return keys, nil
+}
+
+// parseGroupRoles разбирает соответствие групп каталога ролям вида "admins:admin,staff:user"
+func parseGroupRoles(spec string) (map[string]string, error) {
+	groupRoles := make(map[string]string)
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.LastIndex(pair, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("expected group:role, got %q", pair)
+		}
+		group, role := strings.ToLower(strings.TrimSpace(pair[:i])), strings.TrimSpace(pair[i+1:])
+		if role != "user" && role != "admin" {
+			return nil, fmt.Errorf("unknown role %q for group %q", role, group)
+		}
+		groupRoles[group] = role
+	}
+	return groupRoles, nil
+}
+
 // getEnv возвращает значение переменной окружения или значение по умолчанию
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -0,0 +1,120 @@
+// Package deletion выполняет асинхронные операции удаления данных пользователей
+// (право на забвение). Операция сохраняется в user_deletions при запросе и выполняется
+// фоновым обработчиком, поэтому переживает перезапуск сервиса и видна в статусе
+package deletion
+
+import (
+	"context"
+	"time"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/repository"
+
+	"pkg/rolesepoch"
+
+	"go.uber.org/zap"
+)
+
+// pollInterval интервал проверки ожидающих операций, если о новых операциях не было уведомления
+// (например, операция создана другим экземпляром сервиса)
+const pollInterval = 30 * time.Second
+
+// Worker выполняет ожидающие операции удаления
+type Worker struct {
+	repo repository.UserDeletionRepository
+	// epochs nil, если Redis не настроен: тогда access токены удаленного пользователя
+	// действуют до истечения срока, а refresh токены отзываются сразу
+	epochs *rolesepoch.Store
+	wakeup chan struct{}
+}
+
+// NewWorker создает обработчик операций удаления
+func NewWorker(repo repository.UserDeletionRepository, epochs *rolesepoch.Store) *Worker {
+	return &Worker{
+		repo:   repo,
+		epochs: epochs,
+		wakeup: make(chan struct{}, 1),
+	}
+}
+
+// Notify сообщает о новой операции, чтобы она была выполнена без ожидания pollInterval
+func (w *Worker) Notify() {
+	select {
+	case w.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Run выполняет ожидающие операции до отмены контекста
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for w.runOnce(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wakeup:
+		}
+	}
+}
+
+// runOnce выполняет одну ожидающую операцию; false, если выполнять нечего
+func (w *Worker) runOnce(ctx context.Context) bool {
+	zapLogger := logger.GetLogger()
+
+	deletion, err := w.repo.ClaimNext()
+	if err != nil {
+		zapLogger.Error("Ошибка получения операции удаления", zap.Error(err))
+		return false
+	}
+	if deletion == nil {
+		return false
+	}
+
+	zapLogger = zapLogger.With(
+		zap.String("deletion_id", deletion.ID.String()),
+		zap.String("user_id", deletion.UserID.String()),
+	)
+
+	summary, err := w.repo.Execute(deletion)
+	if err != nil {
+		zapLogger.Error("Ошибка удаления данных пользователя", zap.Int("attempt", deletion.Attempts), zap.Error(err))
+		if err := w.repo.MarkFailed(deletion.ID, err.Error()); err != nil {
+			zapLogger.Error("Не удалось сохранить ошибку операции удаления", zap.Error(err))
+		}
+		return true
+	}
+
+	w.publishRolesEpoch(ctx, deletion, summary, zapLogger)
+
+	zapLogger.Info("Данные пользователя удалены",
+		zap.Int64("refresh_tokens_revoked", summary.RefreshTokensRevoked),
+		zap.Int64("notification_preferences_deleted", summary.NotificationPreferencesDeleted),
+		zap.Int64("api_keys_deleted", summary.APIKeysDeleted),
+		zap.Int64("login_attempts_anonymized", summary.LoginAttemptsAnonymized),
+		zap.Int64("deliveries_tombstoned", summary.DeliveriesTombstoned),
+		zap.Int64("deliveries_discarded", summary.DeliveriesDiscarded),
+		zap.Int64("orders_retained", summary.OrdersRetained),
+	)
+	return true
+}
+
+// publishRolesEpoch публикует новую эпоху ролей, чтобы API Gateway перестал принимать
+// access токены удаленного пользователя. Ошибка Redis не отменяет удаление
+func (w *Worker) publishRolesEpoch(ctx context.Context, deletion *models.UserDeletion, summary *models.UserDeletionSummary, zapLogger *zap.Logger) {
+	if w.epochs == nil {
+		return
+	}
+
+	if err := w.epochs.Publish(ctx, deletion.UserID.String(), summary.RolesEpoch); err != nil {
+		zapLogger.Error("Не удалось опубликовать эпоху ролей, активные токены не отозваны",
+			zap.Int64("roles_epoch", summary.RolesEpoch),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,132 @@
+package directory
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"service_users/logger"
+	"service_users/models"
+
+	"go.uber.org/zap"
+)
+
+// MaxPayloadSize максимальный размер выгрузки каталога
+const MaxPayloadSize = 10 << 20
+
+// MaxEntries максимальное число учетных записей в одной выгрузке
+const MaxEntries = 10000
+
+// JobConfig параметры периодической синхронизации
+type JobConfig struct {
+	URL    string
+	Format string
+	// Token bearer токен для запроса выгрузки (пусто — без авторизации)
+	Token             string
+	Source            string
+	Interval          time.Duration
+	DeactivateMissing bool
+}
+
+// Job периодически загружает выгрузку каталога и применяет изменения
+type Job struct {
+	syncer *Syncer
+	config JobConfig
+	client *http.Client
+}
+
+// NewJob создает задачу периодической синхронизации
+func NewJob(syncer *Syncer, config JobConfig) *Job {
+	return &Job{
+		syncer: syncer,
+		config: config,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run синхронизирует каталог сразу и затем с заданным интервалом до отмены контекста
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		j.runOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce выполняет одну синхронизацию и пишет итог в лог
+func (j *Job) runOnce(ctx context.Context) {
+	zapLogger := logger.GetLogger().With(zap.String("source", j.config.Source))
+
+	entries, err := j.fetch(ctx)
+	if err != nil {
+		zapLogger.Error("Не удалось загрузить выгрузку каталога", zap.Error(err))
+		return
+	}
+
+	report, err := j.syncer.Sync(ctx, j.config.Source, entries, Options{DeactivateMissing: j.config.DeactivateMissing})
+	if err != nil {
+		zapLogger.Error("Ошибка синхронизации с каталогом", zap.Error(err))
+		return
+	}
+
+	zapLogger.Info("Синхронизация с каталогом завершена",
+		zap.Int("created", len(report.Created)),
+		zap.Int("updated", len(report.Updated)),
+		zap.Int("deactivated", len(report.Deactivated)),
+		zap.Int("unchanged", report.Unchanged),
+		zap.Int("errors", len(report.Errors)),
+	)
+	for _, syncErr := range report.Errors {
+		zapLogger.Warn("Запись каталога не синхронизирована",
+			zap.String("external_id", syncErr.ExternalID),
+			zap.String("email", syncErr.Email),
+			zap.String("error", syncErr.Error),
+		)
+	}
+}
+
+// fetch загружает и разбирает выгрузку каталога
+func (j *Job) fetch(ctx context.Context) ([]models.DirectoryUser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.config.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный адрес выгрузки: %v", err)
+	}
+	if j.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+j.config.Token)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса выгрузки: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("каталог вернул статус %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxPayloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения выгрузки: %v", err)
+	}
+	if len(body) > MaxPayloadSize {
+		return nil, fmt.Errorf("выгрузка больше %d байт", MaxPayloadSize)
+	}
+
+	entries, err := Parse(j.config.Format, body)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > MaxEntries {
+		return nil, fmt.Errorf("выгрузка содержит больше %d учетных записей", MaxEntries)
+	}
+	return entries, nil
+}
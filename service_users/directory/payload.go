@@ -0,0 +1,191 @@
+// Package directory синхронизирует пользователей и роли с внешним каталогом
+// (выгрузки SCIM 2.0 или LDAP): создает, обновляет и деактивирует учетные записи
+package directory
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"service_users/models"
+)
+
+// Форматы выгрузки каталога
+const (
+	FormatSCIM = "scim"
+	FormatLDAP = "ldap"
+)
+
+// Parse разбирает выгрузку каталога в указанном формате
+func Parse(format string, body []byte) ([]models.DirectoryUser, error) {
+	switch format {
+	case FormatSCIM:
+		return ParseSCIM(body)
+	case FormatLDAP:
+		return ParseLDAP(body)
+	default:
+		return nil, fmt.Errorf("неизвестный формат каталога: %s", format)
+	}
+}
+
+// scimListResponse ответ SCIM 2.0 ListResponse (RFC 7644)
+type scimListResponse struct {
+	Resources []scimUser `json:"Resources"`
+}
+
+// scimUser ресурс SCIM User (RFC 7643)
+type scimUser struct {
+	ID          string `json:"id"`
+	ExternalID  string `json:"externalId"`
+	UserName    string `json:"userName"`
+	DisplayName string `json:"displayName"`
+	Name        struct {
+		Formatted  string `json:"formatted"`
+		GivenName  string `json:"givenName"`
+		FamilyName string `json:"familyName"`
+	} `json:"name"`
+	Emails []struct {
+		Value   string `json:"value"`
+		Primary bool   `json:"primary"`
+	} `json:"emails"`
+	Active *bool `json:"active"`
+	Groups []struct {
+		Value   string `json:"value"`
+		Display string `json:"display"`
+	} `json:"groups"`
+}
+
+// ParseSCIM разбирает SCIM 2.0 ListResponse с ресурсами User
+func ParseSCIM(body []byte) ([]models.DirectoryUser, error) {
+	var response scimListResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("некорректный SCIM ListResponse: %v", err)
+	}
+
+	users := make([]models.DirectoryUser, 0, len(response.Resources))
+	for _, resource := range response.Resources {
+		user := models.DirectoryUser{
+			ExternalID: firstNonEmpty(resource.ExternalID, resource.ID),
+			Name: firstNonEmpty(resource.DisplayName, resource.Name.Formatted,
+				strings.TrimSpace(resource.Name.GivenName+" "+resource.Name.FamilyName), resource.UserName),
+			Active: resource.Active == nil || *resource.Active,
+		}
+
+		for _, email := range resource.Emails {
+			if email.Primary || user.Email == "" {
+				user.Email = email.Value
+			}
+		}
+		if user.Email == "" && strings.Contains(resource.UserName, "@") {
+			user.Email = resource.UserName
+		}
+
+		for _, group := range resource.Groups {
+			if name := firstNonEmpty(group.Display, group.Value); name != "" {
+				user.Groups = append(user.Groups, name)
+			}
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// ldapExport выгрузка LDAP в JSON: записи с DN и атрибутами, значения которых —
+// строка или список строк (формат ldap3 response_to_json)
+type ldapExport struct {
+	Entries []struct {
+		DN         string                     `json:"dn"`
+		Attributes map[string]json.RawMessage `json:"attributes"`
+	} `json:"entries"`
+}
+
+// accountDisable флаг ACCOUNTDISABLE атрибута userAccountControl Active Directory
+const accountDisable = 0x2
+
+// ParseLDAP разбирает JSON выгрузку записей LDAP (OpenLDAP, 389 DS или Active Directory)
+func ParseLDAP(body []byte) ([]models.DirectoryUser, error) {
+	var export ldapExport
+	if err := json.Unmarshal(body, &export); err != nil {
+		return nil, fmt.Errorf("некорректная выгрузка LDAP: %v", err)
+	}
+
+	users := make([]models.DirectoryUser, 0, len(export.Entries))
+	for _, entry := range export.Entries {
+		attrs := make(map[string][]string, len(entry.Attributes))
+		for name, raw := range entry.Attributes {
+			values, err := ldapValues(raw)
+			if err != nil {
+				return nil, fmt.Errorf("некорректный атрибут %s записи %s: %v", name, entry.DN, err)
+			}
+			// Имена атрибутов LDAP регистронезависимы
+			attrs[strings.ToLower(name)] = values
+		}
+		first := func(name string) string {
+			if values := attrs[strings.ToLower(name)]; len(values) > 0 {
+				return values[0]
+			}
+			return ""
+		}
+
+		user := models.DirectoryUser{
+			ExternalID: firstNonEmpty(first("entryUUID"), first("objectGUID"), entry.DN),
+			Email:      first("mail"),
+			Name:       firstNonEmpty(first("displayName"), first("cn"), first("uid")),
+			Active:     !strings.EqualFold(first("nsAccountLock"), "true"),
+		}
+		if uac, err := strconv.ParseInt(first("userAccountControl"), 10, 64); err == nil && uac&accountDisable != 0 {
+			user.Active = false
+		}
+		for _, dn := range attrs["memberof"] {
+			if name := groupName(dn); name != "" {
+				user.Groups = append(user.Groups, name)
+			}
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// ldapValues разбирает значение атрибута: строку, число или список
+func ldapValues(raw json.RawMessage) ([]string, error) {
+	var list []interface{}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var single interface{}
+		if err := json.Unmarshal(raw, &single); err != nil {
+			return nil, err
+		}
+		list = []interface{}{single}
+	}
+
+	values := make([]string, 0, len(list))
+	for _, value := range list {
+		switch v := value.(type) {
+		case string:
+			values = append(values, v)
+		case float64, bool:
+			values = append(values, fmt.Sprint(v))
+		}
+	}
+	return values, nil
+}
+
+// groupName возвращает имя группы из DN (значение первого RDN):
+// cn=admins,ou=groups,dc=example,dc=com -> admins
+func groupName(dn string) string {
+	rdn := strings.SplitN(dn, ",", 2)[0]
+	if i := strings.Index(rdn, "="); i >= 0 {
+		return strings.TrimSpace(rdn[i+1:])
+	}
+	return strings.TrimSpace(rdn)
+}
+
+// firstNonEmpty возвращает первое непустое значение
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
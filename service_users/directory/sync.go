@@ -0,0 +1,424 @@
+package directory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/repository"
+	"service_users/utils"
+
+	"pkg/ids"
+	"pkg/rolesepoch"
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// ErrEmptyPayload возвращается при попытке деактивировать отсутствующих пользователей по пустой выгрузке
+var ErrEmptyPayload = errors.New("выгрузка каталога пуста, деактивация отсутствующих пользователей отменена")
+
+// defaultRole роль, которую получает каждый активный пользователь каталога
+const defaultRole = "user"
+
+// Options параметры синхронизации
+type Options struct {
+	// DryRun только построить отчет об изменениях, не применяя их
+	DryRun bool
+	// DeactivateMissing деактивировать связанных с источником пользователей,
+	// отсутствующих в выгрузке (выгрузка считается полной)
+	DeactivateMissing bool
+	// ActorID администратор, запустивший синхронизацию: его учетная запись не изменяется,
+	// чтобы он не потерял доступ (uuid.Nil для периодической синхронизации)
+	ActorID uuid.UUID
+}
+
+// action тип изменения учетной записи
+type action int
+
+const (
+	actionCreate action = iota
+	actionUpdate
+	actionDeactivate
+)
+
+// change запланированное изменение учетной записи
+type change struct {
+	action action
+	entry  models.DirectoryUser
+	// user существующий пользователь; nil при создании
+	user   *models.User
+	roles  []string
+	link   bool
+	report models.DirectoryChange
+}
+
+// Syncer синхронизирует учетные записи с внешним каталогом. Синхронизация
+// идемпотентна: повторный запуск с той же выгрузкой не вносит изменений.
+// Деактивация — это пустой список ролей: пользователь блокируется, его
+// refresh токены отзываются, а новая эпоха ролей отзывает access токены
+type Syncer struct {
+	users      repository.UserRepository
+	links      repository.DirectoryRepository
+	refresh    repository.RefreshTokenRepository
+	epochs     *rolesepoch.Store
+	groupRoles map[string]string
+
+	// mutex исключает одновременную синхронизацию через API и по расписанию
+	mutex sync.Mutex
+}
+
+// NewSyncer создает синхронизатор. epochs может быть nil, если Redis не настроен
+func NewSyncer(users repository.UserRepository, links repository.DirectoryRepository, refresh repository.RefreshTokenRepository,
+	epochs *rolesepoch.Store, groupRoles map[string]string) *Syncer {
+	return &Syncer{
+		users:      users,
+		links:      links,
+		refresh:    refresh,
+		epochs:     epochs,
+		groupRoles: groupRoles,
+	}
+}
+
+// Sync сравнивает выгрузку каталога с пользователями сервиса и, если это не dry run,
+// применяет изменения. Ошибки отдельных записей попадают в отчет и не прерывают синхронизацию
+func (s *Syncer) Sync(ctx context.Context, source string, entries []models.DirectoryUser, opts Options) (*models.DirectorySyncReport, error) {
+	// Пустая выгрузка чаще означает сбой каталога, чем увольнение всех сотрудников
+	if opts.DeactivateMissing && len(entries) == 0 {
+		return nil, ErrEmptyPayload
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	report := &models.DirectorySyncReport{
+		Source:      source,
+		DryRun:      opts.DryRun,
+		Created:     []models.DirectoryChange{},
+		Updated:     []models.DirectoryChange{},
+		Deactivated: []models.DirectoryChange{},
+		Errors:      []models.DirectorySyncError{},
+	}
+
+	changes, err := s.plan(source, entries, opts, report)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range changes {
+		if !opts.DryRun {
+			if err := s.apply(ctx, source, c); err != nil {
+				report.Errors = append(report.Errors, models.DirectorySyncError{
+					ExternalID: c.entry.ExternalID,
+					Email:      c.entry.Email,
+					Error:      err.Error(),
+				})
+				continue
+			}
+			if c.user != nil {
+				id := c.user.ID
+				c.report.UserID = &id
+			}
+		}
+
+		switch c.action {
+		case actionCreate:
+			report.Created = append(report.Created, c.report)
+		case actionUpdate:
+			report.Updated = append(report.Updated, c.report)
+		case actionDeactivate:
+			report.Deactivated = append(report.Deactivated, c.report)
+		}
+	}
+	return report, nil
+}
+
+// plan строит список изменений; некорректные записи добавляются в report.Errors
+func (s *Syncer) plan(source string, entries []models.DirectoryUser, opts Options, report *models.DirectorySyncReport) ([]*change, error) {
+	linked, err := s.links.ListLinked(source)
+	if err != nil {
+		return nil, err
+	}
+	linkedIDs := make(map[uuid.UUID]string, len(linked))
+	for externalID, id := range linked {
+		linkedIDs[id] = externalID
+	}
+
+	var changes []*change
+	seenExternal := make(map[string]bool, len(entries))
+	seenEmail := make(map[string]bool, len(entries))
+	fail := func(entry models.DirectoryUser, format string, args ...interface{}) {
+		report.Errors = append(report.Errors, models.DirectorySyncError{
+			ExternalID: entry.ExternalID,
+			Email:      entry.Email,
+			Error:      fmt.Sprintf(format, args...),
+		})
+	}
+
+	for _, entry := range entries {
+		entry.Email = strings.TrimSpace(strings.ToLower(entry.Email))
+		entry.Name = strings.TrimSpace(entry.Name)
+
+		switch {
+		case entry.ExternalID == "":
+			fail(entry, "отсутствует внешний ID")
+			continue
+		case entry.Email == "" || !strings.Contains(entry.Email, "@"):
+			fail(entry, "отсутствует или некорректен email")
+			continue
+		case seenExternal[entry.ExternalID]:
+			fail(entry, "внешний ID повторяется в выгрузке")
+			continue
+		case seenEmail[entry.Email]:
+			fail(entry, "email повторяется в выгрузке")
+			continue
+		}
+		seenExternal[entry.ExternalID] = true
+		seenEmail[entry.Email] = true
+		if len(entry.Name) < 2 {
+			entry.Name = strings.SplitN(entry.Email, "@", 2)[0]
+		}
+
+		user, err := s.findUser(entry, linked)
+		if err != nil {
+			fail(entry, "%v", err)
+			continue
+		}
+
+		var roles []string
+		if entry.Active {
+			roles = s.rolesFor(entry.Groups)
+		}
+
+		if user == nil {
+			if !entry.Active {
+				report.Unchanged++
+				continue
+			}
+			changes = append(changes, &change{
+				action: actionCreate,
+				entry:  entry,
+				roles:  roles,
+				link:   true,
+				report: models.DirectoryChange{
+					ExternalID: entry.ExternalID,
+					Email:      entry.Email,
+					Fields: []models.FieldChange{
+						{Field: "name", New: entry.Name},
+						{Field: "roles", New: roles},
+					},
+				},
+			})
+			continue
+		}
+
+		if other, ok := linkedIDs[user.ID]; ok && other != entry.ExternalID {
+			fail(entry, "пользователь %s уже связан с учетной записью каталога %s", user.Email, other)
+			continue
+		}
+		c := &change{action: actionUpdate, entry: entry, user: user, roles: roles, link: linked[entry.ExternalID] != user.ID}
+		if entry.Active {
+			if user.Email != entry.Email {
+				c.report.Fields = append(c.report.Fields, models.FieldChange{Field: "email", Old: user.Email, New: entry.Email})
+			}
+			if user.Name != entry.Name {
+				c.report.Fields = append(c.report.Fields, models.FieldChange{Field: "name", Old: user.Name, New: entry.Name})
+			}
+		} else if !user.IsBlocked() {
+			// Профиль деактивируемого пользователя не обновляется, только роли
+			c.action = actionDeactivate
+		}
+		if !sameRoles(user.Roles, roles) {
+			c.report.Fields = append(c.report.Fields, models.FieldChange{Field: "roles", Old: []string(user.Roles), New: roles})
+		}
+		if c.link {
+			c.report.Fields = append(c.report.Fields, models.FieldChange{Field: "external_id", New: entry.ExternalID})
+		}
+		if len(c.report.Fields) == 0 {
+			report.Unchanged++
+			continue
+		}
+		if opts.ActorID != uuid.Nil && user.ID == opts.ActorID {
+			fail(entry, "нельзя изменить собственную учетную запись")
+			continue
+		}
+		id := user.ID
+		c.report.UserID = &id
+		c.report.ExternalID = entry.ExternalID
+		c.report.Email = entry.Email
+		changes = append(changes, c)
+	}
+
+	if opts.DeactivateMissing {
+		missing := make([]string, 0)
+		for externalID := range linked {
+			if !seenExternal[externalID] {
+				missing = append(missing, externalID)
+			}
+		}
+		sort.Strings(missing)
+
+		for _, externalID := range missing {
+			entry := models.DirectoryUser{ExternalID: externalID}
+			user, err := s.users.GetByID(linked[externalID])
+			if err != nil {
+				fail(entry, "%v", err)
+				continue
+			}
+			entry.Email, entry.Name = user.Email, user.Name
+			if user.IsBlocked() {
+				report.Unchanged++
+				continue
+			}
+			if opts.ActorID != uuid.Nil && user.ID == opts.ActorID {
+				fail(entry, "нельзя изменить собственную учетную запись")
+				continue
+			}
+			id := user.ID
+			changes = append(changes, &change{
+				action: actionDeactivate,
+				entry:  entry,
+				user:   user,
+				report: models.DirectoryChange{
+					UserID:     &id,
+					ExternalID: externalID,
+					Email:      user.Email,
+					Fields:     []models.FieldChange{{Field: "roles", Old: []string(user.Roles), New: []string{}}},
+				},
+			})
+		}
+	}
+
+	return changes, nil
+}
+
+// findUser ищет пользователя по связи с каталогом, а затем по email. Возвращает nil,
+// если пользователь не найден
+func (s *Syncer) findUser(entry models.DirectoryUser, linked map[string]uuid.UUID) (*models.User, error) {
+	if id, ok := linked[entry.ExternalID]; ok {
+		return s.users.GetByID(id)
+	}
+	exists, err := s.users.EmailExists(entry.Email)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return s.users.GetByEmail(entry.Email)
+}
+
+// rolesFor возвращает роли активного пользователя по его группам в каталоге
+func (s *Syncer) rolesFor(groups []string) []string {
+	roles := []string{defaultRole}
+	for _, group := range groups {
+		role, ok := s.groupRoles[strings.ToLower(group)]
+		if !ok {
+			continue
+		}
+		duplicate := false
+		for _, existing := range roles {
+			duplicate = duplicate || existing == role
+		}
+		if !duplicate {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// apply применяет изменение учетной записи
+func (s *Syncer) apply(ctx context.Context, source string, c *change) error {
+	if c.action == actionCreate {
+		password, err := randomPassword()
+		if err != nil {
+			return err
+		}
+		now := timeutil.Now()
+		// Пароль случайный: вход по паролю для учетных записей каталога недоступен до его сброса
+		c.user = &models.User{
+			ID:        ids.New(),
+			Email:     c.entry.Email,
+			Password:  password,
+			Name:      c.entry.Name,
+			Roles:     pq.StringArray(c.roles),
+			Timezone:  timeutil.DefaultTimezone,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := s.users.Create(c.user); err != nil {
+			return err
+		}
+		return s.links.Link(c.user.ID, source, c.entry.ExternalID)
+	}
+
+	user := c.user
+	if c.action == actionUpdate && c.entry.Active && (c.entry.Email != user.Email || c.entry.Name != user.Name) {
+		user.Email, user.Name = c.entry.Email, c.entry.Name
+		if err := s.users.Update(user); err != nil {
+			return err
+		}
+	}
+
+	if c.link {
+		if err := s.links.Link(user.ID, source, c.entry.ExternalID); err != nil {
+			return err
+		}
+	}
+
+	if sameRoles(user.Roles, c.roles) {
+		return nil
+	}
+	updated, err := s.users.UpdateRoles(user.ID, c.roles)
+	if err != nil {
+		return err
+	}
+	if updated.IsBlocked() {
+		if err := s.refresh.RevokeAllForUser(updated.ID); err != nil {
+			logger.GetLogger().Error("Не удалось отозвать refresh токены деактивированного пользователя",
+				zap.String("user_id", updated.ID.String()), zap.Error(err))
+		}
+	}
+	if s.epochs != nil {
+		if err := s.epochs.Publish(ctx, updated.ID.String(), updated.RolesEpoch); err != nil {
+			logger.GetLogger().Error("Не удалось опубликовать эпоху ролей, активные токены не отозваны",
+				zap.String("user_id", updated.ID.String()),
+				zap.Int64("roles_epoch", updated.RolesEpoch),
+				zap.Error(err),
+			)
+		}
+	}
+	return nil
+}
+
+// sameRoles сравнивает наборы ролей без учета порядка
+func sameRoles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool, len(a))
+	for _, role := range a {
+		set[role] = true
+	}
+	for _, role := range b {
+		if !set[role] {
+			return false
+		}
+	}
+	return true
+}
+
+// randomPassword возвращает хеш случайного пароля для новых учетных записей каталога
+func randomPassword() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("ошибка генерации пароля: %v", err)
+	}
+	return utils.HashPassword(hex.EncodeToString(buf))
+}
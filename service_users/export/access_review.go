@@ -0,0 +1,74 @@
+// Package export выгружает отчеты service_users в CSV
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"service_users/models"
+)
+
+// AccessReviewCSV пишет отчет о пересмотре доступа в CSV: строка на каждое действие
+// администратора из отчета, данные пользователя повторяются в каждой его строке.
+// Пользователь без действий за период занимает одну строку с пустыми полями действия
+func AccessReviewCSV(w io.Writer, report *models.AccessReviewReport) error {
+	writer := csv.NewWriter(w)
+	header := []string{
+		"user_id", "email", "name", "roles", "created_at", "last_login_at", "dormant",
+		"actions_count", "action_at", "action", "action_target", "action_details",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, user := range report.Users {
+		lastLogin := ""
+		if user.LastLoginAt != nil {
+			lastLogin = formatTime(*user.LastLoginAt)
+		}
+		userColumns := []string{
+			user.ID.String(),
+			cell(user.Email),
+			cell(user.Name),
+			cell(strings.Join(user.Roles, " ")),
+			formatTime(user.CreatedAt),
+			lastLogin,
+			strconv.FormatBool(user.Dormant),
+			strconv.Itoa(user.ActionsCount),
+		}
+
+		if len(user.RecentActions) == 0 {
+			if err := writer.Write(append(userColumns, "", "", "", "")); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, action := range user.RecentActions {
+			row := append(append([]string{}, userColumns...),
+				formatTime(action.CreatedAt), cell(action.Action), cell(action.Target), cell(action.Details))
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatTime форматирует время в UTC (RFC 3339)
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// cell защищает значение от интерпретации как формулы в табличных редакторах:
+// имя и email задает пользователь, и значение вида =HYPERLINK(...) выполнилось бы при открытии
+func cell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,73 @@
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	"service_users/models"
+	"service_users/repository"
+
+	"pkg/fakes"
+
+	"github.com/google/uuid"
+)
+
+var _ repository.AccessReviewRepository = (*AccessReviewRepository)(nil)
+
+// AccessReviewRepository in-memory реализация repository.AccessReviewRepository:
+// запоминает входы и журнал действий администраторов, отчет о пересмотре пустой.
+// Ошибки внедряются через встроенный fakes.Failures по имени метода
+type AccessReviewRepository struct {
+	fakes.Failures
+
+	mutex   sync.Mutex
+	logins  map[uuid.UUID]time.Time
+	actions []models.AdminAction
+}
+
+// NewAccessReviewRepository создает пустой фейк журнала аудита
+func NewAccessReviewRepository() *AccessReviewRepository {
+	return &AccessReviewRepository{logins: make(map[uuid.UUID]time.Time)}
+}
+
+// AdminActions возвращает сохраненные действия администраторов в порядке записи
+func (r *AccessReviewRepository) AdminActions() []models.AdminAction {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]models.AdminAction(nil), r.actions...)
+}
+
+// RecordLogin сохраняет время успешного входа пользователя
+func (r *AccessReviewRepository) RecordLogin(userID uuid.UUID) error {
+	if err := r.Check("RecordLogin"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.logins[userID] = time.Now()
+	return nil
+}
+
+// RecordAdminAction сохраняет действие администратора
+func (r *AccessReviewRepository) RecordAdminAction(action *models.AdminAction) error {
+	if err := r.Check("RecordAdminAction"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored := *action
+	stored.ID = int64(len(r.actions) + 1)
+	stored.CreatedAt = time.Now()
+	r.actions = append(r.actions, stored)
+	return nil
+}
+
+// Review возвращает пустой отчет о пересмотре доступа
+func (r *AccessReviewRepository) Review(roles []string, since time.Time, actionsLimit int) ([]models.AccessReviewUser, error) {
+	if err := r.Check("Review"); err != nil {
+		return nil, err
+	}
+	return []models.AccessReviewUser{}, nil
+}
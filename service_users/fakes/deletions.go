@@ -0,0 +1,193 @@
+package fakes
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"service_users/models"
+	"service_users/repository"
+
+	"pkg/fakes"
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+)
+
+var _ repository.UserDeletionRepository = (*UserDeletionRepository)(nil)
+
+// UserDeletionRepository in-memory реализация repository.UserDeletionRepository.
+// У пользователя не больше одной незавершенной операции и одного токена
+// подтверждения; Execute только завершает операцию, данные не обезличиваются.
+// Ошибки внедряются через встроенный fakes.Failures по имени метода
+type UserDeletionRepository struct {
+	fakes.Failures
+
+	mutex         sync.Mutex
+	deletions     []*models.UserDeletion
+	confirmations map[uuid.UUID]deletionConfirmation
+}
+
+// deletionConfirmation хеш токена подтверждения удаления и срок его действия
+type deletionConfirmation struct {
+	tokenHash string
+	expiresAt time.Time
+}
+
+// NewUserDeletionRepository создает пустой фейк репозитория операций удаления
+func NewUserDeletionRepository() *UserDeletionRepository {
+	return &UserDeletionRepository{confirmations: make(map[uuid.UUID]deletionConfirmation)}
+}
+
+// Deletions возвращает копии сохраненных операций в порядке создания
+func (r *UserDeletionRepository) Deletions() []models.UserDeletion {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	deletions := make([]models.UserDeletion, 0, len(r.deletions))
+	for _, deletion := range r.deletions {
+		deletions = append(deletions, *deletion)
+	}
+	return deletions
+}
+
+// Create сохраняет новую операцию удаления
+func (r *UserDeletionRepository) Create(deletion *models.UserDeletion) error {
+	if err := r.Check("Create"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, existing := range r.deletions {
+		if existing.UserID == deletion.UserID && existing.IsActive() {
+			return repository.ErrUserDeletionInProgress
+		}
+	}
+	copied := *deletion
+	r.deletions = append(r.deletions, &copied)
+	return nil
+}
+
+// GetByID получает операцию удаления по ID
+func (r *UserDeletionRepository) GetByID(id uuid.UUID) (*models.UserDeletion, error) {
+	if err := r.Check("GetByID"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, deletion := range r.deletions {
+		if deletion.ID == id {
+			copied := *deletion
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("операция удаления не найдена")
+}
+
+// GetLatestForUser возвращает последнюю операцию удаления пользователя
+func (r *UserDeletionRepository) GetLatestForUser(userID uuid.UUID) (*models.UserDeletion, error) {
+	if err := r.Check("GetLatestForUser"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i := len(r.deletions) - 1; i >= 0; i-- {
+		if r.deletions[i].UserID == userID {
+			copied := *r.deletions[i]
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+// ClaimNext забирает в работу самую старую ожидающую операцию
+func (r *UserDeletionRepository) ClaimNext() (*models.UserDeletion, error) {
+	if err := r.Check("ClaimNext"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, deletion := range r.deletions {
+		if deletion.Status == models.UserDeletionPending {
+			now := timeutil.Now()
+			deletion.Status = models.UserDeletionRunning
+			deletion.Attempts++
+			deletion.StartedAt = &now
+			copied := *deletion
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+// Execute завершает операцию удаления
+func (r *UserDeletionRepository) Execute(deletion *models.UserDeletion) (*models.UserDeletionSummary, error) {
+	if err := r.Check("Execute"); err != nil {
+		return nil, err
+	}
+
+	summary := &models.UserDeletionSummary{}
+	r.update(deletion.ID, func(stored *models.UserDeletion) {
+		now := timeutil.Now()
+		stored.Status = models.UserDeletionCompleted
+		stored.Summary = summary
+		stored.CompletedAt = &now
+	})
+	return summary, nil
+}
+
+// MarkFailed отмечает операцию удаления неудачной
+func (r *UserDeletionRepository) MarkFailed(id uuid.UUID, lastError string) error {
+	if err := r.Check("MarkFailed"); err != nil {
+		return err
+	}
+
+	r.update(id, func(stored *models.UserDeletion) {
+		stored.Status = models.UserDeletionFailed
+		stored.LastError = lastError
+	})
+	return nil
+}
+
+// CreateConfirmation сохраняет хеш токена подтверждения, заменяя предыдущий
+func (r *UserDeletionRepository) CreateConfirmation(userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	if err := r.Check("CreateConfirmation"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.confirmations[userID] = deletionConfirmation{tokenHash: tokenHash, expiresAt: expiresAt}
+	return nil
+}
+
+// ConsumeConfirmation погашает токен подтверждения пользователя
+func (r *UserDeletionRepository) ConsumeConfirmation(userID uuid.UUID, tokenHash string) error {
+	if err := r.Check("ConsumeConfirmation"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	confirmation, ok := r.confirmations[userID]
+	if !ok || confirmation.tokenHash != tokenHash || !timeutil.Now().Before(confirmation.expiresAt) {
+		return repository.ErrDeletionConfirmationInvalid
+	}
+	delete(r.confirmations, userID)
+	return nil
+}
+
+// update применяет fn к сохраненной операции с ID id
+func (r *UserDeletionRepository) update(id uuid.UUID, fn func(*models.UserDeletion)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, deletion := range r.deletions {
+		if deletion.ID == id {
+			fn(deletion)
+			return
+		}
+	}
+}
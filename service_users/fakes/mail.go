@@ -0,0 +1,45 @@
+package fakes
+
+import (
+	"context"
+	"time"
+
+	"service_users/mail"
+
+	"pkg/fakes"
+)
+
+var _ mail.Sender = (*MailSender)(nil)
+
+// MailSender фейк mail.Sender: запоминает отправленные письма. Обработчики
+// отправляют письма в фоне, поэтому тест ждет их через Next.
+// Ошибки внедряются через встроенный fakes.Failures по имени метода
+type MailSender struct {
+	fakes.Failures
+
+	messages chan mail.Message
+}
+
+// NewMailSender создает фейк отправителя писем
+func NewMailSender() *MailSender {
+	return &MailSender{messages: make(chan mail.Message, 16)}
+}
+
+// Send запоминает письмо
+func (s *MailSender) Send(ctx context.Context, message mail.Message) error {
+	if err := s.Check("Send"); err != nil {
+		return err
+	}
+	s.messages <- message
+	return nil
+}
+
+// Next ждет следующее отправленное письмо не дольше timeout
+func (s *MailSender) Next(timeout time.Duration) (mail.Message, bool) {
+	select {
+	case message := <-s.messages:
+		return message, true
+	case <-time.After(timeout):
+		return mail.Message{}, false
+	}
+}
@@ -0,0 +1,206 @@
+package fakes
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"service_users/models"
+	"service_users/repository"
+
+	"pkg/fakes"
+)
+
+var _ repository.RoleRepository = (*RoleRepository)(nil)
+
+// RoleRepository in-memory реализация repository.RoleRepository. Справочник прав
+// не проверяется: роли можно выдать любое право. Назначение ролей пользователям
+// фейк не отслеживает, поэтому Delete не возвращает ErrRoleAssigned.
+// Ошибки внедряются через встроенный fakes.Failures по имени метода
+type RoleRepository struct {
+	fakes.Failures
+
+	mutex sync.Mutex
+	roles map[string]*models.Role
+}
+
+// NewRoleRepository создает пустой фейк справочника ролей
+func NewRoleRepository() *RoleRepository {
+	return &RoleRepository{roles: make(map[string]*models.Role)}
+}
+
+// Seed добавляет роли в обход проверок и инъекции ошибок
+func (r *RoleRepository) Seed(roles ...*models.Role) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, role := range roles {
+		r.roles[role.Name] = cloneRole(role)
+	}
+}
+
+// ListPermissions возвращает права всех ролей в алфавитном порядке
+func (r *RoleRepository) ListPermissions() ([]models.Permission, error) {
+	if err := r.Check("ListPermissions"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	permissions := make([]models.Permission, 0)
+	for _, name := range r.permissions(nil) {
+		permissions = append(permissions, models.Permission{Name: name})
+	}
+	return permissions, nil
+}
+
+// List возвращает роли в алфавитном порядке
+func (r *RoleRepository) List() ([]models.Role, error) {
+	if err := r.Check("List"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	roles := make([]models.Role, 0, len(r.roles))
+	for _, role := range r.roles {
+		roles = append(roles, *cloneRole(role))
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+// Get возвращает роль по названию или ErrRoleNotFound
+func (r *RoleRepository) Get(name string) (*models.Role, error) {
+	if err := r.Check("Get"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	role, ok := r.roles[name]
+	if !ok {
+		return nil, repository.ErrRoleNotFound
+	}
+	return cloneRole(role), nil
+}
+
+// Create создает роль; ErrRoleExists, если роль уже есть
+func (r *RoleRepository) Create(role *models.Role) error {
+	if err := r.Check("Create"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.roles[role.Name]; ok {
+		return repository.ErrRoleExists
+	}
+	now := time.Now()
+	role.CreatedAt, role.UpdatedAt = now, now
+	r.roles[role.Name] = cloneRole(role)
+	return nil
+}
+
+// Update заменяет описание и права роли
+func (r *RoleRepository) Update(name, description string, permissions []string) (*models.Role, error) {
+	if err := r.Check("Update"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	role, ok := r.roles[name]
+	if !ok {
+		return nil, repository.ErrRoleNotFound
+	}
+	if role.PermissionsLocked {
+		return nil, repository.ErrRolePermissionsLocked
+	}
+	role.Description = description
+	role.Permissions = append([]string(nil), permissions...)
+	sort.Strings(role.Permissions)
+	role.UpdatedAt = time.Now()
+	return cloneRole(role), nil
+}
+
+// Delete удаляет роль; ErrRoleBuiltin для встроенной роли
+func (r *RoleRepository) Delete(name string) (bool, error) {
+	if err := r.Check("Delete"); err != nil {
+		return false, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	role, ok := r.roles[name]
+	if !ok {
+		return false, nil
+	}
+	if role.Builtin {
+		return false, repository.ErrRoleBuiltin
+	}
+	delete(r.roles, name)
+	return true, nil
+}
+
+// Unknown возвращает названия из names, отсутствующие в справочнике ролей
+func (r *RoleRepository) Unknown(names []string) ([]string, error) {
+	if err := r.Check("Unknown"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var unknown []string
+	for _, name := range names {
+		if _, ok := r.roles[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown, nil
+}
+
+// Permissions возвращает права ролей roles в алфавитном порядке
+func (r *RoleRepository) Permissions(roles []string) ([]string, error) {
+	if err := r.Check("Permissions"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.permissions(roles), nil
+}
+
+// permissions возвращает права ролей names без повторов в алфавитном порядке;
+// nil — права всех ролей
+func (r *RoleRepository) permissions(names []string) []string {
+	seen := make(map[string]bool)
+	for name, role := range r.roles {
+		if names != nil && !containsString(names, name) {
+			continue
+		}
+		for _, permission := range role.Permissions {
+			seen[permission] = true
+		}
+	}
+	permissions := make([]string, 0, len(seen))
+	for permission := range seen {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneRole(role *models.Role) *models.Role {
+	clone := *role
+	clone.Permissions = append([]string(nil), role.Permissions...)
+	return &clone
+}
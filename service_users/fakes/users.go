@@ -0,0 +1,293 @@
+// Package fakes содержит in-memory фейки репозиториев service_users для тестов
+// обработчиков. Ошибки внедряются через pkg/fakes.Failures по имени метода
+package fakes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"service_users/models"
+	"service_users/repository"
+
+	"pkg/fakes"
+
+	"github.com/google/uuid"
+)
+
+var _ repository.UserRepository = (*UserRepository)(nil)
+
+// UserRepository in-memory реализация repository.UserRepository. Повторяет
+// семантику SQL-реализации: email уникален без учета регистра, список
+// сортируется по дате создания по убыванию, пароль в списке очищается.
+// Ошибки внедряются через встроенный fakes.Failures по имени метода
+type UserRepository struct {
+	fakes.Failures
+
+	mutex sync.RWMutex
+	users map[uuid.UUID]*models.User
+}
+
+// NewUserRepository создает пустой фейк репозитория пользователей
+func NewUserRepository() *UserRepository {
+	return &UserRepository{users: make(map[uuid.UUID]*models.User)}
+}
+
+// Seed добавляет пользователей в обход проверок и инъекции ошибок
+func (r *UserRepository) Seed(users ...*models.User) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, user := range users {
+		r.users[user.ID] = cloneUser(user)
+	}
+}
+
+// Create создает нового пользователя
+func (r *UserRepository) Create(user *models.User) error {
+	if err := r.Check("Create"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.findByEmail(user.Email) != nil {
+		return fmt.Errorf("пользователь с email %s уже существует", user.Email)
+	}
+	r.users[user.ID] = cloneUser(user)
+	return nil
+}
+
+// GetByID получает пользователя по ID
+func (r *UserRepository) GetByID(id uuid.UUID) (*models.User, error) {
+	if err := r.Check("GetByID"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, fmt.Errorf("пользователь с ID %s не найден", id)
+	}
+	return cloneUser(user), nil
+}
+
+// GetByEmail получает пользователя по email
+func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+	if err := r.Check("GetByEmail"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	user := r.findByEmail(email)
+	if user == nil {
+		return nil, fmt.Errorf("пользователь с email %s не найден", email)
+	}
+	return cloneUser(user), nil
+}
+
+// EmailExists проверяет существование email
+func (r *UserRepository) EmailExists(email string) (bool, error) {
+	if err := r.Check("EmailExists"); err != nil {
+		return false, err
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.findByEmail(email) != nil, nil
+}
+
+// Update обновляет email, имя и роли пользователя
+func (r *UserRepository) Update(user *models.User) error {
+	if err := r.Check("Update"); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return fmt.Errorf("пользователь с ID %s не найден", user.ID)
+	}
+	if other := r.findByEmail(user.Email); other != nil && other.ID != user.ID {
+		return fmt.Errorf("пользователь с email %s уже существует", user.Email)
+	}
+	stored.Email = user.Email
+	stored.Name = user.Name
+	stored.Timezone = user.Timezone
+	stored.Roles = append(stored.Roles[:0:0], user.Roles...)
+	stored.UpdatedAt = time.Now()
+	return nil
+}
+
+// UpdateRoles заменяет роли пользователя и увеличивает эпоху ролей
+func (r *UserRepository) UpdateRoles(id uuid.UUID, roles []string) (*models.User, error) {
+	if err := r.Check("UpdateRoles"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored, ok := r.users[id]
+	if !ok {
+		return nil, fmt.Errorf("пользователь с ID %s не найден", id)
+	}
+	stored.Roles = append([]string(nil), roles...)
+	stored.RolesEpoch++
+	stored.UpdatedAt = time.Now()
+
+	user := cloneUser(stored)
+	user.Password = ""
+	return user, nil
+}
+
+// BumpRolesEpoch увеличивает эпоху ролей пользователей с ролью role
+func (r *UserRepository) BumpRolesEpoch(role string) (map[uuid.UUID]int64, error) {
+	if err := r.Check("BumpRolesEpoch"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	epochs := make(map[uuid.UUID]int64)
+	for id, stored := range r.users {
+		if stored.HasRole(role) {
+			stored.RolesEpoch++
+			stored.UpdatedAt = time.Now()
+			epochs[id] = stored.RolesEpoch
+		}
+	}
+	return epochs, nil
+}
+
+// Deactivate деактивирует учетную запись и увеличивает эпоху ролей
+func (r *UserRepository) Deactivate(id uuid.UUID) (*models.User, error) {
+	if err := r.Check("Deactivate"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored, ok := r.users[id]
+	if !ok {
+		return nil, fmt.Errorf("пользователь с ID %s не найден", id)
+	}
+	now := time.Now()
+	if stored.DeactivatedAt == nil {
+		stored.DeactivatedAt = &now
+	}
+	stored.RolesEpoch++
+	stored.UpdatedAt = now
+
+	user := cloneUser(stored)
+	user.Password = ""
+	return user, nil
+}
+
+// Reactivate снимает деактивацию учетной записи
+func (r *UserRepository) Reactivate(id uuid.UUID) (*models.User, error) {
+	if err := r.Check("Reactivate"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stored, ok := r.users[id]
+	if !ok {
+		return nil, fmt.Errorf("пользователь с ID %s не найден", id)
+	}
+	stored.DeactivatedAt = nil
+	stored.UpdatedAt = time.Now()
+
+	user := cloneUser(stored)
+	user.Password = ""
+	return user, nil
+}
+
+// List получает список пользователей с фильтрацией и пагинацией
+func (r *UserRepository) List(req *models.ListUsersRequest) (*models.ListUsersResponse, error) {
+	if err := r.Check("List"); err != nil {
+		return nil, err
+	}
+
+	r.mutex.RLock()
+	var matched []*models.User
+	for _, user := range r.users {
+		if req.Email != "" && !containsFold(user.Email, req.Email) {
+			continue
+		}
+		if req.Name != "" && !containsFold(user.Name, req.Name) {
+			continue
+		}
+		if !matchesSearch(user, req.SearchTerms()) {
+			continue
+		}
+		if req.Role != "" && !user.HasRole(req.Role) {
+			continue
+		}
+		if req.Status == models.UserStatusActive && user.IsDeactivated() ||
+			req.Status == models.UserStatusDeactivated && !user.IsDeactivated() {
+			continue
+		}
+		matched = append(matched, user)
+	}
+	r.mutex.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+
+	var users []models.User
+	for i := req.Offset; i < len(matched) && i < req.Offset+req.Limit; i++ {
+		user := cloneUser(matched[i])
+		user.Password = ""
+		users = append(users, *user)
+	}
+
+	return &models.ListUsersResponse{
+		Users:  users,
+		Total:  len(matched),
+		Limit:  req.Limit,
+		Offset: req.Offset,
+	}, nil
+}
+
+// matchesSearch проверяет, что каждое слово поиска встречается в email или имени.
+// В отличие от репозитория, без поиска с опечатками и ранжирования
+func matchesSearch(user *models.User, terms []string) bool {
+	for _, term := range terms {
+		if !containsFold(user.Email, term) && !containsFold(user.Name, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// findByEmail ищет пользователя по email без учета регистра; вызывается под блокировкой
+func (r *UserRepository) findByEmail(email string) *models.User {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return user
+		}
+	}
+	return nil
+}
+
+// containsFold аналог ILIKE '%substr%'
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// cloneUser копирует пользователя, чтобы вызывающий код не менял состояние фейка
+func cloneUser(user *models.User) *models.User {
+	clone := *user
+	clone.Roles = append(clone.Roles[:0:0], user.Roles...)
+	if user.DeactivatedAt != nil {
+		deactivatedAt := *user.DeactivatedAt
+		clone.DeactivatedAt = &deactivatedAt
+	}
+	return &clone
+}
@@ -5,21 +5,27 @@ go 1.24.0
 toolchain go1.24.3
 
 require (
-  github.com/go-playground/validator/v10 v10.28.0
-  github.com/golang-jwt/jwt/v5 v5.3.0
-  github.com/google/uuid v1.6.0
-  github.com/gorilla/mux v1.8.1
-  github.com/lib/pq v1.10.9
-  go.uber.org/zap v1.27.0
-  golang.org/x/crypto v0.43.0
+	github.com/go-playground/validator/v10 v10.28.0
+	github.com/golang-jwt/jwt/v5 v5.3.0
+	github.com/google/uuid v1.6.0
+	github.com/gorilla/mux v1.8.1
+	github.com/lib/pq v1.10.9
+	github.com/redis/go-redis/v9 v9.7.3
+	go.uber.org/zap v1.27.0
+	golang.org/x/crypto v0.43.0
+	pkg v0.0.0-00010101000000-000000000000
 )
 
 require (
-  github.com/gabriel-vasile/mimetype v1.4.10 // indirect
-  github.com/go-playground/locales v0.14.1 // indirect
-  github.com/go-playground/universal-translator v0.18.1 // indirect
-  github.com/leodido/go-urn v1.4.0 // indirect
-  go.uber.org/multierr v1.11.0 // indirect
-  golang.org/x/sys v0.37.0 // indirect
-  golang.org/x/text v0.30.0 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/gabriel-vasile/mimetype v1.4.10 // indirect
+	github.com/go-playground/locales v0.14.1 // indirect
+	github.com/go-playground/universal-translator v0.18.1 // indirect
+	github.com/leodido/go-urn v1.4.0 // indirect
+	go.uber.org/multierr v1.11.0 // indirect
+	golang.org/x/sys v0.37.0 // indirect
+	golang.org/x/text v0.30.0 // indirect
 )
+
+replace pkg => ../pkg
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"service_users/export"
+	"service_users/logger"
+	"service_users/models"
+
+	"pkg/rbac"
+	"pkg/timeutil"
+)
+
+const (
+	// accessReviewDefaultDays период отчета о пересмотре доступа по умолчанию
+	accessReviewDefaultDays = 30
+	// accessReviewMaxDays максимальный период отчета
+	accessReviewMaxDays = 365
+	// accessReviewActionsLimit сколько последних действий каждого администратора попадает в отчет
+	accessReviewActionsLimit = 20
+)
+
+// AccessReviewHandler обработчик отчета о пересмотре доступа
+type AccessReviewHandler struct {
+	*ExportHandler
+}
+
+// NewAccessReviewHandler создает новый обработчик отчета о пересмотре доступа
+func NewAccessReviewHandler(exportHandler *ExportHandler) *AccessReviewHandler {
+	return &AccessReviewHandler{ExportHandler: exportHandler}
+}
+
+// GetAccessReview формирует отчет о пересмотре доступа (право access_review.read):
+// пользователи с ролями из ACCESS_REVIEW_ROLES, их последний вход и действия за период.
+// Параметры: days (1–365, по умолчанию 30), format (json или csv). Администратор формирует
+// не более одной выгрузки одновременно; файл CSV сохраняется для докачки
+func (h *AccessReviewHandler) GetAccessReview(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.AccessReviewRead) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	query := r.URL.Query()
+	days := accessReviewDefaultDays
+	if value := query.Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > accessReviewMaxDays {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation,
+				fmt.Sprintf("Параметр days должен быть числом от 1 до %d", accessReviewMaxDays))
+			return
+		}
+		days = parsed
+	}
+
+	format := strings.ToLower(query.Get("format"))
+	if format == "" {
+		format = models.AccessReviewFormatJSON
+	}
+	if format != models.AccessReviewFormatJSON && format != models.AccessReviewFormatCSV {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Параметр format должен быть json или csv")
+		return
+	}
+
+	unlock, ok := h.beginExport(w, r, "access_review_export", userID)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	now := timeutil.Now()
+	report := &models.AccessReviewReport{
+		GeneratedAt:   now,
+		PeriodStart:   now.AddDate(0, 0, -days),
+		Roles:         h.config.AccessReview.Roles,
+		DormantBefore: now.Add(-h.config.AccessReview.DormantAfter),
+	}
+
+	users, err := h.access(r).Review(report.Roles, report.PeriodStart, accessReviewActionsLimit)
+	if err != nil {
+		logger.LogUserAction(r, "access_review_export", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка формирования отчета о пересмотре доступа")
+		return
+	}
+	// Пользователь, ни разу не входивший, неактивен, если создан раньше порога
+	for i := range users {
+		lastSeen := users[i].CreatedAt
+		if users[i].LastLoginAt != nil {
+			lastSeen = *users[i].LastLoginAt
+		}
+		if lastSeen.Before(report.DormantBefore) {
+			users[i].Dormant = true
+			report.DormantCount++
+		}
+	}
+	report.Users = users
+
+	details := fmt.Sprintf("days=%d, format=%s, users=%d, dormant=%d", days, format, len(users), report.DormantCount)
+	h.recordAdminAction(r, "access_review_export", strings.Join(report.Roles, ","), details)
+	logger.LogUserAction(r, "access_review_export", details, true)
+
+	if format == models.AccessReviewFormatJSON {
+		h.sendSuccessResponse(w, http.StatusOK, report)
+		return
+	}
+
+	// Выгрузка формируется целиком до отправки, чтобы при ошибке вернуть 500 в формате API
+	var body bytes.Buffer
+	if err := export.AccessReviewCSV(&body, report); err != nil {
+		logger.LogUserAction(r, "access_review_export", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка формирования отчета о пересмотре доступа")
+		return
+	}
+
+	h.sendExport(w, r, &models.ExportArtifact{
+		UserID:      userID,
+		Kind:        "access_review",
+		Filename:    fmt.Sprintf("access-review-%s.csv", now.UTC().Format("20060102-150405")),
+		ContentType: "text/csv; charset=utf-8",
+		Content:     body.Bytes(),
+	})
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/repository"
+	"service_users/utils"
+
+	"pkg/ids"
+	"pkg/servertiming"
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// APIKeyHandler обработчик персональных API ключей. Пользователь создает ключи
+// с областями действия для скриптов и интеграций, а API Gateway обменивает ключ
+// из заголовка Authorization: ApiKey на короткоживущий access токен
+type APIKeyHandler struct {
+	*UserHandler
+	apiKeyRepo repository.APIKeyRepository
+}
+
+// NewAPIKeyHandler создает новый обработчик API ключей
+func NewAPIKeyHandler(userHandler *UserHandler, apiKeyRepo repository.APIKeyRepository) *APIKeyHandler {
+	return &APIKeyHandler{
+		UserHandler: userHandler,
+		apiKeyRepo:  apiKeyRepo,
+	}
+}
+
+// apiKeys возвращает репозиторий API ключей, учитывающий время запросов к БД запроса r
+func (h *APIKeyHandler) apiKeys(r *http.Request) repository.APIKeyRepository {
+	return repository.TimedAPIKeyRepository(h.apiKeyRepo, servertiming.FromContext(r.Context()))
+}
+
+// ListAPIKeys возвращает активные API ключи текущего пользователя без их значений
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	keys, err := h.apiKeys(r).ListForUser(userID)
+	if err != nil {
+		logger.LogUserAction(r, "list_api_keys", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения API ключей")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, keys)
+}
+
+// CreateAPIKey создает API ключ текущего пользователя. Значение ключа возвращается
+// только в ответе на создание. Запрос, аутентифицированный API ключом, отклоняется:
+// ключ не может выпускать другие ключи
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireInteractiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Название ключа не может быть пустым")
+		return
+	}
+
+	value, prefix, hash, err := utils.GenerateAPIKey()
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка генерации API ключа")
+		return
+	}
+
+	key := &models.APIKey{
+		ID:      ids.New(),
+		UserID:  userID,
+		Name:    name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  uniqueScopes(req.Scopes),
+	}
+	if req.ExpiresInDays != nil {
+		expiresAt := timeutil.Now().Add(time.Duration(*req.ExpiresInDays) * 24 * time.Hour)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := h.apiKeys(r).Create(key, h.config.APIKeys.MaxPerUser); err != nil {
+		if err == repository.ErrAPIKeyLimit {
+			h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict,
+				fmt.Sprintf("Нельзя создать больше %d активных API ключей", h.config.APIKeys.MaxPerUser))
+			return
+		}
+		logger.LogUserAction(r, "create_api_key", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания API ключа")
+		return
+	}
+
+	logger.LogUserAction(r, "create_api_key", fmt.Sprintf("api_key_id=%s, scopes=%s", key.ID, strings.Join(key.Scopes, ",")), true)
+	h.sendSuccessResponse(w, http.StatusCreated, models.CreateAPIKeyResponse{APIKey: *key, Key: value})
+}
+
+// RevokeAPIKey отзывает API ключ текущего пользователя. Access токены, уже выданные
+// по ключу, действуют до истечения API_KEY_TOKEN_TTL
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireInteractiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID API ключа")
+		return
+	}
+
+	revoked, err := h.apiKeys(r).Revoke(userID, keyID)
+	if err != nil {
+		logger.LogUserAction(r, "revoke_api_key", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка отзыва API ключа")
+		return
+	}
+	if !revoked {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "API ключ не найден")
+		return
+	}
+
+	logger.LogUserAction(r, "revoke_api_key", "api_key_id="+keyID.String(), true)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AuthenticateAPIKey обменивает API ключ на access токен с областями ключа.
+// Внутренний маршрут API Gateway: шлюз не публикует его и кеширует токен до истечения.
+// Заблокированный или деактивированный пользователь и черный список проверяются
+// так же, как при обновлении токена
+func (h *APIKeyHandler) AuthenticateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req models.APIKeyAuthRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	key, err := h.apiKeys(r).GetByHash(utils.HashRefreshToken(req.APIKey))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.LogAuthEvent(r, "api_key_auth", "", false, err.Error())
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки API ключа")
+			return
+		}
+		logger.LogAuthEvent(r, "api_key_auth", "", false, "unknown api key")
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Недействительный API ключ")
+		return
+	}
+	if !key.IsActive() {
+		logger.LogAuthEvent(r, "api_key_auth", "", false, "api key revoked or expired, api_key_id="+key.ID.String())
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Недействительный API ключ")
+		return
+	}
+
+	user, err := h.users(r).GetByID(key.UserID)
+	if err != nil {
+		logger.LogAuthEvent(r, "api_key_auth", "", false, err.Error())
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Недействительный API ключ")
+		return
+	}
+	if user.IsBlocked() {
+		logger.LogAuthEvent(r, "api_key_auth", user.Email, false, "user is blocked")
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Пользователь заблокирован")
+		return
+	}
+	if entry := h.blacklistedLogin(r, user); entry != nil {
+		logger.LogAuthEvent(r, "api_key_auth", user.Email, false, fmt.Sprintf("blacklisted: %s=%s", entry.Kind, entry.Value))
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeBlacklisted, "Вход запрещен")
+		return
+	}
+
+	ttl := h.config.APIKeys.TokenTTL
+	token, err := utils.GenerateAPIKeyJWT(user, key.Scopes, h.config.JWT.Signer, ttl)
+	if err != nil {
+		logger.LogAuthEvent(r, "api_key_auth", user.Email, false, "Token generation failed")
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка генерации токена")
+		return
+	}
+
+	// Время использования информационное: ошибка не мешает аутентификации
+	if err := h.apiKeys(r).Touch(key.ID); err != nil {
+		logger.LogAuthEvent(r, "api_key_auth", user.Email, false, err.Error())
+	}
+
+	logger.LogAuthEvent(r, "api_key_auth", user.Email, true, "api_key_id="+key.ID.String())
+	h.sendSuccessResponse(w, http.StatusOK, models.APIKeyAuthResponse{
+		Token:     token,
+		ExpiresIn: int(ttl.Seconds()),
+		Scopes:    key.Scopes,
+	})
+}
+
+// uniqueScopes возвращает области без повторов в порядке первого упоминания
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
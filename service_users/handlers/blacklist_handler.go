@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/registration"
+	"service_users/repository"
+	"service_users/utils"
+
+	"pkg/ids"
+	"pkg/rbac"
+	"pkg/servertiming"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// BlacklistHandler обработчик управления черным списком покупателей. Записи block
+// запрещают вход, а в service_orders — создание заказов; записи flag отправляют заказы
+// на проверку администратором
+type BlacklistHandler struct {
+	*UserHandler
+}
+
+// NewBlacklistHandler создает новый обработчик черного списка
+func NewBlacklistHandler(userHandler *UserHandler) *BlacklistHandler {
+	return &BlacklistHandler{UserHandler: userHandler}
+}
+
+// blacklist возвращает черный список, учитывающий время запросов к БД в Server-Timing запроса r
+func (h *UserHandler) blacklist(r *http.Request) repository.BlacklistRepository {
+	return repository.TimedBlacklistRepository(h.blacklistRepo, servertiming.FromContext(r.Context()))
+}
+
+// ListBlacklist возвращает записи черного списка, новые первыми; параметр kind
+// (user, email_domain или ip) ограничивает вид записей (право blacklist.manage)
+func (h *BlacklistHandler) ListBlacklist(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.BlacklistManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	kind := models.BlacklistKind(r.URL.Query().Get("kind"))
+	if kind != "" && !kind.IsValid() {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Параметр kind должен быть одним из: user, email_domain, ip")
+		return
+	}
+
+	entries, err := h.blacklist(r).List(kind)
+	if err != nil {
+		logger.LogUserAction(r, "blacklist_list", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения черного списка")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, entries)
+}
+
+// AddBlacklistEntry добавляет пользователя, домен email или IP адрес (сеть CIDR) в черный
+// список или заменяет действие и причину существующей записи (право blacklist.manage).
+// Уже выданные токены не отзываются: запрет входа действует со следующего входа или
+// обновления токена
+func (h *BlacklistHandler) AddBlacklistEntry(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.BlacklistManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	adminID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	var req models.AddBlacklistEntryRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	value, err := normalizeBlacklistValue(req.Kind, req.Value)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+	action := req.Action
+	if action == "" {
+		action = models.BlacklistActionBlock
+	}
+
+	entry, err := h.blacklist(r).Add(&models.BlacklistEntry{
+		ID:        ids.New(),
+		Kind:      req.Kind,
+		Value:     value,
+		Action:    action,
+		Reason:    req.Reason,
+		CreatedBy: &adminID,
+	})
+	if err != nil {
+		logger.LogUserAction(r, "blacklist_add", fmt.Sprintf("%s=%s, error=%v", req.Kind, value, err), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка сохранения записи черного списка")
+		return
+	}
+
+	details := fmt.Sprintf("action=%s, reason=%s", entry.Action, entry.Reason)
+	logger.LogUserAction(r, "blacklist_add", fmt.Sprintf("%s=%s, %s", entry.Kind, entry.Value, details), true)
+	h.recordAdminAction(r, "blacklist_add", string(entry.Kind)+":"+entry.Value, details)
+	h.sendSuccessResponse(w, http.StatusCreated, entry)
+}
+
+// RemoveBlacklistEntry удаляет запись черного списка (право blacklist.manage)
+func (h *BlacklistHandler) RemoveBlacklistEntry(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.BlacklistManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	entryID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID записи")
+		return
+	}
+
+	removed, err := h.blacklist(r).Remove(entryID)
+	if err != nil {
+		logger.LogUserAction(r, "blacklist_remove", fmt.Sprintf("id=%s, error=%v", entryID, err), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка удаления записи черного списка")
+		return
+	}
+	if !removed {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Запись черного списка не найдена")
+		return
+	}
+
+	logger.LogUserAction(r, "blacklist_remove", "id="+entryID.String(), true)
+	h.recordAdminAction(r, "blacklist_remove", entryID.String(), "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// normalizeBlacklistValue проверяет значение записи вида kind и приводит его к виду,
+// в котором оно сравнивается при проверке
+func normalizeBlacklistValue(kind models.BlacklistKind, value string) (string, error) {
+	switch kind {
+	case models.BlacklistKindUser:
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			return "", fmt.Errorf("некорректный ID пользователя %q", value)
+		}
+		return userID.String(), nil
+	case models.BlacklistKindEmailDomain:
+		return registration.NormalizeDomain(value)
+	case models.BlacklistKindIP:
+		if ip := net.ParseIP(value); ip != nil {
+			return ip.String(), nil
+		}
+		if _, network, err := net.ParseCIDR(value); err == nil {
+			return network.String(), nil
+		}
+		return "", fmt.Errorf("некорректный IP адрес или сеть %q", value)
+	}
+	return "", fmt.Errorf("неизвестный вид записи %q", kind)
+}
+
+// blacklistedLogin возвращает запись черного списка с действием block, совпавшую
+// с пользователем, доменом его email или IP адресом клиента, или nil. Записи flag вход
+// не ограничивают; ошибка проверки не мешает входу
+func (h *UserHandler) blacklistedLogin(r *http.Request, user *models.User) *models.BlacklistEntry {
+	if h.blacklistRepo == nil {
+		return nil
+	}
+
+	entry, err := h.blacklist(r).Match(user.ID, user.Email, clientIP(r))
+	if err != nil {
+		logger.GetLogger().Warn("Failed to check blacklist", zap.String("user_id", user.ID.String()), zap.Error(err))
+		return nil
+	}
+	if entry == nil || entry.Action != models.BlacklistActionBlock {
+		return nil
+	}
+	return entry
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"service_users/logger"
+	"service_users/models"
+
+	"pkg/rbac"
+	"pkg/rolesepoch"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// DeactivationHandler обработчик деактивации учетных записей. Деактивированный
+// пользователь не может войти, но его строка users и связанные заказы сохраняются
+type DeactivationHandler struct {
+	*UserHandler
+	// epochs nil, если Redis не настроен: тогда access токены деактивированного
+	// пользователя действуют до истечения срока
+	epochs *rolesepoch.Store
+}
+
+// NewDeactivationHandler создает новый обработчик деактивации учетных записей
+func NewDeactivationHandler(userHandler *UserHandler, epochs *rolesepoch.Store) *DeactivationHandler {
+	return &DeactivationHandler{
+		UserHandler: userHandler,
+		epochs:      epochs,
+	}
+}
+
+// DeactivateUser деактивирует учетную запись (право users.manage): отзывает
+// refresh токены и публикует новую эпоху ролей, чтобы API Gateway перестал принимать
+// ранее выданные access токены
+func (h *DeactivationHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.deactivationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.users(r).GetByID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+	if user.IsDeactivated() {
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, "Учетная запись уже деактивирована")
+		return
+	}
+
+	user, err = h.users(r).Deactivate(userID)
+	if err != nil {
+		logger.LogUserAction(r, "user_deactivate", fmt.Sprintf("user_id=%s", userID), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка деактивации учетной записи")
+		return
+	}
+
+	if err := h.refreshTokens(r).RevokeAllForUser(user.ID); err != nil {
+		logger.LogUserAction(r, "user_deactivate", fmt.Sprintf("user_id=%s, revoke refresh tokens: %v", userID, err), false)
+	}
+	publishRolesEpoch(r, h.epochs, user.ID, user.RolesEpoch)
+
+	logger.LogAuthEvent(r, "account_deactivated", user.Email, true, fmt.Sprintf("user_id=%s, epoch=%d", userID, user.RolesEpoch))
+	h.recordAdminAction(r, "user_deactivate", userID.String(), "")
+
+	h.sendSuccessResponse(w, http.StatusOK, user)
+}
+
+// ReactivateUser снимает деактивацию учетной записи (право users.manage).
+// Роли пользователя не меняются; войти можно снова сразу
+func (h *DeactivationHandler) ReactivateUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.deactivationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := h.users(r).GetByID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+	if !user.IsDeactivated() {
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, "Учетная запись не деактивирована")
+		return
+	}
+
+	user, err = h.users(r).Reactivate(userID)
+	if err != nil {
+		logger.LogUserAction(r, "user_reactivate", fmt.Sprintf("user_id=%s", userID), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка восстановления учетной записи")
+		return
+	}
+
+	logger.LogAuthEvent(r, "account_reactivated", user.Email, true, fmt.Sprintf("user_id=%s", userID))
+	h.recordAdminAction(r, "user_reactivate", userID.String(), "")
+
+	h.sendSuccessResponse(w, http.StatusOK, user)
+}
+
+// deactivationTarget проверяет право users.manage и возвращает ID пользователя из пути.
+// Собственную учетную запись изменить нельзя, чтобы администратор не потерял доступ.
+// Возвращает false, если ответ с ошибкой уже отправлен
+func (h *DeactivationHandler) deactivationTarget(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	if !h.can(r, rbac.UsersManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return uuid.Nil, false
+	}
+
+	adminID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID пользователя")
+		return uuid.Nil, false
+	}
+	if userID == adminID {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Нельзя изменить собственную учетную запись")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
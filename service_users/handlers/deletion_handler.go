@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"service_users/logger"
+	"service_users/mail"
+	"service_users/models"
+	"service_users/repository"
+	"service_users/utils"
+
+	"pkg/ids"
+	"pkg/rbac"
+	"pkg/servertiming"
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// deletionConfirmationSendTimeout ограничение времени отправки письма с токеном подтверждения удаления
+const deletionConfirmationSendTimeout = 30 * time.Second
+
+// DeletionNotifier уведомляет обработчик операций удаления о новой операции
+type DeletionNotifier interface {
+	Notify()
+}
+
+// DeletionHandler обработчик удаления данных пользователей (право на забвение)
+type DeletionHandler struct {
+	*UserHandler
+	deletionRepo repository.UserDeletionRepository
+	worker       DeletionNotifier
+	sender       mail.Sender
+}
+
+// NewDeletionHandler создает новый обработчик удаления данных пользователей
+func NewDeletionHandler(userHandler *UserHandler, deletionRepo repository.UserDeletionRepository, worker DeletionNotifier, sender mail.Sender) *DeletionHandler {
+	return &DeletionHandler{
+		UserHandler:  userHandler,
+		deletionRepo: deletionRepo,
+		worker:       worker,
+		sender:       sender,
+	}
+}
+
+// deletions возвращает репозиторий операций удаления, учитывающий время запросов к БД
+// в Server-Timing запроса r
+func (h *DeletionHandler) deletions(r *http.Request) repository.UserDeletionRepository {
+	return repository.TimedUserDeletionRepository(h.deletionRepo, servertiming.FromContext(r.Context()))
+}
+
+// DeleteCurrentUser запрашивает удаление данных текущего пользователя в два шага: запрос
+// без confirmation_token ничего не удаляет и отправляет одноразовый токен подтверждения
+// на email пользователя, повторный запрос с токеном создает операцию удаления. Операция
+// выполняется асинхронно: ответ 202 содержит ее статус
+func (h *DeletionHandler) DeleteCurrentUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireInteractiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if r.ContentLength != 0 && !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if req.ConfirmationToken == "" {
+		h.issueDeletionConfirmation(w, r, userID)
+		return
+	}
+
+	if err := h.deletions(r).ConsumeConfirmation(userID, utils.HashRefreshToken(req.ConfirmationToken)); err != nil {
+		if errors.Is(err, repository.ErrDeletionConfirmationInvalid) {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Токен подтверждения удаления недействителен или истек, запросите новый")
+			return
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки подтверждения удаления")
+		return
+	}
+
+	h.requestDeletion(w, r, userID, userID)
+}
+
+// issueDeletionConfirmation отправляет токен подтверждения удаления данных на email
+// пользователя: украденного access токена недостаточно, чтобы удалить учетную запись.
+// Действует только последний выданный токен
+func (h *DeletionHandler) issueDeletionConfirmation(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	user, err := h.users(r).GetByID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+
+	token, tokenHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка генерации токена подтверждения")
+		return
+	}
+	expiresAt := timeutil.Now().Add(h.config.Deletion.ConfirmationTTL)
+	if err := h.deletions(r).CreateConfirmation(userID, tokenHash, expiresAt); err != nil {
+		logger.LogUserAction(r, "user_deletion_confirmation", fmt.Sprintf("user_id=%s", userID), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка сохранения подтверждения удаления")
+		return
+	}
+
+	go h.sendConfirmationMessage(user.Email, token)
+
+	logger.LogUserAction(r, "user_deletion_confirmation", fmt.Sprintf("user_id=%s", userID), true)
+	h.sendSuccessResponse(w, http.StatusOK, models.DeletionConfirmation{
+		Message:   "Токен подтверждения удаления отправлен на email",
+		ExpiresAt: expiresAt,
+	})
+}
+
+// sendConfirmationMessage отправляет письмо с токеном подтверждения удаления данных
+func (h *DeletionHandler) sendConfirmationMessage(email, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), deletionConfirmationSendTimeout)
+	defer cancel()
+
+	ttl := h.config.Deletion.ConfirmationTTL
+	body := fmt.Sprintf("Здравствуйте!\n\nМы получили запрос на удаление ваших данных. Код подтверждения:\n\n%s\n\n"+
+		"Срок действия — %d мин., использовать можно один раз. После подтверждения данные будут удалены без возможности восстановления.\n\n"+
+		"Если вы не запрашивали удаление, смените пароль: кто-то мог получить доступ к вашей учетной записи.\n",
+		token, int(ttl.Round(time.Minute).Minutes()))
+
+	err := h.sender.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Подтверждение удаления данных",
+		Body:    body,
+	})
+	if err != nil {
+		logger.GetLogger().Error("Не удалось отправить письмо с подтверждением удаления данных",
+			zap.String("user_email", email),
+			zap.Error(err),
+		)
+	}
+}
+
+// GetCurrentUserDeletion возвращает последнюю операцию удаления данных текущего пользователя
+func (h *DeletionHandler) GetCurrentUserDeletion(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	h.sendLatestDeletion(w, r, userID)
+}
+
+// DeleteUser запрашивает удаление данных пользователя (право users.manage)
+func (h *DeletionHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.UsersManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	adminID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID пользователя")
+		return
+	}
+
+	h.requestDeletion(w, r, userID, adminID)
+}
+
+// GetUserDeletion возвращает последнюю операцию удаления данных пользователя (право users.manage)
+func (h *DeletionHandler) GetUserDeletion(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.UsersManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID пользователя")
+		return
+	}
+
+	h.sendLatestDeletion(w, r, userID)
+}
+
+// requestDeletion создает операцию удаления или возвращает уже выполняющуюся.
+// Повторить можно только неудачную операцию: данные после успешной уже удалены
+func (h *DeletionHandler) requestDeletion(w http.ResponseWriter, r *http.Request, userID, requestedBy uuid.UUID) {
+	if _, err := h.users(r).GetByID(userID); err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+
+	latest, err := h.deletions(r).GetLatestForUser(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения операции удаления")
+		return
+	}
+	switch {
+	case latest != nil && latest.IsActive():
+		h.sendSuccessResponse(w, http.StatusAccepted, latest)
+		return
+	case latest != nil && latest.Status == models.UserDeletionCompleted:
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, "Данные пользователя уже удалены")
+		return
+	}
+
+	deletion := &models.UserDeletion{
+		ID:          ids.New(),
+		UserID:      userID,
+		RequestedBy: &requestedBy,
+		Status:      models.UserDeletionPending,
+		CreatedAt:   timeutil.Now(),
+	}
+	if err := h.deletions(r).Create(deletion); err != nil {
+		if errors.Is(err, repository.ErrUserDeletionInProgress) {
+			// Параллельный запрос уже создал операцию
+			if latest, err := h.deletions(r).GetLatestForUser(userID); err == nil && latest != nil {
+				h.sendSuccessResponse(w, http.StatusAccepted, latest)
+				return
+			}
+		}
+		logger.LogUserAction(r, "user_deletion_request", fmt.Sprintf("user_id=%s", userID), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания операции удаления")
+		return
+	}
+
+	h.worker.Notify()
+
+	logger.LogUserAction(r, "user_deletion_request",
+		fmt.Sprintf("user_id=%s, deletion_id=%s, requested_by=%s", userID, deletion.ID, requestedBy), true)
+	if requestedBy != userID {
+		h.recordAdminAction(r, "user_deletion_request", userID.String(), "deletion_id="+deletion.ID.String())
+	}
+
+	h.sendSuccessResponse(w, http.StatusAccepted, deletion)
+}
+
+// sendLatestDeletion отвечает последней операцией удаления пользователя
+func (h *DeletionHandler) sendLatestDeletion(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	deletion, err := h.deletions(r).GetLatestForUser(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения операции удаления")
+		return
+	}
+	if deletion == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Удаление данных пользователя не запрашивалось")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, deletion)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"service_users/config"
+	"service_users/fakes"
+	"service_users/models"
+
+	"github.com/google/uuid"
+)
+
+// notifyCounter считает уведомления обработчика операций удаления
+type notifyCounter int
+
+func (n *notifyCounter) Notify() { *n++ }
+
+func TestDeleteCurrentUserSendsConfirmationByEmail(t *testing.T) {
+	users := fakes.NewUserRepository()
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Name: "Иван Петров"}
+	users.Seed(user)
+	deletions := fakes.NewUserDeletionRepository()
+	sender := fakes.NewMailSender()
+	var notified notifyCounter
+
+	cfg := &config.Config{}
+	cfg.Deletion.ConfirmationTTL = 15 * time.Minute
+	handler := NewDeletionHandler(NewUserHandler(users, nil, nil, nil, nil, nil, nil, nil, nil, cfg), deletions, &notified, sender)
+
+	rec := httptest.NewRecorder()
+	handler.DeleteCurrentUser(rec, accountRequest(http.MethodDelete, "/v1/users/me", "", user))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("код %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+
+	message, ok := sender.Next(time.Second)
+	if !ok {
+		t.Fatal("письмо с токеном подтверждения не отправлено")
+	}
+	if message.To != user.Email {
+		t.Errorf("письмо отправлено на %q, ожидался email пользователя", message.To)
+	}
+	parts := strings.Split(message.Body, "\n\n")
+	if len(parts) < 3 || parts[2] == "" {
+		t.Fatalf("письмо не содержит токен: %q", message.Body)
+	}
+	token := parts[2]
+	if strings.Contains(rec.Body.String(), token) {
+		t.Fatal("токен подтверждения возвращен в ответе")
+	}
+	if len(deletions.Deletions()) != 0 || notified != 0 {
+		t.Fatal("запрос без токена создал операцию удаления")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.DeleteCurrentUser(rec, accountRequest(http.MethodDelete, "/v1/users/me", `{"confirmation_token":"`+token+`"}`, user))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("код %d, ожидался 202: %s", rec.Code, rec.Body.String())
+	}
+	if created := deletions.Deletions(); len(created) != 1 || created[0].UserID != user.ID || notified != 1 {
+		t.Fatalf("операции удаления %+v, уведомлений %d", created, notified)
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"service_users/directory"
+	"service_users/logger"
+	"service_users/models"
+
+	"pkg/rbac"
+)
+
+// directorySourcePattern допустимые имена источников каталога
+var directorySourcePattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// DirectoryHandler обработчик синхронизации пользователей с внешним каталогом
+type DirectoryHandler struct {
+	*UserHandler
+	syncer *directory.Syncer
+}
+
+// NewDirectoryHandler создает новый обработчик синхронизации с каталогом
+func NewDirectoryHandler(userHandler *UserHandler, syncer *directory.Syncer) *DirectoryHandler {
+	return &DirectoryHandler{
+		UserHandler: userHandler,
+		syncer:      syncer,
+	}
+}
+
+// SyncDirectory импортирует пользователей и роли из выгрузки каталога (право
+// directory.sync). По умолчанию выполняется dry run и возвращается отчет об
+// изменениях; изменения применяются только с dry_run=false.
+// Параметры: format (scim или ldap), source, dry_run, deactivate_missing
+func (h *DirectoryHandler) SyncDirectory(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.DirectorySync) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	adminID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = directory.FormatSCIM
+	}
+	source := query.Get("source")
+	if source == "" {
+		source = h.config.Directory.Source
+	}
+	if !directorySourcePattern.MatchString(source) {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректное имя источника: допустимы a-z, 0-9, _ и -, до 64 символов")
+		return
+	}
+
+	opts := directory.Options{DryRun: true, ActorID: adminID}
+	for name, target := range map[string]*bool{"dry_run": &opts.DryRun, "deactivate_missing": &opts.DeactivateMissing} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, fmt.Sprintf("Параметр %s должен быть true или false", name))
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, directory.MaxPayloadSize))
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusRequestEntityTooLarge, models.ErrorCodeValidation, "Выгрузка каталога слишком большая")
+		return
+	}
+
+	entries, err := directory.Parse(format, body)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+	if len(entries) > directory.MaxEntries {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation,
+			fmt.Sprintf("Выгрузка содержит больше %d учетных записей", directory.MaxEntries))
+		return
+	}
+
+	report, err := h.syncer.Sync(r.Context(), source, entries, opts)
+	if err != nil {
+		if errors.Is(err, directory.ErrEmptyPayload) {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+			return
+		}
+		logger.LogUserAction(r, "directory_sync", fmt.Sprintf("source=%s, error=%v", source, err), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка синхронизации с каталогом")
+		return
+	}
+
+	if !opts.DryRun {
+		logger.LogUserAction(r, "directory_sync", fmt.Sprintf("source=%s, created=%d, updated=%d, deactivated=%d, errors=%d",
+			source, len(report.Created), len(report.Updated), len(report.Deactivated), len(report.Errors)), true)
+		h.recordAdminAction(r, "directory_sync", source, fmt.Sprintf("created=%d, updated=%d, deactivated=%d, errors=%d",
+			len(report.Created), len(report.Updated), len(report.Deactivated), len(report.Errors)))
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, report)
+}
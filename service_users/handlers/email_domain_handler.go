@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/registration"
+	"service_users/repository"
+	"service_users/utils"
+
+	"pkg/rbac"
+	"pkg/servertiming"
+
+	"github.com/gorilla/mux"
+)
+
+// EmailDomainHandler обработчик управления запрещенными доменами email
+type EmailDomainHandler struct {
+	*UserHandler
+	domainRepo repository.EmailDomainRepository
+}
+
+// NewEmailDomainHandler создает новый обработчик запрещенных доменов
+func NewEmailDomainHandler(userHandler *UserHandler, domainRepo repository.EmailDomainRepository) *EmailDomainHandler {
+	return &EmailDomainHandler{
+		UserHandler: userHandler,
+		domainRepo:  domainRepo,
+	}
+}
+
+// domains возвращает репозиторий запрещенных доменов, учитывающий время запросов к БД
+// в Server-Timing запроса r
+func (h *EmailDomainHandler) domains(r *http.Request) repository.EmailDomainRepository {
+	return repository.TimedEmailDomainRepository(h.domainRepo, servertiming.FromContext(r.Context()))
+}
+
+// GetEmailDomainPolicy возвращает запрещенные домены и состояние списка одноразовых
+// доменов (право email_domains.manage)
+func (h *EmailDomainHandler) GetEmailDomainPolicy(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.EmailDomainsManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+	h.sendPolicy(w, r)
+}
+
+// BanEmailDomain добавляет домен в список запрещенных или обновляет причину
+// (право email_domains.manage). Поддомены запрещенного домена тоже запрещаются;
+// уже зарегистрированные пользователи не затрагиваются
+func (h *EmailDomainHandler) BanEmailDomain(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.EmailDomainsManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	adminID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	var req models.BanEmailDomainRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	domain, err := registration.NormalizeDomain(req.Domain)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	if _, err := h.domains(r).Add(domain, req.Reason, adminID); err != nil {
+		logger.LogUserAction(r, "email_domain_ban", fmt.Sprintf("domain=%s, error=%v", domain, err), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка сохранения домена")
+		return
+	}
+
+	logger.LogUserAction(r, "email_domain_ban", fmt.Sprintf("domain=%s, reason=%s", domain, req.Reason), true)
+	h.recordAdminAction(r, "email_domain_ban", domain, "reason="+req.Reason)
+	h.sendPolicy(w, r)
+}
+
+// UnbanEmailDomain удаляет домен из списка запрещенных (право email_domains.manage)
+func (h *EmailDomainHandler) UnbanEmailDomain(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.EmailDomainsManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	domain, err := registration.NormalizeDomain(mux.Vars(r)["domain"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	removed, err := h.domains(r).Remove(domain)
+	if err != nil {
+		logger.LogUserAction(r, "email_domain_unban", fmt.Sprintf("domain=%s, error=%v", domain, err), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка удаления домена")
+		return
+	}
+	if !removed {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Домен не найден в списке запрещенных")
+		return
+	}
+
+	logger.LogUserAction(r, "email_domain_unban", "domain="+domain, true)
+	h.recordAdminAction(r, "email_domain_unban", domain, "")
+	h.sendPolicy(w, r)
+}
+
+// RefreshDisposableDomains загружает актуальный список одноразовых доменов
+// по DISPOSABLE_DOMAINS_URL, не дожидаясь планового обновления (право email_domains.manage)
+func (h *EmailDomainHandler) RefreshDisposableDomains(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.EmailDomainsManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	if h.config.Registration.DisposableURL == "" {
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, "Адрес списка одноразовых доменов не настроен")
+		return
+	}
+
+	count, err := h.emailPolicy.Refresh(r.Context())
+	if err != nil {
+		logger.LogUserAction(r, "disposable_domains_refresh", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusBadGateway, models.ErrorCodeInternalServer, "Не удалось обновить список одноразовых доменов")
+		return
+	}
+
+	logger.LogUserAction(r, "disposable_domains_refresh", fmt.Sprintf("count=%d", count), true)
+	h.recordAdminAction(r, "disposable_domains_refresh", h.config.Registration.DisposableURL, fmt.Sprintf("count=%d", count))
+	h.sendPolicy(w, r)
+}
+
+// sendPolicy отправляет текущую политику доменов
+func (h *EmailDomainHandler) sendPolicy(w http.ResponseWriter, r *http.Request) {
+	banned, err := h.domains(r).List()
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения запрещенных доменов")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, models.EmailDomainPolicy{
+		Banned:     banned,
+		Disposable: h.emailPolicy.DisposableState(),
+	})
+}
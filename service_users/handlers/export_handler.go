@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/repository"
+
+	"pkg/ids"
+	"pkg/rbac"
+	"pkg/servertiming"
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// exportRetryAfter через сколько секунд предлагается повторить выгрузку, если
+// предыдущая выгрузка пользователя еще формируется
+const exportRetryAfter = 10
+
+// exportDownloadPath путь повторного скачивания сохраненной выгрузки
+const exportDownloadPath = "/v1/admin/access-review/exports/"
+
+// ExportHandler обработчик выгрузок: не дает пользователю формировать несколько выгрузок
+// одновременно и хранит сформированные файлы для докачки по Range до истечения EXPORT_TTL
+type ExportHandler struct {
+	*UserHandler
+	exportRepo repository.ExportRepository
+}
+
+// NewExportHandler создает новый обработчик выгрузок
+func NewExportHandler(userHandler *UserHandler, exportRepo repository.ExportRepository) *ExportHandler {
+	return &ExportHandler{
+		UserHandler: userHandler,
+		exportRepo:  exportRepo,
+	}
+}
+
+// exports возвращает репозиторий выгрузок, учитывающий время запросов к БД запроса r
+func (h *ExportHandler) exports(r *http.Request) repository.ExportRepository {
+	return repository.TimedExportRepository(h.exportRepo, servertiming.FromContext(r.Context()))
+}
+
+// DownloadExport отдает сохраненную выгрузку вызывающего администратора. Поддерживает
+// Range и If-Range (ETag — ID выгрузки), чтобы прерванное скачивание можно было продолжить
+func (h *ExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.AccessReviewRead) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	exportID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID выгрузки")
+		return
+	}
+
+	artifact, found, err := h.exports(r).Get(exportID, userID, timeutil.Now())
+	if err != nil {
+		logger.LogUserAction(r, "download_export", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения выгрузки")
+		return
+	}
+	if !found {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Выгрузка не найдена или срок ее хранения истек")
+		return
+	}
+
+	serveExport(w, r, artifact)
+}
+
+// beginExport захватывает блокировку выгрузок пользователя на время формирования выгрузки.
+// Если у пользователя уже формируется выгрузка, отвечает 429 с Retry-After.
+// Возвращает false, если ответ уже отправлен; иначе unlock нужно вызвать по завершении
+func (h *ExportHandler) beginExport(w http.ResponseWriter, r *http.Request, action string, userID uuid.UUID) (unlock func(), ok bool) {
+	unlock, err := h.exports(r).Lock(userID)
+	if errors.Is(err, repository.ErrExportInProgress) {
+		logger.LogUserAction(r, action, "export already in progress", false)
+		w.Header().Set("Retry-After", strconv.Itoa(exportRetryAfter))
+		h.sendErrorResponse(w, http.StatusTooManyRequests, models.ErrorCodeExportInProgress, "Предыдущая выгрузка еще формируется. Повторите попытку позже")
+		return nil, false
+	}
+	if err != nil {
+		logger.LogUserAction(r, action, err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка начала выгрузки")
+		return nil, false
+	}
+	return unlock, true
+}
+
+// sendExport сохраняет сформированную выгрузку на EXPORT_TTL и отправляет ее целиком.
+// Адрес для докачки передается в Content-Location; если сохранить выгрузку не удалось,
+// она отправляется без него
+func (h *ExportHandler) sendExport(w http.ResponseWriter, r *http.Request, artifact *models.ExportArtifact) {
+	now := timeutil.Now()
+	artifact.ID = ids.New()
+	artifact.CreatedAt = now
+	artifact.ExpiresAt = now.Add(h.config.Export.TTL)
+
+	if err := h.exports(r).Create(artifact); err != nil {
+		logger.LogUserAction(r, "save_export", err.Error(), false)
+	} else {
+		w.Header().Set("Content-Location", exportDownloadPath+artifact.ID.String())
+	}
+
+	// Range относится к ранее сохраненной выгрузке, а не к только что сформированной
+	r = r.Clone(r.Context())
+	r.Header.Del("Range")
+	serveExport(w, r, artifact)
+}
+
+// serveExport отправляет выгрузку с учетом Range, If-Range и условных заголовков
+func serveExport(w http.ResponseWriter, r *http.Request, artifact *models.ExportArtifact) {
+	w.Header().Set("Content-Type", artifact.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Filename))
+	w.Header().Set("ETag", strconv.Quote(artifact.ID.String()))
+	w.Header().Set("Cache-Control", "private")
+	w.Header().Set("Expires", artifact.ExpiresAt.UTC().Format(http.TimeFormat))
+	http.ServeContent(w, r, artifact.Filename, artifact.CreatedAt, bytes.NewReader(artifact.Content))
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"service_users/models"
+)
+
+// jwksMaxAge время кеширования JWKS клиентами, секунд. Новый ключ публикуется в JWKS
+// до перехода на него (как предыдущий), поэтому кеш не мешает ротации
+const jwksMaxAge = "300"
+
+// JWKSHandler обработчик публикации публичных ключей подписи токенов
+type JWKSHandler struct {
+	*UserHandler
+}
+
+// NewJWKSHandler создает новый обработчик JWKS
+func NewJWKSHandler(userHandler *UserHandler) *JWKSHandler {
+	return &JWKSHandler{UserHandler: userHandler}
+}
+
+// GetJWKS возвращает публичные ключи подписи токенов в формате JWKS (RFC 7517),
+// без обертки ответа API: формат ожидают Gateway и сторонние библиотеки JWT.
+// При подписи HS256 ключей для публикации нет
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	if h.config.JWT.SigningKeys == nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Токены подписываются общим секретом, JWKS не публикуется")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age="+jwksMaxAge)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.config.JWT.SigningKeys.JWKS())
+}
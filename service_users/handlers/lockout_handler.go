@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"service_users/lockout"
+	"service_users/logger"
+	"service_users/models"
+	"service_users/repository"
+
+	"pkg/rbac"
+	"pkg/servertiming"
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Ограничения длины адреса и User-Agent клиента, сохраняемых в попытках входа и сессиях
+const (
+	maxClientIPLength  = 64
+	maxUserAgentLength = 512
+)
+
+// LockoutHandler обработчик администрирования блокировок входа
+type LockoutHandler struct {
+	*UserHandler
+}
+
+// NewLockoutHandler создает новый обработчик блокировок входа
+func NewLockoutHandler(userHandler *UserHandler) *LockoutHandler {
+	return &LockoutHandler{UserHandler: userHandler}
+}
+
+// UnlockUser снимает блокировку входа пользователя и сбрасывает счетчик неудачных
+// входов (право users.manage). Блокировки IP адресов снимаются по истечении срока
+func (h *LockoutHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.UsersManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID пользователя")
+		return
+	}
+
+	user, err := h.users(r).GetByID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+
+	response := models.UnlockUserResponse{UserID: userID}
+	if h.lockoutRepo != nil {
+		state, err := h.lockouts(r).Get(models.LockoutScopeAccount, userID.String())
+		if err == nil {
+			err = h.lockouts(r).Reset(models.LockoutScopeAccount, userID.String())
+		}
+		if err != nil {
+			logger.LogUserAction(r, "user_unlock", err.Error(), false)
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка снятия блокировки входа")
+			return
+		}
+		response.WasLocked = state.IsLocked(timeutil.Now())
+		response.Failures = state.Failures
+	}
+
+	logger.LogAuthEvent(r, "account_unlocked", user.Email, true, fmt.Sprintf("user_id=%s, was_locked=%t", userID, response.WasLocked))
+	h.recordAdminAction(r, "user_unlock", userID.String(), fmt.Sprintf("was_locked=%t", response.WasLocked))
+
+	h.sendSuccessResponse(w, http.StatusOK, response)
+}
+
+// lockouts возвращает репозиторий блокировок входа, учитывающий время запросов к БД запроса r
+func (h *UserHandler) lockouts(r *http.Request) repository.LoginLockoutRepository {
+	return repository.TimedLoginLockoutRepository(h.lockoutRepo, servertiming.FromContext(r.Context()))
+}
+
+// lockoutPolicy возвращает правила блокировки области scope из конфигурации
+func (h *UserHandler) lockoutPolicy(scope string) lockout.Policy {
+	cfg := h.config.Lockout
+	policy := lockout.Policy{
+		MaxFailures: cfg.MaxFailures,
+		Window:      cfg.Window,
+		Duration:    cfg.Duration,
+		MaxDuration: cfg.MaxDuration,
+		ResetAfter:  cfg.ResetAfter,
+	}
+	if scope == models.LockoutScopeIP {
+		policy.MaxFailures = cfg.IPMaxFailures
+	}
+	return policy
+}
+
+// checkLoginLockout отклоняет вход с ответом 429 и Retry-After, если область заблокирована.
+// Ошибка чтения блокировки не мешает входу. Возвращает false, если ответ уже отправлен
+func (h *UserHandler) checkLoginLockout(w http.ResponseWriter, r *http.Request, scope, subject, email string) bool {
+	if h.lockoutRepo == nil || !h.lockoutPolicy(scope).Enabled() {
+		return true
+	}
+
+	state, err := h.lockouts(r).Get(scope, subject)
+	if err != nil {
+		logger.GetLogger().Warn("Failed to check login lockout", zap.String("scope", scope), zap.Error(err))
+		return true
+	}
+	now := timeutil.Now()
+	if !state.IsLocked(now) {
+		return true
+	}
+
+	var userID *uuid.UUID
+	if scope == models.LockoutScopeAccount {
+		if id, err := uuid.Parse(subject); err == nil {
+			userID = &id
+		}
+	}
+	h.recordLoginAttempt(r, userID, "", false)
+	logger.LogAuthEvent(r, "login_locked", email, false,
+		fmt.Sprintf("scope=%s, locked_until=%s", scope, state.LockedUntil.Format(time.RFC3339)))
+
+	retryAfter := int(math.Ceil(state.LockedUntil.Sub(now).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	h.sendErrorResponse(w, http.StatusTooManyRequests, models.ErrorCodeLoginLocked, "Слишком много неудачных попыток входа. Повторите попытку позже")
+	return false
+}
+
+// recordLoginFailure учитывает неудачный вход в области scope. Блокировка области
+// пишется в лог событием account_locked или ip_locked для мониторинга.
+// Ошибка сохранения не влияет на ответ клиенту
+func (h *UserHandler) recordLoginFailure(r *http.Request, scope, subject, email string) {
+	policy := h.lockoutPolicy(scope)
+	if h.lockoutRepo == nil || !policy.Enabled() {
+		return
+	}
+
+	state, locked, err := h.lockouts(r).RecordFailure(scope, subject, policy, timeutil.Now())
+	if err != nil {
+		logger.GetLogger().Warn("Failed to record login failure", zap.String("scope", scope), zap.Error(err))
+		return
+	}
+	if locked {
+		logger.LogAuthEvent(r, scope+"_locked", email, false,
+			fmt.Sprintf("subject=%s, lock_count=%d, locked_until=%s", subject, state.LockCount, state.LockedUntil.Format(time.RFC3339)))
+	}
+}
+
+// resetLoginLockout сбрасывает счетчик неудачных входов учетной записи после
+// успешного входа или смены пароля. Ошибка не влияет на ответ клиенту
+func (h *UserHandler) resetLoginLockout(r *http.Request, userID uuid.UUID) {
+	if h.lockoutRepo == nil {
+		return
+	}
+	if err := h.lockouts(r).Reset(models.LockoutScopeAccount, userID.String()); err != nil {
+		logger.GetLogger().Warn("Failed to reset login lockout", zap.String("user_id", userID.String()), zap.Error(err))
+	}
+}
+
+// clientIP возвращает IP адрес клиента. Сервис доступен только через API Gateway,
+// который добавляет адрес клиента последним элементом X-Forwarded-For; без заголовка
+// используется адрес соединения
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		parts := strings.Split(forwarded, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// truncate обрезает value до max байт, не разрывая символы UTF-8
+func truncate(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	value = value[:max]
+	for len(value) > 0 && !utf8.ValidString(value) {
+		value = value[:len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"service_users/logger"
+	"service_users/models"
+
+	"pkg/rbac"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// LoginHistoryHandler обработчик истории входов: пользователь видит свои попытки входа,
+// администратор — попытки любого пользователя. История ведется, пока включен учет
+// попыток входа (LOGIN_ATTEMPTS_RETENTION > 0), и хранится столько же
+type LoginHistoryHandler struct {
+	*UserHandler
+}
+
+// NewLoginHistoryHandler создает новый обработчик истории входов
+func NewLoginHistoryHandler(userHandler *UserHandler) *LoginHistoryHandler {
+	return &LoginHistoryHandler{UserHandler: userHandler}
+}
+
+// GetLoginHistory возвращает попытки входа текущего пользователя, новые первыми.
+// Параметры: limit (по умолчанию 20, не больше 100), offset
+func (h *LoginHistoryHandler) GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	h.sendLoginHistory(w, r, userID)
+}
+
+// GetUserLoginHistory возвращает попытки входа пользователя (право users.read)
+func (h *LoginHistoryHandler) GetUserLoginHistory(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.UsersRead) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID пользователя")
+		return
+	}
+
+	if _, err := h.users(r).GetByID(userID); err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+
+	h.sendLoginHistory(w, r, userID)
+}
+
+// sendLoginHistory отправляет страницу истории входов пользователя userID
+// по параметрам limit и offset запроса r
+func (h *LoginHistoryHandler) sendLoginHistory(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	response := models.LoginAttemptsResponse{
+		Attempts: []models.LoginAttempt{},
+		Limit:    20, // значение по умолчанию
+		Offset:   0,
+	}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil && limit > 0 && limit <= 100 {
+			response.Limit = limit
+		}
+	}
+
+	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil && offset >= 0 {
+			response.Offset = offset
+		}
+	}
+
+	// Учет попыток входа отключен: история пуста
+	if h.loginAttempts == nil {
+		h.sendSuccessResponse(w, http.StatusOK, response)
+		return
+	}
+
+	attempts, total, err := h.attempts(r).ListForUser(userID, response.Limit, response.Offset)
+	if err != nil {
+		logger.LogUserAction(r, "login_history", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения истории входов")
+		return
+	}
+
+	response.Attempts = attempts
+	response.Total = total
+	h.sendSuccessResponse(w, http.StatusOK, response)
+}
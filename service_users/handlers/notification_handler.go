@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/repository"
+
+	"pkg/servertiming"
+)
+
+// NotificationHandler обработчик настроек уведомлений пользователя
+type NotificationHandler struct {
+	*UserHandler
+	notificationRepo repository.NotificationRepository
+}
+
+// NewNotificationHandler создает новый обработчик настроек уведомлений
+func NewNotificationHandler(userHandler *UserHandler, notificationRepo repository.NotificationRepository) *NotificationHandler {
+	return &NotificationHandler{
+		UserHandler:      userHandler,
+		notificationRepo: notificationRepo,
+	}
+}
+
+// notifications возвращает репозиторий настроек уведомлений, учитывающий время запросов
+// к БД в Server-Timing запроса r
+func (h *NotificationHandler) notifications(r *http.Request) repository.NotificationRepository {
+	return repository.TimedNotificationRepository(h.notificationRepo, servertiming.FromContext(r.Context()))
+}
+
+// GetNotificationPreferences возвращает настройки уведомлений текущего пользователя
+func (h *NotificationHandler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	prefs, err := h.notifications(r).GetByUserID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения настроек уведомлений")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, prefs)
+}
+
+// UpdateNotificationPreferences обновляет настройки уведомлений текущего пользователя
+func (h *NotificationHandler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	prefs := &models.NotificationPreferences{
+		UserID:   userID,
+		Channels: req.Channels,
+		Events:   req.Events,
+	}
+
+	if err := h.notifications(r).Upsert(prefs); err != nil {
+		logger.LogUserAction(r, "notification_preferences_update", fmt.Sprintf("user_id=%s", userID), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка сохранения настроек уведомлений")
+		return
+	}
+
+	logger.LogUserAction(r, "notification_preferences_update", fmt.Sprintf("user_id=%s", userID), true)
+	h.sendSuccessResponse(w, http.StatusOK, prefs)
+}
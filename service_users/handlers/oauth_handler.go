@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/oauth"
+	"service_users/repository"
+
+	"pkg/ids"
+	"pkg/servertiming"
+	"pkg/timeutil"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// oauthStateCookie cookie со state и code verifier на время входа у провайдера.
+// Связывает ответ провайдера с браузером, начавшим вход: без нее чужой код
+// авторизации, подставленный по ссылке, выполнил бы вход в учетную запись злоумышленника
+const oauthStateCookie = "oauth_state"
+
+// OAuthHandler обработчик входа через внешних провайдеров OAuth2
+type OAuthHandler struct {
+	*UserHandler
+	identityRepo repository.IdentityRepository
+	providers    map[string]oauth.Provider
+}
+
+// NewOAuthHandler создает новый обработчик входа через провайдеров
+func NewOAuthHandler(userHandler *UserHandler, identityRepo repository.IdentityRepository, providers []oauth.Provider) *OAuthHandler {
+	byName := make(map[string]oauth.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+	return &OAuthHandler{
+		UserHandler:  userHandler,
+		identityRepo: identityRepo,
+		providers:    byName,
+	}
+}
+
+// identities возвращает репозиторий учетных записей провайдеров, учитывающий время запросов к БД запроса r
+func (h *OAuthHandler) identities(r *http.Request) repository.IdentityRepository {
+	return repository.TimedIdentityRepository(h.identityRepo, servertiming.FromContext(r.Context()))
+}
+
+// StartOAuthLogin перенаправляет на страницу входа провайдера. State и code verifier
+// PKCE сохраняются в cookie до возврата от провайдера
+func (h *OAuthHandler) StartOAuthLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.provider(w, r)
+	if !ok {
+		return
+	}
+
+	state, verifier, err := oauth.NewState()
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка начала входа через провайдера")
+		return
+	}
+
+	http.SetCookie(w, h.stateCookie(provider.Name(), state+"."+verifier, int(h.config.OAuth.StateTTL.Seconds())))
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, provider.AuthURL(state, oauth.CodeChallenge(verifier), h.redirectURL(provider.Name())), http.StatusFound)
+}
+
+// OAuthCallback завершает вход через провайдера: проверяет state, получает учетную
+// запись провайдера, находит, связывает или создает пользователя и выдает токены
+// так же, как вход по паролю
+func (h *OAuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.provider(w, r)
+	if !ok {
+		return
+	}
+
+	// State одноразовый: cookie удаляется при любом исходе
+	cookie, cookieErr := r.Cookie(oauthStateCookie)
+	http.SetCookie(w, h.stateCookie(provider.Name(), "", -1))
+
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		logger.LogAuthEvent(r, "oauth_login", "", false, fmt.Sprintf("provider=%s, error=%s", provider.Name(), reason))
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Вход через провайдера отменен")
+		return
+	}
+
+	var state, verifier string
+	if cookieErr == nil {
+		state, verifier, _ = strings.Cut(cookie.Value, ".")
+	}
+	if state == "" || verifier == "" || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный или устаревший параметр state, начните вход заново")
+		return
+	}
+	code := query.Get("code")
+	if code == "" {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Отсутствует код авторизации")
+		return
+	}
+
+	identity, err := provider.Identify(r.Context(), code, verifier, h.redirectURL(provider.Name()))
+	if err != nil {
+		h.recordLoginAttempt(r, nil, provider.Name(), false)
+		logger.LogAuthEvent(r, "oauth_login", "", false, fmt.Sprintf("provider=%s, error=%v", provider.Name(), err))
+		if errors.Is(err, oauth.ErrEmailNotVerified) {
+			h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, oauth.ErrEmailNotVerified.Error())
+			return
+		}
+		h.sendErrorResponse(w, http.StatusBadGateway, models.ErrorCodeInternalServer, "Не удалось получить учетную запись провайдера")
+		return
+	}
+
+	user, ok := h.resolveUser(w, r, identity)
+	if !ok {
+		return
+	}
+
+	// Заблокированный (без ролей) или деактивированный пользователь не может войти
+	if user.IsBlocked() {
+		h.recordLoginAttempt(r, &user.ID, provider.Name(), false)
+		logger.LogAuthEvent(r, "oauth_login", user.Email, false, "User is blocked")
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Пользователь заблокирован")
+		return
+	}
+	if entry := h.blacklistedLogin(r, user); entry != nil {
+		h.recordLoginAttempt(r, &user.ID, provider.Name(), false)
+		logger.LogAuthEvent(r, "oauth_login", user.Email, false, fmt.Sprintf("blacklisted: %s=%s", entry.Kind, entry.Value))
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeBlacklisted, "Вход запрещен")
+		return
+	}
+
+	token, err := h.generateAccessToken(r, user)
+	if err != nil {
+		logger.LogAuthEvent(r, "oauth_login", user.Email, false, "Token generation failed")
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка генерации токена")
+		return
+	}
+	refreshToken, err := h.issueRefreshToken(r, user.ID)
+	if err != nil {
+		logger.LogAuthEvent(r, "oauth_login", user.Email, false, err.Error())
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка генерации токена")
+		return
+	}
+
+	h.recordLoginAttempt(r, &user.ID, provider.Name(), true)
+	h.recordLogin(r, user.ID)
+	logger.LogAuthEvent(r, "oauth_login", user.Email, true, "provider="+provider.Name())
+
+	user.Password = ""
+	h.sendSuccessResponse(w, http.StatusOK, models.LoginResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		User:         *user,
+	})
+}
+
+// resolveUser находит пользователя учетной записи провайдера: по сохраненной связи,
+// затем по email (учетная запись связывается с существующим пользователем), иначе
+// регистрирует нового пользователя без пароля. Провайдер возвращает только подтвержденный
+// email. Возвращает false, если ответ уже отправлен
+func (h *OAuthHandler) resolveUser(w http.ResponseWriter, r *http.Request, identity *oauth.Identity) (*models.User, bool) {
+	userID, err := h.identities(r).GetUserID(identity.Provider, identity.Subject)
+	if err != nil {
+		logger.LogAuthEvent(r, "oauth_login", identity.Email, false, err.Error())
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка входа через провайдера")
+		return nil, false
+	}
+	if userID != uuid.Nil {
+		user, err := h.users(r).GetByID(userID)
+		if err != nil {
+			logger.LogAuthEvent(r, "oauth_login", identity.Email, false, err.Error())
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка входа через провайдера")
+			return nil, false
+		}
+		return user, true
+	}
+
+	exists, err := h.users(r).EmailExists(identity.Email)
+	if err != nil {
+		logger.LogAuthEvent(r, "oauth_login", identity.Email, false, err.Error())
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка входа через провайдера")
+		return nil, false
+	}
+
+	var user *models.User
+	action := "oauth_link"
+	if exists {
+		if user, err = h.users(r).GetByEmail(identity.Email); err != nil {
+			logger.LogAuthEvent(r, action, identity.Email, false, err.Error())
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка входа через провайдера")
+			return nil, false
+		}
+	} else {
+		action = "oauth_registration"
+		if !h.checkEmailDomain(w, r, action, identity.Email) {
+			return nil, false
+		}
+		if user, err = h.createOAuthUser(r, identity); err != nil {
+			logger.LogAuthEvent(r, action, identity.Email, false, err.Error())
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания пользователя")
+			return nil, false
+		}
+	}
+
+	err = h.identities(r).Link(&models.UserIdentity{
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		UserID:    user.ID,
+		Email:     identity.Email,
+		CreatedAt: timeutil.Now(),
+	})
+	if err != nil {
+		logger.LogAuthEvent(r, action, identity.Email, false, err.Error())
+		if errors.Is(err, repository.ErrIdentityConflict) {
+			h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, repository.ErrIdentityConflict.Error())
+			return nil, false
+		}
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка входа через провайдера")
+		return nil, false
+	}
+
+	logger.LogAuthEvent(r, action, identity.Email, true, "provider="+identity.Provider)
+	return user, true
+}
+
+// createOAuthUser регистрирует пользователя учетной записи провайдера. Пустой хеш
+// пароля не совпадает ни с одним паролем: пароль можно задать через сброс пароля
+func (h *OAuthHandler) createOAuthUser(r *http.Request, identity *oauth.Identity) (*models.User, error) {
+	name := strings.TrimSpace(identity.Name)
+	if utf8.RuneCountInString(name) < 2 {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+	if utf8.RuneCountInString(name) > 255 {
+		name = string([]rune(name)[:255])
+	}
+
+	now := timeutil.Now()
+	user := &models.User{
+		ID:        ids.New(),
+		Email:     identity.Email,
+		Name:      name,
+		Roles:     pq.StringArray{"user"},
+		Timezone:  timeutil.DefaultTimezone,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.users(r).Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// provider возвращает провайдера из пути запроса. Возвращает false, если провайдер
+// не настроен и ответ уже отправлен
+func (h *OAuthHandler) provider(w http.ResponseWriter, r *http.Request) (oauth.Provider, bool) {
+	provider, ok := h.providers[mux.Vars(r)["provider"]]
+	if !ok {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Вход через этого провайдера не настроен")
+		return nil, false
+	}
+	return provider, true
+}
+
+// redirectURL возвращает адрес возврата от провайдера, зарегистрированный у провайдера
+func (h *OAuthHandler) redirectURL(provider string) string {
+	return strings.ReplaceAll(h.config.OAuth.RedirectURL, "{provider}", provider)
+}
+
+// stateCookie создает cookie со state входа через провайдера; maxAge < 0 удаляет cookie.
+// SameSite=Lax: cookie передается при переходе со страницы провайдера
+func (h *OAuthHandler) stateCookie(provider, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		Path:     "/v1/users/oauth/" + provider,
+		MaxAge:   maxAge,
+		Secure:   h.config.OAuth.CookieSecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/password"
+	"service_users/utils"
+)
+
+// GetPasswordPolicy возвращает требования к паролю для форм регистрации и смены пароля
+func (h *UserHandler) GetPasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	h.sendSuccessResponse(w, http.StatusOK, h.passwordPolicy.Requirements())
+}
+
+// ChangePassword меняет пароль текущего пользователя по текущему паролю. Как и сброс
+// пароля, завершает все сессии пользователя, включая текущую. Неверный текущий пароль
+// учитывается блокировкой входа учетной записи
+func (h *PasswordResetHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireInteractiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	user, err := h.users(r).GetByID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+
+	if !h.checkLoginLockout(w, r, models.LockoutScopeAccount, user.ID.String(), user.Email) {
+		return
+	}
+	if !utils.CheckPassword(req.CurrentPassword, user.Password) {
+		logger.LogAuthEvent(r, "password_change", user.Email, false, "Invalid current password")
+		h.recordLoginFailure(r, models.LockoutScopeAccount, user.ID.String(), user.Email)
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Неверный текущий пароль")
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Новый пароль должен отличаться от текущего")
+		return
+	}
+
+	if !h.checkPassword(w, r, "password_change", user.Email, req.NewPassword, user.Email, user.Name) {
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка обработки пароля")
+		return
+	}
+
+	rolesEpoch, err := h.resets(r).ChangePassword(user.ID, hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+			return
+		}
+		logger.LogAuthEvent(r, "password_change", user.Email, false, err.Error())
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка смены пароля")
+		return
+	}
+
+	publishRolesEpoch(r, h.epochs, user.ID, rolesEpoch)
+	h.resetLoginLockout(r, user.ID)
+
+	logger.LogAuthEvent(r, "password_change", user.Email, true, fmt.Sprintf("epoch=%d", rolesEpoch))
+
+	h.sendSuccessResponse(w, http.StatusOK, map[string]string{
+		"message": "Пароль изменен. Войдите с новым паролем",
+	})
+}
+
+// checkPassword проверяет новый пароль по политике паролей; personal — email и имя,
+// которые пароль не должен содержать. Возвращает false, если ответ с ошибкой уже отправлен
+func (h *UserHandler) checkPassword(w http.ResponseWriter, r *http.Request, action, email, newPassword string, personal ...string) bool {
+	err := h.passwordPolicy.Check(r.Context(), newPassword, personal...)
+	if err == nil {
+		return true
+	}
+
+	var violation *password.Violation
+	if errors.As(err, &violation) {
+		logger.LogAuthEvent(r, action, email, false, "weak password: "+strings.Join(violation.Reasons, "; "))
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeWeakPassword, "Пароль не подходит: "+strings.Join(violation.Reasons, "; "))
+		return false
+	}
+	h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки пароля")
+	return false
+}
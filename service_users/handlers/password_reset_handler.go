@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"service_users/logger"
+	"service_users/mail"
+	"service_users/models"
+	"service_users/repository"
+	"service_users/utils"
+
+	"pkg/ids"
+	"pkg/rolesepoch"
+	"pkg/servertiming"
+	"pkg/timeutil"
+
+	"go.uber.org/zap"
+)
+
+// passwordResetSendTimeout ограничение времени отправки письма со ссылкой для сброса
+const passwordResetSendTimeout = 30 * time.Second
+
+// PasswordResetHandler обработчик сброса пароля по email
+type PasswordResetHandler struct {
+	*UserHandler
+	resetRepo repository.PasswordResetRepository
+	sender    mail.Sender
+	// epochs nil, если Redis не настроен: тогда access токены, выданные до сброса,
+	// действуют до истечения срока, а refresh токены отзываются сразу
+	epochs *rolesepoch.Store
+}
+
+// NewPasswordResetHandler создает новый обработчик сброса пароля
+func NewPasswordResetHandler(userHandler *UserHandler, resetRepo repository.PasswordResetRepository, sender mail.Sender, epochs *rolesepoch.Store) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		UserHandler: userHandler,
+		resetRepo:   resetRepo,
+		sender:      sender,
+		epochs:      epochs,
+	}
+}
+
+// resets возвращает репозиторий токенов сброса пароля, учитывающий время запросов к БД
+// в Server-Timing запроса r
+func (h *PasswordResetHandler) resets(r *http.Request) repository.PasswordResetRepository {
+	return repository.TimedPasswordResetRepository(h.resetRepo, servertiming.FromContext(r.Context()))
+}
+
+// RequestPasswordReset отправляет на email ссылку для сброса пароля.
+// Ответ не зависит от того, зарегистрирован ли email, чтобы по нему нельзя было
+// проверить существование учетной записи; письмо отправляется в фоне
+func (h *PasswordResetHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordResetRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	email := strings.TrimSpace(strings.ToLower(req.Email))
+	accepted := map[string]string{
+		"message": "Если email зарегистрирован, на него отправлена ссылка для сброса пароля",
+	}
+
+	// Заблокированные пользователи не могут войти и после сброса, поэтому письмо им не отправляется
+	user, err := h.users(r).GetByEmail(email)
+	if err != nil || user.IsBlocked() {
+		logger.LogAuthEvent(r, "password_reset_request", email, false, "пользователь не найден или заблокирован")
+		h.sendSuccessResponse(w, http.StatusAccepted, accepted)
+		return
+	}
+
+	token, tokenHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания токена сброса пароля")
+		return
+	}
+
+	now := timeutil.Now()
+	resetToken := &models.PasswordResetToken{
+		ID:        ids.New(),
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(h.config.PasswordReset.TokenTTL),
+		CreatedAt: now,
+	}
+	if err := h.resets(r).Create(resetToken); err != nil {
+		logger.LogAuthEvent(r, "password_reset_request", email, false, err.Error())
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания токена сброса пароля")
+		return
+	}
+
+	go h.sendResetMessage(email, token)
+
+	logger.LogAuthEvent(r, "password_reset_request", email, true, "")
+	h.sendSuccessResponse(w, http.StatusAccepted, accepted)
+}
+
+// ConfirmPasswordReset устанавливает новый пароль по токену из письма.
+// Токен одноразовый; все сессии пользователя завершаются
+func (h *PasswordResetHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordResetConfirmRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	// Пользователь известен только после погашения токена, поэтому email и имя
+	// в пароле здесь не проверяются
+	if !h.checkPassword(w, r, "password_reset_confirm", "", req.NewPassword) {
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка обработки пароля")
+		return
+	}
+
+	userID, rolesEpoch, err := h.resets(r).Reset(utils.HashRefreshToken(req.Token), hashedPassword)
+	if err != nil {
+		if errors.Is(err, repository.ErrPasswordResetTokenInvalid) {
+			logger.LogUserAction(r, "password_reset_confirm", "токен недействителен", false)
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Ссылка для сброса пароля недействительна или истекла")
+			return
+		}
+		logger.LogUserAction(r, "password_reset_confirm", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка сброса пароля")
+		return
+	}
+
+	publishRolesEpoch(r, h.epochs, userID, rolesEpoch)
+	// Новый пароль снимает блокировку входа учетной записи
+	h.resetLoginLockout(r, userID)
+
+	logger.LogUserAction(r, "password_reset_confirm", fmt.Sprintf("user_id=%s, epoch=%d", userID, rolesEpoch), true)
+
+	h.sendSuccessResponse(w, http.StatusOK, map[string]string{
+		"message": "Пароль изменен. Войдите с новым паролем",
+	})
+}
+
+// sendResetMessage отправляет письмо со ссылкой для сброса пароля
+func (h *PasswordResetHandler) sendResetMessage(email, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendTimeout)
+	defer cancel()
+
+	ttl := h.config.PasswordReset.TokenTTL
+	var body strings.Builder
+	body.WriteString("Здравствуйте!\n\nМы получили запрос на сброс пароля для вашей учетной записи.\n")
+	if h.config.PasswordReset.URL != "" {
+		link := strings.ReplaceAll(h.config.PasswordReset.URL, "{token}", url.QueryEscape(token))
+		fmt.Fprintf(&body, "Чтобы задать новый пароль, перейдите по ссылке:\n\n%s\n\n", link)
+	} else {
+		fmt.Fprintf(&body, "Код для сброса пароля:\n\n%s\n\n", token)
+	}
+	fmt.Fprintf(&body, "Срок действия — %d мин., использовать можно один раз. ", int(ttl.Round(time.Minute).Minutes()))
+	body.WriteString("После смены пароля все активные сессии будут завершены.\n\n")
+	body.WriteString("Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.\n")
+
+	err := h.sender.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Сброс пароля",
+		Body:    body.String(),
+	})
+	if err != nil {
+		logger.GetLogger().Error("Не удалось отправить письмо для сброса пароля",
+			zap.String("user_email", email),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/repository"
+
+	"pkg/servertiming"
+)
+
+// PersonalDataHandler обработчик выгрузки персональных данных пользователя
+type PersonalDataHandler struct {
+	*UserHandler
+	personalDataRepo repository.PersonalDataRepository
+}
+
+// NewPersonalDataHandler создает новый обработчик выгрузки персональных данных
+func NewPersonalDataHandler(userHandler *UserHandler, personalDataRepo repository.PersonalDataRepository) *PersonalDataHandler {
+	return &PersonalDataHandler{
+		UserHandler:      userHandler,
+		personalDataRepo: personalDataRepo,
+	}
+}
+
+// personalData возвращает репозиторий персональных данных, учитывающий время запросов
+// к БД в Server-Timing запроса r
+func (h *PersonalDataHandler) personalData(r *http.Request) repository.PersonalDataRepository {
+	return repository.TimedPersonalDataRepository(h.personalDataRepo, servertiming.FromContext(r.Context()))
+}
+
+// ExportPersonalData возвращает все персональные данные текущего пользователя, хранящиеся
+// в service_users: профиль, связанные учетные записи провайдеров входа, настройки
+// уведомлений и историю входов. Хеш пароля и значения токенов не выгружаются
+func (h *PersonalDataHandler) ExportPersonalData(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	if _, err := h.users(r).GetByID(userID); err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+
+	data, err := h.personalData(r).Export(userID)
+	if err != nil {
+		logger.LogUserAction(r, "personal_data_export", fmt.Sprintf("user_id=%s: %v", userID, err), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка выгрузки персональных данных")
+		return
+	}
+
+	logger.LogUserAction(r, "personal_data_export",
+		fmt.Sprintf("user_id=%s, identities=%d, sessions=%d", userID, len(data.Identities), len(data.LoginHistory.Sessions)), true)
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.sendSuccessResponse(w, http.StatusOK, data)
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"service_users/logger"
+	"service_users/mail"
+	"service_users/models"
+	"service_users/repository"
+	"service_users/utils"
+
+	"pkg/ids"
+	"pkg/servertiming"
+	"pkg/timeutil"
+
+	"go.uber.org/zap"
+)
+
+// emailChangeSendTimeout ограничение времени отправки писем о смене email
+const emailChangeSendTimeout = 30 * time.Second
+
+// ProfileHandler обработчик изменения профиля текущим пользователем. Новый email
+// вступает в силу только после подтверждения по ссылке, отправленной на него, а на
+// прежний адрес приходит уведомление: сессии, которой завладел злоумышленник,
+// недостаточно, чтобы переписать учетную запись на свой адрес
+type ProfileHandler struct {
+	*UserHandler
+	changeRepo repository.EmailChangeRepository
+	sender     mail.Sender
+}
+
+// NewProfileHandler создает новый обработчик изменения профиля
+func NewProfileHandler(userHandler *UserHandler, changeRepo repository.EmailChangeRepository, sender mail.Sender) *ProfileHandler {
+	return &ProfileHandler{
+		UserHandler: userHandler,
+		changeRepo:  changeRepo,
+		sender:      sender,
+	}
+}
+
+// emailChanges возвращает репозиторий токенов смены email, учитывающий время запросов к БД
+// в Server-Timing запроса r
+func (h *ProfileHandler) emailChanges(r *http.Request) repository.EmailChangeRepository {
+	return repository.TimedEmailChangeRepository(h.changeRepo, servertiming.FromContext(r.Context()))
+}
+
+// UpdateUserProfile обновляет профиль пользователя. Имя и часовой пояс меняются сразу,
+// новый email — после подтверждения
+func (h *ProfileHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	user, err := h.users(r).GetByID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+
+	var change *emailChange
+	if email := strings.TrimSpace(strings.ToLower(req.Email)); email != user.Email {
+		var ok bool
+		if change, ok = h.startEmailChange(w, r, "profile_update", user, email); !ok {
+			return
+		}
+	}
+
+	user.Name = req.Name
+	if req.Timezone != "" {
+		user.Timezone = req.Timezone
+	}
+
+	if err := h.users(r).Update(user); err != nil {
+		logger.LogUserAction(r, "profile_update", fmt.Sprintf("user_id=%s", userID), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка обновления профиля")
+		return
+	}
+
+	logger.LogUserAction(r, "profile_update", fmt.Sprintf("user_id=%s, email=%s", userID, user.Email), true)
+
+	user.Password = ""
+	h.sendSuccessResponse(w, http.StatusOK, h.profileResponse(user, change))
+}
+
+// patchableProfileFields поля профиля, доступные для изменения через PATCH
+var patchableProfileFields = map[string]bool{"name": true, "email": true, "timezone": true}
+
+// PatchUserProfile частично обновляет профиль пользователя (JSON Merge Patch, RFC 7396).
+// Валидируются только переданные поля; если значения не изменились,
+// запись в БД и аудит не выполняются. Новый email, как и в PUT, требует подтверждения
+func (h *ProfileHandler) PatchUserProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	contentType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+	if contentType != "" && contentType != "application/json" && contentType != "application/merge-patch+json" {
+		h.sendErrorResponse(w, http.StatusUnsupportedMediaType, models.ErrorCodeValidation,
+			"Поддерживается только application/merge-patch+json или application/json")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Не удалось прочитать тело запроса")
+		return
+	}
+
+	var patch map[string]json.RawMessage
+	if err := json.Unmarshal(body, &patch); err != nil || patch == nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный JSON: ожидается объект")
+		return
+	}
+
+	for field, value := range patch {
+		if !patchableProfileFields[field] {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, fmt.Sprintf("поле '%s' не может быть изменено", field))
+			return
+		}
+		// null в merge patch означает удаление поля, но имя и email обязательны
+		if string(value) == "null" {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, fmt.Sprintf("поле '%s' не может быть удалено", field))
+			return
+		}
+	}
+
+	var req models.PatchProfileRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный JSON")
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	user, err := h.users(r).GetByID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+
+	changed := false
+
+	if req.Name != nil && *req.Name != user.Name {
+		user.Name = *req.Name
+		changed = true
+	}
+
+	var change *emailChange
+	if req.Email != nil {
+		if email := strings.TrimSpace(strings.ToLower(*req.Email)); email != user.Email {
+			var ok bool
+			if change, ok = h.startEmailChange(w, r, "profile_patch", user, email); !ok {
+				return
+			}
+		}
+	}
+
+	if req.Timezone != nil && *req.Timezone != user.Timezone {
+		user.Timezone = *req.Timezone
+		changed = true
+	}
+
+	user.Password = ""
+
+	// Ничего не изменилось сразу — не трогаем БД и не пишем аудит изменения профиля
+	if !changed {
+		h.sendSuccessResponse(w, http.StatusOK, h.profileResponse(user, change))
+		return
+	}
+
+	if err := h.users(r).Update(user); err != nil {
+		logger.LogUserAction(r, "profile_patch", fmt.Sprintf("user_id=%s", userID), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка обновления профиля")
+		return
+	}
+
+	logger.LogUserAction(r, "profile_patch", fmt.Sprintf("user_id=%s, fields=%d", userID, len(patch)), true)
+
+	h.sendSuccessResponse(w, http.StatusOK, h.profileResponse(user, change))
+}
+
+// ConfirmEmailChange устанавливает новый email по токену из письма, отправленного на него.
+// Токен одноразовый; смена пароля или новый запрос смены email отменяют его
+func (h *ProfileHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req models.EmailChangeConfirmRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	token, err := h.emailChanges(r).Confirm(utils.HashRefreshToken(req.Token))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrEmailChangeTokenInvalid):
+			logger.LogUserAction(r, "email_change_confirm", "токен недействителен", false)
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Ссылка для подтверждения email недействительна или истекла")
+		case errors.Is(err, repository.ErrEmailTaken):
+			logger.LogUserAction(r, "email_change_confirm", "email уже используется", false)
+			h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, "Пользователь с таким email уже существует")
+		default:
+			logger.LogUserAction(r, "email_change_confirm", err.Error(), false)
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка подтверждения email")
+		}
+		return
+	}
+
+	logger.LogUserAction(r, "email_change_confirm", fmt.Sprintf("user_id=%s, email=%s", token.UserID, token.NewEmail), true)
+
+	h.sendSuccessResponse(w, http.StatusOK, map[string]string{
+		"message": "Email изменен. Входите с новым адресом",
+	})
+}
+
+// emailChange запрошенная смена email: токен сохранен, письма отправляются после ответа
+type emailChange struct {
+	token *models.EmailChangeToken
+	// value значение токена для письма; в БД хранится только хеш
+	value string
+}
+
+// startEmailChange проверяет новый email и сохраняет токен его подтверждения. Текущий
+// email пользователя не меняется; по API ключу email не меняется вовсе.
+// Возвращает false, если ответ уже отправлен
+func (h *ProfileHandler) startEmailChange(w http.ResponseWriter, r *http.Request, action string, user *models.User, email string) (*emailChange, bool) {
+	if h.rejectAPIKey(w, r) {
+		return nil, false
+	}
+	if !h.checkEmailDomain(w, r, action, email) {
+		return nil, false
+	}
+	exists, err := h.users(r).EmailExists(email)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки email")
+		return nil, false
+	}
+	if exists {
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, "Пользователь с таким email уже существует")
+		return nil, false
+	}
+
+	value, tokenHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания токена подтверждения email")
+		return nil, false
+	}
+
+	now := timeutil.Now()
+	token := &models.EmailChangeToken{
+		ID:        ids.New(),
+		UserID:    user.ID,
+		OldEmail:  user.Email,
+		NewEmail:  email,
+		TokenHash: tokenHash,
+		ExpiresAt: now.Add(h.config.EmailChange.TokenTTL),
+		CreatedAt: now,
+	}
+	if err := h.emailChanges(r).Create(token); err != nil {
+		logger.LogUserAction(r, "email_change_request", fmt.Sprintf("user_id=%s", user.ID), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания токена подтверждения email")
+		return nil, false
+	}
+
+	logger.LogUserAction(r, "email_change_request", fmt.Sprintf("user_id=%s, new_email=%s", user.ID, email), true)
+	return &emailChange{token: token, value: value}, true
+}
+
+// profileResponse возвращает профиль с ожидающей подтверждения сменой email и отправляет
+// письма о ней в фоне. Вызывается после сохранения остальных полей профиля, чтобы письма
+// не уходили при ошибке сохранения
+func (h *ProfileHandler) profileResponse(user *models.User, change *emailChange) models.ProfileResponse {
+	response := models.ProfileResponse{User: *user}
+	if change == nil {
+		return response
+	}
+
+	go h.sendEmailChangeMessages(user.Name, change)
+
+	response.PendingEmailChange = &models.PendingEmailChange{
+		Email:     change.token.NewEmail,
+		ExpiresAt: change.token.ExpiresAt,
+	}
+	return response
+}
+
+// sendEmailChangeMessages отправляет ссылку для подтверждения на новый email и
+// уведомление о запросе смены на прежний
+func (h *ProfileHandler) sendEmailChangeMessages(name string, change *emailChange) {
+	ctx, cancel := context.WithTimeout(context.Background(), emailChangeSendTimeout)
+	defer cancel()
+
+	ttl := h.config.EmailChange.TokenTTL
+	var body strings.Builder
+	fmt.Fprintf(&body, "Здравствуйте, %s!\n\nДля вашей учетной записи запрошена смена email на этот адрес.\n", name)
+	if h.config.EmailChange.URL != "" {
+		link := strings.ReplaceAll(h.config.EmailChange.URL, "{token}", url.QueryEscape(change.value))
+		fmt.Fprintf(&body, "Чтобы подтвердить новый адрес, перейдите по ссылке:\n\n%s\n\n", link)
+	} else {
+		fmt.Fprintf(&body, "Код подтверждения нового адреса:\n\n%s\n\n", change.value)
+	}
+	validity := fmt.Sprintf("%d мин.", int(ttl.Round(time.Minute).Minutes()))
+	if ttl%time.Hour == 0 {
+		validity = fmt.Sprintf("%d ч.", int(ttl.Hours()))
+	}
+	fmt.Fprintf(&body, "Срок действия — %s, использовать можно один раз. ", validity)
+	body.WriteString("До подтверждения вход выполняется с прежним адресом.\n\n")
+	body.WriteString("Если вы не запрашивали смену email, просто проигнорируйте это письмо.\n")
+	h.sendEmailChangeMessage(ctx, change.token.NewEmail, "Подтверждение email", body.String())
+
+	body.Reset()
+	fmt.Fprintf(&body, "Здравствуйте, %s!\n\nДля вашей учетной записи запрошена смена email на %s. ", name, change.token.NewEmail)
+	body.WriteString("Адрес изменится, только если запрос подтвердят по ссылке, отправленной на новый адрес.\n\n")
+	body.WriteString("Если вы не запрашивали смену email, смените пароль или сбросьте его по ссылке из письма: ")
+	body.WriteString("это отменит смену email и завершит все сессии.\n")
+	h.sendEmailChangeMessage(ctx, change.token.OldEmail, "Запрошена смена email", body.String())
+}
+
+// sendEmailChangeMessage отправляет одно письмо о смене email
+func (h *ProfileHandler) sendEmailChangeMessage(ctx context.Context, to, subject, body string) {
+	err := h.sender.Send(ctx, mail.Message{
+		To:      to,
+		Subject: subject,
+		Body:    body,
+	})
+	if err != nil {
+		logger.GetLogger().Error("Не удалось отправить письмо о смене email",
+			zap.String("user_email", to),
+			zap.String("subject", subject),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/repository"
+	"service_users/utils"
+
+	"pkg/rbac"
+	"pkg/rolesepoch"
+	"pkg/servertiming"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// RoleHandler обработчик управления ролями пользователей
+type RoleHandler struct {
+	*UserHandler
+	// epochs nil, если Redis не настроен: тогда новые роли применяются
+	// только к токенам, выданным после изменения
+	epochs *rolesepoch.Store
+}
+
+// NewRoleHandler создает новый обработчик управления ролями
+func NewRoleHandler(userHandler *UserHandler, epochs *rolesepoch.Store) *RoleHandler {
+	return &RoleHandler{
+		UserHandler: userHandler,
+		epochs:      epochs,
+	}
+}
+
+// UpdateUserRoles заменяет роли пользователя (право roles.assign). Роли должны
+// существовать в справочнике ролей; назначить можно только роли, все права которых
+// есть у вызывающего, чтобы право roles.assign не позволяло получить больше прав,
+// а отозвать — только собственные права, чтобы нельзя было понизить администратора.
+// Пустой список ролей блокирует пользователя и отзывает его refresh токены.
+// Новая эпоха ролей публикуется в Redis, и API Gateway перестает принимать
+// ранее выданные access токены пользователя
+func (h *RoleHandler) UpdateUserRoles(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.RolesAssign) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	adminID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID пользователя")
+		return
+	}
+
+	// Защита от потери доступа: администратор не может понизить или заблокировать себя
+	if userID == adminID {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Нельзя изменить собственные роли")
+		return
+	}
+
+	var req models.UpdateRolesRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	unknown, err := h.roleDirectory(r).Unknown(req.Roles)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки ролей")
+		return
+	}
+	if len(unknown) > 0 {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Неизвестные роли: "+strings.Join(unknown, ", "))
+		return
+	}
+	granted, err := h.roleDirectory(r).Permissions(req.Roles)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки ролей")
+		return
+	}
+	if !h.checkGrantable(w, r, granted) {
+		return
+	}
+
+	current, err := h.users(r).GetByID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+	held, err := h.roleDirectory(r).Permissions(current.Roles)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки ролей")
+		return
+	}
+	if !h.checkRevocable(w, r, removedPermissions(held, granted)) {
+		return
+	}
+
+	user, err := h.users(r).UpdateRoles(userID, uniqueRoles(req.Roles))
+	if err != nil {
+		logger.LogUserAction(r, "roles_update", fmt.Sprintf("user_id=%s", userID), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка изменения ролей")
+		return
+	}
+
+	if user.IsBlocked() {
+		if err := h.refreshTokens(r).RevokeAllForUser(user.ID); err != nil {
+			logger.LogUserAction(r, "roles_update", fmt.Sprintf("user_id=%s, revoke refresh tokens: %v", userID, err), false)
+		}
+	}
+
+	publishRolesEpoch(r, h.epochs, user.ID, user.RolesEpoch)
+
+	logger.LogUserAction(r, "roles_update", fmt.Sprintf("user_id=%s, roles=%s, epoch=%d", userID, strings.Join(user.Roles, ","), user.RolesEpoch), true)
+	h.recordAdminAction(r, "roles_update", userID.String(), "roles="+strings.Join(user.Roles, ","))
+
+	h.sendSuccessResponse(w, http.StatusOK, user)
+}
+
+// ListPermissions возвращает справочник прав (право roles.manage)
+func (h *RoleHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.RolesManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	permissions, err := h.roleDirectory(r).ListPermissions()
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения прав")
+		return
+	}
+	h.sendSuccessResponse(w, http.StatusOK, permissions)
+}
+
+// ListRoles возвращает роли с их правами (право roles.manage)
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.RolesManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	roles, err := h.roleDirectory(r).List()
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения ролей")
+		return
+	}
+	h.sendSuccessResponse(w, http.StatusOK, roles)
+}
+
+// CreateRole создает роль с правами (право roles.manage)
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.RolesManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	var req models.CreateRoleRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	if !h.checkGrantable(w, r, req.Permissions) {
+		return
+	}
+
+	role := &models.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: uniqueRoles(req.Permissions),
+	}
+	if err := h.roleDirectory(r).Create(role); err != nil {
+		if !h.sendRoleError(w, err) {
+			logger.LogUserAction(r, "role_create", fmt.Sprintf("role=%s, error=%v", req.Name, err), false)
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания роли")
+		}
+		return
+	}
+	sort.Strings(role.Permissions)
+
+	permissions := strings.Join(role.Permissions, ",")
+	logger.LogUserAction(r, "role_create", fmt.Sprintf("role=%s, permissions=%s", role.Name, permissions), true)
+	h.recordAdminAction(r, "role_create", role.Name, "permissions="+permissions)
+	h.sendSuccessResponse(w, http.StatusCreated, role)
+}
+
+// UpdateRole заменяет описание и права роли (право roles.manage). Права роли admin
+// не изменяются; добавить и убрать можно только собственные права вызывающего.
+// Новая эпоха ролей пользователей роли публикуется в Redis, и API Gateway перестает
+// принимать их access токены с прежними правами
+func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.RolesManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+
+	var req models.UpdateRoleRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	if !h.checkGrantable(w, r, req.Permissions) {
+		return
+	}
+	current, err := h.roleDirectory(r).Get(name)
+	if err != nil {
+		if !h.sendRoleError(w, err) {
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка изменения роли")
+		}
+		return
+	}
+	if !h.checkRevocable(w, r, removedPermissions(current.Permissions, req.Permissions)) {
+		return
+	}
+
+	role, err := h.roleDirectory(r).Update(name, req.Description, uniqueRoles(req.Permissions))
+	if err != nil {
+		if !h.sendRoleError(w, err) {
+			logger.LogUserAction(r, "role_update", fmt.Sprintf("role=%s, error=%v", name, err), false)
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка изменения роли")
+		}
+		return
+	}
+
+	// Права роли входят в access токены ее пользователей, поэтому их токены отзываются,
+	// как при изменении ролей пользователя
+	epochs, err := h.users(r).BumpRolesEpoch(role.Name)
+	if err != nil {
+		logger.LogUserAction(r, "role_update", fmt.Sprintf("role=%s, error=%v", name, err), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Роль изменена, но токены ее пользователей не отозваны, повторите запрос")
+		return
+	}
+	for userID, epoch := range epochs {
+		publishRolesEpoch(r, h.epochs, userID, epoch)
+	}
+
+	permissions := strings.Join(role.Permissions, ",")
+	logger.LogUserAction(r, "role_update", fmt.Sprintf("role=%s, permissions=%s, holders=%d", role.Name, permissions, len(epochs)), true)
+	h.recordAdminAction(r, "role_update", role.Name, "permissions="+permissions)
+	h.sendSuccessResponse(w, http.StatusOK, role)
+}
+
+// DeleteRole удаляет роль (право roles.manage). Встроенные роли и роли, назначенные
+// пользователям, не удаляются
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	if !h.can(r, rbac.RolesManage) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	deleted, err := h.roleDirectory(r).Delete(name)
+	if err != nil {
+		if !h.sendRoleError(w, err) {
+			logger.LogUserAction(r, "role_delete", fmt.Sprintf("role=%s, error=%v", name, err), false)
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка удаления роли")
+		}
+		return
+	}
+	if !deleted {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Роль не найдена")
+		return
+	}
+
+	logger.LogUserAction(r, "role_delete", "role="+name, true)
+	h.recordAdminAction(r, "role_delete", name, "")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkGrantable проверяет, что у вызывающего есть все права permissions: выдать роли
+// или пользователю можно только собственные права. Возвращает false, если ответ с
+// ошибкой уже отправлен
+func (h *RoleHandler) checkGrantable(w http.ResponseWriter, r *http.Request, permissions []string) bool {
+	if missing := h.missingPermissions(r, permissions); len(missing) > 0 {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Нельзя выдать права, которых нет у вас: "+strings.Join(missing, ", "))
+		return false
+	}
+	return true
+}
+
+// checkRevocable проверяет, что у вызывающего есть все отзываемые права permissions:
+// отозвать у роли или пользователя можно только собственные права. Возвращает false,
+// если ответ с ошибкой уже отправлен
+func (h *RoleHandler) checkRevocable(w http.ResponseWriter, r *http.Request, permissions []string) bool {
+	if missing := h.missingPermissions(r, permissions); len(missing) > 0 {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Нельзя отозвать права, которых нет у вас: "+strings.Join(missing, ", "))
+		return false
+	}
+	return true
+}
+
+// missingPermissions возвращает права из permissions, которых нет у вызывающего
+func (h *RoleHandler) missingPermissions(r *http.Request, permissions []string) []string {
+	var missing []string
+	for _, permission := range permissions {
+		if !h.can(r, permission) {
+			missing = append(missing, permission)
+		}
+	}
+	return missing
+}
+
+// removedPermissions возвращает права из before, которых нет в after
+func removedPermissions(before, after []string) []string {
+	kept := make(map[string]bool, len(after))
+	for _, permission := range after {
+		kept[permission] = true
+	}
+	var removed []string
+	for _, permission := range before {
+		if !kept[permission] {
+			removed = append(removed, permission)
+		}
+	}
+	return removed
+}
+
+// sendRoleError отвечает на ошибки справочника ролей, вызванные запросом клиента.
+// Возвращает false, если err не относится к ним и ответ не отправлен
+func (h *RoleHandler) sendRoleError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, repository.ErrRoleNotFound):
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Роль не найдена")
+	case errors.Is(err, repository.ErrRoleExists),
+		errors.Is(err, repository.ErrRoleBuiltin),
+		errors.Is(err, repository.ErrRolePermissionsLocked),
+		errors.Is(err, repository.ErrRoleAssigned):
+		h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, err.Error())
+	case errors.Is(err, repository.ErrUnknownPermission):
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// publishRolesEpoch публикует эпоху ролей пользователя, чтобы API Gateway перестал
+// принимать ранее выданные access токены. Ошибка Redis не отменяет изменение:
+// в худшем случае старые токены действуют до истечения срока. epochs nil, если Redis не настроен.
+// Время публикации учитывается в этапе publish заголовка Server-Timing
+func publishRolesEpoch(r *http.Request, epochs *rolesepoch.Store, userID uuid.UUID, rolesEpoch int64) {
+	if epochs == nil {
+		return
+	}
+	defer servertiming.FromContext(r.Context()).Start(servertiming.MetricPublish)()
+
+	if err := epochs.Publish(r.Context(), userID.String(), rolesEpoch); err != nil {
+		logger.GetLogger().Error("Не удалось опубликовать эпоху ролей, активные токены не отозваны",
+			zap.String("user_id", userID.String()),
+			zap.Int64("roles_epoch", rolesEpoch),
+			zap.Error(err),
+		)
+	}
+}
+
+// uniqueRoles удаляет повторяющиеся роли или права, сохраняя порядок
+func uniqueRoles(roles []string) []string {
+	seen := make(map[string]bool, len(roles))
+	result := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if !seen[role] {
+			seen[role] = true
+			result = append(result, role)
+		}
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"service_users/config"
+	"service_users/fakes"
+	"service_users/models"
+
+	"pkg/rbac"
+	"pkg/rolesepoch"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+)
+
+// epochRedis хук клиента Redis, который выполняет команды хранилища эпох ролей
+// в памяти: скрипт записи эпохи (EVALSHA) и GET
+type epochRedis struct {
+	mutex  sync.Mutex
+	values map[string]int64
+}
+
+func (e *epochRedis) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (e *epochRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (e *epochRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		args := cmd.Args()
+		switch cmd := cmd.(type) {
+		case *redis.Cmd:
+			// evalsha <sha> 1 <key> <epoch> <ttl>: запись, только если эпоха больше
+			key, epoch := args[3].(string), args[4].(int64)
+			if epoch > e.values[key] {
+				e.values[key] = epoch
+			}
+			cmd.SetVal(int64(1))
+		case *redis.StringCmd:
+			epoch, ok := e.values[args[1].(string)]
+			if !ok {
+				cmd.SetErr(redis.Nil)
+				return redis.Nil
+			}
+			cmd.SetVal(fmt.Sprint(epoch))
+		default:
+			return fmt.Errorf("команда %s не поддерживается", cmd.Name())
+		}
+		return nil
+	}
+}
+
+// newEpochStore возвращает хранилище эпох ролей поверх Redis в памяти
+func newEpochStore(t *testing.T) *rolesepoch.Store {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	client.AddHook(&epochRedis{values: make(map[string]int64)})
+	t.Cleanup(func() { client.Close() })
+	return rolesepoch.NewStore(client, time.Hour)
+}
+
+func TestUpdateRoleRevokesHolderTokens(t *testing.T) {
+	users := fakes.NewUserRepository()
+	holder := &models.User{ID: uuid.New(), Email: "operator@example.com", Name: "Оператор", Roles: []string{"operator"}, RolesEpoch: 3}
+	other := &models.User{ID: uuid.New(), Email: "user@example.com", Name: "Покупатель", Roles: []string{"user"}, RolesEpoch: 5}
+	users.Seed(holder, other)
+	roles := fakes.NewRoleRepository()
+	roles.Seed(&models.Role{Name: "operator", Permissions: []string{rbac.OrdersManage, rbac.UsersManage}})
+	epochs := newEpochStore(t)
+
+	audit := fakes.NewAccessReviewRepository()
+
+	handler := NewRoleHandler(NewUserHandler(users, nil, nil, nil, nil, nil, audit, nil, roles, &config.Config{}), epochs)
+
+	body := fmt.Sprintf(`{"description":"Оператор склада","permissions":[%q]}`, rbac.OrdersManage)
+	req := httptest.NewRequest(http.MethodPut, "/v1/admin/roles/operator", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", uuid.NewString())
+	req.Header.Set(rbac.HeaderPermissions, strings.Join([]string{rbac.RolesManage, rbac.OrdersManage, rbac.UsersManage}, ","))
+	req = mux.SetURLVars(req, map[string]string{"name": "operator"})
+	rec := httptest.NewRecorder()
+	handler.UpdateRole(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("код %d, ожидался 200: %s", rec.Code, rec.Body.String())
+	}
+
+	// API Gateway отклоняет токен, эпоха которого меньше опубликованной
+	published, err := epochs.Get(context.Background(), holder.ID.String())
+	if err != nil {
+		t.Fatalf("ошибка получения эпохи: %v", err)
+	}
+	if published <= holder.RolesEpoch {
+		t.Errorf("опубликована эпоха %d: токен пользователя роли с эпохой %d по-прежнему принимается", published, holder.RolesEpoch)
+	}
+	if published, _ := epochs.Get(context.Background(), other.ID.String()); published != 0 {
+		t.Errorf("опубликована эпоха %d пользователя без роли", published)
+	}
+	if actions := audit.AdminActions(); len(actions) != 1 || actions[0].Action != "role_update" {
+		t.Errorf("журнал аудита %+v", actions)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"service_users/logger"
+	"service_users/models"
+
+	"pkg/rolesepoch"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// SessionHandler обработчик активных сессий пользователя. Сессия — refresh токен,
+// выданный при входе на устройстве, вместе со всеми его ротациями
+type SessionHandler struct {
+	*UserHandler
+	// epochs nil, если Redis не настроен: тогда после выхода на всех устройствах
+	// выданные access токены действуют до истечения срока
+	epochs *rolesepoch.Store
+}
+
+// NewSessionHandler создает новый обработчик сессий
+func NewSessionHandler(userHandler *UserHandler, epochs *rolesepoch.Store) *SessionHandler {
+	return &SessionHandler{
+		UserHandler: userHandler,
+		epochs:      epochs,
+	}
+}
+
+// ListSessions возвращает активные сессии текущего пользователя, недавно использованные первыми
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
+
+	sessions, err := h.refreshTokens(r).ListSessions(userID)
+	if err != nil {
+		logger.LogUserAction(r, "list_sessions", err.Error(), false)
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения сессий")
+		return
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, sessions)
+}
+
+// RevokeSession завершает сессию текущего пользователя: ее refresh токен больше не
+// обновляется, а выданный по нему access токен действует до истечения срока
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireInteractiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID сессии")
+		return
+	}
+
+	revoked, err := h.refreshTokens(r).RevokeSession(userID, sessionID)
+	if err != nil {
+		logger.LogAuthEvent(r, "session_revoke", "", false, err.Error())
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка завершения сессии")
+		return
+	}
+	if !revoked {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Сессия не найдена")
+		return
+	}
+
+	logger.LogAuthEvent(r, "session_revoke", "", true, fmt.Sprintf("user_id=%s, session_id=%s", userID, sessionID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeAllSessions завершает все сессии текущего пользователя, включая текущую
+// («выйти на всех устройствах»). Новая эпоха ролей публикуется в Redis, и API Gateway
+// перестает принимать ранее выданные access токены
+func (h *SessionHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireInteractiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	rolesEpoch, err := h.refreshTokens(r).RevokeAllSessions(userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+			return
+		}
+		logger.LogAuthEvent(r, "sessions_revoke_all", "", false, err.Error())
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка завершения сессий")
+		return
+	}
+
+	publishRolesEpoch(r, h.epochs, userID, rolesEpoch)
+
+	logger.LogAuthEvent(r, "sessions_revoke_all", "", true, fmt.Sprintf("user_id=%s, epoch=%d", userID, rolesEpoch))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"service_users/logger"
+	"service_users/models"
+
+	"pkg/rbac"
+	"pkg/redact"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// supportRecentLogins число последних попыток входа в карточке пользователя для поддержки
+const supportRecentLogins = 10
+
+// SupportHandler обработчик просмотра пользователей для поддержки: email, имя и IP адреса
+// маскируются, полные данные раскрываются только с правом pii.reveal и записью в журнал аудита
+type SupportHandler struct {
+	*UserHandler
+}
+
+// NewSupportHandler создает новый обработчик просмотра для поддержки
+func NewSupportHandler(userHandler *UserHandler) *SupportHandler {
+	return &SupportHandler{UserHandler: userHandler}
+}
+
+// Redacted оборачивает обработчик просмотра для поддержки: проверяет право support.view
+// и маскирует персональные данные в ответе. Запрос с reveal=true и причиной reason
+// от пользователя с правом pii.reveal получает ответ без маскирования; раскрытие
+// записывается в журнал аудита до отправки ответа
+func (h *SupportHandler) Redacted(next http.HandlerFunc) http.Handler {
+	masked := redact.Default.Handler(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.can(r, rbac.SupportView) {
+			h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Доступ запрещен")
+			return
+		}
+
+		reason, reveal, err := redact.RevealRequested(r)
+		if err != nil {
+			h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+			return
+		}
+		if !reveal {
+			masked.ServeHTTP(w, r)
+			return
+		}
+
+		target := mux.Vars(r)["id"]
+		if !h.can(r, rbac.PIIReveal) {
+			logger.LogUserAction(r, "pii_reveal_user", fmt.Sprintf("target=%s, denied", target), false)
+			h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Недостаточно прав для раскрытия персональных данных")
+			return
+		}
+		// Раскрытие без записи в журнал аудита не выполняется
+		entry := &models.AdminAction{Action: "pii_reveal_user", Target: target, Details: "reason=" + reason}
+		if actorID, err := h.getUserIDFromContext(r); err == nil {
+			entry.ActorID = &actorID
+		}
+		if err := h.access(r).RecordAdminAction(entry); err != nil {
+			logger.LogUserAction(r, "pii_reveal_user", err.Error(), false)
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка записи раскрытия в журнал аудита")
+			return
+		}
+		logger.LogUserAction(r, "pii_reveal_user", fmt.Sprintf("target=%s, reason=%s", target, reason), true)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetSupportUser возвращает карточку пользователя для поддержки: профиль и последние
+// попытки входа
+func (h *SupportHandler) GetSupportUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID пользователя")
+		return
+	}
+
+	user, err := h.users(r).GetByID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
+	user.Password = ""
+
+	view := models.SupportUserView{User: *user, RecentLogins: []models.LoginAttempt{}}
+	if h.loginAttempts != nil {
+		attempts, _, err := h.attempts(r).ListForUser(userID, supportRecentLogins, 0)
+		if err != nil {
+			logger.GetLogger().Warn("Failed to load login attempts for support view", zap.String("user_id", userID.String()), zap.Error(err))
+		} else {
+			view.RecentLogins = attempts
+		}
+	}
+
+	h.sendSuccessResponse(w, http.StatusOK, view)
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"pkg/ids"
+	"pkg/timeutil"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/repository"
+	"service_users/utils"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken выдает новый access токен по refresh токену с ротацией:
+// предъявленный refresh токен отзывается и заменяется новым.
+// Повторное использование уже замененного токена отзывает все токены пользователя
+func (h *UserHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	stored, err := h.refreshTokens(r).GetByHash(utils.HashRefreshToken(req.RefreshToken))
+	if err != nil {
+		logger.LogAuthEvent(r, "token_refresh", "", false, err.Error())
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Недействительный refresh токен")
+		return
+	}
+
+	// Повторное предъявление отозванного токена — признак утечки: отзываем всю цепочку
+	if stored.RevokedAt != nil {
+		h.revokeAllRefreshTokens(r, stored.UserID)
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Недействительный refresh токен")
+		return
+	}
+
+	if !stored.IsActive() {
+		logger.LogAuthEvent(r, "token_refresh", "", false, "refresh token expired")
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Срок действия refresh токена истек")
+		return
+	}
+
+	user, err := h.users(r).GetByID(stored.UserID)
+	if err != nil {
+		logger.LogAuthEvent(r, "token_refresh", "", false, err.Error())
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Недействительный refresh токен")
+		return
+	}
+
+	if user.IsBlocked() {
+		logger.LogAuthEvent(r, "token_refresh", user.Email, false, "user is blocked")
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Пользователь заблокирован")
+		return
+	}
+	if entry := h.blacklistedLogin(r, user); entry != nil {
+		logger.LogAuthEvent(r, "token_refresh", user.Email, false, fmt.Sprintf("blacklisted: %s=%s", entry.Kind, entry.Value))
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeBlacklisted, "Вход запрещен")
+		return
+	}
+
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка генерации токена")
+		return
+	}
+
+	newToken := h.newRefreshToken(r, user.ID, stored.SessionID, refreshHash)
+	if err := h.refreshTokens(r).Rotate(stored.ID, newToken); err != nil {
+		if err == repository.ErrRefreshTokenReused {
+			h.revokeAllRefreshTokens(r, user.ID)
+			h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Недействительный refresh токен")
+			return
+		}
+		logger.LogAuthEvent(r, "token_refresh", user.Email, false, err.Error())
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка обновления токена")
+		return
+	}
+
+	token, err := h.generateAccessToken(r, user)
+	if err != nil {
+		logger.LogAuthEvent(r, "token_refresh", user.Email, false, "Token generation failed")
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка генерации токена")
+		return
+	}
+
+	logger.LogAuthEvent(r, "token_refresh", user.Email, true, "")
+
+	h.sendSuccessResponse(w, http.StatusOK, models.RefreshResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+	})
+}
+
+// RevokeRefreshToken отзывает предъявленный refresh токен при выходе клиента.
+// Неизвестный, истекший или уже отозванный токен не считается ошибкой (как в RFC 7009):
+// ответ не позволяет проверить, существует ли токен
+func (h *UserHandler) RevokeRefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := utils.ValidateStruct(req); err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return
+	}
+
+	stored, err := h.refreshTokens(r).GetByHash(utils.HashRefreshToken(req.RefreshToken))
+	if err != nil {
+		logger.LogAuthEvent(r, "token_revoke", "", false, err.Error())
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if stored.RevokedAt == nil {
+		if err := h.refreshTokens(r).Revoke(stored.ID); err != nil {
+			logger.LogAuthEvent(r, "token_revoke", "", false, err.Error())
+			h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка отзыва токена")
+			return
+		}
+	}
+
+	logger.LogAuthEvent(r, "token_revoke", "", true, "user_id="+stored.UserID.String())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// generateAccessToken выдает access токен пользователя с правами его ролей. Права
+// фиксируются в токене: изменение прав роли применяется к токенам, выданным после изменения
+func (h *UserHandler) generateAccessToken(r *http.Request, user *models.User) (string, error) {
+	permissions, err := h.roleDirectory(r).Permissions(user.Roles)
+	if err != nil {
+		return "", err
+	}
+	return utils.GenerateJWT(user, permissions, h.config.JWT.Signer, h.config.JWT.AccessTTL)
+}
+
+// issueRefreshToken создает и сохраняет refresh токен новой сессии пользователя
+func (h *UserHandler) issueRefreshToken(r *http.Request, userID uuid.UUID) (string, error) {
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := h.refreshTokens(r).Create(h.newRefreshToken(r, userID, ids.New(), refreshHash)); err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// newRefreshToken создает запись refresh токена сессии sessionID со сроком действия
+// из конфигурации, User-Agent и адресом клиента из запроса r
+func (h *UserHandler) newRefreshToken(r *http.Request, userID, sessionID uuid.UUID, tokenHash string) *models.RefreshToken {
+	now := timeutil.Now()
+	return &models.RefreshToken{
+		ID:        ids.New(),
+		UserID:    userID,
+		SessionID: sessionID,
+		TokenHash: tokenHash,
+		UserAgent: truncate(r.UserAgent(), maxUserAgentLength),
+		IPAddress: truncate(clientIP(r), maxClientIPLength),
+		ExpiresAt: now.Add(h.config.JWT.RefreshTTL),
+		CreatedAt: now,
+	}
+}
+
+// revokeAllRefreshTokens отзывает все refresh токены пользователя при обнаружении повторного использования
+func (h *UserHandler) revokeAllRefreshTokens(r *http.Request, userID uuid.UUID) {
+	if err := h.refreshTokens(r).RevokeAllForUser(userID); err != nil {
+		logger.LogAuthEvent(r, "token_reuse", "", false, err.Error())
+		return
+	}
+	logger.LogAuthEvent(r, "token_reuse", "", false, "refresh token reuse detected, user_id="+userID.String())
+}
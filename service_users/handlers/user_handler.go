@@ -1,43 +1,98 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"service_users/config"
 	"service_users/logger"
 	"service_users/models"
+	"service_users/password"
+	"service_users/registration"
 	"service_users/repository"
 	"service_users/utils"
 
+	"pkg/httpreq"
+	"pkg/httpresp"
+	"pkg/ids"
+	"pkg/rbac"
+	"pkg/servertiming"
+	"pkg/timeutil"
+
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
 // UserHandler обработчик для пользователей
 type UserHandler struct {
-    userRepo repository.UserRepository
+    userRepo    repository.UserRepository
+    refreshRepo repository.RefreshTokenRepository
+    emailPolicy *registration.Policy
+    // passwordPolicy требования к новым паролям
+    passwordPolicy *password.Policy
+    // loginAttempts учет результатов входа; nil — не ведется
+    loginAttempts repository.LoginAttemptRepository
+    // lockoutRepo счетчики неудачных входов и блокировки; nil — блокировка отключена
+    lockoutRepo repository.LoginLockoutRepository
+    // accessRepo время входа и журнал аудита для отчета о пересмотре доступа
+    accessRepo repository.AccessReviewRepository
+    // blacklistRepo черный список покупателей; nil — вход не проверяется
+    blacklistRepo repository.BlacklistRepository
+    // roleRepo справочник ролей и их прав
+    roleRepo repository.RoleRepository
     config   *config.Config
 }
 
 // NewUserHandler создает новый обработчик пользователей
-func NewUserHandler(userRepo repository.UserRepository, config *config.Config) *UserHandler {
+func NewUserHandler(userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository, emailPolicy *registration.Policy, passwordPolicy *password.Policy, loginAttempts repository.LoginAttemptRepository, lockoutRepo repository.LoginLockoutRepository, accessRepo repository.AccessReviewRepository, blacklistRepo repository.BlacklistRepository, roleRepo repository.RoleRepository, config *config.Config) *UserHandler {
     return &UserHandler{
-        userRepo: userRepo,
-        config:   config,
+        userRepo:       userRepo,
+        refreshRepo:    refreshRepo,
+        emailPolicy:    emailPolicy,
+        passwordPolicy: passwordPolicy,
+        loginAttempts:  loginAttempts,
+        lockoutRepo:    lockoutRepo,
+        accessRepo:     accessRepo,
+        blacklistRepo:  blacklistRepo,
+        roleRepo:       roleRepo,
+        config:         config,
     }
 }
 
+// users возвращает репозиторий пользователей, учитывающий время запросов к БД в Server-Timing запроса r
+func (h *UserHandler) users(r *http.Request) repository.UserRepository {
+	return repository.TimedUserRepository(h.userRepo, servertiming.FromContext(r.Context()))
+}
+
+// refreshTokens возвращает репозиторий refresh токенов, учитывающий время запросов к БД запроса r
+func (h *UserHandler) refreshTokens(r *http.Request) repository.RefreshTokenRepository {
+	return repository.TimedRefreshTokenRepository(h.refreshRepo, servertiming.FromContext(r.Context()))
+}
+
+// attempts возвращает репозиторий попыток входа, учитывающий время запросов к БД запроса r
+func (h *UserHandler) attempts(r *http.Request) repository.LoginAttemptRepository {
+	return repository.TimedLoginAttemptRepository(h.loginAttempts, servertiming.FromContext(r.Context()))
+}
+
+// access возвращает репозиторий отчета о пересмотре доступа, учитывающий время запросов к БД запроса r
+func (h *UserHandler) access(r *http.Request) repository.AccessReviewRepository {
+	return repository.TimedAccessReviewRepository(h.accessRepo, servertiming.FromContext(r.Context()))
+}
+
+// roleDirectory возвращает справочник ролей, учитывающий время запросов к БД запроса r
+func (h *UserHandler) roleDirectory(r *http.Request) repository.RoleRepository {
+	return repository.TimedRoleRepository(h.roleRepo, servertiming.FromContext(r.Context()))
+}
+
 // RegisterUser обрабатывает регистрацию нового пользователя
 func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
     var req models.RegisterRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный JSON")
+    if !h.decodeJSON(w, r, &req) {
         return
     }
 
@@ -50,8 +105,18 @@ func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
     // Нормализуем email (обрезаем пробелы и приводим к нижнему регистру)
     email := strings.TrimSpace(strings.ToLower(req.Email))
 
+    // Проверка запрещенных и одноразовых доменов
+    if !h.checkEmailDomain(w, r, "registration", email) {
+        return
+    }
+
+    // Проверка пароля по политике паролей
+    if !h.checkPassword(w, r, "registration", email, req.Password, email, req.Name) {
+        return
+    }
+
     // Проверка существования email
-    exists, err := h.userRepo.EmailExists(email)
+    exists, err := h.users(r).EmailExists(email)
     if err != nil {
         h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки email")
         return
@@ -69,17 +134,19 @@ func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
     }
 
     // Создание пользователя
+    now := timeutil.Now()
     user := &models.User{
-        ID:        uuid.New(),
+        ID:        ids.New(),
         Email:     email,
         Password:  hashedPassword,
         Name:      req.Name,
         Roles:     pq.StringArray{"user"},
-        CreatedAt: time.Now(),
-        UpdatedAt: time.Now(),
+        Timezone:  timeutil.DefaultTimezone,
+        CreatedAt: now,
+        UpdatedAt: now,
     }
 
-    if err := h.userRepo.Create(user); err != nil {
+    if err := h.users(r).Create(user); err != nil {
         logger.LogAuthEvent(r, "registration", email, false, err.Error())
         h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка создания пользователя")
         return
@@ -96,8 +163,7 @@ func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 // LoginUser обрабатывает вход пользователя
 func (h *UserHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
     var req models.LoginRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный JSON")
+    if !h.decodeJSON(w, r, &req) {
         return
     }
 
@@ -110,38 +176,82 @@ func (h *UserHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
     // Нормализуем email
     email := strings.TrimSpace(strings.ToLower(req.Email))
 
+    // Вход с IP адреса, заблокированного после неудачных попыток, отклоняется
+    ip := clientIP(r)
+    if !h.checkLoginLockout(w, r, models.LockoutScopeIP, ip, email) {
+        return
+    }
+
     // Поиск пользователя по email
-    user, err := h.userRepo.GetByEmail(email)
+    user, err := h.users(r).GetByEmail(email)
     if err != nil {
+        h.recordLoginAttempt(r, nil, "", false)
+        h.recordLoginFailure(r, models.LockoutScopeIP, ip, email)
         logger.LogAuthEvent(r, "login", email, false, err.Error())
         h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Неверный email или пароль")
         return
     }
 
+    // Пароль заблокированной учетной записи не проверяется до окончания блокировки
+    if !h.checkLoginLockout(w, r, models.LockoutScopeAccount, user.ID.String(), email) {
+        return
+    }
+
     // Проверка пароля
     if !utils.CheckPassword(req.Password, user.Password) {
+        h.recordLoginAttempt(r, &user.ID, "", false)
+        h.recordLoginFailure(r, models.LockoutScopeAccount, user.ID.String(), email)
+        h.recordLoginFailure(r, models.LockoutScopeIP, ip, email)
         logger.LogAuthEvent(r, "login", email, false, "Invalid password")
         h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Неверный email или пароль")
         return
     }
 
+    // Заблокированный (без ролей) или деактивированный пользователь не может войти
+    if user.IsBlocked() {
+        h.recordLoginAttempt(r, &user.ID, "", false)
+        logger.LogAuthEvent(r, "login", email, false, "User is blocked")
+        h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Пользователь заблокирован")
+        return
+    }
+
+    // Пользователь, домен его email или IP адрес в черном списке
+    if entry := h.blacklistedLogin(r, user); entry != nil {
+        h.recordLoginAttempt(r, &user.ID, "", false)
+        logger.LogAuthEvent(r, "login", email, false, fmt.Sprintf("blacklisted: %s=%s", entry.Kind, entry.Value))
+        h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeBlacklisted, "Вход запрещен")
+        return
+    }
+
     // Генерация JWT токена
-    token, err := utils.GenerateJWT(user, h.config.JWT.Secret)
+    token, err := h.generateAccessToken(r, user)
     if err != nil {
         logger.LogAuthEvent(r, "login", email, false, "Token generation failed")
         h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка генерации токена")
         return
     }
 
+    // Выдача refresh токена
+    refreshToken, err := h.issueRefreshToken(r, user.ID)
+    if err != nil {
+        logger.LogAuthEvent(r, "login", email, false, err.Error())
+        h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка генерации токена")
+        return
+    }
+
     // Логируем успешный вход
+    h.recordLoginAttempt(r, &user.ID, "", true)
+    h.resetLoginLockout(r, user.ID)
+    h.recordLogin(r, user.ID)
     logger.LogAuthEvent(r, "login", email, true, "")
 
     // Очищаем пароль перед отправкой
     user.Password = ""
 
     response := models.LoginResponse{
-        Token: token,
-        User:  *user,
+        Token:        token,
+        RefreshToken: refreshToken,
+        User:         *user,
     }
 
     h.sendSuccessResponse(w, http.StatusOK, response)
@@ -155,7 +265,7 @@ func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    user, err := h.userRepo.GetByID(userID)
+    user, err := h.users(r).GetByID(userID)
     if err != nil {
         h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
         return
@@ -165,63 +275,39 @@ func (h *UserHandler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
     h.sendSuccessResponse(w, http.StatusOK, user)
 }
 
-// UpdateUserProfile обновляет профиль пользователя
-func (h *UserHandler) UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
-    userID, err := h.getUserIDFromContext(r)
-    if err != nil {
-        h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
-        return
-    }
-
-    var req models.UpdateProfileRequest
-    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-        h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный JSON")
-        return
-    }
-
-    if err := utils.ValidateStruct(req); err != nil {
-        h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
-        return
-    }
-
-    user, err := h.userRepo.GetByID(userID)
-    if err != nil {
-        h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
-        return
-    }
-
-    // Проверка уникальности email (если изменился)
-    if user.Email != req.Email {
-        exists, err := h.userRepo.EmailExists(strings.TrimSpace(strings.ToLower(req.Email)))
-        if err != nil {
-            h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки email")
-            return
-        }
-        if exists {
-            h.sendErrorResponse(w, http.StatusConflict, models.ErrorCodeConflict, "Пользователь с таким email уже существует")
-            return
-        }
-    }
+// GetUser возвращает пользователя по ID. Доступно самому пользователю и пользователям
+// с правом users.read
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	callerID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return
+	}
 
-    user.Email = strings.TrimSpace(strings.ToLower(req.Email))
-    user.Name = req.Name
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, "Некорректный ID пользователя")
+		return
+	}
 
-    if err := h.userRepo.Update(user); err != nil {
-        logger.LogUserAction(r, "profile_update", fmt.Sprintf("user_id=%s", userID), false)
-        h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка обновления профиля")
-        return
-    }
+	if userID != callerID && !h.can(r, rbac.UsersRead) {
+		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Недостаточно прав доступа")
+		return
+	}
 
-    logger.LogUserAction(r, "profile_update", fmt.Sprintf("user_id=%s, email=%s", userID, user.Email), true)
+	user, err := h.users(r).GetByID(userID)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusNotFound, models.ErrorCodeNotFound, "Пользователь не найден")
+		return
+	}
 
-    user.Password = ""
-    h.sendSuccessResponse(w, http.StatusOK, user)
+	user.Password = ""
+	h.sendSuccessResponse(w, http.StatusOK, user)
 }
 
-// ListUsers возвращает список пользователей (только для администраторов)
+// ListUsers возвращает список пользователей (право users.read)
 func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
-	// Проверка роли администратора
-	if !h.isAdmin(r) {
+	if !h.can(r, rbac.UsersRead) {
 		h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Недостаточно прав доступа")
 		return
 	}
@@ -230,6 +316,7 @@ func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	req := &models.ListUsersRequest{
 		Limit:  10, // значение по умолчанию
 		Offset: 0,
+		Status: models.UserStatusActive,
 	}
 
 	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
@@ -246,7 +333,12 @@ func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 
 	req.Email = r.URL.Query().Get("email")
 	req.Name = r.URL.Query().Get("name")
+	req.Q = r.URL.Query().Get("q")
 	req.Role = r.URL.Query().Get("role")
+	// Деактивированные учетные записи по умолчанию не показываются
+	if status := r.URL.Query().Get("status"); status != "" {
+		req.Status = status
+	}
 
 	// Валидация параметров
 	if err := utils.ValidateStruct(req); err != nil {
@@ -255,7 +347,7 @@ func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Получение списка пользователей
-	response, err := h.userRepo.List(req)
+	response, err := h.users(r).List(req)
 	if err != nil {
 		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка получения списка пользователей")
 		return
@@ -279,30 +371,103 @@ func (h *UserHandler) getUserIDFromContext(r *http.Request) (uuid.UUID, error) {
 	return userID, nil
 }
 
-// isAdmin проверяет, является ли пользователь администратором
-func (h *UserHandler) isAdmin(r *http.Request) bool {
-	rolesStr := r.Header.Get("X-User-Roles")
-	if rolesStr == "" {
-		return false
+// requireInteractiveUser возвращает ID текущего пользователя, если запрос
+// аутентифицирован входом, а не API ключом. Иначе отправляет ошибку и возвращает false
+func (h *UserHandler) requireInteractiveUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := h.getUserIDFromContext(r)
+	if err != nil {
+		h.sendErrorResponse(w, http.StatusUnauthorized, models.ErrorCodeUnauthorized, "Не удалось получить ID пользователя")
+		return uuid.Nil, false
 	}
+	if h.rejectAPIKey(w, r) {
+		return uuid.Nil, false
+	}
+	return userID, true
+}
 
-	roles := strings.Split(rolesStr, ",")
-	for _, role := range roles {
-		if strings.TrimSpace(role) == "admin" {
-			return true
-		}
+// rejectAPIKey отвечает 403, если запрос аутентифицирован API ключом (API Gateway
+// передает области ключа в X-User-Scopes): ключ не может менять учетную запись,
+// сессии и другие ключи. Возвращает true, если ответ уже отправлен
+func (h *UserHandler) rejectAPIKey(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("X-User-Scopes") == "" {
+		return false
 	}
+	h.sendErrorResponse(w, http.StatusForbidden, models.ErrorCodeForbidden, "Действие недоступно по API ключу")
+	return true
+}
+
+// can проверяет, что у пользователя запроса есть право permission (переданное от API Gateway)
+func (h *UserHandler) can(r *http.Request, permission string) bool {
+	return rbac.Has(r, permission)
+}
 
+// checkEmailDomain проверяет домен email по политике регистрации и при нарушении
+// отвечает ошибкой с кодом причины. Возвращает false, если ответ уже отправлен
+func (h *UserHandler) checkEmailDomain(w http.ResponseWriter, r *http.Request, action, email string) bool {
+	err := h.emailPolicy.Check(email)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, registration.ErrBannedDomain):
+		logger.LogAuthEvent(r, action, email, false, err.Error())
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeEmailDomainBanned, registration.ErrBannedDomain.Error())
+	case errors.Is(err, registration.ErrDisposableDomain):
+		logger.LogAuthEvent(r, action, email, false, err.Error())
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeDisposableEmail, registration.ErrDisposableDomain.Error())
+	default:
+		h.sendErrorResponse(w, http.StatusInternalServerError, models.ErrorCodeInternalServer, "Ошибка проверки email")
+	}
 	return false
 }
 
+// recordLoginAttempt сохраняет попытку входа с адресом и User-Agent клиента для доли
+// неудачных входов и истории входов пользователя. userID — nil, если пользователь не
+// определен; provider — провайдер OAuth, пустой при входе по паролю.
+// Ошибка сохранения не влияет на ответ клиенту
+func (h *UserHandler) recordLoginAttempt(r *http.Request, userID *uuid.UUID, provider string, success bool) {
+	if h.loginAttempts == nil {
+		return
+	}
+
+	attempt := &models.LoginAttempt{
+		UserID:    userID,
+		Method:    models.LoginMethodPassword,
+		Provider:  provider,
+		Success:   success,
+		IPAddress: truncate(clientIP(r), maxClientIPLength),
+		UserAgent: truncate(r.UserAgent(), maxUserAgentLength),
+	}
+	if provider != "" {
+		attempt.Method = models.LoginMethodOAuth
+	}
+	if err := h.attempts(r).Record(attempt); err != nil {
+		logger.GetLogger().Warn("Failed to record login attempt", zap.Error(err))
+	}
+}
+
+// recordLogin сохраняет время успешного входа пользователя для отчета о пересмотре доступа.
+// Ошибка сохранения не влияет на ответ клиенту
+func (h *UserHandler) recordLogin(r *http.Request, userID uuid.UUID) {
+	if err := h.access(r).RecordLogin(userID); err != nil {
+		logger.GetLogger().Warn("Failed to record last login", zap.String("user_id", userID.String()), zap.Error(err))
+	}
+}
+
+// recordAdminAction сохраняет действие администратора из запроса r в журнале аудита.
+// Вызывается после успешного выполнения действия; ошибка сохранения не влияет на ответ клиенту
+func (h *UserHandler) recordAdminAction(r *http.Request, action, target, details string) {
+	entry := &models.AdminAction{Action: action, Target: target, Details: details}
+	if actorID, err := h.getUserIDFromContext(r); err == nil {
+		entry.ActorID = &actorID
+	}
+	if err := h.access(r).RecordAdminAction(entry); err != nil {
+		logger.GetLogger().Warn("Failed to record admin action", zap.String("action", action), zap.Error(err))
+	}
+}
+
 // sendSuccessResponse отправляет успешный ответ
 func (h *UserHandler) sendSuccessResponse(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	response := models.NewSuccessResponse(data)
-	json.NewEncoder(w).Encode(response)
+	h.writeJSON(w, statusCode, models.NewSuccessResponse(data))
 }
 
 // sendErrorResponse отправляет ответ с ошибкой
@@ -325,9 +490,46 @@ func (h *UserHandler) sendErrorResponse(w http.ResponseWriter, statusCode int, c
 		)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+	h.writeJSON(w, statusCode, models.NewErrorResponse(code, message))
+}
+
+// decodeJSON разбирает JSON тело запроса в dst через общий httpreq. При ошибке отправляет
+// 400 (413 для слишком большого тела) с причиной и полем и возвращает false
+func (h *UserHandler) decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	err := httpreq.DecodeJSON(w, r, dst, httpreq.DefaultMaxBodySize)
+	if err == nil {
+		return true
+	}
+
+	var decodeErr *httpreq.DecodeError
+	if !errors.As(err, &decodeErr) {
+		h.sendErrorResponse(w, http.StatusBadRequest, models.ErrorCodeValidation, err.Error())
+		return false
+	}
 
-	response := models.NewErrorResponse(code, message)
-	json.NewEncoder(w).Encode(response)
+	logger.GetLogger().Warn("HTTP Error Response",
+		zap.Int("status_code", decodeErr.Status),
+		zap.String("error_code", models.ErrorCodeValidation),
+		zap.String("error_message", decodeErr.Message),
+		zap.String("reason", decodeErr.Reason),
+		zap.String("field", decodeErr.Field),
+		zap.String("service", "service_users"),
+	)
+
+	response := models.NewErrorResponse(models.ErrorCodeValidation, decodeErr.Message)
+	response.Error.Reason = decodeErr.Reason
+	response.Error.Field = decodeErr.Field
+	h.writeJSON(w, decodeErr.Status, response)
+	return false
+}
+
+// writeJSON отправляет JSON ответ через общий httpresp и логирует ошибки сериализации и записи
+func (h *UserHandler) writeJSON(w http.ResponseWriter, statusCode int, response models.APIResponse) {
+	if err := httpresp.JSON(w, statusCode, response); err != nil {
+		logger.GetLogger().Error("Failed to write JSON response",
+			zap.Int("status_code", statusCode),
+			zap.Error(err),
+			zap.String("service", "service_users"),
+		)
+	}
 }
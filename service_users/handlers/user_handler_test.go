@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"service_users/config"
+	"service_users/fakes"
+	"service_users/models"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// apiKeyFixture обработчики учетной записи поверх фейкового репозитория пользователей
+type apiKeyFixture struct {
+	user     *models.User
+	profile  *ProfileHandler
+	password *PasswordResetHandler
+	deletion *DeletionHandler
+	sessions *SessionHandler
+	apiKeys  *APIKeyHandler
+}
+
+func newAPIKeyFixture() *apiKeyFixture {
+	users := fakes.NewUserRepository()
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Name: "Иван Петров", Timezone: "UTC"}
+	users.Seed(user)
+
+	base := NewUserHandler(users, nil, nil, nil, nil, nil, nil, nil, nil, &config.Config{})
+	return &apiKeyFixture{
+		user:     user,
+		profile:  NewProfileHandler(base, nil, nil),
+		password: NewPasswordResetHandler(base, nil, nil, nil),
+		deletion: NewDeletionHandler(base, nil, nil, nil),
+		sessions: NewSessionHandler(base, nil),
+		apiKeys:  NewAPIKeyHandler(base, nil),
+	}
+}
+
+// accountRequest возвращает запрос пользователя user, аутентифицированный входом
+func accountRequest(method, path, body string, user *models.User) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", user.ID.String())
+	return req
+}
+
+// apiKeyRequest возвращает запрос пользователя user, аутентифицированный API ключом:
+// API Gateway передает области ключа в X-User-Scopes
+func apiKeyRequest(method, path, body string, user *models.User) *http.Request {
+	req := accountRequest(method, path, body, user)
+	req.Header.Set("X-User-Scopes", "users:read,users:write")
+	return req
+}
+
+func TestAccountWritesRejectAPIKey(t *testing.T) {
+	f := newAPIKeyFixture()
+	sessionID := uuid.NewString()
+
+	cases := []struct {
+		name    string
+		handler http.HandlerFunc
+		req     *http.Request
+	}{
+		{"смена email через PUT", f.profile.UpdateUserProfile,
+			apiKeyRequest(http.MethodPut, "/v1/users/profile", `{"name":"Иван Петров","email":"new@example.com"}`, f.user)},
+		{"смена email через PATCH", f.profile.PatchUserProfile,
+			apiKeyRequest(http.MethodPatch, "/v1/users/profile", `{"email":"new@example.com"}`, f.user)},
+		{"смена пароля", f.password.ChangePassword,
+			apiKeyRequest(http.MethodPost, "/v1/users/me/password", `{"current_password":"Old-passw0rd","new_password":"New-passw0rd"}`, f.user)},
+		{"удаление учетной записи", f.deletion.DeleteCurrentUser,
+			apiKeyRequest(http.MethodDelete, "/v1/users/me", "", f.user)},
+		{"выход на всех устройствах", f.sessions.RevokeAllSessions,
+			apiKeyRequest(http.MethodDelete, "/v1/users/me/sessions", "", f.user)},
+		{"завершение сессии", f.sessions.RevokeSession,
+			mux.SetURLVars(apiKeyRequest(http.MethodDelete, "/v1/users/me/sessions/"+sessionID, "", f.user), map[string]string{"id": sessionID})},
+		{"создание API ключа", f.apiKeys.CreateAPIKey,
+			apiKeyRequest(http.MethodPost, "/v1/users/profile/api-keys", `{"name":"ci","scopes":["orders:read"]}`, f.user)},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tc.handler(rec, tc.req)
+
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("код %d, ожидался 403: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestProfileUpdateByAPIKeyKeepsEmail(t *testing.T) {
+	f := newAPIKeyFixture()
+
+	rec := httptest.NewRecorder()
+	f.profile.PatchUserProfile(rec, apiKeyRequest(http.MethodPatch, "/v1/users/profile", `{"name":"Петр Иванов"}`, f.user))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("код %d, ожидался 200: имя меняется по API ключу с областью users:write", rec.Code)
+	}
+}
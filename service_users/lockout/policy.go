@@ -0,0 +1,66 @@
+// Package lockout содержит политику временной блокировки входа после неудачных
+// попыток. Счетчики ведутся отдельно для учетной записи и для IP адреса клиента
+package lockout
+
+import (
+	"time"
+
+	"service_users/models"
+)
+
+// Policy правила блокировки для одной области учета (учетная запись или IP адрес)
+type Policy struct {
+	// MaxFailures неудачных входов в пределах Window до блокировки; 0 — не блокировать
+	MaxFailures int
+	// Window неудачные входы старше окна не учитываются
+	Window time.Duration
+	// Duration длительность первой блокировки; каждая следующая подряд вдвое длиннее,
+	// но не более MaxDuration
+	Duration    time.Duration
+	MaxDuration time.Duration
+	// ResetAfter без неудачных входов дольше этого срока длительность блокировки
+	// возвращается к Duration
+	ResetAfter time.Duration
+}
+
+// Enabled проверяет, включена ли блокировка
+func (p Policy) Enabled() bool {
+	return p.MaxFailures > 0
+}
+
+// RegisterFailure учитывает неудачный вход в момент now. Возвращает true, если
+// вход заблокирован этим вызовом: LockedUntil задан, счетчик неудач сброшен
+func (p Policy) RegisterFailure(state *models.LoginLockout, now time.Time) bool {
+	if state.LastFailureAt != nil {
+		idle := now.Sub(*state.LastFailureAt)
+		if idle > p.ResetAfter {
+			state.LockCount = 0
+		}
+		if idle > p.Window {
+			state.Failures = 0
+		}
+	}
+	state.Failures++
+	state.LastFailureAt = &now
+
+	if !p.Enabled() || state.Failures < p.MaxFailures {
+		return false
+	}
+	state.LockCount++
+	state.Failures = 0
+	lockedUntil := now.Add(p.lockDuration(state.LockCount))
+	state.LockedUntil = &lockedUntil
+	return true
+}
+
+// lockDuration возвращает длительность блокировки номер lockCount подряд
+func (p Policy) lockDuration(lockCount int) time.Duration {
+	duration := p.Duration
+	for i := 1; i < lockCount && duration < p.MaxDuration; i++ {
+		duration *= 2
+	}
+	if duration > p.MaxDuration {
+		duration = p.MaxDuration
+	}
+	return duration
+}
@@ -0,0 +1,50 @@
+// Package mail отправляет письма пользователям (например, ссылки для сброса пароля).
+// Способ отправки подключается через Sender: SMTP в рабочих окружениях
+// или запись в лог, если SMTP не настроен
+package mail
+
+import (
+	"context"
+
+	"service_users/logger"
+
+	"go.uber.org/zap"
+)
+
+// Message письмо пользователю
+type Message struct {
+	To      string
+	Subject string
+	// Body текст письма (text/plain)
+	Body string
+}
+
+// Sender отправляет письма
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// LogSender пишет письма в лог вместо отправки; используется, когда SMTP не настроен
+type LogSender struct {
+	// logBody писать текст письма; false скрывает одноразовые ссылки и токены
+	logBody bool
+}
+
+// NewLogSender создает отправителя писем в лог
+func NewLogSender(logBody bool) *LogSender {
+	return &LogSender{logBody: logBody}
+}
+
+// Send пишет письмо в лог
+func (s *LogSender) Send(ctx context.Context, message Message) error {
+	body := "[скрыто]"
+	if s.logBody {
+		body = message.Body
+	}
+	logger.GetLogger().Info("Письмо не отправлено: SMTP не настроен",
+		zap.String("to", message.To),
+		zap.String("subject", message.Subject),
+		zap.String("body", body),
+	)
+	return nil
+}
@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPConfig параметры SMTP сервера
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username пустой — отправка без аутентификации (например, локальный relay)
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPSender отправляет письма через SMTP сервер
+type SMTPSender struct {
+	config SMTPConfig
+}
+
+// NewSMTPSender создает отправителя писем через SMTP
+func NewSMTPSender(config SMTPConfig) *SMTPSender {
+	return &SMTPSender{config: config}
+}
+
+// Send отправляет письмо; STARTTLS используется, если сервер его поддерживает.
+// Срок отправки ограничен контекстом
+func (s *SMTPSender) Send(ctx context.Context, message Message) error {
+	addr := net.JoinHostPort(s.config.Host, strconv.Itoa(s.config.Port))
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("ошибка подключения к SMTP серверу: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("ошибка подключения к SMTP серверу: %v", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.config.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("ошибка STARTTLS: %v", err)
+		}
+	}
+	if s.config.Username != "" {
+		auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("ошибка аутентификации SMTP: %v", err)
+		}
+	}
+
+	if err := client.Mail(s.config.From); err != nil {
+		return fmt.Errorf("ошибка отправки письма: %v", err)
+	}
+	if err := client.Rcpt(message.To); err != nil {
+		return fmt.Errorf("ошибка отправки письма: %v", err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("ошибка отправки письма: %v", err)
+	}
+	if _, err := writer.Write(s.format(message)); err != nil {
+		return fmt.Errorf("ошибка отправки письма: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("ошибка отправки письма: %v", err)
+	}
+	return client.Quit()
+}
+
+// format формирует письмо в формате RFC 5322 с UTF-8 темой и текстом
+func (s *SMTPSender) format(message Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := quotedprintable.NewWriter(&buf)
+	body.Write([]byte(message.Body))
+	body.Close()
+	return buf.Bytes()
+}
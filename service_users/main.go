@@ -1,18 +1,18 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
-	"time"
 
 	"service_users/config"
-	"service_users/handlers"
 	"service_users/logger"
-	"service_users/repository"
+	"service_users/server"
+
+	"pkg/ids"
 
-	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 	"go.uber.org/zap"
 )
@@ -34,6 +34,13 @@ func main() {
 		zapLogger.Fatal("Ошибка загрузки конфигурации", zap.Error(err))
 	}
 
+	// Стратегия идентификаторов новых записей
+	idGenerator, err := ids.NewGenerator(cfg.DB.IDStrategy)
+	if err != nil {
+		zapLogger.Fatal("Ошибка настройки идентификаторов", zap.Error(err))
+	}
+	ids.SetDefault(idGenerator)
+
 	// Подключение к базе данных
 	db, err := sql.Open("postgres", cfg.DB.DSN())
 	if err != nil {
@@ -48,64 +55,26 @@ func main() {
 
 	zapLogger.Info("Успешное подключение к базе данных")
 
-	// Инициализация репозитория и обработчиков
-	userRepo := repository.NewUserRepository(db)
-	userHandler := handlers.NewUserHandler(userRepo, cfg)
-
-	// Настройка маршрутов
-	router := mux.NewRouter()
-
-	// Публичные маршруты
-	router.HandleFunc("/v1/users/register", userHandler.RegisterUser).Methods("POST")
-	router.HandleFunc("/v1/users/login", userHandler.LoginUser).Methods("POST")
-
-	// Защищенные маршруты
-	router.HandleFunc("/v1/users/profile", userHandler.GetUserProfile).Methods("GET")
-	router.HandleFunc("/v1/users/profile", userHandler.UpdateUserProfile).Methods("PUT")
-	router.HandleFunc("/v1/users", userHandler.ListUsers).Methods("GET")
-
-	// Middleware для логирования
-	router.Use(loggingMiddleware)
+	// Репозитории, обработчики и маршруты сервиса; фоновые задачи работают до завершения процесса
+	srv, err := server.New(cfg, db)
+	if err != nil {
+		zapLogger.Fatal("Ошибка инициализации сервиса", zap.Error(err))
+	}
+	defer srv.Close()
+	srv.Start(context.Background())
 
 	zapLogger.Info("Service Users запущен", zap.String("port", cfg.Server.Port))
-	log.Fatal(http.ListenAndServe(":"+cfg.Server.Port, router))
-}
-
-// loggingMiddleware middleware для логирования запросов
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Создаем wrapper для захвата статус кода
-		wrapper := &responseWrapper{ResponseWriter: w, statusCode: http.StatusOK}
-
-		start := time.Now()
-		next.ServeHTTP(wrapper, r)
-		duration := time.Since(start)
-
-		// Используем структурированный логгер
-		logger.LogHTTPRequest(r, wrapper.statusCode, r.Method, r.URL.Path, "service_users")
-
-		// Дополнительные метрики
-		zapLogger := logger.GetLogger()
-		if requestID := r.Header.Get("X-Request-ID"); requestID != "" {
-			zapLogger = logger.WithRequestID(zapLogger, requestID)
-		}
-
-		zapLogger.Info("Request completed",
-			zap.Duration("duration", duration),
-			zap.Int64("content_length", r.ContentLength),
-		)
-	})
-}
-
-// responseWrapper для захвата HTTP статус кода
-type responseWrapper struct {
-	http.ResponseWriter
-	statusCode int
-}
-
-func (rw *responseWrapper) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
+	// h2c: API Gateway может мультиплексировать запросы в одном соединении HTTP/2 без TLS
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(cfg.Server.H2C)
+	httpServer := &http.Server{
+		Addr:        ":" + cfg.Server.Port,
+		Handler:     srv.Handler(),
+		IdleTimeout: cfg.Server.IdleTimeout,
+		Protocols:   protocols,
+	}
+	log.Fatal(httpServer.ListenAndServe())
 }
 
 // getEnv возвращает значение переменной окружения или значение по умолчанию
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Форматы отчета о пересмотре доступа
+const (
+	AccessReviewFormatJSON = "json"
+	AccessReviewFormatCSV  = "csv"
+)
+
+// AdminAction запись журнала аудита действий администраторов
+type AdminAction struct {
+	ID int64 `json:"id"`
+	// ActorID администратор; nil, если его учетная запись удалена
+	ActorID *uuid.UUID `json:"actor_id,omitempty"`
+	Action  string     `json:"action"`
+	// Target объект действия: ID пользователя, домен email или источник каталога
+	Target    string    `json:"target"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccessReviewUser пользователь с привилегированными ролями в отчете о пересмотре доступа
+type AccessReviewUser struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Name      string    `json:"name"`
+	Roles     []string  `json:"roles"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastLoginAt время последнего входа; nil, если пользователь не входил
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	// Dormant пользователь не входил дольше ACCESS_REVIEW_DORMANT_AFTER
+	Dormant bool `json:"dormant"`
+	// ActionsCount число действий администратора за период отчета
+	ActionsCount int `json:"actions_count"`
+	// RecentActions последние действия за период отчета, новые первыми
+	RecentActions []AdminAction `json:"recent_actions"`
+}
+
+// AccessReviewReport отчет о пересмотре доступа: привилегированные пользователи,
+// их последний вход и действия за период
+type AccessReviewReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// PeriodStart начало периода, за который собраны действия администраторов
+	PeriodStart time.Time `json:"period_start"`
+	// Roles роли, считающиеся привилегированными
+	Roles []string `json:"roles"`
+	// DormantBefore пользователи без входа после этого момента считаются неактивными
+	DormantBefore time.Time          `json:"dormant_before"`
+	Users         []AccessReviewUser `json:"users"`
+	DormantCount  int                `json:"dormant_count"`
+}
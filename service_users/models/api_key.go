@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Области действия API ключа. API Gateway допускает по ключу только запросы
+// к /v1/orders и /v1/users: GET и HEAD требуют области :read, остальные методы — :write
+const (
+	APIKeyScopeOrdersRead  = "orders:read"
+	APIKeyScopeOrdersWrite = "orders:write"
+	APIKeyScopeUsersRead   = "users:read"
+	APIKeyScopeUsersWrite  = "users:write"
+)
+
+// APIKeyPrefix начало значения всех API ключей; по нему ключ отличается от JWT
+const APIKeyPrefix = "sck_"
+
+// APIKey персональный API ключ пользователя для скриптов и интеграций.
+// В БД хранится только хеш значения ключа
+type APIKey struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"-"`
+	Name   string    `json:"name"`
+	// Prefix начало значения ключа, по которому пользователь узнает ключ в списке
+	Prefix  string   `json:"prefix"`
+	KeyHash string   `json:"-"`
+	Scopes  []string `json:"scopes"`
+	// ExpiresAt срок действия; пусто — бессрочный ключ
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IsActive проверяет, что ключ не отозван и не истек
+func (k *APIKey) IsActive() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt))
+}
+
+// CreateAPIKeyRequest представляет запрос на создание API ключа
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1,dive,oneof=orders:read orders:write users:read users:write"`
+	// ExpiresInDays срок действия ключа в днях; не задан — бессрочный ключ
+	ExpiresInDays *int `json:"expires_in_days,omitempty" validate:"omitempty,min=1,max=3650"`
+}
+
+// CreateAPIKeyResponse созданный API ключ. Значение ключа возвращается только в этом ответе
+type CreateAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyAuthRequest представляет запрос API Gateway на обмен API ключа на access токен
+type APIKeyAuthRequest struct {
+	APIKey string `json:"api_key" validate:"required"`
+}
+
+// APIKeyAuthResponse access токен, выданный по API ключу
+type APIKeyAuthResponse struct {
+	Token string `json:"token"`
+	// ExpiresIn время жизни токена в секундах
+	ExpiresIn int      `json:"expires_in"`
+	Scopes    []string `json:"scopes"`
+}
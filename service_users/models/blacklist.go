@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BlacklistKind вид записи черного списка покупателей
+type BlacklistKind string
+
+const (
+	// BlacklistKindUser пользователь по ID
+	BlacklistKindUser BlacklistKind = "user"
+	// BlacklistKindEmailDomain домен email вместе с поддоменами
+	BlacklistKindEmailDomain BlacklistKind = "email_domain"
+	// BlacklistKindIP IP адрес или сеть CIDR
+	BlacklistKindIP BlacklistKind = "ip"
+)
+
+// IsValid проверяет, что вид записи известен
+func (k BlacklistKind) IsValid() bool {
+	return k == BlacklistKindUser || k == BlacklistKindEmailDomain || k == BlacklistKindIP
+}
+
+// BlacklistAction действие при совпадении с записью черного списка
+type BlacklistAction string
+
+const (
+	// BlacklistActionBlock вход и создание заказов запрещены
+	BlacklistActionBlock BlacklistAction = "block"
+	// BlacklistActionFlag вход разрешен, заказы ждут решения администратора (статус flagged)
+	BlacklistActionFlag BlacklistAction = "flag"
+)
+
+// BlacklistEntry запись черного списка покупателей. Список общий для service_users
+// (вход) и service_orders (проверки заказа перед созданием)
+type BlacklistEntry struct {
+	ID     uuid.UUID       `json:"id"`
+	Kind   BlacklistKind   `json:"kind"`
+	Value  string          `json:"value"`
+	Action BlacklistAction `json:"action"`
+	Reason string          `json:"reason"`
+	// CreatedBy администратор, добавивший запись; пусто, если он удален
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// AddBlacklistEntryRequest представляет запрос на добавление записи в черный список.
+// Повторное добавление того же значения заменяет действие и причину
+type AddBlacklistEntryRequest struct {
+	Kind   BlacklistKind   `json:"kind" validate:"required,oneof=user email_domain ip"`
+	Value  string          `json:"value" validate:"required,max=253"`
+	Action BlacklistAction `json:"action" validate:"omitempty,oneof=block flag"`
+	Reason string          `json:"reason" validate:"max=500"`
+}
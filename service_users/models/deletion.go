@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserDeletionStatus статус операции удаления данных пользователя
+type UserDeletionStatus string
+
+const (
+	UserDeletionPending   UserDeletionStatus = "pending"
+	UserDeletionRunning   UserDeletionStatus = "running"
+	UserDeletionCompleted UserDeletionStatus = "completed"
+	UserDeletionFailed    UserDeletionStatus = "failed"
+)
+
+// UserDeletion асинхронная операция удаления данных пользователя. Строка пользователя
+// не удаляется, а обезличивается: заказы ссылаются на нее и остаются в отчетности
+type UserDeletion struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	// RequestedBy сам пользователь или администратор; nil, если учетная запись инициатора удалена
+	RequestedBy *uuid.UUID         `json:"requested_by,omitempty"`
+	Status      UserDeletionStatus `json:"status"`
+	// Summary итог выполненных шагов; заполняется при завершении
+	Summary     *UserDeletionSummary `json:"summary,omitempty"`
+	Attempts    int                  `json:"attempts"`
+	LastError   string               `json:"last_error,omitempty"`
+	CreatedAt   time.Time            `json:"created_at"`
+	StartedAt   *time.Time           `json:"started_at,omitempty"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+}
+
+// IsActive проверяет, что операция еще не завершена
+func (d *UserDeletion) IsActive() bool {
+	return d.Status == UserDeletionPending || d.Status == UserDeletionRunning
+}
+
+// DeleteAccountRequest запрос удаления своих данных (DELETE /v1/users/me). Запрос без
+// токена подтверждения ничего не удаляет, а отправляет токен на email пользователя
+type DeleteAccountRequest struct {
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
+}
+
+// DeletionConfirmation ответ на запрос удаления без токена: токен отправлен на email
+// и действует до ExpiresAt
+type DeletionConfirmation struct {
+	Message   string    `json:"message"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UserDeletionSummary число записей, затронутых каждым шагом удаления
+type UserDeletionSummary struct {
+	RefreshTokensRevoked           int64 `json:"refresh_tokens_revoked"`
+	NotificationPreferencesDeleted int64 `json:"notification_preferences_deleted"`
+	// APIKeysDeleted персональные API ключи
+	APIKeysDeleted int64 `json:"api_keys_deleted"`
+	// OAuthIdentitiesDeleted связи с учетными записями провайдеров входа
+	OAuthIdentitiesDeleted int64 `json:"oauth_identities_deleted"`
+	// LoginAttemptsAnonymized попытки входа, отвязанные от пользователя без адреса и User-Agent
+	LoginAttemptsAnonymized int64 `json:"login_attempts_anonymized"`
+	// DeliveriesTombstoned доставки уведомлений и webhook, содержимое которых заменено отметкой об удалении
+	DeliveriesTombstoned int64 `json:"deliveries_tombstoned"`
+	// DeliveriesDiscarded из них еще не отправленные доставки, отмененные без отправки
+	DeliveriesDiscarded int64 `json:"deliveries_discarded"`
+	// OrdersRetained заказы, сохраненные за обезличенным пользователем
+	OrdersRetained int64 `json:"orders_retained"`
+	// RolesEpoch новая эпоха ролей: ранее выданные access токены отклоняются
+	RolesEpoch int64 `json:"roles_epoch"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "github.com/google/uuid"
+
+// DirectoryUser учетная запись внешнего каталога (SCIM или LDAP), приведенная к общему виду
+type DirectoryUser struct {
+	ExternalID string
+	Email      string
+	Name       string
+	Active     bool
+	// Groups имена групп каталога, по которым назначаются роли
+	Groups []string
+}
+
+// DirectorySyncReport отчет о синхронизации с каталогом. При dry_run содержит
+// изменения, которые будут применены, иначе — примененные изменения
+type DirectorySyncReport struct {
+	Source      string               `json:"source"`
+	DryRun      bool                 `json:"dry_run"`
+	Created     []DirectoryChange    `json:"created"`
+	Updated     []DirectoryChange    `json:"updated"`
+	Deactivated []DirectoryChange    `json:"deactivated"`
+	Unchanged   int                  `json:"unchanged"`
+	Errors      []DirectorySyncError `json:"errors"`
+}
+
+// DirectoryChange изменение одной учетной записи
+type DirectoryChange struct {
+	// UserID пустой для пользователей, которые еще не созданы
+	UserID     *uuid.UUID    `json:"user_id,omitempty"`
+	ExternalID string        `json:"external_id"`
+	Email      string        `json:"email"`
+	Fields     []FieldChange `json:"fields,omitempty"`
+}
+
+// FieldChange изменение поля учетной записи
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// DirectorySyncError запись каталога, которую не удалось синхронизировать
+type DirectorySyncError struct {
+	ExternalID string `json:"external_id,omitempty"`
+	Email      string `json:"email,omitempty"`
+	Error      string `json:"error"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChangeToken одноразовый токен подтверждения нового email. В БД хранится только
+// хеш значения токена
+type EmailChangeToken struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	// OldEmail адрес на момент запроса; если он изменился до подтверждения, токен недействителен
+	OldEmail  string     `json:"old_email" db:"old_email"`
+	NewEmail  string     `json:"new_email" db:"new_email"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// EmailChangeConfirmRequest представляет запрос на подтверждение нового email по токену из письма
+type EmailChangeConfirmRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// PendingEmailChange новый email, ожидающий подтверждения
+type PendingEmailChange struct {
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ProfileResponse профиль пользователя после обновления. Новый email не применяется
+// сразу: до подтверждения по ссылке из письма он возвращается в PendingEmailChange
+type ProfileResponse struct {
+	User
+	PendingEmailChange *PendingEmailChange `json:"pending_email_change,omitempty"`
+}
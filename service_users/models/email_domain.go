@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BannedEmailDomain домен email, с которого запрещены регистрация и смена email
+type BannedEmailDomain struct {
+	Domain string `json:"domain"`
+	Reason string `json:"reason"`
+	// CreatedBy администратор, добавивший домен; пусто, если он удален
+	CreatedBy *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BanEmailDomainRequest представляет запрос на добавление домена в список запрещенных
+type BanEmailDomainRequest struct {
+	Domain string `json:"domain" validate:"required,max=253"`
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+// DisposableDomainsState состояние списка одноразовых почтовых доменов
+type DisposableDomainsState struct {
+	// Enabled регистрация с одноразовых доменов запрещена
+	Enabled bool `json:"enabled"`
+	Count   int  `json:"count"`
+	// RefreshedAt время последнего обновления списка по DISPOSABLE_DOMAINS_URL;
+	// пусто, если используется только встроенный список
+	RefreshedAt *time.Time `json:"refreshed_at,omitempty"`
+}
+
+// EmailDomainPolicy запрещенные домены и состояние проверки одноразовых доменов
+type EmailDomainPolicy struct {
+	Banned     []BannedEmailDomain    `json:"banned"`
+	Disposable DisposableDomainsState `json:"disposable"`
+}
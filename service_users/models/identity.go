@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity учетная запись внешнего провайдера OAuth2, связанная с пользователем
+type UserIdentity struct {
+	Provider string `json:"provider" db:"provider"`
+	// Subject постоянный ID учетной записи у провайдера
+	Subject string    `json:"subject" db:"subject"`
+	UserID  uuid.UUID `json:"user_id" db:"user_id"`
+	// Email адрес учетной записи провайдера на момент связывания
+	Email     string    `json:"email" db:"email"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
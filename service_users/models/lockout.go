@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Области учета неудачных входов
+const (
+	// LockoutScopeAccount неудачные входы учетной записи; Subject — ID пользователя
+	LockoutScopeAccount = "account"
+	// LockoutScopeIP неудачные входы с IP адреса клиента; Subject — адрес
+	LockoutScopeIP = "ip"
+)
+
+// LoginLockout счетчик неудачных входов и временная блокировка учетной записи или IP адреса
+type LoginLockout struct {
+	Scope   string `json:"scope" db:"scope"`
+	Subject string `json:"subject" db:"subject"`
+	// Failures неудачные входы в пределах окна после последней блокировки
+	Failures int `json:"failures" db:"failures"`
+	// LockCount число блокировок подряд: каждая следующая вдвое длиннее предыдущей
+	LockCount     int        `json:"lock_count" db:"lock_count"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty" db:"last_failure_at"`
+}
+
+// IsLocked проверяет, действует ли блокировка в момент now
+func (l *LoginLockout) IsLocked(now time.Time) bool {
+	return l.LockedUntil != nil && now.Before(*l.LockedUntil)
+}
+
+// UnlockUserResponse ответ на снятие блокировки входа пользователя
+type UnlockUserResponse struct {
+	UserID uuid.UUID `json:"user_id"`
+	// WasLocked вход был заблокирован в момент запроса
+	WasLocked bool `json:"was_locked"`
+	// Failures число неудачных входов, сброшенных вместе с блокировкой
+	Failures int `json:"failures"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Способы входа в истории попыток входа
+const (
+	// LoginMethodPassword вход по email и паролю
+	LoginMethodPassword = "password"
+	// LoginMethodOAuth вход через провайдера OAuth; провайдер указывается в Provider
+	LoginMethodOAuth = "oauth"
+)
+
+// LoginAttempt попытка входа. Попытки с неизвестным email хранятся без UserID и
+// учитываются только в доле неудачных входов
+type LoginAttempt struct {
+	ID       int64      `json:"id" db:"id"`
+	UserID   *uuid.UUID `json:"-" db:"user_id"`
+	Method   string     `json:"method" db:"method"`
+	Provider string     `json:"provider,omitempty" db:"provider"`
+	Success  bool       `json:"success" db:"success"`
+	// IPAddress адрес клиента (последний адрес X-Forwarded-For, установленного API Gateway)
+	IPAddress   string    `json:"ip_address" db:"ip_address"`
+	UserAgent   string    `json:"user_agent" db:"user_agent"`
+	AttemptedAt time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+// LoginAttemptsResponse страница истории входов пользователя, новые попытки первыми
+type LoginAttemptsResponse struct {
+	Attempts []LoginAttempt `json:"attempts"`
+	Total    int            `json:"total"`
+	Limit    int            `json:"limit"`
+	Offset   int            `json:"offset"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationChannels включенные каналы доставки уведомлений
+type NotificationChannels struct {
+	Email    bool `json:"email"`
+	SMS      bool `json:"sms"`
+	Telegram bool `json:"telegram"`
+}
+
+// NotificationEvents события, на уведомления о которых подписан пользователь
+type NotificationEvents struct {
+	OrderCreated       bool `json:"order_created"`
+	OrderStatusChanged bool `json:"order_status_changed"`
+	Marketing          bool `json:"marketing"`
+}
+
+// NotificationPreferences представляет настройки уведомлений пользователя
+type NotificationPreferences struct {
+	UserID    uuid.UUID            `json:"user_id" db:"user_id"`
+	Channels  NotificationChannels `json:"channels"`
+	Events    NotificationEvents   `json:"events"`
+	UpdatedAt time.Time            `json:"updated_at" db:"updated_at"`
+}
+
+// UpdateNotificationPreferencesRequest представляет запрос на обновление настроек уведомлений
+type UpdateNotificationPreferencesRequest struct {
+	Channels NotificationChannels `json:"channels"`
+	Events   NotificationEvents   `json:"events"`
+}
+
+// DefaultNotificationPreferences возвращает настройки по умолчанию:
+// транзакционные уведомления по email включены, маркетинг выключен
+func DefaultNotificationPreferences(userID uuid.UUID) *NotificationPreferences {
+	return &NotificationPreferences{
+		UserID: userID,
+		Channels: NotificationChannels{
+			Email: true,
+		},
+		Events: NotificationEvents{
+			OrderCreated:       true,
+			OrderStatusChanged: true,
+		},
+	}
+}
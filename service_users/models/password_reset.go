@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordResetToken одноразовый токен сброса пароля. В БД хранится только хеш значения токена
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PasswordResetRequest представляет запрос на отправку ссылки для сброса пароля
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// PasswordResetConfirmRequest представляет запрос на установку нового пароля по токену сброса
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+// ChangePasswordRequest представляет запрос на смену пароля текущего пользователя
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// PasswordPolicyResponse требования политики паролей для отображения в формах
+type PasswordPolicyResponse struct {
+	// MinLength минимальная длина в символах
+	MinLength int `json:"min_length"`
+	// MaxLength максимальная длина в байтах UTF-8
+	MaxLength int `json:"max_length"`
+	// MinClasses сколько видов символов (строчные и заглавные буквы, цифры, прочие
+	// символы) должен содержать пароль
+	MinClasses int `json:"min_classes"`
+	// BreachCheck пароль проверяется по базе утекших паролей
+	BreachCheck bool `json:"breach_check"`
+}
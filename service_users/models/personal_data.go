@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// PersonalData персональные данные пользователя, хранящиеся в service_users
+// (GET /v1/users/me/export). Заказы пользователя отдает service_orders
+type PersonalData struct {
+	ExportedAt time.Time `json:"exported_at"`
+	Profile    User      `json:"profile"`
+	// Identities связанные учетные записи провайдеров входа (OAuth)
+	Identities              []UserIdentity           `json:"identities"`
+	NotificationPreferences *NotificationPreferences `json:"notification_preferences"`
+	LoginHistory            LoginHistory             `json:"login_history"`
+}
+
+// LoginHistory сведения о входах пользователя: попытки входа, сессии и счетчик
+// неудачных входов
+type LoginHistory struct {
+	LastLoginAt *time.Time `json:"last_login_at"`
+	// Attempts попытки входа от новых к старым за срок хранения LOGIN_ATTEMPTS_RETENTION
+	Attempts []LoginAttempt `json:"attempts"`
+	// Sessions сессии (refresh токены) от новых к старым; значения токенов не хранятся
+	Sessions []LoginSession `json:"sessions"`
+	// Lockout счетчик неудачных входов учетной записи; nil, если их не было
+	Lockout *LoginLockout `json:"lockout"`
+}
+
+// LoginSession сессия пользователя: выданный при входе refresh токен и его ротации
+type LoginSession struct {
+	UserAgent string     `json:"user_agent"`
+	IPAddress string     `json:"ip_address"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
@@ -11,6 +11,10 @@ type APIResponse struct {
 type APIError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	// Reason и Field уточняют ошибку разбора тела запроса: причина (unknown_field,
+	// type_mismatch, ...) и поле JSON, к которому она относится
+	Reason string `json:"reason,omitempty"`
+	Field  string `json:"field,omitempty"`
 }
 
 // NewSuccessResponse создает успешный ответ
@@ -40,4 +44,18 @@ const (
 	ErrorCodeForbidden      = "FORBIDDEN"
 	ErrorCodeConflict       = "CONFLICT"
 	ErrorCodeInternalServer = "INTERNAL_SERVER_ERROR"
+	// ErrorCodeMethodNotAllowed маршрут не поддерживает метод запроса
+	ErrorCodeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	// ErrorCodeEmailDomainBanned домен email запрещен администратором
+	ErrorCodeEmailDomainBanned = "EMAIL_DOMAIN_BANNED"
+	// ErrorCodeDisposableEmail адрес на одноразовом почтовом сервисе
+	ErrorCodeDisposableEmail = "DISPOSABLE_EMAIL"
+	// ErrorCodeLoginLocked вход временно заблокирован после неудачных попыток
+	ErrorCodeLoginLocked = "LOGIN_LOCKED"
+	// ErrorCodeWeakPassword пароль не соответствует политике паролей
+	ErrorCodeWeakPassword = "WEAK_PASSWORD"
+	// ErrorCodeExportInProgress у пользователя уже формируется другая выгрузка
+	ErrorCodeExportInProgress = "EXPORT_IN_PROGRESS"
+	// ErrorCodeBlacklisted пользователь, его домен email или IP адрес в черном списке
+	ErrorCodeBlacklisted = "BLACKLISTED"
 )
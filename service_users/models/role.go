@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Role роль пользователя и ее права
+type Role struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Builtin встроенная роль (user, admin); не удаляется
+	Builtin bool `json:"builtin"`
+	// PermissionsLocked права роли не изменяются: admin всегда имеет все права
+	PermissionsLocked bool      `json:"permissions_locked"`
+	Permissions       []string  `json:"permissions"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Permission право доступа, которое может быть выдано роли
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateRoleRequest представляет запрос на создание роли
+type CreateRoleRequest struct {
+	Name        string   `json:"name" validate:"required,max=50,rolename"`
+	Description string   `json:"description" validate:"max=500"`
+	Permissions []string `json:"permissions" validate:"max=100,dive,required,max=100"`
+}
+
+// UpdateRoleRequest представляет запрос на изменение описания и прав роли.
+// Permissions заменяет все права роли
+type UpdateRoleRequest struct {
+	Description string   `json:"description" validate:"max=500"`
+	Permissions []string `json:"permissions" validate:"required,max=100,dive,required,max=100"`
+}
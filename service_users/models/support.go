@@ -0,0 +1,9 @@
+package models
+
+// SupportUserView карточка пользователя для поддержки: профиль и последние попытки входа.
+// Персональные данные маскируются, если раскрытие не запрошено
+type SupportUserView struct {
+	User User `json:"user"`
+	// RecentLogins последние попытки входа, новые первыми; пусто, если учет попыток отключен
+	RecentLogins []LoginAttempt `json:"recent_logins"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken представляет refresh токен. В БД хранится только хеш значения токена
+type RefreshToken struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	// SessionID сессия: общий ID токена, выданного при входе, и всех его ротаций
+	SessionID uuid.UUID `json:"session_id" db:"session_id"`
+	TokenHash string    `json:"-" db:"token_hash"`
+	// UserAgent и IPAddress клиента, получившего токен при входе или ротации
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IPAddress  string     `json:"ip_address" db:"ip_address"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	ReplacedBy *uuid.UUID `json:"replaced_by,omitempty" db:"replaced_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// IsActive проверяет, что токен не отозван и не истек
+func (t *RefreshToken) IsActive() bool {
+	return t.RevokedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// RefreshRequest представляет запрос на обновление access токена
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// RefreshResponse представляет ответ с новой парой токенов
+type RefreshResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Session активная сессия пользователя на устройстве: цепочка refresh токенов от входа
+// до последней ротации. Значения токенов не раскрываются
+type Session struct {
+	ID uuid.UUID `json:"id"`
+	// UserAgent и IPAddress клиента при последнем обновлении токена
+	UserAgent string `json:"user_agent"`
+	IPAddress string `json:"ip_address"`
+	// CreatedAt время входа, LastUsedAt — последнего обновления токена
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
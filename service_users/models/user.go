@@ -1,7 +1,11 @@
 package models
 
 import (
+	"strings"
 	"time"
+	"unicode"
+
+	"pkg/timeutil"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -16,12 +20,18 @@ type User struct {
 	Roles     pq.StringArray `json:"roles" db:"roles"`
 	CreatedAt time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at" db:"updated_at"`
+	// Timezone часовой пояс IANA для представления дат в выгрузках и счетах; в API даты всегда в UTC
+	Timezone string `json:"timezone" db:"timezone"`
+	// RolesEpoch увеличивается при каждом изменении ролей; access токены с меньшей эпохой отклоняются
+	RolesEpoch int64 `json:"-" db:"roles_epoch"`
+	// DeactivatedAt время деактивации учетной записи администратором; nil — учетная запись активна
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty" db:"deactivated_at"`
 }
 
 // RegisterRequest представляет запрос на регистрацию пользователя
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
 	Name     string `json:"name" validate:"required,min=2"`
 }
 
@@ -33,23 +43,61 @@ type LoginRequest struct {
 
 // LoginResponse представляет ответ при успешном входе
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	User         User   `json:"user"`
 }
 
 // UpdateProfileRequest представляет запрос на обновление профиля
 type UpdateProfileRequest struct {
 	Name  string `json:"name" validate:"required,min=2"`
 	Email string `json:"email" validate:"required,email"`
+	// Timezone необязателен: если не передан, текущее значение сохраняется
+	Timezone string `json:"timezone,omitempty" validate:"omitempty,timezone"`
+}
+
+// PatchProfileRequest представляет частичное обновление профиля (JSON Merge Patch).
+// Отсутствующие в запросе поля остаются nil и не изменяются
+type PatchProfileRequest struct {
+	Name     *string `json:"name,omitempty" validate:"omitempty,min=2"`
+	Email    *string `json:"email,omitempty" validate:"omitempty,email"`
+	Timezone *string `json:"timezone,omitempty" validate:"omitempty,timezone"`
 }
 
+// UpdateRolesRequest представляет запрос на изменение ролей пользователя.
+// Пустой список ролей блокирует пользователя; роли должны существовать в справочнике ролей
+type UpdateRolesRequest struct {
+	Roles []string `json:"roles" validate:"required,max=10,dive,max=50,rolename"`
+}
+
+// Фильтры списка пользователей по состоянию учетной записи
+const (
+	UserStatusActive      = "active"
+	UserStatusDeactivated = "deactivated"
+	UserStatusAll         = "all"
+)
+
 // ListUsersRequest представляет параметры для получения списка пользователей
 type ListUsersRequest struct {
 	Limit  int    `json:"limit" validate:"min=1,max=100"`
 	Offset int    `json:"offset" validate:"min=0"`
 	Email  string `json:"email"`
 	Name   string `json:"name"`
-	Role   string `json:"role"`
+	// Q поисковый запрос по email и имени: все слова должны встречаться как начала слов
+	// email или имени, либо запрос похож на email или имя (опечатки). Результаты
+	// упорядочиваются по релевантности
+	Q    string `json:"q" validate:"max=200"`
+	Role string `json:"role"`
+	// Status состояние учетных записей: active (по умолчанию), deactivated или all
+	Status string `json:"status" validate:"oneof=active deactivated all"`
+}
+
+// SearchTerms разбивает поисковый запрос Q на слова в нижнем регистре по пробелам и
+// знакам препинания, как email и имя разбиваются в индексе поиска
+func (r *ListUsersRequest) SearchTerms() []string {
+	return strings.FieldsFunc(strings.ToLower(r.Q), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
 }
 
 // ListUsersResponse представляет ответ со списком пользователей
@@ -74,3 +122,20 @@ func (u *User) HasRole(role string) bool {
 func (u *User) IsAdmin() bool {
 	return u.HasRole("admin")
 }
+
+// IsBlocked проверяет, заблокирован ли пользователь: у него нет ни одной роли
+// или учетная запись деактивирована
+func (u *User) IsBlocked() bool {
+	return len(u.Roles) == 0 || u.IsDeactivated()
+}
+
+// IsDeactivated проверяет, деактивирована ли учетная запись
+func (u *User) IsDeactivated() bool {
+	return u.DeactivatedAt != nil
+}
+
+// LocalTime переводит время в часовой пояс пользователя. Используется только при
+// представлении (выгрузки, счета); хранение и ответы API остаются в UTC
+func (u *User) LocalTime(t time.Time) time.Time {
+	return timeutil.In(t, u.Timezone)
+}
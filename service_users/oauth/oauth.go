@@ -0,0 +1,203 @@
+// Package oauth реализует вход через внешних провайдеров OAuth2 (Google, GitHub):
+// authorization code flow с PKCE и получение подтвержденного email учетной записи.
+// Провайдер подключается через Provider
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Поддерживаемые провайдеры
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+)
+
+// maxResponseSize ограничение размера ответа провайдера
+const maxResponseSize = 1 << 20
+
+// ErrEmailNotVerified возвращается, если провайдер не подтвердил email учетной записи:
+// без подтверждения email нельзя ни связать учетную запись с пользователем, ни создать нового
+var ErrEmailNotVerified = errors.New("email учетной записи провайдера не подтвержден")
+
+// Identity учетная запись пользователя у провайдера
+type Identity struct {
+	Provider string
+	// Subject постоянный ID учетной записи у провайдера; email может меняться
+	Subject string
+	// Email подтвержденный провайдером адрес в нижнем регистре
+	Email string
+	Name  string
+}
+
+// Provider провайдер OAuth2
+type Provider interface {
+	// Name имя провайдера в пути /v1/users/oauth/{provider}
+	Name() string
+	// AuthURL возвращает адрес страницы входа провайдера
+	AuthURL(state, codeChallenge, redirectURL string) string
+	// Identify обменивает код авторизации на токен и возвращает учетную запись пользователя
+	Identify(ctx context.Context, code, codeVerifier, redirectURL string) (*Identity, error)
+}
+
+// Credentials учетные данные приложения у провайдера
+type Credentials struct {
+	ClientID     string
+	ClientSecret string
+}
+
+// provider общая реализация Provider; провайдеры различаются адресами, scope
+// и способом получения учетной записи по access токену
+type provider struct {
+	name        string
+	credentials Credentials
+	authURL     string
+	tokenURL    string
+	scopes      []string
+	client      *http.Client
+	identify    func(ctx context.Context, client *http.Client, accessToken string) (*Identity, error)
+}
+
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// Name возвращает имя провайдера
+func (p *provider) Name() string {
+	return p.name
+}
+
+// AuthURL возвращает адрес страницы входа провайдера с PKCE (S256)
+func (p *provider) AuthURL(state, codeChallenge, redirectURL string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.credentials.ClientID},
+		"redirect_uri":          {redirectURL},
+		"scope":                 {strings.Join(p.scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	return p.authURL + "?" + query.Encode()
+}
+
+// Identify обменивает код авторизации на access токен и получает по нему учетную запись
+func (p *provider) Identify(ctx context.Context, code, codeVerifier, redirectURL string) (*Identity, error) {
+	accessToken, err := p.exchange(ctx, code, codeVerifier, redirectURL)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := p.identify(ctx, p.client, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	identity.Provider = p.name
+	identity.Email = strings.ToLower(strings.TrimSpace(identity.Email))
+	if identity.Subject == "" || identity.Email == "" {
+		return nil, fmt.Errorf("провайдер %s не вернул ID или email учетной записи", p.name)
+	}
+	return identity, nil
+}
+
+// exchange обменивает код авторизации на access токен
+func (p *provider) exchange(ctx context.Context, code, codeVerifier, redirectURL string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {p.credentials.ClientID},
+		"client_secret": {p.credentials.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("некорректный адрес получения токена: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ошибка запроса токена у %s: %v", p.name, err)
+	}
+	defer resp.Body.Close()
+
+	// GitHub сообщает об ошибке обмена со статусом 200, поэтому проверяется и поле error
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&token); err != nil {
+		return "", fmt.Errorf("некорректный ответ %s при получении токена (статус %d): %v", p.name, resp.StatusCode, err)
+	}
+	if token.Error != "" {
+		return "", fmt.Errorf("%s отклонил код авторизации: %s %s", p.name, token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", fmt.Errorf("%s не выдал access токен (статус %d)", p.name, resp.StatusCode)
+	}
+	return token.AccessToken, nil
+}
+
+// getJSON запрашивает ресурс провайдера с access токеном и разбирает JSON ответ в dst
+func getJSON(ctx context.Context, client *http.Client, resource, accessToken string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resource, nil)
+	if err != nil {
+		return fmt.Errorf("некорректный адрес %s: %v", resource, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса %s: %v", resource, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s вернул статус %d", resource, resp.StatusCode)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(dst); err != nil {
+		return fmt.Errorf("некорректный ответ %s: %v", resource, err)
+	}
+	return nil
+}
+
+// NewState возвращает случайные state (защита от подмены ответа провайдера)
+// и code verifier PKCE
+func NewState() (state, codeVerifier string, err error) {
+	if state, err = randomString(); err != nil {
+		return "", "", err
+	}
+	if codeVerifier, err = randomString(); err != nil {
+		return "", "", err
+	}
+	return state, codeVerifier, nil
+}
+
+// CodeChallenge возвращает code challenge PKCE (S256) для codeVerifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString возвращает 256 случайных бит в base64url (43 символа)
+func randomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("ошибка генерации случайного значения: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,88 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// NewGoogle создает провайдера Google (OpenID Connect userinfo)
+func NewGoogle(credentials Credentials) Provider {
+	return &provider{
+		name:        ProviderGoogle,
+		credentials: credentials,
+		authURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+		tokenURL:    "https://oauth2.googleapis.com/token",
+		scopes:      []string{"openid", "email", "profile"},
+		client:      newHTTPClient(),
+		identify:    identifyGoogle,
+	}
+}
+
+// identifyGoogle получает учетную запись Google по access токену
+func identifyGoogle(ctx context.Context, client *http.Client, accessToken string) (*Identity, error) {
+	var info struct {
+		Subject       string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+		Name          string `json:"name"`
+	}
+	if err := getJSON(ctx, client, "https://openidconnect.googleapis.com/v1/userinfo", accessToken, &info); err != nil {
+		return nil, err
+	}
+	if !info.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+	return &Identity{Subject: info.Subject, Email: info.Email, Name: info.Name}, nil
+}
+
+// NewGitHub создает провайдера GitHub
+func NewGitHub(credentials Credentials) Provider {
+	return &provider{
+		name:        ProviderGitHub,
+		credentials: credentials,
+		authURL:     "https://github.com/login/oauth/authorize",
+		tokenURL:    "https://github.com/login/oauth/access_token",
+		scopes:      []string{"read:user", "user:email"},
+		client:      newHTTPClient(),
+		identify:    identifyGitHub,
+	}
+}
+
+// identifyGitHub получает учетную запись GitHub по access токену. Email профиля
+// может быть скрыт или не подтвержден, поэтому используется основной подтвержденный
+// адрес из списка адресов учетной записи
+func identifyGitHub(ctx context.Context, client *http.Client, accessToken string) (*Identity, error) {
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := getJSON(ctx, client, "https://api.github.com/user", accessToken, &user); err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, client, "https://api.github.com/user/emails", accessToken, &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{Name: user.Name}
+	if user.ID != 0 {
+		identity.Subject = strconv.FormatInt(user.ID, 10)
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			identity.Email = email.Email
+			return identity, nil
+		}
+	}
+	return nil, ErrEmailNotVerified
+}
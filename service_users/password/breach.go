@@ -0,0 +1,71 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRangeSize максимальный размер ответа k-anonymity API на один префикс
+const maxRangeSize = 1 << 20
+
+// breachClient клиент k-anonymity API базы утекших паролей (формат Have I Been Pwned):
+// отправляются только первые 5 символов SHA-1 пароля, сравнение выполняется локально
+type breachClient struct {
+	url    string
+	client *http.Client
+}
+
+// newBreachClient создает клиента для адреса url с таймаутом запроса timeout
+func newBreachClient(url string, timeout time.Duration) *breachClient {
+	return &breachClient{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// Breached проверяет, встречается ли пароль в базе утекших паролей
+func (c *breachClient) Breached(ctx context.Context, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+prefix, nil)
+	if err != nil {
+		return false, fmt.Errorf("некорректный адрес базы утекших паролей: %v", err)
+	}
+	// Дополнение ответа фиктивными записями скрывает по размеру ответа, какой был префикс
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("ошибка запроса к базе утекших паролей: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("база утекших паролей вернула статус %d", resp.StatusCode)
+	}
+
+	// Строки ответа — "SUFFIX:COUNT"; у фиктивных записей COUNT равен 0
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxRangeSize))
+	for scanner.Scan() {
+		candidate, count, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		return err == nil && n > 0, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("ошибка чтения ответа базы утекших паролей: %v", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,218 @@
+// Package password содержит политику паролей: длина, классы символов, словарь
+// распространенных паролей и проверка по базе утекших паролей (k-anonymity API)
+package password
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"service_users/logger"
+	"service_users/models"
+
+	"go.uber.org/zap"
+)
+
+//go:embed common_passwords.txt
+var embeddedDenylist string
+
+// BcryptMaxBytes длина пароля в байтах, после которой bcrypt не принимает пароль
+const BcryptMaxBytes = 72
+
+// minPersonalLength минимальная длина части email или имени, которую нельзя использовать
+// в пароле; более короткие совпадения слишком часто случайны
+const minPersonalLength = 4
+
+// Config параметры политики паролей
+type Config struct {
+	// MinLength минимальная длина пароля в символах
+	MinLength int
+	// MaxLength максимальная длина пароля в байтах UTF-8, не более BcryptMaxBytes
+	MaxLength int
+	// MinClasses сколько классов символов из четырех (строчные и заглавные буквы,
+	// цифры, прочие символы) должен содержать пароль; 0 — не проверять
+	MinClasses int
+	// DenylistFile файл запрещенных паролей (один в строке), дополняющий встроенный список
+	DenylistFile string
+	// BreachCheck проверять пароль по базе утекших паролей
+	BreachCheck bool
+	// BreachURL адрес k-anonymity API; к нему добавляются первые 5 символов SHA-1 пароля
+	BreachURL     string
+	BreachTimeout time.Duration
+}
+
+// Violation ошибка проверки пароля со списком причин, по которым пароль не подходит
+type Violation struct {
+	Reasons []string
+}
+
+// Error возвращает причины через точку с запятой
+func (v *Violation) Error() string {
+	return "пароль не подходит: " + strings.Join(v.Reasons, "; ")
+}
+
+// Policy проверяет новые пароли при регистрации, сбросе и смене пароля.
+// Пароли существующих пользователей не проверяются повторно
+type Policy struct {
+	config   Config
+	denylist map[string]struct{}
+	breaches *breachClient
+}
+
+// NewPolicy создает политику со встроенным словарем и словарем из DenylistFile
+func NewPolicy(config Config) (*Policy, error) {
+	denylist, err := parseList(strings.NewReader(embeddedDenylist))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения встроенного словаря паролей: %v", err)
+	}
+
+	if config.DenylistFile != "" {
+		file, err := os.Open(config.DenylistFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка открытия словаря паролей: %v", err)
+		}
+		defer file.Close()
+
+		custom, err := parseList(file)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения словаря паролей %s: %v", config.DenylistFile, err)
+		}
+		for word := range custom {
+			denylist[word] = struct{}{}
+		}
+	}
+
+	policy := &Policy{config: config, denylist: denylist}
+	if config.BreachCheck {
+		policy.breaches = newBreachClient(config.BreachURL, config.BreachTimeout)
+	}
+	return policy, nil
+}
+
+// Check проверяет пароль. personal — email и имя пользователя: пароль не должен их
+// содержать. Возвращает *Violation, если пароль не соответствует политике.
+// Недоступность базы утекших паролей не мешает регистрации: ошибка только пишется в лог
+func (p *Policy) Check(ctx context.Context, password string, personal ...string) error {
+	var reasons []string
+
+	if utf8.RuneCountInString(password) < p.config.MinLength {
+		reasons = append(reasons, fmt.Sprintf("короче %d символов", p.config.MinLength))
+	}
+	if len(password) > p.config.MaxLength {
+		reasons = append(reasons, fmt.Sprintf("длиннее %d байт (символ кириллицы занимает 2 байта)", p.config.MaxLength))
+	}
+	if p.config.MinClasses > 0 && characterClasses(password) < p.config.MinClasses {
+		reasons = append(reasons, fmt.Sprintf("содержит менее %d из 4 видов символов: строчные буквы, заглавные буквы, цифры, прочие символы", p.config.MinClasses))
+	}
+	if p.denied(password) {
+		reasons = append(reasons, "слишком распространенный пароль")
+	}
+	if containsPersonal(password, personal) {
+		reasons = append(reasons, "содержит email или имя")
+	}
+
+	// Запрос к базе утекших паролей не нужен, если пароль уже отклонен
+	if len(reasons) == 0 && p.breaches != nil {
+		breached, err := p.breaches.Breached(ctx, password)
+		if err != nil {
+			logger.GetLogger().Warn("Не удалось проверить пароль по базе утекших паролей", zap.Error(err))
+		} else if breached {
+			reasons = append(reasons, "встречается в известных утечках паролей")
+		}
+	}
+
+	if len(reasons) > 0 {
+		return &Violation{Reasons: reasons}
+	}
+	return nil
+}
+
+// Requirements возвращает требования политики для отображения клиентам
+func (p *Policy) Requirements() models.PasswordPolicyResponse {
+	return models.PasswordPolicyResponse{
+		MinLength:   p.config.MinLength,
+		MaxLength:   p.config.MaxLength,
+		MinClasses:  p.config.MinClasses,
+		BreachCheck: p.config.BreachCheck,
+	}
+}
+
+// denied проверяет пароль по словарю: целиком и без цифр и символов в конце
+func (p *Policy) denied(password string) bool {
+	lower := strings.ToLower(password)
+	if _, ok := p.denylist[lower]; ok {
+		return true
+	}
+	stem := strings.TrimRightFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if stem == "" || stem == lower {
+		return false
+	}
+	_, ok := p.denylist[stem]
+	return ok
+}
+
+// characterClasses возвращает число видов символов пароля: строчные и заглавные буквы,
+// цифры, прочие символы
+func characterClasses(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// containsPersonal проверяет, содержит ли пароль email (часть до @) или слово имени
+func containsPersonal(password string, personal []string) bool {
+	lower := strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if at := strings.LastIndex(value, "@"); at >= 0 {
+			value = value[:at]
+		}
+		for _, word := range strings.Fields(value) {
+			if utf8.RuneCountInString(word) >= minPersonalLength && strings.Contains(lower, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseList читает словарь паролей: один пароль в строке, строки с # — комментарии
+func parseList(reader io.Reader) (map[string]struct{}, error) {
+	words := make(map[string]struct{})
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words[strings.ToLower(line)] = struct{}{}
+	}
+	return words, scanner.Err()
+}
@@ -0,0 +1,228 @@
+// Package registration содержит политику допустимых адресов email при регистрации
+// и смене email: домены, запрещенные администраторами, и одноразовые почтовые
+// сервисы (встроенный список, дополняемый загрузкой по URL)
+package registration
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"service_users/logger"
+	"service_users/models"
+	"service_users/repository"
+
+	"go.uber.org/zap"
+)
+
+//go:embed disposable_domains.txt
+var embeddedDisposable string
+
+// maxListSize максимальный размер загружаемого списка одноразовых доменов
+const maxListSize = 5 << 20
+
+var (
+	// ErrBannedDomain домен email запрещен администратором
+	ErrBannedDomain = errors.New("регистрация с этого домена email запрещена")
+	// ErrDisposableDomain адрес на одноразовом почтовом сервисе
+	ErrDisposableDomain = errors.New("адреса одноразовых почтовых сервисов не принимаются")
+)
+
+// domainPattern допустимое имя домена в нижнем регистре
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z0-9-]{2,63}$`)
+
+// Config параметры проверки одноразовых доменов
+type Config struct {
+	// BlockDisposable запрещать адреса одноразовых почтовых сервисов
+	BlockDisposable bool
+	// DisposableURL адрес списка одноразовых доменов (один домен в строке);
+	// пусто — используется только встроенный список
+	DisposableURL   string
+	RefreshInterval time.Duration
+}
+
+// Policy проверяет домен email. Запрещенные домены читаются из БД при каждой
+// проверке, поэтому изменения администратора сразу действуют на всех экземплярах
+type Policy struct {
+	repo   repository.EmailDomainRepository
+	config Config
+	client *http.Client
+
+	mutex       sync.RWMutex
+	disposable  map[string]struct{}
+	refreshedAt time.Time
+}
+
+// NewPolicy создает политику со встроенным списком одноразовых доменов
+func NewPolicy(repo repository.EmailDomainRepository, config Config) *Policy {
+	disposable, _ := parseList(strings.NewReader(embeddedDisposable))
+	return &Policy{
+		repo:       repo,
+		config:     config,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		disposable: disposable,
+	}
+}
+
+// NormalizeDomain приводит домен к нижнему регистру и проверяет его формат;
+// допускается ввод с ведущим "@"
+func NormalizeDomain(value string) (string, error) {
+	domain := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "@")
+	if !domainPattern.MatchString(domain) || len(domain) > 253 {
+		return "", fmt.Errorf("некорректный домен %q", value)
+	}
+	return domain, nil
+}
+
+// Check проверяет домен email. Возвращает ErrBannedDomain или ErrDisposableDomain,
+// если адрес не допускается; поддомены запрещенных доменов тоже не допускаются
+func (p *Policy) Check(email string) error {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil
+	}
+	domains := parentDomains(strings.ToLower(email[at+1:]))
+	if len(domains) == 0 {
+		return nil
+	}
+
+	banned, err := p.repo.FindBanned(domains)
+	if err != nil {
+		return err
+	}
+	if banned != nil {
+		return fmt.Errorf("%w: %s", ErrBannedDomain, banned.Domain)
+	}
+
+	if p.config.BlockDisposable {
+		p.mutex.RLock()
+		defer p.mutex.RUnlock()
+		for _, domain := range domains {
+			if _, ok := p.disposable[domain]; ok {
+				return fmt.Errorf("%w: %s", ErrDisposableDomain, domain)
+			}
+		}
+	}
+	return nil
+}
+
+// Run обновляет список одноразовых доменов сразу и затем с интервалом
+// RefreshInterval до отмены контекста. Без DisposableURL ничего не делает
+func (p *Policy) Run(ctx context.Context) {
+	if p.config.DisposableURL == "" || !p.config.BlockDisposable {
+		return
+	}
+
+	ticker := time.NewTicker(p.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if count, err := p.Refresh(ctx); err != nil {
+			logger.GetLogger().Error("Не удалось обновить список одноразовых почтовых доменов", zap.Error(err))
+		} else {
+			logger.GetLogger().Info("Список одноразовых почтовых доменов обновлен", zap.Int("count", count))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh загружает список по DisposableURL и объединяет его со встроенным.
+// При ошибке загрузки продолжает действовать предыдущий список
+func (p *Policy) Refresh(ctx context.Context) (int, error) {
+	if p.config.DisposableURL == "" {
+		return 0, fmt.Errorf("адрес списка одноразовых доменов не настроен")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.DisposableURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("некорректный адрес списка: %v", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка запроса списка: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("источник списка вернул статус %d", resp.StatusCode)
+	}
+
+	fetched, err := parseList(io.LimitReader(resp.Body, maxListSize))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения списка: %v", err)
+	}
+	// Пустой ответ скорее означает сбой источника, чем пустой список
+	if len(fetched) == 0 {
+		return 0, fmt.Errorf("загруженный список не содержит доменов")
+	}
+
+	embedded, _ := parseList(strings.NewReader(embeddedDisposable))
+	for domain := range embedded {
+		fetched[domain] = struct{}{}
+	}
+
+	p.mutex.Lock()
+	p.disposable = fetched
+	p.refreshedAt = time.Now().UTC()
+	p.mutex.Unlock()
+
+	return len(fetched), nil
+}
+
+// DisposableState возвращает состояние списка одноразовых доменов
+func (p *Policy) DisposableState() models.DisposableDomainsState {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	state := models.DisposableDomainsState{
+		Enabled: p.config.BlockDisposable,
+		Count:   len(p.disposable),
+	}
+	if !p.refreshedAt.IsZero() {
+		refreshedAt := p.refreshedAt
+		state.RefreshedAt = &refreshedAt
+	}
+	return state
+}
+
+// parentDomains возвращает домен и его родительские домены без домена верхнего уровня:
+// mail.example.co.uk -> mail.example.co.uk, example.co.uk, co.uk
+func parentDomains(domain string) []string {
+	domain = strings.TrimSuffix(domain, ".")
+	var domains []string
+	for strings.Contains(domain, ".") {
+		domains = append(domains, domain)
+		domain = domain[strings.Index(domain, ".")+1:]
+	}
+	return domains
+}
+
+// parseList разбирает список доменов: один домен в строке, строки с # — комментарии.
+// Некорректные строки пропускаются
+func parseList(reader io.Reader) (map[string]struct{}, error) {
+	domains := make(map[string]struct{})
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if domain, err := NormalizeDomain(line); err == nil {
+			domains[domain] = struct{}{}
+		}
+	}
+	return domains, scanner.Err()
+}
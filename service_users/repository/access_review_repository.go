@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// AccessReviewRepository интерфейс данных отчета о пересмотре доступа: время входа
+// пользователей и журнал аудита действий администраторов
+type AccessReviewRepository interface {
+	// RecordLogin сохраняет время успешного входа пользователя
+	RecordLogin(userID uuid.UUID) error
+	// RecordAdminAction сохраняет действие администратора в журнале аудита
+	RecordAdminAction(action *models.AdminAction) error
+	// Review возвращает пользователей с любой из ролей roles с числом их действий
+	// с момента since и не более actionsLimit последними действиями каждого
+	Review(roles []string, since time.Time, actionsLimit int) ([]models.AccessReviewUser, error)
+}
+
+// accessReviewRepository реализация AccessReviewRepository
+type accessReviewRepository struct {
+	db *sql.DB
+}
+
+// NewAccessReviewRepository создает новый экземпляр AccessReviewRepository
+func NewAccessReviewRepository(db *sql.DB) AccessReviewRepository {
+	return &accessReviewRepository{db: db}
+}
+
+// RecordLogin сохраняет время успешного входа пользователя
+func (r *accessReviewRepository) RecordLogin(userID uuid.UUID) error {
+	query := `
+		INSERT INTO user_logins (user_id, last_login_at)
+		VALUES ($1, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET last_login_at = EXCLUDED.last_login_at
+	`
+
+	if _, err := r.db.Exec(query, userID); err != nil {
+		return fmt.Errorf("ошибка сохранения времени входа: %v", err)
+	}
+	return nil
+}
+
+// RecordAdminAction сохраняет действие администратора; ID и CreatedAt заполняются из БД
+func (r *accessReviewRepository) RecordAdminAction(action *models.AdminAction) error {
+	query := `
+		INSERT INTO admin_audit_log (actor_id, action, target, details)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(query, action.ActorID, action.Action, action.Target, action.Details).Scan(&action.ID, &action.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения действия администратора: %v", err)
+	}
+	return nil
+}
+
+// Review собирает пользователей с привилегированными ролями и их действия за период.
+// Обезличенные (удаленные) пользователи в отчет не попадают
+func (r *accessReviewRepository) Review(roles []string, since time.Time, actionsLimit int) ([]models.AccessReviewUser, error) {
+	rows, err := r.db.Query(`
+		SELECT u.id, u.email, u.name, u.roles, u.created_at, l.last_login_at
+		FROM users u
+		LEFT JOIN user_logins l ON l.user_id = u.id
+		WHERE u.roles && $1 AND u.deleted_at IS NULL
+		ORDER BY u.email
+	`, pq.Array(roles))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения привилегированных пользователей: %v", err)
+	}
+	defer rows.Close()
+
+	users := []models.AccessReviewUser{}
+	index := make(map[uuid.UUID]int)
+	var ids []string
+	for rows.Next() {
+		user := models.AccessReviewUser{RecentActions: []models.AdminAction{}}
+		var userRoles pq.StringArray
+		var lastLogin sql.NullTime
+		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &userRoles, &user.CreatedAt, &lastLogin); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования пользователя: %v", err)
+		}
+		user.Roles = userRoles
+		if lastLogin.Valid {
+			user.LastLoginAt = &lastLogin.Time
+		}
+		index[user.ID] = len(users)
+		users = append(users, user)
+		ids = append(ids, user.ID.String())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка чтения пользователей: %v", err)
+	}
+	if len(users) == 0 {
+		return users, nil
+	}
+
+	if err := r.attachActions(users, index, ids, since, actionsLimit); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// attachActions добавляет пользователям число и последние действия за период одним запросом
+func (r *accessReviewRepository) attachActions(users []models.AccessReviewUser, index map[uuid.UUID]int, ids []string, since time.Time, limit int) error {
+	rows, err := r.db.Query(`
+		SELECT id, actor_id, action, target, details, created_at, total
+		FROM (
+			SELECT id, actor_id, action, target, details, created_at,
+			       ROW_NUMBER() OVER (PARTITION BY actor_id ORDER BY created_at DESC, id DESC) AS position,
+			       COUNT(*) OVER (PARTITION BY actor_id) AS total
+			FROM admin_audit_log
+			WHERE actor_id = ANY($1::uuid[]) AND created_at >= $2
+		) actions
+		WHERE position <= $3
+		ORDER BY actor_id, created_at DESC, id DESC
+	`, pq.Array(ids), since, limit)
+	if err != nil {
+		return fmt.Errorf("ошибка получения журнала аудита: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var action models.AdminAction
+		var actorID uuid.UUID
+		var total int
+		if err := rows.Scan(&action.ID, &actorID, &action.Action, &action.Target, &action.Details, &action.CreatedAt, &total); err != nil {
+			return fmt.Errorf("ошибка сканирования журнала аудита: %v", err)
+		}
+		action.ActorID = &actorID
+
+		user := &users[index[actorID]]
+		user.ActionsCount = total
+		user.RecentActions = append(user.RecentActions, action)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("ошибка чтения журнала аудита: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrAPIKeyLimit возвращается при создании ключа сверх допустимого числа активных ключей
+var ErrAPIKeyLimit = errors.New("достигнуто максимальное число API ключей")
+
+// APIKeyRepository персональные API ключи пользователей
+type APIKeyRepository interface {
+	// Create сохраняет ключ, если у пользователя меньше maxActive активных ключей,
+	// иначе возвращает ErrAPIKeyLimit
+	Create(key *models.APIKey, maxActive int) error
+	// ListForUser возвращает активные ключи пользователя, новые первыми
+	ListForUser(userID uuid.UUID) ([]models.APIKey, error)
+	// GetByHash получает ключ по хешу значения; sql.ErrNoRows, если ключа нет
+	GetByHash(keyHash string) (*models.APIKey, error)
+	// Revoke отзывает активный ключ пользователя; false, если такого ключа нет
+	Revoke(userID, id uuid.UUID) (bool, error)
+	// Touch отмечает время последнего использования ключа
+	Touch(id uuid.UUID) error
+}
+
+// apiKeyRepository реализация APIKeyRepository
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository создает новый экземпляр APIKeyRepository
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// Create сохраняет новый API ключ. Строка пользователя блокируется, чтобы параллельные
+// запросы не превысили ограничение числа ключей
+func (r *apiKeyRepository) Create(key *models.APIKey, maxActive int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	var active int
+	err = tx.QueryRow(`
+		SELECT COUNT(k.id)
+		FROM (SELECT id FROM users WHERE id = $1 FOR UPDATE) u
+		LEFT JOIN api_keys k ON k.user_id = u.id
+			AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())
+	`, key.UserID).Scan(&active)
+	if err != nil {
+		return fmt.Errorf("ошибка подсчета API ключей: %v", err)
+	}
+	if active >= maxActive {
+		return ErrAPIKeyLimit
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO api_keys (id, user_id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`, key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes), key.ExpiresAt).Scan(&key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания API ключа: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return nil
+}
+
+// ListForUser возвращает активные API ключи пользователя
+func (r *apiKeyRepository) ListForUser(userID uuid.UUID) ([]models.APIKey, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked_at
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения API ключей: %v", err)
+	}
+	defer rows.Close()
+
+	keys := make([]models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования API ключа: %v", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return keys, nil
+}
+
+// GetByHash получает API ключ по хешу значения
+func (r *apiKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	row := r.db.QueryRow(`
+		SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, created_at, revoked_at
+		FROM api_keys
+		WHERE key_hash = $1
+	`, keyHash)
+
+	key, err := scanAPIKey(row)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения API ключа: %v", err)
+	}
+	return key, nil
+}
+
+// Revoke отзывает активный API ключ пользователя
+func (r *apiKeyRepository) Revoke(userID, id uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE api_keys SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отзыва API ключа: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения количества обновленных строк: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Touch отмечает время последнего использования API ключа
+func (r *apiKeyRepository) Touch(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления API ключа: %v", err)
+	}
+	return nil
+}
+
+// scanAPIKey читает строку api_keys
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*models.APIKey, error) {
+	var key models.APIKey
+	var scopes pq.StringArray
+	err := row.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash, &scopes,
+		&key.ExpiresAt, &key.LastUsedAt, &key.CreatedAt, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	key.Scopes = []string(scopes)
+	return &key, nil
+}
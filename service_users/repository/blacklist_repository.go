@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+)
+
+// BlacklistRepository черный список покупателей: пользователи, домены email и IP адреса.
+// Тот же список читает service_orders при проверке заказов перед созданием
+type BlacklistRepository interface {
+	// List возвращает записи вида kind (пустой — всех видов), новые первыми
+	List(kind models.BlacklistKind) ([]models.BlacklistEntry, error)
+	// Add добавляет запись или заменяет действие и причину, если значение уже в списке
+	// (тогда сохраняются прежние ID, автор и время добавления)
+	Add(entry *models.BlacklistEntry) (*models.BlacklistEntry, error)
+	// Remove удаляет запись; возвращает false, если записи не было
+	Remove(id uuid.UUID) (bool, error)
+	// Match возвращает запись, совпавшую с пользователем, доменом его email (с учетом
+	// родительских доменов) или IP адресом (пустой не проверяется), или nil. Записи block
+	// имеют приоритет над flag
+	Match(userID uuid.UUID, email, ip string) (*models.BlacklistEntry, error)
+}
+
+// blacklistRepository реализация BlacklistRepository
+type blacklistRepository struct {
+	db *sql.DB
+}
+
+// NewBlacklistRepository создает новый экземпляр BlacklistRepository
+func NewBlacklistRepository(db *sql.DB) BlacklistRepository {
+	return &blacklistRepository{db: db}
+}
+
+// List возвращает записи черного списка
+func (r *blacklistRepository) List(kind models.BlacklistKind) ([]models.BlacklistEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT id, kind, value, action, reason, created_by, created_at
+		FROM blacklist_entries
+		WHERE $1 = '' OR kind = $1
+		ORDER BY created_at DESC, id
+	`, string(kind))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения черного списка: %v", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.BlacklistEntry, 0)
+	for rows.Next() {
+		entry, err := scanBlacklistEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования записи черного списка: %v", err)
+		}
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return entries, nil
+}
+
+// Add добавляет запись в черный список
+func (r *blacklistRepository) Add(entry *models.BlacklistEntry) (*models.BlacklistEntry, error) {
+	row := r.db.QueryRow(`
+		INSERT INTO blacklist_entries (id, kind, value, action, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (kind, value) DO UPDATE SET action = EXCLUDED.action, reason = EXCLUDED.reason
+		RETURNING id, kind, value, action, reason, created_by, created_at
+	`, entry.ID, string(entry.Kind), entry.Value, string(entry.Action), entry.Reason, entry.CreatedBy)
+
+	saved, err := scanBlacklistEntry(row)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка добавления записи черного списка: %v", err)
+	}
+	return saved, nil
+}
+
+// Remove удаляет запись черного списка
+func (r *blacklistRepository) Remove(id uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM blacklist_entries WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("ошибка удаления записи черного списка: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения количества удаленных строк: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Match ищет запись черного списка, совпавшую с пользователем, email или IP адресом
+func (r *blacklistRepository) Match(userID uuid.UUID, email, ip string) (*models.BlacklistEntry, error) {
+	row := r.db.QueryRow(`
+		SELECT id, kind, value, action, reason, created_by, created_at
+		FROM blacklist_entries
+		WHERE (kind = 'user' AND value = $1::text)
+		   OR (kind = 'email_domain' AND $2 <> '' AND (lower(split_part($2, '@', 2)) = value
+		       OR lower(split_part($2, '@', 2)) LIKE '%.' || value))
+		   OR (kind = 'ip' AND $3 <> '' AND $3::inet <<= value::inet)
+		ORDER BY action = 'block' DESC, created_at
+		LIMIT 1
+	`, userID.String(), email, ip)
+
+	entry, err := scanBlacklistEntry(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки черного списка: %v", err)
+	}
+	return entry, nil
+}
+
+// scanBlacklistEntry читает строку blacklist_entries
+func scanBlacklistEntry(row interface{ Scan(...interface{}) error }) (*models.BlacklistEntry, error) {
+	var entry models.BlacklistEntry
+	var createdBy uuid.NullUUID
+	if err := row.Scan(&entry.ID, &entry.Kind, &entry.Value, &entry.Action, &entry.Reason, &createdBy, &entry.CreatedAt); err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		entry.CreatedBy = &createdBy.UUID
+	}
+	return &entry, nil
+}
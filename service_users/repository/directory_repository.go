@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// DirectoryRepository хранит связи пользователей с учетными записями внешних каталогов
+type DirectoryRepository interface {
+	// ListLinked возвращает пользователей, связанных с источником, по внешнему ID
+	ListLinked(source string) (map[string]uuid.UUID, error)
+	// Link связывает пользователя с учетной записью каталога
+	Link(userID uuid.UUID, source, externalID string) error
+}
+
+// directoryRepository реализация DirectoryRepository
+type directoryRepository struct {
+	db *sql.DB
+}
+
+// NewDirectoryRepository создает новый экземпляр DirectoryRepository
+func NewDirectoryRepository(db *sql.DB) DirectoryRepository {
+	return &directoryRepository{db: db}
+}
+
+// ListLinked возвращает пользователей, связанных с источником, по внешнему ID
+func (r *directoryRepository) ListLinked(source string) (map[string]uuid.UUID, error) {
+	rows, err := r.db.Query(`SELECT external_id, id FROM users WHERE directory_source = $1`, source)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения связанных пользователей: %v", err)
+	}
+	defer rows.Close()
+
+	linked := make(map[string]uuid.UUID)
+	for rows.Next() {
+		var externalID string
+		var id uuid.UUID
+		if err := rows.Scan(&externalID, &id); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования связанного пользователя: %v", err)
+		}
+		linked[externalID] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return linked, nil
+}
+
+// Link связывает пользователя с учетной записью каталога
+func (r *directoryRepository) Link(userID uuid.UUID, source, externalID string) error {
+	result, err := r.db.Exec(`
+		UPDATE users
+		SET directory_source = $2, external_id = $3, updated_at = NOW()
+		WHERE id = $1
+	`, userID, source, externalID)
+	if err != nil {
+		return fmt.Errorf("ошибка связывания пользователя с каталогом: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк: %v", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("пользователь с ID %s не найден", userID)
+	}
+	return nil
+}
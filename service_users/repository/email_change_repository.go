@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrEmailChangeTokenInvalid возвращается, если токен смены email не найден, истек,
+// уже использован или email пользователя изменился после запроса
+var ErrEmailChangeTokenInvalid = errors.New("токен подтверждения email недействителен")
+
+// ErrEmailTaken возвращается, если новый email занял другой пользователь до подтверждения
+var ErrEmailTaken = errors.New("email уже используется")
+
+// EmailChangeRepository интерфейс для работы с токенами подтверждения смены email
+type EmailChangeRepository interface {
+	// Create сохраняет новый токен; предыдущие токены пользователя перестают действовать
+	Create(token *models.EmailChangeToken) error
+	// Confirm погашает токен и устанавливает пользователю новый email в одной транзакции.
+	// Возвращает погашенный токен; ErrEmailChangeTokenInvalid или ErrEmailTaken
+	Confirm(tokenHash string) (*models.EmailChangeToken, error)
+}
+
+// emailChangeRepository реализация EmailChangeRepository
+type emailChangeRepository struct {
+	db *sql.DB
+}
+
+// NewEmailChangeRepository создает новый экземпляр EmailChangeRepository
+func NewEmailChangeRepository(db *sql.DB) EmailChangeRepository {
+	return &emailChangeRepository{db: db}
+}
+
+// Create сохраняет новый токен смены email, удаляя предыдущие токены пользователя:
+// действует только ссылка из последнего письма
+func (r *emailChangeRepository) Create(token *models.EmailChangeToken) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := cancelEmailChanges(tx, token.UserID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO email_change_tokens (id, user_id, old_email, new_email, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, token.ID, token.UserID, token.OldEmail, token.NewEmail, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания токена смены email: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return nil
+}
+
+// Confirm устанавливает новый email по токену. Токен погашается условным UPDATE, поэтому
+// при параллельных запросах с одним токеном email меняет только один из них. Email
+// меняется, только если он не изменился с момента запроса, а пользователь не удален
+func (r *emailChangeRepository) Confirm(tokenHash string) (*models.EmailChangeToken, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	token := &models.EmailChangeToken{TokenHash: tokenHash}
+	err = tx.QueryRow(`
+		UPDATE email_change_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, old_email, new_email, expires_at, used_at, created_at
+	`, tokenHash).Scan(&token.ID, &token.UserID, &token.OldEmail, &token.NewEmail, &token.ExpiresAt, &token.UsedAt, &token.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrEmailChangeTokenInvalid
+		}
+		return nil, fmt.Errorf("ошибка погашения токена смены email: %v", err)
+	}
+
+	result, err := tx.Exec(`
+		UPDATE users SET email = $3, updated_at = NOW()
+		WHERE id = $1 AND email = $2 AND deleted_at IS NULL
+	`, token.UserID, token.OldEmail, token.NewEmail)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, ErrEmailTaken
+		}
+		return nil, fmt.Errorf("ошибка обновления email: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения количества обновленных строк: %v", err)
+	}
+	if rowsAffected == 0 {
+		return nil, ErrEmailChangeTokenInvalid
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return token, nil
+}
+
+// cancelEmailChanges удаляет неподтвержденные токены смены email пользователя в транзакции tx
+func cancelEmailChanges(tx *sql.Tx, userID uuid.UUID) error {
+	if _, err := tx.Exec(`DELETE FROM email_change_tokens WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("ошибка удаления токенов смены email: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// EmailDomainRepository хранит домены email, запрещенные администраторами
+type EmailDomainRepository interface {
+	List() ([]models.BannedEmailDomain, error)
+	// Add добавляет домен или обновляет причину, если домен уже запрещен
+	Add(domain, reason string, createdBy uuid.UUID) (*models.BannedEmailDomain, error)
+	// Remove удаляет домен; возвращает false, если домена не было в списке
+	Remove(domain string) (bool, error)
+	// FindBanned возвращает первый запрещенный домен из domains или nil
+	FindBanned(domains []string) (*models.BannedEmailDomain, error)
+}
+
+// emailDomainRepository реализация EmailDomainRepository
+type emailDomainRepository struct {
+	db *sql.DB
+}
+
+// NewEmailDomainRepository создает новый экземпляр EmailDomainRepository
+func NewEmailDomainRepository(db *sql.DB) EmailDomainRepository {
+	return &emailDomainRepository{db: db}
+}
+
+// List возвращает запрещенные домены в алфавитном порядке
+func (r *emailDomainRepository) List() ([]models.BannedEmailDomain, error) {
+	rows, err := r.db.Query(`SELECT domain, reason, created_by, created_at FROM banned_email_domains ORDER BY domain`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения запрещенных доменов: %v", err)
+	}
+	defer rows.Close()
+
+	domains := make([]models.BannedEmailDomain, 0)
+	for rows.Next() {
+		domain, err := scanBannedDomain(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования запрещенного домена: %v", err)
+		}
+		domains = append(domains, *domain)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return domains, nil
+}
+
+// Add добавляет домен или обновляет причину, если домен уже запрещен
+func (r *emailDomainRepository) Add(domain, reason string, createdBy uuid.UUID) (*models.BannedEmailDomain, error) {
+	row := r.db.QueryRow(`
+		INSERT INTO banned_email_domains (domain, reason, created_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (domain) DO UPDATE SET reason = EXCLUDED.reason
+		RETURNING domain, reason, created_by, created_at
+	`, domain, reason, createdBy)
+
+	banned, err := scanBannedDomain(row)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка добавления запрещенного домена: %v", err)
+	}
+	return banned, nil
+}
+
+// Remove удаляет домен; возвращает false, если домена не было в списке
+func (r *emailDomainRepository) Remove(domain string) (bool, error) {
+	result, err := r.db.Exec(`DELETE FROM banned_email_domains WHERE domain = $1`, domain)
+	if err != nil {
+		return false, fmt.Errorf("ошибка удаления запрещенного домена: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения количества удаленных строк: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// FindBanned возвращает первый запрещенный домен из domains или nil
+func (r *emailDomainRepository) FindBanned(domains []string) (*models.BannedEmailDomain, error) {
+	row := r.db.QueryRow(`
+		SELECT domain, reason, created_by, created_at
+		FROM banned_email_domains
+		WHERE domain = ANY($1)
+		ORDER BY length(domain) DESC
+		LIMIT 1
+	`, pq.Array(domains))
+
+	banned, err := scanBannedDomain(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки запрещенных доменов: %v", err)
+	}
+	return banned, nil
+}
+
+// scanBannedDomain читает строку banned_email_domains
+func scanBannedDomain(row interface{ Scan(...interface{}) error }) (*models.BannedEmailDomain, error) {
+	var domain models.BannedEmailDomain
+	var createdBy uuid.NullUUID
+	if err := row.Scan(&domain.Domain, &domain.Reason, &createdBy, &domain.CreatedAt); err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		domain.CreatedBy = &createdBy.UUID
+	}
+	return &domain, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"service_users/models"
+
+	"pkg/lock"
+
+	"github.com/google/uuid"
+)
+
+// ErrExportInProgress возвращается, если у пользователя уже формируется другая выгрузка
+var ErrExportInProgress = errors.New("выгрузка пользователя уже формируется")
+
+// ExportRepository интерфейс для работы с выгрузками: ограничение одновременных
+// выгрузок пользователя и хранение сформированных файлов до истечения срока
+type ExportRepository interface {
+	// Lock захватывает блокировку выгрузок пользователя до вызова unlock.
+	// Возвращает ErrExportInProgress, если блокировку держит другой запрос
+	Lock(userID uuid.UUID) (unlock func(), err error)
+	Create(artifact *models.ExportArtifact) error
+	// Get возвращает выгрузку пользователя, не истекшую к now; found == false, если ее нет
+	Get(id, userID uuid.UUID, now time.Time) (artifact *models.ExportArtifact, found bool, err error)
+	// DeleteExpired удаляет выгрузки, истекшие к now, и возвращает их число
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// exportRepository реализация ExportRepository
+type exportRepository struct {
+	db    *sql.DB
+	locks *lock.Postgres
+}
+
+// NewExportRepository создает новый экземпляр ExportRepository. Блокировки выгрузок
+// общие для service_users и service_orders: пользователь формирует не более одной
+// выгрузки на оба сервиса
+func NewExportRepository(db *sql.DB) ExportRepository {
+	return &exportRepository{db: db, locks: lock.NewPostgres(db, lock.NamespaceExports)}
+}
+
+// Lock захватывает рекомендательную блокировку PostgreSQL с ключом user_id. Она снимается
+// и при обрыве соединения, если сервис завершится во время выгрузки
+func (r *exportRepository) Lock(userID uuid.UUID) (func(), error) {
+	lease, err := r.locks.TryAcquire(context.Background(), userID.String())
+	if errors.Is(err, lock.ErrNotAcquired) {
+		return nil, ErrExportInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка блокировки выгрузки: %v", err)
+	}
+	return func() { lease.Release() }, nil
+}
+
+// Create сохраняет выгрузку
+func (r *exportRepository) Create(artifact *models.ExportArtifact) error {
+	query := `
+		INSERT INTO export_artifacts (id, user_id, kind, filename, content_type, content, size, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.db.Exec(query, artifact.ID, artifact.UserID, artifact.Kind, artifact.Filename, artifact.ContentType,
+		artifact.Content, len(artifact.Content), artifact.CreatedAt, artifact.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения выгрузки: %v", err)
+	}
+	return nil
+}
+
+// Get возвращает не истекшую выгрузку пользователя по ID
+func (r *exportRepository) Get(id, userID uuid.UUID, now time.Time) (*models.ExportArtifact, bool, error) {
+	query := `
+		SELECT id, user_id, kind, filename, content_type, content, created_at, expires_at
+		FROM export_artifacts
+		WHERE id = $1 AND user_id = $2 AND expires_at > $3
+	`
+
+	artifact := &models.ExportArtifact{}
+	err := r.db.QueryRow(query, id, userID, now).Scan(&artifact.ID, &artifact.UserID, &artifact.Kind, &artifact.Filename,
+		&artifact.ContentType, &artifact.Content, &artifact.CreatedAt, &artifact.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка получения выгрузки: %v", err)
+	}
+	return artifact, true, nil
+}
+
+// DeleteExpired удаляет выгрузки всех пользователей, истекшие к now
+func (r *exportRepository) DeleteExpired(now time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM export_artifacts WHERE expires_at <= $1", now)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления истекших выгрузок: %v", err)
+	}
+	return result.RowsAffected()
+}
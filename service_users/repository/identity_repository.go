@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrIdentityConflict возвращается, если пользователь уже связан с другой учетной записью провайдера
+var ErrIdentityConflict = errors.New("пользователь уже связан с другой учетной записью провайдера")
+
+// IdentityRepository хранит связи пользователей с учетными записями провайдеров OAuth2
+type IdentityRepository interface {
+	// GetUserID возвращает пользователя, связанного с учетной записью провайдера; uuid.Nil, если связи нет
+	GetUserID(provider, subject string) (uuid.UUID, error)
+	// Link связывает пользователя с учетной записью провайдера; ErrIdentityConflict,
+	// если пользователь уже связан с другой учетной записью этого провайдера
+	Link(identity *models.UserIdentity) error
+}
+
+// identityRepository реализация IdentityRepository
+type identityRepository struct {
+	db *sql.DB
+}
+
+// NewIdentityRepository создает новый экземпляр IdentityRepository
+func NewIdentityRepository(db *sql.DB) IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+// GetUserID возвращает пользователя, связанного с учетной записью провайдера
+func (r *identityRepository) GetUserID(provider, subject string) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := r.db.QueryRow(`
+		SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2
+	`, provider, subject).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, nil
+		}
+		return uuid.Nil, fmt.Errorf("ошибка получения учетной записи провайдера: %v", err)
+	}
+	return userID, nil
+}
+
+// Link связывает пользователя с учетной записью провайдера. Повторное связывание той же
+// учетной записи (параллельный вход) не является ошибкой
+func (r *identityRepository) Link(identity *models.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (provider, subject, user_id, email, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (provider, subject) DO NOTHING
+	`
+
+	_, err := r.db.Exec(query, identity.Provider, identity.Subject, identity.UserID, identity.Email, identity.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrIdentityConflict
+		}
+		return fmt.Errorf("ошибка связывания учетной записи провайдера: %v", err)
+	}
+	return nil
+}
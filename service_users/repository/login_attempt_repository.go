@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+)
+
+// LoginAttemptRepository интерфейс для учета попыток входа. По результатам попыток
+// service_orders считает долю неудачных входов, а попытки известных пользователей
+// образуют их историю входов
+type LoginAttemptRepository interface {
+	Record(attempt *models.LoginAttempt) error
+	// ListForUser возвращает страницу попыток входа пользователя, новые первыми, и их общее число
+	ListForUser(userID uuid.UUID, limit, offset int) ([]models.LoginAttempt, int, error)
+	DeleteBefore(before time.Time) (int64, error)
+}
+
+// loginAttemptRepository реализация LoginAttemptRepository
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+// NewLoginAttemptRepository создает новый экземпляр LoginAttemptRepository
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+// Record сохраняет попытку входа; время попытки назначает БД
+func (r *loginAttemptRepository) Record(attempt *models.LoginAttempt) error {
+	query := `
+		INSERT INTO login_attempts (user_id, method, provider, success, ip_address, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, attempted_at
+	`
+	err := r.db.QueryRow(query, attempt.UserID, attempt.Method, attempt.Provider, attempt.Success,
+		attempt.IPAddress, attempt.UserAgent).Scan(&attempt.ID, &attempt.AttemptedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения попытки входа: %v", err)
+	}
+	return nil
+}
+
+// ListForUser возвращает страницу попыток входа пользователя, новые первыми, и их общее число
+func (r *loginAttemptRepository) ListForUser(userID uuid.UUID, limit, offset int) ([]models.LoginAttempt, int, error) {
+	var total int
+	if err := r.db.QueryRow(`SELECT COUNT(*) FROM login_attempts WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("ошибка подсчета попыток входа: %v", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, user_id, method, provider, success, ip_address, user_agent, attempted_at
+		FROM login_attempts
+		WHERE user_id = $1
+		ORDER BY attempted_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("ошибка получения попыток входа: %v", err)
+	}
+	defer rows.Close()
+
+	attempts := []models.LoginAttempt{}
+	for rows.Next() {
+		var attempt models.LoginAttempt
+		var attemptUserID uuid.NullUUID
+		if err := rows.Scan(&attempt.ID, &attemptUserID, &attempt.Method, &attempt.Provider, &attempt.Success,
+			&attempt.IPAddress, &attempt.UserAgent, &attempt.AttemptedAt); err != nil {
+			return nil, 0, fmt.Errorf("ошибка сканирования попытки входа: %v", err)
+		}
+		if attemptUserID.Valid {
+			attempt.UserID = &attemptUserID.UUID
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return attempts, total, nil
+}
+
+// DeleteBefore удаляет попытки входа старше before
+func (r *loginAttemptRepository) DeleteBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`DELETE FROM login_attempts WHERE attempted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления попыток входа: %v", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"service_users/lockout"
+	"service_users/models"
+)
+
+// LoginLockoutRepository интерфейс для счетчиков неудачных входов и блокировок входа.
+// Состояние хранится в БД, поэтому блокировка действует на всех экземплярах сервиса
+type LoginLockoutRepository interface {
+	// Get возвращает состояние области; без неудачных входов — пустое состояние
+	Get(scope, subject string) (*models.LoginLockout, error)
+	// RecordFailure учитывает неудачный вход по policy. locked == true, если вход
+	// заблокирован этим вызовом
+	RecordFailure(scope, subject string, policy lockout.Policy, now time.Time) (state *models.LoginLockout, locked bool, err error)
+	// Reset удаляет счетчик и блокировку области
+	Reset(scope, subject string) error
+	// DeleteStale удаляет снятые блокировки без неудачных входов после before
+	DeleteStale(before time.Time) (int64, error)
+}
+
+// loginLockoutRepository реализация LoginLockoutRepository
+type loginLockoutRepository struct {
+	db *sql.DB
+}
+
+// NewLoginLockoutRepository создает новый экземпляр LoginLockoutRepository
+func NewLoginLockoutRepository(db *sql.DB) LoginLockoutRepository {
+	return &loginLockoutRepository{db: db}
+}
+
+// Get возвращает счетчик неудачных входов и блокировку области
+func (r *loginLockoutRepository) Get(scope, subject string) (*models.LoginLockout, error) {
+	query := `
+		SELECT failures, lock_count, locked_until, last_failure_at
+		FROM login_lockouts
+		WHERE scope = $1 AND subject = $2
+	`
+
+	state := &models.LoginLockout{Scope: scope, Subject: subject}
+	err := r.db.QueryRow(query, scope, subject).Scan(&state.Failures, &state.LockCount, &state.LockedUntil, &state.LastFailureAt)
+	if err == sql.ErrNoRows {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения блокировки входа: %v", err)
+	}
+	return state, nil
+}
+
+// RecordFailure учитывает неудачный вход в транзакции: строка области блокируется,
+// поэтому параллельные неудачные входы не теряются
+func (r *loginLockoutRepository) RecordFailure(scope, subject string, policy lockout.Policy, now time.Time) (*models.LoginLockout, bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	insertQuery := `
+		INSERT INTO login_lockouts (scope, subject)
+		VALUES ($1, $2)
+		ON CONFLICT (scope, subject) DO NOTHING
+	`
+	if _, err := tx.Exec(insertQuery, scope, subject); err != nil {
+		return nil, false, fmt.Errorf("ошибка сохранения блокировки входа: %v", err)
+	}
+
+	selectQuery := `
+		SELECT failures, lock_count, locked_until, last_failure_at
+		FROM login_lockouts
+		WHERE scope = $1 AND subject = $2
+		FOR UPDATE
+	`
+	state := &models.LoginLockout{Scope: scope, Subject: subject}
+	if err := tx.QueryRow(selectQuery, scope, subject).Scan(&state.Failures, &state.LockCount, &state.LockedUntil, &state.LastFailureAt); err != nil {
+		return nil, false, fmt.Errorf("ошибка получения блокировки входа: %v", err)
+	}
+
+	locked := policy.RegisterFailure(state, now)
+
+	updateQuery := `
+		UPDATE login_lockouts
+		SET failures = $3, lock_count = $4, locked_until = $5, last_failure_at = $6
+		WHERE scope = $1 AND subject = $2
+	`
+	if _, err := tx.Exec(updateQuery, scope, subject, state.Failures, state.LockCount, state.LockedUntil, state.LastFailureAt); err != nil {
+		return nil, false, fmt.Errorf("ошибка сохранения блокировки входа: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return state, locked, nil
+}
+
+// Reset удаляет счетчик и блокировку области
+func (r *loginLockoutRepository) Reset(scope, subject string) error {
+	if _, err := r.db.Exec(`DELETE FROM login_lockouts WHERE scope = $1 AND subject = $2`, scope, subject); err != nil {
+		return fmt.Errorf("ошибка снятия блокировки входа: %v", err)
+	}
+	return nil
+}
+
+// DeleteStale удаляет области, последний неудачный вход которых раньше before
+// и блокировка которых уже снята
+func (r *loginLockoutRepository) DeleteStale(before time.Time) (int64, error) {
+	query := `
+		DELETE FROM login_lockouts
+		WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < $1)
+	`
+	result, err := r.db.Exec(query, before)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка удаления устаревших блокировок входа: %v", err)
+	}
+	return result.RowsAffected()
+}
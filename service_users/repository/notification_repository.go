@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+)
+
+// NotificationRepository интерфейс для работы с настройками уведомлений
+type NotificationRepository interface {
+	GetByUserID(userID uuid.UUID) (*models.NotificationPreferences, error)
+	Upsert(prefs *models.NotificationPreferences) error
+}
+
+// notificationRepository реализация NotificationRepository
+type notificationRepository struct {
+	db *sql.DB
+}
+
+// NewNotificationRepository создает новый экземпляр NotificationRepository
+func NewNotificationRepository(db *sql.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+// GetByUserID получает настройки уведомлений пользователя; если настройки не сохранялись,
+// возвращаются значения по умолчанию
+func (r *notificationRepository) GetByUserID(userID uuid.UUID) (*models.NotificationPreferences, error) {
+	query := `
+		SELECT user_id, email_enabled, sms_enabled, telegram_enabled,
+		       order_created, order_status_changed, marketing, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+	`
+
+	prefs := &models.NotificationPreferences{}
+	err := r.db.QueryRow(query, userID).Scan(
+		&prefs.UserID,
+		&prefs.Channels.Email,
+		&prefs.Channels.SMS,
+		&prefs.Channels.Telegram,
+		&prefs.Events.OrderCreated,
+		&prefs.Events.OrderStatusChanged,
+		&prefs.Events.Marketing,
+		&prefs.UpdatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.DefaultNotificationPreferences(userID), nil
+		}
+		return nil, fmt.Errorf("ошибка получения настроек уведомлений: %v", err)
+	}
+	return prefs, nil
+}
+
+// Upsert сохраняет настройки уведомлений пользователя
+func (r *notificationRepository) Upsert(prefs *models.NotificationPreferences) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, email_enabled, sms_enabled, telegram_enabled,
+		                                      order_created, order_status_changed, marketing, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET
+			email_enabled = EXCLUDED.email_enabled,
+			sms_enabled = EXCLUDED.sms_enabled,
+			telegram_enabled = EXCLUDED.telegram_enabled,
+			order_created = EXCLUDED.order_created,
+			order_status_changed = EXCLUDED.order_status_changed,
+			marketing = EXCLUDED.marketing,
+			updated_at = NOW()
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(query,
+		prefs.UserID,
+		prefs.Channels.Email,
+		prefs.Channels.SMS,
+		prefs.Channels.Telegram,
+		prefs.Events.OrderCreated,
+		prefs.Events.OrderStatusChanged,
+		prefs.Events.Marketing,
+	).Scan(&prefs.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения настроек уведомлений: %v", err)
+	}
+	return nil
+}
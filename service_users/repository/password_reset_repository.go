@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrPasswordResetTokenInvalid возвращается, если токен сброса не найден, истек или уже использован
+var ErrPasswordResetTokenInvalid = errors.New("токен сброса пароля недействителен")
+
+// PasswordResetRepository интерфейс для работы с токенами сброса пароля
+type PasswordResetRepository interface {
+	// Create сохраняет новый токен; предыдущие токены пользователя перестают действовать
+	Create(token *models.PasswordResetToken) error
+	// Reset погашает токен, устанавливает новый хеш пароля, отзывает все refresh токены
+	// пользователя и отменяет неподтвержденную смену email в одной транзакции. Возвращает
+	// пользователя и его новую эпоху ролей
+	Reset(tokenHash, passwordHash string) (uuid.UUID, int64, error)
+	// ChangePassword устанавливает новый хеш пароля пользователя, отзывает все его refresh
+	// токены и отменяет неподтвержденную смену email в одной транзакции. Возвращает новую
+	// эпоху ролей; sql.ErrNoRows, если пользователь не найден или удален
+	ChangePassword(userID uuid.UUID, passwordHash string) (int64, error)
+}
+
+// passwordResetRepository реализация PasswordResetRepository
+type passwordResetRepository struct {
+	db *sql.DB
+}
+
+// NewPasswordResetRepository создает новый экземпляр PasswordResetRepository
+func NewPasswordResetRepository(db *sql.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+// Create сохраняет новый токен сброса пароля, удаляя предыдущие токены пользователя:
+// действует только ссылка из последнего письма
+func (r *passwordResetRepository) Create(token *models.PasswordResetToken) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM password_reset_tokens WHERE user_id = $1`, token.UserID); err != nil {
+		return fmt.Errorf("ошибка удаления предыдущих токенов сброса пароля: %v", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, token.ID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания токена сброса пароля: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return nil
+}
+
+// Reset устанавливает новый пароль по токену сброса. Токен погашается условным UPDATE,
+// поэтому при параллельных запросах с одним токеном пароль меняет только один из них.
+// Увеличение эпохи ролей отзывает ранее выданные access токены, как при изменении ролей
+func (r *passwordResetRepository) Reset(tokenHash, passwordHash string) (uuid.UUID, int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return uuid.Nil, 0, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	err = tx.QueryRow(`
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, tokenHash).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, 0, ErrPasswordResetTokenInvalid
+		}
+		return uuid.Nil, 0, fmt.Errorf("ошибка погашения токена сброса пароля: %v", err)
+	}
+
+	// Удаленные пользователи не могут восстановить доступ
+	rolesEpoch, err := setPassword(tx, userID, passwordHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, 0, ErrPasswordResetTokenInvalid
+		}
+		return uuid.Nil, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, 0, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return userID, rolesEpoch, nil
+}
+
+// ChangePassword устанавливает новый пароль пользователя, завершая все его сессии так же,
+// как сброс пароля
+func (r *passwordResetRepository) ChangePassword(userID uuid.UUID, passwordHash string) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	rolesEpoch, err := setPassword(tx, userID, passwordHash)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return rolesEpoch, nil
+}
+
+// setPassword обновляет хеш пароля неудаленного пользователя, увеличивает эпоху ролей,
+// отзывает refresh токены и отменяет неподтвержденную смену email в транзакции tx.
+// Возвращает sql.ErrNoRows, если пользователя нет
+func setPassword(tx *sql.Tx, userID uuid.UUID, passwordHash string) (int64, error) {
+	var rolesEpoch int64
+	err := tx.QueryRow(`
+		UPDATE users
+		SET password_hash = $2, roles_epoch = roles_epoch + 1, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING roles_epoch
+	`, userID, passwordHash).Scan(&rolesEpoch)
+	if err == sql.ErrNoRows {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка обновления пароля: %v", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID); err != nil {
+		return 0, fmt.Errorf("ошибка отзыва refresh токенов: %v", err)
+	}
+
+	// Смена email, запрошенная до смены пароля, могла быть запрошена тем, кто завладел сессией
+	if err := cancelEmailChanges(tx, userID); err != nil {
+		return 0, err
+	}
+	return rolesEpoch, nil
+}
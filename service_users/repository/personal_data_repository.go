@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+)
+
+// PersonalDataRepository интерфейс для выгрузки персональных данных пользователя
+type PersonalDataRepository interface {
+	// Export собирает персональные данные пользователя одним снимком БД
+	Export(userID uuid.UUID) (*models.PersonalData, error)
+}
+
+// personalDataRepository реализация PersonalDataRepository
+type personalDataRepository struct {
+	db *sql.DB
+}
+
+// NewPersonalDataRepository создает новый экземпляр PersonalDataRepository
+func NewPersonalDataRepository(db *sql.DB) PersonalDataRepository {
+	return &personalDataRepository{db: db}
+}
+
+// Export читает профиль, связанные учетные записи провайдеров, настройки уведомлений и
+// историю входов в одной транзакции REPEATABLE READ, чтобы части выгрузки не расходились
+func (r *personalDataRepository) Export(userID uuid.UUID) (*models.PersonalData, error) {
+	tx, err := r.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	data := &models.PersonalData{
+		Identities: []models.UserIdentity{},
+		LoginHistory: models.LoginHistory{
+			Attempts: []models.LoginAttempt{},
+			Sessions: []models.LoginSession{},
+		},
+	}
+
+	profile := &data.Profile
+	err = tx.QueryRow(`
+		SELECT id, email, name, roles, timezone, created_at, updated_at, deactivated_at, NOW()
+		FROM users
+		WHERE id = $1 AND deleted_at IS NULL
+	`, userID).Scan(
+		&profile.ID,
+		&profile.Email,
+		&profile.Name,
+		&profile.Roles,
+		&profile.Timezone,
+		&profile.CreatedAt,
+		&profile.UpdatedAt,
+		&profile.DeactivatedAt,
+		&data.ExportedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("пользователь с ID %s не найден", userID)
+		}
+		return nil, fmt.Errorf("ошибка получения пользователя: %v", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT provider, subject, user_id, email, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения связанных учетных записей: %v", err)
+	}
+	for rows.Next() {
+		var identity models.UserIdentity
+		if err := rows.Scan(&identity.Provider, &identity.Subject, &identity.UserID, &identity.Email, &identity.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка сканирования связанной учетной записи: %v", err)
+		}
+		data.Identities = append(data.Identities, identity)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+
+	prefs := &models.NotificationPreferences{}
+	err = tx.QueryRow(`
+		SELECT user_id, email_enabled, sms_enabled, telegram_enabled,
+		       order_created, order_status_changed, marketing, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+	`, userID).Scan(
+		&prefs.UserID,
+		&prefs.Channels.Email,
+		&prefs.Channels.SMS,
+		&prefs.Channels.Telegram,
+		&prefs.Events.OrderCreated,
+		&prefs.Events.OrderStatusChanged,
+		&prefs.Events.Marketing,
+		&prefs.UpdatedAt,
+	)
+	switch {
+	case err == sql.ErrNoRows:
+		prefs = models.DefaultNotificationPreferences(userID)
+	case err != nil:
+		return nil, fmt.Errorf("ошибка получения настроек уведомлений: %v", err)
+	}
+	data.NotificationPreferences = prefs
+
+	var lastLogin time.Time
+	err = tx.QueryRow(`SELECT last_login_at FROM user_logins WHERE user_id = $1`, userID).Scan(&lastLogin)
+	switch {
+	case err == nil:
+		data.LoginHistory.LastLoginAt = &lastLogin
+	case err != sql.ErrNoRows:
+		return nil, fmt.Errorf("ошибка получения времени последнего входа: %v", err)
+	}
+
+	rows, err = tx.Query(`
+		SELECT id, method, provider, success, ip_address, user_agent, attempted_at
+		FROM login_attempts
+		WHERE user_id = $1
+		ORDER BY attempted_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения попыток входа: %v", err)
+	}
+	for rows.Next() {
+		attempt := models.LoginAttempt{UserID: &userID}
+		if err := rows.Scan(&attempt.ID, &attempt.Method, &attempt.Provider, &attempt.Success,
+			&attempt.IPAddress, &attempt.UserAgent, &attempt.AttemptedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка сканирования попытки входа: %v", err)
+		}
+		data.LoginHistory.Attempts = append(data.LoginHistory.Attempts, attempt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+
+	rows, err = tx.Query(`
+		SELECT user_agent, ip_address, created_at, expires_at, revoked_at
+		FROM refresh_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения сессий: %v", err)
+	}
+	for rows.Next() {
+		var session models.LoginSession
+		if err := rows.Scan(&session.UserAgent, &session.IPAddress, &session.CreatedAt, &session.ExpiresAt, &session.RevokedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("ошибка сканирования сессии: %v", err)
+		}
+		data.LoginHistory.Sessions = append(data.LoginHistory.Sessions, session)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+
+	lockout := &models.LoginLockout{Scope: models.LockoutScopeAccount, Subject: userID.String()}
+	err = tx.QueryRow(`
+		SELECT failures, lock_count, locked_until, last_failure_at
+		FROM login_lockouts
+		WHERE scope = $1 AND subject = $2
+	`, lockout.Scope, lockout.Subject).Scan(&lockout.Failures, &lockout.LockCount, &lockout.LockedUntil, &lockout.LastFailureAt)
+	switch {
+	case err == nil:
+		data.LoginHistory.Lockout = lockout
+	case err != sql.ErrNoRows:
+		return nil, fmt.Errorf("ошибка получения блокировки входа: %v", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrRefreshTokenReused возвращается при повторном использовании уже замененного токена
+var ErrRefreshTokenReused = errors.New("refresh токен уже использован")
+
+// RefreshTokenRepository интерфейс для работы с refresh токенами
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) error
+	GetByHash(tokenHash string) (*models.RefreshToken, error)
+	Rotate(oldID uuid.UUID, newToken *models.RefreshToken) error
+	Revoke(id uuid.UUID) error
+	RevokeAllForUser(userID uuid.UUID) error
+	// ListSessions возвращает активные сессии пользователя, недавно использованные первыми
+	ListSessions(userID uuid.UUID) ([]models.Session, error)
+	// RevokeSession отзывает токены сессии пользователя; false, если активной сессии нет
+	RevokeSession(userID, sessionID uuid.UUID) (bool, error)
+	// RevokeAllSessions отзывает все сессии пользователя и увеличивает эпоху ролей,
+	// чтобы выданные access токены тоже отклонялись. Возвращает новую эпоху ролей
+	RevokeAllSessions(userID uuid.UUID) (int64, error)
+}
+
+// refreshTokenRepository реализация RefreshTokenRepository
+type refreshTokenRepository struct {
+	db *sql.DB
+}
+
+// NewRefreshTokenRepository создает новый экземпляр RefreshTokenRepository
+func NewRefreshTokenRepository(db *sql.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+// Create сохраняет новый refresh токен
+func (r *refreshTokenRepository) Create(token *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (id, user_id, session_id, token_hash, user_agent, ip_address, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(query, token.ID, token.UserID, token.SessionID, token.TokenHash,
+		token.UserAgent, token.IPAddress, token.ExpiresAt, token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания refresh токена: %v", err)
+	}
+	return nil
+}
+
+// GetByHash получает refresh токен по хешу значения
+func (r *refreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, session_id, token_hash, user_agent, ip_address, expires_at, revoked_at, replaced_by, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	token := &models.RefreshToken{}
+	err := r.db.QueryRow(query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.SessionID,
+		&token.TokenHash,
+		&token.UserAgent,
+		&token.IPAddress,
+		&token.ExpiresAt,
+		&token.RevokedAt,
+		&token.ReplacedBy,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("refresh токен не найден")
+		}
+		return nil, fmt.Errorf("ошибка получения refresh токена: %v", err)
+	}
+	return token, nil
+}
+
+// Rotate атомарно отзывает старый токен и сохраняет новый в той же сессии.
+// Если старый токен уже отозван (параллельное или повторное использование),
+// возвращает ErrRefreshTokenReused
+func (r *refreshTokenRepository) Rotate(oldID uuid.UUID, newToken *models.RefreshToken) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO refresh_tokens (id, user_id, session_id, token_hash, user_agent, ip_address, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, newToken.ID, newToken.UserID, newToken.SessionID, newToken.TokenHash,
+		newToken.UserAgent, newToken.IPAddress, newToken.ExpiresAt, newToken.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка создания refresh токена: %v", err)
+	}
+
+	result, err := tx.Exec(`
+		UPDATE refresh_tokens
+		SET revoked_at = NOW(), replaced_by = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`, oldID, newToken.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка отзыва refresh токена: %v", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества обновленных строк: %v", err)
+	}
+	if rowsAffected == 0 {
+		return ErrRefreshTokenReused
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return nil
+}
+
+// Revoke отзывает refresh токен; уже отозванный токен не изменяется
+func (r *refreshTokenRepository) Revoke(id uuid.UUID) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.Exec(query, id); err != nil {
+		return fmt.Errorf("ошибка отзыва refresh токена: %v", err)
+	}
+	return nil
+}
+
+// RevokeAllForUser отзывает все активные refresh токены пользователя
+func (r *refreshTokenRepository) RevokeAllForUser(userID uuid.UUID) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	if _, err := r.db.Exec(query, userID); err != nil {
+		return fmt.Errorf("ошибка отзыва refresh токенов: %v", err)
+	}
+	return nil
+}
+
+// ListSessions возвращает активные сессии пользователя: сессия активна, пока ее последний
+// токен не отозван и не истек. Время входа — время создания первого токена сессии
+func (r *refreshTokenRepository) ListSessions(userID uuid.UUID) ([]models.Session, error) {
+	query := `
+		SELECT t.session_id, t.user_agent, t.ip_address, s.created_at, t.created_at, t.expires_at
+		FROM refresh_tokens t
+		JOIN LATERAL (
+			SELECT MIN(created_at) AS created_at
+			FROM refresh_tokens
+			WHERE session_id = t.session_id
+		) s ON TRUE
+		WHERE t.user_id = $1 AND t.revoked_at IS NULL AND t.expires_at > NOW()
+		ORDER BY t.created_at DESC
+	`
+
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения сессий: %v", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.UserAgent, &session.IPAddress,
+			&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования сессии: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession отзывает токены сессии пользователя. Истекшая сессия считается
+// отсутствующей, как и сессия другого пользователя
+func (r *refreshTokenRepository) RevokeSession(userID, sessionID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND session_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`
+
+	result, err := r.db.Exec(query, userID, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отзыва сессии: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения количества обновленных строк: %v", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// RevokeAllSessions отзывает все refresh токены пользователя и увеличивает эпоху ролей
+// в одной транзакции. Возвращает sql.ErrNoRows, если пользователя нет
+func (r *refreshTokenRepository) RevokeAllSessions(userID uuid.UUID) (int64, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	var rolesEpoch int64
+	err = tx.QueryRow(`
+		UPDATE users
+		SET roles_epoch = roles_epoch + 1, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING roles_epoch
+	`, userID).Scan(&rolesEpoch)
+	if err == sql.ErrNoRows {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("ошибка обновления эпохи ролей: %v", err)
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID); err != nil {
+		return 0, fmt.Errorf("ошибка отзыва refresh токенов: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return rolesEpoch, nil
+}
@@ -0,0 +1,331 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"service_users/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrRoleNotFound роль отсутствует в справочнике ролей
+var ErrRoleNotFound = errors.New("роль не найдена")
+
+// ErrRoleExists роль с таким названием уже существует
+var ErrRoleExists = errors.New("роль с таким названием уже существует")
+
+// ErrRoleBuiltin встроенная роль не удаляется
+var ErrRoleBuiltin = errors.New("встроенную роль нельзя удалить")
+
+// ErrRolePermissionsLocked права роли не изменяются
+var ErrRolePermissionsLocked = errors.New("права роли не изменяются")
+
+// ErrRoleAssigned роль назначена пользователям и не может быть удалена
+var ErrRoleAssigned = errors.New("роль назначена пользователям")
+
+// ErrUnknownPermission право отсутствует в справочнике прав
+var ErrUnknownPermission = errors.New("неизвестное право")
+
+// RoleRepository хранит роли и права, выданные ролям
+type RoleRepository interface {
+	// ListPermissions возвращает справочник прав
+	ListPermissions() ([]models.Permission, error)
+	List() ([]models.Role, error)
+	// Get возвращает роль по названию или ErrRoleNotFound
+	Get(name string) (*models.Role, error)
+	// Create создает роль с правами role.Permissions; ErrRoleExists, если роль уже есть
+	Create(role *models.Role) error
+	// Update заменяет описание и права роли
+	Update(name, description string, permissions []string) (*models.Role, error)
+	// Delete удаляет роль, не назначенную ни одному пользователю; возвращает false,
+	// если роли не было
+	Delete(name string) (bool, error)
+	// Unknown возвращает названия из names, отсутствующие в справочнике ролей
+	Unknown(names []string) ([]string, error)
+	// Permissions возвращает права, выданные хотя бы одной из ролей roles, в алфавитном порядке
+	Permissions(roles []string) ([]string, error)
+}
+
+// roleRepository реализация RoleRepository
+type roleRepository struct {
+	db *sql.DB
+}
+
+// NewRoleRepository создает новый экземпляр RoleRepository
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+// roleColumns колонки роли с ее правами в алфавитном порядке
+const roleColumns = `
+	r.name, r.description, r.builtin, r.permissions_locked,
+	COALESCE(ARRAY(SELECT rp.permission FROM role_permissions rp WHERE rp.role = r.name ORDER BY rp.permission), '{}'),
+	r.created_at, r.updated_at`
+
+// ListPermissions возвращает справочник прав в алфавитном порядке
+func (r *roleRepository) ListPermissions() ([]models.Permission, error) {
+	rows, err := r.db.Query(`SELECT name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения прав: %v", err)
+	}
+	defer rows.Close()
+
+	permissions := make([]models.Permission, 0)
+	for rows.Next() {
+		var permission models.Permission
+		if err := rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования права: %v", err)
+		}
+		permissions = append(permissions, permission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return permissions, nil
+}
+
+// List возвращает роли в алфавитном порядке
+func (r *roleRepository) List() ([]models.Role, error) {
+	rows, err := r.db.Query(fmt.Sprintf(`SELECT %s FROM roles r ORDER BY r.name`, roleColumns))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения ролей: %v", err)
+	}
+	defer rows.Close()
+
+	roles := make([]models.Role, 0)
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования роли: %v", err)
+		}
+		roles = append(roles, *role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return roles, nil
+}
+
+// Get возвращает роль по названию или ErrRoleNotFound
+func (r *roleRepository) Get(name string) (*models.Role, error) {
+	role, err := scanRole(r.db.QueryRow(fmt.Sprintf(`SELECT %s FROM roles r WHERE r.name = $1`, roleColumns), name))
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения роли: %v", err)
+	}
+	return role, nil
+}
+
+// Create создает роль с правами role.Permissions; ErrRoleExists, если роль уже есть,
+// ErrUnknownPermission, если какого-то права нет в справочнике
+func (r *roleRepository) Create(role *models.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO roles (name, description)
+		VALUES ($1, $2)
+		RETURNING builtin, permissions_locked, created_at, updated_at
+	`, role.Name, role.Description).Scan(&role.Builtin, &role.PermissionsLocked, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrRoleExists
+		}
+		return fmt.Errorf("ошибка создания роли: %v", err)
+	}
+
+	if err := setRolePermissions(tx, role.Name, role.Permissions); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return nil
+}
+
+// Update заменяет описание и права роли. ErrRoleNotFound, если роли нет,
+// ErrRolePermissionsLocked, если права роли не изменяются
+func (r *roleRepository) Update(name, description string, permissions []string) (*models.Role, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	err = tx.QueryRow(`SELECT permissions_locked FROM roles WHERE name = $1 FOR UPDATE`, name).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return nil, ErrRoleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения роли: %v", err)
+	}
+	if locked {
+		return nil, ErrRolePermissionsLocked
+	}
+
+	if _, err := tx.Exec(`UPDATE roles SET description = $2, updated_at = NOW() WHERE name = $1`, name, description); err != nil {
+		return nil, fmt.Errorf("ошибка изменения роли: %v", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role = $1`, name); err != nil {
+		return nil, fmt.Errorf("ошибка удаления прав роли: %v", err)
+	}
+	if err := setRolePermissions(tx, name, permissions); err != nil {
+		return nil, err
+	}
+
+	role, err := scanRole(tx.QueryRow(fmt.Sprintf(`SELECT %s FROM roles r WHERE r.name = $1`, roleColumns), name))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения роли: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return role, nil
+}
+
+// Delete удаляет роль. ErrRoleBuiltin для встроенной роли, ErrRoleAssigned, если роль
+// назначена хотя бы одному пользователю; возвращает false, если роли не было
+func (r *roleRepository) Delete(name string) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	var builtin bool
+	err = tx.QueryRow(`SELECT builtin FROM roles WHERE name = $1 FOR UPDATE`, name).Scan(&builtin)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ошибка получения роли: %v", err)
+	}
+	if builtin {
+		return false, ErrRoleBuiltin
+	}
+
+	var assigned bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE $1 = ANY(roles))`, name).Scan(&assigned); err != nil {
+		return false, fmt.Errorf("ошибка проверки назначения роли: %v", err)
+	}
+	if assigned {
+		return false, ErrRoleAssigned
+	}
+
+	if _, err := tx.Exec(`DELETE FROM roles WHERE name = $1`, name); err != nil {
+		return false, fmt.Errorf("ошибка удаления роли: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return true, nil
+}
+
+// Unknown возвращает названия из names, отсутствующие в справочнике ролей
+func (r *roleRepository) Unknown(names []string) ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT DISTINCT n FROM unnest($1::text[]) AS n
+		WHERE NOT EXISTS (SELECT 1 FROM roles WHERE roles.name = n)
+		ORDER BY n
+	`, pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки ролей: %v", err)
+	}
+	defer rows.Close()
+
+	unknown := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования роли: %v", err)
+		}
+		unknown = append(unknown, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return unknown, nil
+}
+
+// Permissions возвращает права, выданные хотя бы одной из ролей roles, в алфавитном порядке
+func (r *roleRepository) Permissions(roles []string) ([]string, error) {
+	permissions := make([]string, 0)
+	if len(roles) == 0 {
+		return permissions, nil
+	}
+
+	rows, err := r.db.Query(`
+		SELECT DISTINCT permission FROM role_permissions
+		WHERE role = ANY($1)
+		ORDER BY permission
+	`, pq.Array(roles))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения прав ролей: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования права: %v", err)
+		}
+		permissions = append(permissions, permission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+	}
+	return permissions, nil
+}
+
+// setRolePermissions выдает роли role права permissions; ErrUnknownPermission с
+// перечнем неизвестных прав, если каких-то прав нет в справочнике
+func setRolePermissions(tx *sql.Tx, role string, permissions []string) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	var unknown pq.StringArray
+	err := tx.QueryRow(`
+		SELECT COALESCE(array_agg(p ORDER BY p), '{}') FROM unnest($1::text[]) AS p
+		WHERE NOT EXISTS (SELECT 1 FROM permissions WHERE permissions.name = p)
+	`, pq.Array(permissions)).Scan(&unknown)
+	if err != nil {
+		return fmt.Errorf("ошибка проверки прав: %v", err)
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownPermission, strings.Join(unknown, ", "))
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO role_permissions (role, permission)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, role, pq.Array(permissions))
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения прав роли: %v", err)
+	}
+	return nil
+}
+
+// scanRole читает строку roles с правами роли
+func scanRole(row interface{ Scan(...interface{}) error }) (*models.Role, error) {
+	var role models.Role
+	var permissions pq.StringArray
+	if err := row.Scan(&role.Name, &role.Description, &role.Builtin, &role.PermissionsLocked, &permissions, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		return nil, err
+	}
+	role.Permissions = []string(permissions)
+	return &role, nil
+}
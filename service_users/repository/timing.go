@@ -0,0 +1,579 @@
+package repository
+
+import (
+	"time"
+
+	"service_users/lockout"
+	"service_users/models"
+
+	"pkg/servertiming"
+
+	"github.com/google/uuid"
+)
+
+// Декораторы репозиториев учитывают время запросов к БД в этапе db запроса
+// (заголовок Server-Timing). Репозитории не принимают контекст, поэтому обработчики
+// оборачивают их Recorder'ом своего запроса; nil Recorder возвращает репозиторий как есть
+
+// TimedUserRepository возвращает UserRepository, учитывающий время запросов в timing
+func TimedUserRepository(repo UserRepository, timing *servertiming.Recorder) UserRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedUserRepository{next: repo, timing: timing}
+}
+
+type timedUserRepository struct {
+	next   UserRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedUserRepository) Create(user *models.User) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(user)
+}
+
+func (r *timedUserRepository) GetByID(id uuid.UUID) (*models.User, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetByID(id)
+}
+
+func (r *timedUserRepository) GetByEmail(email string) (*models.User, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetByEmail(email)
+}
+
+func (r *timedUserRepository) Update(user *models.User) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Update(user)
+}
+
+func (r *timedUserRepository) List(req *models.ListUsersRequest) (*models.ListUsersResponse, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.List(req)
+}
+
+func (r *timedUserRepository) UpdateRoles(id uuid.UUID, roles []string) (*models.User, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.UpdateRoles(id, roles)
+}
+
+func (r *timedUserRepository) BumpRolesEpoch(role string) (map[uuid.UUID]int64, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.BumpRolesEpoch(role)
+}
+
+func (r *timedUserRepository) Deactivate(id uuid.UUID) (*models.User, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Deactivate(id)
+}
+
+func (r *timedUserRepository) Reactivate(id uuid.UUID) (*models.User, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Reactivate(id)
+}
+
+func (r *timedUserRepository) EmailExists(email string) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.EmailExists(email)
+}
+
+// TimedRefreshTokenRepository возвращает RefreshTokenRepository, учитывающий время запросов в timing
+func TimedRefreshTokenRepository(repo RefreshTokenRepository, timing *servertiming.Recorder) RefreshTokenRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedRefreshTokenRepository{next: repo, timing: timing}
+}
+
+type timedRefreshTokenRepository struct {
+	next   RefreshTokenRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedRefreshTokenRepository) Create(token *models.RefreshToken) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(token)
+}
+
+func (r *timedRefreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetByHash(tokenHash)
+}
+
+func (r *timedRefreshTokenRepository) Rotate(oldID uuid.UUID, newToken *models.RefreshToken) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Rotate(oldID, newToken)
+}
+
+func (r *timedRefreshTokenRepository) Revoke(id uuid.UUID) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Revoke(id)
+}
+
+func (r *timedRefreshTokenRepository) RevokeAllForUser(userID uuid.UUID) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.RevokeAllForUser(userID)
+}
+
+func (r *timedRefreshTokenRepository) ListSessions(userID uuid.UUID) ([]models.Session, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.ListSessions(userID)
+}
+
+func (r *timedRefreshTokenRepository) RevokeSession(userID, sessionID uuid.UUID) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.RevokeSession(userID, sessionID)
+}
+
+func (r *timedRefreshTokenRepository) RevokeAllSessions(userID uuid.UUID) (int64, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.RevokeAllSessions(userID)
+}
+
+// TimedLoginAttemptRepository возвращает LoginAttemptRepository, учитывающий время запросов в timing
+func TimedLoginAttemptRepository(repo LoginAttemptRepository, timing *servertiming.Recorder) LoginAttemptRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedLoginAttemptRepository{next: repo, timing: timing}
+}
+
+type timedLoginAttemptRepository struct {
+	next   LoginAttemptRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedLoginAttemptRepository) Record(attempt *models.LoginAttempt) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Record(attempt)
+}
+
+func (r *timedLoginAttemptRepository) ListForUser(userID uuid.UUID, limit, offset int) ([]models.LoginAttempt, int, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.ListForUser(userID, limit, offset)
+}
+
+func (r *timedLoginAttemptRepository) DeleteBefore(before time.Time) (int64, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.DeleteBefore(before)
+}
+
+// TimedLoginLockoutRepository возвращает LoginLockoutRepository, учитывающий время запросов в timing
+func TimedLoginLockoutRepository(repo LoginLockoutRepository, timing *servertiming.Recorder) LoginLockoutRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedLoginLockoutRepository{next: repo, timing: timing}
+}
+
+type timedLoginLockoutRepository struct {
+	next   LoginLockoutRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedLoginLockoutRepository) Get(scope, subject string) (*models.LoginLockout, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Get(scope, subject)
+}
+
+func (r *timedLoginLockoutRepository) RecordFailure(scope, subject string, policy lockout.Policy, now time.Time) (*models.LoginLockout, bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.RecordFailure(scope, subject, policy, now)
+}
+
+func (r *timedLoginLockoutRepository) Reset(scope, subject string) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Reset(scope, subject)
+}
+
+func (r *timedLoginLockoutRepository) DeleteStale(before time.Time) (int64, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.DeleteStale(before)
+}
+
+// TimedEmailDomainRepository возвращает EmailDomainRepository, учитывающий время запросов в timing
+func TimedEmailDomainRepository(repo EmailDomainRepository, timing *servertiming.Recorder) EmailDomainRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedEmailDomainRepository{next: repo, timing: timing}
+}
+
+type timedEmailDomainRepository struct {
+	next   EmailDomainRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedEmailDomainRepository) List() ([]models.BannedEmailDomain, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.List()
+}
+
+func (r *timedEmailDomainRepository) Add(domain, reason string, createdBy uuid.UUID) (*models.BannedEmailDomain, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Add(domain, reason, createdBy)
+}
+
+func (r *timedEmailDomainRepository) Remove(domain string) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Remove(domain)
+}
+
+func (r *timedEmailDomainRepository) FindBanned(domains []string) (*models.BannedEmailDomain, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.FindBanned(domains)
+}
+
+// TimedNotificationRepository возвращает NotificationRepository, учитывающий время запросов в timing
+func TimedNotificationRepository(repo NotificationRepository, timing *servertiming.Recorder) NotificationRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedNotificationRepository{next: repo, timing: timing}
+}
+
+type timedNotificationRepository struct {
+	next   NotificationRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedNotificationRepository) GetByUserID(userID uuid.UUID) (*models.NotificationPreferences, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetByUserID(userID)
+}
+
+func (r *timedNotificationRepository) Upsert(prefs *models.NotificationPreferences) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Upsert(prefs)
+}
+
+// TimedPasswordResetRepository возвращает PasswordResetRepository, учитывающий время запросов в timing
+func TimedPasswordResetRepository(repo PasswordResetRepository, timing *servertiming.Recorder) PasswordResetRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedPasswordResetRepository{next: repo, timing: timing}
+}
+
+type timedPasswordResetRepository struct {
+	next   PasswordResetRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedPasswordResetRepository) Create(token *models.PasswordResetToken) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(token)
+}
+
+func (r *timedPasswordResetRepository) Reset(tokenHash, passwordHash string) (uuid.UUID, int64, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Reset(tokenHash, passwordHash)
+}
+
+func (r *timedPasswordResetRepository) ChangePassword(userID uuid.UUID, passwordHash string) (int64, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.ChangePassword(userID, passwordHash)
+}
+
+// TimedEmailChangeRepository возвращает EmailChangeRepository, учитывающий время запросов в timing
+func TimedEmailChangeRepository(repo EmailChangeRepository, timing *servertiming.Recorder) EmailChangeRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedEmailChangeRepository{next: repo, timing: timing}
+}
+
+type timedEmailChangeRepository struct {
+	next   EmailChangeRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedEmailChangeRepository) Create(token *models.EmailChangeToken) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(token)
+}
+
+func (r *timedEmailChangeRepository) Confirm(tokenHash string) (*models.EmailChangeToken, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Confirm(tokenHash)
+}
+
+// TimedUserDeletionRepository возвращает UserDeletionRepository, учитывающий время запросов в timing
+func TimedUserDeletionRepository(repo UserDeletionRepository, timing *servertiming.Recorder) UserDeletionRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedUserDeletionRepository{next: repo, timing: timing}
+}
+
+type timedUserDeletionRepository struct {
+	next   UserDeletionRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedUserDeletionRepository) Create(deletion *models.UserDeletion) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(deletion)
+}
+
+func (r *timedUserDeletionRepository) GetByID(id uuid.UUID) (*models.UserDeletion, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetByID(id)
+}
+
+func (r *timedUserDeletionRepository) GetLatestForUser(userID uuid.UUID) (*models.UserDeletion, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetLatestForUser(userID)
+}
+
+func (r *timedUserDeletionRepository) ClaimNext() (*models.UserDeletion, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.ClaimNext()
+}
+
+func (r *timedUserDeletionRepository) Execute(deletion *models.UserDeletion) (*models.UserDeletionSummary, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Execute(deletion)
+}
+
+func (r *timedUserDeletionRepository) MarkFailed(id uuid.UUID, lastError string) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.MarkFailed(id, lastError)
+}
+
+func (r *timedUserDeletionRepository) CreateConfirmation(userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.CreateConfirmation(userID, tokenHash, expiresAt)
+}
+
+func (r *timedUserDeletionRepository) ConsumeConfirmation(userID uuid.UUID, tokenHash string) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.ConsumeConfirmation(userID, tokenHash)
+}
+
+// TimedPersonalDataRepository возвращает PersonalDataRepository, учитывающий время запросов в timing
+func TimedPersonalDataRepository(repo PersonalDataRepository, timing *servertiming.Recorder) PersonalDataRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedPersonalDataRepository{next: repo, timing: timing}
+}
+
+type timedPersonalDataRepository struct {
+	next   PersonalDataRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedPersonalDataRepository) Export(userID uuid.UUID) (*models.PersonalData, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Export(userID)
+}
+
+// TimedAccessReviewRepository возвращает AccessReviewRepository, учитывающий время запросов в timing
+func TimedAccessReviewRepository(repo AccessReviewRepository, timing *servertiming.Recorder) AccessReviewRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedAccessReviewRepository{next: repo, timing: timing}
+}
+
+type timedAccessReviewRepository struct {
+	next   AccessReviewRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedAccessReviewRepository) RecordLogin(userID uuid.UUID) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.RecordLogin(userID)
+}
+
+func (r *timedAccessReviewRepository) RecordAdminAction(action *models.AdminAction) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.RecordAdminAction(action)
+}
+
+func (r *timedAccessReviewRepository) Review(roles []string, since time.Time, actionsLimit int) ([]models.AccessReviewUser, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Review(roles, since, actionsLimit)
+}
+
+// TimedIdentityRepository возвращает IdentityRepository, учитывающий время запросов в timing
+func TimedIdentityRepository(repo IdentityRepository, timing *servertiming.Recorder) IdentityRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedIdentityRepository{next: repo, timing: timing}
+}
+
+type timedIdentityRepository struct {
+	next   IdentityRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedIdentityRepository) GetUserID(provider, subject string) (uuid.UUID, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetUserID(provider, subject)
+}
+
+func (r *timedIdentityRepository) Link(identity *models.UserIdentity) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Link(identity)
+}
+
+// TimedExportRepository возвращает ExportRepository, учитывающий время запросов в timing
+func TimedExportRepository(repo ExportRepository, timing *servertiming.Recorder) ExportRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedExportRepository{next: repo, timing: timing}
+}
+
+type timedExportRepository struct {
+	next   ExportRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedExportRepository) Lock(userID uuid.UUID) (func(), error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Lock(userID)
+}
+
+func (r *timedExportRepository) Create(artifact *models.ExportArtifact) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(artifact)
+}
+
+func (r *timedExportRepository) Get(id, userID uuid.UUID, now time.Time) (*models.ExportArtifact, bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Get(id, userID, now)
+}
+
+func (r *timedExportRepository) DeleteExpired(now time.Time) (int64, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.DeleteExpired(now)
+}
+
+// TimedBlacklistRepository возвращает BlacklistRepository, учитывающий время запросов в timing
+func TimedBlacklistRepository(repo BlacklistRepository, timing *servertiming.Recorder) BlacklistRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedBlacklistRepository{next: repo, timing: timing}
+}
+
+type timedBlacklistRepository struct {
+	next   BlacklistRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedBlacklistRepository) List(kind models.BlacklistKind) ([]models.BlacklistEntry, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.List(kind)
+}
+
+func (r *timedBlacklistRepository) Add(entry *models.BlacklistEntry) (*models.BlacklistEntry, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Add(entry)
+}
+
+func (r *timedBlacklistRepository) Remove(id uuid.UUID) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Remove(id)
+}
+
+func (r *timedBlacklistRepository) Match(userID uuid.UUID, email, ip string) (*models.BlacklistEntry, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Match(userID, email, ip)
+}
+
+// TimedAPIKeyRepository возвращает APIKeyRepository, учитывающий время запросов в timing
+func TimedAPIKeyRepository(repo APIKeyRepository, timing *servertiming.Recorder) APIKeyRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedAPIKeyRepository{next: repo, timing: timing}
+}
+
+type timedAPIKeyRepository struct {
+	next   APIKeyRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedAPIKeyRepository) Create(key *models.APIKey, maxActive int) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(key, maxActive)
+}
+
+func (r *timedAPIKeyRepository) ListForUser(userID uuid.UUID) ([]models.APIKey, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.ListForUser(userID)
+}
+
+func (r *timedAPIKeyRepository) GetByHash(keyHash string) (*models.APIKey, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.GetByHash(keyHash)
+}
+
+func (r *timedAPIKeyRepository) Revoke(userID, id uuid.UUID) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Revoke(userID, id)
+}
+
+func (r *timedAPIKeyRepository) Touch(id uuid.UUID) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Touch(id)
+}
+
+// TimedRoleRepository возвращает RoleRepository, учитывающий время запросов в timing
+func TimedRoleRepository(repo RoleRepository, timing *servertiming.Recorder) RoleRepository {
+	if timing == nil {
+		return repo
+	}
+	return &timedRoleRepository{next: repo, timing: timing}
+}
+
+type timedRoleRepository struct {
+	next   RoleRepository
+	timing *servertiming.Recorder
+}
+
+func (r *timedRoleRepository) ListPermissions() ([]models.Permission, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.ListPermissions()
+}
+
+func (r *timedRoleRepository) List() ([]models.Role, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.List()
+}
+
+func (r *timedRoleRepository) Get(name string) (*models.Role, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Get(name)
+}
+
+func (r *timedRoleRepository) Create(role *models.Role) error {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Create(role)
+}
+
+func (r *timedRoleRepository) Update(name, description string, permissions []string) (*models.Role, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Update(name, description, permissions)
+}
+
+func (r *timedRoleRepository) Delete(name string) (bool, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Delete(name)
+}
+
+func (r *timedRoleRepository) Unknown(names []string) ([]string, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Unknown(names)
+}
+
+func (r *timedRoleRepository) Permissions(roles []string) ([]string, error) {
+	defer r.timing.Start(servertiming.MetricDB)()
+	return r.next.Permissions(roles)
+}
@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"service_users/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ErrUserDeletionInProgress возвращается, если для пользователя уже есть незавершенная операция удаления
+var ErrUserDeletionInProgress = errors.New("удаление данных пользователя уже выполняется")
+
+// ErrDeletionConfirmationInvalid возвращается, если токен подтверждения удаления не найден,
+// истек или уже использован
+var ErrDeletionConfirmationInvalid = errors.New("токен подтверждения удаления недействителен")
+
+// deletionRunningTimeout время, после которого зависшая в running операция
+// (например, при падении сервиса во время выполнения) снова забирается в работу
+const deletionRunningTimeout = 5 * time.Minute
+
+// DeletedEmailDomain домен адресов обезличенных пользователей; .invalid не может
+// принадлежать реальному почтовому ящику (RFC 2606)
+const DeletedEmailDomain = "deleted.invalid"
+
+// deletedUserName имя обезличенного пользователя
+const deletedUserName = "Удаленный пользователь"
+
+// UserDeletionRepository интерфейс для работы с операциями удаления данных пользователей
+type UserDeletionRepository interface {
+	// Create сохраняет новую операцию; ErrUserDeletionInProgress, если у пользователя есть незавершенная
+	Create(deletion *models.UserDeletion) error
+	GetByID(id uuid.UUID) (*models.UserDeletion, error)
+	// GetLatestForUser возвращает последнюю операцию пользователя или nil, если их не было
+	GetLatestForUser(userID uuid.UUID) (*models.UserDeletion, error)
+	// ClaimNext забирает в работу самую старую ожидающую операцию; nil, если таких нет
+	ClaimNext() (*models.UserDeletion, error)
+	// Execute обезличивает пользователя и его данные и завершает операцию в одной транзакции
+	Execute(deletion *models.UserDeletion) (*models.UserDeletionSummary, error)
+	MarkFailed(id uuid.UUID, lastError string) error
+	// CreateConfirmation сохраняет хеш токена подтверждения удаления своих данных;
+	// предыдущий токен пользователя перестает действовать
+	CreateConfirmation(userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	// ConsumeConfirmation погашает токен подтверждения пользователя;
+	// ErrDeletionConfirmationInvalid, если токен не найден или истек
+	ConsumeConfirmation(userID uuid.UUID, tokenHash string) error
+}
+
+// userDeletionRepository реализация UserDeletionRepository
+type userDeletionRepository struct {
+	db *sql.DB
+}
+
+// NewUserDeletionRepository создает новый экземпляр UserDeletionRepository
+func NewUserDeletionRepository(db *sql.DB) UserDeletionRepository {
+	return &userDeletionRepository{db: db}
+}
+
+const userDeletionColumns = `id, user_id, requested_by, status, summary, attempts, last_error, created_at, started_at, completed_at`
+
+// Create сохраняет новую операцию удаления
+func (r *userDeletionRepository) Create(deletion *models.UserDeletion) error {
+	query := `
+		INSERT INTO user_deletions (id, user_id, requested_by, status, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Exec(query, deletion.ID, deletion.UserID, deletion.RequestedBy, deletion.Status, deletion.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrUserDeletionInProgress
+		}
+		return fmt.Errorf("ошибка создания операции удаления: %v", err)
+	}
+	return nil
+}
+
+// GetByID получает операцию удаления по ID
+func (r *userDeletionRepository) GetByID(id uuid.UUID) (*models.UserDeletion, error) {
+	query := fmt.Sprintf(`SELECT %s FROM user_deletions WHERE id = $1`, userDeletionColumns)
+
+	deletion, err := scanUserDeletion(r.db.QueryRow(query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("операция удаления не найдена")
+		}
+		return nil, fmt.Errorf("ошибка получения операции удаления: %v", err)
+	}
+	return deletion, nil
+}
+
+// GetLatestForUser возвращает последнюю операцию удаления пользователя
+func (r *userDeletionRepository) GetLatestForUser(userID uuid.UUID) (*models.UserDeletion, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM user_deletions
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, userDeletionColumns)
+
+	deletion, err := scanUserDeletion(r.db.QueryRow(query, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения операции удаления: %v", err)
+	}
+	return deletion, nil
+}
+
+// ClaimNext атомарно забирает в работу самую старую ожидающую операцию.
+// SKIP LOCKED позволяет нескольким экземплярам сервиса не выполнять одну операцию дважды
+func (r *userDeletionRepository) ClaimNext() (*models.UserDeletion, error) {
+	query := fmt.Sprintf(`
+		UPDATE user_deletions
+		SET status = 'running', attempts = attempts + 1, started_at = NOW()
+		WHERE id = (
+			SELECT id FROM user_deletions
+			WHERE status = 'pending'
+			   OR (status = 'running' AND started_at < NOW() - INTERVAL '%d seconds')
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %s
+	`, int(deletionRunningTimeout.Seconds()), userDeletionColumns)
+
+	deletion, err := scanUserDeletion(r.db.QueryRow(query))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения операции удаления для выполнения: %v", err)
+	}
+	return deletion, nil
+}
+
+// Execute выполняет шаги удаления в одной транзакции: при ошибке любого шага данные
+// пользователя остаются нетронутыми, и операцию можно повторить.
+//   - персональные поля пользователя заменяются, вход и роли отключаются, эпоха ролей увеличивается;
+//   - refresh токены отзываются, User-Agent и адрес клиента сессий стираются;
+//   - настройки уведомлений и API ключи удаляются;
+//   - попытки входа отвязываются от пользователя, адрес и User-Agent клиента стираются;
+//   - содержимое доставок уведомлений и webhook о пользователе заменяется отметкой об удалении,
+//     неотправленные доставки отменяются;
+//   - заказы сохраняются и ссылаются на обезличенного пользователя
+func (r *userDeletionRepository) Execute(deletion *models.UserDeletion) (*models.UserDeletionSummary, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback()
+
+	summary := &models.UserDeletionSummary{}
+	userID := deletion.UserID
+
+	// Пустой хеш пароля не совпадает ни с одним паролем, а пустой список ролей
+	// блокирует пользователя при входе и обновлении токенов
+	err = tx.QueryRow(`
+		UPDATE users
+		SET email = $2, name = $3, password_hash = '', roles = '{}', timezone = 'UTC',
+		    directory_source = NULL, external_id = NULL,
+		    roles_epoch = roles_epoch + 1, deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING roles_epoch
+	`, userID, fmt.Sprintf("deleted-%s@%s", userID, DeletedEmailDomain), deletedUserName).Scan(&summary.RolesEpoch)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("пользователь не найден")
+		}
+		return nil, fmt.Errorf("ошибка обезличивания пользователя: %v", err)
+	}
+
+	if summary.RefreshTokensRevoked, err = execCount(tx, `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID); err != nil {
+		return nil, fmt.Errorf("ошибка отзыва refresh токенов: %v", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE refresh_tokens SET user_agent = '', ip_address = ''
+		WHERE user_id = $1
+	`, userID); err != nil {
+		return nil, fmt.Errorf("ошибка обезличивания сессий: %v", err)
+	}
+
+	if summary.NotificationPreferencesDeleted, err = execCount(tx, `
+		DELETE FROM notification_preferences WHERE user_id = $1
+	`, userID); err != nil {
+		return nil, fmt.Errorf("ошибка удаления настроек уведомлений: %v", err)
+	}
+
+	if summary.APIKeysDeleted, err = execCount(tx, `
+		DELETE FROM api_keys WHERE user_id = $1
+	`, userID); err != nil {
+		return nil, fmt.Errorf("ошибка удаления API ключей: %v", err)
+	}
+
+	if summary.OAuthIdentitiesDeleted, err = execCount(tx, `
+		DELETE FROM user_identities WHERE user_id = $1
+	`, userID); err != nil {
+		return nil, fmt.Errorf("ошибка удаления связей с провайдерами входа: %v", err)
+	}
+
+	// Результаты попыток остаются для доли неудачных входов детектора аномалий
+	if summary.LoginAttemptsAnonymized, err = execCount(tx, `
+		UPDATE login_attempts SET user_id = NULL, ip_address = '', user_agent = ''
+		WHERE user_id = $1
+	`, userID); err != nil {
+		return nil, fmt.Errorf("ошибка обезличивания попыток входа: %v", err)
+	}
+
+	// Webhook доставки не привязаны к пользователю, но их события содержат его ID
+	tombstone, err := json.Marshal(map[string]string{"tombstone": "user_deleted", "deletion_id": deletion.ID.String()})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка формирования отметки об удалении: %v", err)
+	}
+	if summary.DeliveriesDiscarded, err = execCount(tx, `
+		UPDATE deliveries SET status = 'discarded', updated_at = NOW()
+		WHERE (user_id = $1 OR (user_id IS NULL AND payload LIKE '%' || $2::text || '%'))
+		  AND status IN ('pending', 'processing', 'failed')
+	`, userID, userID.String()); err != nil {
+		return nil, fmt.Errorf("ошибка отмены доставок: %v", err)
+	}
+	if summary.DeliveriesTombstoned, err = execCount(tx, `
+		UPDATE deliveries SET payload = $3, target = '', last_error = '', updated_at = NOW()
+		WHERE user_id = $1 OR (user_id IS NULL AND payload LIKE '%' || $2::text || '%')
+	`, userID, userID.String(), string(tombstone)); err != nil {
+		return nil, fmt.Errorf("ошибка удаления содержимого доставок: %v", err)
+	}
+
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM orders WHERE user_id = $1`, userID).Scan(&summary.OrdersRetained); err != nil {
+		return nil, fmt.Errorf("ошибка подсчета заказов: %v", err)
+	}
+
+	rawSummary, err := json.Marshal(summary)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации итога удаления: %v", err)
+	}
+	if _, err := tx.Exec(`
+		UPDATE user_deletions
+		SET status = 'completed', summary = $2, last_error = '', completed_at = NOW()
+		WHERE id = $1
+	`, deletion.ID, string(rawSummary)); err != nil {
+		return nil, fmt.Errorf("ошибка завершения операции удаления: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ошибка фиксации транзакции: %v", err)
+	}
+	return summary, nil
+}
+
+// MarkFailed отмечает операцию неудачной с текстом ошибки; повторить ее можно новым запросом
+func (r *userDeletionRepository) MarkFailed(id uuid.UUID, lastError string) error {
+	query := `
+		UPDATE user_deletions
+		SET status = 'failed', last_error = $2, completed_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id, lastError); err != nil {
+		return fmt.Errorf("ошибка обновления операции удаления: %v", err)
+	}
+	return nil
+}
+
+// CreateConfirmation сохраняет токен подтверждения удаления. У пользователя хранится
+// только последний токен
+func (r *userDeletionRepository) CreateConfirmation(userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO user_deletion_confirmations (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+	`
+
+	if _, err := r.db.Exec(query, userID, tokenHash, expiresAt); err != nil {
+		return fmt.Errorf("ошибка сохранения подтверждения удаления: %v", err)
+	}
+	return nil
+}
+
+// ConsumeConfirmation погашает токен подтверждения удалением строки, поэтому из
+// параллельных запросов с одним токеном подтверждение проходит только один
+func (r *userDeletionRepository) ConsumeConfirmation(userID uuid.UUID, tokenHash string) error {
+	result, err := r.db.Exec(`
+		DELETE FROM user_deletion_confirmations
+		WHERE user_id = $1 AND token_hash = $2 AND expires_at > NOW()
+	`, userID, tokenHash)
+	if err != nil {
+		return fmt.Errorf("ошибка погашения подтверждения удаления: %v", err)
+	}
+	consumed, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("ошибка получения количества удаленных строк: %v", err)
+	}
+	if consumed == 0 {
+		return ErrDeletionConfirmationInvalid
+	}
+	return nil
+}
+
+// execCount выполняет запрос в транзакции и возвращает число затронутых строк
+func execCount(tx *sql.Tx, query string, args ...interface{}) (int64, error) {
+	result, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// scanUserDeletion сканирует строку user_deletions
+func scanUserDeletion(row *sql.Row) (*models.UserDeletion, error) {
+	deletion := &models.UserDeletion{}
+	var summary []byte
+	err := row.Scan(
+		&deletion.ID,
+		&deletion.UserID,
+		&deletion.RequestedBy,
+		&deletion.Status,
+		&summary,
+		&deletion.Attempts,
+		&deletion.LastError,
+		&deletion.CreatedAt,
+		&deletion.StartedAt,
+		&deletion.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if summary != nil {
+		deletion.Summary = &models.UserDeletionSummary{}
+		if err := json.Unmarshal(summary, deletion.Summary); err != nil {
+			return nil, fmt.Errorf("некорректный итог операции удаления: %v", err)
+		}
+	}
+	return deletion, nil
+}
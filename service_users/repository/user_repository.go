@@ -18,6 +18,12 @@ type UserRepository interface {
 	GetByEmail(email string) (*models.User, error)
 	Update(user *models.User) error
 	List(req *models.ListUsersRequest) (*models.ListUsersResponse, error)
+	UpdateRoles(id uuid.UUID, roles []string) (*models.User, error)
+	// BumpRolesEpoch увеличивает эпоху ролей пользователей с ролью role и возвращает
+	// новые эпохи по ID пользователя
+	BumpRolesEpoch(role string) (map[uuid.UUID]int64, error)
+	Deactivate(id uuid.UUID) (*models.User, error)
+	Reactivate(id uuid.UUID) (*models.User, error)
 	EmailExists(email string) (bool, error)
 }
 
@@ -34,8 +40,8 @@ func NewUserRepository(db *sql.DB) UserRepository {
 // Create создает нового пользователя
 func (r *userRepository) Create(user *models.User) error {
 	query := `
-		INSERT INTO users (id, email, password_hash, name, roles, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO users (id, email, password_hash, name, roles, timezone, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 	
 	_, err := r.db.Exec(query,
@@ -44,6 +50,7 @@ func (r *userRepository) Create(user *models.User) error {
 		user.Password,
 		user.Name,
 		pq.Array(user.Roles),
+		user.Timezone,
 		user.CreatedAt,
 		user.UpdatedAt,
 	)
@@ -61,7 +68,7 @@ func (r *userRepository) Create(user *models.User) error {
 // GetByID получает пользователя по ID
 func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
     query := `
-        SELECT id, email, password_hash, name, roles, created_at, updated_at
+        SELECT id, email, password_hash, name, roles, timezone, created_at, updated_at, roles_epoch, deactivated_at
         FROM users
         WHERE id = $1
     `
@@ -73,8 +80,11 @@ func (r *userRepository) GetByID(id uuid.UUID) (*models.User, error) {
         &user.Password,
         &user.Name,
         &user.Roles,
+        &user.Timezone,
         &user.CreatedAt,
         &user.UpdatedAt,
+        &user.RolesEpoch,
+        &user.DeactivatedAt,
     )
     if err != nil {
         if err == sql.ErrNoRows {
@@ -99,7 +109,7 @@ func (r *userRepository) EmailExists(email string) (bool, error) {
 // GetByEmail получает пользователя по email
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
     query := `
-        SELECT id, email, password_hash, name, roles, created_at, updated_at
+        SELECT id, email, password_hash, name, roles, timezone, created_at, updated_at, roles_epoch, deactivated_at
         FROM users
         WHERE lower(email) = $1
     `
@@ -111,8 +121,11 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
         &user.Password,
         &user.Name,
         &user.Roles,
+        &user.Timezone,
         &user.CreatedAt,
         &user.UpdatedAt,
+        &user.RolesEpoch,
+        &user.DeactivatedAt,
     )
     if err != nil {
         if err == sql.ErrNoRows {
@@ -127,7 +140,7 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 func (r *userRepository) Update(user *models.User) error {
     query := `
         UPDATE users
-        SET email = $2, name = $3, roles = $4, updated_at = NOW()
+        SET email = $2, name = $3, roles = $4, timezone = $5, updated_at = NOW()
         WHERE id = $1
     `
 
@@ -136,6 +149,7 @@ func (r *userRepository) Update(user *models.User) error {
         user.Email,
         user.Name,
         pq.Array(user.Roles),
+        user.Timezone,
     )
     if err != nil {
         if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
@@ -154,6 +168,115 @@ func (r *userRepository) Update(user *models.User) error {
     return nil
 }
 
+// UpdateRoles заменяет роли пользователя и увеличивает эпоху ролей,
+// чтобы ранее выданные access токены перестали приниматься
+func (r *userRepository) UpdateRoles(id uuid.UUID, roles []string) (*models.User, error) {
+    query := `
+        UPDATE users
+        SET roles = $2, roles_epoch = roles_epoch + 1, updated_at = NOW()
+        WHERE id = $1
+        RETURNING id, email, name, roles, timezone, created_at, updated_at, roles_epoch, deactivated_at
+    `
+
+    user := &models.User{}
+    err := r.db.QueryRow(query, id, pq.Array(roles)).Scan(
+        &user.ID,
+        &user.Email,
+        &user.Name,
+        &user.Roles,
+        &user.Timezone,
+        &user.CreatedAt,
+        &user.UpdatedAt,
+        &user.RolesEpoch,
+        &user.DeactivatedAt,
+    )
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, fmt.Errorf("пользователь с ID %s не найден", id)
+        }
+        return nil, fmt.Errorf("ошибка обновления ролей пользователя: %v", err)
+    }
+    return user, nil
+}
+
+// BumpRolesEpoch увеличивает эпоху ролей всех пользователей с ролью role, чтобы
+// access токены с прежними правами роли перестали приниматься
+func (r *userRepository) BumpRolesEpoch(role string) (map[uuid.UUID]int64, error) {
+    query := `
+        UPDATE users
+        SET roles_epoch = roles_epoch + 1, updated_at = NOW()
+        WHERE $1 = ANY(roles)
+        RETURNING id, roles_epoch
+    `
+
+    rows, err := r.db.Query(query, role)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка обновления эпохи ролей: %v", err)
+    }
+    defer rows.Close()
+
+    epochs := make(map[uuid.UUID]int64)
+    for rows.Next() {
+        var id uuid.UUID
+        var epoch int64
+        if err := rows.Scan(&id, &epoch); err != nil {
+            return nil, fmt.Errorf("ошибка сканирования эпохи ролей: %v", err)
+        }
+        epochs[id] = epoch
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка итерации по строкам: %v", err)
+    }
+    return epochs, nil
+}
+
+// Deactivate деактивирует учетную запись и увеличивает эпоху ролей, чтобы ранее
+// выданные access токены перестали приниматься. Время деактивации уже
+// деактивированной учетной записи не меняется
+func (r *userRepository) Deactivate(id uuid.UUID) (*models.User, error) {
+    query := `
+        UPDATE users
+        SET deactivated_at = COALESCE(deactivated_at, NOW()), roles_epoch = roles_epoch + 1, updated_at = NOW()
+        WHERE id = $1
+        RETURNING id, email, name, roles, timezone, created_at, updated_at, roles_epoch, deactivated_at
+    `
+    return r.updateStatus(query, id)
+}
+
+// Reactivate снимает деактивацию учетной записи. Роли и эпоха ролей не меняются
+func (r *userRepository) Reactivate(id uuid.UUID) (*models.User, error) {
+    query := `
+        UPDATE users
+        SET deactivated_at = NULL, updated_at = NOW()
+        WHERE id = $1
+        RETURNING id, email, name, roles, timezone, created_at, updated_at, roles_epoch, deactivated_at
+    `
+    return r.updateStatus(query, id)
+}
+
+// updateStatus выполняет запрос изменения состояния учетной записи и возвращает пользователя
+func (r *userRepository) updateStatus(query string, id uuid.UUID) (*models.User, error) {
+    user := &models.User{}
+    err := r.db.QueryRow(query, id).Scan(
+        &user.ID,
+        &user.Email,
+        &user.Name,
+        &user.Roles,
+        &user.Timezone,
+        &user.CreatedAt,
+        &user.UpdatedAt,
+        &user.RolesEpoch,
+        &user.DeactivatedAt,
+    )
+    if err != nil {
+        if err == sql.ErrNoRows {
+            return nil, fmt.Errorf("пользователь с ID %s не найден", id)
+        }
+        return nil, fmt.Errorf("ошибка изменения состояния учетной записи: %v", err)
+    }
+    return user, nil
+}
+
 // List получает список пользователей с фильтрацией и пагинацией
 func (r *userRepository) List(req *models.ListUsersRequest) (*models.ListUsersResponse, error) {
     // Построение WHERE условий
@@ -173,12 +296,40 @@ func (r *userRepository) List(req *models.ListUsersRequest) (*models.ListUsersRe
         argIndex++
     }
 
+    // Поиск: все слова запроса как префиксы слов email и имени или похожесть всего
+    // запроса на email или имя по триграммам (pg_trgm)
+    orderBy := "created_at DESC"
+    if q := strings.TrimSpace(req.Q); q != "" {
+        similarity := fmt.Sprintf("GREATEST(similarity(email, $%d), similarity(name, $%d))", argIndex, argIndex)
+        match := fmt.Sprintf("(email %% $%d OR name %% $%d", argIndex, argIndex)
+        rank := similarity
+        args = append(args, q)
+        argIndex++
+
+        if terms := req.SearchTerms(); len(terms) > 0 {
+            tsquery := fmt.Sprintf("to_tsquery('simple', $%d)", argIndex)
+            match += " OR search_vector @@ " + tsquery
+            rank = fmt.Sprintf("ts_rank(search_vector, %s) + %s", tsquery, similarity)
+            args = append(args, prefixQuery(terms))
+            argIndex++
+        }
+        conditions = append(conditions, match+")")
+        orderBy = rank + " DESC, created_at DESC"
+    }
+
     if req.Role != "" {
         conditions = append(conditions, fmt.Sprintf("$%d = ANY(roles)", argIndex))
         args = append(args, req.Role)
         argIndex++
     }
 
+    switch req.Status {
+    case models.UserStatusActive:
+        conditions = append(conditions, "deactivated_at IS NULL")
+    case models.UserStatusDeactivated:
+        conditions = append(conditions, "deactivated_at IS NOT NULL")
+    }
+
     whereClause := ""
     if len(conditions) > 0 {
         whereClause = "WHERE " + strings.Join(conditions, " AND ")
@@ -193,12 +344,12 @@ func (r *userRepository) List(req *models.ListUsersRequest) (*models.ListUsersRe
 
     // Получение списка пользователей
     query := fmt.Sprintf(`
-        SELECT id, email, password_hash, name, roles, created_at, updated_at
+        SELECT id, email, password_hash, name, roles, timezone, created_at, updated_at, deactivated_at
         FROM users
         %s
-        ORDER BY created_at DESC
+        ORDER BY %s
         LIMIT $%d OFFSET $%d
-    `, whereClause, argIndex, argIndex+1)
+    `, whereClause, orderBy, argIndex, argIndex+1)
 
     args = append(args, req.Limit, req.Offset)
 
@@ -217,8 +368,10 @@ func (r *userRepository) List(req *models.ListUsersRequest) (*models.ListUsersRe
             &user.Password,
             &user.Name,
             &user.Roles,
+            &user.Timezone,
             &user.CreatedAt,
             &user.UpdatedAt,
+            &user.DeactivatedAt,
         ); err != nil {
             return nil, fmt.Errorf("ошибка сканирования пользователя: %v", err)
         }
@@ -237,3 +390,13 @@ func (r *userRepository) List(req *models.ListUsersRequest) (*models.ListUsersRe
         Offset: req.Offset,
     }, nil
 }
+
+// prefixQuery строит tsquery, в котором каждое слово — префикс: ivan pet → ivan:* & pet:*.
+// Слова состоят только из букв и цифр (SearchTerms), поэтому экранирование не нужно
+func prefixQuery(terms []string) string {
+    parts := make([]string, len(terms))
+    for i, term := range terms {
+        parts[i] = term + ":*"
+    }
+    return strings.Join(parts, " & ")
+}
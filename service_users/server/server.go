@@ -0,0 +1,409 @@
+// Package server собирает service_users из конфигурации и подключения к БД: репозитории,
+// обработчики, маршруты и middleware. Фоновые задачи запускаются отдельно (Start), поэтому
+// команды, которым нужен сервис в процессе (например, fuzz_api), получают обработчик
+// запросов без фоновых задач
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"service_users/config"
+	"service_users/deletion"
+	"service_users/directory"
+	"service_users/handlers"
+	"service_users/logger"
+	"service_users/mail"
+	"service_users/models"
+	"service_users/oauth"
+	"service_users/password"
+	"service_users/registration"
+	"service_users/repository"
+
+	"pkg/buildinfo"
+	"pkg/httpmw"
+	"pkg/httpresp"
+	"pkg/lock"
+	"pkg/rolesepoch"
+	"pkg/servertiming"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// Server собранный сервис: обработчик запросов и фоновые задачи
+type Server struct {
+	handler http.Handler
+	// jobs фоновые задачи; выполняются до отмены контекста Start
+	jobs []func(ctx context.Context)
+	// epochs эпохи ролей в Redis; nil, если Redis не настроен
+	epochs *rolesepoch.Store
+}
+
+// New создает репозитории и обработчики сервиса и регистрирует маршруты.
+// Фоновые задачи не запускаются до вызова Start
+func New(cfg *config.Config, db *sql.DB) (*Server, error) {
+	zapLogger := logger.GetLogger()
+	s := &Server{}
+
+	// Инициализация репозитория и обработчиков
+	userRepo := repository.NewUserRepository(db)
+	refreshRepo := repository.NewRefreshTokenRepository(db)
+	emailDomainRepo := repository.NewEmailDomainRepository(db)
+	emailPolicy := registration.NewPolicy(emailDomainRepo, registration.Config{
+		BlockDisposable: cfg.Registration.BlockDisposable,
+		DisposableURL:   cfg.Registration.DisposableURL,
+		RefreshInterval: cfg.Registration.RefreshInterval,
+	})
+	s.jobs = append(s.jobs, emailPolicy.Run)
+	passwordPolicy, err := password.NewPolicy(password.Config{
+		MinLength:     cfg.Password.MinLength,
+		MaxLength:     cfg.Password.MaxLength,
+		MinClasses:    cfg.Password.MinClasses,
+		DenylistFile:  cfg.Password.DenylistFile,
+		BreachCheck:   cfg.Password.BreachCheck,
+		BreachURL:     cfg.Password.BreachURL,
+		BreachTimeout: cfg.Password.BreachTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки политики паролей: %v", err)
+	}
+	// Учет попыток входа для детектора аномалий service_orders и истории входов пользователей
+	var loginAttempts repository.LoginAttemptRepository
+	if cfg.Login.AttemptsRetention > 0 {
+		loginAttempts = repository.NewLoginAttemptRepository(db)
+		s.jobs = append(s.jobs, func(ctx context.Context) {
+			pruneLoginAttempts(ctx, loginAttempts, cfg.Login.AttemptsRetention)
+		})
+	}
+	// Временная блокировка входа после неудачных попыток
+	var lockoutRepo repository.LoginLockoutRepository
+	if cfg.Lockout.Enabled() {
+		lockoutRepo = repository.NewLoginLockoutRepository(db)
+		s.jobs = append(s.jobs, func(ctx context.Context) {
+			pruneLoginLockouts(ctx, lockoutRepo, cfg.Lockout.ResetAfter)
+		})
+	}
+	// Время входа и журнал действий администраторов для отчета о пересмотре доступа
+	accessRepo := repository.NewAccessReviewRepository(db)
+	// Черный список покупателей: запрет входа (записи block); заказы проверяет service_orders
+	blacklistRepo := repository.NewBlacklistRepository(db)
+	// Роли и их права: права ролей пользователя записываются в access токен при выдаче
+	roleRepo := repository.NewRoleRepository(db)
+	userHandler := handlers.NewUserHandler(userRepo, refreshRepo, emailPolicy, passwordPolicy, loginAttempts, lockoutRepo, accessRepo, blacklistRepo, roleRepo, cfg)
+	lockoutHandler := handlers.NewLockoutHandler(userHandler)
+	loginHistoryHandler := handlers.NewLoginHistoryHandler(userHandler)
+	supportHandler := handlers.NewSupportHandler(userHandler)
+	// Выгрузки: одна одновременная выгрузка на пользователя и хранение файлов для докачки
+	exportRepo := repository.NewExportRepository(db)
+	s.jobs = append(s.jobs, func(ctx context.Context) {
+		pruneExports(ctx, exportRepo)
+	})
+	exportHandler := handlers.NewExportHandler(userHandler, exportRepo)
+	accessReviewHandler := handlers.NewAccessReviewHandler(exportHandler)
+	jwksHandler := handlers.NewJWKSHandler(userHandler)
+	emailDomainHandler := handlers.NewEmailDomainHandler(userHandler, emailDomainRepo)
+	blacklistHandler := handlers.NewBlacklistHandler(userHandler)
+	notificationRepo := repository.NewNotificationRepository(db)
+	// Персональные API ключи: API Gateway обменивает ключ на access токен с областями ключа
+	apiKeyHandler := handlers.NewAPIKeyHandler(userHandler, repository.NewAPIKeyRepository(db))
+	notificationHandler := handlers.NewNotificationHandler(userHandler, notificationRepo)
+
+	// Эпохи ролей в Redis для мгновенного отзыва токенов при изменении ролей
+	var epochs *rolesepoch.Store
+	if cfg.Redis.Host != "" {
+		redisClient := rolesepoch.NewRedisClient(cfg.Redis.Addr(), cfg.Redis.Password, cfg.Redis.DB, cfg.Redis.Timeout)
+		epochs = rolesepoch.NewStore(redisClient, cfg.JWT.AccessTTL)
+		s.epochs = epochs
+		zapLogger.Info("Публикация эпох ролей в Redis включена", zap.String("addr", cfg.Redis.Addr()))
+	}
+	roleHandler := handlers.NewRoleHandler(userHandler, epochs)
+	deactivationHandler := handlers.NewDeactivationHandler(userHandler, epochs)
+	sessionHandler := handlers.NewSessionHandler(userHandler, epochs)
+
+	// Синхронизация пользователей и ролей с внешним каталогом
+	syncer := directory.NewSyncer(userRepo, repository.NewDirectoryRepository(db), refreshRepo, epochs, cfg.Directory.GroupRoles)
+	directoryHandler := handlers.NewDirectoryHandler(userHandler, syncer)
+	if cfg.Directory.SyncURL != "" {
+		job := directory.NewJob(syncer, directory.JobConfig{
+			URL:               cfg.Directory.SyncURL,
+			Format:            cfg.Directory.SyncFormat,
+			Token:             cfg.Directory.SyncToken,
+			Source:            cfg.Directory.Source,
+			Interval:          cfg.Directory.SyncInterval,
+			DeactivateMissing: cfg.Directory.DeactivateMissing,
+		})
+		// Синхронизацию выполняет один экземпляр сервиса, остальные ждут блокировку
+		s.jobs = append(s.jobs, func(ctx context.Context) {
+			lock.Leader(ctx, lock.NewPostgres(db, lock.NamespaceJobs),
+				"directory-sync:"+cfg.Directory.Source, leaderRetryInterval, job.Run)
+		})
+		zapLogger.Info("Периодическая синхронизация с каталогом включена",
+			zap.String("source", cfg.Directory.Source),
+			zap.Duration("interval", cfg.Directory.SyncInterval))
+	}
+
+	// Удаление данных пользователей выполняется в фоне; операции переживают перезапуск
+	deletionRepo := repository.NewUserDeletionRepository(db)
+	deletionWorker := deletion.NewWorker(deletionRepo, epochs)
+	s.jobs = append(s.jobs, deletionWorker.Run)
+	personalDataHandler := handlers.NewPersonalDataHandler(userHandler, repository.NewPersonalDataRepository(db))
+
+	// Вход через Google и GitHub: включаются заданием client ID провайдера
+	var oauthProviders []oauth.Provider
+	if cfg.OAuth.GoogleClientID != "" {
+		oauthProviders = append(oauthProviders, oauth.NewGoogle(oauth.Credentials{
+			ClientID:     cfg.OAuth.GoogleClientID,
+			ClientSecret: cfg.OAuth.GoogleClientSecret,
+		}))
+	}
+	if cfg.OAuth.GitHubClientID != "" {
+		oauthProviders = append(oauthProviders, oauth.NewGitHub(oauth.Credentials{
+			ClientID:     cfg.OAuth.GitHubClientID,
+			ClientSecret: cfg.OAuth.GitHubClientSecret,
+		}))
+	}
+	oauthHandler := handlers.NewOAuthHandler(userHandler, repository.NewIdentityRepository(db), oauthProviders)
+
+	// Письма пользователям (сброс пароля, смена email, удаление данных): через SMTP или в лог, если SMTP не настроен
+	var mailSender mail.Sender = mail.NewLogSender(cfg.Mail.LogBody)
+	if cfg.Mail.SMTPHost != "" {
+		mailSender = mail.NewSMTPSender(mail.SMTPConfig{
+			Host:     cfg.Mail.SMTPHost,
+			Port:     cfg.Mail.SMTPPort,
+			Username: cfg.Mail.SMTPUsername,
+			Password: cfg.Mail.SMTPPassword,
+			From:     cfg.Mail.From,
+		})
+	} else {
+		zapLogger.Warn("SMTP не настроен, письма пишутся в лог")
+	}
+	passwordResetHandler := handlers.NewPasswordResetHandler(userHandler, repository.NewPasswordResetRepository(db), mailSender, epochs)
+	profileHandler := handlers.NewProfileHandler(userHandler, repository.NewEmailChangeRepository(db), mailSender)
+	deletionHandler := handlers.NewDeletionHandler(userHandler, deletionRepo, deletionWorker, mailSender)
+
+	// Настройка маршрутов
+	router := mux.NewRouter()
+	// Неизвестные маршруты и методы отвечают в формате APIResponse, как и обработчики
+	router.NotFoundHandler = errorHandler(http.StatusNotFound, models.ErrorCodeNotFound, "Маршрут не найден")
+	router.MethodNotAllowedHandler = errorHandler(http.StatusMethodNotAllowed, models.ErrorCodeMethodNotAllowed, "Метод не поддерживается")
+
+	router.HandleFunc("/version", buildinfo.Handler("service_users")).Methods("GET")
+
+	// Публичные маршруты
+	router.HandleFunc("/v1/users/register", userHandler.RegisterUser).Methods("POST")
+	router.HandleFunc("/v1/users/login", userHandler.LoginUser).Methods("POST")
+	router.HandleFunc("/v1/auth/refresh", userHandler.RefreshToken).Methods("POST")
+	router.HandleFunc("/v1/auth/revoke", userHandler.RevokeRefreshToken).Methods("POST")
+	// Обмен API ключа на access токен; вызывается только API Gateway
+	router.HandleFunc("/v1/auth/api-key", apiKeyHandler.AuthenticateAPIKey).Methods("POST")
+	router.HandleFunc("/v1/users/password-reset/request", passwordResetHandler.RequestPasswordReset).Methods("POST")
+	router.HandleFunc("/v1/users/password-reset/confirm", passwordResetHandler.ConfirmPasswordReset).Methods("POST")
+	// Ссылка из письма может быть открыта без входа, поэтому подтверждение — по токену
+	router.HandleFunc("/v1/users/profile/email/confirm", profileHandler.ConfirmEmailChange).Methods("POST")
+	router.HandleFunc("/v1/users/password-policy", userHandler.GetPasswordPolicy).Methods("GET")
+	router.HandleFunc("/v1/users/oauth/{provider}/start", oauthHandler.StartOAuthLogin).Methods("GET")
+	router.HandleFunc("/v1/users/oauth/{provider}/callback", oauthHandler.OAuthCallback).Methods("GET")
+	router.HandleFunc("/.well-known/jwks.json", jwksHandler.GetJWKS).Methods("GET")
+
+	// Защищенные маршруты
+	router.HandleFunc("/v1/users/profile", userHandler.GetUserProfile).Methods("GET")
+	router.HandleFunc("/v1/users/profile", profileHandler.UpdateUserProfile).Methods("PUT")
+	router.HandleFunc("/v1/users/profile", profileHandler.PatchUserProfile).Methods("PATCH")
+	router.HandleFunc("/v1/users/profile/logins", loginHistoryHandler.GetLoginHistory).Methods("GET")
+	router.HandleFunc("/v1/users/profile/api-keys", apiKeyHandler.ListAPIKeys).Methods("GET")
+	router.HandleFunc("/v1/users/profile/api-keys", apiKeyHandler.CreateAPIKey).Methods("POST")
+	router.HandleFunc("/v1/users/profile/api-keys/{id}", apiKeyHandler.RevokeAPIKey).Methods("DELETE")
+	router.HandleFunc("/v1/users", userHandler.ListUsers).Methods("GET")
+	router.HandleFunc("/v1/users/{id}/roles", roleHandler.UpdateUserRoles).Methods("PUT")
+	router.HandleFunc("/v1/users/{id:[0-9a-fA-F-]{36}}", userHandler.GetUser).Methods("GET")
+	router.HandleFunc("/v1/users/me/notifications", notificationHandler.GetNotificationPreferences).Methods("GET")
+	router.HandleFunc("/v1/users/me/notifications", notificationHandler.UpdateNotificationPreferences).Methods("PUT")
+	router.HandleFunc("/v1/users/me", deletionHandler.DeleteCurrentUser).Methods("DELETE")
+	router.HandleFunc("/v1/users/me/deletion", deletionHandler.GetCurrentUserDeletion).Methods("GET")
+	router.HandleFunc("/v1/users/me/export", personalDataHandler.ExportPersonalData).Methods("GET")
+	router.HandleFunc("/v1/users/me/password", passwordResetHandler.ChangePassword).Methods("POST")
+	router.HandleFunc("/v1/users/me/sessions", sessionHandler.ListSessions).Methods("GET")
+	router.HandleFunc("/v1/users/me/sessions", sessionHandler.RevokeAllSessions).Methods("DELETE")
+	router.HandleFunc("/v1/users/me/sessions/{id}", sessionHandler.RevokeSession).Methods("DELETE")
+	router.HandleFunc("/v1/admin/users/{id}", deletionHandler.DeleteUser).Methods("DELETE")
+	router.HandleFunc("/v1/admin/users/{id}/deletion", deletionHandler.GetUserDeletion).Methods("GET")
+	router.HandleFunc("/v1/admin/users/{id}/unlock", lockoutHandler.UnlockUser).Methods("POST")
+	router.HandleFunc("/v1/admin/users/{id}/logins", loginHistoryHandler.GetUserLoginHistory).Methods("GET")
+	router.HandleFunc("/v1/admin/users/{id}/deactivate", deactivationHandler.DeactivateUser).Methods("POST")
+	router.HandleFunc("/v1/admin/users/{id}/reactivate", deactivationHandler.ReactivateUser).Methods("POST")
+	router.HandleFunc("/v1/admin/directory-sync", directoryHandler.SyncDirectory).Methods("POST")
+	router.HandleFunc("/v1/admin/email-domains", emailDomainHandler.GetEmailDomainPolicy).Methods("GET")
+	router.HandleFunc("/v1/admin/email-domains", emailDomainHandler.BanEmailDomain).Methods("POST")
+	router.HandleFunc("/v1/admin/email-domains/disposable/refresh", emailDomainHandler.RefreshDisposableDomains).Methods("POST")
+	router.HandleFunc("/v1/admin/email-domains/{domain}", emailDomainHandler.UnbanEmailDomain).Methods("DELETE")
+	router.HandleFunc("/v1/admin/blacklist", blacklistHandler.ListBlacklist).Methods("GET")
+	router.HandleFunc("/v1/admin/blacklist", blacklistHandler.AddBlacklistEntry).Methods("POST")
+	router.HandleFunc("/v1/admin/blacklist/{id}", blacklistHandler.RemoveBlacklistEntry).Methods("DELETE")
+	router.Handle("/v1/support/users/{id}", supportHandler.Redacted(supportHandler.GetSupportUser)).Methods("GET")
+	router.HandleFunc("/v1/admin/permissions", roleHandler.ListPermissions).Methods("GET")
+	router.HandleFunc("/v1/admin/roles", roleHandler.ListRoles).Methods("GET")
+	router.HandleFunc("/v1/admin/roles", roleHandler.CreateRole).Methods("POST")
+	router.HandleFunc("/v1/admin/roles/{name}", roleHandler.UpdateRole).Methods("PUT")
+	router.HandleFunc("/v1/admin/roles/{name}", roleHandler.DeleteRole).Methods("DELETE")
+	router.HandleFunc("/v1/admin/access-review", accessReviewHandler.GetAccessReview).Methods("GET")
+	router.HandleFunc("/v1/admin/access-review/exports/{id}", exportHandler.DownloadExport).Methods("GET")
+
+	// X-Request-ID, этапы обработки (Server-Timing), лог запросов и перехват panic внутри лога,
+	// чтобы ответ 500 был залогирован
+	s.handler = httpmw.NewChain(
+		httpmw.RequestID(httpmw.RequestIDConfig{}),
+		httpmw.ServerTiming(),
+		httpmw.Logging(httpmw.LoggingConfig{Log: logRequest}),
+		httpmw.Recovery(httpmw.RecoveryConfig{
+			Body:   mustMarshal(models.NewErrorResponse(models.ErrorCodeInternalServer, "Внутренняя ошибка сервера")),
+			Report: reportPanic,
+		}),
+	).Then(router)
+
+	return s, nil
+}
+
+// Handler возвращает обработчик запросов сервиса с middleware
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Start запускает фоновые задачи сервиса; задачи завершаются при отмене ctx
+func (s *Server) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go job(ctx)
+	}
+}
+
+// Close освобождает ресурсы сервиса (подключение к Redis)
+func (s *Server) Close() error {
+	if s.epochs == nil {
+		return nil
+	}
+	return s.epochs.Close()
+}
+
+// pruneLoginAttempts раз в час удаляет попытки входа старше retention, пока не будет отменен ctx
+func pruneLoginAttempts(ctx context.Context, repo repository.LoginAttemptRepository, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := repo.DeleteBefore(time.Now().Add(-retention))
+		if err != nil {
+			logger.GetLogger().Warn("Ошибка удаления устаревших попыток входа", zap.Error(err))
+		} else if deleted > 0 {
+			logger.GetLogger().Info("Удалены устаревшие попытки входа", zap.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pruneLoginLockouts раз в час удаляет счетчики неудачных входов без неудач дольше resetAfter,
+// пока не будет отменен ctx
+func pruneLoginLockouts(ctx context.Context, repo repository.LoginLockoutRepository, resetAfter time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := repo.DeleteStale(time.Now().Add(-resetAfter))
+		if err != nil {
+			logger.GetLogger().Warn("Ошибка удаления устаревших блокировок входа", zap.Error(err))
+		} else if deleted > 0 {
+			logger.GetLogger().Info("Удалены устаревшие блокировки входа", zap.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// leaderRetryInterval период попытки захватить блокировку фоновой задачи, которую
+// выполняет другой экземпляр сервиса
+const leaderRetryInterval = 30 * time.Second
+
+// pruneExportsInterval период удаления выгрузок с истекшим сроком хранения
+const pruneExportsInterval = 10 * time.Minute
+
+// pruneExports удаляет выгрузки с истекшим сроком хранения, пока не будет отменен ctx
+func pruneExports(ctx context.Context, repo repository.ExportRepository) {
+	ticker := time.NewTicker(pruneExportsInterval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := repo.DeleteExpired(time.Now())
+		if err != nil {
+			logger.GetLogger().Warn("Ошибка удаления истекших выгрузок", zap.Error(err))
+		} else if deleted > 0 {
+			logger.GetLogger().Info("Удалены истекшие выгрузки", zap.Int64("deleted", deleted))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// logRequest пишет в лог итог обработки запроса
+func logRequest(r *http.Request, result httpmw.Result) {
+	// Используем структурированный логгер
+	logger.LogHTTPRequest(r, result.Status, r.Method, r.URL.Path, "service_users")
+
+	// Дополнительные метрики
+	zapLogger := logger.WithRequestID(logger.GetLogger(), httpmw.RequestIDFromContext(r.Context()))
+	// Этапы обработки из Server-Timing: ожидание, обработчик, БД, публикация событий
+	timing := servertiming.FromContext(r.Context())
+	zapLogger.Info("Request completed",
+		zap.Duration("duration", result.Duration),
+		zap.Int64("content_length", r.ContentLength),
+		zap.Int64("bytes_out", result.BytesOut),
+		zap.Duration("wait_duration", timing.Get(servertiming.MetricWait)),
+		zap.Duration("app_duration", timing.Get(servertiming.MetricApp)),
+		zap.Duration("db_duration", timing.Get(servertiming.MetricDB)),
+		zap.Duration("publish_duration", timing.Get(servertiming.MetricPublish)),
+	)
+}
+
+// reportPanic пишет в лог panic обработчика со стеком и X-Request-ID;
+// клиент получает 500 в формате APIResponse
+func reportPanic(r *http.Request, value interface{}, stack []byte) {
+	zapLogger := logger.WithRequestID(logger.GetLogger(), httpmw.RequestIDFromContext(r.Context()))
+	zapLogger.Error("Panic при обработке запроса",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.Any("panic", value),
+		zap.ByteString("stack", stack),
+	)
+}
+
+// errorHandler отвечает на любой запрос ошибкой в формате APIResponse
+func errorHandler(status int, code, message string) http.Handler {
+	body := mustMarshal(models.NewErrorResponse(code, message))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpresp.Write(w, status, httpresp.ContentTypeJSON, body)
+	})
+}
+
+// mustMarshal сериализует постоянное тело ответа при запуске
+func mustMarshal(v interface{}) []byte {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return body
+}
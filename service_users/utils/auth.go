@@ -1,11 +1,19 @@
 package utils
 
 import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"time"
 
 	"service_users/models"
 
+	"pkg/jwtkeys"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
@@ -16,6 +24,12 @@ type JWTClaims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
 	Roles  []string  `json:"roles"`
+	// RolesEpoch эпоха ролей на момент выдачи; API Gateway сверяет ее с текущей
+	RolesEpoch int64 `json:"roles_epoch"`
+	// Scopes области API ключа, по которому выдан токен; пусто у токенов входа
+	Scopes []string `json:"scopes,omitempty"`
+	// Permissions права ролей пользователя на момент выдачи; пусто у токенов API ключей
+	Permissions []string `json:"permissions,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -34,23 +48,58 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
-// GenerateJWT генерирует JWT токен для пользователя
-func GenerateJWT(user *models.User, secret string) (string, error) {
-	claims := JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Roles:  user.Roles,
+// GenerateJWT генерирует JWT токен для пользователя с правами его ролей permissions
+// и временем жизни ttl. Токен подписывается текущим ключом signer (HS256, RS256 или ES256),
+// идентификатор которого указывается в заголовке kid
+func GenerateJWT(user *models.User, permissions []string, signer jwtkeys.Signer, ttl time.Duration) (string, error) {
+	claims := newClaims(user, user.Roles, ttl)
+	claims.Permissions = permissions
+	return signJWT(claims, signer)
+}
+
+// GenerateAPIKeyJWT генерирует access токен по API ключу с областями scopes.
+// Роль admin и права ролей в токен не попадают: API ключ не дает административных прав
+func GenerateAPIKeyJWT(user *models.User, scopes []string, signer jwtkeys.Signer, ttl time.Duration) (string, error) {
+	roles := make([]string, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		if role != "admin" {
+			roles = append(roles, role)
+		}
+	}
+
+	claims := newClaims(user, roles, ttl)
+	claims.Scopes = scopes
+	return signJWT(claims, signer)
+}
+
+// newClaims создает claims токена пользователя с ролями roles и временем жизни ttl
+func newClaims(user *models.User, roles []string, ttl time.Duration) JWTClaims {
+	now := time.Now()
+	return JWTClaims{
+		UserID:     user.ID,
+		Email:      user.Email,
+		Roles:      roles,
+		RolesEpoch: user.RolesEpoch,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // токен действует 24 часа
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "service_users",
 			Subject:   user.ID.String(),
 		},
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(secret))
+// signJWT подписывает claims текущим ключом signer
+func signJWT(claims JWTClaims, signer jwtkeys.Signer) (string, error) {
+	algorithm, kid, key := signer.SigningKey()
+	method := jwt.GetSigningMethod(algorithm)
+	if method == nil {
+		return "", fmt.Errorf("неподдерживаемый алгоритм подписи JWT: %s", algorithm)
+	}
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	tokenString, err := token.SignedString(key)
 	if err != nil {
 		return "", fmt.Errorf("ошибка генерации JWT токена: %v", err)
 	}
@@ -58,14 +107,54 @@ func GenerateJWT(user *models.User, secret string) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateJWT проверяет и парсит JWT токен
-func ValidateJWT(tokenString, secret string) (*JWTClaims, error) {
+// GenerateRefreshToken генерирует случайный refresh токен и его хеш для хранения в БД
+func GenerateRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("ошибка генерации refresh токена: %v", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, HashRefreshToken(token), nil
+}
+
+// GenerateAPIKey генерирует значение API ключа с префиксом models.APIKeyPrefix,
+// его отображаемое начало и хеш для хранения в БД
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", "", fmt.Errorf("ошибка генерации API ключа: %v", err)
+	}
+
+	key = models.APIKeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return key, key[:len(models.APIKeyPrefix)+8], HashRefreshToken(key), nil
+}
+
+// HashRefreshToken возвращает SHA-256 хеш refresh токена
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateJWT проверяет и парсит JWT токен: HS256 секретами keys (токен с kid проверяется
+// секретом с этим идентификатором, токен без kid — каждым из секретов), RS256 и ES256 —
+// публичным ключом signing по kid. keys или signing могут быть nil
+func ValidateJWT(tokenString string, keys *jwtkeys.Keyring, signing *jwtkeys.SigningKeys) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("неожиданный метод подписи: %v", token.Header["alg"])
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if keys == nil {
+				return nil, fmt.Errorf("неожиданный метод подписи: %v", token.Header["alg"])
+			}
+			return verificationKey(token, keys)
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+			if signing == nil {
+				return nil, fmt.Errorf("неожиданный метод подписи: %v", token.Header["alg"])
+			}
+			return publicVerificationKey(token, signing)
 		}
-		return []byte(secret), nil
-	})
+		return nil, fmt.Errorf("неожиданный метод подписи: %v", token.Header["alg"])
+	}, jwt.WithValidMethods([]string{jwtkeys.AlgorithmHS256, jwtkeys.AlgorithmRS256, jwtkeys.AlgorithmES256}))
 
 	if err != nil {
 		return nil, fmt.Errorf("ошибка парсинга JWT токена: %v", err)
@@ -77,3 +166,43 @@ func ValidateJWT(tokenString, secret string) (*JWTClaims, error) {
 
 	return nil, fmt.Errorf("недействительный JWT токен")
 }
+
+// verificationKey возвращает ключ проверки HMAC подписи токена: секрет по kid
+// или все секреты keys для токенов, выпущенных без kid
+func verificationKey(token *jwt.Token, keys *jwtkeys.Keyring) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok {
+		keySet := jwt.VerificationKeySet{}
+		for _, secret := range keys.Secrets() {
+			keySet.Keys = append(keySet.Keys, secret)
+		}
+		return keySet, nil
+	}
+
+	secret, ok := keys.Secret(kid)
+	if !ok {
+		return nil, fmt.Errorf("неизвестный идентификатор ключа: %s", kid)
+	}
+	return secret, nil
+}
+
+// publicVerificationKey возвращает публичный ключ signing по kid токена. Тип ключа
+// должен соответствовать алгоритму токена
+func publicVerificationKey(token *jwt.Token, signing *jwtkeys.SigningKeys) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	public, ok := signing.PublicKey(kid)
+	if !ok {
+		return nil, fmt.Errorf("неизвестный идентификатор ключа: %s", kid)
+	}
+	switch public.(type) {
+	case *rsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); ok {
+			return public, nil
+		}
+	case *ecdsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); ok {
+			return public, nil
+		}
+	}
+	return nil, fmt.Errorf("ключ %s не подходит для алгоритма %v", kid, token.Header["alg"])
+}
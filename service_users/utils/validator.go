@@ -2,16 +2,29 @@ package utils
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
+	"pkg/timeutil"
+
 	"github.com/go-playground/validator/v10"
 )
 
 // Validator глобальный экземпляр валидатора
 var Validator *validator.Validate
 
+// roleNamePattern допустимое название роли: роли передаются сервисам через запятую
+// в заголовке X-User-Roles
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
 func init() {
 	Validator = validator.New()
+	Validator.RegisterValidation("timezone", func(fl validator.FieldLevel) bool {
+		return timeutil.IsValidTimezone(fl.Field().String())
+	})
+	Validator.RegisterValidation("rolename", func(fl validator.FieldLevel) bool {
+		return roleNamePattern.MatchString(fl.Field().String())
+	})
 }
 
 // ValidateStruct валидирует структуру и возвращает читаемые ошибки
@@ -22,7 +35,7 @@ func ValidateStruct(s interface{}) error {
 		for _, err := range err.(validator.ValidationErrors) {
 			errors = append(errors, getErrorMessage(err))
 		}
-		return fmt.Errorf(strings.Join(errors, "; "))
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
 	}
 	return nil
 }
@@ -40,6 +53,12 @@ func getErrorMessage(fe validator.FieldError) string {
 		return fmt.Sprintf("поле '%s' должно содержать минимум %s символов", field, fe.Param())
 	case "max":
 		return fmt.Sprintf("поле '%s' должно содержать максимум %s символов", field, fe.Param())
+	case "oneof":
+		return fmt.Sprintf("поле '%s' должно иметь одно из значений: %s", field, fe.Param())
+	case "timezone":
+		return fmt.Sprintf("поле '%s' должно содержать часовой пояс IANA, например Europe/Moscow", field)
+	case "rolename":
+		return fmt.Sprintf("поле '%s' должно начинаться со строчной латинской буквы и содержать только строчные латинские буквы, цифры, '_' и '-'", field)
 	default:
 		return fmt.Sprintf("поле '%s' содержит некорректное значение", field)
 	}
@@ -0,0 +1,192 @@
+// Package apifuzz проверяет обработчик сервиса запросами, построенными по его
+// спецификации OpenAPI: для каждой операции из корректного запроса получаются
+// испорченные варианты (некорректный JSON, значения за границами, неверные типы,
+// слишком большие массивы и тела). Ответ на любой из них должен быть успешным или
+// ошибкой клиента в формате APIResponse — 5xx, panic и ошибки в другом формате
+// считаются отказами. Запросы выполняются в процессе, без сети
+package apifuzz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxFailureBody длина тела ответа, сохраняемая в отказе
+const maxFailureBody = 500
+
+// Target проверяемый сервис
+type Target struct {
+	// Name имя сервиса в отчете
+	Name string
+	// Spec путь к спецификации OpenAPI сервиса
+	Spec string
+	// Handler обработчик запросов сервиса со всеми middleware
+	Handler http.Handler
+	// Header заголовки каждого запроса, например пользователь, которого подставляет API Gateway
+	Header http.Header
+}
+
+// Options параметры проверки
+type Options struct {
+	// Skip operationId операций, которые не проверяются
+	Skip []string
+	// Only operationId проверяемых операций; пусто — все операции, кроме Skip
+	Only []string
+}
+
+// Failure отказ сервиса на одном запросе
+type Failure struct {
+	// Operation метод и путь операции из спецификации
+	Operation string `json:"operation"`
+	// Case испорченная часть запроса и способ порчи
+	Case   string `json:"case"`
+	Status int    `json:"status"`
+	Reason string `json:"reason"`
+	// Body начало тела ответа
+	Body string `json:"body,omitempty"`
+}
+
+// Report результат проверки сервиса
+type Report struct {
+	Service    string    `json:"service"`
+	Operations int       `json:"operations"`
+	Cases      int       `json:"cases"`
+	Failures   []Failure `json:"failures"`
+}
+
+// Run загружает спецификацию цели и проверяет ее операции
+func Run(target Target, options Options) (*Report, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromFile(target.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки спецификации %s: %v", target.Spec, err)
+	}
+
+	skip := toSet(options.Skip)
+	only := toSet(options.Only)
+	report := &Report{Service: target.Name, Failures: []Failure{}}
+
+	paths := doc.Paths.Map()
+	names := make([]string, 0, len(paths))
+	for path := range paths {
+		names = append(names, path)
+	}
+	sort.Strings(names)
+
+	for _, path := range names {
+		item := paths[path]
+		operations := item.Operations()
+		methods := make([]string, 0, len(operations))
+		for method := range operations {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+
+		for _, method := range methods {
+			operation := operations[method]
+			if skip[operation.OperationID] || (len(only) > 0 && !only[operation.OperationID]) {
+				continue
+			}
+
+			report.Operations++
+			name := method + " " + path
+			for _, c := range newOperation(method, path, item, operation).cases() {
+				report.Cases++
+				if failure := execute(target, c); failure != nil {
+					failure.Operation = name
+					report.Failures = append(report.Failures, *failure)
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// execute выполняет запрос и проверяет ответ; nil — ответ допустим
+func execute(target Target, c fuzzCase) (failure *Failure) {
+	var body io.Reader
+	if c.body != nil {
+		body = bytes.NewReader(c.body)
+	}
+	req := httptest.NewRequest(c.method, c.target(), body)
+	for key, values := range target.Header {
+		req.Header[key] = values
+	}
+	if c.contentType != "" {
+		req.Header.Set("Content-Type", c.contentType)
+	}
+
+	recorder := httptest.NewRecorder()
+	defer func() {
+		// Recovery сервиса отвечает 500 на panic обработчика; сюда доходят panic
+		// после начала ответа и panic самих middleware
+		if value := recover(); value != nil {
+			failure = &Failure{Case: c.name, Status: recorder.Code, Reason: fmt.Sprintf("panic: %v", value)}
+		}
+	}()
+	target.Handler.ServeHTTP(recorder, req)
+
+	if reason := checkResponse(recorder); reason != "" {
+		text := recorder.Body.String()
+		if len(text) > maxFailureBody {
+			text = text[:maxFailureBody]
+		}
+		return &Failure{Case: c.name, Status: recorder.Code, Reason: reason, Body: text}
+	}
+	return nil
+}
+
+// envelope ответ сервиса в формате APIResponse
+type envelope struct {
+	Success *bool `json:"success"`
+	Error   *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// checkResponse возвращает причину отказа или пустую строку, если ответ допустим:
+// не 5xx, а ошибка клиента — в формате APIResponse с кодом и сообщением
+func checkResponse(recorder *httptest.ResponseRecorder) string {
+	status := recorder.Code
+	switch {
+	case status >= 500:
+		return fmt.Sprintf("ошибка сервера %d", status)
+	case status < 400:
+		return ""
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+	if mediaType != "application/json" {
+		return fmt.Sprintf("ошибка не в формате APIResponse: Content-Type %q", recorder.Header().Get("Content-Type"))
+	}
+	var response envelope
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		return fmt.Sprintf("ошибка не в формате APIResponse: %v", err)
+	}
+	if response.Success == nil || *response.Success {
+		return "ошибка не в формате APIResponse: success не false"
+	}
+	if response.Error == nil || response.Error.Code == "" || strings.TrimSpace(response.Error.Message) == "" {
+		return "ошибка не в формате APIResponse: нет error.code или error.message"
+	}
+	return ""
+}
+
+// toSet преобразует список в множество
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
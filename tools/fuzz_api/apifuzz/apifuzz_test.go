@@ -0,0 +1,175 @@
+package apifuzz
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// testSpec спецификация с операцией создания с телом и операцией чтения с параметром пути
+const testSpec = `openapi: 3.0.3
+info:
+  title: test
+  version: "1"
+paths:
+  /items:
+    post:
+      operationId: createItem
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              type: object
+              required: [name, count]
+              properties:
+                name:
+                  type: string
+                  maxLength: 10
+                count:
+                  type: integer
+                  minimum: 1
+                  maximum: 5
+      responses:
+        '201':
+          description: created
+  /items/{id}:
+    get:
+      operationId: getItem
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+            format: uuid
+      responses:
+        '200':
+          description: ok
+`
+
+// item тело запроса createItem
+type item struct {
+	Name  *string `json:"name"`
+	Count *int    `json:"count"`
+}
+
+// writeSpec сохраняет testSpec во временный файл и возвращает путь к нему
+func writeSpec(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(path, []byte(testSpec), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// respondError отвечает ошибкой в формате APIResponse
+func respondError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   map[string]string{"code": "VALIDATION_ERROR", "message": message},
+	})
+}
+
+// itemsHandler проверяет запросы по testSpec; checkCount false пропускает проверку
+// границ count, как обработчик с ошибкой валидации
+func itemsHandler(checkCount bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if _, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, "/items/")); err != nil {
+				respondError(w, http.StatusBadRequest, "некорректный ID")
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		var req item
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "некорректный JSON")
+			return
+		}
+		if req.Name == nil || len(*req.Name) > 10 || req.Count == nil {
+			respondError(w, http.StatusBadRequest, "некорректные поля")
+			return
+		}
+		if *req.Count < 1 || *req.Count > 5 {
+			if checkCount {
+				respondError(w, http.StatusBadRequest, "count вне диапазона")
+				return
+			}
+			http.Error(w, "count out of range", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+}
+
+func TestRunAcceptsClientErrors(t *testing.T) {
+	report, err := Run(Target{Name: "items", Spec: writeSpec(t), Handler: itemsHandler(true)}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Operations != 2 || report.Cases == 0 {
+		t.Fatalf("операций %d, случаев %d; ожидались 2 операции и испорченные запросы", report.Operations, report.Cases)
+	}
+	for _, failure := range report.Failures {
+		t.Errorf("отказ %s [%s]: %d %s", failure.Operation, failure.Case, failure.Status, failure.Reason)
+	}
+}
+
+func TestRunReportsServerErrors(t *testing.T) {
+	report, err := Run(Target{Name: "items", Spec: writeSpec(t), Handler: itemsHandler(false)}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Failures) == 0 {
+		t.Fatal("ответы 500 на count вне диапазона не попали в отчет")
+	}
+	for _, failure := range report.Failures {
+		if failure.Operation != "POST /items" || !strings.HasPrefix(failure.Case, "body.count") || failure.Status != http.StatusInternalServerError {
+			t.Errorf("неожиданный отказ %s [%s]: %d %s", failure.Operation, failure.Case, failure.Status, failure.Reason)
+		}
+	}
+}
+
+func TestRunReportsPanics(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		panic("обработчик упал после начала ответа")
+	})
+
+	report, err := Run(Target{Name: "items", Spec: writeSpec(t), Handler: handler}, Options{Only: []string{"getItem"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Operations != 1 || len(report.Failures) != report.Cases {
+		t.Fatalf("операций %d, случаев %d, отказов %d; ожидался отказ на каждом запросе getItem",
+			report.Operations, report.Cases, len(report.Failures))
+	}
+	if !strings.HasPrefix(report.Failures[0].Reason, "panic:") {
+		t.Errorf("причина %q, ожидался panic", report.Failures[0].Reason)
+	}
+}
+
+func TestRunSkipsOperations(t *testing.T) {
+	report, err := Run(Target{Name: "items", Spec: writeSpec(t), Handler: itemsHandler(false)}, Options{Skip: []string{"createItem"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Operations != 1 || len(report.Failures) != 0 {
+		t.Errorf("операций %d, отказов %d; createItem должна быть пропущена", report.Operations, len(report.Failures))
+	}
+}
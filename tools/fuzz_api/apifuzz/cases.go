@@ -0,0 +1,630 @@
+package apifuzz
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	// sampleUUID UUID в корректных запросах; записи с таким ID в базе нет
+	sampleUUID = "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	// oversizedItems число элементов массива и значений списка, если спецификация их не ограничивает
+	oversizedItems = 10000
+	// oversizedString длина строки, если спецификация ее не ограничивает
+	oversizedString = 100000
+	// oversizedBody размер тела больше предела httpreq.DefaultMaxBodySize
+	oversizedBody = 2 << 20
+	// maxDepth глубина вложенности объектов, до которой строятся и портятся поля тела
+	maxDepth = 4
+)
+
+// fuzzCase испорченный запрос
+type fuzzCase struct {
+	// name испорченная часть запроса и способ порчи, например body.items[0].quantity: max+1
+	name   string
+	method string
+	// path шаблон пути из спецификации; params — значения его параметров
+	path        string
+	params      map[string]string
+	query       url.Values
+	body        []byte
+	contentType string
+}
+
+// target возвращает путь и строку запроса
+func (c fuzzCase) target() string {
+	path := c.path
+	for name, value := range c.params {
+		path = strings.ReplaceAll(path, "{"+name+"}", url.PathEscape(value))
+	}
+	if len(c.query) == 0 {
+		return path
+	}
+	return path + "?" + c.query.Encode()
+}
+
+// mutation порча одного значения
+type mutation struct {
+	name  string
+	value interface{}
+	// remove удалить поле вместо замены значения
+	remove bool
+}
+
+// operation операция спецификации и ее корректный запрос, из которого получаются испорченные
+type operation struct {
+	method string
+	path   string
+	// pathParams и queryParams параметры операции и пути, отсортированные по имени
+	pathParams  []*openapi3.Parameter
+	queryParams []*openapi3.Parameter
+	// params и query значения корректного запроса: все параметры пути и обязательные параметры запроса
+	params map[string]string
+	query  url.Values
+	// body схема JSON тела; nil — операция без JSON тела
+	body   *openapi3.Schema
+	sample interface{}
+}
+
+// newOperation строит корректный запрос операции по спецификации
+func newOperation(method, path string, item *openapi3.PathItem, op *openapi3.Operation) *operation {
+	o := &operation{method: method, path: path, params: map[string]string{}, query: url.Values{}}
+
+	// Параметры операции переопределяют одноименные параметры пути
+	parameters := map[string]*openapi3.Parameter{}
+	for _, list := range []openapi3.Parameters{item.Parameters, op.Parameters} {
+		for _, ref := range list {
+			if ref != nil && ref.Value != nil {
+				parameters[ref.Value.In+":"+ref.Value.Name] = ref.Value
+			}
+		}
+	}
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		parameter := parameters[key]
+		switch parameter.In {
+		case openapi3.ParameterInPath:
+			o.pathParams = append(o.pathParams, parameter)
+			o.params[parameter.Name] = paramString(sample(resolve(parameter.Schema), 0))
+		case openapi3.ParameterInQuery:
+			o.queryParams = append(o.queryParams, parameter)
+			if parameter.Required {
+				o.query.Set(parameter.Name, paramString(sample(resolve(parameter.Schema), 0)))
+			}
+		}
+	}
+
+	if op.RequestBody != nil && op.RequestBody.Value != nil {
+		if media := op.RequestBody.Value.Content.Get("application/json"); media != nil {
+			if o.body = resolve(media.Schema); o.body != nil {
+				o.sample = sample(o.body, 0)
+			}
+		}
+	}
+	return o
+}
+
+// cases возвращает испорченные запросы операции
+func (o *operation) cases() []fuzzCase {
+	var cases []fuzzCase
+
+	for _, parameter := range o.pathParams {
+		for _, m := range paramMutations(resolve(parameter.Schema)) {
+			c := o.request("path." + parameter.Name + ": " + m.name)
+			c.params[parameter.Name] = m.value.(string)
+			cases = append(cases, c)
+		}
+	}
+
+	for _, parameter := range o.queryParams {
+		name := "query." + parameter.Name + ": "
+		if parameter.Required {
+			c := o.request(name + "missing required")
+			c.query.Del(parameter.Name)
+			cases = append(cases, c)
+		}
+		for _, m := range paramMutations(resolve(parameter.Schema)) {
+			c := o.request(name + m.name)
+			c.query.Set(parameter.Name, m.value.(string))
+			cases = append(cases, c)
+		}
+	}
+
+	if o.body == nil {
+		return cases
+	}
+
+	for _, malformed := range malformedBodies {
+		c := o.request("body: " + malformed.name)
+		c.body = []byte(malformed.body)
+		cases = append(cases, c)
+	}
+
+	c := o.request("body: oversized")
+	c.body = []byte(`{"fuzz":"` + strings.Repeat("a", oversizedBody) + `"}`)
+	cases = append(cases, c)
+
+	if object, ok := o.sample.(map[string]interface{}); ok {
+		extended := copyJSON(object).(map[string]interface{})
+		extended["__fuzz__"] = true
+		cases = append(cases, o.requestWithBody("body: unknown field", extended))
+	}
+
+	walkFields(o.body, o.sample, nil, 0, func(path []interface{}, schema *openapi3.Schema, required bool) {
+		for _, m := range valueMutations(schema, o.sampleAt(path), required) {
+			document := setPath(copyJSON(o.sample), path, m)
+			cases = append(cases, o.requestWithBody("body"+formatPath(path)+": "+m.name, document))
+		}
+	})
+	return cases
+}
+
+// request возвращает копию корректного запроса операции с именем name
+func (o *operation) request(name string) fuzzCase {
+	params := make(map[string]string, len(o.params))
+	for key, value := range o.params {
+		params[key] = value
+	}
+	query := url.Values{}
+	for key, values := range o.query {
+		query[key] = append([]string(nil), values...)
+	}
+
+	c := fuzzCase{name: name, method: o.method, path: o.path, params: params, query: query}
+	if o.body != nil {
+		c.body, _ = json.Marshal(o.sample)
+		c.contentType = "application/json"
+	}
+	return c
+}
+
+// requestWithBody возвращает корректный запрос с телом document
+func (o *operation) requestWithBody(name string, document interface{}) fuzzCase {
+	c := o.request(name)
+	c.body, _ = json.Marshal(document)
+	return c
+}
+
+// sampleAt возвращает значение корректного тела по пути path
+func (o *operation) sampleAt(path []interface{}) interface{} {
+	current := o.sample
+	for _, key := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			current = node[key.(string)]
+		case []interface{}:
+			current = node[key.(int)]
+		}
+	}
+	return current
+}
+
+// malformedBodies тела, которые не разбираются как ожидаемый JSON объект
+var malformedBodies = []struct{ name, body string }{
+	{"empty", ""},
+	{"truncated object", `{"`},
+	{"unclosed array", `[1, 2`},
+	{"not json", "not json"},
+	{"null", "null"},
+	{"array instead of object", "[]"},
+	{"string instead of object", `"text"`},
+	{"number instead of object", "0"},
+	{"trailing data", "{} {}"},
+	{"invalid utf-8", "{\"fuzz\": \"\xff\xfe\"}"},
+	{"deep nesting", strings.Repeat("[", 100000)},
+}
+
+// paramMutations испорченные значения параметра пути или запроса
+func paramMutations(schema *openapi3.Schema) []mutation {
+	mutations := []mutation{
+		{name: "empty", value: ""},
+		{name: "special characters", value: "\x00'\"<>{}%;--"},
+		{name: "oversized", value: strings.Repeat("a", oversizedString)},
+	}
+	if schema == nil {
+		return mutations
+	}
+
+	switch typeOf(schema) {
+	case openapi3.TypeInteger, openapi3.TypeNumber:
+		mutations = append(mutations, mutation{name: "wrong type", value: "abc"},
+			mutation{name: "overflow", value: "99999999999999999999999"})
+		if typeOf(schema) == openapi3.TypeInteger {
+			mutations = append(mutations, mutation{name: "fraction", value: "1.5"})
+		}
+		for _, m := range numberBounds(schema) {
+			mutations = append(mutations, mutation{name: m.name, value: fmt.Sprint(m.value)})
+		}
+	case openapi3.TypeBoolean:
+		mutations = append(mutations, mutation{name: "wrong type", value: "maybe"})
+	case openapi3.TypeArray:
+		mutations = append(mutations, mutation{name: "oversized list", value: strings.TrimSuffix(strings.Repeat("a,", oversizedItems), ",")})
+	case openapi3.TypeString:
+		for _, m := range stringMutations(schema) {
+			if value, ok := m.value.(string); ok {
+				mutations = append(mutations, mutation{name: m.name, value: value})
+			}
+		}
+	}
+	return mutations
+}
+
+// valueMutations испорченные значения поля тела со схемой schema и корректным значением value
+func valueMutations(schema *openapi3.Schema, value interface{}, required bool) []mutation {
+	var mutations []mutation
+	if required {
+		mutations = append(mutations, mutation{name: "missing required", remove: true})
+	}
+	mutations = append(mutations, mutation{name: "null", value: nil})
+
+	switch typeOf(schema) {
+	case openapi3.TypeString:
+		mutations = append(mutations,
+			mutation{name: "wrong type number", value: 12345},
+			mutation{name: "wrong type boolean", value: true},
+			mutation{name: "wrong type object", value: map[string]interface{}{}})
+		mutations = append(mutations, stringMutations(schema)...)
+	case openapi3.TypeInteger, openapi3.TypeNumber:
+		mutations = append(mutations,
+			mutation{name: "wrong type string", value: "abc"},
+			mutation{name: "wrong type boolean", value: true},
+			mutation{name: "huge", value: json.Number("1e308")},
+			mutation{name: "overflow", value: json.Number("99999999999999999999999")},
+			mutation{name: "negative overflow", value: json.Number("-99999999999999999999999")})
+		if typeOf(schema) == openapi3.TypeInteger {
+			mutations = append(mutations, mutation{name: "fraction", value: 1.5})
+		}
+		mutations = append(mutations, numberBounds(schema)...)
+	case openapi3.TypeBoolean:
+		mutations = append(mutations,
+			mutation{name: "wrong type string", value: "true"},
+			mutation{name: "wrong type number", value: 1})
+	case openapi3.TypeArray:
+		mutations = append(mutations,
+			mutation{name: "wrong type string", value: "not an array"},
+			mutation{name: "wrong type object", value: map[string]interface{}{}})
+		mutations = append(mutations, arrayMutations(schema, value)...)
+	case openapi3.TypeObject:
+		mutations = append(mutations,
+			mutation{name: "wrong type string", value: "not an object"},
+			mutation{name: "wrong type array", value: []interface{}{}})
+	}
+	return mutations
+}
+
+// stringMutations строки за границами длины и вне формата или перечисления
+func stringMutations(schema *openapi3.Schema) []mutation {
+	var mutations []mutation
+	if schema.MaxLength != nil {
+		mutations = append(mutations, mutation{name: "longer than maxLength", value: strings.Repeat("a", int(*schema.MaxLength)+1)})
+	} else {
+		mutations = append(mutations, mutation{name: "oversized string", value: strings.Repeat("a", oversizedString)})
+	}
+	if schema.MinLength > 0 {
+		mutations = append(mutations, mutation{name: "shorter than minLength", value: strings.Repeat("a", int(schema.MinLength)-1)})
+	}
+	if len(schema.Enum) > 0 {
+		mutations = append(mutations, mutation{name: "not in enum", value: "__fuzz__"})
+	}
+	switch schema.Format {
+	case "uuid":
+		mutations = append(mutations, mutation{name: "invalid uuid", value: "not-a-uuid"})
+	case "email":
+		mutations = append(mutations, mutation{name: "invalid email", value: "not-an-email"})
+	case "date-time", "date":
+		mutations = append(mutations, mutation{name: "invalid " + schema.Format, value: "not-a-date"})
+	}
+	return mutations
+}
+
+// numberBounds числа сразу за границами minimum и maximum
+func numberBounds(schema *openapi3.Schema) []mutation {
+	step := 1.0
+	if typeOf(schema) == openapi3.TypeNumber {
+		step = 0.01
+	}
+
+	var mutations []mutation
+	if schema.Min != nil {
+		value := *schema.Min - step
+		if schema.ExclusiveMin {
+			value = *schema.Min
+		}
+		mutations = append(mutations, mutation{name: "below minimum", value: value})
+	}
+	if schema.Max != nil {
+		value := *schema.Max + step
+		if schema.ExclusiveMax {
+			value = *schema.Max
+		}
+		mutations = append(mutations, mutation{name: "above maximum", value: value})
+	}
+	return mutations
+}
+
+// arrayMutations массивы за границами числа элементов и с элементами неверного типа
+func arrayMutations(schema *openapi3.Schema, value interface{}) []mutation {
+	var item interface{} = "fuzz"
+	if items, ok := value.([]interface{}); ok && len(items) > 0 {
+		item = items[0]
+	}
+	repeat := func(n int) []interface{} {
+		result := make([]interface{}, n)
+		for i := range result {
+			result[i] = copyJSON(item)
+		}
+		return result
+	}
+
+	var mutations []mutation
+	if schema.MaxItems != nil {
+		mutations = append(mutations, mutation{name: "more than maxItems", value: repeat(int(*schema.MaxItems) + 1)})
+	} else {
+		mutations = append(mutations, mutation{name: "oversized array", value: repeat(oversizedItems)})
+	}
+	if schema.MinItems > 0 {
+		mutations = append(mutations, mutation{name: "fewer than minItems", value: repeat(int(schema.MinItems) - 1)})
+	}
+
+	wrong := interface{}(12345)
+	if itemType := typeOf(resolve(schema.Items)); itemType == openapi3.TypeInteger || itemType == openapi3.TypeNumber {
+		wrong = "abc"
+	}
+	mutations = append(mutations, mutation{name: "wrong item type", value: []interface{}{wrong}})
+	return mutations
+}
+
+// walkFields вызывает fn для каждого поля тела, вложенных объектов и первого элемента
+// массивов объектов. Поля только для чтения пропускаются
+func walkFields(schema *openapi3.Schema, value interface{}, path []interface{}, depth int, fn func(path []interface{}, schema *openapi3.Schema, required bool)) {
+	if schema == nil || depth >= maxDepth {
+		return
+	}
+
+	switch node := value.(type) {
+	case map[string]interface{}:
+		required := toSet(schema.Required)
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			property := resolve(schema.Properties[name])
+			if property == nil || property.ReadOnly {
+				continue
+			}
+			fieldPath := append(append([]interface{}(nil), path...), name)
+			fn(fieldPath, property, required[name])
+			if child, ok := node[name]; ok {
+				walkFields(property, child, fieldPath, depth+1, fn)
+			}
+		}
+	case []interface{}:
+		if len(node) > 0 {
+			walkFields(resolve(schema.Items), node[0], append(append([]interface{}(nil), path...), 0), depth+1, fn)
+		}
+	}
+}
+
+// setPath применяет порчу m к полю документа по пути path и возвращает документ
+func setPath(document interface{}, path []interface{}, m mutation) interface{} {
+	if len(path) == 0 {
+		return m.value
+	}
+
+	parent := document
+	for _, key := range path[:len(path)-1] {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			parent = node[key.(string)]
+		case []interface{}:
+			parent = node[key.(int)]
+		}
+	}
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		key := path[len(path)-1].(string)
+		if m.remove {
+			delete(node, key)
+		} else {
+			node[key] = m.value
+		}
+	case []interface{}:
+		node[path[len(path)-1].(int)] = m.value
+	}
+	return document
+}
+
+// formatPath записывает путь поля как .items[0].quantity
+func formatPath(path []interface{}) string {
+	var b strings.Builder
+	for _, key := range path {
+		switch k := key.(type) {
+		case string:
+			b.WriteString("." + k)
+		case int:
+			fmt.Fprintf(&b, "[%d]", k)
+		}
+	}
+	return b.String()
+}
+
+// sample строит корректное значение по схеме: пример, значение по умолчанию или первое
+// значение перечисления, иначе значение по типу с учетом границ
+func sample(schema *openapi3.Schema, depth int) interface{} {
+	if schema == nil {
+		return "fuzz"
+	}
+	if schema.Example != nil {
+		return copyJSON(schema.Example)
+	}
+	if schema.Default != nil {
+		return copyJSON(schema.Default)
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+
+	switch typeOf(schema) {
+	case openapi3.TypeObject:
+		object := map[string]interface{}{}
+		if depth >= maxDepth {
+			return object
+		}
+		for name, ref := range schema.Properties {
+			if property := resolve(ref); property != nil && !property.ReadOnly {
+				object[name] = sample(property, depth+1)
+			}
+		}
+		return object
+	case openapi3.TypeArray:
+		n := int(schema.MinItems)
+		if n == 0 {
+			n = 1
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i] = sample(resolve(schema.Items), depth+1)
+		}
+		return items
+	case openapi3.TypeInteger, openapi3.TypeNumber:
+		value := 1.0
+		if schema.Min != nil {
+			value = *schema.Min
+			if schema.ExclusiveMin {
+				value++
+			}
+		}
+		if schema.Max != nil && value > *schema.Max {
+			value = *schema.Max
+		}
+		return value
+	case openapi3.TypeBoolean:
+		return true
+	default:
+		return sampleString(schema)
+	}
+}
+
+// sampleString строит строку по формату и границам длины схемы
+func sampleString(schema *openapi3.Schema) string {
+	switch schema.Format {
+	case "uuid":
+		return sampleUUID
+	case "email":
+		return "fuzz@example.com"
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "uri", "url":
+		return "https://example.com"
+	case "ipv4":
+		return "203.0.113.1"
+	}
+
+	value := "fuzz"
+	if n := int(schema.MinLength); len(value) < n {
+		value += strings.Repeat("a", n-len(value))
+	}
+	if schema.MaxLength != nil && len(value) > int(*schema.MaxLength) {
+		value = value[:*schema.MaxLength]
+	}
+	return value
+}
+
+// paramString записывает значение параметра пути или запроса; элементы массива через запятую
+func paramString(value interface{}) string {
+	if items, ok := value.([]interface{}); ok {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// resolve возвращает схему значения: объединяет части allOf, из oneOf и anyOf берет первый вариант
+func resolve(ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref == nil || ref.Value == nil {
+		return nil
+	}
+	schema := ref.Value
+	switch {
+	case len(schema.AllOf) > 0:
+		merged := &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeObject}, Properties: openapi3.Schemas{}}
+		parts := []*openapi3.Schema{schema}
+		for _, part := range schema.AllOf {
+			parts = append(parts, resolve(part))
+		}
+		for _, part := range parts {
+			if part == nil {
+				continue
+			}
+			for name, property := range part.Properties {
+				merged.Properties[name] = property
+			}
+			merged.Required = append(merged.Required, part.Required...)
+		}
+		return merged
+	case len(schema.OneOf) > 0:
+		return resolve(schema.OneOf[0])
+	case len(schema.AnyOf) > 0:
+		return resolve(schema.AnyOf[0])
+	}
+	return schema
+}
+
+// typeOf возвращает тип схемы; без type тип определяется по properties и items
+func typeOf(schema *openapi3.Schema) string {
+	if schema == nil {
+		return ""
+	}
+	if schema.Type != nil {
+		for _, name := range schema.Type.Slice() {
+			if name != openapi3.TypeNull {
+				return name
+			}
+		}
+	}
+	switch {
+	case len(schema.Properties) > 0:
+		return openapi3.TypeObject
+	case schema.Items != nil:
+		return openapi3.TypeArray
+	}
+	return ""
+}
+
+// copyJSON возвращает глубокую копию значения JSON
+func copyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = copyJSON(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = copyJSON(item)
+		}
+		return result
+	}
+	return value
+}
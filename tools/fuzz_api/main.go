@@ -0,0 +1,203 @@
+// Команда fuzz_api проверяет service_users и service_orders испорченными запросами
+// по их спецификациям OpenAPI (пакет apifuzz): некорректный JSON, значения за
+// границами, неверные типы, слишком большие массивы и тела. Сервисы собираются в
+// процессе (server.New) без сети и без API Gateway, поэтому проверяются ответы самих
+// сервисов, а не валидация Gateway. Ответ должен быть успешным или ошибкой клиента в
+// формате APIResponse; 5xx и panic — отказы (стек panic пишется в журнал сервиса).
+//
+// Команда работает с базой данных из переменных DB_* и изменяет ее данные, поэтому
+// запускается только с ENVIRONMENT=development или test, например с тестовой БД
+// из docker-compose.test.yml:
+//
+//	cd tools/fuzz_api
+//	ENVIRONMENT=test DB_HOST=localhost DB_PORT=5433 DB_NAME=system_control_test \
+//	  DB_USER=postgres DB_PASSWORD=postgres JWT_SECRET=test go run .
+//
+// С теми же переменными проверку выполняет go test; без DB_HOST тест пропускается.
+// Запросы выполняются от имени -user-email с его ролями и правами, как если бы их
+// подставил API Gateway. Отчеты сервисов печатаются в JSON; код выхода 1, если есть
+// хотя бы один отказ
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"fuzz_api/apifuzz"
+	"pkg/ids"
+	"pkg/profile"
+	"pkg/rbac"
+
+	ordersconfig "service_orders/config"
+	orderslogger "service_orders/logger"
+	ordersserver "service_orders/server"
+	usersconfig "service_users/config"
+	userslogger "service_users/logger"
+	usersserver "service_users/server"
+
+	"github.com/lib/pq"
+)
+
+// runOptions параметры проверки сервисов
+type runOptions struct {
+	usersSpec  string
+	ordersSpec string
+	userEmail  string
+	options    apifuzz.Options
+}
+
+func main() {
+	usersSpec := flag.String("users-spec", "../../docs/service_users_api.yaml", "спецификация OpenAPI Service Users")
+	ordersSpec := flag.String("orders-spec", "../../docs/service_orders_api.yaml", "спецификация OpenAPI Service Orders")
+	userEmail := flag.String("user-email", "admin@system.com", "пользователь, от имени которого выполняются запросы")
+	skip := flag.String("skip", "", "operationId операций через запятую, которые не проверяются")
+	only := flag.String("only", "", "operationId проверяемых операций через запятую; пусто — все")
+	flag.Parse()
+
+	reports, err := run(runOptions{
+		usersSpec:  *usersSpec,
+		ordersSpec: *ordersSpec,
+		userEmail:  *userEmail,
+		options:    apifuzz.Options{Skip: splitList(*skip), Only: splitList(*only)},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failed := false
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	for _, report := range reports {
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Ошибка вывода отчета: %v", err)
+		}
+		failed = failed || len(report.Failures) > 0
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// run собирает сервисы в процессе с базой данных из переменных DB_* и возвращает
+// отчеты их проверки
+func run(opts runOptions) ([]*apifuzz.Report, error) {
+	environment := os.Getenv("ENVIRONMENT")
+	if environment != profile.Development && environment != profile.Test {
+		return nil, fmt.Errorf("fuzz_api изменяет данные в БД и запускается только с ENVIRONMENT=%s или %s", profile.Development, profile.Test)
+	}
+	if err := userslogger.Init(environment); err != nil {
+		return nil, fmt.Errorf("ошибка инициализации логгера: %v", err)
+	}
+	if err := orderslogger.Init(environment); err != nil {
+		return nil, fmt.Errorf("ошибка инициализации логгера: %v", err)
+	}
+
+	usersCfg, err := usersconfig.Load()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки конфигурации Service Users: %v", err)
+	}
+	ordersCfg, err := ordersconfig.Load()
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки конфигурации Service Orders: %v", err)
+	}
+	idGenerator, err := ids.NewGenerator(ordersCfg.DB.IDStrategy)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка настройки идентификаторов: %v", err)
+	}
+	ids.SetDefault(idGenerator)
+
+	db, err := sql.Open("postgres", ordersCfg.DB.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к базе данных: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("ошибка проверки подключения к БД: %v", err)
+	}
+
+	header, err := userHeader(db, opts.userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки пользователя %s: %v", opts.userEmail, err)
+	}
+
+	// Фоновые задачи сервисов (Start) не запускаются: проверяются только обработчики
+	users, err := usersserver.New(usersCfg, db)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка инициализации Service Users: %v", err)
+	}
+	defer users.Close()
+	orders, err := ordersserver.New(ordersCfg, db)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка инициализации Service Orders: %v", err)
+	}
+	defer orders.Close()
+
+	targets := []apifuzz.Target{
+		{Name: "service_users", Spec: opts.usersSpec, Handler: users.Handler(), Header: header},
+		{Name: "service_orders", Spec: opts.ordersSpec, Handler: orders.Handler(), Header: header},
+	}
+
+	var reports []*apifuzz.Report
+	for _, target := range targets {
+		report, err := apifuzz.Run(target, opts.options)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка проверки %s: %v", target.Name, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// userHeader возвращает заголовки пользователя email, которые подставляет API Gateway:
+// ID, email, роли и права ролей
+func userHeader(db *sql.DB, email string) (http.Header, error) {
+	var id string
+	var roles []string
+	err := db.QueryRow(`SELECT id, roles FROM users WHERE email = $1`, email).Scan(&id, pq.Array(&roles))
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT DISTINCT permission FROM role_permissions WHERE role = ANY($1) ORDER BY permission`, pq.Array(roles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, permission)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer fuzz")
+	header.Set("X-User-ID", id)
+	header.Set("X-User-Email", email)
+	header.Set("X-User-Roles", strings.Join(roles, ","))
+	header.Set(rbac.HeaderPermissions, strings.Join(permissions, ","))
+	return header, nil
+}
+
+// splitList разбирает список через запятую
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// TestServicesHandleMalformedRequests выполняет ту же проверку, что и команда.
+// Сервисам нужна тестовая БД, поэтому без DB_HOST тест пропускается
+func TestServicesHandleMalformedRequests(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("нужна тестовая БД: задайте ENVIRONMENT=test, DB_* и JWT_SECRET (docker-compose.test.yml, см. docs/README.md)")
+	}
+
+	reports, err := run(runOptions{
+		usersSpec:  "../../docs/service_users_api.yaml",
+		ordersSpec: "../../docs/service_orders_api.yaml",
+		userEmail:  "admin@system.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, report := range reports {
+		for _, failure := range report.Failures {
+			t.Errorf("%s %s [%s]: %d %s", report.Service, failure.Operation, failure.Case, failure.Status, failure.Reason)
+		}
+	}
+}